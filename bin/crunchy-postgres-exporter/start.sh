@@ -206,23 +206,34 @@ else
         fi
     elif (( ${VERSION?} >= 140000 ))
     then
-        if [[ -f ${CONFIG_DIR?}/pg14/queries_general.yml ]]
+        # Newer versions of PostgreSQL use the queries of the newest version
+        # that this image has.
+        QUERY_VERSION=14
+        for (( v = VERSION / 10000; v > 14; v-- ))
+        do
+            if [[ -d ${CONFIG_DIR?}/pg${v} ]]
+            then
+                QUERY_VERSION=${v}
+                break
+            fi
+        done
+        if [[ -f ${CONFIG_DIR?}/pg${QUERY_VERSION?}/queries_general.yml ]]
         then
-            cat ${CONFIG_DIR?}/pg14/queries_general.yml >> /tmp/queries.yml
+            cat ${CONFIG_DIR?}/pg${QUERY_VERSION?}/queries_general.yml >> /tmp/queries.yml
         else
             echo_err "Query file queries_general.yml does not exist (it should).."
         fi
-        if [[ -f ${CONFIG_DIR?}/pg14/queries_pg_stat_statements.yml ]]
+        if [[ -f ${CONFIG_DIR?}/pg${QUERY_VERSION?}/queries_pg_stat_statements.yml ]]
         then
-          cat ${CONFIG_DIR?}/pg14/queries_pg_stat_statements.yml >> /tmp/queries.yml
+          cat ${CONFIG_DIR?}/pg${QUERY_VERSION?}/queries_pg_stat_statements.yml >> /tmp/queries.yml
         else
           echo_warn "Query file queries_pg_stat_statements.yml not loaded."
         fi
         # queries_pg_stat_statements_reset is only available in PG12+. This may
         # need to be updated based on a new path
-        if [[ -f ${CONFIG_DIR?}/pg14/queries_pg_stat_statements_reset_info.yml ]];
+        if [[ -f ${CONFIG_DIR?}/pg${QUERY_VERSION?}/queries_pg_stat_statements_reset_info.yml ]];
         then
-          cat ${CONFIG_DIR?}/pg14/queries_pg_stat_statements_reset_info.yml >> /tmp/queries.yml
+          cat ${CONFIG_DIR?}/pg${QUERY_VERSION?}/queries_pg_stat_statements_reset_info.yml >> /tmp/queries.yml
         else
           echo_warn "Query file queries_pg_stat_statements_reset_info.yml not loaded."
         fi
@@ -230,6 +241,14 @@ else
         echo_err "Unknown or unsupported version of PostgreSQL.  Exiting.."
         exit 1
     fi
+
+    # Queries generated by the operator, such as query statistics, are passed
+    # through the environment and appended to the defaults.
+    if [[ -n "${EXPORTER_EXTRA_QUERIES:-}" ]]
+    then
+        echo_info "Applying additional queries from the environment.."
+        printf '\n%s\n' "${EXPORTER_EXTRA_QUERIES}" >> /tmp/queries.yml
+    fi
 fi
 
 sed -i \
@@ -0,0 +1,320 @@
+package main
+
+/*
+Copyright 2021 Crunchy Data
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// commands implements each "kubectl pgo" command using Client.
+type commands struct {
+	Client    client.Client
+	Namespace string
+	Out       io.Writer
+	Now       func() time.Time
+}
+
+// stringsFlag is a flag.Value that can be repeated.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string     { return strings.Join(*s, " ") }
+func (s *stringsFlag) Set(v string) error { *s = append(*s, v); return nil }
+
+// parse parses args using flags and returns the positional arguments. Unlike
+// flag.FlagSet.Parse, flags may follow positional arguments, as in kubectl.
+func (c *commands) parse(flags *flag.FlagSet, args []string, names ...string) ([]string, error) {
+	flags.StringVar(&c.Namespace, "n", c.Namespace, "the namespace of the PostgresCluster")
+	flags.StringVar(&c.Namespace, "namespace", c.Namespace, "the namespace of the PostgresCluster")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: kubectl pgo %s [flags] %s\n",
+			flags.Name(), strings.ToUpper(strings.Join(names, " ")))
+		flags.PrintDefaults()
+	}
+
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if args = flags.Args(); len(args) == 0 {
+			break
+		}
+		positional, args = append(positional, args[0]), args[1:]
+	}
+
+	if len(positional) != len(names) {
+		flags.Usage()
+		return nil, errors.Errorf("expected %d arguments, got %d", len(names), len(positional))
+	}
+	return positional, nil
+}
+
+func (c *commands) run(ctx context.Context, name string, args []string) error {
+	switch name {
+	case "create":
+		return c.create(ctx, args)
+	case "backup":
+		return c.backup(ctx, args)
+	case "restore":
+		return c.restore(ctx, args)
+	case "switchover":
+		return c.switchover(ctx, args)
+	case "show-backup":
+		return c.showBackup(ctx, args)
+	case "show-user":
+		return c.showUser(ctx, args)
+	}
+	return errors.Errorf("unknown command %q; see \"kubectl pgo --help\"", name)
+}
+
+// patch sends a JSON merge patch to the PostgresCluster named name.
+// - https://tools.ietf.org/html/rfc7386
+func (c *commands) patch(ctx context.Context, name string, patch map[string]interface{}) error {
+	data, err := json.Marshal(patch)
+	if err == nil {
+		cluster := &v1beta1.PostgresCluster{}
+		cluster.Namespace, cluster.Name = c.Namespace, name
+		err = c.Client.Patch(ctx, cluster, client.RawPatch(types.MergePatchType, data))
+	}
+	return errors.WithStack(err)
+}
+
+// trigger returns a unique value for an annotation that starts an operation.
+func (c *commands) trigger() string {
+	return c.Now().UTC().Format(time.RFC3339)
+}
+
+func (c *commands) create(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	version := flags.Int("postgres-version", 14, "the major version of PostgreSQL")
+	replicas := flags.Int("replicas", 1, "the number of PostgreSQL instances")
+	storage := flags.String("storage", "1Gi", "the size of each data volume and of the backup repository")
+
+	args, err := c.parse(flags, args, "name")
+	if err != nil {
+		return err
+	}
+
+	size, err := resource.ParseQuantity(*storage)
+	if err != nil {
+		return errors.Wrap(err, "invalid --storage")
+	}
+
+	claim := corev1.PersistentVolumeClaimSpec{
+		AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+		},
+	}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = c.Namespace, args[0]
+	cluster.Spec.PostgresVersion = *version
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{
+		Name:                "instance1",
+		Replicas:            initialize.Int32(int32(*replicas)),
+		DataVolumeClaimSpec: claim,
+	}}
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{
+		Name:   "repo1",
+		Volume: &v1beta1.RepoPVC{VolumeClaimSpec: claim},
+	}}
+
+	if err := c.Client.Create(ctx, cluster); err != nil {
+		return errors.WithStack(err)
+	}
+
+	fmt.Fprintf(c.Out, "postgrescluster/%s created\n", cluster.Name)
+	return nil
+}
+
+func (c *commands) backup(ctx context.Context, args []string) error {
+	var options stringsFlag
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	repo := flags.String("repo-name", "repo1", "the repository in which to store the backup")
+	flags.Var(&options, "options", "an option for \"pgbackrest backup\", e.g. --type=full; may be repeated")
+
+	args, err := c.parse(flags, args, "name")
+	if err != nil {
+		return err
+	}
+
+	err = c.patch(ctx, args[0], map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{naming.PGBackRestBackup: c.trigger()},
+		},
+		"spec": map[string]interface{}{
+			"backups": map[string]interface{}{
+				"pgbackrest": map[string]interface{}{
+					"manual": map[string]interface{}{
+						"repoName": *repo,
+						"options":  options,
+					},
+				},
+			},
+		},
+	})
+	if err == nil {
+		fmt.Fprintf(c.Out, "postgrescluster/%s backup initiated\n", args[0])
+	}
+	return err
+}
+
+func (c *commands) restore(ctx context.Context, args []string) error {
+	var options stringsFlag
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	repo := flags.String("repo-name", "repo1", "the repository from which to restore")
+	flags.Var(&options, "options", "an option for \"pgbackrest restore\", e.g. --type=time; may be repeated")
+
+	args, err := c.parse(flags, args, "name")
+	if err != nil {
+		return err
+	}
+
+	err = c.patch(ctx, args[0], map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{naming.PGBackRestRestore: c.trigger()},
+		},
+		"spec": map[string]interface{}{
+			"backups": map[string]interface{}{
+				"pgbackrest": map[string]interface{}{
+					"restore": map[string]interface{}{
+						"enabled":  true,
+						"repoName": *repo,
+						"options":  options,
+					},
+				},
+			},
+		},
+	})
+	if err == nil {
+		fmt.Fprintf(c.Out, "postgrescluster/%s restore initiated\n", args[0])
+	}
+	return err
+}
+
+func (c *commands) switchover(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("switchover", flag.ContinueOnError)
+	target := flags.String("target", "", "the instance to promote; by default Patroni chooses")
+
+	args, err := c.parse(flags, args, "name")
+	if err != nil {
+		return err
+	}
+
+	// A null value removes the field from the spec.
+	var targetInstance interface{}
+	if *target != "" {
+		targetInstance = *target
+	}
+
+	err = c.patch(ctx, args[0], map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{naming.PatroniSwitchover: c.trigger()},
+		},
+		"spec": map[string]interface{}{
+			"patroni": map[string]interface{}{
+				"switchover": map[string]interface{}{
+					"enabled":        true,
+					"targetInstance": targetInstance,
+				},
+			},
+		},
+	})
+	if err == nil {
+		fmt.Fprintf(c.Out, "postgrescluster/%s switchover initiated\n", args[0])
+	}
+	return err
+}
+
+func (c *commands) showBackup(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("show-backup", flag.ContinueOnError)
+
+	args, err := c.parse(flags, args, "name")
+	if err != nil {
+		return err
+	}
+
+	cluster := &v1beta1.PostgresCluster{}
+	err = errors.WithStack(c.Client.Get(ctx,
+		client.ObjectKey{Namespace: c.Namespace, Name: args[0]}, cluster))
+	if err != nil {
+		return err
+	}
+	if cluster.Status.PGBackRest == nil {
+		fmt.Fprintf(c.Out, "postgrescluster/%s has no backup status\n", args[0])
+		return nil
+	}
+
+	data, err := yaml.Marshal(cluster.Status.PGBackRest)
+	if err == nil {
+		_, err = c.Out.Write(data)
+	}
+	return errors.WithStack(err)
+}
+
+func (c *commands) showUser(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("show-user", flag.ContinueOnError)
+	reveal := flags.Bool("show-password", false, "print the password and connection URIs")
+
+	args, err := c.parse(flags, args, "name", "user")
+	if err != nil {
+		return err
+	}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = c.Namespace, args[0]
+
+	secret := &corev1.Secret{ObjectMeta: naming.PostgresUserSecret(cluster, args[1])}
+	err = errors.WithStack(c.Client.Get(ctx, client.ObjectKeyFromObject(secret), secret))
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(secret.Data))
+	for key := range secret.Data {
+		switch {
+		case key == "verifier":
+		case !*reveal && (key == "password" || strings.Contains(key, "uri")):
+		default:
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(c.Out, "%s: %s\n", key, secret.Data[key])
+	}
+	return nil
+}
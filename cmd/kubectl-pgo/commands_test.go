@@ -0,0 +1,158 @@
+package main
+
+/*
+Copyright 2021 Crunchy Data
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestCommands(t *testing.T) {
+	ctx := context.Background()
+
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	var out bytes.Buffer
+	cc := fake.NewClientBuilder().WithScheme(testScheme).Build()
+	now := time.Date(2021, time.June, 1, 2, 3, 4, 0, time.UTC)
+	cmd := &commands{
+		Client: cc, Namespace: "ns1", Out: &out,
+		Now: func() time.Time { return now },
+	}
+
+	get := func(t testing.TB) *v1beta1.PostgresCluster {
+		cluster := &v1beta1.PostgresCluster{}
+		assert.NilError(t, cc.Get(ctx, client.ObjectKey{Namespace: "ns1", Name: "hippo"}, cluster))
+		return cluster
+	}
+
+	t.Run("Unknown", func(t *testing.T) {
+		assert.ErrorContains(t, cmd.run(ctx, "frobnicate", nil), "unknown command")
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		out.Reset()
+		assert.NilError(t, cmd.run(ctx, "create", []string{"hippo", "--storage=2Gi", "--replicas", "2"}))
+		assert.Equal(t, out.String(), "postgrescluster/hippo created\n")
+
+		cluster := get(t)
+		assert.Equal(t, cluster.Spec.PostgresVersion, 14)
+		assert.Equal(t, len(cluster.Spec.InstanceSets), 1)
+		assert.Equal(t, *cluster.Spec.InstanceSets[0].Replicas, int32(2))
+		assert.Equal(t, cluster.Spec.InstanceSets[0].DataVolumeClaimSpec.
+			Resources.Requests.Storage().String(), "2Gi")
+		assert.Equal(t, cluster.Spec.Backups.PGBackRest.Repos[0].Name, "repo1")
+
+		assert.ErrorContains(t, cmd.run(ctx, "create", []string{"hippo", "--storage=lots"}), "storage")
+		assert.ErrorContains(t, cmd.run(ctx, "create", nil), "expected 1 arguments")
+	})
+
+	t.Run("Backup", func(t *testing.T) {
+		assert.NilError(t, cmd.run(ctx, "backup",
+			[]string{"hippo", "--repo-name=repo2", "--options=--type=full", "--options", "--start-fast=y"}))
+
+		cluster := get(t)
+		assert.Equal(t, cluster.Annotations[naming.PGBackRestBackup], "2021-06-01T02:03:04Z")
+		assert.Equal(t, cluster.Spec.Backups.PGBackRest.Manual.RepoName, "repo2")
+		assert.DeepEqual(t, cluster.Spec.Backups.PGBackRest.Manual.Options,
+			[]string{"--type=full", "--start-fast=y"})
+
+		// Options from an earlier backup are removed.
+		now = now.Add(time.Minute)
+		assert.NilError(t, cmd.run(ctx, "backup", []string{"hippo"}))
+
+		cluster = get(t)
+		assert.Equal(t, cluster.Annotations[naming.PGBackRestBackup], "2021-06-01T02:04:04Z")
+		assert.Equal(t, cluster.Spec.Backups.PGBackRest.Manual.RepoName, "repo1")
+		assert.Assert(t, cluster.Spec.Backups.PGBackRest.Manual.Options == nil)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		assert.NilError(t, cmd.run(ctx, "restore",
+			[]string{"hippo", "--options=--type=time", "--options=--target=2021-06-01 00:00:00+00"}))
+
+		cluster := get(t)
+		restore := cluster.Spec.Backups.PGBackRest.Restore
+		assert.Equal(t, cluster.Annotations[naming.PGBackRestRestore], "2021-06-01T02:04:04Z")
+		assert.Equal(t, *restore.Enabled, true)
+		assert.Equal(t, restore.RepoName, "repo1")
+		assert.DeepEqual(t, restore.Options,
+			[]string{"--type=time", "--target=2021-06-01 00:00:00+00"})
+	})
+
+	t.Run("Switchover", func(t *testing.T) {
+		assert.NilError(t, cmd.run(ctx, "switchover", []string{"hippo", "--target=hippo-instance1-abcd"}))
+
+		cluster := get(t)
+		assert.Equal(t, cluster.Annotations[naming.PatroniSwitchover], "2021-06-01T02:04:04Z")
+		assert.Equal(t, cluster.Spec.Patroni.Switchover.Enabled, true)
+		assert.Equal(t, *cluster.Spec.Patroni.Switchover.TargetInstance, "hippo-instance1-abcd")
+
+		assert.NilError(t, cmd.run(ctx, "switchover", []string{"hippo"}))
+		assert.Assert(t, get(t).Spec.Patroni.Switchover.TargetInstance == nil)
+	})
+
+	t.Run("ShowBackup", func(t *testing.T) {
+		out.Reset()
+		assert.NilError(t, cmd.run(ctx, "show-backup", []string{"hippo"}))
+		assert.Equal(t, out.String(), "postgrescluster/hippo has no backup status\n")
+
+		cluster := get(t)
+		cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+			Repos: []v1beta1.RepoStatus{{Name: "repo1", StanzaCreated: true}},
+		}
+		assert.NilError(t, cc.Status().Update(ctx, cluster))
+
+		out.Reset()
+		assert.NilError(t, cmd.run(ctx, "show-backup", []string{"-n", "ns1", "hippo"}))
+		assert.Assert(t, bytes.Contains(out.Bytes(), []byte("name: repo1")), "got %q", out.String())
+	})
+
+	t.Run("ShowUser", func(t *testing.T) {
+		secret := &corev1.Secret{}
+		secret.Namespace, secret.Name = "ns1", "hippo-pguser-rhino"
+		secret.Data = map[string][]byte{
+			"host": []byte("hippo-primary.ns1.svc"), "user": []byte("rhino"),
+			"password": []byte("secret"), "uri": []byte("postgresql://rhino:secret@"),
+			"verifier": []byte("SCRAM"),
+		}
+		assert.NilError(t, cc.Create(ctx, secret))
+
+		out.Reset()
+		assert.NilError(t, cmd.run(ctx, "show-user", []string{"hippo", "rhino"}))
+		assert.Equal(t, out.String(), "host: hippo-primary.ns1.svc\nuser: rhino\n")
+
+		out.Reset()
+		assert.NilError(t, cmd.run(ctx, "show-user", []string{"hippo", "rhino", "--show-password"}))
+		assert.Equal(t, out.String(), ""+
+			"host: hippo-primary.ns1.svc\npassword: secret\n"+
+			"uri: postgresql://rhino:secret@\nuser: rhino\n")
+	})
+}
@@ -0,0 +1,100 @@
+package main
+
+/*
+Copyright 2021 Crunchy Data
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-pgo is a kubectl plugin for common PostgresCluster operations. Each
+// command creates, patches, or reads Kubernetes objects; the operator does the
+// rest. Install it by placing the binary on your PATH, then call "kubectl pgo".
+// - https://kubernetes.io/docs/tasks/extend-kubectl/kubectl-plugins/
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+const usage = `Usage: kubectl pgo [-n NAMESPACE] COMMAND [flags] NAME
+
+Commands:
+  create NAME        Create a PostgresCluster
+  backup NAME        Start a manual pgBackRest backup
+  restore NAME       Restore a PostgresCluster in place from a pgBackRest repository
+  switchover NAME    Change which instance is the primary
+  show-backup NAME   Show the backups reported in status
+  show-user NAME USER
+                     Show the connection information of a PostgreSQL user
+
+Run "kubectl pgo COMMAND -h" for the flags of a command.
+`
+
+func main() {
+	if err := run(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	overrides := &clientcmd.ConfigOverrides{}
+
+	// Accept the namespace flag before the command, like kubectl.
+	for len(args) > 1 && (args[0] == "-n" || args[0] == "--namespace") {
+		overrides.Context.Namespace, args = args[1], args[2:]
+	}
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Print(usage)
+		return nil
+	}
+
+	kubeconfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+	namespace, _, err := kubeconfig.Namespace()
+	if err != nil {
+		return err
+	}
+	config, err := kubeconfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+
+	s := runtime.NewScheme()
+	if err := scheme.AddToScheme(s); err != nil {
+		return err
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		return err
+	}
+
+	cc, err := client.New(config, client.Options{Scheme: s})
+	if err != nil {
+		return err
+	}
+
+	return (&commands{
+		Client:    cc,
+		Namespace: namespace,
+		Out:       os.Stdout,
+		Now:       time.Now,
+	}).run(ctx, args[0], args[1:])
+}
@@ -17,18 +17,30 @@ limitations under the License.
 
 import (
 	"context"
+	"io"
 	"os"
 	"strings"
 
+	"github.com/wojas/genericr"
 	"go.opentelemetry.io/otel"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/rest"
 	cruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/crunchydata/postgres-operator/internal/audit"
+	"github.com/crunchydata/postgres-operator/internal/config"
 	"github.com/crunchydata/postgres-operator/internal/controller/postgrescluster"
 	"github.com/crunchydata/postgres-operator/internal/controller/runtime"
+	"github.com/crunchydata/postgres-operator/internal/inventory"
+	"github.com/crunchydata/postgres-operator/internal/kubeapi"
 	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/migration"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/notify"
+	"github.com/crunchydata/postgres-operator/internal/pki"
+	"github.com/crunchydata/postgres-operator/internal/updatecheck"
 )
 
 var versionString string
@@ -41,12 +53,42 @@ func assertNoError(err error) {
 }
 
 func initLogging() {
-	// Configure a singleton that treats logr.Logger.V(1) as logrus.DebugLevel.
+	// Configure a singleton that treats logr.Logger.V(1) as the debug level.
 	var verbosity int
 	if strings.EqualFold(os.Getenv("CRUNCHY_DEBUG"), "true") {
 		verbosity = 1
 	}
-	logging.SetLogFunc(verbosity, logging.Logrus(os.Stdout, versionString, 1))
+	if value := os.Getenv("PGO_LOG_LEVEL"); value != "" {
+		var err error
+		verbosity, err = logging.ParseVerbosity(value)
+		assertNoError(err)
+	}
+
+	// Loggers can be more or less verbose by name, e.g. per controller.
+	names, err := logging.ParseNamedVerbosity(os.Getenv("PGO_LOG_VERBOSITY"))
+	assertNoError(err)
+
+	// The default format is logrus text; zap provides JSON and console.
+	output := func(out io.Writer) genericr.LogFunc {
+		switch format := os.Getenv("PGO_LOG_FORMAT"); format {
+		case "json", "console":
+			return logging.Zap(out, versionString, 1, format)
+		default:
+			return logging.Logrus(out, versionString, 1)
+		}
+	}
+
+	logging.SetLogFuncNamed(verbosity, names, output(os.Stdout))
+
+	// Clusters being debugged also log everything to a separate stream: the
+	// file named by PGO_DEBUG_LOG_FILE or, by default, standard error.
+	var debug io.Writer = os.Stderr
+	if path := os.Getenv("PGO_DEBUG_LOG_FILE"); path != "" {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		assertNoError(err)
+		debug = file
+	}
+	logging.SetDebugLogFunc(output(debug))
 }
 
 func main() {
@@ -63,6 +105,14 @@ func main() {
 
 	cruntime.SetLogger(log)
 
+	// Generated names can follow a convention, such as a required prefix.
+	assertNoError(naming.SetNameTemplates(
+		os.Getenv("PGO_NAME_TEMPLATE"), os.Getenv("PGO_USER_SECRET_NAME_TEMPLATE")))
+
+	// Some environments require particular keys and signatures in certificates.
+	assertNoError(pki.SetAlgorithm(
+		os.Getenv("PGO_CERTIFICATE_KEY_ALGORITHM"), os.Getenv("PGO_CERTIFICATE_SIGNATURE_HASH")))
+
 	cfg, err := runtime.GetConfig()
 	assertNoError(err)
 
@@ -76,8 +126,31 @@ func main() {
 	mgr, err := runtime.CreateRuntimeManager(os.Getenv("PGO_TARGET_NAMESPACE"), cfg, false)
 	assertNoError(err)
 
+	// serve conversions between API versions when a webhook certificate is provided
+	if certDir := os.Getenv("PGO_WEBHOOK_CERT_DIR"); certDir != "" {
+		runtime.AddConversionWebhook(mgr, certDir)
+
+		// validate PostgreSQL memory settings; by default problems are only warnings
+		mode := runtime.GuardrailWarn
+		if strings.EqualFold(os.Getenv("PGO_MEMORY_GUARDRAILS"), string(runtime.GuardrailReject)) {
+			mode = runtime.GuardrailReject
+		}
+
+		// limit the clusters of each namespace or team, if at all
+		quota, err := config.ClusterQuotaPolicy()
+		assertNoError(err)
+		assertNoError(runtime.AddValidationWebhook(mgr, mode, quota))
+	}
+
+	// migrate clusters created by older versions of the operator before any
+	// controller sees them; the manager's client cannot read until it starts
+	migrator, err := client.New(cfg, client.Options{Scheme: mgr.GetScheme()})
+	assertNoError(err)
+	assertNoError(migration.Run(ctx, migrator,
+		os.Getenv("PGO_TARGET_NAMESPACE"), os.Getenv("PGO_OPERATOR_CLASS")))
+
 	// add all PostgreSQL Operator controllers to the runtime manager
-	err = addControllersToManager(ctx, mgr)
+	err = addControllersToManager(ctx, mgr, os.Getenv("PGO_WEBHOOK_CERT_DIR") != "")
 	assertNoError(err)
 
 	log.Info("starting controller runtime manager and will wait for signal to exit")
@@ -86,16 +159,90 @@ func main() {
 }
 
 // addControllersToManager adds all PostgreSQL Operator controllers to the provided controller
-// runtime manager.
-func addControllersToManager(ctx context.Context, mgr manager.Manager) error {
+// runtime manager. When webhooks is true, it also registers their webhooks.
+func addControllersToManager(ctx context.Context, mgr manager.Manager, webhooks bool) error {
+	// labels and annotations for the objects of every cluster
+	defaultMetadata, err := config.DefaultMetadata()
+	if err != nil {
+		return err
+	}
+
+	// limits on commands in containers
+	execPolicy, err := config.PodExecPolicy()
+	if err != nil {
+		return err
+	}
+
+	// where to find the latest minor releases of PostgreSQL, if anywhere
+	updateCheck, err := config.UpdateCheckPolicy()
+	if err != nil {
+		return err
+	}
+
+	// where to send notifications of some events, if anywhere
+	notifications, err := config.NotificationPolicy()
+	if err != nil {
+		return err
+	}
+	notifier, err := notify.New(notifications, postgrescluster.NotificationEvents)
+	if err != nil {
+		return err
+	}
+
+	// where to record the changes made to each cluster, if anywhere
+	auditPolicy, err := config.AuditPolicy()
+	if err != nil {
+		return err
+	}
+	reconcileClient := mgr.GetClient()
+	if auditPolicy.Enabled() {
+		reconcileClient = audit.NewClient(reconcileClient, mgr.GetAPIReader())
+	}
+
 	r := &postgrescluster.Reconciler{
-		Client:      mgr.GetClient(),
-		Owner:       postgrescluster.ControllerName,
-		Recorder:    mgr.GetEventRecorderFor(postgrescluster.ControllerName),
+		Client: reconcileClient,
+		Owner:  postgrescluster.ControllerName,
+		Recorder: notify.NewRecorder(
+			mgr.GetEventRecorderFor(postgrescluster.ControllerName), notifier),
 		Tracer:      otel.Tracer(postgrescluster.ControllerName),
 		IsOpenShift: isOpenshift(ctx, mgr.GetConfig()),
+		APIs:        discoverAPIs(ctx, mgr.GetConfig()),
+
+		OperatorClass:   os.Getenv("PGO_OPERATOR_CLASS"),
+		DefaultMetadata: defaultMetadata,
+		ExecPolicy:      execPolicy,
+		UpdateCheck:     updatecheck.NewSource(updateCheck, mgr.GetAPIReader()),
+		Audit:           auditPolicy,
+		AuditActor:      "postgres-operator/" + versionString,
 	}
-	return r.SetupWithManager(mgr)
+	err = r.SetupWithManager(mgr)
+
+	// summarize every cluster for dashboards alongside the operator metrics
+	if err == nil {
+		err = mgr.AddMetricsExtraHandler(inventory.Path,
+			inventory.Handler(mgr.GetClient(), r.OperatorClass))
+	}
+
+	// move the primary before its pod is evicted, e.g. during a node drain
+	if err == nil && webhooks {
+		r.AddEvictionWebhook(mgr)
+	}
+	return err
+}
+
+// discoverAPIs returns the versions of Kubernetes APIs to use.
+func discoverAPIs(ctx context.Context, cfg *rest.Config) kubeapi.APIs {
+	client, err := discovery.NewDiscoveryClientForConfig(cfg)
+	assertNoError(err)
+
+	apis, err := kubeapi.DiscoverAPIs(client)
+	assertNoError(err)
+
+	logging.FromContext(ctx).Info("detected Kubernetes APIs",
+		"cronjob", apis.CronJob.String(),
+		"podsecurityadmission", apis.PodSecurityAdmission)
+
+	return apis
 }
 
 func isOpenshift(ctx context.Context, cfg *rest.Config) bool {
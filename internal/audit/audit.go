@@ -0,0 +1,198 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package audit records the changes the operator makes to Kubernetes objects
+// so they can be reviewed later. Each Record includes the hash of the one
+// before it, keyed by a secret of the operator, so removing or editing a Record
+// breaks the chain.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// These are the values of Record.Action.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Record is one change to one object.
+type Record struct {
+	Time time.Time `json:"time"`
+
+	// Actor is what made the change, e.g. "postgres-operator/5.0.0".
+	Actor string `json:"actor"`
+
+	// Action is one of create, update, or delete.
+	Action string `json:"action"`
+
+	// Kind and Name identify the object that changed.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+
+	// DiffHash is the SHA-256 of the request that changed the object. It is
+	// empty for deletions.
+	DiffHash string `json:"diffHash,omitempty"`
+
+	// Previous is the Hash of the record before this one, if any.
+	Previous string `json:"previous,omitempty"`
+
+	// Hash is the HMAC-SHA-256 of this record without its Hash. Without a
+	// key, it is the SHA-256.
+	Hash string `json:"hash"`
+}
+
+// digest returns the HMAC-SHA-256 of r without its Hash using key. Anyone can
+// compute a digest without a key, so only a keyed digest detects a record
+// that was edited by someone who also recomputed its Hash.
+func (r Record) digest(key []byte) string {
+	r.Hash = ""
+	b, _ := json.Marshal(r)
+	if len(key) == 0 {
+		sum := sha256.Sum256(b)
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Chain sets the Previous and Hash of each record in order so that the first
+// follows previous. It returns the Hash of the last record.
+func Chain(key []byte, previous string, records []Record) string {
+	for i := range records {
+		records[i].Previous = previous
+		records[i].Hash = records[i].digest(key)
+		previous = records[i].Hash
+	}
+	return previous
+}
+
+// Verify returns an error when any record does not match its Hash using key
+// or does not follow the record before it. The first record must follow
+// previous, which is empty when there is no record before it.
+func Verify(key []byte, previous string, records []Record) error {
+	for i := range records {
+		if !hmac.Equal([]byte(records[i].Hash), []byte(records[i].digest(key))) {
+			return errors.Errorf("record %d does not match its hash", i)
+		}
+		if i == 0 && records[i].Previous != previous {
+			return errors.Errorf("record %d does not follow the records before it", i)
+		}
+		if i > 0 && records[i].Previous != records[i-1].Hash {
+			return errors.Errorf("record %d does not follow record %d", i, i-1)
+		}
+	}
+	return nil
+}
+
+// Marshal encodes records as JSON Lines: one record per line.
+func Marshal(records []Record) (string, error) {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	for i := range records {
+		if err := encoder.Encode(records[i]); err != nil {
+			return "", errors.WithStack(err)
+		}
+	}
+	return b.String(), nil
+}
+
+// Unmarshal decodes the JSON Lines in data.
+func Unmarshal(data string) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			var record Record
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			records = append(records, record)
+		}
+	}
+	return records, errors.WithStack(scanner.Err())
+}
+
+// Trail collects the records of one reconcile. It is safe for concurrent use.
+type Trail struct {
+	actor string
+
+	mu      sync.Mutex
+	records []Record
+}
+
+// NewTrail returns an empty Trail of changes made by actor.
+func NewTrail(actor string) *Trail { return &Trail{actor: actor} }
+
+// Add records that action changed the object of kind and name with request.
+// It does nothing when t is nil.
+func (t *Trail) Add(action, kind, name string, request []byte) {
+	if t == nil {
+		return
+	}
+	record := Record{
+		Time:   time.Now().UTC(),
+		Actor:  t.actor,
+		Action: action,
+		Kind:   kind,
+		Name:   name,
+	}
+	if request != nil {
+		sum := sha256.Sum256(request)
+		record.DiffHash = hex.EncodeToString(sum[:])
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.records = append(t.records, record)
+}
+
+// Records returns a copy of the records in t, oldest first.
+func (t *Trail) Records() []Record {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Record(nil), t.records...)
+}
+
+type trailContextKey struct{}
+
+// NewContext returns a copy of ctx in which changes are recorded in trail.
+// When trail is nil, changes are not recorded.
+func NewContext(ctx context.Context, trail *Trail) context.Context {
+	return context.WithValue(ctx, trailContextKey{}, trail)
+}
+
+// FromContext returns the Trail of ctx, or nil when there is none.
+func FromContext(ctx context.Context) *Trail {
+	trail, _ := ctx.Value(trailContextKey{}).(*Trail)
+	return trail
+}
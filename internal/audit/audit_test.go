@@ -0,0 +1,168 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestChain(t *testing.T) {
+	trail := NewTrail("postgres-operator/test")
+	trail.Add(ActionCreate, "ConfigMap", "one", []byte(`{"a":1}`))
+	trail.Add(ActionUpdate, "ConfigMap", "one", []byte(`{"a":2}`))
+	trail.Add(ActionDelete, "ConfigMap", "one", nil)
+
+	records := trail.Records()
+	assert.Equal(t, len(records), 3)
+	assert.Equal(t, records[0].Actor, "postgres-operator/test")
+	assert.Assert(t, records[0].DiffHash != records[1].DiffHash)
+	assert.Equal(t, records[2].DiffHash, "")
+
+	key := []byte("secret")
+	head := Chain(key, "abc", records)
+	assert.Equal(t, records[0].Previous, "abc")
+	assert.Equal(t, records[2].Hash, head)
+	assert.NilError(t, Verify(key, "abc", records))
+	assert.ErrorContains(t, Verify(key, "", records), "record 0 does not follow")
+
+	t.Run("Marshal", func(t *testing.T) {
+		data, err := Marshal(records)
+		assert.NilError(t, err)
+
+		decoded, err := Unmarshal(data)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, decoded, records)
+		assert.NilError(t, Verify(key, "abc", decoded))
+
+		_, err = Unmarshal("{")
+		assert.Assert(t, err != nil)
+	})
+
+	t.Run("Edited", func(t *testing.T) {
+		edited := append([]Record(nil), records...)
+		edited[1].Name = "two"
+		assert.ErrorContains(t, Verify(key, "abc", edited), "record 1 does not match")
+	})
+
+	t.Run("Removed", func(t *testing.T) {
+		removed := []Record{records[0], records[2]}
+		assert.ErrorContains(t, Verify(key, "abc", removed), "record 1 does not follow")
+
+		// The oldest records can be moved elsewhere, but those that remain
+		// still follow them.
+		assert.NilError(t, Verify(key, records[0].Hash, records[1:]))
+		assert.ErrorContains(t, Verify(key, "abc", records[1:]), "record 0 does not follow")
+	})
+
+	t.Run("Recomputed", func(t *testing.T) {
+		// Records edited and hashed again without the key do not match.
+		edited := append([]Record(nil), records...)
+		edited[1].Name = "two"
+		Chain(nil, edited[0].Hash, edited[1:])
+		assert.NilError(t, Verify(nil, edited[0].Hash, edited[1:]))
+		assert.ErrorContains(t, Verify(key, "abc", edited), "record 1 does not match")
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		var trail *Trail
+		trail.Add(ActionCreate, "ConfigMap", "one", nil)
+		assert.Assert(t, trail.Records() == nil)
+	})
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	cc := NewClient(c, c)
+
+	object := &corev1.ConfigMap{}
+	object.Namespace, object.Name = "ns1", "one"
+
+	// Changes are not recorded without a Trail.
+	assert.NilError(t, cc.Create(ctx, object))
+	assert.NilError(t, cc.Delete(ctx, object))
+
+	trail := NewTrail("postgres-operator/test")
+	ctx = NewContext(ctx, trail)
+
+	object = &corev1.ConfigMap{}
+	object.Namespace, object.Name = "ns1", "one"
+	assert.NilError(t, cc.Create(ctx, object))
+
+	object.Data = map[string]string{"a": "1"}
+	assert.NilError(t, cc.Update(ctx, object))
+
+	before := object.DeepCopy()
+	object.Data = map[string]string{"a": "2"}
+	assert.NilError(t, cc.Patch(ctx, object, client.MergeFrom(before)))
+
+	assert.NilError(t, cc.Delete(ctx, object))
+
+	// Failed requests are not recorded.
+	assert.Assert(t, cc.Delete(ctx, object) != nil)
+
+	records := trail.Records()
+	assert.Equal(t, len(records), 4)
+	for i, action := range []string{ActionCreate, ActionUpdate, ActionUpdate, ActionDelete} {
+		assert.Equal(t, records[i].Action, action)
+		assert.Equal(t, records[i].Kind, "ConfigMap")
+		assert.Equal(t, records[i].Name, "one")
+	}
+	assert.Assert(t, records[2].DiffHash != "")
+}
+
+// forbiddenReader is a client.Reader that is not allowed to read anything.
+type forbiddenReader struct{}
+
+func (forbiddenReader) Get(_ context.Context, key client.ObjectKey, _ client.Object) error {
+	return apierrors.NewForbidden(schema.GroupResource{}, key.Name, nil)
+}
+
+func (forbiddenReader) List(context.Context, client.ObjectList, ...client.ListOption) error {
+	return apierrors.NewForbidden(schema.GroupResource{}, "", nil)
+}
+
+func TestClientUnreadable(t *testing.T) {
+	trail := NewTrail("postgres-operator/test")
+	ctx := NewContext(context.Background(), trail)
+	cc := NewClient(fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(), forbiddenReader{})
+
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion("v1")
+	object.SetKind("ConfigMap")
+	object.SetNamespace("ns1")
+	object.SetName("one")
+	assert.NilError(t, cc.Create(ctx, object.DeepCopy()))
+
+	// Objects that cannot be read are patched but not recorded.
+	patch := object.DeepCopy()
+	assert.NilError(t, unstructured.SetNestedField(patch.Object, "1", "data", "a"))
+	assert.NilError(t, cc.Patch(ctx, patch, client.Merge))
+
+	records := trail.Records()
+	assert.Equal(t, len(records), 1)
+	assert.Equal(t, records[0].Action, ActionCreate)
+}
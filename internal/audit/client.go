@@ -0,0 +1,143 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// auditClient is a client.Client that adds the objects it changes to the
+// Trail of each request's context.
+type auditClient struct {
+	client.Client
+
+	// reader reads objects that may not be in the cache of Client.
+	reader client.Reader
+}
+
+// NewClient returns a client.Client that records in the Trail of each
+// request's context every object that c creates, changes, or deletes. An
+// object is changed when its resourceVersion changes. Requests without a
+// Trail are not recorded. Status is not recorded. Objects applied without a
+// resourceVersion are first read with c or, when they are unstructured, with
+// reader. Those that cannot be read are not recorded.
+func NewClient(c client.Client, reader client.Reader) client.Client {
+	return auditClient{Client: c, reader: reader}
+}
+
+func (c auditClient) add(
+	trail *Trail, action string, object client.Object, request []byte,
+) {
+	kind := object.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		if gvk, err := apiutil.GVKForObject(object, c.Scheme()); err == nil {
+			kind = gvk.Kind
+		}
+	}
+	trail.Add(action, kind, object.GetName(), request)
+}
+
+// stored returns the resourceVersion of object in the API, or an empty
+// string when it does not exist. Unstructured objects are read without the
+// cache so that kinds the operator does not watch, such as cert-manager
+// Certificates, do not start an informer.
+func (c auditClient) stored(ctx context.Context, object client.Object) (string, error) {
+	var current client.Object
+	reader := client.Reader(c.Client)
+	if u, ok := object.(*unstructured.Unstructured); ok {
+		current = &unstructured.Unstructured{}
+		current.GetObjectKind().SetGroupVersionKind(u.GroupVersionKind())
+		reader = c.reader
+	} else {
+		current = reflect.New(reflect.TypeOf(object).Elem()).Interface().(client.Object)
+	}
+	err := reader.Get(ctx, client.ObjectKeyFromObject(object), current)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	return current.GetResourceVersion(), err
+}
+
+func (c auditClient) Create(
+	ctx context.Context, object client.Object, options ...client.CreateOption,
+) error {
+	err := c.Client.Create(ctx, object, options...)
+	if trail := FromContext(ctx); trail != nil && err == nil {
+		request, _ := json.Marshal(object)
+		c.add(trail, ActionCreate, object, request)
+	}
+	return err
+}
+
+func (c auditClient) Update(
+	ctx context.Context, object client.Object, options ...client.UpdateOption,
+) error {
+	before := object.GetResourceVersion()
+	err := c.Client.Update(ctx, object, options...)
+	if trail := FromContext(ctx); trail != nil && err == nil &&
+		object.GetResourceVersion() != before {
+		request, _ := json.Marshal(object)
+		c.add(trail, ActionUpdate, object, request)
+	}
+	return err
+}
+
+func (c auditClient) Patch(
+	ctx context.Context, object client.Object, patch client.Patch, options ...client.PatchOption,
+) error {
+	trail := FromContext(ctx)
+	if trail == nil {
+		return c.Client.Patch(ctx, object, patch, options...)
+	}
+
+	// Apply-patches are built from objects that have never been read, so
+	// read what is stored to tell whether anything changed. When that fails,
+	// there is no telling a creation from an update or no change at all.
+	action, before := ActionUpdate, object.GetResourceVersion()
+	if before == "" {
+		var err error
+		if before, err = c.stored(ctx, object); err != nil {
+			return c.Client.Patch(ctx, object, patch, options...)
+		}
+		if before == "" {
+			action = ActionCreate
+		}
+	}
+
+	request, _ := patch.Data(object)
+	err := c.Client.Patch(ctx, object, patch, options...)
+	if err == nil && object.GetResourceVersion() != before {
+		c.add(trail, action, object, request)
+	}
+	return err
+}
+
+func (c auditClient) Delete(
+	ctx context.Context, object client.Object, options ...client.DeleteOption,
+) error {
+	err := c.Client.Delete(ctx, object, options...)
+	if trail := FromContext(ctx); trail != nil && err == nil {
+		c.add(trail, ActionDelete, object, nil)
+	}
+	return err
+}
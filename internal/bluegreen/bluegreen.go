@@ -0,0 +1,152 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package bluegreen replaces one PostgresCluster, "blue", with another,
+// "green". Green follows blue through logical replication until blue stops
+// accepting writes, then takes over its Services.
+package bluegreen
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// These are the values of PostgresBlueGreenStatus.Phase, in order.
+const (
+	// PhasePreparing is while blue gets a role, publications, and replication
+	// slots for green.
+	PhasePreparing = "Preparing"
+
+	// PhaseSubscribing is while green subscribes to the publications of blue.
+	PhaseSubscribing = "Subscribing"
+
+	// PhaseReplicating is while green follows blue and waits for cutover.
+	PhaseReplicating = "Replicating"
+
+	// PhaseCuttingOver is after blue stopped accepting writes and before green
+	// takes over.
+	PhaseCuttingOver = "CuttingOver"
+
+	// PhaseCutOver is after green took over the Services of blue.
+	PhaseCutOver = "CutOver"
+)
+
+// Enabled returns true when cluster replaces another through spec.blueGreen.
+func Enabled(cluster *v1beta1.PostgresCluster) bool {
+	return cluster.Spec.BlueGreen != nil
+}
+
+// Restores returns true when cluster starts from a backup of the cluster it
+// replaces. Otherwise, it starts from a copy of the schema.
+func Restores(cluster *v1beta1.PostgresCluster) bool {
+	if !Enabled(cluster) || cluster.Spec.DataSource == nil ||
+		cluster.Spec.DataSource.PostgresCluster == nil {
+		return false
+	}
+	source := cluster.Spec.DataSource.PostgresCluster
+	return source.ClusterName == cluster.Spec.BlueGreen.Source &&
+		(source.ClusterNamespace == "" || source.ClusterNamespace == cluster.Namespace)
+}
+
+// CutoverRequested returns true when spec.blueGreen.cutover is true.
+func CutoverRequested(cluster *v1beta1.PostgresCluster) bool {
+	return Enabled(cluster) &&
+		cluster.Spec.BlueGreen.Cutover != nil && *cluster.Spec.BlueGreen.Cutover
+}
+
+// Source returns a PostgresCluster with the namespace and name of the cluster
+// that cluster replaces.
+func Source(cluster *v1beta1.PostgresCluster) *v1beta1.PostgresCluster {
+	source := &v1beta1.PostgresCluster{}
+	source.Namespace = cluster.Namespace
+	if Enabled(cluster) {
+		source.Name = cluster.Spec.BlueGreen.Source
+	}
+	return source
+}
+
+// Databases returns the names of the databases that cluster replicates.
+func Databases(cluster *v1beta1.PostgresCluster) []string {
+	var databases []string
+	if Enabled(cluster) {
+		for _, database := range cluster.Spec.BlueGreen.Databases {
+			databases = append(databases, string(database))
+		}
+	}
+	return databases
+}
+
+// Name returns the name of the role and publications that cluster uses in
+// the cluster it replaces. It is the same for every database.
+func Name(cluster *v1beta1.PostgresCluster) string {
+	hash := fnv.New32()
+	_, _ = hash.Write([]byte(cluster.Namespace + "/" + cluster.Name))
+	return fmt.Sprintf("_crunchybluegreen_%08x", hash.Sum32())
+}
+
+// Slot returns the name of both the replication slot of database in blue and
+// the subscription in green. Replication slots are shared by every database,
+// so the name includes a hash of database.
+func Slot(name, database string) string {
+	hash := fnv.New32()
+	_, _ = hash.Write([]byte(database))
+	return fmt.Sprintf("%s_%08x", name, hash.Sum32())
+}
+
+// RestoreOptions returns the pgBackRest restore options that recover green to
+// lsn and then promote it.
+// - https://pgbackrest.org/command.html#command-restore
+func RestoreOptions(lsn string) []string {
+	return []string{"--type=lsn", "--target=" + lsn, "--target-action=promote"}
+}
+
+// Conninfo returns the libpq connection string that green uses to reach
+// database through the primary Service of source.
+// - https://www.postgresql.org/docs/current/libpq-connect.html#LIBPQ-CONNSTRING
+func Conninfo(source *v1beta1.PostgresCluster, user, password, database string) string {
+	quote := func(value string) string {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+		return `'` + strings.ReplaceAll(value, `'`, `\'`) + `'`
+	}
+
+	primary := naming.ClusterPrimaryService(source)
+	port := int32(5432)
+	if source.Spec.Port != nil {
+		port = *source.Spec.Port
+	}
+
+	return strings.Join([]string{
+		"host=" + quote(primary.Name+"."+primary.Namespace+".svc"),
+		"port=" + quote(fmt.Sprint(port)),
+		"dbname=" + quote(database),
+		"user=" + quote(user),
+		"password=" + quote(password),
+		"sslmode=" + quote("require"),
+	}, " ")
+}
+
+// DumpCommand returns the command that writes the schema of database to
+// stdout. The schema does not include publications nor subscriptions.
+// - https://www.postgresql.org/docs/current/app-pgdump.html
+func DumpCommand(database string) []string {
+	// Pass the database name as an argument and assign it to PGDATABASE so
+	// that it is not interpreted as a connection string.
+	const script = `PGDATABASE="$1" exec pg_dump --schema-only --no-publications --no-subscriptions`
+	return []string{"bash", "-ceu", "--", script, "-", database}
+}
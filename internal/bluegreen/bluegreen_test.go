@@ -0,0 +1,123 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package bluegreen
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestEnabled(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name = "ns1", "green"
+
+	assert.Assert(t, !Enabled(cluster))
+	assert.Assert(t, !Restores(cluster))
+	assert.Assert(t, !CutoverRequested(cluster))
+	assert.Assert(t, Databases(cluster) == nil)
+	assert.Equal(t, Source(cluster).Name, "")
+
+	cluster.Spec.BlueGreen = &v1beta1.PostgresBlueGreenSpec{
+		Source:    "blue",
+		Databases: []v1beta1.PostgresIdentifier{"app", "other"},
+	}
+	assert.Assert(t, Enabled(cluster))
+	assert.Assert(t, !Restores(cluster))
+	assert.Assert(t, !CutoverRequested(cluster))
+	assert.DeepEqual(t, Databases(cluster), []string{"app", "other"})
+	assert.Equal(t, Source(cluster).Namespace, "ns1")
+	assert.Equal(t, Source(cluster).Name, "blue")
+
+	cluster.Spec.BlueGreen.Cutover = initialize.Bool(false)
+	assert.Assert(t, !CutoverRequested(cluster))
+	cluster.Spec.BlueGreen.Cutover = initialize.Bool(true)
+	assert.Assert(t, CutoverRequested(cluster))
+}
+
+func TestRestores(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace = "ns1"
+	cluster.Spec.BlueGreen = &v1beta1.PostgresBlueGreenSpec{Source: "blue"}
+	cluster.Spec.DataSource = &v1beta1.DataSource{
+		PostgresCluster: &v1beta1.PostgresClusterDataSource{ClusterName: "blue"},
+	}
+	assert.Assert(t, Restores(cluster))
+
+	cluster.Spec.DataSource.PostgresCluster.ClusterNamespace = "ns1"
+	assert.Assert(t, Restores(cluster))
+
+	cluster.Spec.DataSource.PostgresCluster.ClusterNamespace = "ns2"
+	assert.Assert(t, !Restores(cluster), "expected only the same namespace")
+
+	cluster.Spec.DataSource.PostgresCluster.ClusterNamespace = ""
+	cluster.Spec.DataSource.PostgresCluster.ClusterName = "other"
+	assert.Assert(t, !Restores(cluster), "expected only blue")
+}
+
+func TestName(t *testing.T) {
+	green := new(v1beta1.PostgresCluster)
+	green.Namespace, green.Name = "ns1", "green"
+
+	name := Name(green)
+	assert.Assert(t, strings.HasPrefix(name, "_crunchybluegreen_"))
+	assert.Assert(t, len(name) < 64, "expected a PostgreSQL identifier")
+	assert.Equal(t, Name(green), name, "expected the same name")
+
+	other := green.DeepCopy()
+	other.Namespace = "ns2"
+	assert.Assert(t, Name(other) != name, "expected a different name")
+
+	slot := Slot(name, "app")
+	assert.Assert(t, strings.HasPrefix(slot, name+"_"))
+	assert.Assert(t, len(slot) < 64, "expected a PostgreSQL identifier")
+	assert.Assert(t, Slot(name, "other") != slot, "expected a different slot")
+}
+
+func TestRestoreOptions(t *testing.T) {
+	assert.DeepEqual(t, RestoreOptions("0/3000028"),
+		[]string{"--type=lsn", "--target=0/3000028", "--target-action=promote"})
+}
+
+func TestConninfo(t *testing.T) {
+	blue := new(v1beta1.PostgresCluster)
+	blue.Namespace, blue.Name = "ns1", "blue"
+
+	assert.Equal(t, Conninfo(blue, "_crunchybluegreen_x", `it's\secret`, "app"), strings.Join([]string{
+		`host='blue-primary.ns1.svc'`,
+		`port='5432'`,
+		`dbname='app'`,
+		`user='_crunchybluegreen_x'`,
+		`password='it\'s\\secret'`,
+		`sslmode='require'`,
+	}, " "))
+
+	blue.Spec.Port = initialize.Int32(6543)
+	assert.Assert(t, strings.Contains(
+		Conninfo(blue, "u", "p", "d"), `port='6543'`))
+}
+
+func TestDumpCommand(t *testing.T) {
+	command := DumpCommand("some; database")
+	assert.Equal(t, command[len(command)-1], "some; database",
+		"expected database as an argument")
+	assert.Assert(t, strings.Contains(strings.Join(command, " "), "--schema-only"))
+	assert.Assert(t, strings.Contains(strings.Join(command, " "), "--no-subscriptions"))
+}
@@ -0,0 +1,543 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package bluegreen
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+)
+
+// PrepareSource creates the role name in blue, or updates its password to
+// verifier, and creates in each of databases a publication of every table
+// and a logical replication slot. The role can read every table so that green
+// can copy them.
+// - https://www.postgresql.org/docs/current/logical-replication-publication.html
+func PrepareSource(
+	ctx context.Context, exec postgres.Executor,
+	name, verifier string, databases []string,
+) error {
+	log := logging.FromContext(ctx)
+
+	var err error
+	for _, database := range databases {
+		var stdout, stderr string
+		stdout, stderr, err = exec.ExecInDatabase(ctx, database,
+			strings.NewReader(strings.Join([]string{
+				// Quiet NOTICE messages from IF EXISTS statements.
+				// - https://www.postgresql.org/docs/current/runtime-config-client.html
+				`SET client_min_messages = WARNING;`,
+
+				// Prevent unexpected dereferences by emptying "search_path".
+				`SET search_path TO '';`,
+
+				`BEGIN;`,
+
+				// Create the role when it does not exist. It can login and
+				// start replication; it gets no other attributes.
+				// - https://www.postgresql.org/docs/current/sql-createrole.html
+				`SELECT pg_catalog.format('CREATE ROLE %I', :'username')`,
+				` WHERE NOT EXISTS (SELECT 1 FROM pg_catalog.pg_roles WHERE rolname = :'username')`,
+				`\gexec`,
+				`ALTER ROLE :"username" WITH LOGIN REPLICATION NOSUPERUSER NOCREATEDB` +
+					` NOCREATEROLE NOBYPASSRLS PASSWORD :'verifier';`,
+
+				// Publish every table, including those created later.
+				// - https://www.postgresql.org/docs/current/sql-createpublication.html
+				`SELECT pg_catalog.format('CREATE PUBLICATION %I FOR ALL TABLES', :'username')`,
+				` WHERE NOT EXISTS (SELECT 1 FROM pg_catalog.pg_publication WHERE pubname = :'username')`,
+				`\gexec`,
+
+				// Allow the role to read the tables that exist now. Green copies
+				// them through this role when it does not start from a backup.
+				`SELECT pg_catalog.format('GRANT USAGE ON SCHEMA %I TO %I', nspname, :'username'),`,
+				`       pg_catalog.format('GRANT SELECT ON ALL TABLES IN SCHEMA %I TO %I', nspname, :'username')`,
+				`  FROM pg_catalog.pg_namespace`,
+				` WHERE nspname NOT IN ('information_schema')`,
+				`   AND nspname NOT LIKE 'pg\_%'`,
+				` ORDER BY nspname`,
+				`\gexec`,
+
+				`COMMIT;`,
+
+				// Create the slot outside of any transaction that has written.
+				// Its position is where green starts to follow blue.
+				// - https://www.postgresql.org/docs/current/functions-admin.html#FUNCTIONS-REPLICATION
+				`SELECT pg_catalog.pg_create_logical_replication_slot(:'slot', 'pgoutput')`,
+				` WHERE NOT EXISTS (SELECT 1 FROM pg_catalog.pg_replication_slots WHERE slot_name = :'slot');`,
+			}, "\n")),
+			map[string]string{
+				"slot":     Slot(name, database),
+				"username": name,
+				"verifier": verifier,
+
+				"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+				"QUIET":         "on", // Do not print successful statements to stdout.
+			})
+
+		log.V(1).Info("prepared blue/green source", "database", database,
+			"stdout", stdout, "stderr", stderr)
+
+		if err != nil {
+			break
+		}
+	}
+	return err
+}
+
+// SourcePosition returns the newest position of the replication slots of
+// name in blue and whether or not the WAL file containing it is archived.
+// Green can recover to that position from the pgBackRest repository once it
+// is. The position is empty when there are no slots.
+func SourcePosition(ctx context.Context, exec postgres.Executor, name string) (string, bool, error) {
+	stdout, _, err := exec.Exec(ctx, strings.NewReader(`
+\pset format unaligned
+\pset tuples_only on
+WITH slot AS (
+  SELECT pg_catalog.max(confirmed_flush_lsn) AS lsn
+    FROM pg_catalog.pg_replication_slots
+   WHERE pg_catalog.left(slot_name, pg_catalog.length(:'name') + 1) = :'name' || '_')
+SELECT slot.lsn, COALESCE(
+       archiver.last_archived_wal >= pg_catalog.pg_walfile_name(slot.lsn), false)
+  FROM slot, pg_catalog.pg_stat_archiver AS archiver
+ WHERE slot.lsn IS NOT NULL;
+`),
+		map[string]string{
+			"name": name,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	var lsn string
+	var archived bool
+	if fields := strings.Split(strings.TrimSpace(stdout), "|"); err == nil && len(fields) == 2 {
+		lsn, archived = fields[0], fields[1] == "t"
+	}
+	return lsn, archived, err
+}
+
+// SwitchSourceWAL starts a new WAL file in blue when the current one contains
+// lsn so that it can be archived.
+// - https://www.postgresql.org/docs/current/functions-admin.html#FUNCTIONS-ADMIN-BACKUP
+func SwitchSourceWAL(ctx context.Context, exec postgres.Executor, lsn string) error {
+	_, _, err := exec.Exec(ctx, strings.NewReader(`
+SELECT pg_catalog.pg_switch_wal()
+ WHERE pg_catalog.pg_walfile_name(pg_catalog.pg_current_wal_lsn())
+     = pg_catalog.pg_walfile_name(:'lsn');
+`),
+		map[string]string{
+			"lsn": lsn,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	return err
+}
+
+// CreateDatabases creates those of databases that do not exist in green.
+func CreateDatabases(ctx context.Context, exec postgres.Executor, databases []string) error {
+	log := logging.FromContext(ctx)
+
+	names, err := json.Marshal(databases)
+	if err != nil {
+		return err
+	}
+
+	stdout, stderr, err := exec.Exec(ctx, strings.NewReader(strings.Join([]string{
+		// CREATE DATABASE cannot run in a transaction, so each runs on its own.
+		// - https://www.postgresql.org/docs/current/sql-createdatabase.html
+		`SELECT pg_catalog.format('CREATE DATABASE %I', name)`,
+		`  FROM pg_catalog.json_array_elements_text(:'databases'::json) AS name`,
+		` WHERE NOT EXISTS (SELECT 1 FROM pg_catalog.pg_database WHERE datname = name)`,
+		`\gexec`,
+	}, "\n")),
+		map[string]string{
+			"databases": string(names),
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("created blue/green databases", "stdout", stdout, "stderr", stderr)
+
+	return err
+}
+
+// Subscribed returns whether or not database in green has the subscription
+// named slot.
+func Subscribed(ctx context.Context, exec postgres.Executor, database, slot string) (bool, error) {
+	stdout, _, err := exec.ExecInDatabase(ctx, database, strings.NewReader(`
+\pset format unaligned
+\pset tuples_only on
+SELECT EXISTS (
+  SELECT 1 FROM pg_catalog.pg_subscription
+   WHERE subname = :'slot'
+     AND subdbid = (SELECT oid FROM pg_catalog.pg_database
+                     WHERE datname = pg_catalog.current_database()));
+`),
+		map[string]string{
+			"slot": slot,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	return strings.TrimSpace(stdout) == "t", err
+}
+
+// Subscribe creates in database of green a subscription named slot to the
+// publication name through conninfo. The subscription uses the existing
+// replication slot in blue. When lsn is empty, green first copies every
+// table. Otherwise, green already has every change before lsn, and the
+// subscription starts there.
+// - https://www.postgresql.org/docs/current/sql-createsubscription.html
+// - https://www.postgresql.org/docs/current/replication-origins.html
+func Subscribe(
+	ctx context.Context, exec postgres.Executor,
+	database, slot, name, conninfo, lsn string,
+) error {
+	log := logging.FromContext(ctx)
+
+	// Without a connection, the subscription starts disabled and copies
+	// nothing. Its replication origin moves to lsn before it is enabled.
+	options := `connect = false`
+	if lsn == "" {
+		options = `create_slot = false, copy_data = true`
+	}
+
+	script := []string{
+		`SET client_min_messages = WARNING;`,
+
+		`SELECT pg_catalog.format('CREATE SUBSCRIPTION %I CONNECTION %L PUBLICATION %I`,
+		`       WITH (slot_name = %L, %s)', :'slot', :'conninfo', :'publication', :'slot', :'options')`,
+		` WHERE NOT EXISTS (`,
+		`       SELECT 1 FROM pg_catalog.pg_subscription`,
+		`        WHERE subname = :'slot'`,
+		`          AND subdbid = (SELECT oid FROM pg_catalog.pg_database`,
+		`                          WHERE datname = pg_catalog.current_database()))`,
+		`\gexec`,
+	}
+	if lsn != "" {
+		script = append(script,
+			`SELECT pg_catalog.format('SELECT pg_catalog.pg_replication_origin_advance(%L, %L)',`,
+			`       'pg_' || oid, :'lsn')`,
+			`  FROM pg_catalog.pg_subscription`,
+			` WHERE subname = :'slot' AND NOT subenabled`,
+			`   AND subdbid = (SELECT oid FROM pg_catalog.pg_database`,
+			`                   WHERE datname = pg_catalog.current_database())`,
+			`\gexec`,
+		)
+	}
+	script = append(script,
+		`SELECT pg_catalog.format('ALTER SUBSCRIPTION %I ENABLE', subname)`,
+		`  FROM pg_catalog.pg_subscription`,
+		` WHERE subname = :'slot' AND NOT subenabled`,
+		`   AND subdbid = (SELECT oid FROM pg_catalog.pg_database`,
+		`                   WHERE datname = pg_catalog.current_database())`,
+		`\gexec`,
+	)
+
+	stdout, stderr, err := exec.ExecInDatabase(ctx, database,
+		strings.NewReader(strings.Join(script, "\n")),
+		map[string]string{
+			"conninfo":    conninfo,
+			"lsn":         lsn,
+			"options":     options,
+			"publication": name,
+			"slot":        slot,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("subscribed to blue/green source", "database", database,
+		"stdout", stdout, "stderr", stderr)
+
+	return err
+}
+
+// Fence stops blue from accepting new sessions of roles that could write:
+// every role that can login except superusers and those that replicate. It
+// returns the roles it changed so that Unfence can change them back.
+// - https://www.postgresql.org/docs/current/sql-alterrole.html
+func Fence(ctx context.Context, exec postgres.Executor) ([]string, error) {
+	stdout, _, err := exec.Exec(ctx, strings.NewReader(strings.Join([]string{
+		`\pset format unaligned`,
+		`\pset tuples_only on`,
+
+		// Change every role or none of them, and print their names only when
+		// they are changed.
+		`BEGIN;`,
+		`CREATE TEMPORARY TABLE pgo_fenced_roles ON COMMIT DROP AS`,
+		` SELECT rolname FROM pg_catalog.pg_roles`,
+		`  WHERE rolcanlogin AND NOT rolsuper AND NOT rolreplication`,
+		`    AND rolname NOT LIKE 'pg\_%';`,
+		`SELECT pg_catalog.format('ALTER ROLE %I NOLOGIN', rolname)`,
+		`  FROM pgo_fenced_roles ORDER BY rolname`,
+		`\gexec`,
+		`SELECT rolname FROM pgo_fenced_roles ORDER BY rolname;`,
+		`COMMIT;`,
+	}, "\n")),
+		map[string]string{
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	var roles []string
+	for _, line := range strings.Split(stdout, "\n") {
+		if err == nil && line != "" {
+			roles = append(roles, line)
+		}
+	}
+	return roles, err
+}
+
+// Unfence allows roles to login to blue again after Fence.
+func Unfence(ctx context.Context, exec postgres.Executor, roles []string) error {
+	names, err := json.Marshal(roles)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = exec.Exec(ctx, strings.NewReader(`
+SELECT pg_catalog.format('ALTER ROLE %I LOGIN', rolname)
+  FROM pg_catalog.pg_roles
+ WHERE rolname IN (SELECT pg_catalog.json_array_elements_text(:'roles'::json))
+ ORDER BY rolname
+\gexec
+`),
+		map[string]string{
+			"roles": string(names),
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	return err
+}
+
+// Freeze terminates the client sessions of databases in blue. Once Fence has
+// stopped new ones, it returns the WAL position of blue when no session that
+// is not a superuser remains. The position is empty until then.
+// - https://www.postgresql.org/docs/current/functions-admin.html#FUNCTIONS-ADMIN-SIGNAL
+func Freeze(ctx context.Context, exec postgres.Executor, databases []string) (string, error) {
+	names, err := json.Marshal(databases)
+	if err != nil {
+		return "", err
+	}
+
+	// Sessions end some time after they are signaled; look for them again
+	// before reading the position.
+	stdout, _, err := exec.Exec(ctx, strings.NewReader(`
+\pset format unaligned
+\pset tuples_only on
+SELECT pg_catalog.count(pg_catalog.pg_terminate_backend(pid)) AS terminated
+  FROM pg_catalog.pg_stat_activity
+ WHERE datname IN (SELECT pg_catalog.json_array_elements_text(:'databases'::json))
+   AND backend_type = 'client backend'
+   AND pid <> pg_catalog.pg_backend_pid()
+\gset
+SELECT pg_catalog.pg_current_wal_lsn()
+ WHERE NOT EXISTS (
+       SELECT 1 FROM pg_catalog.pg_stat_activity AS activity
+         JOIN pg_catalog.pg_roles AS roles ON roles.oid = activity.usesysid
+        WHERE activity.datname IN (SELECT pg_catalog.json_array_elements_text(:'databases'::json))
+          AND activity.backend_type = 'client backend'
+          AND activity.pid <> pg_catalog.pg_backend_pid()
+          AND NOT roles.rolsuper);
+`),
+		map[string]string{
+			"databases": string(names),
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	return strings.TrimSpace(stdout), err
+}
+
+// CaughtUp returns whether or not green has confirmed every change of blue
+// through lsn on every replication slot of name.
+func CaughtUp(ctx context.Context, exec postgres.Executor, name, lsn string) (bool, error) {
+	stdout, _, err := exec.Exec(ctx, strings.NewReader(`
+\pset format unaligned
+\pset tuples_only on
+SELECT COALESCE(pg_catalog.bool_and(confirmed_flush_lsn >= :'lsn'::pg_lsn), false)
+  FROM pg_catalog.pg_replication_slots
+ WHERE pg_catalog.left(slot_name, pg_catalog.length(:'name') + 1) = :'name' || '_';
+`),
+		map[string]string{
+			"lsn":  lsn,
+			"name": name,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	return strings.TrimSpace(stdout) == "t", err
+}
+
+// SequenceScript returns SQL that sets every sequence of database to its
+// value in blue. Logical replication does not replicate sequences.
+// - https://www.postgresql.org/docs/current/logical-replication-restrictions.html
+func SequenceScript(ctx context.Context, exec postgres.Executor, database string) (string, error) {
+	stdout, _, err := exec.ExecInDatabase(ctx, database, strings.NewReader(`
+\pset format unaligned
+\pset tuples_only on
+SELECT pg_catalog.format('SELECT pg_catalog.setval(%L, %s);',
+       pg_catalog.format('%I.%I', schemaname, sequencename), last_value)
+  FROM pg_catalog.pg_sequences
+ WHERE last_value IS NOT NULL
+ ORDER BY schemaname, sequencename;
+`),
+		map[string]string{
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	return stdout, err
+}
+
+// Unsubscribe drops the subscription named slot from database in green. This
+// also drops the replication slot in blue.
+// - https://www.postgresql.org/docs/current/sql-dropsubscription.html
+func Unsubscribe(ctx context.Context, exec postgres.Executor, database, slot string) error {
+	log := logging.FromContext(ctx)
+
+	stdout, stderr, err := exec.ExecInDatabase(ctx, database, strings.NewReader(strings.Join([]string{
+		`SET client_min_messages = WARNING;`,
+		`DROP SUBSCRIPTION IF EXISTS :"slot";`,
+	}, "\n")),
+		map[string]string{
+			"slot": slot,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("unsubscribed from blue/green source", "database", database,
+		"stdout", stdout, "stderr", stderr)
+
+	return err
+}
+
+// DropSubscriptions drops every subscription of name from green without
+// touching blue, which may be gone. Their replication slots remain in blue;
+// see DropSlots.
+// - https://www.postgresql.org/docs/current/sql-dropsubscription.html
+func DropSubscriptions(ctx context.Context, exec postgres.Executor, name string) error {
+	log := logging.FromContext(ctx)
+
+	stdout, stderr, err := exec.ExecInAllDatabases(ctx, strings.Join([]string{
+		`SET client_min_messages = WARNING;`,
+		`SELECT pg_catalog.format('ALTER SUBSCRIPTION %I DISABLE', subname),`,
+		`       pg_catalog.format('ALTER SUBSCRIPTION %I SET (slot_name = NONE)', subname),`,
+		`       pg_catalog.format('DROP SUBSCRIPTION %I', subname)`,
+		`  FROM pg_catalog.pg_subscription`,
+		` WHERE pg_catalog.left(subname, pg_catalog.length(:'name') + 1) = :'name' || '_'`,
+		`   AND subdbid = (SELECT oid FROM pg_catalog.pg_database`,
+		`                   WHERE datname = pg_catalog.current_database())`,
+		`\gexec`,
+	}, "\n"),
+		map[string]string{
+			"name": name,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("dropped blue/green subscriptions", "stdout", stdout, "stderr", stderr)
+
+	return err
+}
+
+// DropSlots drops every replication slot of name from blue. The processes
+// using any of them are terminated first; this returns an error when one has
+// yet to exit, so try again later.
+// - https://www.postgresql.org/docs/current/functions-admin.html#FUNCTIONS-REPLICATION
+func DropSlots(ctx context.Context, exec postgres.Executor, name string) error {
+	log := logging.FromContext(ctx)
+
+	stdout, stderr, err := exec.Exec(ctx, strings.NewReader(`
+SELECT pg_catalog.count(pg_catalog.pg_terminate_backend(active_pid)) AS terminated
+  FROM pg_catalog.pg_replication_slots
+ WHERE pg_catalog.left(slot_name, pg_catalog.length(:'name') + 1) = :'name' || '_'
+   AND active_pid IS NOT NULL
+\gset
+SELECT pg_catalog.count(pg_catalog.pg_drop_replication_slot(slot_name)) AS dropped
+  FROM pg_catalog.pg_replication_slots
+ WHERE pg_catalog.left(slot_name, pg_catalog.length(:'name') + 1) = :'name' || '_'
+\gset
+`),
+		map[string]string{
+			"name": name,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("dropped blue/green replication slots", "stdout", stdout, "stderr", stderr)
+
+	return err
+}
+
+// RemoveFromPostgreSQL drops the publications of name from every database,
+// then the role name and its privileges. It works in blue and in green, which
+// has a copy of them when it starts from a backup.
+func RemoveFromPostgreSQL(ctx context.Context, exec postgres.Executor, name string) error {
+	log := logging.FromContext(ctx)
+
+	stdout, stderr, err := exec.ExecInAllDatabases(ctx, strings.Join([]string{
+		`SET client_min_messages = WARNING;`,
+		`DROP PUBLICATION IF EXISTS :"username";`,
+
+		// Remove privileges granted in this database.
+		// - https://www.postgresql.org/docs/current/sql-drop-owned.html
+		`SELECT pg_catalog.format('DROP OWNED BY %I', :'username')`,
+		` WHERE EXISTS (SELECT 1 FROM pg_catalog.pg_roles WHERE rolname = :'username')`,
+		`\gexec`,
+	}, "\n"),
+		map[string]string{
+			"username": name,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("removed blue/green objects", "stdout", stdout, "stderr", stderr)
+
+	if err == nil {
+		stdout, stderr, err = exec.Exec(ctx, strings.NewReader(strings.Join([]string{
+			`SET client_min_messages = WARNING;`,
+			`DROP ROLE IF EXISTS :"username";`,
+		}, "\n")),
+			map[string]string{
+				"username": name,
+
+				"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+				"QUIET":         "on", // Do not print successful statements to stdout.
+			})
+
+		log.V(1).Info("removed blue/green role", "stdout", stdout, "stderr", stderr)
+	}
+
+	return err
+}
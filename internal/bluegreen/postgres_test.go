@@ -0,0 +1,253 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package bluegreen
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestPrepareSource(t *testing.T) {
+	expected := errors.New("whoops")
+	calls := 0
+	exec := func(
+		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		calls++
+		assert.Assert(t, stdout != nil, "should capture stdout")
+		assert.Assert(t, stderr != nil, "should capture stderr")
+
+		assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+			`--set=username=_crunchybluegreen_x`))
+		assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+			`--set=verifier=SCRAM-SHA-256$secret`))
+		assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+			`--set=slot=`+Slot("_crunchybluegreen_x", "app")))
+
+		b, err := ioutil.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(string(b), `LOGIN REPLICATION NOSUPERUSER`))
+		assert.Assert(t, strings.Contains(string(b), `FOR ALL TABLES`))
+		assert.Assert(t, strings.Contains(string(b), `pg_create_logical_replication_slot`))
+
+		return expected
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, expected, PrepareSource(ctx, exec,
+		"_crunchybluegreen_x", "SCRAM-SHA-256$secret", []string{"app", "other"}))
+	assert.Equal(t, calls, 1, "expected to stop at the first error")
+}
+
+func TestSourcePosition(t *testing.T) {
+	ctx := context.Background()
+	output := func(s string) func(context.Context, io.Reader, io.Writer, io.Writer, ...string) error {
+		return func(_ context.Context, stdin io.Reader, stdout, _ io.Writer, command ...string) error {
+			assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+				`--set=name=_crunchybluegreen_x`))
+			_, err := io.WriteString(stdout, s)
+			return err
+		}
+	}
+
+	lsn, archived, err := SourcePosition(ctx, output(""), "_crunchybluegreen_x")
+	assert.NilError(t, err)
+	assert.Equal(t, lsn, "")
+	assert.Assert(t, !archived)
+
+	lsn, archived, err = SourcePosition(ctx, output("0/3000028|f\n"), "_crunchybluegreen_x")
+	assert.NilError(t, err)
+	assert.Equal(t, lsn, "0/3000028")
+	assert.Assert(t, !archived)
+
+	lsn, archived, err = SourcePosition(ctx, output("0/3000028|t\n"), "_crunchybluegreen_x")
+	assert.NilError(t, err)
+	assert.Equal(t, lsn, "0/3000028")
+	assert.Assert(t, archived)
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx := context.Background()
+	script := func(t *testing.T, lsn string) string {
+		var sql string
+		exec := func(
+			_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			assert.Assert(t, strings.Contains(strings.Join(command, "\n"), `--set=slot=s1`))
+			assert.Assert(t, strings.Contains(strings.Join(command, "\n"), `--set=publication=p1`))
+
+			b, err := ioutil.ReadAll(stdin)
+			sql = string(b) + "\n" + strings.Join(command, "\n")
+			return err
+		}
+		assert.NilError(t, Subscribe(ctx, exec, "app", "s1", "p1", "host=blue", lsn))
+		return sql
+	}
+
+	t.Run("Copy", func(t *testing.T) {
+		sql := script(t, "")
+		assert.Assert(t, strings.Contains(sql, `--set=options=create_slot = false, copy_data = true`))
+		assert.Assert(t, !strings.Contains(sql, `pg_replication_origin_advance`))
+		assert.Assert(t, strings.Contains(sql, `ENABLE`))
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		sql := script(t, "0/3000028")
+		assert.Assert(t, strings.Contains(sql, `--set=options=connect = false`))
+		assert.Assert(t, strings.Contains(sql, `--set=lsn=0/3000028`))
+		assert.Assert(t, strings.Contains(sql, `pg_replication_origin_advance`))
+		assert.Assert(t, strings.Contains(sql, `ENABLE`))
+	})
+}
+
+func TestFence(t *testing.T) {
+	exec := func(
+		_ context.Context, stdin io.Reader, stdout, _ io.Writer, _ ...string,
+	) error {
+		b, err := ioutil.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(string(b), `NOT rolsuper AND NOT rolreplication`))
+		assert.Assert(t, strings.Contains(string(b), `NOLOGIN`))
+
+		_, err = io.WriteString(stdout, "app user\nhippo\n")
+		return err
+	}
+
+	roles, err := Fence(context.Background(), exec)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, roles, []string{"app user", "hippo"})
+
+	t.Run("Unfence", func(t *testing.T) {
+		exec := func(
+			_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+				`--set=roles=["app user","hippo"]`))
+
+			b, err := ioutil.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Assert(t, strings.Contains(string(b), `ALTER ROLE %I LOGIN`))
+			return nil
+		}
+		assert.NilError(t, Unfence(context.Background(), exec, roles))
+	})
+}
+
+func TestFreeze(t *testing.T) {
+	exec := func(
+		_ context.Context, stdin io.Reader, stdout, _ io.Writer, command ...string,
+	) error {
+		assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+			`--set=databases=["app","other"]`))
+
+		b, err := ioutil.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(string(b), `pg_terminate_backend`))
+		assert.Assert(t, strings.Contains(string(b), `NOT roles.rolsuper`))
+
+		_, err = io.WriteString(stdout, "0/4000060\n")
+		return err
+	}
+
+	lsn, err := Freeze(context.Background(), exec, []string{"app", "other"})
+	assert.NilError(t, err)
+	assert.Equal(t, lsn, "0/4000060")
+}
+
+func TestCaughtUp(t *testing.T) {
+	for _, tt := range []struct {
+		output   string
+		expected bool
+	}{
+		{output: "t\n", expected: true},
+		{output: "f\n", expected: false},
+		{output: "", expected: false},
+	} {
+		exec := func(
+			_ context.Context, _ io.Reader, stdout, _ io.Writer, command ...string,
+		) error {
+			assert.Assert(t, strings.Contains(strings.Join(command, "\n"), `--set=lsn=0/4000060`))
+			_, err := io.WriteString(stdout, tt.output)
+			return err
+		}
+
+		done, err := CaughtUp(context.Background(), exec, "_crunchybluegreen_x", "0/4000060")
+		assert.NilError(t, err)
+		assert.Equal(t, done, tt.expected, "output: %q", tt.output)
+	}
+}
+
+func TestDropSubscriptions(t *testing.T) {
+	exec := func(
+		_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+			`--set=name=_crunchybluegreen_x`))
+
+		// Blue may be gone, so the subscription lets go of its slot first.
+		b, err := ioutil.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(string(b), `SET (slot_name = NONE)`))
+		assert.Assert(t, strings.Contains(string(b), `DROP SUBSCRIPTION`))
+		return nil
+	}
+
+	assert.NilError(t, DropSubscriptions(context.Background(), exec, "_crunchybluegreen_x"))
+}
+
+func TestDropSlots(t *testing.T) {
+	exec := func(
+		_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+			`--set=name=_crunchybluegreen_x`))
+
+		b, err := ioutil.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Index(string(b), `pg_terminate_backend(active_pid)`) <
+			strings.Index(string(b), `pg_drop_replication_slot(slot_name)`))
+		return nil
+	}
+
+	assert.NilError(t, DropSlots(context.Background(), exec, "_crunchybluegreen_x"))
+}
+
+func TestRemoveFromPostgreSQL(t *testing.T) {
+	var scripts []string
+	exec := func(
+		_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+			`--set=username=_crunchybluegreen_x`))
+
+		b, err := ioutil.ReadAll(stdin)
+		scripts = append(scripts, string(b))
+		return err
+	}
+
+	assert.NilError(t, RemoveFromPostgreSQL(context.Background(), exec, "_crunchybluegreen_x"))
+
+	assert.Equal(t, len(scripts), 2, "expected every database then the role")
+	assert.Assert(t, strings.Contains(scripts[0], `DROP PUBLICATION IF EXISTS`))
+	assert.Assert(t, strings.Contains(scripts[0], `DROP OWNED BY`))
+	assert.Assert(t, strings.Contains(scripts[1], `DROP ROLE`))
+}
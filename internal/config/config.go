@@ -18,6 +18,13 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -38,6 +45,18 @@ func defaultFromEnv(value, key string) string {
 // - https://redhat-connect.gitbook.io/certified-operator-guide/troubleshooting-and-resources/offline-enabled-operators
 // - https://osbs.readthedocs.io/en/latest/users.html#pullspec-locations
 
+// FluentBitContainerImage returns the container image to use for the
+// PostgreSQL log shipper.
+func FluentBitContainerImage(cluster *v1beta1.PostgresCluster) string {
+	var image string
+	if cluster.Spec.Logging != nil &&
+		cluster.Spec.Logging.Shipper != nil {
+		image = cluster.Spec.Logging.Shipper.Image
+	}
+
+	return defaultFromEnv(image, "RELATED_IMAGE_FLUENTBIT")
+}
+
 // PGBackRestContainerImage returns the container image to use for pgBackRest.
 func PGBackRestContainerImage(cluster *v1beta1.PostgresCluster) string {
 	image := cluster.Spec.Backups.PGBackRest.Image
@@ -80,3 +99,378 @@ func PostgresContainerImage(cluster *v1beta1.PostgresCluster) string {
 
 	return defaultFromEnv(image, key)
 }
+
+// ImageArchitectures returns the CPU architectures for which the images of
+// cluster are built. An empty result means any architecture.
+func ImageArchitectures(cluster *v1beta1.PostgresCluster) []string {
+	var result []string
+	for _, arch := range cluster.Spec.Architectures {
+		result = append(result, string(arch))
+	}
+	if len(result) == 0 {
+		for _, arch := range strings.Split(os.Getenv("PGO_IMAGE_ARCHITECTURES"), ",") {
+			if arch = strings.TrimSpace(arch); arch != "" {
+				result = append(result, arch)
+			}
+		}
+	}
+	return result
+}
+
+// DefaultMetadata returns the labels and annotations that the operator adds to
+// every object it creates. They come from the "PGO_DEFAULT_LABELS" and
+// "PGO_DEFAULT_ANNOTATIONS" environment variables, each a comma-separated list
+// of key=value pairs. It returns nil when neither is set.
+func DefaultMetadata() (*v1beta1.Metadata, error) {
+	parse := func(key string, isLabel bool) (map[string]string, error) {
+		var result map[string]string
+		for _, pair := range strings.Split(os.Getenv(key), ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) != 2 {
+				return nil, errors.Errorf("%s: expected key=value, got %q", key, pair)
+			}
+			name, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+			problems := validation.IsQualifiedName(name)
+			if isLabel {
+				problems = append(problems, validation.IsValidLabelValue(value)...)
+			}
+			if len(problems) > 0 {
+				return nil, errors.Errorf("%s: invalid %q: %s",
+					key, pair, strings.Join(problems, "; "))
+			}
+
+			if result == nil {
+				result = make(map[string]string)
+			}
+			result[name] = value
+		}
+		return result, nil
+	}
+
+	labels, err := parse("PGO_DEFAULT_LABELS", true)
+	if err != nil {
+		return nil, err
+	}
+	annotations, err := parse("PGO_DEFAULT_ANNOTATIONS", false)
+	if err != nil {
+		return nil, err
+	}
+	if labels == nil && annotations == nil {
+		return nil, nil
+	}
+	return &v1beta1.Metadata{Labels: labels, Annotations: annotations}, nil
+}
+
+// ExecPolicy limits the commands that the operator runs in containers.
+type ExecPolicy struct {
+	// Timeout is how long one attempt of a command may run before it is
+	// stopped. Zero means no limit.
+	Timeout time.Duration
+
+	// Retries is how many more times to attempt a command that could not
+	// connect to its container.
+	Retries int
+
+	// BreakerThreshold is how many commands in a row can fail to reach the
+	// containers of one cluster before commands in that cluster are refused
+	// for BreakerCooldown. Zero means commands are never refused.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// SQLOverNetwork is whether or not to connect to PostgreSQL over the
+	// network before running "psql" in its container. It is off by default
+	// because it creates a superuser that can login with a certificate.
+	SQLOverNetwork bool
+}
+
+// PodExecPolicy returns the ExecPolicy from the "PGO_EXEC_TIMEOUT",
+// "PGO_EXEC_RETRIES", "PGO_EXEC_BREAKER_THRESHOLD", "PGO_EXEC_BREAKER_COOLDOWN",
+// and "PGO_SQL_OVER_NETWORK" environment variables. Durations are in the
+// format of time.ParseDuration, e.g. "90s".
+func PodExecPolicy() (ExecPolicy, error) {
+	policy := ExecPolicy{
+		Timeout:          5 * time.Minute,
+		Retries:          2,
+		BreakerThreshold: 5,
+		BreakerCooldown:  time.Minute,
+	}
+
+	duration := func(key string, value *time.Duration) error {
+		if s := strings.TrimSpace(os.Getenv(key)); s != "" {
+			d, err := time.ParseDuration(s)
+			if err == nil && d < 0 {
+				err = errors.New("must not be negative")
+			}
+			if err != nil {
+				return errors.Errorf("%s: invalid %q: %v", key, s, err)
+			}
+			*value = d
+		}
+		return nil
+	}
+	count := func(key string, value *int) error {
+		if s := strings.TrimSpace(os.Getenv(key)); s != "" {
+			i, err := strconv.Atoi(s)
+			if err == nil && i < 0 {
+				err = errors.New("must not be negative")
+			}
+			if err != nil {
+				return errors.Errorf("%s: invalid %q: %v", key, s, err)
+			}
+			*value = i
+		}
+		return nil
+	}
+
+	err := duration("PGO_EXEC_TIMEOUT", &policy.Timeout)
+	if err == nil {
+		err = count("PGO_EXEC_RETRIES", &policy.Retries)
+	}
+	if err == nil {
+		err = count("PGO_EXEC_BREAKER_THRESHOLD", &policy.BreakerThreshold)
+	}
+	if err == nil {
+		err = duration("PGO_EXEC_BREAKER_COOLDOWN", &policy.BreakerCooldown)
+	}
+	if s := strings.TrimSpace(os.Getenv("PGO_SQL_OVER_NETWORK")); err == nil && s != "" {
+		if policy.SQLOverNetwork, err = strconv.ParseBool(s); err != nil {
+			err = errors.Errorf("%s: invalid %q: %v", "PGO_SQL_OVER_NETWORK", s, err)
+		}
+	}
+	return policy, err
+}
+
+// UpdateCheck configures where the operator finds the latest minor releases of
+// PostgreSQL and how often it compares them to the images clusters run.
+type UpdateCheck struct {
+	// ConfigMapNamespace and ConfigMapName identify a ConfigMap that holds
+	// the release index. This works without access to the internet.
+	ConfigMapNamespace, ConfigMapName string
+
+	// URL is where to download the release index when there is no ConfigMap.
+	URL string
+
+	// Interval is how long to wait between checks.
+	Interval time.Duration
+}
+
+// Enabled returns whether or not the operator should check for updates.
+func (u UpdateCheck) Enabled() bool { return u.ConfigMapName != "" || u.URL != "" }
+
+// UpdateCheckPolicy returns the UpdateCheck from the
+// "PGO_UPDATE_INDEX_CONFIGMAP", "PGO_UPDATE_INDEX_URL", and
+// "PGO_UPDATE_CHECK_INTERVAL" environment variables. The ConfigMap is in the
+// format namespace/name, and the interval is in the format of
+// time.ParseDuration, e.g. "12h". Checks are disabled when neither the
+// ConfigMap nor the URL is set.
+func UpdateCheckPolicy() (UpdateCheck, error) {
+	check := UpdateCheck{Interval: 24 * time.Hour}
+
+	if s := strings.TrimSpace(os.Getenv("PGO_UPDATE_INDEX_CONFIGMAP")); s != "" {
+		parts := strings.Split(s, "/")
+		if len(parts) != 2 ||
+			len(validation.IsDNS1123Label(parts[0])) > 0 ||
+			len(validation.IsDNS1123Subdomain(parts[1])) > 0 {
+			return check, errors.Errorf(
+				"%s: expected namespace/name, got %q", "PGO_UPDATE_INDEX_CONFIGMAP", s)
+		}
+		check.ConfigMapNamespace, check.ConfigMapName = parts[0], parts[1]
+	}
+
+	if s := strings.TrimSpace(os.Getenv("PGO_UPDATE_INDEX_URL")); s != "" {
+		if !strings.HasPrefix(s, "https://") && !strings.HasPrefix(s, "http://") {
+			return check, errors.Errorf(
+				"%s: expected an HTTP URL, got %q", "PGO_UPDATE_INDEX_URL", s)
+		}
+		check.URL = s
+	}
+
+	if s := strings.TrimSpace(os.Getenv("PGO_UPDATE_CHECK_INTERVAL")); s != "" {
+		d, err := time.ParseDuration(s)
+		if err == nil && d < time.Minute {
+			err = errors.New("must be at least one minute")
+		}
+		if err != nil {
+			return check, errors.Errorf(
+				"%s: invalid %q: %v", "PGO_UPDATE_CHECK_INTERVAL", s, err)
+		}
+		check.Interval = d
+	}
+	return check, nil
+}
+
+// Notifications configures where the operator sends notifications of events
+// in the lifecycle of clusters, such as failovers and failed backups.
+type Notifications struct {
+	// URL is where to POST each notification.
+	URL string
+
+	// Format is the payload to send when there is no Template: "json" for a
+	// generic webhook or "slack" for a Slack incoming webhook.
+	Format string
+
+	// Template is a Go text/template that renders the payload instead.
+	Template string
+
+	// Events are the reasons of the events to send. Empty means the default
+	// set of the notify package.
+	Events []string
+}
+
+// Enabled returns whether or not the operator should send notifications.
+func (n Notifications) Enabled() bool { return n.URL != "" }
+
+// NotificationPolicy returns the Notifications from the "PGO_NOTIFY_URL",
+// "PGO_NOTIFY_FORMAT", "PGO_NOTIFY_TEMPLATE", and "PGO_NOTIFY_EVENTS"
+// environment variables. Events are a comma-separated list of event reasons.
+// Notifications are disabled when the URL is not set.
+func NotificationPolicy() (Notifications, error) {
+	notifications := Notifications{Format: "json"}
+
+	if s := strings.TrimSpace(os.Getenv("PGO_NOTIFY_URL")); s != "" {
+		if !strings.HasPrefix(s, "https://") && !strings.HasPrefix(s, "http://") {
+			return notifications, errors.Errorf(
+				"%s: expected an HTTP URL, got %q", "PGO_NOTIFY_URL", s)
+		}
+		notifications.URL = s
+	}
+
+	if s := strings.TrimSpace(os.Getenv("PGO_NOTIFY_FORMAT")); s != "" {
+		if s != "json" && s != "slack" {
+			return notifications, errors.Errorf(
+				"%s: expected %q or %q, got %q", "PGO_NOTIFY_FORMAT", "json", "slack", s)
+		}
+		notifications.Format = s
+	}
+
+	notifications.Template = os.Getenv("PGO_NOTIFY_TEMPLATE")
+
+	for _, s := range strings.Split(os.Getenv("PGO_NOTIFY_EVENTS"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			notifications.Events = append(notifications.Events, s)
+		}
+	}
+	return notifications, nil
+}
+
+// Audit configures where the operator records the changes it makes to the
+// objects of each cluster.
+type Audit struct {
+	// ConfigMap is whether or not to append records to a ConfigMap in the
+	// namespace of each cluster.
+	ConfigMap bool
+
+	// Log is whether or not to log each record.
+	Log bool
+
+	// Key signs each record so that only the operator can write records that
+	// verify. Anyone who can edit a ConfigMap can also recompute a hash that
+	// has no key.
+	Key []byte
+}
+
+// Enabled returns whether or not the operator should record changes.
+func (a Audit) Enabled() bool { return a.ConfigMap || a.Log }
+
+// AuditPolicy returns the Audit from the "PGO_AUDIT_LOG" environment variable,
+// a comma-separated list of where to record changes: "configmap" or "log".
+// Nothing is recorded when it is not set. Records are signed with the key in
+// the "PGO_AUDIT_KEY" environment variable, which is required to keep records
+// in ConfigMaps.
+func AuditPolicy() (Audit, error) {
+	var audit Audit
+	for _, s := range strings.Split(os.Getenv("PGO_AUDIT_LOG"), ",") {
+		switch s = strings.TrimSpace(s); s {
+		case "":
+		case "configmap":
+			audit.ConfigMap = true
+		case "log":
+			audit.Log = true
+		default:
+			return audit, errors.Errorf(
+				"%s: expected %q or %q, got %q", "PGO_AUDIT_LOG", "configmap", "log", s)
+		}
+	}
+
+	if key := os.Getenv("PGO_AUDIT_KEY"); key != "" {
+		audit.Key = []byte(key)
+	} else if audit.ConfigMap {
+		return audit, errors.Errorf(
+			"%s: %q requires a key in %s", "PGO_AUDIT_LOG", "configmap", "PGO_AUDIT_KEY")
+	}
+	return audit, nil
+}
+
+// ClusterQuota limits the PostgresClusters of each namespace or team. A limit
+// of zero is no limit.
+type ClusterQuota struct {
+	// Clusters is the most PostgresClusters in each namespace or team.
+	Clusters int64
+
+	// Replicas is the most PostgreSQL instances of all the PostgresClusters
+	// in each namespace or team.
+	Replicas int64
+
+	// Storage is the most bytes of storage requested by all the
+	// PostgresClusters in each namespace or team.
+	Storage int64
+
+	// Label, when not empty, is the key of a PostgresCluster label that names
+	// its team. PostgresClusters with the same value of this label count
+	// toward the same limits, regardless of namespace.
+	Label string
+}
+
+// Enabled returns whether or not the operator should limit PostgresClusters.
+func (q ClusterQuota) Enabled() bool { return q.Clusters > 0 || q.Replicas > 0 || q.Storage > 0 }
+
+// ClusterQuotaPolicy returns the ClusterQuota from the "PGO_CLUSTER_QUOTA" and
+// "PGO_CLUSTER_QUOTA_LABEL" environment variables. The first is a comma-separated
+// list of limits, e.g. "clusters=5,replicas=10,storage=1Ti". The second is a
+// label key. There are no limits when the first is not set.
+func ClusterQuotaPolicy() (ClusterQuota, error) {
+	var quota ClusterQuota
+
+	for _, pair := range strings.Split(os.Getenv("PGO_CLUSTER_QUOTA"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return quota, errors.Errorf(
+				"%s: expected name=limit, got %q", "PGO_CLUSTER_QUOTA", pair)
+		}
+
+		var err error
+		switch k, v := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]); k {
+		case "clusters":
+			quota.Clusters, err = strconv.ParseInt(v, 10, 64)
+		case "replicas":
+			quota.Replicas, err = strconv.ParseInt(v, 10, 64)
+		case "storage":
+			var q resource.Quantity
+			if q, err = resource.ParseQuantity(v); err == nil {
+				quota.Storage = q.Value()
+			}
+		default:
+			err = errors.Errorf("expected %q, %q, or %q, got %q",
+				"clusters", "replicas", "storage", k)
+		}
+		if err != nil {
+			return quota, errors.Wrap(err, "PGO_CLUSTER_QUOTA")
+		}
+	}
+
+	if s := strings.TrimSpace(os.Getenv("PGO_CLUSTER_QUOTA_LABEL")); s != "" {
+		if errs := validation.IsQualifiedName(s); len(errs) > 0 {
+			return quota, errors.Errorf("%s: %s", "PGO_CLUSTER_QUOTA_LABEL", strings.Join(errs, "; "))
+		}
+		quota.Label = s
+	}
+	return quota, nil
+}
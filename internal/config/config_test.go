@@ -18,6 +18,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"gotest.tools/v3/assert"
 	"sigs.k8s.io/yaml"
@@ -49,6 +50,21 @@ func unsetEnv(t testing.TB, key string) {
 	assert.NilError(t, os.Unsetenv(key))
 }
 
+func TestFluentBitContainerImage(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+
+	unsetEnv(t, "RELATED_IMAGE_FLUENTBIT")
+	assert.Equal(t, FluentBitContainerImage(cluster), "")
+
+	setEnv(t, "RELATED_IMAGE_FLUENTBIT", "env-var-fluentbit")
+	assert.Equal(t, FluentBitContainerImage(cluster), "env-var-fluentbit")
+
+	assert.NilError(t, yaml.Unmarshal([]byte(`{
+		logging: { shipper: { image: spec-image } },
+	}`), &cluster.Spec))
+	assert.Equal(t, FluentBitContainerImage(cluster), "spec-image")
+}
+
 func TestPGBackRestContainerImage(t *testing.T) {
 	cluster := &v1beta1.PostgresCluster{}
 
@@ -127,3 +143,238 @@ func TestPostgresContainerImage(t *testing.T) {
 	cluster.Spec.Image = "spec-image"
 	assert.Equal(t, PostgresContainerImage(cluster), "spec-image")
 }
+
+func TestImageArchitectures(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+
+	unsetEnv(t, "PGO_IMAGE_ARCHITECTURES")
+	assert.Assert(t, ImageArchitectures(cluster) == nil)
+
+	setEnv(t, "PGO_IMAGE_ARCHITECTURES", " amd64, arm64,")
+	assert.DeepEqual(t, ImageArchitectures(cluster), []string{"amd64", "arm64"})
+
+	cluster.Spec.Architectures = []v1beta1.Architecture{"arm64"}
+	assert.DeepEqual(t, ImageArchitectures(cluster), []string{"arm64"})
+}
+
+func TestDefaultMetadata(t *testing.T) {
+	unsetEnv(t, "PGO_DEFAULT_LABELS")
+	unsetEnv(t, "PGO_DEFAULT_ANNOTATIONS")
+
+	meta, err := DefaultMetadata()
+	assert.NilError(t, err)
+	assert.Assert(t, meta == nil)
+
+	setEnv(t, "PGO_DEFAULT_LABELS", " cost-center=1234, example.com/tier=gold ,")
+	setEnv(t, "PGO_DEFAULT_ANNOTATIONS", "example.com/owner=Team A: DBAs")
+
+	meta, err = DefaultMetadata()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, meta, &v1beta1.Metadata{
+		Labels: map[string]string{
+			"cost-center":      "1234",
+			"example.com/tier": "gold",
+		},
+		Annotations: map[string]string{
+			"example.com/owner": "Team A: DBAs",
+		},
+	})
+
+	// Annotation values can be anything, but label values cannot.
+	setEnv(t, "PGO_DEFAULT_LABELS", "owner=Team A")
+	_, err = DefaultMetadata()
+	assert.ErrorContains(t, err, "PGO_DEFAULT_LABELS")
+
+	setEnv(t, "PGO_DEFAULT_LABELS", "")
+	setEnv(t, "PGO_DEFAULT_ANNOTATIONS", "missing-value")
+	_, err = DefaultMetadata()
+	assert.ErrorContains(t, err, "expected key=value")
+
+	setEnv(t, "PGO_DEFAULT_ANNOTATIONS", "bad key=value")
+	_, err = DefaultMetadata()
+	assert.ErrorContains(t, err, "PGO_DEFAULT_ANNOTATIONS")
+}
+
+func TestPodExecPolicy(t *testing.T) {
+	unsetEnv(t, "PGO_EXEC_TIMEOUT")
+	unsetEnv(t, "PGO_EXEC_RETRIES")
+	unsetEnv(t, "PGO_EXEC_BREAKER_THRESHOLD")
+	unsetEnv(t, "PGO_EXEC_BREAKER_COOLDOWN")
+	unsetEnv(t, "PGO_SQL_OVER_NETWORK")
+
+	policy, err := PodExecPolicy()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, policy, ExecPolicy{
+		Timeout:          5 * time.Minute,
+		Retries:          2,
+		BreakerThreshold: 5,
+		BreakerCooldown:  time.Minute,
+	})
+
+	setEnv(t, "PGO_EXEC_TIMEOUT", "90s")
+	setEnv(t, "PGO_EXEC_RETRIES", "0")
+	setEnv(t, "PGO_EXEC_BREAKER_THRESHOLD", " 3 ")
+	setEnv(t, "PGO_EXEC_BREAKER_COOLDOWN", "5m")
+	setEnv(t, "PGO_SQL_OVER_NETWORK", "true")
+
+	policy, err = PodExecPolicy()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, policy, ExecPolicy{
+		Timeout:          90 * time.Second,
+		Retries:          0,
+		BreakerThreshold: 3,
+		BreakerCooldown:  5 * time.Minute,
+		SQLOverNetwork:   true,
+	})
+
+	setEnv(t, "PGO_EXEC_TIMEOUT", "soon")
+	_, err = PodExecPolicy()
+	assert.ErrorContains(t, err, "PGO_EXEC_TIMEOUT")
+
+	setEnv(t, "PGO_EXEC_TIMEOUT", "")
+	setEnv(t, "PGO_EXEC_RETRIES", "-1")
+	_, err = PodExecPolicy()
+	assert.ErrorContains(t, err, "PGO_EXEC_RETRIES")
+
+	setEnv(t, "PGO_EXEC_RETRIES", "")
+	setEnv(t, "PGO_SQL_OVER_NETWORK", "sometimes")
+	_, err = PodExecPolicy()
+	assert.ErrorContains(t, err, "PGO_SQL_OVER_NETWORK")
+}
+
+func TestUpdateCheckPolicy(t *testing.T) {
+	unsetEnv(t, "PGO_UPDATE_INDEX_CONFIGMAP")
+	unsetEnv(t, "PGO_UPDATE_INDEX_URL")
+	unsetEnv(t, "PGO_UPDATE_CHECK_INTERVAL")
+
+	check, err := UpdateCheckPolicy()
+	assert.NilError(t, err)
+	assert.Assert(t, !check.Enabled())
+	assert.Equal(t, check.Interval, 24*time.Hour)
+
+	setEnv(t, "PGO_UPDATE_INDEX_CONFIGMAP", "postgres-operator/releases")
+	setEnv(t, "PGO_UPDATE_INDEX_URL", "https://example.com/releases.json")
+	setEnv(t, "PGO_UPDATE_CHECK_INTERVAL", "6h")
+
+	check, err = UpdateCheckPolicy()
+	assert.NilError(t, err)
+	assert.Assert(t, check.Enabled())
+	assert.DeepEqual(t, check, UpdateCheck{
+		ConfigMapNamespace: "postgres-operator",
+		ConfigMapName:      "releases",
+		URL:                "https://example.com/releases.json",
+		Interval:           6 * time.Hour,
+	})
+
+	setEnv(t, "PGO_UPDATE_INDEX_CONFIGMAP", "releases")
+	_, err = UpdateCheckPolicy()
+	assert.ErrorContains(t, err, "PGO_UPDATE_INDEX_CONFIGMAP")
+
+	setEnv(t, "PGO_UPDATE_INDEX_CONFIGMAP", "")
+	setEnv(t, "PGO_UPDATE_INDEX_URL", "ftp://example.com")
+	_, err = UpdateCheckPolicy()
+	assert.ErrorContains(t, err, "PGO_UPDATE_INDEX_URL")
+
+	setEnv(t, "PGO_UPDATE_INDEX_URL", "")
+	setEnv(t, "PGO_UPDATE_CHECK_INTERVAL", "1s")
+	_, err = UpdateCheckPolicy()
+	assert.ErrorContains(t, err, "PGO_UPDATE_CHECK_INTERVAL")
+}
+
+func TestNotificationPolicy(t *testing.T) {
+	unsetEnv(t, "PGO_NOTIFY_URL")
+	unsetEnv(t, "PGO_NOTIFY_FORMAT")
+	unsetEnv(t, "PGO_NOTIFY_TEMPLATE")
+	unsetEnv(t, "PGO_NOTIFY_EVENTS")
+
+	notifications, err := NotificationPolicy()
+	assert.NilError(t, err)
+	assert.Assert(t, !notifications.Enabled())
+	assert.Equal(t, notifications.Format, "json")
+
+	setEnv(t, "PGO_NOTIFY_URL", "https://hooks.example.com/abc")
+	setEnv(t, "PGO_NOTIFY_FORMAT", "slack")
+	setEnv(t, "PGO_NOTIFY_TEMPLATE", `{"text": {{ json .Message }}}`)
+	setEnv(t, "PGO_NOTIFY_EVENTS", "PrimaryChanged, BackupFailed,")
+
+	notifications, err = NotificationPolicy()
+	assert.NilError(t, err)
+	assert.Assert(t, notifications.Enabled())
+	assert.DeepEqual(t, notifications, Notifications{
+		URL:      "https://hooks.example.com/abc",
+		Format:   "slack",
+		Template: `{"text": {{ json .Message }}}`,
+		Events:   []string{"PrimaryChanged", "BackupFailed"},
+	})
+
+	setEnv(t, "PGO_NOTIFY_URL", "hooks.example.com")
+	_, err = NotificationPolicy()
+	assert.ErrorContains(t, err, "PGO_NOTIFY_URL")
+
+	setEnv(t, "PGO_NOTIFY_URL", "")
+	setEnv(t, "PGO_NOTIFY_FORMAT", "teams")
+	_, err = NotificationPolicy()
+	assert.ErrorContains(t, err, "PGO_NOTIFY_FORMAT")
+}
+
+func TestAuditPolicy(t *testing.T) {
+	unsetEnv(t, "PGO_AUDIT_LOG")
+	unsetEnv(t, "PGO_AUDIT_KEY")
+
+	audit, err := AuditPolicy()
+	assert.NilError(t, err)
+	assert.Assert(t, !audit.Enabled())
+
+	setEnv(t, "PGO_AUDIT_LOG", "log")
+	audit, err = AuditPolicy()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, audit, Audit{Log: true})
+
+	// Records in ConfigMaps must be signed.
+	setEnv(t, "PGO_AUDIT_LOG", "configmap")
+	_, err = AuditPolicy()
+	assert.ErrorContains(t, err, "PGO_AUDIT_KEY")
+
+	setEnv(t, "PGO_AUDIT_KEY", "secret")
+	audit, err = AuditPolicy()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, audit, Audit{ConfigMap: true, Key: []byte("secret")})
+
+	setEnv(t, "PGO_AUDIT_LOG", " log, configmap ")
+	audit, err = AuditPolicy()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, audit, Audit{ConfigMap: true, Log: true, Key: []byte("secret")})
+
+	setEnv(t, "PGO_AUDIT_LOG", "syslog")
+	_, err = AuditPolicy()
+	assert.ErrorContains(t, err, "PGO_AUDIT_LOG")
+}
+
+func TestClusterQuotaPolicy(t *testing.T) {
+	unsetEnv(t, "PGO_CLUSTER_QUOTA")
+	unsetEnv(t, "PGO_CLUSTER_QUOTA_LABEL")
+
+	quota, err := ClusterQuotaPolicy()
+	assert.NilError(t, err)
+	assert.Assert(t, !quota.Enabled())
+
+	setEnv(t, "PGO_CLUSTER_QUOTA", " clusters=5, storage=1Gi,replicas = 10")
+	setEnv(t, "PGO_CLUSTER_QUOTA_LABEL", "example.com/team")
+	quota, err = ClusterQuotaPolicy()
+	assert.NilError(t, err)
+	assert.Assert(t, quota.Enabled())
+	assert.Equal(t, quota, ClusterQuota{
+		Clusters: 5, Replicas: 10, Storage: 1 << 30, Label: "example.com/team",
+	})
+
+	for _, value := range []string{"clusters", "clusters=many", "storage=lots", "pods=3"} {
+		setEnv(t, "PGO_CLUSTER_QUOTA", value)
+		_, err = ClusterQuotaPolicy()
+		assert.ErrorContains(t, err, "PGO_CLUSTER_QUOTA", "value: %q", value)
+	}
+
+	setEnv(t, "PGO_CLUSTER_QUOTA", "clusters=1")
+	setEnv(t, "PGO_CLUSTER_QUOTA_LABEL", "not a label")
+	_, err = ClusterQuotaPolicy()
+	assert.ErrorContains(t, err, "PGO_CLUSTER_QUOTA_LABEL")
+}
@@ -0,0 +1,184 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/patroni"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs={get,patch,delete}
+
+// reconcileAdoption takes over the Patroni cluster in spec.dataSource.patroni.
+// Once its StatefulSet is found, the volume of its leader is recorded in status
+// and the StatefulSet is scaled to zero. After its Pods stop, that volume is
+// used like one in spec.dataSource.volumes; see existingPGDataVolume. The
+// StatefulSet is deleted once this cluster bootstraps, leaving its volumes.
+//
+// It returns whether or not the main control loop should return early while
+// the Patroni cluster is found and stopped.
+func (r *Reconciler) reconcileAdoption(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (bool, error) {
+	if cluster.Spec.DataSource == nil || cluster.Spec.DataSource.Patroni == nil {
+		return false, nil
+	}
+
+	// A cluster that bootstrapped some other way adopts nothing.
+	if cluster.Status.Adoption == nil {
+		if patroni.ClusterBootstrapped(cluster) {
+			return false, nil
+		}
+
+		adoption, err := r.observeAdoption(ctx, cluster)
+		if err != nil || adoption == nil {
+			return true, err
+		}
+
+		cluster.Status.Adoption = adoption
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "AdoptingPatroni",
+			"Stopping Patroni StatefulSet %q to adopt its volume %q",
+			adoption.StatefulSet, adoption.PGDataVolume)
+	}
+
+	sts := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      cluster.Status.Adoption.StatefulSet,
+	}}
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(sts), sts); err != nil {
+		return false, errors.WithStack(client.IgnoreNotFound(err))
+	}
+
+	if patroni.ClusterBootstrapped(cluster) {
+		err := errors.WithStack(client.IgnoreNotFound(r.Client.Delete(ctx, sts,
+			client.PropagationPolicy(metav1.DeletePropagationBackground))))
+		if err == nil {
+			r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "AdoptedPatroni",
+				"Deleted Patroni StatefulSet %q; its volumes remain", sts.Name)
+		}
+		return false, err
+	}
+
+	if sts.Spec.Replicas == nil || *sts.Spec.Replicas != 0 {
+		before := sts.DeepCopy()
+		sts.Spec.Replicas = initialize.Int32(0)
+		return true, errors.WithStack(r.patch(ctx, sts, client.MergeFrom(before)))
+	}
+
+	// Wait for every Patroni Pod to stop before its data is moved.
+	return sts.Status.Replicas > 0, nil
+}
+
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs={list}
+// +kubebuilder:rbac:groups="",resources=pods,verbs={list}
+
+// observeAdoption looks for the StatefulSet in spec.dataSource.patroni and
+// the pgData volume of its leader. It records an event and returns nil when
+// there is not exactly one StatefulSet that confirms the adoption or when its
+// leader cannot be found.
+func (r *Reconciler) observeAdoption(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (*v1beta1.PostgresAdoptionStatus, error) {
+	source := cluster.Spec.DataSource.Patroni
+
+	selector, err := naming.AsSelector(source.Selector)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	list := &appsv1.StatefulSetList{}
+	if err := errors.WithStack(r.Client.List(ctx, list,
+		client.InNamespace(cluster.Namespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	)); err != nil {
+		return nil, err
+	}
+
+	var confirmed []*appsv1.StatefulSet
+	for i := range list.Items {
+		if list.Items[i].Annotations[naming.AdoptPatroni] == cluster.Name {
+			confirmed = append(confirmed, &list.Items[i])
+		}
+	}
+	if len(confirmed) != 1 {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "AdoptionNotConfirmed",
+			"Found %d StatefulSets matching spec.dataSource.patroni.selector with "+
+				"annotation %q set to %q; expected one",
+			len(confirmed), naming.AdoptPatroni, cluster.Name)
+		return nil, nil
+	}
+	sts := confirmed[0]
+
+	template := source.VolumeClaimTemplate
+	if template == "" && len(sts.Spec.VolumeClaimTemplates) > 0 {
+		template = sts.Spec.VolumeClaimTemplates[0].Name
+	}
+
+	pods := &corev1.PodList{}
+	if sts.Spec.Selector != nil {
+		podSelector, err := naming.AsSelector(*sts.Spec.Selector)
+		if err == nil {
+			err = errors.WithStack(r.Client.List(ctx, pods,
+				client.InNamespace(cluster.Namespace),
+				client.MatchingLabelsSelector{Selector: podSelector},
+			))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	leader := patroniLeader(sts, pods.Items)
+	if template == "" || leader == "" {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "AdoptionLeaderNotFound",
+			"Unable to find the pgData volume of the Patroni leader in StatefulSet %q",
+			sts.Name)
+		return nil, nil
+	}
+
+	// StatefulSets name volumes after their template and Pod.
+	// - https://docs.k8s.io/concepts/workloads/controllers/statefulset/#stable-storage
+	return &v1beta1.PostgresAdoptionStatus{
+		StatefulSet:  sts.Name,
+		PGDataVolume: template + "-" + leader,
+	}, nil
+}
+
+// patroniLeader returns the name of the Pod of sts that Patroni labeled as its
+// leader. Patroni labels it "master" before version 3 and "primary" after.
+// - https://patroni.readthedocs.io/en/latest/yaml_configuration.html#kubernetes
+func patroniLeader(sts *appsv1.StatefulSet, pods []corev1.Pod) string {
+	for i := range pods {
+		role := pods[i].Labels["role"]
+		if metav1.IsControlledBy(&pods[i], sts) &&
+			strings.HasPrefix(pods[i].Name, sts.Name+"-") &&
+			(role == "master" || role == "primary") {
+			return pods[i].Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,211 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestPatroniLeader(t *testing.T) {
+	sts := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "pg", UID: "abc"}}
+	owned := []metav1.OwnerReference{*metav1.NewControllerRef(sts,
+		appsv1.SchemeGroupVersion.WithKind("StatefulSet"))}
+
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pg-0", OwnerReferences: owned,
+			Labels: map[string]string{"role": "replica"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other-1",
+			Labels: map[string]string{"role": "master"}}},
+	}
+	assert.Equal(t, patroniLeader(sts, pods), "")
+
+	pods = append(pods, corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: "pg-2", OwnerReferences: owned,
+		Labels: map[string]string{"role": "master"},
+	}})
+	assert.Equal(t, patroniLeader(sts, pods), "pg-2")
+
+	pods[2].Labels["role"] = "primary"
+	assert.Equal(t, patroniLeader(sts, pods), "pg-2")
+}
+
+func TestReconcileAdoption(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	setup := func(objects ...client.Object) (*Reconciler, *record.FakeRecorder) {
+		recorder := record.NewFakeRecorder(10)
+		return &Reconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects...).Build(),
+			Recorder: recorder,
+			Owner:    client.FieldOwner(t.Name()),
+		}, recorder
+	}
+
+	newCluster := func() *v1beta1.PostgresCluster {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Namespace, cluster.Name = "ns1", "hippo"
+		cluster.Spec.DataSource = &v1beta1.DataSource{
+			Patroni: &v1beta1.PatroniDataSource{
+				Selector: metav1.LabelSelector{
+					MatchLabels: map[string]string{"application": "spilo"},
+				},
+				Directory: "pgroot/data",
+			},
+		}
+		return cluster
+	}
+
+	newStatefulSet := func() *appsv1.StatefulSet {
+		sts := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "ns1",
+			Name:        "pg",
+			UID:         "abc",
+			Labels:      map[string]string{"application": "spilo"},
+			Annotations: map[string]string{naming.AdoptPatroni: "hippo"},
+		}}
+		sts.Spec.Replicas = initialize.Int32(2)
+		sts.Spec.Selector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{"application": "spilo"},
+		}
+		sts.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{
+			ObjectMeta: metav1.ObjectMeta{Name: "pgdata"},
+		}}
+		return sts
+	}
+
+	newPod := func(sts *appsv1.StatefulSet, name, role string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns1",
+			Name:      name,
+			Labels:    map[string]string{"application": "spilo", "role": role},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(sts,
+				appsv1.SchemeGroupVersion.WithKind("StatefulSet"))},
+		}}
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		r, _ := setup()
+		cluster := newCluster()
+		cluster.Spec.DataSource = nil
+
+		returnEarly, err := r.reconcileAdoption(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, !returnEarly)
+	})
+
+	t.Run("NotConfirmed", func(t *testing.T) {
+		sts := newStatefulSet()
+		sts.Annotations = nil
+
+		r, recorder := setup(sts)
+		cluster := newCluster()
+
+		returnEarly, err := r.reconcileAdoption(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, returnEarly)
+		assert.Assert(t, cluster.Status.Adoption == nil)
+		assert.Assert(t, strings.Contains(<-recorder.Events, "AdoptionNotConfirmed"))
+	})
+
+	t.Run("NoLeader", func(t *testing.T) {
+		sts := newStatefulSet()
+		r, recorder := setup(sts, newPod(sts, "pg-0", "replica"))
+		cluster := newCluster()
+
+		returnEarly, err := r.reconcileAdoption(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, returnEarly)
+		assert.Assert(t, cluster.Status.Adoption == nil)
+		assert.Assert(t, strings.Contains(<-recorder.Events, "AdoptionLeaderNotFound"))
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		sts := newStatefulSet()
+		r, _ := setup(sts, newPod(sts, "pg-0", "replica"), newPod(sts, "pg-1", "master"))
+		cluster := newCluster()
+
+		returnEarly, err := r.reconcileAdoption(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, returnEarly)
+		assert.DeepEqual(t, cluster.Status.Adoption, &v1beta1.PostgresAdoptionStatus{
+			StatefulSet: "pg", PGDataVolume: "pgdata-pg-1",
+		})
+		assert.DeepEqual(t, existingPGDataVolume(cluster), &v1beta1.DataSourceVolume{
+			PVCName: "pgdata-pg-1", Directory: "pgroot/data",
+		})
+
+		assert.NilError(t, r.Client.Get(ctx, client.ObjectKeyFromObject(sts), sts))
+		assert.Equal(t, *sts.Spec.Replicas, int32(0))
+
+		// Wait for Pods to stop.
+		sts.Status.Replicas = 1
+		assert.NilError(t, r.Client.Status().Update(ctx, sts))
+		returnEarly, err = r.reconcileAdoption(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, returnEarly)
+
+		sts.Status.Replicas = 0
+		assert.NilError(t, r.Client.Status().Update(ctx, sts))
+		returnEarly, err = r.reconcileAdoption(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, !returnEarly)
+	})
+
+	t.Run("Bootstrapped", func(t *testing.T) {
+		sts := newStatefulSet()
+		sts.Spec.Replicas = initialize.Int32(0)
+		r, recorder := setup(sts)
+
+		cluster := newCluster()
+		cluster.Status.Adoption = &v1beta1.PostgresAdoptionStatus{
+			StatefulSet: "pg", PGDataVolume: "pgdata-pg-1",
+		}
+		cluster.Status.Patroni = &v1beta1.PatroniStatus{SystemIdentifier: "123"}
+
+		returnEarly, err := r.reconcileAdoption(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, !returnEarly)
+		assert.Assert(t, strings.Contains(<-recorder.Events, "AdoptedPatroni"))
+
+		err = r.Client.Get(ctx, client.ObjectKeyFromObject(sts), sts)
+		assert.Assert(t, apierrors.IsNotFound(err), "expected NotFound, got %v", err)
+
+		// Nothing happens once it is gone.
+		returnEarly, err = r.reconcileAdoption(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, !returnEarly)
+	})
+}
@@ -0,0 +1,131 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// appliedSQLKey is the key of the ConfigMap of naming.ClusterAppliedSQL that
+// holds the checksums.
+const appliedSQLKey = "checksums.json"
+
+// appliedSQL maps each piece of SQL, like that of one user or one extension,
+// to its checksum when it last executed successfully. Keys are the kind of
+// SQL and its name separated by a slash.
+type appliedSQL map[string]string
+
+// checksum returns the checksum of the SQL for name of kind, if any.
+func (a appliedSQL) checksum(kind, name string) string {
+	return a[kind+"/"+name]
+}
+
+// replace forgets every checksum of kind and stores checksums in their place.
+func (a appliedSQL) replace(kind string, checksums map[string]string) {
+	for key := range a {
+		if strings.HasPrefix(key, kind+"/") {
+			delete(a, key)
+		}
+	}
+	for name, checksum := range checksums {
+		a[kind+"/"+name] = checksum
+	}
+}
+
+// sqlChecksum returns a hash of the commands that statements would execute.
+// Nothing is executed.
+func sqlChecksum(
+	ctx context.Context, statements func(context.Context, postgres.Executor) error,
+) (string, error) {
+	return safeHash32(func(hasher io.Writer) error {
+		// Discard log messages about executing SQL.
+		return statements(logging.NewContext(ctx, logging.Discard()), func(
+			_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			_, err := fmt.Fprint(hasher, command)
+			if err == nil && stdin != nil {
+				_, err = io.Copy(hasher, stdin)
+			}
+			return err
+		})
+	})
+}
+
+// +kubebuilder:rbac:groups="",resources="configmaps",verbs={get}
+
+// getAppliedSQL returns the checksums of SQL that executed in the PostgreSQL
+// of cluster. It is empty when nothing has been recorded.
+func (r *Reconciler) getAppliedSQL(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (appliedSQL, error) {
+	configmap := &corev1.ConfigMap{ObjectMeta: naming.ClusterAppliedSQL(cluster)}
+	err := errors.WithStack(client.IgnoreNotFound(
+		r.Client.Get(ctx, client.ObjectKeyFromObject(configmap), configmap)))
+
+	applied := appliedSQL{}
+	if data := configmap.Data[appliedSQLKey]; err == nil && data != "" {
+		// Checksums that cannot be read are the same as none at all; the SQL
+		// executes again.
+		if json.Unmarshal([]byte(data), &applied) != nil {
+			applied = appliedSQL{}
+		}
+	}
+	return applied, err
+}
+
+// +kubebuilder:rbac:groups="",resources="configmaps",verbs={create,patch}
+
+// setAppliedSQL writes the checksums of SQL that executed in the PostgreSQL of
+// cluster to a ConfigMap. It is deleted along with cluster.
+func (r *Reconciler) setAppliedSQL(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, applied appliedSQL,
+) error {
+	configmap := &corev1.ConfigMap{ObjectMeta: naming.ClusterAppliedSQL(cluster)}
+	configmap.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+
+	configmap.Annotations = naming.Merge(cluster.Spec.Metadata.GetAnnotationsOrNil())
+	configmap.Labels = naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster: cluster.Name,
+			naming.LabelRole:    naming.RoleAppliedSQL,
+		})
+
+	// Marshal sorts the keys, so the same checksums are always the same data.
+	data, err := json.Marshal(applied)
+	configmap.Data = map[string]string{appliedSQLKey: string(data)}
+
+	if err == nil {
+		err = errors.WithStack(r.setControllerReference(cluster, configmap))
+	}
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, configmap))
+	}
+	return err
+}
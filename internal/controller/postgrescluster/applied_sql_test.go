@@ -0,0 +1,118 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestAppliedSQL(t *testing.T) {
+	applied := appliedSQL{
+		"database/a": "1", "database/b": "2", "user/a": "3",
+	}
+
+	assert.Equal(t, applied.checksum("database", "a"), "1")
+	assert.Equal(t, applied.checksum("user", "a"), "3")
+	assert.Equal(t, applied.checksum("user", "b"), "")
+
+	applied.replace("database", map[string]string{"b": "4", "c": "5"})
+	assert.DeepEqual(t, applied, appliedSQL{
+		"database/b": "4", "database/c": "5", "user/a": "3",
+	})
+
+	applied.replace("user", nil)
+	assert.DeepEqual(t, applied, appliedSQL{
+		"database/b": "4", "database/c": "5",
+	})
+}
+
+func TestSQLChecksum(t *testing.T) {
+	ctx := context.Background()
+	statements := func(sql string) func(context.Context, postgres.Executor) error {
+		return func(ctx context.Context, exec postgres.Executor) error {
+			_, _, err := exec.Exec(ctx, strings.NewReader(sql), nil)
+			return err
+		}
+	}
+
+	one, err := sqlChecksum(ctx, statements("SELECT 1"))
+	assert.NilError(t, err)
+	again, err := sqlChecksum(ctx, statements("SELECT 1"))
+	assert.NilError(t, err)
+	two, err := sqlChecksum(ctx, statements("SELECT 2"))
+	assert.NilError(t, err)
+
+	assert.Assert(t, one != "")
+	assert.Equal(t, one, again)
+	assert.Assert(t, one != two)
+
+	t.Run("NothingExecutes", func(t *testing.T) {
+		_, err := sqlChecksum(ctx, func(ctx context.Context, exec postgres.Executor) error {
+			return exec(ctx, nil, io.Discard, io.Discard, "false")
+		})
+		assert.NilError(t, err)
+	})
+}
+
+func TestGetAppliedSQL(t *testing.T) {
+	ctx := context.Background()
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+
+	reconciler := func(data map[string]string) *Reconciler {
+		builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+		if data != nil {
+			configmap := &corev1.ConfigMap{ObjectMeta: naming.ClusterAppliedSQL(cluster)}
+			configmap.Data = data
+			builder = builder.WithObjects(configmap)
+		}
+		return &Reconciler{Client: builder.Build()}
+	}
+
+	t.Run("Missing", func(t *testing.T) {
+		applied, err := reconciler(nil).getAppliedSQL(ctx, cluster)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, applied, appliedSQL{})
+	})
+
+	t.Run("Stored", func(t *testing.T) {
+		applied, err := reconciler(map[string]string{
+			appliedSQLKey: `{"user/a":"1","database/b":"2"}`,
+		}).getAppliedSQL(ctx, cluster)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, applied, appliedSQL{"user/a": "1", "database/b": "2"})
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		applied, err := reconciler(map[string]string{
+			appliedSQLKey: `not json`,
+		}).getAppliedSQL(ctx, cluster)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, applied, appliedSQL{})
+	})
+}
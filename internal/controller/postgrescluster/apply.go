@@ -25,6 +25,7 @@ import (
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -33,6 +34,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/crunchydata/postgres-operator/internal/kubeapi"
+	"github.com/crunchydata/postgres-operator/internal/logging"
 )
 
 // apply sends an apply patch to object's endpoint in the Kubernetes API and
@@ -41,6 +43,8 @@ import (
 // - https://docs.k8s.io/reference/using-api/server-side-apply/#managers
 // - https://docs.k8s.io/reference/using-api/server-side-apply/#conflicts
 func (r *Reconciler) apply(ctx context.Context, object client.Object) error {
+	r.setSeccompProfile(object)
+
 	// Generate an apply-patch by comparing the object to its zero value.
 	zero := reflect.New(reflect.TypeOf(object).Elem()).Interface()
 	data, err := client.MergeFrom(zero.(client.Object)).Data(object)
@@ -50,6 +54,20 @@ func (r *Reconciler) apply(ctx context.Context, object client.Object) error {
 	intent := object.DeepCopyObject()
 	patch := kubeapi.NewJSONPatch()
 
+	// Keep what is stored now to detect name collisions and, when debugging,
+	// to log what changes.
+	debug := logging.FromContext(ctx).V(2)
+	var stored client.Object
+	if debug.Enabled() || metav1.GetControllerOf(object) != nil {
+		stored = reflect.New(reflect.TypeOf(object).Elem()).Interface().(client.Object)
+		if r.Client.Get(ctx, client.ObjectKeyFromObject(object), stored) != nil {
+			stored = nil
+		}
+	}
+	if err == nil && stored != nil {
+		err = checkNameCollision(object, stored)
+	}
+
 	// Send the apply-patch with force=true.
 	if err == nil {
 		err = r.patch(ctx, object, apply, client.ForceOwnership)
@@ -81,9 +99,53 @@ func (r *Reconciler) apply(ctx context.Context, object client.Object) error {
 	if err == nil && !patch.IsEmpty() {
 		err = r.patch(ctx, object, patch)
 	}
+
+	if debug.Enabled() && err == nil {
+		debug.Info("applied",
+			"kind", object.GetObjectKind().GroupVersionKind().Kind,
+			"name", object.GetName(),
+			"diff", applyDiff(stored, object))
+	}
 	return err
 }
 
+// checkNameCollision returns an error when stored belongs to a different
+// PostgresCluster than intent. Long names are shortened, so two clusters could
+// generate the same name; applying one would take the object from the other.
+func checkNameCollision(intent, stored client.Object) error {
+	want, have := metav1.GetControllerOf(intent), metav1.GetControllerOf(stored)
+
+	if want != nil && have != nil &&
+		want.Kind == "PostgresCluster" && have.Kind == "PostgresCluster" &&
+		want.Name != have.Name {
+		return errors.Errorf("%s %q already belongs to PostgresCluster %q",
+			intent.GetObjectKind().GroupVersionKind().Kind, intent.GetName(), have.Name)
+	}
+	return nil
+}
+
+// applyDiff returns a JSON merge patch from before to after, ignoring their
+// managed fields. When before is nil, it returns all of after.
+func applyDiff(before, after client.Object) string {
+	marshal := func(object client.Object) []byte {
+		object = object.DeepCopyObject().(client.Object)
+		object.SetManagedFields(nil)
+		b, _ := json.Marshal(object)
+		return b
+	}
+
+	modified := marshal(after)
+	if before == nil {
+		return string(modified)
+	}
+
+	diff, err := jsonpatch.CreateMergePatch(marshal(before), modified)
+	if err != nil {
+		return err.Error()
+	}
+	return string(diff)
+}
+
 // handleServiceError inspects err for expected Kubernetes API responses to
 // writing a Service. It returns err when it cannot resolve the issue, otherwise
 // it returns nil.
@@ -151,6 +213,41 @@ func applyPodSecurityContext(
 	}
 }
 
+// setSeccompProfile gives the Pods of object the default seccomp profile of
+// their container runtime when Kubernetes enforces Pod Security Standards. The
+// "restricted" standard requires a profile. A profile that is already set is
+// kept.
+func (r *Reconciler) setSeccompProfile(object client.Object) {
+	if !r.APIs.PodSecurityAdmission {
+		return
+	}
+
+	var pod *corev1.PodSpec
+	switch actual := object.(type) {
+	case *appsv1.Deployment:
+		pod = &actual.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		pod = &actual.Spec.Template.Spec
+	case *batchv1.Job:
+		pod = &actual.Spec.Template.Spec
+	case *batchv1beta1.CronJob:
+		pod = &actual.Spec.JobTemplate.Spec.Template.Spec
+	case *kubeapi.CronJob:
+		pod = &actual.Spec.JobTemplate.Spec.Template.Spec
+	default:
+		return
+	}
+
+	if pod.SecurityContext == nil {
+		pod.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	if pod.SecurityContext.SeccompProfile == nil {
+		pod.SecurityContext.SeccompProfile = &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		}
+	}
+}
+
 // applyPodTemplateSpec is called by Reconciler.apply to work around issues
 // with server-side apply.
 func applyPodTemplateSpec(
@@ -39,6 +39,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/kubeapi"
 )
 
 func TestServerSideApply(t *testing.T) {
@@ -442,3 +445,71 @@ func TestServerSideApply(t *testing.T) {
 			"expected to keep the same ClusterIP")
 	})
 }
+
+func TestApplyDiff(t *testing.T) {
+	before := &corev1.ConfigMap{}
+	before.Name = "some-cm"
+	before.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "somebody"}}
+	before.Data = map[string]string{"a": "1", "b": "2"}
+
+	after := before.DeepCopy()
+	after.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "else"}}
+	after.Data = map[string]string{"a": "1", "c": "3"}
+
+	// Only the changes, without managed fields.
+	assert.Equal(t, applyDiff(before, after), `{"data":{"b":null,"c":"3"}}`)
+
+	// Everything when there was nothing before.
+	assert.Assert(t, !strings.Contains(applyDiff(nil, after), "managedFields"))
+	assert.Assert(t, strings.Contains(applyDiff(nil, after), `"name":"some-cm"`))
+}
+
+func TestCheckNameCollision(t *testing.T) {
+	owner := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{
+			Kind: kind, Name: name, Controller: initialize.Bool(true),
+		}}
+	}
+
+	intent := &corev1.Service{}
+	intent.Kind, intent.Name = "Service", "some-svc"
+	intent.OwnerReferences = owner("PostgresCluster", "hippo")
+
+	stored := &corev1.Service{}
+	assert.NilError(t, checkNameCollision(intent, stored), "expected no owner to be okay")
+
+	stored.OwnerReferences = owner("PostgresCluster", "hippo")
+	assert.NilError(t, checkNameCollision(intent, stored))
+
+	stored.OwnerReferences = owner("Other", "rhino")
+	assert.NilError(t, checkNameCollision(intent, stored))
+
+	stored.OwnerReferences = owner("PostgresCluster", "rhino")
+	assert.ErrorContains(t, checkNameCollision(intent, stored),
+		`Service "some-svc" already belongs to PostgresCluster "rhino"`)
+}
+
+func TestSetSeccompProfile(t *testing.T) {
+	r := &Reconciler{}
+
+	sts := &appsv1.StatefulSet{}
+	r.setSeccompProfile(sts)
+	assert.Assert(t, sts.Spec.Template.Spec.SecurityContext == nil,
+		"expected no change when Kubernetes does not enforce Pod Security Standards")
+
+	r.APIs.PodSecurityAdmission = true
+	r.setSeccompProfile(sts)
+	assert.Equal(t, sts.Spec.Template.Spec.SecurityContext.SeccompProfile.Type,
+		corev1.SeccompProfileTypeRuntimeDefault)
+
+	cronjob := &kubeapi.CronJob{}
+	cronjob.Spec.JobTemplate.Spec.Template.Spec.SecurityContext = &corev1.PodSecurityContext{
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined},
+	}
+	r.setSeccompProfile(cronjob)
+	assert.Equal(t, cronjob.Spec.JobTemplate.Spec.Template.Spec.SecurityContext.SeccompProfile.Type,
+		corev1.SeccompProfileTypeUnconfined, "expected an existing profile to be kept")
+
+	service := &corev1.Service{}
+	r.setSeccompProfile(service)
+}
@@ -0,0 +1,276 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/audit"
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// auditKey is the key of the ConfigMap of naming.ClusterAudit that holds the
+// records as JSON Lines.
+const auditKey = "records.jsonl"
+
+// auditRecordLimit is the number of records kept in the ConfigMap of
+// naming.ClusterAudit. When it is full, its records move to a ConfigMap of
+// naming.ClusterAuditArchive and newer records start over in an empty one.
+const auditRecordLimit = 1000
+
+// ConditionAuditTampered is the type used in a condition to indicate that the
+// audit records of a cluster do not match their hashes. No more records are
+// added to the ConfigMap while it is true.
+const ConditionAuditTampered = "AuditTampered"
+
+// EventAuditTampered is the event reason used when the audit records of a
+// cluster do not match their hashes.
+const EventAuditTampered = "AuditTampered"
+
+// verifyAuditRecords reads the records in the audit ConfigMap of cluster and
+// returns them. When they do not match their hashes, do not follow the
+// archived records before them, or no longer include the newest record in the
+// status of cluster, tampered explains why.
+func (r *Reconciler) verifyAuditRecords(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (stored []audit.Record, tampered, err error) {
+	read := func(objectMeta metav1.ObjectMeta) ([]audit.Record, error) {
+		existing := &corev1.ConfigMap{ObjectMeta: objectMeta}
+		err := errors.WithStack(client.IgnoreNotFound(
+			r.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing)))
+		if err != nil {
+			return nil, err
+		}
+		return audit.Unmarshal(existing.Data[auditKey])
+	}
+
+	if stored, err = read(naming.ClusterAudit(cluster)); err != nil {
+		return nil, nil, err
+	}
+
+	// The newest record written must still be stored. Records written after
+	// it are allowed because the status is patched after the ConfigMap.
+	if head := cluster.Status.AuditHead; head != "" {
+		found := len(stored) > 0 && stored[0].Previous == head
+		for i := range stored {
+			found = found || stored[i].Hash == head
+		}
+		if !found {
+			return stored, errors.Errorf(
+				"the newest record written, %q, is missing", head), nil
+		}
+	}
+
+	// Verify each ConfigMap back to the first record. The records of each
+	// archive end with the one before the first record of the next.
+	name, records := naming.ClusterAudit(cluster).Name, stored
+	for {
+		var previous, olderName string
+		var older []audit.Record
+		if len(records) > 0 && len(records[0].Previous) >= 10 {
+			archive := naming.ClusterAuditArchive(cluster, records[0].Previous[:10])
+			if older, err = read(archive); err != nil {
+				return nil, nil, err
+			}
+			if len(older) > 0 {
+				olderName, previous = archive.Name, older[len(older)-1].Hash
+			}
+		}
+		if err := audit.Verify(r.Audit.Key, previous, records); err != nil {
+			return stored, errors.WithMessage(err, name), nil
+		}
+		if len(older) == 0 {
+			return stored, nil, nil
+		}
+		name, records = olderName, older
+	}
+}
+
+// reconcileAuditVerification reports in the status of cluster whether the
+// records in its audit ConfigMaps match their hashes and include the newest
+// record written. An event is recorded when they stop matching.
+func (r *Reconciler) reconcileAuditVerification(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) {
+	if !r.Audit.ConfigMap {
+		return
+	}
+
+	_, tampered, err := r.verifyAuditRecords(ctx, cluster)
+	if err != nil {
+		// Records that cannot be read cannot be verified either; try again
+		// during the next reconcile.
+		logging.FromContext(ctx).Error(err, "unable to read audit records")
+		return
+	}
+
+	if tampered == nil {
+		if meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionAuditTampered) {
+			meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+				ObservedGeneration: cluster.GetGeneration(),
+				Type:               ConditionAuditTampered,
+				Status:             metav1.ConditionFalse,
+				Reason:             "Verified",
+				Message:            "Audit records match their hashes",
+			})
+		}
+		return
+	}
+
+	message := "Audit records have changed since they were written: " + tampered.Error()
+
+	if condition := meta.FindStatusCondition(cluster.Status.Conditions,
+		ConditionAuditTampered); condition == nil ||
+		condition.Status != metav1.ConditionTrue || condition.Message != message {
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, EventAuditTampered, message)
+	}
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		ObservedGeneration: cluster.GetGeneration(),
+		Type:               ConditionAuditTampered,
+		Status:             metav1.ConditionTrue,
+		Reason:             EventAuditTampered,
+		Message:            message,
+	})
+}
+
+// +kubebuilder:rbac:groups="",resources="configmaps",verbs={get,create,patch}
+
+// writeAuditTrail records the changes in trail to the sinks of r.Audit. Each
+// record follows the newest one in the ConfigMap of cluster or, when there is
+// no ConfigMap, the newest one in the status of cluster. Nothing is added to
+// a ConfigMap whose records do not verify. The ConfigMaps have no owner, so
+// they remain after cluster is deleted. Problems are logged.
+func (r *Reconciler) writeAuditTrail(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, trail *audit.Trail,
+) {
+	records := trail.Records()
+	if len(records) == 0 {
+		return
+	}
+
+	// Do not record changes to the ConfigMap itself.
+	ctx = audit.NewContext(ctx, nil)
+	log := logging.FromContext(ctx)
+
+	previous := cluster.Status.AuditHead
+
+	var stored []audit.Record
+	writeConfigMap := r.Audit.ConfigMap &&
+		!meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionAuditTampered)
+	if writeConfigMap {
+		var tampered, err error
+		stored, tampered, err = r.verifyAuditRecords(ctx, cluster)
+		if err != nil {
+			// Leave the ConfigMap as it is so nothing there is lost.
+			log.Error(err, "unable to read audit records")
+			writeConfigMap = false
+		} else if tampered != nil {
+			// Adding to these records would make them appear legitimate.
+			log.Error(tampered, "audit records have changed since they were written")
+			writeConfigMap = false
+		} else if len(stored) > 0 {
+			previous = stored[len(stored)-1].Hash
+		}
+	}
+
+	head := audit.Chain(r.Audit.Key, previous, records)
+
+	if r.Audit.Log {
+		log := log.WithName("audit")
+		for _, record := range records {
+			log.Info("recorded change",
+				"time", record.Time, "actor", record.Actor, "action", record.Action,
+				"kind", record.Kind, "name", record.Name, "diffHash", record.DiffHash,
+				"previous", record.Previous, "hash", record.Hash)
+		}
+	}
+
+	if writeConfigMap && len(stored) > 0 && len(stored)+len(records) > auditRecordLimit {
+		// Move the stored records to a ConfigMap named after the newest one.
+		// The records that follow still begin with its Hash.
+		archive := &corev1.ConfigMap{
+			ObjectMeta: naming.ClusterAuditArchive(cluster, previous[:10]),
+		}
+		archive.Annotations = naming.Merge(cluster.Spec.Metadata.GetAnnotationsOrNil())
+		archive.Labels = naming.Merge(
+			cluster.Spec.Metadata.GetLabelsOrNil(),
+			map[string]string{
+				naming.LabelCluster: cluster.Name,
+				naming.LabelRole:    naming.RoleAudit,
+			})
+
+		data, err := audit.Marshal(stored)
+		archive.Data = map[string]string{auditKey: data}
+
+		if err == nil {
+			err = errors.WithStack(r.Client.Create(ctx, archive))
+		}
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			// Leave the ConfigMap as it is so nothing there is lost.
+			log.Error(err, "unable to archive audit records")
+			writeConfigMap = false
+		} else {
+			stored = nil
+		}
+	}
+
+	if writeConfigMap {
+		stored = append(stored, records...)
+
+		intent := &corev1.ConfigMap{ObjectMeta: naming.ClusterAudit(cluster)}
+		intent.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+
+		intent.Annotations = naming.Merge(cluster.Spec.Metadata.GetAnnotationsOrNil())
+		intent.Labels = naming.Merge(
+			cluster.Spec.Metadata.GetLabelsOrNil(),
+			map[string]string{
+				naming.LabelCluster: cluster.Name,
+				naming.LabelRole:    naming.RoleAudit,
+			})
+
+		data, err := audit.Marshal(stored)
+		intent.Data = map[string]string{auditKey: data}
+
+		if err == nil {
+			err = errors.WithStack(r.apply(ctx, intent))
+		}
+		if err != nil {
+			log.Error(err, "unable to write audit records")
+			return
+		}
+	} else if r.Audit.ConfigMap {
+		// Later records follow the newest one in the ConfigMap.
+		return
+	}
+
+	// Keep the newest record in the status so that its removal can be noticed
+	// after the operator restarts.
+	before := cluster.DeepCopy()
+	cluster.Status.AuditHead = head
+	if err := errors.WithStack(client.IgnoreNotFound(r.Client.Status().Patch(
+		ctx, cluster, client.MergeFrom(before), r.Owner))); err != nil {
+		log.Error(err, "unable to record the newest audit record")
+	}
+}
@@ -0,0 +1,224 @@
+//go:build envtest
+// +build envtest
+
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/audit"
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+)
+
+func TestWriteAuditTrail(t *testing.T) {
+	ctx := context.Background()
+	env, cc, _ := setupTestEnv(t, ControllerName)
+	t.Cleanup(func() { teardownTestEnv(t, env) })
+
+	ns := &corev1.Namespace{}
+	ns.GenerateName = "postgres-operator-test-"
+	ns.Labels = labels.Set{"postgres-operator-test": t.Name()}
+	assert.NilError(t, cc.Create(ctx, ns))
+	t.Cleanup(func() { assert.Check(t, cc.Delete(ctx, ns)) })
+
+	reconciler := &Reconciler{
+		Client:   audit.NewClient(cc, cc),
+		Owner:    client.FieldOwner(t.Name()),
+		Recorder: record.NewFakeRecorder(10),
+		Audit:    config.Audit{ConfigMap: true, Key: []byte("secret")},
+	}
+
+	cluster := testCluster()
+	cluster.Namespace = ns.Name
+	assert.NilError(t, cc.Create(ctx, cluster))
+
+	read := func(t *testing.T) []audit.Record {
+		t.Helper()
+		stored := &corev1.ConfigMap{ObjectMeta: naming.ClusterAudit(cluster)}
+		assert.NilError(t, cc.Get(ctx, client.ObjectKeyFromObject(stored), stored))
+		assert.Equal(t, stored.Labels[naming.LabelRole], naming.RoleAudit)
+		assert.Equal(t, len(stored.OwnerReferences), 0)
+
+		records, err := audit.Unmarshal(stored.Data[auditKey])
+		assert.NilError(t, err)
+		return records
+	}
+
+	trail := audit.NewTrail("postgres-operator/test")
+	trail.Add(audit.ActionCreate, "Service", "one", []byte(`{}`))
+	trail.Add(audit.ActionCreate, "Service", "two", []byte(`{}`))
+	reconciler.writeAuditTrail(ctx, cluster, trail)
+
+	records := read(t)
+	assert.Equal(t, len(records), 2)
+	assert.NilError(t, audit.Verify(reconciler.Audit.Key, "", records))
+
+	// The newest record is kept in the status.
+	assert.Equal(t, cluster.Status.AuditHead, records[1].Hash)
+	fetched := cluster.DeepCopy()
+	assert.NilError(t, cc.Get(ctx, client.ObjectKeyFromObject(fetched), fetched))
+	assert.Equal(t, fetched.Status.AuditHead, records[1].Hash)
+
+	// Later records follow those already stored.
+	trail = audit.NewTrail("postgres-operator/test")
+	trail.Add(audit.ActionDelete, "Service", "one", nil)
+	reconciler.writeAuditTrail(ctx, cluster, trail)
+
+	records = read(t)
+	assert.Equal(t, len(records), 3)
+	assert.NilError(t, audit.Verify(reconciler.Audit.Key, "", records))
+	assert.Equal(t, cluster.Status.AuditHead, records[2].Hash)
+	assert.Equal(t, records[2].Action, audit.ActionDelete)
+
+	t.Run("Rotate", func(t *testing.T) {
+		// Fill the ConfigMap.
+		full := make([]audit.Record, auditRecordLimit-len(records))
+		for i := range full {
+			full[i] = audit.Record{Action: audit.ActionUpdate, Kind: "Service", Name: "two"}
+		}
+		audit.Chain(reconciler.Audit.Key, records[len(records)-1].Hash, full)
+		full = append(records, full...)
+
+		stored := &corev1.ConfigMap{ObjectMeta: naming.ClusterAudit(cluster)}
+		assert.NilError(t, cc.Get(ctx, client.ObjectKeyFromObject(stored), stored))
+		stored.Data[auditKey], _ = audit.Marshal(full)
+		assert.NilError(t, cc.Update(ctx, stored))
+
+		trail := audit.NewTrail("postgres-operator/test")
+		trail.Add(audit.ActionDelete, "Service", "two", nil)
+		reconciler.writeAuditTrail(ctx, cluster, trail)
+
+		// Newer records start over and follow the older ones.
+		records := read(t)
+		assert.Equal(t, len(records), 1)
+		assert.Equal(t, records[0].Previous, full[len(full)-1].Hash)
+		assert.NilError(t, audit.Verify(reconciler.Audit.Key, full[len(full)-1].Hash, records))
+		assert.Equal(t, cluster.Status.AuditHead, records[0].Hash)
+
+		// Older records are all kept.
+		archive := &corev1.ConfigMap{ObjectMeta: naming.ClusterAuditArchive(
+			cluster, full[len(full)-1].Hash[:10])}
+		assert.NilError(t, cc.Get(ctx, client.ObjectKeyFromObject(archive), archive))
+		assert.Equal(t, archive.Labels[naming.LabelRole], naming.RoleAudit)
+
+		archived, err := audit.Unmarshal(archive.Data[auditKey])
+		assert.NilError(t, err)
+		assert.DeepEqual(t, archived, full)
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		stored := &corev1.ConfigMap{ObjectMeta: naming.ClusterAudit(cluster)}
+		assert.NilError(t, cc.Get(ctx, client.ObjectKeyFromObject(stored), stored))
+
+		original := read(t)
+		edited := read(t)
+		edited[0].Name = "other"
+		stored.Data[auditKey], _ = audit.Marshal(edited)
+		assert.NilError(t, cc.Update(ctx, stored))
+
+		reconciler.reconcileAuditVerification(ctx, cluster)
+		assert.Assert(t, meta.IsStatusConditionTrue(
+			cluster.Status.Conditions, ConditionAuditTampered))
+
+		// Nothing more is added to records that do not match.
+		trail := audit.NewTrail("postgres-operator/test")
+		trail.Add(audit.ActionCreate, "Service", "three", []byte(`{}`))
+		reconciler.writeAuditTrail(ctx, cluster, trail)
+		assert.DeepEqual(t, read(t), edited)
+
+		// The condition clears once the records match again.
+		assert.NilError(t, cc.Get(ctx, client.ObjectKeyFromObject(stored), stored))
+		stored.Data[auditKey], _ = audit.Marshal(original)
+		assert.NilError(t, cc.Update(ctx, stored))
+		reconciler.reconcileAuditVerification(ctx, cluster)
+		assert.Assert(t, meta.IsStatusConditionFalse(
+			cluster.Status.Conditions, ConditionAuditTampered))
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		trail := audit.NewTrail("postgres-operator/test")
+		trail.Add(audit.ActionCreate, "Service", "three", []byte(`{}`))
+		reconciler.writeAuditTrail(ctx, cluster, trail)
+
+		original := read(t)
+		assert.Equal(t, len(original), 2)
+
+		// Records that remain still match their hashes, but the newest one
+		// written is gone.
+		stored := &corev1.ConfigMap{ObjectMeta: naming.ClusterAudit(cluster)}
+		assert.NilError(t, cc.Get(ctx, client.ObjectKeyFromObject(stored), stored))
+		stored.Data[auditKey], _ = audit.Marshal(original[:1])
+		assert.NilError(t, cc.Update(ctx, stored))
+
+		reconciler.reconcileAuditVerification(ctx, cluster)
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionAuditTampered)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionTrue)
+		assert.Assert(t, strings.Contains(condition.Message, "is missing"), condition.Message)
+
+		// Records that no longer follow the archive do not verify either.
+		stored.Data[auditKey], _ = audit.Marshal(original[1:])
+		assert.NilError(t, cc.Update(ctx, stored))
+
+		reconciler.reconcileAuditVerification(ctx, cluster)
+		condition = meta.FindStatusCondition(cluster.Status.Conditions, ConditionAuditTampered)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionTrue)
+		assert.Assert(t, strings.Contains(condition.Message, "does not follow"), condition.Message)
+
+		assert.NilError(t, cc.Get(ctx, client.ObjectKeyFromObject(stored), stored))
+		stored.Data[auditKey], _ = audit.Marshal(original)
+		assert.NilError(t, cc.Update(ctx, stored))
+		reconciler.reconcileAuditVerification(ctx, cluster)
+		assert.Assert(t, meta.IsStatusConditionFalse(
+			cluster.Status.Conditions, ConditionAuditTampered))
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		stored := &corev1.ConfigMap{ObjectMeta: naming.ClusterAudit(cluster)}
+		assert.NilError(t, cc.Delete(ctx, stored))
+
+		// The newest record written is read from the API, as it would be
+		// after the operator restarts.
+		restarted := cluster.DeepCopy()
+		assert.NilError(t, cc.Get(ctx, client.ObjectKeyFromObject(restarted), restarted))
+
+		reconciler.reconcileAuditVerification(ctx, restarted)
+		assert.Assert(t, meta.IsStatusConditionTrue(
+			restarted.Status.Conditions, ConditionAuditTampered))
+
+		// Nothing starts over in an empty ConfigMap.
+		trail := audit.NewTrail("postgres-operator/test")
+		trail.Add(audit.ActionCreate, "Service", "four", []byte(`{}`))
+		reconciler.writeAuditTrail(ctx, restarted, trail)
+		assert.Assert(t, apierrors.IsNotFound(
+			cc.Get(ctx, client.ObjectKeyFromObject(stored), stored)))
+	})
+}
@@ -0,0 +1,471 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crunchydata/postgres-operator/internal/bluegreen"
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	pgpassword "github.com/crunchydata/postgres-operator/internal/postgres/password"
+	"github.com/crunchydata/postgres-operator/internal/util"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={get}
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={create,delete,patch}
+
+// reconcileBlueGreenSecret writes the Secret that holds the password green uses
+// to connect to blue. It deletes the Secret and returns nil when cluster does
+// not replace another.
+func (r *Reconciler) reconcileBlueGreenSecret(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (*corev1.Secret, error) {
+	existing := &corev1.Secret{ObjectMeta: naming.BlueGreenSecret(cluster)}
+	err := errors.WithStack(
+		r.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing))
+	if client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+
+	if !bluegreen.Enabled(cluster) {
+		if err == nil {
+			err = errors.WithStack(r.deleteControlled(ctx, cluster, existing))
+		}
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	intent := &corev1.Secret{ObjectMeta: naming.BlueGreenSecret(cluster)}
+	intent.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+
+	intent.Annotations = naming.Merge(cluster.Spec.Metadata.GetAnnotationsOrNil())
+	intent.Labels = naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster: cluster.Name,
+			naming.LabelRole:    naming.RoleBlueGreen,
+		})
+
+	intent.Data = make(map[string][]byte)
+	intent.Data["password"] = existing.Data["password"]
+	intent.Data["verifier"] = existing.Data["verifier"]
+
+	// Generate both the password and verifier when either is missing.
+	if len(intent.Data["password"]) == 0 || len(intent.Data["verifier"]) == 0 {
+		password, err := util.GeneratePassword(util.DefaultGeneratedPasswordLength)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		verifier, err := pgpassword.NewSCRAMPassword(password).Build()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		intent.Data["password"] = []byte(password)
+		intent.Data["verifier"] = []byte(verifier)
+	}
+
+	err = errors.WithStack(r.setControllerReference(cluster, intent))
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, intent))
+	}
+	if err == nil {
+		return intent, nil
+	}
+	return nil, err
+}
+
+// reconcileBlueGreen moves cluster, "green", through the phases of replacing
+// the cluster in spec.blueGreen, "blue". It prepares blue for logical
+// replication, subscribes green to it, and, once cutover is requested, stops
+// writes to blue and waits for green to catch up before green takes over.
+// Problems with either cluster are reported in events and tried again later.
+// When spec.blueGreen is removed before cutover, the switch is undone.
+func (r *Reconciler) reconcileBlueGreen(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) (reconcile.Result, error) {
+	secret, err := r.reconcileBlueGreenSecret(ctx, cluster)
+	if err == nil && secret == nil {
+		return r.abandonBlueGreen(ctx, cluster, instances)
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if cluster.Status.BlueGreen == nil {
+		cluster.Status.BlueGreen = &v1beta1.PostgresBlueGreenStatus{
+			Phase: bluegreen.PhasePreparing,
+		}
+	}
+	status := cluster.Status.BlueGreen
+	if status.Phase == bluegreen.PhaseCutOver {
+		return reconcile.Result{}, nil
+	}
+	status.Source = cluster.Spec.BlueGreen.Source
+
+	// Green either restores a backup of blue or starts empty.
+	if cluster.Spec.DataSource != nil && !bluegreen.Restores(cluster) {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "InvalidBlueGreen",
+			"The data source must be empty or a backup of PostgresCluster %q",
+			cluster.Spec.BlueGreen.Source)
+		return reconcile.Result{}, nil
+	}
+
+	source := bluegreen.Source(cluster)
+	err = errors.WithStack(r.Client.Get(ctx, client.ObjectKeyFromObject(source), source))
+	if apierrors.IsNotFound(err) {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "BlueGreenSourceMissing",
+			"PostgresCluster %q does not exist", source.Name)
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// Blue is not watched through this cluster; check it again soon when it
+	// has no writable instance.
+	sourceInstances, err := r.observeInstances(ctx, source.DeepCopy())
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	sourcePod, _ := sourceInstances.writablePod(naming.ContainerDatabase)
+	if sourcePod == nil {
+		return reconcile.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+
+	name := bluegreen.Name(cluster)
+	databases := bluegreen.Databases(cluster)
+	sourceExec := r.postgresExecutor(source, sourcePod, nil)
+
+	ctx = logging.NewContext(ctx, logging.FromContext(ctx).WithValues(
+		"source", source.Name, "phase", status.Phase))
+	log := logging.FromContext(ctx)
+
+	// failed reports err and tries again later.
+	failed := func(err error, message string) (reconcile.Result, error) {
+		log.Error(err, "blue/green switch failed")
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, "BlueGreenFailed", message)
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	if status.Phase == bluegreen.PhasePreparing {
+		if err := bluegreen.PrepareSource(ctx, sourceExec, name,
+			string(secret.Data["verifier"]), databases); err != nil {
+			return failed(err, "Unable to prepare "+source.Name+
+				" for logical replication; check that its databases exist")
+		}
+
+		// A restore recovers to the newest slot position, which must be in
+		// the pgBackRest repository before the restore starts.
+		if bluegreen.Restores(cluster) {
+			lsn, archived, err := bluegreen.SourcePosition(ctx, sourceExec, name)
+			if err == nil && lsn != "" && !archived {
+				err = bluegreen.SwitchSourceWAL(ctx, sourceExec, lsn)
+			}
+			if err != nil {
+				return failed(err, "Unable to find the replication position of "+source.Name)
+			}
+			if lsn == "" || !archived {
+				return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+			}
+			status.RestoreLSN = lsn
+		}
+		status.Phase = bluegreen.PhaseSubscribing
+	}
+
+	// Find the PostgreSQL instance of green that can execute SQL that writes
+	// system catalogs. When there is none, return early.
+	pod, _ := instances.writablePod(naming.ContainerDatabase)
+	if pod == nil {
+		return reconcile.Result{}, nil
+	}
+	exec := r.postgresExecutor(cluster, pod, nil)
+
+	if status.Phase == bluegreen.PhaseSubscribing {
+		if !bluegreen.Restores(cluster) {
+			if err := bluegreen.CreateDatabases(ctx, exec, databases); err != nil {
+				return failed(err, "Unable to create databases to replicate")
+			}
+		}
+		for _, database := range databases {
+			slot := bluegreen.Slot(name, database)
+			subscribed, err := bluegreen.Subscribed(ctx, exec, database, slot)
+			if err == nil && !subscribed && !bluegreen.Restores(cluster) {
+				err = r.copyBlueGreenSchema(ctx, sourcePod, pod, database)
+			}
+			if err == nil && !subscribed {
+				err = bluegreen.Subscribe(ctx, exec, database, slot, name,
+					bluegreen.Conninfo(source, name, string(secret.Data["password"]), database),
+					status.RestoreLSN)
+			}
+			if err != nil {
+				return failed(err, "Unable to subscribe to "+source.Name+" in database "+database)
+			}
+		}
+		status.Phase = bluegreen.PhaseReplicating
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "BlueGreenReplicating",
+			"Replicating from PostgresCluster %q", source.Name)
+	}
+
+	if status.Phase == bluegreen.PhaseReplicating {
+		if !bluegreen.CutoverRequested(cluster) {
+			// Allow logins again when cutover is withdrawn before it began.
+			if len(status.FencedRoles) > 0 {
+				if err := bluegreen.Unfence(ctx, sourceExec, status.FencedRoles); err != nil {
+					return failed(err, "Unable to allow logins to "+source.Name)
+				}
+				status.FencedRoles = nil
+			}
+			return reconcile.Result{}, nil
+		}
+
+		// Stop new sessions, then end existing ones. Blue stops changing once
+		// they are gone.
+		roles, err := bluegreen.Fence(ctx, sourceExec)
+		if err == nil {
+			status.FencedRoles = append(status.FencedRoles, roles...)
+		}
+		var lsn string
+		if err == nil {
+			lsn, err = bluegreen.Freeze(ctx, sourceExec, databases)
+		}
+		if err != nil {
+			return failed(err, "Unable to stop writes to "+source.Name)
+		}
+		if lsn == "" {
+			return reconcile.Result{RequeueAfter: time.Second}, nil
+		}
+		status.CutoverLSN = lsn
+		status.Phase = bluegreen.PhaseCuttingOver
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "BlueGreenCuttingOver",
+			"PostgresCluster %q stopped accepting writes at %s", source.Name, lsn)
+	}
+
+	if status.Phase == bluegreen.PhaseCuttingOver {
+		caughtUp, err := bluegreen.CaughtUp(ctx, sourceExec, name, status.CutoverLSN)
+		if err != nil {
+			return failed(err, "Unable to check the replication position of "+source.Name)
+		}
+		if !caughtUp {
+			return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
+		}
+
+		// Logical replication does not replicate sequences; copy them now
+		// that nothing writes to blue.
+		for _, database := range databases {
+			script, err := bluegreen.SequenceScript(ctx, sourceExec, database)
+			if err == nil && strings.TrimSpace(script) != "" {
+				_, _, err = exec.ExecInDatabase(ctx, database, strings.NewReader(script),
+					map[string]string{
+						"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+						"QUIET":         "on", // Do not print successful statements to stdout.
+					})
+			}
+			if err == nil {
+				err = bluegreen.Unsubscribe(ctx, exec, database, bluegreen.Slot(name, database))
+			}
+			if err != nil {
+				return failed(err, "Unable to finish replicating from "+source.Name+" in database "+database)
+			}
+		}
+
+		err = bluegreen.RemoveFromPostgreSQL(ctx, exec, name)
+		if err == nil {
+			err = bluegreen.RemoveFromPostgreSQL(ctx, sourceExec, name)
+		}
+		if err != nil {
+			return failed(err, "Unable to remove replication objects")
+		}
+
+		status.Phase = bluegreen.PhaseCutOver
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "BlueGreenCutOver",
+			"Now serving the Services of PostgresCluster %q", source.Name)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// abandonBlueGreen undoes a switch that stopped before cutover because
+// spec.blueGreen was removed. Green drops its subscriptions, then blue drops
+// what it had for green and allows logins again. The status is cleared once
+// both are done.
+func (r *Reconciler) abandonBlueGreen(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) (reconcile.Result, error) {
+	status := cluster.Status.BlueGreen
+	if status == nil || status.Phase == bluegreen.PhaseCutOver {
+		cluster.Status.BlueGreen = nil
+		return reconcile.Result{}, nil
+	}
+
+	name := bluegreen.Name(cluster)
+	pod, _ := instances.writablePod(naming.ContainerDatabase)
+	if pod == nil {
+		return reconcile.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+	exec := r.postgresExecutor(cluster, pod, nil)
+
+	err := bluegreen.DropSubscriptions(ctx, exec, name)
+	if err == nil {
+		err = bluegreen.RemoveFromPostgreSQL(ctx, exec, name)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error(err, "blue/green cleanup failed")
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, "BlueGreenFailed",
+			"Unable to remove replication objects")
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	result, err := r.cleanupBlueGreenSource(ctx, cluster)
+	if err == nil && result == nil {
+		cluster.Status.BlueGreen = nil
+		return reconcile.Result{}, nil
+	}
+	if result == nil {
+		result = &reconcile.Result{}
+	}
+	return *result, err
+}
+
+// cleanupBlueGreenSource removes the replication slots, publications, and role
+// of cluster from the blue cluster of a switch that stopped before cutover,
+// and allows logins to blue again. It returns (nil, nil) when there is nothing
+// left to remove.
+func (r *Reconciler) cleanupBlueGreenSource(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (*reconcile.Result, error) {
+	status := cluster.Status.BlueGreen
+	if status == nil || status.Source == "" || status.Phase == bluegreen.PhaseCutOver {
+		return nil, nil
+	}
+
+	source := &v1beta1.PostgresCluster{}
+	source.Namespace, source.Name = cluster.Namespace, status.Source
+	err := errors.WithStack(r.Client.Get(ctx, client.ObjectKeyFromObject(source), source))
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sourceInstances, err := r.observeInstances(ctx, source.DeepCopy())
+	if err != nil {
+		return nil, err
+	}
+	sourcePod, _ := sourceInstances.writablePod(naming.ContainerDatabase)
+	if sourcePod == nil {
+		return &reconcile.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+
+	name := bluegreen.Name(cluster)
+	sourceExec := r.postgresExecutor(source, sourcePod, nil)
+
+	err = bluegreen.DropSlots(ctx, sourceExec, name)
+	if err == nil {
+		err = bluegreen.RemoveFromPostgreSQL(ctx, sourceExec, name)
+	}
+	if err == nil {
+		err = bluegreen.Unfence(ctx, sourceExec, status.FencedRoles)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error(err, "blue/green cleanup failed", "source", source.Name)
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, "BlueGreenFailed",
+			"Unable to remove replication objects from "+source.Name)
+		return &reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	status.Source, status.FencedRoles = "", nil
+	r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "BlueGreenAbandoned",
+		"Removed replication objects from PostgresCluster %q", source.Name)
+	return nil, nil
+}
+
+// copyBlueGreenSchema copies the schema of database from the PostgreSQL
+// instance in sourcePod to the one in pod. Statements that fail are logged and
+// skipped, like objects that already exist.
+func (r *Reconciler) copyBlueGreenSchema(
+	ctx context.Context, sourcePod, pod *corev1.Pod, database string,
+) error {
+	var schema, stdout, stderr bytes.Buffer
+	err := errors.WithStack(r.PodExec(ctx, sourcePod.Namespace, sourcePod.Name,
+		naming.ContainerDatabase, nil, &schema, &stderr, bluegreen.DumpCommand(database)...))
+
+	if err == nil {
+		stderr.Reset()
+		err = errors.WithStack(r.PodExec(ctx, pod.Namespace, pod.Name,
+			naming.ContainerDatabase, &schema, &stdout, &stderr,
+			"bash", "-ceu", "--", `PGDATABASE="$1" exec psql -Xwq --file=-`, "-", database))
+	}
+
+	logging.FromContext(ctx).V(1).Info("copied blue/green schema",
+		"database", database, "stderr", stderr.String())
+
+	return err
+}
+
+// blueGreenReplacement returns the PostgresCluster that has taken over the
+// Services of cluster through spec.blueGreen, if any.
+func (r *Reconciler) blueGreenReplacement(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (*v1beta1.PostgresCluster, error) {
+	clusters := &v1beta1.PostgresClusterList{}
+	if err := errors.WithStack(r.Client.List(ctx, clusters,
+		client.InNamespace(cluster.Namespace))); err != nil {
+		return nil, err
+	}
+
+	for i := range clusters.Items {
+		green := &clusters.Items[i]
+		if green.Name != cluster.Name &&
+			bluegreen.Enabled(green) && green.Spec.BlueGreen.Source == cluster.Name &&
+			green.Status.BlueGreen != nil &&
+			green.Status.BlueGreen.Phase == bluegreen.PhaseCutOver {
+			return green, nil
+		}
+	}
+	return nil, nil
+}
+
+// blueGreenUserSecret returns a Secret with the password and verifier of
+// userName in the cluster that cluster replaces. It returns nil when blue has
+// no Secret for userName.
+func (r *Reconciler) blueGreenUserSecret(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, userName string,
+) (*corev1.Secret, error) {
+	blue := &corev1.Secret{ObjectMeta: naming.PostgresUserSecret(bluegreen.Source(cluster), userName)}
+	err := errors.WithStack(r.Client.Get(ctx, client.ObjectKeyFromObject(blue), blue))
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy only the credentials; everything else describes blue.
+	return &corev1.Secret{Data: map[string][]byte{
+		"password": blue.Data["password"],
+		"verifier": blue.Data["verifier"],
+	}}, nil
+}
@@ -0,0 +1,141 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/pki"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// ConditionCertificateExpiring is the type used in a condition to indicate
+// that a certificate in a custom TLS Secret expires soon. The operator does
+// not renew these. Its message lists the Secrets and when they expire.
+const ConditionCertificateExpiring = "CertificateExpiring"
+
+// EventCertificateExpiring is the event reason used when a certificate in a
+// custom TLS Secret expires soon.
+const EventCertificateExpiring = "CertificateExpiring"
+
+// certificateExpiryWarning is how long before a certificate expires that
+// ConditionCertificateExpiring becomes true.
+const certificateExpiryWarning = 14 * 24 * time.Hour
+
+// customCertificates returns the custom TLS Secrets of cluster, which the
+// operator does not renew, keyed by the field that names them.
+func customCertificates(cluster *v1beta1.PostgresCluster) map[string]*corev1.SecretProjection {
+	secrets := make(map[string]*corev1.SecretProjection)
+	if cluster.Spec.CustomTLSSecret != nil {
+		secrets["customTLSSecret"] = cluster.Spec.CustomTLSSecret
+	}
+	if cluster.Spec.CustomReplicationClientTLSSecret != nil {
+		secrets["customReplicationTLSSecret"] = cluster.Spec.CustomReplicationClientTLSSecret
+	}
+	if cluster.Spec.Proxy != nil && cluster.Spec.Proxy.PGBouncer != nil &&
+		cluster.Spec.Proxy.PGBouncer.CustomTLSSecret != nil {
+		secrets["proxy.pgBouncer.customTLSSecret"] = cluster.Spec.Proxy.PGBouncer.CustomTLSSecret
+	}
+	return secrets
+}
+
+// projectedCertificate returns the certificate that projection puts at the
+// "tls.crt" path of a volume.
+func projectedCertificate(projection *corev1.SecretProjection, secret *corev1.Secret) []byte {
+	key := clusterCertFile
+	for _, item := range projection.Items {
+		if item.Path == clusterCertFile {
+			key = item.Key
+		}
+	}
+	return secret.Data[key]
+}
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+// reconcileCertificateExpiry reports in the status of cluster whether any
+// certificate in its custom TLS Secrets expires within certificateExpiryWarning.
+// Certificates that cannot be read are logged and not reported.
+func (r *Reconciler) reconcileCertificateExpiry(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) {
+	now := time.Now()
+	var expiring []string
+
+	for field, projection := range customCertificates(cluster) {
+		secret := &corev1.Secret{}
+		err := r.Client.Get(ctx, client.ObjectKey{
+			Namespace: cluster.Namespace, Name: projection.Name,
+		}, secret)
+
+		var certificate *x509.Certificate
+		if err == nil {
+			var parsed *pki.Certificate
+			parsed, err = pki.ParseCertificate(projectedCertificate(projection, secret))
+			if err == nil {
+				certificate, err = x509.ParseCertificate(parsed.Certificate)
+			}
+		}
+		if err != nil {
+			logging.FromContext(ctx).V(1).Info("unable to read custom certificate",
+				"field", field, "secret", projection.Name, "error", err.Error())
+			continue
+		}
+
+		if certificate.NotAfter.Sub(now) < certificateExpiryWarning {
+			expiring = append(expiring, fmt.Sprintf("Secret %q of %s expires %s",
+				projection.Name, field, certificate.NotAfter.UTC().Format(time.RFC3339)))
+		}
+	}
+	sort.Strings(expiring)
+
+	if len(expiring) == 0 {
+		if meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionCertificateExpiring) {
+			meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+				ObservedGeneration: cluster.GetGeneration(),
+				Type:               ConditionCertificateExpiring,
+				Status:             metav1.ConditionFalse,
+				Reason:             "CertificatesValid",
+				Message:            "Custom certificates are not near expiration",
+			})
+		}
+		return
+	}
+
+	message := "Renew custom certificates: " + strings.Join(expiring, "; ")
+
+	if !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionCertificateExpiring) {
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, EventCertificateExpiring, message)
+	}
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		ObservedGeneration: cluster.GetGeneration(),
+		Type:               ConditionCertificateExpiring,
+		Status:             metav1.ConditionTrue,
+		Reason:             EventCertificateExpiring,
+		Message:            message,
+	})
+}
@@ -0,0 +1,105 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestReconcileCertificateExpiry(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	certificate := func(notAfter time.Time) []byte {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NilError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "hippo"},
+			NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+			NotAfter:     notAfter,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		assert.NilError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+
+	secret := &corev1.Secret{}
+	secret.Namespace, secret.Name = "ns1", "custom-tls"
+	secret.Data = map[string][]byte{"server.crt": certificate(time.Now().Add(72 * time.Hour))}
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.CustomTLSSecret = &corev1.SecretProjection{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "custom-tls"},
+		Items:                []corev1.KeyToPath{{Key: "server.crt", Path: "tls.crt"}},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	cc := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(secret).Build()
+	r := &Reconciler{Client: cc, Recorder: recorder}
+
+	r.reconcileCertificateExpiry(ctx, cluster)
+
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionCertificateExpiring)
+	assert.Assert(t, condition != nil)
+	assert.Assert(t, strings.Contains(condition.Message, `Secret "custom-tls" of customTLSSecret`),
+		"got %q", condition.Message)
+	assert.Assert(t, strings.Contains(<-recorder.Events, EventCertificateExpiring))
+
+	// The event is recorded only once.
+	r.reconcileCertificateExpiry(ctx, cluster)
+	assert.Equal(t, len(recorder.Events), 0)
+
+	// The condition resolves once the certificate is renewed.
+	secret.Data["server.crt"] = certificate(time.Now().Add(90 * 24 * time.Hour))
+	assert.NilError(t, cc.Update(ctx, secret))
+
+	r.reconcileCertificateExpiry(ctx, cluster)
+	assert.Assert(t, !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionCertificateExpiring))
+
+	t.Run("Unreadable", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Status.Conditions = nil
+		cluster.Spec.CustomReplicationClientTLSSecret = &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "missing"},
+		}
+		r.reconcileCertificateExpiry(ctx, cluster)
+		assert.Assert(t, meta.FindStatusCondition(cluster.Status.Conditions, ConditionCertificateExpiring) == nil)
+	})
+}
@@ -24,9 +24,12 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/crunchydata/postgres-operator/internal/bluegreen"
 	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/patroni"
+	"github.com/crunchydata/postgres-operator/internal/pgbackrest"
 	"github.com/crunchydata/postgres-operator/internal/postgres"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -54,6 +57,10 @@ func (r *Reconciler) reconcileClusterConfigMap(
 		err = patroni.ClusterConfigMap(ctx, cluster, pgHBAs, pgParameters,
 			clusterConfigMap)
 	}
+	if err == nil && logShipperEnabled(cluster) {
+		addLogShipperToConfigMap(cluster, postgres.LogVolumeMount(), clusterConfigMap,
+			logShipperInput{Component: "postgres", Path: postgres.LogFilePattern(cluster)})
+	}
 	if err == nil {
 		err = errors.WithStack(r.apply(ctx, clusterConfigMap))
 	}
@@ -141,6 +148,17 @@ func (r *Reconciler) generateClusterPrimaryService(
 	service.Spec.ClusterIP = corev1.ClusterIPNone
 	service.Spec.Selector = nil
 
+	// When selecting by label, let Kubernetes manage the Endpoints of the
+	// Pod that Patroni labels as its leader. The Service stays headless so
+	// that switching between modes does not change its immutable ClusterIP.
+	// See Reconciler.reconcileInstanceRoleLabels.
+	if primaryServiceSelectsLabel(cluster) {
+		service.Spec.Selector = map[string]string{
+			naming.LabelCluster: cluster.Name,
+			naming.LabelRole:    naming.RolePatroniLeader,
+		}
+	}
+
 	service.Spec.Ports = []corev1.ServicePort{{
 		Name:       naming.PortPostgreSQL,
 		Port:       *cluster.Spec.Port,
@@ -162,6 +180,10 @@ func (r *Reconciler) generateClusterPrimaryService(
 		}
 	}
 
+	if service.Spec.Selector != nil {
+		return service, nil, err
+	}
+
 	// Resolve to the ClusterIP for which Patroni has configured the Endpoints.
 	endpoints.Subsets = []corev1.EndpointSubset{{
 		Addresses: []corev1.EndpointAddress{{IP: leader.Spec.ClusterIP}},
@@ -195,15 +217,35 @@ func (r *Reconciler) reconcileClusterPrimaryService(
 ) (*corev1.Service, error) {
 	service, endpoints, err := r.generateClusterPrimaryService(cluster, leader)
 
+	// After a blue/green switch, select the primary of the cluster that
+	// replaced this one and let Kubernetes manage the Endpoints.
 	if err == nil {
-		err = errors.WithStack(r.apply(ctx, service))
+		var green *v1beta1.PostgresCluster
+		if green, err = r.blueGreenReplacement(ctx, cluster); err == nil && green != nil {
+			service.Spec.Selector = map[string]string{
+				naming.LabelCluster: green.Name,
+				naming.LabelRole:    naming.RolePatroniLeader,
+			}
+			endpoints = nil
+		}
 	}
+
 	if err == nil {
+		err = errors.WithStack(r.apply(ctx, service))
+	}
+	if err == nil && endpoints != nil {
 		err = errors.WithStack(r.apply(ctx, endpoints))
 	}
 	return service, err
 }
 
+// primaryServiceSelectsLabel returns whether or not the primary Service of
+// cluster should select Pods by their Patroni role label.
+func primaryServiceSelectsLabel(cluster *v1beta1.PostgresCluster) bool {
+	return cluster.Spec.PrimaryService != nil &&
+		cluster.Spec.PrimaryService.SelectorMode == "patroniLabel"
+}
+
 // generateClusterReplicaService returns a v1.Service that exposes PostgreSQL
 // replica instances.
 func (r *Reconciler) generateClusterReplicaService(
@@ -260,6 +302,173 @@ func (r *Reconciler) reconcileClusterReplicaService(
 	return err
 }
 
+// generateClusterAnalyticsService returns a v1.Service that exposes the
+// PostgreSQL replicas of the "analytics" workload. It returns false when that
+// Service is not specified.
+func (r *Reconciler) generateClusterAnalyticsService(
+	cluster *v1beta1.PostgresCluster) (*corev1.Service, bool, error,
+) {
+	service := &corev1.Service{ObjectMeta: naming.ClusterAnalyticsService(cluster)}
+	service.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Service"))
+
+	if cluster.Spec.AnalyticsService == nil {
+		return service, false, nil
+	}
+
+	service.Annotations = naming.Merge(
+		cluster.Spec.Metadata.GetAnnotationsOrNil())
+	service.Labels = naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster:  cluster.Name,
+			naming.LabelRole:     naming.RoleReplica,
+			naming.LabelWorkload: naming.WorkloadAnalytics,
+		})
+
+	// Select Pods with the Patroni replica role whose instance set has the
+	// "analytics" workload tag. Replicas of other sets, such as those that
+	// are synchronous, are left out.
+	service.Spec.Type = corev1.ServiceType(cluster.Spec.AnalyticsService.Type)
+	service.Spec.Selector = map[string]string{
+		naming.LabelCluster:  cluster.Name,
+		naming.LabelRole:     naming.RolePatroniReplica,
+		naming.LabelWorkload: naming.WorkloadAnalytics,
+	}
+
+	// The TargetPort must be the name (not the number) of the PostgreSQL
+	// ContainerPort. This name allows the port number to differ between Pods,
+	// which can happen during a rolling update.
+	service.Spec.Ports = []corev1.ServicePort{{
+		Name:       naming.PortPostgreSQL,
+		Port:       *cluster.Spec.Port,
+		Protocol:   corev1.ProtocolTCP,
+		TargetPort: intstr.FromString(naming.PortPostgreSQL),
+	}}
+
+	err := errors.WithStack(r.setControllerReference(cluster, service))
+
+	return service, true, err
+}
+
+// +kubebuilder:rbac:groups="",resources="services",verbs={get}
+// +kubebuilder:rbac:groups="",resources="services",verbs={create,delete,patch}
+
+// reconcileClusterAnalyticsService writes the Service that exposes PostgreSQL
+// replicas of the "analytics" workload, or deletes it when it is not specified.
+func (r *Reconciler) reconcileClusterAnalyticsService(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) error {
+	service, specified, err := r.generateClusterAnalyticsService(cluster)
+
+	if err == nil && !specified {
+		// Check the client cache first using Get.
+		key := client.ObjectKeyFromObject(service)
+		err := errors.WithStack(r.Client.Get(ctx, key, service))
+		if err == nil {
+			err = errors.WithStack(r.deleteControlled(ctx, cluster, service))
+		}
+		return client.IgnoreNotFound(err)
+	}
+
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, service))
+	}
+	return err
+}
+
+// generateInstanceSetService returns a v1.Service that resolves to the ready
+// PostgreSQL instances of set.
+func (r *Reconciler) generateInstanceSetService(
+	cluster *v1beta1.PostgresCluster, set *v1beta1.PostgresInstanceSetSpec,
+) (*corev1.Service, error) {
+	service := &corev1.Service{ObjectMeta: naming.InstanceSetService(cluster, set)}
+	service.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Service"))
+
+	service.Annotations = naming.Merge(
+		cluster.Spec.Metadata.GetAnnotationsOrNil(),
+		set.Metadata.GetAnnotationsOrNil())
+	service.Labels = naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		set.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster:     cluster.Name,
+			naming.LabelInstanceSet: set.Name,
+		})
+
+	// Allocate no IP address (headless) and select the ready Pods of the set.
+	// The DNS name of the Service resolves to the address of each one so that
+	// clients doing their own routing can enumerate them.
+	// - https://docs.k8s.io/concepts/services-networking/service/#headless-services
+	service.Spec.ClusterIP = corev1.ClusterIPNone
+	service.Spec.Selector = map[string]string{
+		naming.LabelCluster:     cluster.Name,
+		naming.LabelInstanceSet: set.Name,
+	}
+
+	// The TargetPort must be the name (not the number) of the PostgreSQL
+	// ContainerPort. This name allows the port number to differ between Pods,
+	// which can happen during a rolling update.
+	service.Spec.Ports = []corev1.ServicePort{{
+		Name:       naming.PortPostgreSQL,
+		Port:       *cluster.Spec.Port,
+		Protocol:   corev1.ProtocolTCP,
+		TargetPort: intstr.FromString(naming.PortPostgreSQL),
+	}}
+
+	err := errors.WithStack(r.setControllerReference(cluster, service))
+
+	return service, err
+}
+
+// +kubebuilder:rbac:groups="",resources="services",verbs={list}
+// +kubebuilder:rbac:groups="",resources="services",verbs={create,delete,patch}
+
+// reconcileInstanceSetServices writes a headless Service for every instance
+// set of cluster and deletes those of instance sets that no longer exist.
+func (r *Reconciler) reconcileInstanceSetServices(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) error {
+	existing := &corev1.ServiceList{}
+	selector, err := naming.AsSelector(metav1.LabelSelector{
+		MatchLabels: map[string]string{naming.LabelCluster: cluster.Name},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: naming.LabelInstanceSet, Operator: metav1.LabelSelectorOpExists},
+		},
+	})
+	if err == nil {
+		err = errors.WithStack(
+			r.Client.List(ctx, existing,
+				client.InNamespace(cluster.Namespace),
+				client.MatchingLabelsSelector{Selector: selector},
+			))
+	}
+
+	specified := make(map[string]bool, len(cluster.Spec.InstanceSets))
+	for i := range cluster.Spec.InstanceSets {
+		specified[cluster.Spec.InstanceSets[i].Name] = true
+	}
+
+	for i := range existing.Items {
+		if service := &existing.Items[i]; err == nil &&
+			!specified[service.Labels[naming.LabelInstanceSet]] {
+			err = errors.WithStack(client.IgnoreNotFound(
+				r.deleteControlled(ctx, cluster, service)))
+		}
+	}
+
+	for i := range cluster.Spec.InstanceSets {
+		var service *corev1.Service
+		if err == nil {
+			service, err = r.generateInstanceSetService(cluster, &cluster.Spec.InstanceSets[i])
+		}
+		if err == nil {
+			err = errors.WithStack(r.apply(ctx, service))
+		}
+	}
+
+	return err
+}
+
 // reconcileDataSource is responsible for reconciling the data source for a PostgreSQL cluster.
 // This involves ensuring the PostgreSQL data directory for the cluster is properly populated
 // prior to bootstrapping the cluster, specifically according to any data source configured in the
@@ -287,7 +496,8 @@ func (r *Reconciler) reconcileDataSource(ctx context.Context,
 
 	// determine if the user wants to initialize the PG data directory
 	postgresDataInitRequested := cluster.Spec.DataSource != nil &&
-		cluster.Spec.DataSource.PostgresCluster != nil
+		(cluster.Spec.DataSource.PostgresCluster != nil ||
+			cluster.Spec.DataSource.PGBackRest != nil)
 
 	// determine if the user has requested an in-place restore
 	restoreID := cluster.GetAnnotations()[naming.PGBackRestRestore]
@@ -300,6 +510,8 @@ func (r *Reconciler) reconcileDataSource(ctx context.Context,
 	// in place (and therefore recreating the data directory).  If the user hasn't requested
 	// PG data initialization or an in-place restore, then simply return.
 	var dataSource *v1beta1.PostgresClusterDataSource
+	var cloudDataSource *v1beta1.PGBackRestDataSource
+	var blueGreenRestore bool
 	switch {
 	case restoreInPlaceRequested:
 		dataSource = cluster.Spec.Backups.PGBackRest.Restore.PostgresClusterDataSource
@@ -308,6 +520,19 @@ func (r *Reconciler) reconcileDataSource(ctx context.Context,
 		// restore ID for bootstrap
 		restoreID = "~pgo-bootstrap-" + cluster.GetName()
 		dataSource = cluster.Spec.DataSource.PostgresCluster
+		if dataSource == nil {
+			cloudDataSource = cluster.Spec.DataSource.PGBackRest
+			dataSource = restoreDataSource(cloudDataSource)
+		}
+
+		// A blue/green switch recovers to the position in the source where
+		// logical replication starts. See Reconciler.reconcileBlueGreen.
+		if blueGreenRestore = bluegreen.Restores(cluster); blueGreenRestore &&
+			cluster.Status.BlueGreen != nil && cluster.Status.BlueGreen.RestoreLSN != "" {
+			dataSource = dataSource.DeepCopy()
+			dataSource.Options = append(dataSource.Options,
+				bluegreen.RestoreOptions(cluster.Status.BlueGreen.RestoreLSN)...)
+		}
 	default:
 		return false, nil
 	}
@@ -342,6 +567,9 @@ func (r *Reconciler) reconcileDataSource(ctx context.Context,
 	// restore Job exists, determine if the config has changed
 	configs := []string{dataSource.ClusterName, dataSource.RepoName}
 	configs = append(configs, dataSource.Options...)
+	if cloudDataSource != nil {
+		configs = append(configs, cloudDataSource.Stanza)
+	}
 	configHash, err := hashFunc(configs)
 	if err != nil {
 		return false, errors.WithStack(err)
@@ -373,11 +601,53 @@ func (r *Reconciler) reconcileDataSource(ctx context.Context,
 		return false, nil
 	}
 
+	// wait until the position of a blue/green restore is known
+	if blueGreenRestore &&
+		(cluster.Status.BlueGreen == nil || cluster.Status.BlueGreen.RestoreLSN == "") {
+		return true, nil
+	}
+
 	// proceed with initializing the PG data directory if not already initialized
 	if err := r.reconcilePostgresClusterDataSource(ctx, cluster, dataSource,
-		configHash, clusterVolumes); err != nil {
+		cloudDataSource, configHash, clusterVolumes); err != nil {
 		return true, err
 	}
 	// return early until the PG data directory is initialized
 	return true, nil
 }
+
+// reconcileInitializedCondition sets the Initialized condition of cluster to
+// true once PostgreSQL has bootstrapped and, when there are backups, the
+// backup from which replicas are created is complete. The condition stays true
+// after that, even during restores, so that tools can wait for it once before
+// they connect and change schemas.
+func (r *Reconciler) reconcileInitializedCondition(cluster *v1beta1.PostgresCluster) {
+	if meta.IsStatusConditionTrue(cluster.Status.Conditions, v1beta1.Initialized) {
+		return
+	}
+
+	condition := metav1.Condition{
+		ObservedGeneration: cluster.GetGeneration(),
+		Type:               v1beta1.Initialized,
+		Status:             metav1.ConditionFalse,
+	}
+
+	// A standby cluster never takes backups.
+	standby := cluster.StandbyEnabled()
+
+	switch {
+	case !patroni.ClusterBootstrapped(cluster):
+		condition.Reason = "Bootstrapping"
+		condition.Message = "PostgreSQL has not bootstrapped"
+	case pgbackrest.BackupsEnabled(cluster) && !standby &&
+		!meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionReplicaCreate):
+		condition.Reason = "ReplicaBackupPending"
+		condition.Message = "The backup for creating replicas is not complete"
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Initialized"
+		condition.Message = "PostgreSQL has bootstrapped"
+	}
+
+	meta.SetStatusCondition(&cluster.Status.Conditions, condition)
+}
@@ -32,13 +32,16 @@ import (
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -802,6 +805,23 @@ subsets:
 		})
 		assert.Equal(t, service.Spec.ExternalName, "some.host")
 	})
+
+	t.Run("PatroniLabel", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.PrimaryService = &v1beta1.PrimaryServiceSpec{
+			SelectorMode: "patroniLabel",
+		}
+
+		service, endpoints, err := reconciler.generateClusterPrimaryService(cluster, leader)
+		assert.NilError(t, err)
+		assert.Assert(t, endpoints == nil, "expected Kubernetes to manage Endpoints")
+
+		assert.Equal(t, service.Spec.ClusterIP, "None")
+		assert.Assert(t, marshalMatches(service.Spec.Selector, `
+postgres-operator.crunchydata.com/cluster: pg5
+postgres-operator.crunchydata.com/role: master
+		`))
+	})
 }
 
 func TestReconcileClusterPrimaryService(t *testing.T) {
@@ -906,3 +926,160 @@ postgres-operator.crunchydata.com/role: replica
 		`))
 	})
 }
+
+func TestGenerateClusterAnalyticsService(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace = "ns1"
+	cluster.Name = "pg2"
+	cluster.Spec.Port = initialize.Int32(9876)
+
+	service, specified, err := reconciler.generateClusterAnalyticsService(cluster)
+	assert.NilError(t, err)
+	assert.Assert(t, !specified)
+	assert.Equal(t, service.Name, "pg2-analytics")
+
+	cluster.Spec.AnalyticsService = &v1beta1.ServiceSpec{Type: "LoadBalancer"}
+
+	service, specified, err = reconciler.generateClusterAnalyticsService(cluster)
+	assert.NilError(t, err)
+	assert.Assert(t, specified)
+
+	assert.Assert(t, marshalMatches(service.ObjectMeta, `
+creationTimestamp: null
+labels:
+  postgres-operator.crunchydata.com/cluster: pg2
+  postgres-operator.crunchydata.com/role: replica
+  postgres-operator.crunchydata.com/workload: analytics
+name: pg2-analytics
+namespace: ns1
+ownerReferences:
+- apiVersion: postgres-operator.crunchydata.com/v1beta1
+  blockOwnerDeletion: true
+  controller: true
+  kind: PostgresCluster
+  name: pg2
+  uid: ""
+	`))
+	assert.Assert(t, marshalMatches(service.Spec, `
+ports:
+- name: postgres
+  port: 9876
+  protocol: TCP
+  targetPort: postgres
+selector:
+  postgres-operator.crunchydata.com/cluster: pg2
+  postgres-operator.crunchydata.com/role: replica
+  postgres-operator.crunchydata.com/workload: analytics
+type: LoadBalancer
+	`))
+}
+
+func TestGenerateInstanceSetService(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace = "ns1"
+	cluster.Name = "pg2"
+	cluster.Spec.Port = initialize.Int32(9876)
+
+	set := &v1beta1.PostgresInstanceSetSpec{Name: "one"}
+	set.Metadata = &v1beta1.Metadata{Labels: map[string]string{"set": "label"}}
+
+	service, err := reconciler.generateInstanceSetService(cluster, set)
+	assert.NilError(t, err)
+
+	assert.Assert(t, marshalMatches(service.ObjectMeta, `
+creationTimestamp: null
+labels:
+  postgres-operator.crunchydata.com/cluster: pg2
+  postgres-operator.crunchydata.com/instance-set: one
+  set: label
+name: pg2-one-members
+namespace: ns1
+ownerReferences:
+- apiVersion: postgres-operator.crunchydata.com/v1beta1
+  blockOwnerDeletion: true
+  controller: true
+  kind: PostgresCluster
+  name: pg2
+  uid: ""
+	`))
+	assert.Assert(t, marshalMatches(service.Spec, `
+clusterIP: None
+ports:
+- name: postgres
+  port: 9876
+  protocol: TCP
+  targetPort: postgres
+selector:
+  postgres-operator.crunchydata.com/cluster: pg2
+  postgres-operator.crunchydata.com/instance-set: one
+	`))
+}
+
+func TestReconcileInitializedCondition(t *testing.T) {
+	reconciler := &Reconciler{}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Generation = 2
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{Name: "repo1"}}
+
+	reconciler.reconcileInitializedCondition(cluster)
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, v1beta1.Initialized)
+	assert.Assert(t, condition != nil)
+	assert.Equal(t, condition.Status, metav1.ConditionFalse)
+	assert.Equal(t, condition.Reason, "Bootstrapping")
+	assert.Equal(t, condition.ObservedGeneration, int64(2))
+
+	// Bootstrapped but without the replica backup.
+	cluster.Status.Patroni = &v1beta1.PatroniStatus{SystemIdentifier: "123"}
+	reconciler.reconcileInitializedCondition(cluster)
+	condition = meta.FindStatusCondition(cluster.Status.Conditions, v1beta1.Initialized)
+	assert.Equal(t, condition.Status, metav1.ConditionFalse)
+	assert.Equal(t, condition.Reason, "ReplicaBackupPending")
+
+	t.Run("Standby", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Standby = &v1beta1.PostgresStandbySpec{Enabled: true, RepoName: "repo1"}
+
+		reconciler.reconcileInitializedCondition(cluster)
+		assert.Assert(t, meta.IsStatusConditionTrue(cluster.Status.Conditions, v1beta1.Initialized))
+	})
+
+	t.Run("NoBackups", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Backups.PGBackRest.Repos = nil
+
+		reconciler.reconcileInitializedCondition(cluster)
+		assert.Assert(t, meta.IsStatusConditionTrue(cluster.Status.Conditions, v1beta1.Initialized))
+	})
+
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		Type: ConditionReplicaCreate, Status: metav1.ConditionTrue, Reason: "RepoBackupComplete",
+	})
+	reconciler.reconcileInitializedCondition(cluster)
+	condition = meta.FindStatusCondition(cluster.Status.Conditions, v1beta1.Initialized)
+	assert.Equal(t, condition.Status, metav1.ConditionTrue)
+	assert.Equal(t, condition.Reason, "Initialized")
+	transitioned := condition.LastTransitionTime
+
+	// The condition does not change after it is true.
+	cluster.Generation = 3
+	cluster.Status.Patroni.SystemIdentifier = ""
+	meta.RemoveStatusCondition(&cluster.Status.Conditions, ConditionReplicaCreate)
+	reconciler.reconcileInitializedCondition(cluster)
+	condition = meta.FindStatusCondition(cluster.Status.Conditions, v1beta1.Initialized)
+	assert.Equal(t, condition.Status, metav1.ConditionTrue)
+	assert.Equal(t, condition.ObservedGeneration, int64(2))
+	assert.Equal(t, condition.LastTransitionTime, transitioned)
+}
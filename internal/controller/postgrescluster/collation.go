@@ -0,0 +1,108 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// ConditionCollationVersionMismatch is the type used in a condition to
+// indicate that collations in some databases were defined by a different
+// version of the collation library than PostgreSQL now uses. Its message
+// lists those databases.
+const ConditionCollationVersionMismatch = "CollationVersionMismatch"
+
+// EventCollationVersionMismatch is the event reason used when collation
+// versions in some databases no longer match the collation library.
+const EventCollationVersionMismatch = "CollationVersionMismatch"
+
+// +kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+
+// reconcileCollationVersions reports in the status of cluster whether any
+// database has collations from a different version of the collation library,
+// e.g. after the PostgreSQL image changes. It checks once for each generation
+// of cluster and on every reconcile while there is a mismatch, so the
+// condition resolves once the collation versions are refreshed. Nothing is
+// reported when the check fails.
+func (r *Reconciler) reconcileCollationVersions(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) {
+	if condition := meta.FindStatusCondition(
+		cluster.Status.Conditions, ConditionCollationVersionMismatch,
+	); condition != nil &&
+		condition.Status == metav1.ConditionFalse &&
+		condition.ObservedGeneration == cluster.GetGeneration() {
+		return
+	}
+
+	pod, _ := instances.writablePod(naming.ContainerDatabase)
+	if pod == nil {
+		return
+	}
+
+	exec := func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+		return r.PodExec(ctx, pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+	}
+
+	databases, err := postgres.CollationVersionMismatches(ctx, exec, cluster.Spec.PostgresVersion)
+	if err != nil {
+		logging.FromContext(ctx).V(1).Info("unable to check collation versions",
+			"pod", pod.Name, "error", err.Error())
+		return
+	}
+
+	if len(databases) == 0 {
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			ObservedGeneration: cluster.GetGeneration(),
+			Type:               ConditionCollationVersionMismatch,
+			Status:             metav1.ConditionFalse,
+			Reason:             "CollationVersionsMatch",
+			Message:            "Collation versions match the collation library",
+		})
+		return
+	}
+
+	quoted := make([]string, len(databases))
+	for i := range databases {
+		quoted[i] = fmt.Sprintf("%q", databases[i])
+	}
+	message := fmt.Sprintf("Collation versions changed in databases %s;"+
+		" reindex affected indexes, then refresh the collation versions",
+		strings.Join(quoted, ", "))
+
+	if !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionCollationVersionMismatch) {
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, EventCollationVersionMismatch, message)
+	}
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		ObservedGeneration: cluster.GetGeneration(),
+		Type:               ConditionCollationVersionMismatch,
+		Status:             metav1.ConditionTrue,
+		Reason:             EventCollationVersionMismatch,
+		Message:            message,
+	})
+}
@@ -0,0 +1,89 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestReconcileCollationVersions(t *testing.T) {
+	ctx := context.Background()
+
+	calls, output := 0, "app\n"
+	recorder := record.NewFakeRecorder(10)
+	r := &Reconciler{
+		Recorder: recorder,
+		PodExec: func(_ context.Context, namespace, pod, container string, stdin io.Reader, stdout,
+			stderr io.Writer, command ...string) error {
+			calls++
+			_, err := io.WriteString(stdout, output)
+			return err
+		},
+	}
+
+	pod := &corev1.Pod{}
+	pod.Namespace, pod.Name = "ns1", "hippo-00-abcd-0"
+	pod.Annotations = map[string]string{"status": `{"role":"master"}`}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  naming.ContainerDatabase,
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}}
+	instances := &observedInstances{forCluster: []*Instance{{
+		Name: "hippo-00-abcd", Pods: []*corev1.Pod{pod}, Runner: &appsv1.StatefulSet{},
+	}}}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Generation = 1
+	cluster.Spec.PostgresVersion = 15
+
+	r.reconcileCollationVersions(ctx, cluster, instances)
+	assert.Equal(t, calls, 1)
+
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionCollationVersionMismatch)
+	assert.Assert(t, condition != nil)
+	assert.Assert(t, meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionCollationVersionMismatch))
+	assert.Assert(t, strings.Contains(condition.Message, `"app"`), "got %q", condition.Message)
+	assert.Assert(t, strings.Contains(<-recorder.Events, EventCollationVersionMismatch))
+
+	// The check repeats while there is a mismatch; the condition resolves
+	// once collation versions are refreshed.
+	output = ""
+	r.reconcileCollationVersions(ctx, cluster, instances)
+	assert.Equal(t, calls, 2)
+	assert.Assert(t, !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionCollationVersionMismatch))
+	assert.Equal(t, len(recorder.Events), 0)
+
+	// The check is skipped until the cluster changes.
+	r.reconcileCollationVersions(ctx, cluster, instances)
+	assert.Equal(t, calls, 2)
+
+	cluster.Generation = 2
+	r.reconcileCollationVersions(ctx, cluster, instances)
+	assert.Equal(t, calls, 3)
+}
@@ -19,8 +19,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/pkg/errors"
+	attributes "go.opentelemetry.io/otel/label"
 	"go.opentelemetry.io/otel/trace"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
@@ -28,6 +30,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -38,13 +41,20 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/crunchydata/postgres-operator/internal/audit"
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/internal/kubeapi"
 	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/pgaudit"
 	"github.com/crunchydata/postgres-operator/internal/pgbackrest"
 	"github.com/crunchydata/postgres-operator/internal/pgbouncer"
+	"github.com/crunchydata/postgres-operator/internal/pgcron"
 	"github.com/crunchydata/postgres-operator/internal/pgmonitor"
+	"github.com/crunchydata/postgres-operator/internal/pgvector"
 	"github.com/crunchydata/postgres-operator/internal/pki"
 	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/internal/updatecheck"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -56,6 +66,17 @@ const (
 	workerCount = 2
 )
 
+// NotificationEvents are the reasons of the events that are sent as
+// notifications when the operator is not configured with others.
+// See notify.NewRecorder.
+var NotificationEvents = []string{
+	EventPrimaryChanged,
+	EventBackupFailed,
+	EventRestoreComplete,
+	EventRestoreFailed,
+	EventCertificateExpiring,
+}
+
 // Reconciler holds resources for the PostgresCluster reconciler
 type Reconciler struct {
 	Client      client.Client
@@ -64,10 +85,43 @@ type Reconciler struct {
 	Tracer      trace.Tracer
 	IsOpenShift bool
 
+	// APIs are the versions of Kubernetes APIs to use. See kubeapi.DiscoverAPIs.
+	APIs kubeapi.APIs
+
+	// OperatorClass is the class of PostgresClusters this reconciler manages.
+	// See v1beta1.PostgresClusterSpec.OperatorClass.
+	OperatorClass string
+
+	// DefaultMetadata are labels and annotations added to every object of
+	// every cluster beneath the metadata of each cluster.
+	// See config.DefaultMetadata.
+	DefaultMetadata *v1beta1.Metadata
+
+	// ExecPolicy limits the commands that PodExec runs when it is not set
+	// before SetupWithManager. See config.PodExecPolicy.
+	ExecPolicy config.ExecPolicy
+
+	// UpdateCheck is the index of the latest minor releases of PostgreSQL.
+	// When nil, clusters are not compared to it. See config.UpdateCheckPolicy.
+	UpdateCheck *updatecheck.Source
+
+	// Audit is where to record the changes this reconciler makes to the
+	// objects of each cluster. Changes are seen only when Client comes from
+	// audit.NewClient. See config.AuditPolicy.
+	Audit config.Audit
+
+	// AuditActor identifies this operator in audit records.
+	AuditActor string
+
 	PodExec func(
-		namespace, pod, container string,
+		ctx context.Context, namespace, pod, container string,
 		stdin io.Reader, stdout, stderr io.Writer, command ...string,
 	) error
+
+	// operatorCertificates keeps the client certificates of postgres.OperatorUser.
+	// When nil, a certificate is issued for every command. It is set during
+	// SetupWithManager. See Reconciler.operatorCertificate.
+	operatorCertificates *certificateCache
 }
 
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
@@ -79,6 +133,7 @@ func (r *Reconciler) Reconcile(
 	ctx context.Context, request reconcile.Request) (reconcile.Result, error,
 ) {
 	ctx, span := r.Tracer.Start(ctx, "Reconcile")
+	ctx = logging.NewRequestContext(ctx)
 	log := logging.FromContext(ctx)
 	defer span.End()
 
@@ -100,10 +155,37 @@ func (r *Reconciler) Reconcile(
 		if err = client.IgnoreNotFound(err); err != nil {
 			log.Error(err, "unable to fetch PostgresCluster")
 			span.RecordError(err)
+		} else {
+			forgetReconcileErrors(request.Namespace, request.Name)
+			forgetBackupMetrics(request.Namespace, request.Name)
 		}
 		return result, err
 	}
 
+	// Send everything about this cluster to the debug stream when asked.
+	if cluster.GetAnnotations()[naming.DebugReconcile] == "true" {
+		ctx = logging.NewDebugContext(ctx, "postgrescluster", request.NamespacedName.String())
+		log = logging.FromContext(ctx)
+		span.SetAttributes(attributes.Bool("debug", true))
+		log.V(1).Info("debugging reconcile", "generation", cluster.GetGeneration())
+	}
+
+	// Record the changes made while reconciling this cluster.
+	if r.Audit.Enabled() {
+		trail := audit.NewTrail(r.AuditActor)
+		ctx = audit.NewContext(ctx, trail)
+		defer r.writeAuditTrail(ctx, cluster, trail)
+	}
+
+	// Leave clusters of other operators alone.
+	if !r.manages(cluster) {
+		log.V(1).Info("skipping cluster of another operator class",
+			"operatorClass", cluster.Spec.OperatorClass)
+		forgetReconcileErrors(cluster.Namespace, cluster.Name)
+		forgetBackupMetrics(cluster.Namespace, cluster.Name)
+		return result, nil
+	}
+
 	// Set any defaults that may not have been stored in the API. No DeepCopy
 	// is necessary because controller-runtime makes a copy before returning
 	// from its cache.
@@ -112,6 +194,7 @@ func (r *Reconciler) Reconcile(
 	if cluster.Spec.OpenShift == nil {
 		cluster.Spec.OpenShift = &r.IsOpenShift
 	}
+	cluster.Spec.Metadata = mergeDefaultMetadata(r.DefaultMetadata, cluster.Spec.Metadata)
 
 	// Keep a copy of cluster prior to any manipulations.
 	before := cluster.DeepCopy()
@@ -160,6 +243,8 @@ func (r *Reconciler) Reconcile(
 	// occurs while attempting to patch the status, while otherwise simply returning the
 	// Result and error variables that are populated while reconciling the PostgresCluster.
 	patchClusterStatus := func() (reconcile.Result, error) {
+		setReconcileErrorCondition(cluster, err)
+
 		if !equality.Semantic.DeepEqual(before.Status, cluster.Status) {
 			// NOTE(cbandy): Kubernetes prior to v1.16.10 and v1.17.6 does not track
 			// managed fields on the status subresource: https://issue.k8s.io/88901
@@ -173,15 +258,45 @@ func (r *Reconciler) Reconcile(
 		return result, err
 	}
 
+	// A standby cluster may wait to be promoted; see reconcileStandbyPromotion.
+	holdingPromotion := holdStandbyPromotion(cluster)
+
 	pgHBAs := postgres.NewHBAs()
 	pgmonitor.PostgreSQLHBAs(cluster, &pgHBAs)
 	pgbouncer.PostgreSQL(cluster, &pgHBAs)
+	postgres.ClientCertificateHBAs(cluster, &pgHBAs)
+	postgres.TLSHBAs(cluster, &pgHBAs)
+	if r.ExecPolicy.SQLOverNetwork {
+		postgres.OperatorHBAs(&pgHBAs)
+	}
 
 	pgParameters := postgres.NewParameters()
 	pgaudit.PostgreSQLParameters(&pgParameters)
+	pgcron.PostgreSQLParameters(cluster, &pgParameters)
 	pgbackrest.PostgreSQL(cluster, &pgParameters)
+	if walArchiveSacrificed(cluster) {
+		pgParameters.Mandatory.Add("archive_command", "true")
+	}
 	pgmonitor.PostgreSQLParameters(cluster, &pgParameters)
+	postgres.LoggingParameters(cluster, &pgParameters)
+	postgres.TLSParameters(cluster, &pgParameters)
+	postgres.AutoTuneParameters(cluster, &pgParameters)
+	pgvector.PostgreSQLParameters(cluster, &pgParameters)
+
+	r.reconcileMemoryGuardrails(cluster)
+	r.reconcileStartupDebug(cluster)
+	r.reconcileAuditVerification(ctx, cluster)
 
+	if err == nil {
+		// An existing Patroni cluster must stop before its data directory can be
+		// moved. Func reconcileAdoption() returns a bool indicating that the
+		// controller should return early until it has.
+		var returnEarly bool
+		returnEarly, err = r.reconcileAdoption(ctx, cluster)
+		if err != nil || returnEarly {
+			return patchClusterStatus()
+		}
+	}
 	if err == nil {
 		// Since any existing data directories must be moved prior to bootstrapping the
 		// cluster, further reconciliation will not occur until the directory move Jobs
@@ -207,6 +322,9 @@ func (r *Reconciler) Reconcile(
 	if err == nil {
 		err = updateResult(r.reconcilePatroniStatus(ctx, cluster, instances))
 	}
+	if err == nil {
+		err = updateResult(r.reconcileStandbyPromotion(ctx, cluster, instances, holdingPromotion))
+	}
 	// reconcile the Pod service before reconciling any data source in case it is necessary
 	// to start Pods during data source reconciliation that require network connections (e.g.
 	// if it is necessary to start a dedicated repo host to bootstrap a new cluster using its
@@ -214,6 +332,11 @@ func (r *Reconciler) Reconcile(
 	if err == nil {
 		clusterPodService, err = r.reconcileClusterPodService(ctx, cluster)
 	}
+	// A blue/green switch prepares its source before any restore so that the
+	// restore knows where to stop.
+	if err == nil {
+		err = updateResult(r.reconcileBlueGreen(ctx, cluster, instances))
+	}
 	// First handle reconciling any data source configured for the PostgresCluster.  This includes
 	// reconciling the data source defined to bootstrap a new cluster, as well as a reconciling
 	// a data source to perform restore in-place and re-bootstrap the cluster.
@@ -248,40 +371,102 @@ func (r *Reconciler) Reconcile(
 	if err == nil {
 		err = r.reconcileClusterReplicaService(ctx, cluster)
 	}
+	if err == nil {
+		err = r.reconcileClusterAnalyticsService(ctx, cluster)
+	}
+	if err == nil {
+		err = r.reconcileInstanceSetServices(ctx, cluster)
+	}
 	if err == nil {
 		primaryCertificate, err = r.reconcileClusterCertificate(ctx, rootCA, cluster, primaryService)
 	}
 	if err == nil {
 		instanceServiceAccount, err = r.reconcileRBACResources(ctx, cluster)
 	}
+	if err == nil {
+		err = r.reconcilePatroniAuthentication(ctx, cluster)
+	}
 	if err == nil {
 		err = r.reconcilePatroniDistributedConfiguration(ctx, cluster)
 	}
 	if err == nil {
 		err = r.reconcilePatroniDynamicConfiguration(ctx, cluster, instances, pgHBAs, pgParameters)
 	}
+	if err == nil {
+		err = r.reconcilePatroniPendingRestart(ctx, cluster, instances)
+	}
 	if err == nil {
 		monitoringSecret, err = r.reconcileMonitoringSecret(ctx, cluster)
 	}
+	if err == nil {
+		err = r.reconcileRepair(ctx, cluster, instances, clusterReplicationSecret, clusterVolumes)
+	}
 	if err == nil {
 		err = r.reconcileInstanceSets(
-			ctx, cluster, clusterConfigMap, clusterReplicationSecret,
+			ctx, cluster, clusterConfigMap, pgParameters, clusterReplicationSecret,
 			rootCA, clusterPodService, instanceServiceAccount, instances,
 			patroniLeaderService, primaryCertificate, clusterVolumes)
 	}
+	if err == nil &&
+		meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionInsufficientQuota) {
+		// ResourceQuotas and LimitRanges are not watched; check them again soon.
+		err = updateResult(reconcile.Result{RequeueAfter: time.Minute}, nil)
+	}
+	if err == nil {
+		r.reconcilePodSecurity(ctx, cluster, instances)
+	}
+	if err == nil {
+		r.reconcileCertificateExpiry(ctx, cluster)
+	}
+	if err == nil {
+		err = r.reconcileInstanceRoleLabels(ctx, cluster, instances)
+	}
+	if err == nil {
+		err = r.reconcilePatroniSwitchover(ctx, cluster, instances)
+	}
+	if err == nil {
+		err = updateResult(r.reconcileReplicaHealing(ctx, cluster, instances))
+	}
+	if err == nil {
+		err = updateResult(r.reconcileNodeFailureRecovery(ctx, cluster, instances))
+	}
+	if err == nil {
+		err = updateResult(r.reconcileVolumeUsage(ctx, cluster, instances))
+	}
+	if err == nil {
+		err = updateResult(r.reconcileResourceUsage(ctx, cluster, instances))
+	}
+	if err == nil {
+		err = updateResult(r.reconcileWALArchiveSacrifice(ctx, cluster, instances))
+	}
+	if err == nil {
+		err = updateResult(r.reconcileUpgradeAvailable(ctx, cluster, instances), nil)
+	}
 
 	if err == nil {
-		err = r.reconcilePostgresDatabases(ctx, cluster, instances)
+		err = r.reconcilePostgresDatabases(ctx, cluster, instances, rootCA)
+	}
+	if err == nil {
+		err = updateResult(r.reconcilePostgresUsers(ctx, cluster, instances, rootCA))
 	}
 	if err == nil {
-		err = r.reconcilePostgresUsers(ctx, cluster, instances)
+		err = updateResult(r.reconcileForeignServers(ctx, cluster, instances, rootCA))
+	}
+	if err == nil {
+		err = r.reconcilePostgresDatabaseInitSQL(ctx, cluster, instances)
+	}
+	if err == nil {
+		r.reconcileCollationVersions(ctx, cluster, instances)
+	}
+	if err == nil {
+		err = r.reconcileMaintenance(ctx, cluster, instances, primaryCertificate)
 	}
 
 	if err == nil {
 		err = updateResult(r.reconcilePGBackRest(ctx, cluster, instances))
 	}
 	if err == nil {
-		err = r.reconcilePGBouncer(ctx, cluster, instances, primaryCertificate, rootCA)
+		err = updateResult(r.reconcilePGBouncer(ctx, cluster, instances, primaryCertificate, rootCA))
 	}
 	if err == nil {
 		err = r.reconcilePGMonitor(ctx, cluster, instances, monitoringSecret)
@@ -289,6 +474,12 @@ func (r *Reconciler) Reconcile(
 	if err == nil {
 		err = r.reconcileDatabaseInitSQL(ctx, cluster, instances)
 	}
+	if err == nil {
+		err = updateResult(r.reconcileExport(ctx, cluster))
+	}
+	if err == nil {
+		r.reconcileInitializedCondition(cluster)
+	}
 
 	// TODO reconcile pgadmin4
 
@@ -301,6 +492,13 @@ func (r *Reconciler) Reconcile(
 	return patchClusterStatus()
 }
 
+// retained returns whether or not object should outlive its PostgresCluster.
+// Retained objects have no owner reference; PGO does not delete them.
+// See naming.Retain.
+func retained(object metav1.Object) bool {
+	return object.GetAnnotations()[naming.Retain] == "true"
+}
+
 // deleteControlled safely deletes object when it is controlled by cluster.
 func (r *Reconciler) deleteControlled(
 	ctx context.Context, cluster *v1beta1.PostgresCluster, object client.Object,
@@ -352,6 +550,26 @@ func (r *Reconciler) setOwnerReference(
 	return controllerutil.SetOwnerReference(owner, controlled, r.Client.Scheme())
 }
 
+// mergeDefaultMetadata returns the labels and annotations of defaults overridden
+// by those of metadata. It returns metadata when there are no defaults.
+func mergeDefaultMetadata(defaults, metadata *v1beta1.Metadata) *v1beta1.Metadata {
+	if defaults == nil {
+		return metadata
+	}
+	return &v1beta1.Metadata{
+		Labels: naming.Merge(
+			defaults.GetLabelsOrNil(), metadata.GetLabelsOrNil()),
+		Annotations: naming.Merge(
+			defaults.GetAnnotationsOrNil(), metadata.GetAnnotationsOrNil()),
+	}
+}
+
+// manages returns whether or not r manages cluster according to their
+// operator classes.
+func (r *Reconciler) manages(cluster *v1beta1.PostgresCluster) bool {
+	return cluster.Spec.OperatorClass == r.OperatorClass
+}
+
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=endpoints,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch
@@ -368,11 +586,20 @@ func (r *Reconciler) setOwnerReference(
 // SetupWithManager adds the PostgresCluster controller to the provided runtime manager
 func (r *Reconciler) SetupWithManager(mgr manager.Manager) error {
 	if r.PodExec == nil {
-		var err error
-		r.PodExec, err = newPodExecutor(mgr.GetConfig())
+		exec, err := newPodExecutor(mgr.GetConfig())
 		if err != nil {
 			return err
 		}
+		r.PodExec = newPodExecGuard(exec, r.ExecPolicy, r.podCluster).Exec
+	}
+	if r.operatorCertificates == nil {
+		r.operatorCertificates = new(certificateCache)
+	}
+
+	// Kubernetes serves CronJobs in one API or the other.
+	var cronjobs client.Object = &batchv1beta1.CronJob{}
+	if r.APIs.CronJobV1() {
+		cronjobs = &kubeapi.CronJob{}
 	}
 
 	return builder.ControllerManagedBy(mgr).
@@ -391,9 +618,17 @@ func (r *Reconciler) SetupWithManager(mgr manager.Manager) error {
 		Owns(&batchv1.Job{}).
 		Owns(&rbacv1.Role{}).
 		Owns(&rbacv1.RoleBinding{}).
-		Owns(&batchv1beta1.CronJob{}).
+		Owns(cronjobs).
 		Watches(&source.Kind{Type: &corev1.Pod{}}, r.watchPods()).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, r.watchClusterLabel(patroniInitialized)).
+		Watches(&source.Kind{Type: &corev1.Endpoints{}}, r.watchClusterLabel(patroniInitialized)).
+		Watches(&source.Kind{Type: &batchv1.Job{}}, r.watchClusterLabel(scheduledBackupChanged)).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, r.watchCredentialSecrets()).
+		Watches(&source.Kind{Type: &v1beta1.PostgresCluster{}}, r.watchBlueGreen()).
 		Watches(&source.Kind{Type: &appsv1.StatefulSet{}},
 			r.controllerRefHandlerFuncs()). // watch all StatefulSets
+		Watches(&source.Kind{Type: &appsv1.StatefulSet{}}, r.watchDrift("StatefulSet")).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, r.watchDrift("ConfigMap")).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, r.watchDrift("Secret")).
 		Complete(r)
 }
@@ -21,6 +21,7 @@ package postgrescluster
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 
@@ -35,10 +36,13 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/yaml"
 
@@ -497,3 +501,60 @@ spec:
 		})
 	})
 })
+
+func TestReconcileOperatorClass(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.OperatorClass = "staging"
+
+	r := &Reconciler{
+		Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(cluster).Build(),
+		Recorder: record.NewFakeRecorder(10),
+		Tracer:   otel.Tracer(t.Name()),
+		PodExec: func(context.Context, string, string, string, io.Reader, io.Writer, io.Writer, ...string) error {
+			t.Fatal("expected no exec")
+			return nil
+		},
+	}
+
+	// Clusters of another class are left alone.
+	result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(cluster)})
+	assert.NilError(t, err)
+	assert.Equal(t, result, reconcile.Result{})
+
+	stored := &v1beta1.PostgresCluster{}
+	assert.NilError(t, r.Client.Get(ctx, client.ObjectKeyFromObject(cluster), stored))
+	assert.Equal(t, len(stored.Finalizers), 0)
+	assert.Assert(t, stored.Status.Patroni == nil && len(stored.Status.Conditions) == 0)
+
+	assert.Assert(t, !r.manages(cluster))
+	r.OperatorClass = "staging"
+	assert.Assert(t, r.manages(cluster))
+}
+
+func TestMergeDefaultMetadata(t *testing.T) {
+	metadata := &v1beta1.Metadata{
+		Labels:      map[string]string{"tier": "silver"},
+		Annotations: map[string]string{"note": "cluster"},
+	}
+	assert.Assert(t, mergeDefaultMetadata(nil, metadata) == metadata)
+	assert.Assert(t, mergeDefaultMetadata(nil, nil) == nil)
+
+	defaults := &v1beta1.Metadata{
+		Labels:      map[string]string{"tier": "gold", "cost-center": "1234"},
+		Annotations: map[string]string{"owner": "dba"},
+	}
+	assert.DeepEqual(t, mergeDefaultMetadata(defaults, metadata), &v1beta1.Metadata{
+		Labels:      map[string]string{"tier": "silver", "cost-center": "1234"},
+		Annotations: map[string]string{"note": "cluster", "owner": "dba"},
+	})
+	assert.DeepEqual(t, mergeDefaultMetadata(defaults, nil), &v1beta1.Metadata{
+		Labels:      map[string]string{"tier": "gold", "cost-center": "1234"},
+		Annotations: map[string]string{"owner": "dba"},
+	})
+}
@@ -0,0 +1,204 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cronMacros are the predefined schedules understood by the CronJob controller.
+// - https://k8s.io/docs/concepts/workloads/controllers/cron-jobs/#cron-schedule-syntax
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronScheduleInZone converts schedule, a Cron schedule in the IANA time zone
+// zone, to the equivalent schedule in UTC at the moment now. The CronJob API
+// of this Kubernetes version has no time zone field and interprets schedules
+// in the time zone of the kube-controller-manager, which is usually UTC.
+//
+// Only the minute, hour, and day-of-week fields are adjusted. An error is
+// returned when the schedule cannot be expressed in UTC, such as when a
+// day-of-month would change. The result follows daylight saving time only
+// when it is calculated again after the offset of zone changes.
+func cronScheduleInZone(schedule, zone string, now time.Time) (string, error) {
+	if zone == "" {
+		return schedule, nil
+	}
+
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid time zone %q", zone)
+	}
+
+	// Go reports seconds east of UTC. Subtract that to go from local to UTC.
+	_, offset := now.In(location).Zone()
+	if offset%60 != 0 {
+		return "", errors.Errorf("time zone %q is not a whole number of minutes from UTC", zone)
+	}
+	shift := -offset / 60
+	if shift == 0 {
+		return schedule, nil
+	}
+
+	if macro, ok := cronMacros[strings.TrimSpace(schedule)]; ok {
+		schedule = macro
+	}
+	fields := strings.Fields(schedule)
+	if len(fields) != 5 {
+		return "", errors.Errorf("cannot convert schedule %q to UTC", schedule)
+	}
+	unsupported := errors.Errorf("cannot convert schedule %q from %q to UTC", schedule, zone)
+
+	// Move the minutes and determine how many hours that carries. Every minute
+	// must carry the same number of hours.
+	carry := floorDiv(shift, 60)
+	if shift%60 != 0 {
+		minutes, err := cronValues(fields[0], 0, 59)
+		if err != nil {
+			return "", err
+		}
+		for i, minute := range minutes {
+			c := floorDiv(minute+shift, 60)
+			if i > 0 && c != carry {
+				return "", unsupported
+			}
+			carry, minutes[i] = c, floorMod(minute+shift, 60)
+		}
+		fields[0] = cronList(minutes)
+	}
+
+	// A schedule that runs every hour is the same in every whole-hour offset.
+	if fields[1] == "*" {
+		return strings.Join(fields, " "), nil
+	}
+
+	// Move the hours and determine how many days that carries. Every hour
+	// must carry the same number of days.
+	hours, err := cronValues(fields[1], 0, 23)
+	if err != nil {
+		return "", err
+	}
+	days := 0
+	for i, hour := range hours {
+		d := floorDiv(hour+carry, 24)
+		if i > 0 && d != days {
+			return "", unsupported
+		}
+		days, hours[i] = d, floorMod(hour+carry, 24)
+	}
+	fields[1] = cronList(hours)
+
+	if days != 0 && fields[2] != "*" {
+		return "", unsupported
+	}
+	if days != 0 && fields[4] != "*" {
+		weekdays, err := cronValues(fields[4], 0, 7)
+		if err != nil {
+			return "", err
+		}
+		for i, weekday := range weekdays {
+			weekdays[i] = floorMod(weekday+days, 7)
+		}
+		fields[4] = cronList(weekdays)
+	}
+
+	return strings.Join(fields, " "), nil
+}
+
+// cronValues returns the numbers in a field of a Cron schedule. It understands
+// lists, ranges, and steps but not names.
+func cronValues(field string, min, max int) ([]int, error) {
+	set := make(map[int]bool)
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s < 1 {
+				return nil, errors.Errorf("invalid step in Cron field %q", field)
+			}
+			part, step = part[:i], s
+		}
+
+		low, high := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			l, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, errors.Errorf("unsupported Cron field %q", field)
+			}
+			low, high = l, l
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, errors.Errorf("unsupported Cron field %q", field)
+				}
+			} else if step > 1 {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return nil, errors.Errorf("out of range Cron field %q", field)
+		}
+
+		for v := low; v <= high; v += step {
+			set[v] = true
+		}
+	}
+
+	values := make([]int, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	sort.Ints(values)
+	return values, nil
+}
+
+// cronList formats values as a list field of a Cron schedule.
+func cronList(values []int) string {
+	sort.Ints(values)
+	result := make([]string, 0, len(values))
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			result = append(result, strconv.Itoa(v))
+		}
+	}
+	return strings.Join(result, ",")
+}
+
+// floorDiv and floorMod round toward negative infinity rather than zero.
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+func floorMod(a, b int) int {
+	return a - floorDiv(a, b)*b
+}
@@ -0,0 +1,81 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestCronScheduleInZone(t *testing.T) {
+	winter := time.Date(2021, time.January, 15, 0, 0, 0, 0, time.UTC)
+	summer := time.Date(2021, time.July, 15, 0, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		schedule, zone string
+		now            time.Time
+		expected       string
+	}{
+		{schedule: "0 2 * * *", zone: "", now: winter, expected: "0 2 * * *"},
+		{schedule: "0 2 * * *", zone: "UTC", now: winter, expected: "0 2 * * *"},
+		{schedule: "@daily", zone: "Etc/UTC", now: winter, expected: "@daily"},
+
+		// New York is five hours behind UTC in winter and four in summer.
+		{schedule: "0 2 * * *", zone: "America/New_York", now: winter, expected: "0 7 * * *"},
+		{schedule: "0 2 * * *", zone: "America/New_York", now: summer, expected: "0 6 * * *"},
+		{schedule: "@daily", zone: "America/New_York", now: winter, expected: "0 5 * * *"},
+		{schedule: "30 */6 * * *", zone: "America/New_York", now: winter, expected: "30 5,11,17,23 * * *"},
+		{schedule: "15 * * * *", zone: "America/New_York", now: winter, expected: "15 * * * *"},
+
+		// A day later in UTC moves the day of the week.
+		{schedule: "0 21 * * 0,6", zone: "America/New_York", now: winter, expected: "0 2 * * 0,1"},
+		{schedule: "0 21 * * 1-5", zone: "America/New_York", now: winter, expected: "0 2 * * 2,3,4,5,6"},
+
+		// Tokyo is nine hours ahead; a day earlier in UTC.
+		{schedule: "0 3 * * 1", zone: "Asia/Tokyo", now: winter, expected: "0 18 * * 0"},
+		{schedule: "0 3 * * *", zone: "Asia/Tokyo", now: winter, expected: "0 18 * * *"},
+
+		// Kolkata is five and a half hours ahead.
+		{schedule: "45 2 * * *", zone: "Asia/Kolkata", now: winter, expected: "15 21 * * *"},
+	} {
+		actual, err := cronScheduleInZone(tt.schedule, tt.zone, tt.now)
+		assert.NilError(t, err, "%q in %q", tt.schedule, tt.zone)
+		assert.Equal(t, actual, tt.expected, "%q in %q", tt.schedule, tt.zone)
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := cronScheduleInZone("0 2 * * *", "Mars/Olympus_Mons", winter)
+		assert.ErrorContains(t, err, "invalid time zone")
+
+		_, err = cronScheduleInZone("0 22 1 * *", "America/New_York", winter)
+		assert.ErrorContains(t, err, "cannot convert")
+
+		// 08:15 is 02:45 UTC but 08:45 is 03:15 UTC.
+		_, err = cronScheduleInZone("15,45 8 * * *", "Asia/Kolkata", winter)
+		assert.ErrorContains(t, err, "cannot convert")
+
+		_, err = cronScheduleInZone("0 1,22 * * *", "America/New_York", winter)
+		assert.ErrorContains(t, err, "cannot convert")
+
+		_, err = cronScheduleInZone("0 2 * *", "America/New_York", winter)
+		assert.ErrorContains(t, err, "cannot convert")
+
+		_, err = cronScheduleInZone("0 2 * * MON", "Asia/Tokyo", winter)
+		assert.ErrorContains(t, err, "unsupported")
+	})
+}
@@ -0,0 +1,79 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/kubeapi"
+)
+
+// CronJobs are generated as batch/v1beta1 objects. The functions below send
+// them to Kubernetes as batch/v1 objects when Kubernetes serves that API.
+
+// cronJobGroupVersion returns the API version of CronJobs in Kubernetes.
+func (r *Reconciler) cronJobGroupVersion() schema.GroupVersion {
+	if r.APIs.CronJobV1() {
+		return kubeapi.CronJobGroupVersion
+	}
+	return batchv1beta1.SchemeGroupVersion
+}
+
+// cronJobObject returns an object of the CronJob API in Kubernetes that has
+// the fields of cronjob. It returns cronjob itself or a copy of it.
+func (r *Reconciler) cronJobObject(cronjob *batchv1beta1.CronJob) client.Object {
+	if r.APIs.CronJobV1() {
+		object := &kubeapi.CronJob{CronJob: *cronjob}
+		object.SetGroupVersionKind(kubeapi.CronJobGroupVersion.WithKind("CronJob"))
+		return object
+	}
+	return cronjob
+}
+
+// applyCronJob is like apply but for CronJobs of either API version.
+func (r *Reconciler) applyCronJob(ctx context.Context, cronjob *batchv1beta1.CronJob) error {
+	object := r.cronJobObject(cronjob)
+	err := r.apply(ctx, object)
+
+	if v1, ok := object.(*kubeapi.CronJob); ok {
+		*cronjob = v1.CronJob
+		cronjob.SetGroupVersionKind(batchv1beta1.SchemeGroupVersion.WithKind("CronJob"))
+	}
+	return err
+}
+
+// listCronJobs is like client.Reader.List but for CronJobs of either API
+// version.
+func (r *Reconciler) listCronJobs(
+	ctx context.Context, list *batchv1beta1.CronJobList, opts ...client.ListOption,
+) error {
+	if r.APIs.CronJobV1() {
+		v1 := &kubeapi.CronJobList{}
+		err := r.Client.List(ctx, v1, opts...)
+
+		list.ListMeta = v1.ListMeta
+		list.Items = make([]batchv1beta1.CronJob, len(v1.Items))
+		for i := range v1.Items {
+			list.Items[i] = v1.Items[i].CronJob
+		}
+		return err
+	}
+	return r.Client.List(ctx, list, opts...)
+}
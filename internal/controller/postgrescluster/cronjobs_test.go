@@ -0,0 +1,78 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/kubeapi"
+)
+
+func TestCronJobAPI(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, kubeapi.AddCronJobToScheme(testScheme))
+
+	v1 := &kubeapi.CronJob{}
+	v1.Namespace, v1.Name = "ns1", "in-v1"
+	beta := &batchv1beta1.CronJob{}
+	beta.Namespace, beta.Name = "ns1", "in-v1beta1"
+
+	r := &Reconciler{
+		Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(v1, beta).Build(),
+	}
+
+	for _, tt := range []struct {
+		v1      bool
+		version string
+		found   string
+	}{
+		{v1: true, version: "batch/v1", found: "in-v1"},
+		{v1: false, version: "batch/v1beta1", found: "in-v1beta1"},
+	} {
+		r := *r
+		if tt.v1 {
+			r.APIs.CronJob = kubeapi.CronJobGroupVersion
+		}
+		assert.Equal(t, r.cronJobGroupVersion().String(), tt.version)
+
+		list := &batchv1beta1.CronJobList{}
+		assert.NilError(t, r.listCronJobs(ctx, list, client.InNamespace("ns1")))
+		assert.Equal(t, len(list.Items), 1)
+		assert.Equal(t, list.Items[0].Name, tt.found)
+
+		object := r.cronJobObject(&list.Items[0])
+		assert.Equal(t, object.GetName(), tt.found)
+		_, isV1 := object.(*kubeapi.CronJob)
+		assert.Equal(t, isV1, tt.v1)
+		assert.NilError(t, r.Client.Delete(ctx, object))
+	}
+
+	// Both were deleted using their own API.
+	assert.NilError(t, r.Client.List(ctx, &kubeapi.CronJobList{}))
+	remaining := &batchv1beta1.CronJobList{}
+	assert.NilError(t, r.Client.List(ctx, remaining))
+	assert.Equal(t, len(remaining.Items), 0)
+}
@@ -17,13 +17,22 @@ package postgrescluster
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/pgbackrest"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -83,12 +92,18 @@ func (r *Reconciler) handleDelete(
 	}
 
 	// The cluster is being deleted and our finalizer is still set; run our
-	// finalizer logic.
+	// finalizer logic unless the user has asked to skip it.
 
-	if result, err := r.deleteInstances(ctx, cluster); err != nil {
-		return nil, err
-	} else if result != nil {
-		return result, nil
+	if cluster.Annotations[naming.ForceDelete] == "true" {
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, EventTeardownForced,
+			"Skipping teardown and removing finalizer as requested by annotation")
+
+	} else if result, err := r.teardown(ctx, cluster); err != nil || result != nil {
+		return result, err
+
+	} else if result, err := r.cleanupBlueGreenSource(ctx, cluster); err != nil || result != nil {
+		// Green is gone, so blue must let go of what it kept for green.
+		return result, err
 	}
 
 	// Instances are stopped, now cleanup some Patroni stuff.
@@ -113,3 +128,258 @@ func (r *Reconciler) handleDelete(
 	// The caller should wait for further events or requeue upon error.
 	return &reconcile.Result{}, err
 }
+
+const (
+	// ConditionTeardownSchedulesStopped is the type used in a condition to indicate whether or
+	// not the backup schedules of a PostgresCluster being deleted are suspended
+	ConditionTeardownSchedulesStopped = "TeardownSchedulesStopped"
+
+	// ConditionTeardownFinalBackup is the type used in a condition to indicate whether or not
+	// the final backup of a PostgresCluster being deleted was successful
+	ConditionTeardownFinalBackup = "TeardownFinalBackup"
+
+	// ConditionTeardownInstancesStopped is the type used in a condition to indicate whether or
+	// not the instances of a PostgresCluster being deleted are stopped
+	ConditionTeardownInstancesStopped = "TeardownInstancesStopped"
+
+	// EventTeardownForced is the event reason utilized when teardown of a PostgresCluster is
+	// skipped because of the force-delete annotation
+	EventTeardownForced = "TeardownForced"
+)
+
+// teardown stops cluster one step at a time: it suspends backup schedules,
+// takes a final backup when one is requested, and stops instances. Progress of
+// each step is recorded in a status condition. It returns (nil, nil) when
+// every step is finished.
+func (r *Reconciler) teardown(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (*reconcile.Result, error) {
+	before := cluster.DeepCopy()
+
+	var result *reconcile.Result
+	err := r.stopBackupSchedules(ctx, cluster)
+
+	if err == nil {
+		result, err = r.reconcileFinalBackup(ctx, cluster)
+	}
+	if err == nil && result == nil {
+		result, err = r.deleteInstances(ctx, cluster)
+
+		condition := metav1.Condition{
+			ObservedGeneration: cluster.GetGeneration(),
+			Type:               ConditionTeardownInstancesStopped,
+			Status:             metav1.ConditionFalse,
+			Reason:             "Stopping",
+			Message:            "Waiting for instances to stop",
+		}
+		if err == nil && result == nil {
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = "Stopped"
+			condition.Message = "Instances are stopped"
+		}
+		meta.SetStatusCondition(&cluster.Status.Conditions, condition)
+	}
+
+	if !equality.Semantic.DeepEqual(before.Status, cluster.Status) {
+		patchErr := errors.WithStack(r.Client.Status().Patch(
+			ctx, cluster, client.MergeFrom(before), r.Owner))
+		if err == nil {
+			err = patchErr
+		}
+	}
+
+	return result, err
+}
+
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=list;patch
+
+// stopBackupSchedules suspends the pgBackRest backup CronJobs of cluster.
+// Backup Jobs that have already started continue.
+func (r *Reconciler) stopBackupSchedules(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) error {
+	cronjobs := &batchv1beta1.CronJobList{}
+	err := errors.WithStack(r.listCronJobs(ctx, cronjobs,
+		client.InNamespace(cluster.Namespace),
+		client.MatchingLabels{naming.LabelCluster: cluster.Name},
+		client.HasLabels{naming.LabelPGBackRestCronJob},
+	))
+
+	for i := range cronjobs.Items {
+		cronjob := &cronjobs.Items[i]
+		suspended := cronjob.Spec.Suspend != nil && *cronjob.Spec.Suspend
+
+		if err == nil && !suspended && metav1.IsControlledBy(cronjob, cluster) {
+			patch := client.RawPatch(client.Merge.Type(), []byte(`{"spec":{"suspend":true}}`))
+			err = errors.WithStack(r.patch(ctx, r.cronJobObject(cronjob), patch))
+		}
+	}
+
+	if err == nil {
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			ObservedGeneration: cluster.GetGeneration(),
+			Type:               ConditionTeardownSchedulesStopped,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Suspended",
+			Message:            "Backup schedules are suspended",
+		})
+	}
+	return err
+}
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=list
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=list;create;patch
+
+// reconcileFinalBackup takes a full pgBackRest backup of cluster when one is
+// requested in its teardown spec. It returns (nil, nil) when there is no such
+// request or the backup is complete. Otherwise, the caller should wait for
+// further events.
+func (r *Reconciler) reconcileFinalBackup(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (*reconcile.Result, error) {
+	if cluster.Spec.Teardown == nil || cluster.Spec.Teardown.FinalBackupRepoName == "" ||
+		!pgbackrest.BackupsEnabled(cluster) {
+		return nil, nil
+	}
+
+	repoName := cluster.Spec.Teardown.FinalBackupRepoName
+	labels := naming.Merge(cluster.Spec.Metadata.GetLabelsOrNil(),
+		cluster.Spec.Backups.PGBackRest.Metadata.GetLabelsOrNil(),
+		naming.PGBackRestBackupJobLabels(cluster.Name, repoName, naming.BackupFinal))
+	annotations := naming.Merge(cluster.Spec.Metadata.GetAnnotationsOrNil(),
+		cluster.Spec.Backups.PGBackRest.Metadata.GetAnnotationsOrNil())
+
+	setCondition := func(status metav1.ConditionStatus, reason, message string) {
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			ObservedGeneration: cluster.GetGeneration(),
+			Type:               ConditionTeardownFinalBackup,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+		})
+	}
+
+	jobs := &batchv1.JobList{}
+	if err := r.Client.List(ctx, jobs,
+		client.InNamespace(cluster.Namespace),
+		client.MatchingLabels(naming.PGBackRestBackupJobLabels(
+			cluster.Name, repoName, naming.BackupFinal)),
+	); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Report on an existing Job. A failed Job is not retried; the user can
+	// delete it to try again or annotate the cluster to skip teardown.
+	if len(jobs.Items) > 0 {
+		job := &jobs.Items[0]
+
+		switch {
+		case jobCompleted(job):
+			setCondition(metav1.ConditionTrue, "FinalBackupComplete",
+				"Final backup completed successfully")
+			return nil, nil
+		case jobFailed(job):
+			setCondition(metav1.ConditionFalse, "FinalBackupFailed", fmt.Sprintf(
+				"Final backup did not complete successfully. Delete Job %q to try again"+
+					" or set the %q annotation to skip teardown.", job.Name, naming.ForceDelete))
+		default:
+			setCondition(metav1.ConditionFalse, "FinalBackupInProgress",
+				"Waiting for the final backup to complete")
+		}
+		return &reconcile.Result{}, nil
+	}
+
+	// pgBackRest needs a running primary and a stanza to take a backup. Check
+	// again in a little while.
+	available, err := r.fullBackupAvailable(ctx, cluster, repoName)
+	if err != nil {
+		return nil, err
+	}
+	if !available {
+		setCondition(metav1.ConditionFalse, "FinalBackupUnavailable", fmt.Sprintf(
+			"Unable to take a final backup to %q without a running primary and stanza."+
+				" Set the %q annotation to skip teardown.", repoName, naming.ForceDelete))
+		return &reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	err = r.applyFullBackupJob(ctx, cluster, repoName, labels, annotations)
+	if err == nil {
+		setCondition(metav1.ConditionFalse, "FinalBackupInProgress",
+			"Waiting for the final backup to complete")
+	}
+	return &reconcile.Result{}, err
+}
+
+// fullBackupAvailable returns whether or not pgBackRest can take a backup of
+// cluster to repoName. It needs a running primary and a stanza in that repository.
+func (r *Reconciler) fullBackupAvailable(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, repoName string,
+) (bool, error) {
+	var stanzaCreated bool
+	if cluster.Status.PGBackRest != nil {
+		for _, repo := range cluster.Status.PGBackRest.Repos {
+			if repo.Name == repoName {
+				stanzaCreated = repo.StanzaCreated
+			}
+		}
+	}
+
+	pods := &corev1.PodList{}
+	selector, err := naming.AsSelector(naming.ClusterPrimary(cluster.Name))
+	if err == nil {
+		err = errors.WithStack(r.Client.List(ctx, pods,
+			client.InNamespace(cluster.Namespace),
+			client.MatchingLabelsSelector{Selector: selector},
+		))
+	}
+	if err != nil {
+		return false, err
+	}
+
+	primaryRunning := false
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == corev1.PodRunning {
+			primaryRunning = true
+		}
+	}
+
+	return stanzaCreated && primaryRunning, nil
+}
+
+// applyFullBackupJob creates a Job that takes a full pgBackRest backup of
+// cluster to repoName. The Job has labels and annotations.
+func (r *Reconciler) applyFullBackupJob(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, repoName string,
+	labels, annotations map[string]string,
+) error {
+	execSelector, containerName, err := getPGBackRestExecSelector(cluster, repoName)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// set the name of the pgbackrest config file that will be mounted to the backup Job
+	configName := pgbackrest.CMInstanceKey
+	if containerName == naming.PGBackRestRepoContainerName {
+		configName = pgbackrest.CMRepoKey
+	}
+
+	backupJob := &batchv1.Job{}
+	backupJob.ObjectMeta = naming.PGBackRestBackupJob(cluster)
+	backupJob.Labels = labels
+	backupJob.Annotations = annotations
+
+	spec, err := generateBackupJobSpecIntent(cluster, execSelector.String(), containerName,
+		repoName, naming.PGBackRestRBAC(cluster).Name, configName, labels, annotations,
+		"--type=full")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	backupJob.Spec = *spec
+
+	backupJob.SetGroupVersionKind(batchv1.SchemeGroupVersion.WithKind("Job"))
+	err = errors.WithStack(r.setControllerReference(cluster, backupJob))
+	if err == nil {
+		err = r.apply(ctx, backupJob)
+	}
+	return err
+}
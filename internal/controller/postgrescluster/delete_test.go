@@ -30,8 +30,11 @@ import (
 	"go.opentelemetry.io/otel"
 	"gotest.tools/v3/assert"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -40,11 +43,14 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/yaml"
 
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/patroni"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -146,8 +152,8 @@ func TestReconcilerHandleDelete(t *testing.T) {
 					assert.Check(t, primary != nil, "expected to find a primary in %+v", list.Items) &&
 					assert.Check(t, replica != nil, "expected to find a replica in %+v", list.Items) {
 					success, err := patroni.Executor(
-						func(_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
-							return reconciler.PodExec(replica.Namespace, replica.Name, "database", stdin, stdout, stderr, command...)
+						func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+							return reconciler.PodExec(ctx, replica.Namespace, replica.Name, "database", stdin, stdout, stderr, command...)
 						},
 					).ChangePrimaryAndWait(ctx, primary.Name, replica.Name)
 
@@ -496,3 +502,101 @@ func TestReconcilerHandleDeleteNamespace(t *testing.T) {
 		return apierrors.IsNotFound(err), client.IgnoreNotFound(err)
 	}), "expected namespace to be deleted, got status:\n%+v", namespace.Status)
 }
+
+func TestReconcilerTeardownSteps(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name, cluster.UID = "ns1", "hippo", "hippo-uid"
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{Name: "repo1"}}
+
+	owned := func(object metav1.Object) {
+		object.SetNamespace(cluster.Namespace)
+		object.SetOwnerReferences([]metav1.OwnerReference{{
+			APIVersion: v1beta1.GroupVersion.String(), Kind: "PostgresCluster",
+			Name: cluster.Name, UID: cluster.UID, Controller: initialize.Bool(true),
+		}})
+	}
+
+	t.Run("StopBackupSchedules", func(t *testing.T) {
+		cronjob := &batchv1beta1.CronJob{}
+		cronjob.Name = "hippo-pgbackrest-repo1-full"
+		cronjob.Labels = naming.PGBackRestCronJobLabels(cluster.Name, "repo1", "full")
+		owned(cronjob)
+
+		r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).
+			WithObjects(cronjob).Build()}
+
+		cluster := cluster.DeepCopy()
+		assert.NilError(t, r.stopBackupSchedules(ctx, cluster))
+
+		assert.NilError(t, r.Client.Get(ctx, client.ObjectKeyFromObject(cronjob), cronjob))
+		assert.Assert(t, cronjob.Spec.Suspend != nil && *cronjob.Spec.Suspend)
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions,
+			ConditionTeardownSchedulesStopped)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionTrue)
+	})
+
+	t.Run("FinalBackupNotRequested", func(t *testing.T) {
+		r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+
+		result, err := r.reconcileFinalBackup(ctx, cluster.DeepCopy())
+		assert.NilError(t, err)
+		assert.Assert(t, result == nil)
+	})
+
+	requested := cluster.DeepCopy()
+	requested.Spec.Teardown = &v1beta1.PostgresTeardownSpec{FinalBackupRepoName: "repo1"}
+
+	t.Run("FinalBackupUnavailable", func(t *testing.T) {
+		r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+
+		cluster := requested.DeepCopy()
+		result, err := r.reconcileFinalBackup(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, result != nil && result.RequeueAfter > 0)
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions,
+			ConditionTeardownFinalBackup)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Reason, "FinalBackupUnavailable")
+		assert.Assert(t, strings.Contains(condition.Message, naming.ForceDelete))
+	})
+
+	for _, tt := range []struct {
+		condition batchv1.JobConditionType
+		done      bool
+		reason    string
+	}{
+		{condition: batchv1.JobComplete, done: true, reason: "FinalBackupComplete"},
+		{condition: batchv1.JobFailed, done: false, reason: "FinalBackupFailed"},
+	} {
+		t.Run("FinalBackup"+string(tt.condition), func(t *testing.T) {
+			job := &batchv1.Job{}
+			job.Name = "hippo-backup-abcd"
+			job.Labels = naming.PGBackRestBackupJobLabels(cluster.Name, "repo1", naming.BackupFinal)
+			job.Status.Conditions = []batchv1.JobCondition{{
+				Type: tt.condition, Status: corev1.ConditionTrue,
+			}}
+			owned(job)
+
+			r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).
+				WithObjects(job).Build()}
+
+			cluster := requested.DeepCopy()
+			result, err := r.reconcileFinalBackup(ctx, cluster)
+			assert.NilError(t, err)
+			assert.Equal(t, result == nil, tt.done)
+
+			condition := meta.FindStatusCondition(cluster.Status.Conditions,
+				ConditionTeardownFinalBackup)
+			assert.Assert(t, condition != nil)
+			assert.Equal(t, condition.Reason, tt.reason)
+		})
+	}
+}
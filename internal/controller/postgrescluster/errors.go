@@ -0,0 +1,127 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// ConditionReconcileError is the type used in a condition to indicate that
+// the most recent reconcile of a PostgresCluster failed. Its reason is the
+// class of the error; see classifyReconcileError.
+const ConditionReconcileError = "ReconcileError"
+
+// These are the classes of reconcile errors. Each is the reason of a true
+// ConditionReconcileError and a label value of reconcileErrorsTotal.
+const (
+	// reconcileErrorConflict is an object that changed or appeared while it
+	// was being reconciled. These usually resolve on the next attempt.
+	reconcileErrorConflict = "APIConflict"
+
+	// reconcileErrorInvalidSpec is an object rejected by the Kubernetes API,
+	// usually because of values in the PostgresCluster spec.
+	reconcileErrorInvalidSpec = "InvalidSpec"
+
+	// reconcileErrorPodExec is a command that failed in a container.
+	reconcileErrorPodExec = "PodExecFailure"
+
+	// reconcileErrorStorage is a failure of a volume or a volume claim.
+	reconcileErrorStorage = "ExternalStorage"
+
+	// reconcileErrorOther is everything else.
+	reconcileErrorOther = "Other"
+)
+
+// podExecError is the error of a command that failed in a container. See
+// newPodExecutor.
+type podExecError struct{ error }
+
+func (e podExecError) Unwrap() error { return e.error }
+
+// classifyReconcileError returns the class of err.
+func classifyReconcileError(err error) string {
+	var exec podExecError
+	var status apierrors.APIStatus
+
+	switch {
+	case errors.As(err, &exec):
+		return reconcileErrorPodExec
+
+	case apierrors.IsConflict(err), apierrors.IsAlreadyExists(err):
+		return reconcileErrorConflict
+
+	case errors.As(err, &status) &&
+		status.Status().Details != nil &&
+		(status.Status().Details.Kind == "persistentvolumeclaims" ||
+			status.Status().Details.Kind == "persistentvolumes"):
+		return reconcileErrorStorage
+
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return reconcileErrorInvalidSpec
+	}
+
+	return reconcileErrorOther
+}
+
+// setReconcileErrorCondition counts err and reports it in the status of
+// cluster. When err is nil, a previously reported error is marked resolved.
+func setReconcileErrorCondition(cluster *v1beta1.PostgresCluster, err error) {
+	if err == nil {
+		if meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionReconcileError) {
+			meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+				ObservedGeneration: cluster.GetGeneration(),
+				Type:               ConditionReconcileError,
+				Status:             metav1.ConditionFalse,
+				Reason:             "Reconciled",
+				Message:            "The most recent reconcile succeeded",
+			})
+		}
+		return
+	}
+
+	class := classifyReconcileError(err)
+	reconcileErrorsTotal.WithLabelValues(cluster.Namespace, cluster.Name, class).Inc()
+
+	// Keep the message short enough to be read in "kubectl describe".
+	message := err.Error()
+	if len(message) > 1024 {
+		message = message[:1024] + "…"
+	}
+
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		ObservedGeneration: cluster.GetGeneration(),
+		Type:               ConditionReconcileError,
+		Status:             metav1.ConditionTrue,
+		Reason:             class,
+		Message:            message,
+	})
+}
+
+// forgetReconcileErrors removes the counts of a PostgresCluster that no longer
+// exists so they are no longer exported.
+func forgetReconcileErrors(namespace, name string) {
+	for _, class := range []string{
+		reconcileErrorConflict, reconcileErrorInvalidSpec,
+		reconcileErrorPodExec, reconcileErrorStorage, reconcileErrorOther,
+	} {
+		reconcileErrorsTotal.DeleteLabelValues(namespace, name, class)
+	}
+}
@@ -0,0 +1,81 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/v3/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestClassifyReconcileError(t *testing.T) {
+	for _, tt := range []struct {
+		err   error
+		class string
+	}{
+		{errors.New("boom"), reconcileErrorOther},
+		{errors.WithStack(podExecError{errors.New("exit 1")}), reconcileErrorPodExec},
+		{errors.WithStack(apierrors.NewConflict(
+			schema.GroupResource{Resource: "statefulsets"}, "x", errors.New("changed"),
+		)), reconcileErrorConflict},
+		{apierrors.NewAlreadyExists(
+			schema.GroupResource{Resource: "services"}, "x",
+		), reconcileErrorConflict},
+		{errors.WithStack(apierrors.NewInvalid(
+			schema.GroupKind{Kind: "StatefulSet"}, "x", nil,
+		)), reconcileErrorInvalidSpec},
+		{apierrors.NewForbidden(
+			schema.GroupResource{Resource: "persistentvolumeclaims"}, "x", errors.New("quota"),
+		), reconcileErrorStorage},
+	} {
+		assert.Equal(t, classifyReconcileError(tt.err), tt.class, "%v", tt.err)
+	}
+}
+
+func TestSetReconcileErrorCondition(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	t.Cleanup(func() { forgetReconcileErrors("ns1", "hippo") })
+
+	setReconcileErrorCondition(cluster, nil)
+	assert.Equal(t, len(cluster.Status.Conditions), 0, "expected nothing when there was no error")
+
+	setReconcileErrorCondition(cluster, podExecError{errors.New(strings.Repeat("x", 2000))})
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionReconcileError)
+	assert.Assert(t, condition != nil)
+	assert.Equal(t, condition.Status, metav1.ConditionTrue)
+	assert.Equal(t, condition.Reason, reconcileErrorPodExec)
+	assert.Assert(t, len(condition.Message) < 1100)
+	assert.Equal(t, testutil.ToFloat64(
+		reconcileErrorsTotal.WithLabelValues("ns1", "hippo", reconcileErrorPodExec)), float64(1))
+
+	setReconcileErrorCondition(cluster, nil)
+	condition = meta.FindStatusCondition(cluster.Status.Conditions, ConditionReconcileError)
+	assert.Equal(t, condition.Status, metav1.ConditionFalse)
+
+	forgetReconcileErrors("ns1", "hippo")
+	assert.Equal(t, testutil.ToFloat64(
+		reconcileErrorsTotal.WithLabelValues("ns1", "hippo", reconcileErrorPodExec)), float64(0))
+}
@@ -0,0 +1,156 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/patroni"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// AddEvictionWebhook registers a webhook on mgr that is called when a Pod is
+// about to be evicted, e.g. by "kubectl drain". When that Pod is the primary
+// of a PostgresCluster, the webhook moves the primary to another instance
+// before admitting the eviction. The webhook server must already be configured
+// with a certificate, and r must already be set up with mgr.
+func (r *Reconciler) AddEvictionWebhook(mgr manager.Manager) {
+	mgr.GetWebhookServer().Register("/evict", &webhook.Admission{
+		Handler: admission.HandlerFunc(r.handleEviction),
+	})
+}
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=list
+// +kubebuilder:rbac:groups=postgres-operator.crunchydata.com,resources=postgresclusters,verbs=get
+
+// handleEviction implements admission.HandlerFunc for Evictions. It always
+// admits the eviction; when the evicted Pod is a primary with a ready replica
+// on another node, it performs a Patroni switchover first. Otherwise, Patroni
+// fails over when the Pod stops as it would without this webhook.
+func (r *Reconciler) handleEviction(ctx context.Context, req admission.Request) admission.Response {
+	log := logging.FromContext(ctx).WithValues("pod", req.Name, "namespace", req.Namespace)
+
+	// The switchover is a side effect that must not happen during a dry run.
+	if req.DryRun != nil && *req.DryRun {
+		return admission.Allowed("")
+	}
+
+	pod := &corev1.Pod{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: req.Name}, pod)
+	if err != nil || pod.Labels[naming.LabelRole] != naming.RolePatroniLeader {
+		return admission.Allowed("")
+	}
+
+	cluster := &v1beta1.PostgresCluster{}
+	err = r.Client.Get(ctx, client.ObjectKey{
+		Namespace: pod.Namespace, Name: pod.Labels[naming.LabelCluster],
+	}, cluster)
+	if err != nil || !r.manages(cluster) {
+		return admission.Allowed("")
+	}
+
+	candidate, err := r.evictionCandidate(ctx, cluster, pod)
+	if err != nil || candidate == "" {
+		log.V(1).Info("no switchover before eviction", "candidate", candidate, "error", err)
+		return admission.Allowed("")
+	}
+
+	exec := func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+		return r.PodExec(ctx, pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+	}
+
+	success, err := patroni.Executor(exec).ChangePrimaryAndWait(ctx, pod.Name, candidate)
+	if err = errors.WithStack(err); err == nil && !success {
+		err = errors.New("unable to switchover")
+	}
+
+	if err != nil {
+		log.Error(err, "switchover before eviction")
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "EvictionSwitchoverFailed",
+			"Unable to move the primary from %q to %q before eviction: %v",
+			pod.Name, candidate, err)
+	} else {
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "EvictionSwitchover",
+			"Moved the primary from %q to %q before eviction", pod.Name, candidate)
+	}
+
+	return admission.Allowed("")
+}
+
+// evictionCandidate returns the name of a ready Pod in cluster that can become
+// the primary when primary is evicted. It returns an empty string when there
+// is no such Pod.
+func (r *Reconciler) evictionCandidate(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, primary *corev1.Pod,
+) (string, error) {
+	pods := &corev1.PodList{}
+	runners := &appsv1.StatefulSetList{}
+
+	selector, err := naming.AsSelector(naming.ClusterInstances(cluster.Name))
+	if err == nil {
+		err = errors.WithStack(
+			r.Client.List(ctx, pods,
+				client.InNamespace(cluster.Namespace),
+				client.MatchingLabelsSelector{Selector: selector},
+			))
+	}
+	if err == nil {
+		err = errors.WithStack(
+			r.Client.List(ctx, runners,
+				client.InNamespace(cluster.Namespace),
+				client.MatchingLabelsSelector{Selector: selector},
+			))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	instances := newObservedInstances(cluster, runners.Items, pods.Items)
+
+	for _, instance := range instances.forCluster {
+		// Patroni will not promote members that are tagged "nofailover".
+		if instance.Spec != nil && instance.Spec.Tags != nil &&
+			instance.Spec.Tags.NoFailover != nil && *instance.Spec.Tags.NoFailover {
+			continue
+		}
+		if primary, known := instance.IsPrimary(); primary || !known {
+			continue
+		}
+		if available, known := instance.IsAvailable(); !available || !known {
+			continue
+		}
+
+		// A Pod on the same Node is likely to be evicted next.
+		if pod := instance.Pods[0]; pod.Spec.NodeName != primary.Spec.NodeName {
+			return pod.Name, nil
+		}
+	}
+
+	return "", nil
+}
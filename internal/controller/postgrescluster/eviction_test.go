@@ -0,0 +1,143 @@
+//go:build envtest
+// +build envtest
+
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestHandleEviction(t *testing.T) {
+	ctx := context.Background()
+
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+
+	pod := func(instance, role, node string) *corev1.Pod {
+		pod := &corev1.Pod{}
+		pod.Namespace, pod.Name = "ns1", instance+"-0"
+		pod.Labels = map[string]string{
+			naming.LabelCluster:     "hippo",
+			naming.LabelInstance:    instance,
+			naming.LabelInstanceSet: "00",
+			naming.LabelRole:        role,
+		}
+		pod.Spec.NodeName = node
+		pod.Status.Conditions = []corev1.PodCondition{{
+			Type: corev1.PodReady, Status: corev1.ConditionTrue,
+		}}
+		return pod
+	}
+	evict := func(name string) admission.Request {
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Namespace: "ns1", Name: name,
+		}}
+	}
+
+	setup := func(objects ...*corev1.Pod) (*Reconciler, *record.FakeRecorder, *[]string) {
+		builder := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(cluster.DeepCopy())
+		for _, object := range objects {
+			builder = builder.WithObjects(object)
+		}
+
+		var commands []string
+		recorder := record.NewFakeRecorder(10)
+		return &Reconciler{
+			Client:   builder.Build(),
+			Recorder: recorder,
+			PodExec: func(_ context.Context, namespace, pod, container string,
+				stdin io.Reader, stdout, stderr io.Writer, command ...string,
+			) error {
+				commands = append(commands, pod+": "+strings.Join(command, " "))
+				_, err := stdout.Write([]byte("Successfully switched over"))
+				return err
+			},
+		}, recorder, &commands
+	}
+
+	t.Run("Replica", func(t *testing.T) {
+		reconciler, recorder, commands := setup(
+			pod("hippo-00-a", "master", "node1"), pod("hippo-00-b", "replica", "node2"))
+
+		response := reconciler.handleEviction(ctx, evict("hippo-00-b-0"))
+		assert.Assert(t, response.Allowed)
+		assert.Equal(t, len(*commands), 0)
+		assert.Equal(t, len(recorder.Events), 0)
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		reconciler, _, commands := setup(
+			pod("hippo-00-a", "master", "node1"), pod("hippo-00-b", "replica", "node2"))
+
+		request := evict("hippo-00-a-0")
+		request.DryRun = initialize.Bool(true)
+
+		response := reconciler.handleEviction(ctx, request)
+		assert.Assert(t, response.Allowed)
+		assert.Equal(t, len(*commands), 0)
+	})
+
+	t.Run("NoCandidate", func(t *testing.T) {
+		notReady := pod("hippo-00-c", "replica", "node3")
+		notReady.Status.Conditions[0].Status = corev1.ConditionFalse
+
+		reconciler, _, commands := setup(
+			pod("hippo-00-a", "master", "node1"),
+			pod("hippo-00-b", "replica", "node1"), // same node
+			notReady)
+
+		response := reconciler.handleEviction(ctx, evict("hippo-00-a-0"))
+		assert.Assert(t, response.Allowed)
+		assert.Equal(t, len(*commands), 0)
+	})
+
+	t.Run("Switchover", func(t *testing.T) {
+		reconciler, recorder, commands := setup(
+			pod("hippo-00-a", "master", "node1"), pod("hippo-00-b", "replica", "node2"))
+
+		response := reconciler.handleEviction(ctx, evict("hippo-00-a-0"))
+		assert.Assert(t, response.Allowed)
+		assert.DeepEqual(t, *commands, []string{
+			"hippo-00-a-0: patronictl switchover --scheduled=now --force --master=hippo-00-a-0 --candidate=hippo-00-b-0",
+		})
+
+		assert.Equal(t, len(recorder.Events), 1)
+		assert.Assert(t, strings.Contains(<-recorder.Events, "EvictionSwitchover"))
+	})
+}
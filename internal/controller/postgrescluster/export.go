@@ -0,0 +1,296 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/pgbackrest"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// exportSizeLimit is the most data Kubernetes stores in one ConfigMap.
+// - https://docs.k8s.io/concepts/configuration/configmap/#motivation
+const exportSizeLimit = 1 << 20
+
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs={list,create,delete,patch}
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs={create,patch}
+
+// reconcileExport exports cluster when the "export" annotation changes. A full
+// backup is taken to the first pgBackRest repository, when there is one. Once
+// it completes, the objects of cluster are copied into a ConfigMap without
+// their owner references; see generateExportIntent. The outcome is recorded
+// in status.
+func (r *Reconciler) reconcileExport(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (reconcile.Result, error) {
+	annotation := cluster.GetAnnotations()[naming.Export]
+	export := cluster.Status.Export
+
+	if annotation == "" || (export != nil && export.ID == annotation && export.Finished) {
+		return reconcile.Result{}, nil
+	}
+	if export == nil || export.ID != annotation {
+		now := metav1.Now()
+		export = &v1beta1.PostgresExportStatus{ID: annotation, StartTime: &now}
+		cluster.Status.Export = export
+	}
+
+	if pgbackrest.BackupsEnabled(cluster) {
+		repoName := cluster.Spec.Backups.PGBackRest.Repos[0].Name
+		labels := naming.PGBackRestBackupJobLabels(cluster.Name, repoName, naming.BackupExport)
+
+		jobs := &batchv1.JobList{}
+		if err := r.Client.List(ctx, jobs,
+			client.InNamespace(cluster.Namespace),
+			client.MatchingLabels(labels),
+		); err != nil {
+			return reconcile.Result{}, errors.WithStack(err)
+		}
+
+		// Remove the Jobs of previous exports.
+		var job *batchv1.Job
+		for i := range jobs.Items {
+			if !metav1.IsControlledBy(&jobs.Items[i], cluster) {
+				continue
+			}
+			if jobs.Items[i].GetAnnotations()[naming.Export] == export.ID {
+				job = &jobs.Items[i]
+			} else if err := errors.WithStack(client.IgnoreNotFound(
+				r.Client.Delete(ctx, &jobs.Items[i],
+					client.PropagationPolicy(metav1.DeletePropagationBackground)),
+			)); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+
+		switch {
+		case job == nil:
+			// pgBackRest needs a running primary and a stanza to take a
+			// backup. Check again in a little while.
+			available, err := r.fullBackupAvailable(ctx, cluster, repoName)
+			if err != nil || !available {
+				return reconcile.Result{RequeueAfter: time.Minute}, err
+			}
+
+			labels = naming.Merge(cluster.Spec.Metadata.GetLabelsOrNil(),
+				cluster.Spec.Backups.PGBackRest.Metadata.GetLabelsOrNil(), labels)
+			annotations := naming.Merge(cluster.Spec.Metadata.GetAnnotationsOrNil(),
+				cluster.Spec.Backups.PGBackRest.Metadata.GetAnnotationsOrNil(),
+				map[string]string{naming.Export: export.ID})
+
+			return reconcile.Result{}, r.applyFullBackupJob(
+				ctx, cluster, repoName, labels, annotations)
+
+		case jobFailed(job):
+			r.finishExport(cluster, export, false,
+				fmt.Sprintf("Backup Job %q did not complete successfully", job.Name))
+			return reconcile.Result{}, nil
+
+		case !jobCompleted(job):
+			// Wait for the backup to complete.
+			return reconcile.Result{}, nil
+		}
+	}
+
+	configmap, err := r.generateExportIntent(ctx, cluster)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	var size int
+	for key, value := range configmap.Data {
+		size += len(key) + len(value)
+	}
+	if size > exportSizeLimit {
+		r.finishExport(cluster, export, false, fmt.Sprintf(
+			"Exported objects are %d bytes; a ConfigMap holds at most %d", size, exportSizeLimit))
+		return reconcile.Result{}, nil
+	}
+
+	if err := r.apply(ctx, configmap); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	export.ConfigMap = configmap.Name
+	r.finishExport(cluster, export, true,
+		fmt.Sprintf("Exported %d objects to ConfigMap %q", len(configmap.Data)-1, configmap.Name))
+	return reconcile.Result{}, nil
+}
+
+// finishExport records the outcome of export in the status and events of cluster.
+func (r *Reconciler) finishExport(
+	cluster *v1beta1.PostgresCluster, export *v1beta1.PostgresExportStatus,
+	succeeded bool, message string,
+) {
+	now := metav1.Now()
+	export.Finished = true
+	export.Succeeded = succeeded
+	export.Message = message
+	export.CompletionTime = &now
+
+	if succeeded {
+		r.Recorder.Event(cluster, corev1.EventTypeNormal, "Exported", message)
+	} else {
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, "ExportFailed", message)
+	}
+}
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs={list}
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs={list}
+// +kubebuilder:rbac:groups="",resources=secrets,verbs={list}
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs={list}
+// +kubebuilder:rbac:groups="",resources=services,verbs={list}
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs={list}
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs={list}
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs={list}
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs={list}
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs={list}
+
+// generateExportIntent returns a ConfigMap that holds cluster and the objects
+// it controls as YAML, one per key. Fields that only make sense to this
+// Kubernetes cluster, such as owner references, UIDs, and status, are removed.
+// Secrets are only listed by name so their contents stay out of the ConfigMap.
+// Jobs and Endpoints are left out; they are recreated as needed.
+func (r *Reconciler) generateExportIntent(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (*corev1.ConfigMap, error) {
+	configmap := &corev1.ConfigMap{ObjectMeta: naming.ClusterExport(cluster)}
+	configmap.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+
+	configmap.Annotations = naming.Merge(cluster.Spec.Metadata.GetAnnotationsOrNil(),
+		map[string]string{naming.Export: cluster.GetAnnotations()[naming.Export]})
+	configmap.Labels = naming.Merge(cluster.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{naming.LabelCluster: cluster.Name})
+	configmap.Data = make(map[string]string)
+
+	add := func(object map[string]interface{}) error {
+		u := &unstructured.Unstructured{Object: object}
+		stripExportedObject(u)
+
+		b, err := yaml.Marshal(u.Object)
+		if err == nil {
+			key := strings.ToLower(u.GetKind()) + "." + u.GetName() + ".yaml"
+			configmap.Data[key] = string(b)
+		}
+		return errors.WithStack(err)
+	}
+
+	// Status is not exported, so name the stanza in the spec. The cluster
+	// reads the same stanza wherever it is applied.
+	exported := cluster.DeepCopy()
+	if pgbackrest.BackupsEnabled(cluster) {
+		exported.Spec.Backups.PGBackRest.Stanza = pgbackrest.StanzaName(cluster)
+	}
+
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(exported)
+	if err == nil {
+		u := &unstructured.Unstructured{Object: object}
+		u.SetGroupVersionKind(v1beta1.GroupVersion.WithKind("PostgresCluster"))
+		err = add(u.Object)
+	}
+
+	gvks := []schema.GroupVersionKind{
+		corev1.SchemeGroupVersion.WithKind("ConfigMapList"),
+		corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaimList"),
+		corev1.SchemeGroupVersion.WithKind("ServiceAccountList"),
+		corev1.SchemeGroupVersion.WithKind("ServiceList"),
+		appsv1.SchemeGroupVersion.WithKind("DeploymentList"),
+		appsv1.SchemeGroupVersion.WithKind("StatefulSetList"),
+		r.cronJobGroupVersion().WithKind("CronJobList"),
+		rbacv1.SchemeGroupVersion.WithKind("RoleList"),
+		rbacv1.SchemeGroupVersion.WithKind("RoleBindingList"),
+	}
+
+	for _, gvk := range gvks {
+		uList := &unstructured.UnstructuredList{}
+		uList.SetGroupVersionKind(gvk)
+
+		if err == nil {
+			err = errors.WithStack(r.Client.List(ctx, uList,
+				client.InNamespace(cluster.Namespace),
+				client.MatchingLabels{naming.LabelCluster: cluster.Name},
+			))
+		}
+		for i := range uList.Items {
+			if err == nil && metav1.IsControlledBy(&uList.Items[i], cluster) {
+				uList.Items[i].SetGroupVersionKind(gvk.GroupVersion().WithKind(
+					strings.TrimSuffix(gvk.Kind, "List")))
+				err = add(uList.Items[i].Object)
+			}
+		}
+	}
+
+	secrets := &corev1.SecretList{}
+	if err == nil {
+		err = errors.WithStack(r.Client.List(ctx, secrets,
+			client.InNamespace(cluster.Namespace),
+			client.MatchingLabels{naming.LabelCluster: cluster.Name},
+		))
+	}
+	var names []string
+	for i := range secrets.Items {
+		if metav1.IsControlledBy(&secrets.Items[i], cluster) {
+			names = append(names, secrets.Items[i].Name)
+		}
+	}
+	sort.Strings(names)
+	configmap.Data["secrets.txt"] = strings.Join(names, "\n")
+
+	return configmap, err
+}
+
+// stripExportedObject removes the fields of u that are assigned by this
+// Kubernetes cluster or that tie u to the operator.
+func stripExportedObject(u *unstructured.Unstructured) {
+	for _, field := range []string{
+		"creationTimestamp", "deletionGracePeriodSeconds", "deletionTimestamp",
+		"finalizers", "generation", "managedFields", "ownerReferences",
+		"resourceVersion", "selfLink", "uid",
+	} {
+		unstructured.RemoveNestedField(u.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(u.Object, "metadata", "annotations", naming.Export)
+	unstructured.RemoveNestedField(u.Object, "status")
+
+	switch u.GetKind() {
+	case "PersistentVolumeClaim":
+		// The bound PersistentVolume belongs to this Kubernetes cluster.
+		unstructured.RemoveNestedField(u.Object, "spec", "volumeName")
+	case "Service":
+		// Kubernetes assigns addresses to Services.
+		unstructured.RemoveNestedField(u.Object, "spec", "clusterIP")
+		unstructured.RemoveNestedField(u.Object, "spec", "clusterIPs")
+	}
+}
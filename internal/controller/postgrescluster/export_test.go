@@ -0,0 +1,234 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestGenerateExportIntent(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name, cluster.UID = "ns1", "hippo", "hippo-uid"
+	cluster.Annotations = map[string]string{naming.Export: "one"}
+	cluster.Status.Patroni = &v1beta1.PatroniStatus{SystemIdentifier: "123"}
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{Name: "repo1"}}
+	cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{Stanza: "db"}
+
+	owned := func(object client.Object, name string) client.Object {
+		object.SetNamespace(cluster.Namespace)
+		object.SetName(name)
+		object.SetLabels(map[string]string{naming.LabelCluster: cluster.Name})
+		object.SetOwnerReferences([]metav1.OwnerReference{{
+			APIVersion: v1beta1.GroupVersion.String(), Kind: "PostgresCluster",
+			Name: cluster.Name, UID: cluster.UID, Controller: initialize.Bool(true),
+		}})
+		return object
+	}
+
+	service := owned(&corev1.Service{}, "hippo-primary").(*corev1.Service)
+	service.Spec.ClusterIP = "10.0.0.1"
+	pvc := owned(&corev1.PersistentVolumeClaim{}, "hippo-data").(*corev1.PersistentVolumeClaim)
+	pvc.Spec.VolumeName = "pv-123"
+
+	unowned := &corev1.ConfigMap{}
+	unowned.Namespace, unowned.Name = "ns1", "other"
+	unowned.Labels = map[string]string{naming.LabelCluster: cluster.Name}
+
+	r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		service, pvc, unowned,
+		owned(&corev1.ConfigMap{}, "hippo-config"),
+		owned(&corev1.Secret{}, "hippo-pguser-hippo"),
+		owned(&corev1.Secret{}, "hippo-cluster-cert"),
+		owned(&batchv1.Job{}, "hippo-backup-abcd"),
+	).Build()}
+
+	configmap, err := r.generateExportIntent(ctx, cluster)
+	assert.NilError(t, err)
+	assert.Equal(t, configmap.Name, "hippo-export")
+	assert.Equal(t, configmap.Labels[naming.LabelCluster], "hippo")
+	assert.Equal(t, configmap.Annotations[naming.Export], "one")
+	assert.Assert(t, len(configmap.OwnerReferences) == 0)
+
+	var keys []string
+	for key := range configmap.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	assert.DeepEqual(t, keys, []string{
+		"configmap.hippo-config.yaml",
+		"persistentvolumeclaim.hippo-data.yaml",
+		"postgrescluster.hippo.yaml",
+		"secrets.txt",
+		"service.hippo-primary.yaml",
+	})
+
+	assert.Equal(t, configmap.Data["secrets.txt"], "hippo-cluster-cert\nhippo-pguser-hippo")
+
+	for key, value := range configmap.Data {
+		if strings.HasSuffix(key, ".yaml") {
+			assert.Assert(t, !strings.Contains(value, "ownerReferences"), "%v", key)
+			assert.Assert(t, !strings.Contains(value, "uid"), "%v", key)
+			assert.Assert(t, !strings.Contains(value, "resourceVersion"), "%v", key)
+			assert.Assert(t, !strings.Contains(value, "status:"), "%v", key)
+			assert.Assert(t, !strings.Contains(value, naming.Export), "%v", key)
+		}
+	}
+
+	var exported corev1.Service
+	assert.NilError(t, yaml.Unmarshal([]byte(configmap.Data["service.hippo-primary.yaml"]), &exported))
+	assert.Equal(t, exported.Kind, "Service")
+	assert.Equal(t, exported.Name, "hippo-primary")
+	assert.Equal(t, exported.Spec.ClusterIP, "")
+
+	var claim corev1.PersistentVolumeClaim
+	assert.NilError(t, yaml.Unmarshal([]byte(configmap.Data["persistentvolumeclaim.hippo-data.yaml"]), &claim))
+	assert.Equal(t, claim.Spec.VolumeName, "")
+
+	var postgres v1beta1.PostgresCluster
+	assert.NilError(t, yaml.Unmarshal([]byte(configmap.Data["postgrescluster.hippo.yaml"]), &postgres))
+	assert.Equal(t, postgres.APIVersion, v1beta1.GroupVersion.String())
+	assert.Equal(t, postgres.Kind, "PostgresCluster")
+	assert.Assert(t, postgres.Status.Patroni == nil)
+	assert.Equal(t, postgres.Spec.Backups.PGBackRest.Stanza, "db",
+		"expected the stanza in status to move to the spec")
+}
+
+func TestReconcileExport(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	setup := func(objects ...client.Object) (*Reconciler, *record.FakeRecorder) {
+		recorder := record.NewFakeRecorder(10)
+		return &Reconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects...).Build(),
+			Recorder: recorder,
+			Owner:    client.FieldOwner(t.Name()),
+		}, recorder
+	}
+
+	newCluster := func() *v1beta1.PostgresCluster {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Namespace, cluster.Name, cluster.UID = "ns1", "hippo", "hippo-uid"
+		cluster.Annotations = map[string]string{naming.Export: "two"}
+		cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{Name: "repo1"}}
+		return cluster
+	}
+
+	newJob := func(cluster *v1beta1.PostgresCluster, name, id string) *batchv1.Job {
+		job := &batchv1.Job{}
+		job.Namespace, job.Name = cluster.Namespace, name
+		job.Labels = naming.PGBackRestBackupJobLabels(cluster.Name, "repo1", naming.BackupExport)
+		job.Annotations = map[string]string{naming.Export: id}
+		job.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: v1beta1.GroupVersion.String(), Kind: "PostgresCluster",
+			Name: cluster.Name, UID: cluster.UID, Controller: initialize.Bool(true),
+		}}
+		return job
+	}
+
+	t.Run("NotRequested", func(t *testing.T) {
+		r, _ := setup()
+		cluster := newCluster()
+		cluster.Annotations = nil
+
+		result, err := r.reconcileExport(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.Assert(t, cluster.Status.Export == nil)
+	})
+
+	t.Run("Finished", func(t *testing.T) {
+		r, _ := setup()
+		cluster := newCluster()
+		cluster.Status.Export = &v1beta1.PostgresExportStatus{ID: "two", Finished: true}
+
+		result, err := r.reconcileExport(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.DeepEqual(t, cluster.Status.Export,
+			&v1beta1.PostgresExportStatus{ID: "two", Finished: true})
+	})
+
+	t.Run("BackupUnavailable", func(t *testing.T) {
+		cluster := newCluster()
+		previous := newJob(cluster, "hippo-backup-abcd", "one")
+		r, _ := setup(previous)
+
+		result, err := r.reconcileExport(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, result.RequeueAfter > 0)
+		assert.Equal(t, cluster.Status.Export.ID, "two")
+		assert.Assert(t, !cluster.Status.Export.Finished)
+		assert.Assert(t, cluster.Status.Export.StartTime != nil)
+
+		// The Job of the previous export is gone.
+		err = r.Client.Get(ctx, client.ObjectKeyFromObject(previous), previous)
+		assert.Assert(t, apierrors.IsNotFound(err), "expected NotFound, got %v", err)
+	})
+
+	t.Run("BackupRunning", func(t *testing.T) {
+		cluster := newCluster()
+		r, _ := setup(newJob(cluster, "hippo-backup-efgh", "two"))
+
+		result, err := r.reconcileExport(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.Assert(t, !cluster.Status.Export.Finished)
+	})
+
+	t.Run("BackupFailed", func(t *testing.T) {
+		cluster := newCluster()
+		job := newJob(cluster, "hippo-backup-efgh", "two")
+		job.Status.Conditions = []batchv1.JobCondition{{
+			Type: batchv1.JobFailed, Status: corev1.ConditionTrue,
+		}}
+		r, recorder := setup(job)
+
+		_, err := r.reconcileExport(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, cluster.Status.Export.Finished)
+		assert.Assert(t, !cluster.Status.Export.Succeeded)
+		assert.Assert(t, strings.Contains(cluster.Status.Export.Message, "hippo-backup-efgh"))
+		assert.Assert(t, strings.Contains(<-recorder.Events, "ExportFailed"))
+	})
+}
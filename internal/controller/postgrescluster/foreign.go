@@ -0,0 +1,145 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/pki"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// foreignServers returns the foreign servers of cluster, if any.
+func foreignServers(cluster *v1beta1.PostgresCluster) []v1beta1.PostgresForeignServerSpec {
+	if cluster.Spec.Extensions == nil {
+		return nil
+	}
+	return cluster.Spec.Extensions.ForeignServers
+}
+
+// foreignServerSecrets returns the names of the Secrets that hold the remote
+// credentials of the user mappings of cluster.
+func foreignServerSecrets(cluster *v1beta1.PostgresCluster) []string {
+	var names []string
+	for _, server := range foreignServers(cluster) {
+		for _, mapping := range server.UserMappings {
+			names = append(names, mapping.SecretName)
+		}
+	}
+	return names
+}
+
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={get}
+
+// reconcileForeignServers creates the foreign servers of cluster and their
+// user mappings in PostgreSQL. The remote credentials of each user mapping
+// come from a Secret that the user manages; a Secret that is missing or
+// incomplete is reported in an event and its user mapping is skipped. The
+// revision of what was written depends on a digest of each password rather
+// than the password itself.
+func (r *Reconciler) reconcileForeignServers(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+	rootCA *pki.RootCertificateAuthority,
+) (reconcile.Result, error) {
+	servers := foreignServers(cluster)
+	if len(servers) == 0 {
+		cluster.Status.ForeignServersRevision = ""
+		return reconcile.Result{}, nil
+	}
+
+	// Find the PostgreSQL instance that can execute SQL that writes system
+	// catalogs. When there is none, return early.
+	pod, _ := instances.writablePod(naming.ContainerDatabase)
+	if pod == nil {
+		return reconcile.Result{}, nil
+	}
+
+	credentials := make(map[string]postgres.ForeignCredentials)
+	digests := make(map[string]postgres.ForeignCredentials)
+	for _, name := range foreignServerSecrets(cluster) {
+		if _, ok := credentials[name]; ok {
+			continue
+		}
+
+		secret := &corev1.Secret{}
+		err := errors.WithStack(r.Client.Get(ctx,
+			client.ObjectKey{Namespace: cluster.Namespace, Name: name}, secret))
+		if apierrors.IsNotFound(err) {
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "ForeignServerSecretMissing",
+				"Secret %q of a user mapping does not exist", name)
+			continue
+		}
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if len(secret.Data["user"]) == 0 || len(secret.Data["password"]) == 0 {
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "ForeignServerSecretMissing",
+				"Secret %q of a user mapping needs both %q and %q", name, "user", "password")
+			continue
+		}
+
+		sum := sha256.Sum256(secret.Data["password"])
+		credentials[name] = postgres.ForeignCredentials{
+			User: string(secret.Data["user"]), Password: string(secret.Data["password"]),
+		}
+		digests[name] = postgres.ForeignCredentials{
+			User: string(secret.Data["user"]), Password: hex.EncodeToString(sum[:]),
+		}
+	}
+
+	// Calculate a hash of the SQL that should be executed in PostgreSQL.
+
+	revision, err := sqlChecksum(ctx, func(ctx context.Context, exec postgres.Executor) error {
+		return postgres.WriteForeignServersInPostgreSQL(ctx, exec, servers, digests)
+	})
+
+	if err == nil && revision == cluster.Status.ForeignServersRevision {
+		// The necessary SQL has already been applied; there's nothing more to do.
+		return reconcile.Result{}, nil
+	}
+
+	ctx = logging.NewContext(ctx, logging.FromContext(ctx).WithValues(
+		"pod", pod.Name, "revision", revision))
+
+	// Mistakes in the spec, such as a database or user that does not exist,
+	// should not stop the rest of the cluster from reconciling. Report them
+	// and try again later.
+	if err == nil {
+		if err := errors.WithStack(postgres.WriteForeignServersInPostgreSQL(
+			ctx, r.postgresExecutor(cluster, pod, rootCA), servers, credentials),
+		); err != nil {
+			logging.FromContext(ctx).Error(err, "unable to write foreign servers")
+			r.Recorder.Event(cluster, corev1.EventTypeWarning, "ForeignServersFailed",
+				"Unable to write foreign servers; check that their wrappers, databases, and users exist")
+			return reconcile.Result{RequeueAfter: time.Minute}, nil
+		}
+		cluster.Status.ForeignServersRevision = revision
+	}
+
+	return reconcile.Result{}, err
+}
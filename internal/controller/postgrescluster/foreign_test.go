@@ -0,0 +1,142 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestReconcileForeignServers(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	secret := &corev1.Secret{}
+	secret.Namespace, secret.Name = "ns1", "remote-login"
+	secret.Data = map[string][]byte{"user": []byte("reader"), "password": []byte("s3cret")}
+
+	var calls int
+	var stdin string
+	var failure error
+	recorder := record.NewFakeRecorder(10)
+	r := &Reconciler{
+		Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(secret).Build(),
+		Recorder: recorder,
+		PodExec: func(_ context.Context, namespace, pod, container string, in io.Reader, stdout,
+			stderr io.Writer, command ...string) error {
+			calls++
+			b, err := ioutil.ReadAll(in)
+			stdin = string(b)
+			if err == nil {
+				err = failure
+			}
+			return err
+		},
+	}
+
+	pod := &corev1.Pod{}
+	pod.Namespace, pod.Name = "ns1", "hippo-00-abcd-0"
+	pod.Annotations = map[string]string{"status": `{"role":"master"}`}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  naming.ContainerDatabase,
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}}
+	instances := &observedInstances{forCluster: []*Instance{{
+		Name: "hippo-00-abcd", Pods: []*corev1.Pod{pod}, Runner: &appsv1.StatefulSet{},
+	}}}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.Extensions = &v1beta1.PostgresExtensionsSpec{
+		ForeignServers: []v1beta1.PostgresForeignServerSpec{{
+			Name: "reports", Database: "app", Wrapper: "postgres_fdw",
+			Options: map[string]string{"host": "reports.example.com"},
+			UserMappings: []v1beta1.PostgresUserMappingSpec{
+				{User: "app", SecretName: "remote-login"},
+				{User: "PUBLIC", SecretName: "missing"},
+			},
+		}},
+	}
+
+	result, err := r.reconcileForeignServers(ctx, cluster, instances, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, result.RequeueAfter, time.Duration(0))
+	assert.Equal(t, calls, 1)
+	assert.Assert(t, cluster.Status.ForeignServersRevision != "")
+	assert.Assert(t, strings.Contains(<-recorder.Events, `Secret "missing"`))
+
+	// The password is written but is not part of the revision.
+	assert.Assert(t, strings.Contains(stdin, `"password":"s3cret"`))
+	assert.Assert(t, !strings.Contains(stdin, `"user":"PUBLIC"`))
+
+	// Nothing happens until something changes.
+	revision := cluster.Status.ForeignServersRevision
+	_, err = r.reconcileForeignServers(ctx, cluster, instances, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, calls, 1)
+	<-recorder.Events
+
+	t.Run("Rotated", func(t *testing.T) {
+		secret.Data["password"] = []byte("n3w")
+		assert.NilError(t, r.Client.Update(ctx, secret))
+
+		_, err := r.reconcileForeignServers(ctx, cluster, instances, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, calls, 2)
+		assert.Assert(t, cluster.Status.ForeignServersRevision != revision)
+		assert.Assert(t, strings.Contains(stdin, `"password":"n3w"`))
+		<-recorder.Events
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		failure = errors.New("boom")
+		cluster.Spec.Extensions.ForeignServers[0].Options["port"] = "6543"
+		revision := cluster.Status.ForeignServersRevision
+
+		result, err := r.reconcileForeignServers(ctx, cluster, instances, nil)
+		assert.NilError(t, err, "should not stop other reconciliation")
+		assert.Assert(t, result.RequeueAfter > 0)
+		assert.Equal(t, cluster.Status.ForeignServersRevision, revision)
+
+		<-recorder.Events
+		assert.Assert(t, strings.Contains(<-recorder.Events, "ForeignServersFailed"))
+	})
+
+	t.Run("Removed", func(t *testing.T) {
+		cluster.Spec.Extensions = nil
+		_, err := r.reconcileForeignServers(ctx, cluster, instances, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, cluster.Status.ForeignServersRevision, "")
+	})
+}
@@ -0,0 +1,174 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/patroni"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+const (
+	// EventReplicaFailed is the event reason used when a failed replica is
+	// removed so that it can be replaced.
+	EventReplicaFailed = "ReplicaFailed"
+
+	// EventReplicaReplaced is the event reason used when the instance set of
+	// a removed replica is ready again.
+	EventReplicaReplaced = "ReplicaReplaced"
+)
+
+// replicaFailedSince returns when instance stopped being ready because
+// PostgreSQL in it crashed or failed to start. It returns nil when instance
+// is the primary, is terminating, or has not failed. Instances that are
+// slowly creating their data directory have not failed.
+func replicaFailedSince(instance *Instance) *metav1.Time {
+	if len(instance.Pods) != 1 || instance.Pods[0].DeletionTimestamp != nil {
+		return nil
+	}
+	if primary, _ := instance.IsPrimary(); primary {
+		return nil
+	}
+
+	pod := instance.Pods[0]
+	if patroni.PodIsStandbyLeader(pod) {
+		return nil
+	}
+
+	// Patroni reports PostgreSQL that stopped unexpectedly or could not start.
+	// The kubelet reports a database container that keeps exiting.
+	// - https://github.com/zalando/patroni/blob/v2.1.1/patroni/ha.py
+	failed := false
+	switch patroni.PodState(pod) {
+	case "crashed", "start failed", "restart failed":
+		failed = true
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == naming.ContainerDatabase && status.State.Waiting != nil &&
+			status.State.Waiting.Reason == "CrashLoopBackOff" {
+			failed = true
+		}
+	}
+	if !failed {
+		return nil
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady && condition.Status != corev1.ConditionTrue {
+			return condition.LastTransitionTime.DeepCopy()
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:rbac:groups="",resources="configmaps",verbs={list,delete}
+// +kubebuilder:rbac:groups="",resources="persistentvolumeclaims",verbs={list,delete}
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={list,delete}
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs={list,delete}
+
+// reconcileReplicaHealing removes a replica of cluster that has failed for the
+// duration in spec.replicaHealing, along with its volumes. Its instance set
+// then creates a new replica in its place; see scaleUpInstances. The removed
+// replica is recorded in status until its instance set is ready again.
+func (r *Reconciler) reconcileReplicaHealing(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, observed *observedInstances,
+) (reconcile.Result, error) {
+	if status := cluster.Status.ReplicaHealing; status != nil {
+		var ready int
+		for _, instance := range observed.bySet[status.InstanceSet] {
+			if available, _ := instance.IsAvailable(); available &&
+				instance.Name != status.Instance {
+				ready++
+			}
+		}
+
+		var replicas int
+		for i := range cluster.Spec.InstanceSets {
+			if cluster.Spec.InstanceSets[i].Name == status.InstanceSet {
+				replicas = int(*cluster.Spec.InstanceSets[i].Replicas)
+			}
+		}
+
+		// Wait for every instance of the set to be ready, one at a time.
+		if ready < replicas {
+			return reconcile.Result{}, nil
+		}
+
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, EventReplicaReplaced,
+			"Instance set %q is ready after replacing failed instance %q",
+			status.InstanceSet, status.Instance)
+		cluster.Status.ReplicaHealing = nil
+	}
+
+	spec := cluster.Spec.ReplicaHealing
+	if spec == nil || (cluster.Spec.Shutdown != nil && *cluster.Spec.Shutdown) ||
+		repairInProgress(cluster) != nil {
+		return reconcile.Result{}, nil
+	}
+
+	// A new replica copies its data from a backup or the primary. Replace
+	// replicas only while the primary is ready.
+	primaryReady := false
+	for _, instance := range observed.forCluster {
+		primary, _ := instance.IsPrimary()
+		ready, _ := instance.IsReady()
+		primaryReady = primaryReady || (primary && ready)
+	}
+	if !primaryReady {
+		return reconcile.Result{}, nil
+	}
+
+	threshold := 10 * time.Minute
+	if spec.FailureSeconds != nil {
+		threshold = time.Duration(*spec.FailureSeconds) * time.Second
+	}
+
+	var result reconcile.Result
+	for _, instance := range observed.forCluster {
+		since := replicaFailedSince(instance)
+		if since == nil || instance.Runner == nil || instance.Spec == nil {
+			continue
+		}
+
+		if remaining := time.Until(since.Add(threshold)); remaining > 0 {
+			if result.RequeueAfter == 0 || remaining < result.RequeueAfter {
+				result.RequeueAfter = remaining
+			}
+			continue
+		}
+
+		now := metav1.Now()
+		cluster.Status.ReplicaHealing = &v1beta1.PostgresReplicaHealingStatus{
+			Instance:    instance.Name,
+			InstanceSet: instance.Spec.Name,
+			StartTime:   &now,
+		}
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, EventReplicaFailed,
+			"Instance %q has failed since %v; removing it and its volumes so that it is replaced",
+			instance.Name, since.UTC().Format(time.RFC3339))
+
+		return reconcile.Result{}, r.deleteInstance(ctx, cluster, instance.Name, true)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,232 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestReplicaFailedSince(t *testing.T) {
+	earlier := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+
+	newInstance := func() *Instance {
+		pod := &corev1.Pod{}
+		pod.Labels = map[string]string{naming.LabelRole: naming.RolePatroniReplica}
+		pod.Annotations = map[string]string{"status": `{"role":"replica","state":"crashed"}`}
+		pod.Status.Conditions = []corev1.PodCondition{{
+			Type: corev1.PodReady, Status: corev1.ConditionFalse,
+			LastTransitionTime: earlier,
+		}}
+		return &Instance{Pods: []*corev1.Pod{pod}}
+	}
+
+	assert.Assert(t, replicaFailedSince(&Instance{}) == nil)
+
+	instance := newInstance()
+	assert.DeepEqual(t, replicaFailedSince(instance), &earlier)
+
+	t.Run("Primary", func(t *testing.T) {
+		instance := newInstance()
+		instance.Pods[0].Labels[naming.LabelRole] = naming.RolePatroniLeader
+		assert.Assert(t, replicaFailedSince(instance) == nil)
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		instance := newInstance()
+		instance.Pods[0].Status.Conditions[0].Status = corev1.ConditionTrue
+		assert.Assert(t, replicaFailedSince(instance) == nil)
+	})
+
+	t.Run("CreatingReplica", func(t *testing.T) {
+		instance := newInstance()
+		instance.Pods[0].Annotations["status"] = `{"role":"replica","state":"creating replica"}`
+		assert.Assert(t, replicaFailedSince(instance) == nil)
+	})
+
+	t.Run("CrashLoopBackOff", func(t *testing.T) {
+		instance := newInstance()
+		instance.Pods[0].Annotations = nil
+		instance.Pods[0].Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name: naming.ContainerDatabase,
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+			},
+		}}
+		assert.DeepEqual(t, replicaFailedSince(instance), &earlier)
+	})
+}
+
+func TestReconcileReplicaHealing(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name, cluster.UID = "ns1", "hippo", "hippo-uid"
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{
+		Name: "00", Replicas: initialize.Int32(2),
+	}}
+	cluster.Spec.ReplicaHealing = &v1beta1.PostgresReplicaHealingSpec{
+		FailureSeconds: initialize.Int32(600),
+	}
+
+	newInstance := func(name, role string, ready bool, since time.Duration) *Instance {
+		pod := &corev1.Pod{}
+		pod.Namespace, pod.Name = "ns1", name+"-0"
+		pod.Labels = map[string]string{naming.LabelRole: role}
+		pod.Annotations = map[string]string{"status": `{"state":"running"}`}
+		pod.Status.Conditions = []corev1.PodCondition{{
+			Type: corev1.PodReady, Status: corev1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-since)),
+		}}
+		if !ready {
+			pod.Annotations["status"] = `{"state":"crashed"}`
+			pod.Status.Conditions[0].Status = corev1.ConditionFalse
+		}
+
+		sts := &appsv1.StatefulSet{}
+		sts.Namespace, sts.Name = "ns1", name
+		sts.Labels = map[string]string{
+			naming.LabelCluster: cluster.Name, naming.LabelInstance: name,
+		}
+		sts.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: v1beta1.GroupVersion.String(), Kind: "PostgresCluster",
+			Name: cluster.Name, UID: cluster.UID, Controller: initialize.Bool(true),
+		}}
+
+		return &Instance{
+			Name: name, Pods: []*corev1.Pod{pod}, Runner: sts,
+			Spec: &cluster.Spec.InstanceSets[0],
+		}
+	}
+
+	observe := func(instances ...*Instance) *observedInstances {
+		observed := &observedInstances{
+			byName: make(map[string]*Instance),
+			bySet:  make(map[string][]*Instance),
+		}
+		for _, instance := range instances {
+			observed.forCluster = append(observed.forCluster, instance)
+			observed.byName[instance.Name] = instance
+			observed.bySet[instance.Spec.Name] = append(observed.bySet[instance.Spec.Name], instance)
+		}
+		return observed
+	}
+
+	setup := func(instances ...*Instance) (*Reconciler, *record.FakeRecorder) {
+		builder := fake.NewClientBuilder().WithScheme(testScheme)
+		for _, instance := range instances {
+			builder = builder.WithObjects(instance.Runner)
+		}
+		recorder := record.NewFakeRecorder(10)
+		return &Reconciler{Client: builder.Build(), Recorder: recorder}, recorder
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		primary := newInstance("hippo-00-aaaa", naming.RolePatroniLeader, true, time.Hour)
+		replica := newInstance("hippo-00-bbbb", naming.RolePatroniReplica, false, time.Hour)
+		r, _ := setup(primary, replica)
+
+		cluster := cluster.DeepCopy()
+		cluster.Spec.ReplicaHealing = nil
+
+		result, err := r.reconcileReplicaHealing(ctx, cluster, observe(primary, replica))
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.Assert(t, cluster.Status.ReplicaHealing == nil)
+	})
+
+	t.Run("PrimaryNotReady", func(t *testing.T) {
+		primary := newInstance("hippo-00-aaaa", naming.RolePatroniLeader, false, time.Hour)
+		replica := newInstance("hippo-00-bbbb", naming.RolePatroniReplica, false, time.Hour)
+		r, _ := setup(primary, replica)
+
+		cluster := cluster.DeepCopy()
+		result, err := r.reconcileReplicaHealing(ctx, cluster, observe(primary, replica))
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.Assert(t, cluster.Status.ReplicaHealing == nil)
+	})
+
+	t.Run("Recent", func(t *testing.T) {
+		primary := newInstance("hippo-00-aaaa", naming.RolePatroniLeader, true, time.Hour)
+		replica := newInstance("hippo-00-bbbb", naming.RolePatroniReplica, false, time.Minute)
+		r, _ := setup(primary, replica)
+
+		cluster := cluster.DeepCopy()
+		result, err := r.reconcileReplicaHealing(ctx, cluster, observe(primary, replica))
+		assert.NilError(t, err)
+		assert.Assert(t, result.RequeueAfter > 8*time.Minute, "got %v", result.RequeueAfter)
+		assert.Assert(t, result.RequeueAfter <= 9*time.Minute, "got %v", result.RequeueAfter)
+		assert.Assert(t, cluster.Status.ReplicaHealing == nil)
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		primary := newInstance("hippo-00-aaaa", naming.RolePatroniLeader, true, time.Hour)
+		replica := newInstance("hippo-00-bbbb", naming.RolePatroniReplica, false, time.Hour)
+		r, recorder := setup(primary, replica)
+
+		cluster := cluster.DeepCopy()
+		result, err := r.reconcileReplicaHealing(ctx, cluster, observe(primary, replica))
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.Assert(t, cluster.Status.ReplicaHealing != nil)
+		assert.Equal(t, cluster.Status.ReplicaHealing.Instance, "hippo-00-bbbb")
+		assert.Equal(t, cluster.Status.ReplicaHealing.InstanceSet, "00")
+		assert.Assert(t, strings.Contains(<-recorder.Events, EventReplicaFailed))
+
+		// The failed instance is gone; the primary remains.
+		err = r.Client.Get(ctx, client.ObjectKeyFromObject(replica.Runner), &appsv1.StatefulSet{})
+		assert.Assert(t, apierrors.IsNotFound(err), "expected NotFound, got %v", err)
+		assert.NilError(t, r.Client.Get(ctx,
+			client.ObjectKeyFromObject(primary.Runner), &appsv1.StatefulSet{}))
+
+		// Nothing else happens until the instance set is ready again.
+		waiting := newInstance("hippo-00-cccc", naming.RolePatroniReplica, false, time.Hour)
+		waiting.Pods[0].Annotations["status"] = `{"state":"creating replica"}`
+		result, err = r.reconcileReplicaHealing(ctx, cluster, observe(primary, waiting))
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.Assert(t, cluster.Status.ReplicaHealing != nil)
+
+		replacement := newInstance("hippo-00-cccc", naming.RolePatroniReplica, true, time.Minute)
+		result, err = r.reconcileReplicaHealing(ctx, cluster, observe(primary, replacement))
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.Assert(t, cluster.Status.ReplicaHealing == nil)
+		assert.Assert(t, strings.Contains(<-recorder.Events, EventReplicaReplaced))
+	})
+}
@@ -28,6 +28,7 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -47,6 +48,23 @@ import (
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
+// EventPrimaryChanged is the event reason used when a different instance
+// becomes the primary, e.g. after a failover or switchover.
+const EventPrimaryChanged = "PrimaryChanged"
+
+// ConditionStartupDebug is the type used in a condition to indicate that
+// instance sets are waiting for repairs rather than running PostgreSQL. Its
+// message lists those instance sets.
+const ConditionStartupDebug = "StartupDebug"
+
+// EventStartupDebug is the event reason used when instance sets start waiting
+// for repairs rather than running PostgreSQL.
+const EventStartupDebug = "StartupDebug"
+
+// ConditionZonesObserved is the type used in a condition to indicate whether
+// or not the zones of instance Pods that use zoneSpread could be determined.
+const ConditionZonesObserved = "ZonesObserved"
+
 // Instance represents a single PostgreSQL instance of a PostgresCluster.
 type Instance struct {
 	Name   string
@@ -209,6 +227,9 @@ type observedInstances struct {
 	bySet      map[string][]*Instance
 	forCluster []*Instance
 	setNames   sets.String
+
+	// renamed maps the previous names of instance sets to their current names.
+	renamed map[string]string
 }
 
 // newObservedInstances builds an observedInstances from Kubernetes API objects.
@@ -221,6 +242,7 @@ func newObservedInstances(
 		byName:   make(map[string]*Instance),
 		bySet:    make(map[string][]*Instance),
 		setNames: make(sets.String),
+		renamed:  make(map[string]string),
 	}
 
 	sets := make(map[string]*v1beta1.PostgresInstanceSetSpec)
@@ -229,9 +251,26 @@ func newObservedInstances(
 		sets[name] = &cluster.Spec.InstanceSets[i]
 		observed.setNames.Insert(name)
 	}
+
+	// Instances labeled with the previous name of an instance set belong to
+	// that set, unless another set has that name now.
+	for i := range cluster.Spec.InstanceSets {
+		for _, previous := range cluster.Spec.InstanceSets[i].PreviousNames {
+			if _, current := sets[previous]; !current {
+				observed.renamed[previous] = cluster.Spec.InstanceSets[i].Name
+			}
+		}
+	}
+	setName := func(label string) string {
+		if name, ok := observed.renamed[label]; ok {
+			return name
+		}
+		return label
+	}
+
 	for i := range runners {
 		ri := runners[i].Name
-		rs := runners[i].Labels[naming.LabelInstanceSet]
+		rs := setName(runners[i].Labels[naming.LabelInstanceSet])
 
 		instance := &Instance{
 			Name:   ri,
@@ -246,7 +285,7 @@ func newObservedInstances(
 	}
 	for i := range pods {
 		pi := pods[i].Labels[naming.LabelInstance]
-		ps := pods[i].Labels[naming.LabelInstanceSet]
+		ps := setName(pods[i].Labels[naming.LabelInstanceSet])
 
 		instance := observed.byName[pi]
 		if instance == nil {
@@ -320,10 +359,35 @@ func (r *Reconciler) observeInstances(
 	observed := newObservedInstances(cluster, runners.Items, pods.Items)
 
 	// Fill out status sorted by set name.
+	domain := strings.TrimSuffix(naming.KubernetesClusterDomain(ctx), ".")
+	nodeZones := make(map[string]string)
+	zoneSpread, nodesForbidden := false, false
+
+	// Volume measurements are kept for Pods that still exist.
+	// See Reconciler.reconcileVolumeUsage.
+	previousVolumes := make(map[string][]v1beta1.PostgresVolumeUsageStatus)
+	for _, status := range cluster.Status.InstanceSets {
+		previousVolumes[status.Name] = status.Volumes
+	}
+
 	cluster.Status.InstanceSets = cluster.Status.InstanceSets[:0]
 	for _, name := range observed.setNames.List() {
 		status := v1beta1.PostgresInstanceSetStatus{Name: name}
+		for i := range cluster.Spec.InstanceSets {
+			if set := &cluster.Spec.InstanceSets[i]; set.Name == name {
+				status.ServiceName = naming.InstanceSetService(cluster, set).Name
+			}
+		}
 		for _, instance := range observed.bySet[name] {
+			if member, ok := instanceMember(instance, domain); ok {
+				status.Members = append(status.Members, member)
+			}
+			if instance.Spec != nil && instance.Spec.ZoneSpread != nil && *instance.Spec.ZoneSpread {
+				var forbidden bool
+				zoneSpread = true
+				status.Zones, forbidden = r.observeZones(ctx, instance, nodeZones, status.Zones)
+				nodesForbidden = nodesForbidden || forbidden
+			}
 			if ready, known := instance.IsReady(); known && ready {
 				status.ReadyReplicas++
 			}
@@ -336,8 +400,40 @@ func (r *Reconciler) observeInstances(
 			}
 		}
 
+		sort.Slice(status.Members, func(i, j int) bool {
+			return status.Members[i].Name < status.Members[j].Name
+		})
+		for _, volume := range previousVolumes[name] {
+			for _, instance := range observed.bySet[name] {
+				if len(instance.Pods) > 0 && instance.Pods[0].Name == volume.Pod {
+					status.Volumes = append(status.Volumes, volume)
+				}
+			}
+		}
 		cluster.Status.InstanceSets = append(cluster.Status.InstanceSets, status)
 	}
+	observeZonesCondition(cluster, zoneSpread, nodesForbidden)
+
+	// Remember the primary and report when it changes. There is no primary
+	// while Patroni elects one, so compare with the most recent one.
+	var primary string
+	for _, set := range cluster.Status.InstanceSets {
+		for _, member := range set.Members {
+			if member.Role == "primary" {
+				primary = member.Name
+			}
+		}
+	}
+	if primary != "" {
+		if cluster.Status.Patroni == nil {
+			cluster.Status.Patroni = new(v1beta1.PatroniStatus)
+		}
+		if previous := cluster.Status.Patroni.Primary; previous != "" && previous != primary {
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, EventPrimaryChanged,
+				"The primary changed from %q to %q", previous, primary)
+		}
+		cluster.Status.Patroni.Primary = primary
+	}
 
 	// Determine if a restore is in progress.  If so, simply return to ensure the startup instance
 	// remains properly set throughout the duration of the restore.
@@ -356,7 +452,8 @@ func (r *Reconciler) observeInstances(
 	// startup instance values.
 	for _, instance := range observed.forCluster {
 		if primary, known := instance.IsPrimary(); primary && known {
-			if cluster.Spec.Shutdown != nil && *cluster.Spec.Shutdown {
+			if (cluster.Spec.Shutdown != nil && *cluster.Spec.Shutdown) ||
+				repairStopsCluster(cluster) {
 				cluster.Status.StartupInstance = instance.Name
 			} else {
 				cluster.Status.StartupInstance = ""
@@ -368,6 +465,111 @@ func (r *Reconciler) observeInstances(
 	return observed, err
 }
 
+// instanceMember returns the status of the Pod of instance as a member of its
+// set. Pods get stable DNS names through the subdomain of their StatefulSet,
+// e.g. "{pod}.{service}.{namespace}.svc.{domain}".
+// - https://docs.k8s.io/concepts/services-networking/dns-pod-service/#pods
+func instanceMember(instance *Instance, domain string) (v1beta1.PostgresInstanceMemberStatus, bool) {
+	var member v1beta1.PostgresInstanceMemberStatus
+	if len(instance.Pods) != 1 {
+		return member, false
+	}
+
+	pod := instance.Pods[0]
+	member.Name = pod.Name
+
+	if pod.Spec.Subdomain != "" {
+		hostname := pod.Spec.Hostname
+		if hostname == "" {
+			hostname = pod.Name
+		}
+		member.FQDN = hostname + "." + pod.Spec.Subdomain + "." +
+			pod.Namespace + ".svc." + domain
+	}
+
+	switch pod.Labels[naming.LabelRole] {
+	case naming.RolePatroniLeader:
+		member.Role = "primary"
+	case naming.RolePatroniReplica:
+		member.Role = "replica"
+	}
+
+	member.Ready, _ = instance.IsReady()
+
+	return member, true
+}
+
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get
+
+// observeZones counts the scheduled pods of instance by the zone of their node.
+// The zone of each node is remembered in nodeZones. Nodes are read directly
+// from the API rather than cached, and any that cannot be read are not counted.
+// Nodes are cluster-scoped, so reading them is forbidden when the operator is
+// installed with a namespaced Role; forbidden reports when that happens.
+func (r *Reconciler) observeZones(
+	ctx context.Context, instance *Instance,
+	nodeZones map[string]string, zones map[string]int32,
+) (_ map[string]int32, forbidden bool) {
+	for _, pod := range instance.Pods {
+		name := pod.Spec.NodeName
+		if name == "" {
+			continue
+		}
+
+		zone, ok := nodeZones[name]
+		if !ok {
+			node := &unstructured.Unstructured{}
+			node.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Node"))
+
+			if err := r.Client.Get(ctx, client.ObjectKey{Name: name}, node); err != nil {
+				forbidden = forbidden || apierrors.IsForbidden(err)
+				logging.FromContext(ctx).V(1).Info("unable to read node zone",
+					"node", name, "error", err.Error())
+			}
+
+			zone = node.GetLabels()[corev1.LabelTopologyZone]
+			nodeZones[name] = zone
+		}
+
+		if zone != "" {
+			if zones == nil {
+				zones = make(map[string]int32)
+			}
+			zones[zone]++
+		}
+	}
+	return zones, forbidden
+}
+
+// observeZonesCondition reports in the status of cluster whether the zones of
+// its instance Pods could be determined. The condition is removed when no
+// instance set uses zoneSpread.
+func observeZonesCondition(cluster *v1beta1.PostgresCluster, zoneSpread, forbidden bool) {
+	switch {
+	case !zoneSpread:
+		if len(cluster.Status.Conditions) > 0 {
+			meta.RemoveStatusCondition(&cluster.Status.Conditions, ConditionZonesObserved)
+		}
+	case forbidden:
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			ObservedGeneration: cluster.GetGeneration(),
+			Type:               ConditionZonesObserved,
+			Status:             metav1.ConditionFalse,
+			Reason:             "Forbidden",
+			Message: "Unable to read the zones of Nodes. This requires permission " +
+				"to get nodes, which only the cluster-wide installation has.",
+		})
+	default:
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			ObservedGeneration: cluster.GetGeneration(),
+			Type:               ConditionZonesObserved,
+			Status:             metav1.ConditionTrue,
+			Reason:             "NodesRead",
+			Message:            "Zones of instance Pods are reported in status",
+		})
+	}
+}
+
 // +kubebuilder:rbac:groups="",resources=pods,verbs=list
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=patch
 
@@ -466,11 +668,13 @@ func (r *Reconciler) deleteInstances(
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=delete;list
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=delete;list
 
-// deleteInstance will delete all resources related to a single instance
+// deleteInstance will delete all resources related to a single instance. Its
+// volumes are kept unless deleteVolumes is true.
 func (r *Reconciler) deleteInstance(
 	ctx context.Context,
 	cluster *v1beta1.PostgresCluster,
 	instanceName string,
+	deleteVolumes bool,
 ) error {
 	gvks := []schema.GroupVersionKind{{
 		Group:   corev1.SchemeGroupVersion.Group,
@@ -484,11 +688,14 @@ func (r *Reconciler) deleteInstance(
 		Group:   appsv1.SchemeGroupVersion.Group,
 		Version: appsv1.SchemeGroupVersion.Version,
 		Kind:    "StatefulSetList",
-	}, {
-		Group:   corev1.SchemeGroupVersion.Group,
-		Version: corev1.SchemeGroupVersion.Version,
-		Kind:    "PersistentVolumeClaimList",
 	}}
+	if deleteVolumes {
+		gvks = append(gvks, schema.GroupVersionKind{
+			Group:   corev1.SchemeGroupVersion.Group,
+			Version: corev1.SchemeGroupVersion.Version,
+			Kind:    "PersistentVolumeClaimList",
+		})
+	}
 
 	selector, err := naming.AsSelector(naming.ClusterInstance(cluster.Name, instanceName))
 	for _, gvk := range gvks {
@@ -503,9 +710,19 @@ func (r *Reconciler) deleteInstance(
 				))
 
 			for i := range uList.Items {
+				object := &uList.Items[i]
+
+				// Volumes retained for after the cluster is deleted have no
+				// owner. See volumesRetainedWhenDeleted.
+				if err == nil && object.GetKind() == "PersistentVolumeClaim" &&
+					metav1.GetControllerOf(object) == nil {
+					uid := object.GetUID()
+					err = errors.WithStack(client.IgnoreNotFound(
+						r.Client.Delete(ctx, object, client.Preconditions{UID: &uid})))
+				}
 				if err == nil {
 					err = errors.WithStack(client.IgnoreNotFound(
-						r.deleteControlled(ctx, cluster, &uList.Items[i])))
+						r.deleteControlled(ctx, cluster, object)))
 				}
 			}
 		}
@@ -520,6 +737,7 @@ func (r *Reconciler) reconcileInstanceSets(
 	ctx context.Context,
 	cluster *v1beta1.PostgresCluster,
 	clusterConfigMap *corev1.ConfigMap,
+	pgParameters postgres.Parameters,
 	clusterReplicationSecret *corev1.Secret,
 	rootCA *pki.RootCertificateAuthority,
 	clusterPodService *corev1.Service,
@@ -535,17 +753,59 @@ func (r *Reconciler) reconcileInstanceSets(
 		numInstancePods += len(instances.forCluster[i].Pods)
 	}
 
+	// Move instances of renamed instance sets, one at a time.
+	if err := r.recreateRenamedInstance(ctx, cluster, instances); err != nil {
+		return err
+	}
+
+	// Replace instances on an outdated storage class, one at a time.
+	surge, err := r.migrateInstanceStorage(ctx, cluster, instances, clusterVolumes)
+	if err != nil {
+		return err
+	}
+
+	// Add up what new instances need so that a shortage of quota is reported
+	// on the cluster rather than as Pods and volumes that never start.
+	available := make([][]string, len(cluster.Spec.InstanceSets))
+	usage := corev1.ResourceList{}
+	for i, set := range cluster.Spec.InstanceSets {
+		available[i] = findAvailableInstanceNames(set, instances, clusterVolumes)
+
+		var existing int
+		for _, instance := range instances.bySet[set.Name] {
+			if instance.Runner != nil {
+				existing++
+			}
+		}
+		missing := int(*set.Replicas) + surge[set.Name] - existing
+		for name, quantity := range instanceQuotaUsage(
+			&cluster.Spec.InstanceSets[i], missing, missing-len(available[i]),
+		) {
+			total := usage[name]
+			total.Add(quantity)
+			usage[name] = total
+		}
+	}
+
+	scaleUp := true
+	if len(usage) > 0 ||
+		meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionInsufficientQuota) {
+		if scaleUp, err = r.checkInstanceQuota(ctx, cluster, usage); err != nil {
+			return err
+		}
+	}
+
 	// Range over instance sets to scale up and ensure that each set has
 	// at least the number of replicas defined in the spec. The set can
-	// have more replicas than defined
+	// have more replicas than defined. Existing instances are updated even
+	// when there is not enough quota to create new ones.
 	for i, set := range cluster.Spec.InstanceSets {
 		_, err := r.scaleUpInstances(
 			ctx, cluster, instances, &cluster.Spec.InstanceSets[i],
-			clusterConfigMap, clusterReplicationSecret,
+			clusterConfigMap, pgParameters, clusterReplicationSecret,
 			rootCA, clusterPodService, instanceServiceAccount,
 			patroniLeaderService, primaryCertificate,
-			findAvailableInstanceNames(set, instances, clusterVolumes),
-			numInstancePods, clusterVolumes)
+			available[i], numInstancePods, clusterVolumes, surge[set.Name], scaleUp)
 		if err != nil {
 			return err
 		}
@@ -554,7 +814,7 @@ func (r *Reconciler) reconcileInstanceSets(
 	// Scaledown is called on the whole cluster in order to consider all
 	// instances. This is necessary because we have no way to determine
 	// which instance or instance set contains the primary pod.
-	err := r.scaleDownInstances(ctx, cluster, instances)
+	err = r.scaleDownInstances(ctx, cluster, instances, surge)
 	if err != nil {
 		return err
 	}
@@ -591,7 +851,10 @@ func findAvailableInstanceNames(set v1beta1.PostgresInstanceSetSpec,
 		}
 		pvcSet := pvc.GetLabels()[naming.LabelInstanceSet]
 		pvcRole := pvc.GetLabels()[naming.LabelRole]
-		if pvcRole == naming.RolePostgresData && pvcSet == set.Name {
+		// volumes of an outdated storage class are not reused
+		if pvcRole == naming.RolePostgresData &&
+			(pvcSet == set.Name || observedInstances.renamed[pvcSet] == set.Name) &&
+			!dataVolumeOutdated(&set, &pvc) {
 			setVolumes = append(setVolumes, pvc)
 		}
 	}
@@ -612,7 +875,8 @@ func findAvailableInstanceNames(set v1beta1.PostgresInstanceSetSpec,
 				pvcSet := pvc.GetLabels()[naming.LabelInstanceSet]
 				pvcInstance := pvc.GetLabels()[naming.LabelInstance]
 				pvcRole := pvc.GetLabels()[naming.LabelRole]
-				if pvcRole == naming.RolePostgresWAL && pvcSet == set.Name &&
+				if pvcRole == naming.RolePostgresWAL &&
+					(pvcSet == set.Name || observedInstances.renamed[pvcSet] == set.Name) &&
 					pvcInstance == setVolInstance {
 					setVolumesWithWAL = append(setVolumesWithWAL, pvc)
 				}
@@ -653,8 +917,8 @@ func (r *Reconciler) rolloutInstance(
 	}
 
 	pod := instance.Pods[0]
-	exec := func(_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
-		return r.PodExec(pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+	exec := func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+		return r.PodExec(ctx, pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
 	}
 
 	primary, known := instance.IsPrimary()
@@ -843,19 +1107,26 @@ func (r *Reconciler) scaleDownInstances(
 	ctx context.Context,
 	cluster *v1beta1.PostgresCluster,
 	observedInstances *observedInstances,
+	surge map[string]int,
 ) error {
 
 	// want defines the number of replicas we want for each instance set
 	want := map[string]int{}
 	for _, set := range cluster.Spec.InstanceSets {
-		want[set.Name] = int(*set.Replicas)
+		want[set.Name] = int(*set.Replicas) + surge[set.Name]
 	}
 
-	// grab all pods for the cluster using the observed instances
+	// grab all pods for the cluster using the observed instances; pods of
+	// renamed instance sets are counted by their current set name
 	pods := []corev1.Pod{}
-	for instanceIndex := range observedInstances.forCluster {
-		for podIndex := range observedInstances.forCluster[instanceIndex].Pods {
-			pods = append(pods, *observedInstances.forCluster[instanceIndex].Pods[podIndex])
+	for setName, instances := range observedInstances.bySet {
+		for _, instance := range instances {
+			for _, pod := range instance.Pods {
+				pod := *pod
+				pod.Labels = naming.Merge(pod.Labels,
+					map[string]string{naming.LabelInstanceSet: setName})
+				pods = append(pods, pod)
+			}
 		}
 	}
 
@@ -865,10 +1136,12 @@ func (r *Reconciler) scaleDownInstances(
 		namesToKeep.Insert(pod.Labels[naming.LabelInstance])
 	}
 
+	// Volumes of instances in a set that was removed from the spec are deleted.
 	for _, instance := range observedInstances.forCluster {
 		for _, pod := range instance.Pods {
 			if !namesToKeep.Has(pod.Labels[naming.LabelInstance]) {
-				err := r.deleteInstance(ctx, cluster, pod.Labels[naming.LabelInstance])
+				err := r.deleteInstance(ctx, cluster, pod.Labels[naming.LabelInstance],
+					!volumesRetainedWhenScaled(instance.Spec))
 				if err != nil {
 					return err
 				}
@@ -919,16 +1192,169 @@ func podsToKeep(instances []corev1.Pod, want map[string]int) []corev1.Pod {
 
 }
 
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=delete
+
+// recreateRenamedInstance deletes the StatefulSet of one instance whose
+// instance set was renamed. Its volumes remain, and scaleUpInstances recreates
+// it with the same name and the current set name. Nothing is deleted until
+// every instance is ready, and replicas go before the primary.
+func (r *Reconciler) recreateRenamedInstance(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, observed *observedInstances,
+) error {
+	var candidate *Instance
+
+	for _, instance := range observed.forCluster {
+		if instance.Runner == nil {
+			return nil
+		}
+		if ready, known := instance.IsReady(); !ready || !known {
+			return nil
+		}
+		if instance.Spec == nil ||
+			instance.Runner.Labels[naming.LabelInstanceSet] == instance.Spec.Name {
+			continue
+		}
+		if primary, _ := instance.IsPrimary(); candidate == nil || !primary {
+			candidate = instance
+		}
+	}
+
+	if candidate == nil {
+		return nil
+	}
+
+	logging.FromContext(ctx).Info("recreating instance of renamed instance set",
+		"instance", candidate.Name, "instance-set", candidate.Spec.Name)
+
+	return errors.WithStack(client.IgnoreNotFound(
+		r.deleteControlled(ctx, cluster, candidate.Runner)))
+}
+
+// instanceDataVolume returns the PostgreSQL data volume of instance from
+// volumes, or nil when there is none.
+func instanceDataVolume(
+	instance string, volumes []corev1.PersistentVolumeClaim,
+) *corev1.PersistentVolumeClaim {
+	for i := range volumes {
+		if volumes[i].Labels[naming.LabelInstance] == instance &&
+			volumes[i].Labels[naming.LabelRole] == naming.RolePostgresData &&
+			volumes[i].DeletionTimestamp == nil {
+			return &volumes[i]
+		}
+	}
+	return nil
+}
+
+// dataVolumeOutdated returns whether or not pvc has a storage class other than
+// the one specified for the data volumes of set.
+func dataVolumeOutdated(
+	set *v1beta1.PostgresInstanceSetSpec, pvc *corev1.PersistentVolumeClaim,
+) bool {
+	class := set.DataVolumeClaimSpec.StorageClassName
+	return pvc != nil && set.Ephemeral == nil && class != nil &&
+		(pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName != *class)
+}
+
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=delete
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=delete
+
+// migrateInstanceStorage replaces instances whose data volumes have a storage
+// class other than the one in their instance set spec. It returns the number
+// of instances each set should have beyond its replicas while that happens.
+//
+// The storage class of a PVC cannot change, so an instance set that needs
+// migration gets one more instance with a data volume of the new class. Once
+// every instance of the set is ready, one outdated instance is removed along
+// with its volumes; a primary is switched over to an instance on the new class
+// first. This repeats until no outdated instances remain.
+func (r *Reconciler) migrateInstanceStorage(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	observed *observedInstances, clusterVolumes []corev1.PersistentVolumeClaim,
+) (map[string]int, error) {
+	surge := make(map[string]int)
+
+	for i := range cluster.Spec.InstanceSets {
+		set := &cluster.Spec.InstanceSets[i]
+
+		var current, outdated []*Instance
+		for _, instance := range observed.bySet[set.Name] {
+			if dataVolumeOutdated(set, instanceDataVolume(instance.Name, clusterVolumes)) {
+				outdated = append(outdated, instance)
+			} else {
+				current = append(current, instance)
+			}
+		}
+		if len(outdated) == 0 {
+			continue
+		}
+
+		surge[set.Name] = 1
+
+		// Wait for the additional instance and for every instance in the set
+		// to be ready. Replicas are ready once they are streaming from the primary.
+		if len(current) == 0 || len(observed.bySet[set.Name]) <= int(*set.Replicas) {
+			continue
+		}
+		ready := true
+		for _, instance := range observed.bySet[set.Name] {
+			if available, known := instance.IsAvailable(); !available || !known {
+				ready = false
+			}
+		}
+		if !ready {
+			continue
+		}
+
+		// Retire replicas before the primary.
+		var retire *Instance
+		for _, instance := range outdated {
+			if primary, _ := instance.IsPrimary(); retire == nil || !primary {
+				retire = instance
+			}
+		}
+
+		if primary, _ := retire.IsPrimary(); primary {
+			pod := retire.Pods[0]
+			exec := func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+				return r.PodExec(ctx, pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+			}
+
+			candidate := current[0].Pods[0].Name
+			success, err := patroni.Executor(exec).ChangePrimaryAndWait(ctx, pod.Name, candidate)
+			if err = errors.WithStack(err); err == nil && !success {
+				err = errors.New("unable to switchover")
+			}
+			if err == nil {
+				r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "StorageMigration",
+					"Moved the primary from %q to %q to change its storage class",
+					retire.Name, current[0].Name)
+			}
+
+			// The labels of both Pods change, which triggers another reconcile.
+			return surge, err
+		}
+
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "StorageMigration",
+			"Removing instance %q to change its storage class to %q",
+			retire.Name, *set.DataVolumeClaimSpec.StorageClassName)
+
+		return surge, r.deleteInstance(ctx, cluster, retire.Name, true)
+	}
+
+	return surge, nil
+}
+
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=list
 
 // scaleUpInstances updates the cluster until the number of instances matches
-// the cluster spec
+// the cluster spec. When create is false, only existing instances are updated.
 func (r *Reconciler) scaleUpInstances(
 	ctx context.Context,
 	cluster *v1beta1.PostgresCluster,
 	observed *observedInstances,
 	set *v1beta1.PostgresInstanceSetSpec,
 	clusterConfigMap *corev1.ConfigMap,
+	pgParameters postgres.Parameters,
 	clusterReplicationSecret *corev1.Secret,
 	rootCA *pki.RootCertificateAuthority,
 	clusterPodService *corev1.Service,
@@ -938,6 +1364,8 @@ func (r *Reconciler) scaleUpInstances(
 	availableInstanceNames []string,
 	numInstancePods int,
 	clusterVolumes []corev1.PersistentVolumeClaim,
+	surge int,
+	create bool,
 ) ([]*appsv1.StatefulSet, error) {
 	log := logging.FromContext(ctx)
 
@@ -953,7 +1381,7 @@ func (r *Reconciler) scaleUpInstances(
 	}
 	// While there are fewer instances than specified, generate another empty one
 	// and append it.
-	for len(instances) < int(*set.Replicas) {
+	for create && len(instances) < int(*set.Replicas)+surge {
 		var span trace.Span
 		ctx, span = r.Tracer.Start(ctx, "generateInstanceName")
 		next := naming.GenerateInstance(cluster, set)
@@ -976,9 +1404,16 @@ func (r *Reconciler) scaleUpInstances(
 
 	var err error
 	for i := range instances {
+		// The selector of a StatefulSet cannot change. Leave the instances of
+		// renamed sets alone until recreateRenamedInstance deletes them.
+		if instances[i].Labels[naming.LabelInstanceSet] != "" &&
+			instances[i].Labels[naming.LabelInstanceSet] != set.Name {
+			continue
+		}
+
 		err = r.reconcileInstance(
 			ctx, cluster, observed.byName[instances[i].Name], set,
-			clusterConfigMap, clusterReplicationSecret,
+			clusterConfigMap, pgParameters, clusterReplicationSecret,
 			rootCA, clusterPodService, instanceServiceAccount,
 			patroniLeaderService, primaryCertificate, instances[i],
 			numInstancePods, clusterVolumes,
@@ -1001,6 +1436,7 @@ func (r *Reconciler) reconcileInstance(
 	observed *Instance,
 	spec *v1beta1.PostgresInstanceSetSpec,
 	clusterConfigMap *corev1.ConfigMap,
+	pgParameters postgres.Parameters,
 	clusterReplicationSecret *corev1.Secret,
 	rootCA *pki.RootCertificateAuthority,
 	clusterPodService *corev1.Service,
@@ -1033,7 +1469,7 @@ func (r *Reconciler) reconcileInstance(
 	)
 
 	if err == nil {
-		instanceConfigMap, err = r.reconcileInstanceConfigMap(ctx, cluster, spec, instance)
+		instanceConfigMap, err = r.reconcileInstanceConfigMap(ctx, cluster, spec, instance, pgParameters)
 	}
 	if err == nil {
 		instanceCertificates, err = r.reconcileInstanceCertificates(
@@ -1067,6 +1503,12 @@ func (r *Reconciler) reconcileInstance(
 		err = addPGMonitorToInstancePodSpec(cluster, &instance.Spec.Template)
 	}
 
+	// Add a sidecar that ships PostgreSQL logs, when enabled
+	if err == nil && logShipperEnabled(cluster) {
+		addLogShipperToPodSpec(cluster, postgres.LogVolumeMount(),
+			clusterConfigMap, &instance.Spec.Template)
+	}
+
 	// add nss_wrapper init container and add nss_wrapper env vars to the database and pgbackrest
 	// containers
 	if err == nil {
@@ -1087,6 +1529,15 @@ func (r *Reconciler) reconcileInstance(
 		addDevSHM(&instance.Spec.Template)
 	}
 
+	// add environment variables from the spec after those of the operator
+	if err == nil {
+		if ignored := addInstanceEnvironment(spec, &instance.Spec.Template); len(ignored) > 0 {
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "InvalidEnvironment",
+				"Instance set %q cannot change environment variables set by the operator: %v",
+				spec.Name, ignored)
+		}
+	}
+
 	if err == nil {
 		err = errors.WithStack(r.apply(ctx, instance))
 	}
@@ -1097,6 +1548,46 @@ func (r *Reconciler) reconcileInstance(
 	return err
 }
 
+// reconcileStartupDebug reports in the status of cluster which instance sets
+// have startupDebug enabled. An event is recorded when those sets change.
+func (r *Reconciler) reconcileStartupDebug(cluster *v1beta1.PostgresCluster) {
+	var names []string
+	for _, set := range cluster.Spec.InstanceSets {
+		if set.StartupDebug != nil && *set.StartupDebug {
+			names = append(names, fmt.Sprintf("%q", set.Name))
+		}
+	}
+
+	if len(names) == 0 {
+		if meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionStartupDebug) {
+			meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+				ObservedGeneration: cluster.GetGeneration(),
+				Type:               ConditionStartupDebug,
+				Status:             metav1.ConditionFalse,
+				Reason:             "Running",
+				Message:            "Every instance set runs PostgreSQL",
+			})
+		}
+		return
+	}
+
+	message := fmt.Sprintf("Instance sets %s are waiting for repairs; PostgreSQL is not running",
+		strings.Join(names, ", "))
+
+	if condition := meta.FindStatusCondition(cluster.Status.Conditions,
+		ConditionStartupDebug); condition == nil ||
+		condition.Status != metav1.ConditionTrue || condition.Message != message {
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, EventStartupDebug, message)
+	}
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		ObservedGeneration: cluster.GetGeneration(),
+		Type:               ConditionStartupDebug,
+		Status:             metav1.ConditionTrue,
+		Reason:             EventStartupDebug,
+		Message:            message,
+	})
+}
+
 func generateInstanceStatefulSetIntent(_ context.Context,
 	cluster *v1beta1.PostgresCluster,
 	spec *v1beta1.PostgresInstanceSetSpec,
@@ -1136,6 +1627,9 @@ func generateInstanceStatefulSetIntent(_ context.Context,
 			naming.LabelInstance:    sts.Name,
 			naming.LabelData:        naming.DataPostgres,
 		})
+	if spec.Tags != nil && spec.Tags.Workload != "" {
+		sts.Spec.Template.Labels[naming.LabelWorkload] = spec.Tags.Workload
+	}
 
 	// Don't clutter the namespace with extra ControllerRevisions.
 	// The "controller-revision-hash" label still exists on the Pod.
@@ -1172,20 +1666,37 @@ func generateInstanceStatefulSetIntent(_ context.Context,
 			)...)
 	}
 
+	// when zone spread is enabled, require instances to be spread across zones
+	// and keep replicas away from the zone of the primary
+	if spec.ZoneSpread != nil && *spec.ZoneSpread {
+		sts.Spec.Template.Spec.TopologySpreadConstraints = append(
+			sts.Spec.Template.Spec.TopologySpreadConstraints,
+			zoneSpreadConstraint(naming.ClusterInstances(cluster.Name)))
+		sts.Spec.Template.Spec.Affinity = zoneSpreadAffinity(
+			naming.ClusterPrimary(cluster.Name), spec.Affinity.DeepCopy())
+	}
+
+	// require nodes that can run the images of the cluster, if so configured
+	sts.Spec.Template.Spec.Affinity = architectureAffinity(
+		config.ImageArchitectures(cluster), sts.Spec.Template.Spec.Affinity)
+	setPodNameResolution(cluster, &sts.Spec.Template.Spec)
+
 	// Though we use a StatefulSet to keep an instance running, we only ever
 	// want one Pod from it. This means that Replicas should only ever be
 	// 1, the default case for a running cluster, or 0, if the existing replicas
 	// value is set to 0 due to being 'shutdown'.
 	// The logic below is designed to make sure that the primary/leader instance
-	// is always the first to startup and the last to shutdown.
+	// is always the first to startup and the last to shutdown. Most repairs
+	// stop the cluster the same way.
+	shutdown := (cluster.Spec.Shutdown != nil && *cluster.Spec.Shutdown) ||
+		repairStopsCluster(cluster)
 	if cluster.Status.StartupInstance == "" {
 		// there is no designated startup instance; all instances should run.
 		sts.Spec.Replicas = initialize.Int32(1)
 	} else if cluster.Status.StartupInstance != sts.Name {
 		// there is a startup instance defined, but not this instance; do not run.
 		sts.Spec.Replicas = initialize.Int32(0)
-	} else if cluster.Spec.Shutdown != nil && *cluster.Spec.Shutdown &&
-		numInstancePods <= 1 {
+	} else if shutdown && numInstancePods <= 1 {
 		// this is the last instance of the shutdown sequence; do not run.
 		sts.Spec.Replicas = initialize.Int32(0)
 	} else {
@@ -1195,6 +1706,11 @@ func generateInstanceStatefulSetIntent(_ context.Context,
 		sts.Spec.Replicas = initialize.Int32(1)
 	}
 
+	// Some repairs stop only the instance they repair.
+	if repairStopsInstance(cluster, sts.Name) {
+		sts.Spec.Replicas = initialize.Int32(0)
+	}
+
 	// Restart containers any time they stop, die, are killed, etc.
 	// - https://docs.k8s.io/concepts/workloads/pods/pod-lifecycle/#restart-policy
 	sts.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyAlways
@@ -1223,6 +1739,11 @@ func generateInstanceStatefulSetIntent(_ context.Context,
 func addPGBackRestToInstancePodSpec(cluster *v1beta1.PostgresCluster,
 	template *corev1.PodTemplateSpec) error {
 
+	// There is nothing to add when backups are disabled.
+	if !pgbackrest.BackupsEnabled(cluster) {
+		return nil
+	}
+
 	dedicatedRepoEnabled := pgbackrest.DedicatedRepoHostEnabled(cluster)
 	pgBackRestConfigContainers := []string{naming.ContainerDatabase}
 	if dedicatedRepoEnabled {
@@ -1238,6 +1759,7 @@ func addPGBackRestToInstancePodSpec(cluster *v1beta1.PostgresCluster,
 			resources, naming.ContainerDatabase); err != nil {
 			return errors.WithStack(err)
 		}
+		pgbackrest.AddSidecarSettingsToPod(cluster, template)
 	}
 	if err := pgbackrest.AddConfigsToPod(cluster, template, pgbackrest.CMInstanceKey,
 		pgBackRestConfigContainers...); err != nil {
@@ -1253,7 +1775,7 @@ func addPGBackRestToInstancePodSpec(cluster *v1beta1.PostgresCluster,
 // files (etc) that apply to instance of cluster.
 func (r *Reconciler) reconcileInstanceConfigMap(
 	ctx context.Context, cluster *v1beta1.PostgresCluster, spec *v1beta1.PostgresInstanceSetSpec,
-	instance *appsv1.StatefulSet,
+	instance *appsv1.StatefulSet, pgParameters postgres.Parameters,
 ) (*corev1.ConfigMap, error) {
 	instanceConfigMap := &corev1.ConfigMap{ObjectMeta: naming.InstanceConfigMap(instance)}
 	instanceConfigMap.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
@@ -1274,7 +1796,7 @@ func (r *Reconciler) reconcileInstanceConfigMap(
 		})
 
 	if err == nil {
-		err = patroni.InstanceConfigMap(ctx, cluster, spec, instanceConfigMap)
+		err = patroni.InstanceConfigMap(ctx, cluster, spec, pgParameters, instanceConfigMap)
 	}
 	if err == nil {
 		err = errors.WithStack(r.apply(ctx, instanceConfigMap))
@@ -74,7 +74,7 @@ func TestReconcilerRolloutInstance(t *testing.T) {
 
 		execCalls := 0
 		reconciler.PodExec = func(
-			namespace, pod, container string, stdin io.Reader, _, _ io.Writer, command ...string,
+			_ context.Context, namespace, pod, container string, stdin io.Reader, _, _ io.Writer, command ...string,
 		) error {
 			execCalls++
 
@@ -133,7 +133,7 @@ func TestReconcilerRolloutInstance(t *testing.T) {
 			reconciler := &Reconciler{}
 			reconciler.Tracer = oteltest.DefaultTracer()
 			reconciler.PodExec = func(
-				namespace, pod, container string, _ io.Reader, stdout, _ io.Writer, command ...string,
+				_ context.Context, namespace, pod, container string, _ io.Reader, stdout, _ io.Writer, command ...string,
 			) error {
 				execCalls++
 
@@ -161,7 +161,7 @@ func TestReconcilerRolloutInstance(t *testing.T) {
 			reconciler := &Reconciler{}
 			reconciler.Tracer = oteltest.DefaultTracer()
 			reconciler.PodExec = func(
-				_, _, _ string, _ io.Reader, _, _ io.Writer, _ ...string,
+				_ context.Context, _, _, _ string, _ io.Reader, _, _ io.Writer, _ ...string,
 			) error {
 				// Nothing useful in stdout.
 				return nil
@@ -21,7 +21,9 @@ package postgrescluster
 import (
 	"context"
 	"fmt"
+	"io"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -30,14 +32,20 @@ import (
 	"gotest.tools/v3/assert"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -140,6 +148,97 @@ func TestInstanceIsWritable(t *testing.T) {
 	assert.Assert(t, !writable)
 }
 
+func TestObserveZones(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+
+	node := &corev1.Node{}
+	node.Name = "node1"
+	node.Labels = map[string]string{corev1.LabelTopologyZone: "zone-a"}
+
+	pod := &corev1.Pod{}
+	pod.Spec.NodeName = "node1"
+	instance := &Instance{Name: "hippo-00-aaaa", Pods: []*corev1.Pod{pod}}
+
+	r := &Reconciler{
+		Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(node).Build(),
+	}
+
+	zones, forbidden := r.observeZones(ctx, instance, map[string]string{}, nil)
+	assert.Assert(t, !forbidden)
+	assert.DeepEqual(t, zones, map[string]int32{"zone-a": 1})
+
+	cluster := new(v1beta1.PostgresCluster)
+	observeZonesCondition(cluster, true, forbidden)
+	assert.Assert(t, meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionZonesObserved))
+
+	t.Run("Forbidden", func(t *testing.T) {
+		r := &Reconciler{Client: forbiddenGetClient{Client: r.Client}}
+
+		zones, forbidden := r.observeZones(ctx, instance, map[string]string{}, nil)
+		assert.Assert(t, forbidden)
+		assert.Equal(t, len(zones), 0)
+
+		observeZonesCondition(cluster, true, forbidden)
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionZonesObserved)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionFalse)
+		assert.Equal(t, condition.Reason, "Forbidden")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		observeZonesCondition(cluster, false, false)
+		assert.Assert(t, meta.FindStatusCondition(cluster.Status.Conditions, ConditionZonesObserved) == nil)
+
+		// Nothing changes when there are no conditions.
+		cluster := new(v1beta1.PostgresCluster)
+		observeZonesCondition(cluster, false, false)
+		assert.Equal(t, len(cluster.Status.Conditions), 0)
+	})
+}
+
+func TestReconcileStartupDebug(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{
+		{Name: "one", StartupDebug: initialize.Bool(true)},
+		{Name: "two"},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	r := &Reconciler{Recorder: recorder}
+
+	r.reconcileStartupDebug(cluster)
+	assert.Assert(t, meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionStartupDebug))
+	assert.Equal(t, len(recorder.Events), 1)
+	assert.Assert(t, strings.Contains(<-recorder.Events, `"one"`))
+
+	// The event is recorded only once.
+	r.reconcileStartupDebug(cluster)
+	assert.Equal(t, len(recorder.Events), 0)
+
+	// Another event is recorded when the instance sets change.
+	cluster.Spec.InstanceSets[1].StartupDebug = initialize.Bool(true)
+	r.reconcileStartupDebug(cluster)
+	assert.Equal(t, len(recorder.Events), 1)
+	assert.Assert(t, strings.Contains(<-recorder.Events, `"one", "two"`))
+
+	// The condition resolves once every instance set runs PostgreSQL.
+	cluster.Spec.InstanceSets[0].StartupDebug = nil
+	cluster.Spec.InstanceSets[1].StartupDebug = initialize.Bool(false)
+	r.reconcileStartupDebug(cluster)
+	assert.Assert(t, !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionStartupDebug))
+	assert.Equal(t, len(recorder.Events), 0)
+
+	t.Run("Disabled", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Status.Conditions = nil
+
+		r.reconcileStartupDebug(cluster)
+		assert.Assert(t, meta.FindStatusCondition(cluster.Status.Conditions, ConditionStartupDebug) == nil)
+	})
+}
+
 func TestNewObservedInstances(t *testing.T) {
 	t.Run("Empty", func(t *testing.T) {
 		cluster := new(v1beta1.PostgresCluster)
@@ -261,6 +360,60 @@ func TestNewObservedInstances(t *testing.T) {
 		assert.DeepEqual(t, observed.bySet["00"], []*Instance{instance})
 		assert.DeepEqual(t, observed.setNames.List(), []string{"00"})
 	})
+
+	t.Run("Renamed", func(t *testing.T) {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{
+			{Name: "new", PreviousNames: []string{"old", "other"}},
+			{Name: "other"},
+		}
+
+		observed := newObservedInstances(
+			cluster,
+			[]appsv1.StatefulSet{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "moved",
+						Labels: map[string]string{
+							"postgres-operator.crunchydata.com/instance-set": "old",
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "stayed",
+						Labels: map[string]string{
+							"postgres-operator.crunchydata.com/instance-set": "other",
+						},
+					},
+				},
+			},
+			[]corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "moved-0",
+						Labels: map[string]string{
+							"postgres-operator.crunchydata.com/instance-set": "old",
+							"postgres-operator.crunchydata.com/instance":     "moved",
+						},
+					},
+				},
+			})
+
+		// The previous name is an alias of the current one.
+		assert.DeepEqual(t, observed.renamed, map[string]string{"old": "new"})
+		assert.DeepEqual(t, observed.setNames.List(), []string{"new", "other"})
+
+		moved := observed.byName["moved"]
+		assert.Equal(t, len(moved.Pods), 1)
+		assert.Equal(t, moved.Spec, &cluster.Spec.InstanceSets[0])
+		assert.DeepEqual(t, observed.bySet["new"], []*Instance{moved})
+
+		// Another set with the previous name keeps its instances.
+		stayed := observed.byName["stayed"]
+		assert.Equal(t, stayed.Spec, &cluster.Spec.InstanceSets[1])
+		assert.DeepEqual(t, observed.bySet["other"], []*Instance{stayed})
+	})
 }
 
 func TestWritablePod(t *testing.T) {
@@ -595,6 +748,21 @@ func TestAddPGBackRestToInstancePodSpec(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("BackupsDisabled", func(t *testing.T) {
+		cluster := postgresCluster.DeepCopy()
+		cluster.Spec.Backups = v1beta1.Backups{}
+
+		template := &corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: naming.ContainerDatabase}},
+			},
+		}
+		before := template.DeepCopy()
+
+		assert.NilError(t, addPGBackRestToInstancePodSpec(cluster, template))
+		assert.DeepEqual(t, template, before)
+	})
 }
 
 func TestPodsToKeep(t *testing.T) {
@@ -1019,7 +1187,7 @@ func TestDeleteInstance(t *testing.T) {
 	instanceName := stsList.Items[0].Labels[naming.LabelInstance]
 
 	// Use the instance name to delete the single instance
-	assert.NilError(t, reconciler.deleteInstance(ctx, cluster, instanceName))
+	assert.NilError(t, reconciler.deleteInstance(ctx, cluster, instanceName, true))
 
 	gvks := []schema.GroupVersionKind{
 		corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"),
@@ -1067,6 +1235,59 @@ func TestDeleteInstance(t *testing.T) {
 	}
 }
 
+func TestDeleteInstanceVolumes(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name, cluster.UID = "ns1", "hippo", "some-uid"
+	owner := metav1.NewControllerRef(cluster, v1beta1.GroupVersion.WithKind("PostgresCluster"))
+
+	objects := func() []client.Object {
+		labels := map[string]string{
+			naming.LabelCluster:  "hippo",
+			naming.LabelInstance: "hippo-00-abcd",
+		}
+		data := &corev1.PersistentVolumeClaim{}
+		data.Namespace, data.Name, data.Labels = "ns1", "hippo-00-abcd-pgdata", labels
+		data.OwnerReferences = []metav1.OwnerReference{*owner}
+
+		// Retained after the cluster is deleted; see volumesRetainedWhenDeleted.
+		wal := &corev1.PersistentVolumeClaim{}
+		wal.Namespace, wal.Name, wal.Labels = "ns1", "hippo-00-abcd-pgwal", labels
+
+		config := &corev1.ConfigMap{}
+		config.Namespace, config.Name, config.Labels = "ns1", "hippo-00-abcd-config", labels
+		config.OwnerReferences = []metav1.OwnerReference{*owner}
+
+		return []client.Object{data, wal, config}
+	}
+	count := func(cc client.Client, list client.ObjectList) int {
+		assert.NilError(t, cc.List(ctx, list, client.InNamespace("ns1")))
+		return meta.LenList(list)
+	}
+
+	t.Run("KeepVolumes", func(t *testing.T) {
+		cc := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects()...).Build()
+		r := &Reconciler{Client: cc}
+
+		assert.NilError(t, r.deleteInstance(ctx, cluster, "hippo-00-abcd", false))
+		assert.Equal(t, count(cc, &corev1.ConfigMapList{}), 0)
+		assert.Equal(t, count(cc, &corev1.PersistentVolumeClaimList{}), 2)
+	})
+
+	t.Run("DeleteVolumes", func(t *testing.T) {
+		cc := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects()...).Build()
+		r := &Reconciler{Client: cc}
+
+		assert.NilError(t, r.deleteInstance(ctx, cluster, "hippo-00-abcd", true))
+		assert.Equal(t, count(cc, &corev1.ConfigMapList{}), 0)
+		assert.Equal(t, count(cc, &corev1.PersistentVolumeClaimList{}), 0)
+	})
+}
+
 func TestGenerateInstanceStatefulSetIntent(t *testing.T) {
 	type intentParams struct {
 		cluster                    *v1beta1.PostgresCluster
@@ -1109,6 +1330,24 @@ func TestGenerateInstanceStatefulSetIntent(t *testing.T) {
 		run: func(t *testing.T, ss *appsv1.StatefulSet) {
 			assert.Assert(t, ss.Spec.Template.Spec.Affinity != nil)
 		},
+	}, {
+		name: "zone spread",
+		ip: intentParams{
+			spec: &v1beta1.PostgresInstanceSetSpec{
+				Affinity:   &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{}},
+				ZoneSpread: initialize.Bool(true),
+			},
+		},
+		run: func(t *testing.T, ss *appsv1.StatefulSet) {
+			constraints := ss.Spec.Template.Spec.TopologySpreadConstraints
+			last := constraints[len(constraints)-1]
+			assert.Equal(t, last.TopologyKey, corev1.LabelTopologyZone)
+			assert.Equal(t, last.WhenUnsatisfiable, corev1.DoNotSchedule)
+
+			affinity := ss.Spec.Template.Spec.Affinity
+			assert.Assert(t, affinity.NodeAffinity != nil)
+			assert.Equal(t, len(affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution), 1)
+		},
 	}, {
 		name: "custom tolerations",
 		ip: intentParams{
@@ -1119,6 +1358,17 @@ func TestGenerateInstanceStatefulSetIntent(t *testing.T) {
 		run: func(t *testing.T, ss *appsv1.StatefulSet) {
 			assert.Assert(t, ss.Spec.Template.Spec.Tolerations != nil)
 		},
+	}, {
+		name: "workload tag",
+		ip: intentParams{
+			spec: &v1beta1.PostgresInstanceSetSpec{
+				Tags: &v1beta1.PatroniTags{Workload: "analytics"},
+			},
+		},
+		run: func(t *testing.T, ss *appsv1.StatefulSet) {
+			assert.Equal(t, ss.Spec.Template.Labels[naming.LabelWorkload], "analytics")
+			assert.Equal(t, ss.Spec.Selector.MatchLabels[naming.LabelWorkload], "")
+		},
 	}, {
 		name: "custom topology spread constraints",
 		ip: intentParams{
@@ -1186,6 +1436,68 @@ func TestGenerateInstanceStatefulSetIntent(t *testing.T) {
 		run: func(t *testing.T, ss *appsv1.StatefulSet) {
 			assert.Equal(t, *ss.Spec.Replicas, int32(1))
 		},
+	}, {
+		name: "repair keeps primary while others stop",
+		ip: intentParams{
+			cluster: func() *v1beta1.PostgresCluster {
+				cluster := testCluster()
+				cluster.Status.Repair = &v1beta1.PostgresRepairStatus{Method: "resetWAL"}
+				return cluster
+			}(),
+			sts:             &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "testInstance1"}},
+			numInstancePods: 2,
+			startupInstance: "testInstance1",
+		},
+		run: func(t *testing.T, ss *appsv1.StatefulSet) {
+			assert.Equal(t, *ss.Spec.Replicas, int32(1))
+		},
+	}, {
+		name: "repair stops primary last",
+		ip: intentParams{
+			cluster: func() *v1beta1.PostgresCluster {
+				cluster := testCluster()
+				cluster.Status.Repair = &v1beta1.PostgresRepairStatus{Method: "resetWAL"}
+				return cluster
+			}(),
+			sts:             &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "testInstance1"}},
+			numInstancePods: 1,
+			startupInstance: "testInstance1",
+		},
+		run: func(t *testing.T, ss *appsv1.StatefulSet) {
+			assert.Equal(t, *ss.Spec.Replicas, int32(0))
+		},
+	}, {
+		name: "rewind stops only its instance",
+		ip: intentParams{
+			cluster: func() *v1beta1.PostgresCluster {
+				cluster := testCluster()
+				cluster.Status.Repair = &v1beta1.PostgresRepairStatus{
+					Method: "rewind", Instance: "testInstance1",
+				}
+				return cluster
+			}(),
+			sts:             &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "testInstance1"}},
+			numInstancePods: 2,
+		},
+		run: func(t *testing.T, ss *appsv1.StatefulSet) {
+			assert.Equal(t, *ss.Spec.Replicas, int32(0))
+		},
+	}, {
+		name: "rewind of another instance",
+		ip: intentParams{
+			cluster: func() *v1beta1.PostgresCluster {
+				cluster := testCluster()
+				cluster.Status.Repair = &v1beta1.PostgresRepairStatus{
+					Method: "rewind", Instance: "testInstance2",
+				}
+				return cluster
+			}(),
+			sts:             &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "testInstance1"}},
+			numInstancePods: 2,
+		},
+		run: func(t *testing.T, ss *appsv1.StatefulSet) {
+			assert.Equal(t, *ss.Spec.Replicas, int32(1))
+		},
 	}, {
 		name: "check imagepullsecret",
 		run: func(t *testing.T, ss *appsv1.StatefulSet) {
@@ -1557,6 +1869,24 @@ func TestFindAvailableInstanceNames(t *testing.T) {
 				naming.LabelInstanceSet: "instance1",
 				naming.LabelInstance:    "instance1-def"}}}},
 		expectedInstanceNames: []string{},
+	}, {
+		set: v1beta1.PostgresInstanceSetSpec{Name: "renamed", PreviousNames: []string{"instance1"}},
+		fakeObservedInstances: newObservedInstances(
+			&v1beta1.PostgresCluster{Spec: v1beta1.PostgresClusterSpec{
+				InstanceSets: []v1beta1.PostgresInstanceSetSpec{
+					{Name: "renamed", PreviousNames: []string{"instance1"}},
+				},
+			}},
+			[]appsv1.StatefulSet{},
+			[]corev1.Pod{},
+		),
+		fakeClusterVolumes: []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{
+			Name: "instance1-def-ghi",
+			Labels: map[string]string{
+				naming.LabelRole:        naming.RolePostgresData,
+				naming.LabelInstanceSet: "instance1",
+				naming.LabelInstance:    "instance1-def"}}}},
+		expectedInstanceNames: []string{"instance1-def"},
 	}}
 
 	for _, tc := range testCases {
@@ -1573,3 +1903,222 @@ func TestFindAvailableInstanceNames(t *testing.T) {
 		})
 	}
 }
+
+func TestInstanceMember(t *testing.T) {
+	var instance Instance
+
+	// No pods
+	_, ok := instanceMember(&instance, "cluster.local")
+	assert.Assert(t, !ok)
+
+	pod := &corev1.Pod{}
+	pod.Namespace, pod.Name = "ns1", "hippo-00-abcd-0"
+	pod.Labels = map[string]string{naming.LabelRole: naming.RolePatroniLeader}
+	pod.Spec.Subdomain = "hippo-pods"
+	pod.Status.Conditions = []corev1.PodCondition{{
+		Type: corev1.PodReady, Status: corev1.ConditionTrue,
+	}}
+	instance.Pods = []*corev1.Pod{pod}
+
+	member, ok := instanceMember(&instance, "cluster.local")
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, member, v1beta1.PostgresInstanceMemberStatus{
+		Name:  "hippo-00-abcd-0",
+		FQDN:  "hippo-00-abcd-0.hippo-pods.ns1.svc.cluster.local",
+		Role:  "primary",
+		Ready: true,
+	})
+
+	// Replica without a subdomain
+	pod.Labels[naming.LabelRole] = naming.RolePatroniReplica
+	pod.Spec.Subdomain = ""
+	member, ok = instanceMember(&instance, "cluster.local")
+	assert.Assert(t, ok)
+	assert.Equal(t, member.FQDN, "")
+	assert.Equal(t, member.Role, "replica")
+
+	// Unknown role
+	delete(pod.Labels, naming.LabelRole)
+	member, _ = instanceMember(&instance, "cluster.local")
+	assert.Equal(t, member.Role, "")
+}
+
+func TestDataVolumeOutdated(t *testing.T) {
+	set := &v1beta1.PostgresInstanceSetSpec{}
+	pvc := &corev1.PersistentVolumeClaim{}
+	pvc.Spec.StorageClassName = initialize.String("slow")
+
+	// No class specified; nothing to compare.
+	assert.Assert(t, !dataVolumeOutdated(set, pvc))
+
+	set.DataVolumeClaimSpec.StorageClassName = initialize.String("slow")
+	assert.Assert(t, !dataVolumeOutdated(set, pvc))
+	assert.Assert(t, !dataVolumeOutdated(set, nil))
+
+	set.DataVolumeClaimSpec.StorageClassName = initialize.String("fast")
+	assert.Assert(t, dataVolumeOutdated(set, pvc))
+
+	pvc.Spec.StorageClassName = nil
+	assert.Assert(t, dataVolumeOutdated(set, pvc))
+
+	// Ephemeral volumes have no PVC to migrate.
+	set.Ephemeral = &v1beta1.PostgresEphemeralVolumeSpec{}
+	assert.Assert(t, !dataVolumeOutdated(set, pvc))
+}
+
+func TestMigrateInstanceStorage(t *testing.T) {
+	ctx := context.Background()
+
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name, cluster.UID = "ns1", "hippo", "some-uid"
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{
+		Name:     "00",
+		Replicas: initialize.Int32(1),
+		DataVolumeClaimSpec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: initialize.String("fast"),
+		},
+	}}
+
+	owner := metav1.OwnerReference{
+		APIVersion: v1beta1.GroupVersion.String(), Kind: "PostgresCluster",
+		Name: cluster.Name, UID: cluster.UID, Controller: initialize.Bool(true),
+	}
+	instanceLabels := func(name string) map[string]string {
+		return map[string]string{
+			naming.LabelCluster:     "hippo",
+			naming.LabelInstanceSet: "00",
+			naming.LabelInstance:    name,
+		}
+	}
+
+	// instance returns the StatefulSet, Pod, and data volume of an instance.
+	instance := func(name, class, role string, ready bool) (
+		*appsv1.StatefulSet, *corev1.Pod, *corev1.PersistentVolumeClaim,
+	) {
+		runner := &appsv1.StatefulSet{}
+		runner.Namespace, runner.Name = "ns1", name
+		runner.Labels = instanceLabels(name)
+		runner.OwnerReferences = []metav1.OwnerReference{owner}
+
+		pod := &corev1.Pod{}
+		pod.Namespace, pod.Name = "ns1", name+"-0"
+		pod.Labels = naming.Merge(instanceLabels(name),
+			map[string]string{naming.LabelRole: role})
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}
+
+		pvc := &corev1.PersistentVolumeClaim{}
+		pvc.Namespace, pvc.Name = "ns1", name+"-pgdata"
+		pvc.Labels = naming.Merge(instanceLabels(name),
+			map[string]string{naming.LabelRole: naming.RolePostgresData})
+		pvc.OwnerReferences = []metav1.OwnerReference{owner}
+		pvc.Spec.StorageClassName = initialize.String(class)
+
+		return runner, pod, pvc
+	}
+
+	setup := func(objects ...client.Object) (*Reconciler, *record.FakeRecorder, *[]string) {
+		var commands []string
+		recorder := record.NewFakeRecorder(10)
+		return &Reconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects...).Build(),
+			Recorder: recorder,
+			PodExec: func(_ context.Context, namespace, pod, container string,
+				stdin io.Reader, stdout, stderr io.Writer, command ...string,
+			) error {
+				commands = append(commands, pod+": "+strings.Join(command, " "))
+				_, err := stdout.Write([]byte("Successfully switched over"))
+				return err
+			},
+		}, recorder, &commands
+	}
+
+	t.Run("Current", func(t *testing.T) {
+		runner, pod, pvc := instance("hippo-00-aaaa", "fast", "master", true)
+		reconciler, recorder, _ := setup(runner, pvc)
+		observed := newObservedInstances(cluster,
+			[]appsv1.StatefulSet{*runner}, []corev1.Pod{*pod})
+
+		surge, err := reconciler.migrateInstanceStorage(ctx, cluster, observed,
+			[]corev1.PersistentVolumeClaim{*pvc})
+		assert.NilError(t, err)
+		assert.Equal(t, surge["00"], 0)
+		assert.Equal(t, len(recorder.Events), 0)
+	})
+
+	t.Run("Surge", func(t *testing.T) {
+		runner, pod, pvc := instance("hippo-00-aaaa", "slow", "master", true)
+		reconciler, recorder, _ := setup(runner, pvc)
+		observed := newObservedInstances(cluster,
+			[]appsv1.StatefulSet{*runner}, []corev1.Pod{*pod})
+
+		surge, err := reconciler.migrateInstanceStorage(ctx, cluster, observed,
+			[]corev1.PersistentVolumeClaim{*pvc})
+		assert.NilError(t, err)
+		assert.Equal(t, surge["00"], 1)
+		assert.Equal(t, len(recorder.Events), 0)
+	})
+
+	t.Run("NotReady", func(t *testing.T) {
+		oldRunner, oldPod, oldPVC := instance("hippo-00-aaaa", "slow", "master", true)
+		newRunner, newPod, newPVC := instance("hippo-00-bbbb", "fast", "replica", false)
+		reconciler, recorder, commands := setup(oldRunner, oldPVC, newRunner, newPVC)
+		observed := newObservedInstances(cluster,
+			[]appsv1.StatefulSet{*oldRunner, *newRunner}, []corev1.Pod{*oldPod, *newPod})
+
+		surge, err := reconciler.migrateInstanceStorage(ctx, cluster, observed,
+			[]corev1.PersistentVolumeClaim{*oldPVC, *newPVC})
+		assert.NilError(t, err)
+		assert.Equal(t, surge["00"], 1)
+		assert.Equal(t, len(*commands), 0)
+		assert.Equal(t, len(recorder.Events), 0)
+	})
+
+	t.Run("Switchover", func(t *testing.T) {
+		oldRunner, oldPod, oldPVC := instance("hippo-00-aaaa", "slow", "master", true)
+		newRunner, newPod, newPVC := instance("hippo-00-bbbb", "fast", "replica", true)
+		reconciler, recorder, commands := setup(oldRunner, oldPVC, newRunner, newPVC)
+		observed := newObservedInstances(cluster,
+			[]appsv1.StatefulSet{*oldRunner, *newRunner}, []corev1.Pod{*oldPod, *newPod})
+
+		surge, err := reconciler.migrateInstanceStorage(ctx, cluster, observed,
+			[]corev1.PersistentVolumeClaim{*oldPVC, *newPVC})
+		assert.NilError(t, err)
+		assert.Equal(t, surge["00"], 1)
+		assert.DeepEqual(t, *commands, []string{
+			"hippo-00-aaaa-0: patronictl switchover --scheduled=now --force --master=hippo-00-aaaa-0 --candidate=hippo-00-bbbb-0",
+		})
+		assert.Equal(t, len(recorder.Events), 1)
+
+		// Nothing is deleted until the outdated instance is a replica.
+		assert.NilError(t, reconciler.Client.Get(ctx, client.ObjectKeyFromObject(oldRunner), oldRunner))
+	})
+
+	t.Run("RemoveReplica", func(t *testing.T) {
+		oldRunner, oldPod, oldPVC := instance("hippo-00-aaaa", "slow", "replica", true)
+		newRunner, newPod, newPVC := instance("hippo-00-bbbb", "fast", "master", true)
+		reconciler, recorder, commands := setup(oldRunner, oldPVC, newRunner, newPVC)
+		observed := newObservedInstances(cluster,
+			[]appsv1.StatefulSet{*oldRunner, *newRunner}, []corev1.Pod{*oldPod, *newPod})
+
+		surge, err := reconciler.migrateInstanceStorage(ctx, cluster, observed,
+			[]corev1.PersistentVolumeClaim{*oldPVC, *newPVC})
+		assert.NilError(t, err)
+		assert.Equal(t, surge["00"], 1)
+		assert.Equal(t, len(*commands), 0)
+		assert.Equal(t, len(recorder.Events), 1)
+
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(oldRunner), oldRunner)
+		assert.Assert(t, apierrors.IsNotFound(err), "expected outdated StatefulSet deleted, got %v", err)
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(oldPVC), oldPVC)
+		assert.Assert(t, apierrors.IsNotFound(err), "expected outdated PVC deleted, got %v", err)
+		assert.NilError(t, reconciler.Client.Get(ctx, client.ObjectKeyFromObject(newPVC), newPVC))
+	})
+}
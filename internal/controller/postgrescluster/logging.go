@@ -0,0 +1,148 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+const (
+	// logShipperConfigKey is the key of the Fluent Bit configuration file in
+	// a ConfigMap.
+	logShipperConfigKey = "fluent-bit.conf"
+
+	// logShipperConfigPath is where the log shipper reads its configuration.
+	logShipperConfigPath = "/etc/fluent-bit"
+)
+
+// logShipperInput is a set of log files written by one component of a cluster.
+type logShipperInput struct {
+	// Component is the tag and "component" field of every record.
+	Component string
+
+	// Path is a glob of log files.
+	Path string
+}
+
+// logShipperEnabled returns whether or not cluster ships logs using a sidecar.
+func logShipperEnabled(cluster *v1beta1.PostgresCluster) bool {
+	return cluster.Spec.Logging != nil && cluster.Spec.Logging.Shipper != nil
+}
+
+// logShipperConfig returns the Fluent Bit configuration that reads inputs
+// from the logs volume and includes every user-supplied file. Every record
+// has the same fields so that one pipeline can handle all components.
+// - https://docs.fluentbit.io/manual/administration/configuring-fluent-bit/classic-mode
+func logShipperConfig(
+	cluster *v1beta1.PostgresCluster, logs corev1.VolumeMount, inputs ...logShipperInput,
+) string {
+	lines := []string{
+		`[SERVICE]`,
+		`    Flush 5`,
+		`    Log_Level info`,
+	}
+	for _, input := range inputs {
+		lines = append(lines, ``,
+			`[INPUT]`,
+			`    Name tail`,
+			`    Tag `+input.Component,
+			`    Path `+input.Path,
+			`    DB `+logs.MountPath+`/fluent-bit.db`,
+			`    Refresh_Interval 5`,
+			`    Skip_Long_Lines On`,
+			``,
+			`[FILTER]`,
+			`    Name record_modifier`,
+			`    Match `+input.Component,
+			`    Record cluster `+cluster.Name,
+			`    Record component `+input.Component,
+			`    Record pod ${HOSTNAME}`,
+		)
+	}
+	lines = append(lines, ``,
+		`@INCLUDE `+logShipperConfigPath+`/custom/*.conf`)
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// addLogShipperToConfigMap writes the log shipper configuration for inputs
+// into configMap.
+func addLogShipperToConfigMap(
+	cluster *v1beta1.PostgresCluster, logs corev1.VolumeMount,
+	configMap *corev1.ConfigMap, inputs ...logShipperInput,
+) {
+	initialize.StringMap(&configMap.Data)
+	configMap.Data[logShipperConfigKey] = logShipperConfig(cluster, logs, inputs...)
+}
+
+// addLogShipperToPodSpec adds a Fluent Bit sidecar that reads the logs volume
+// of template using the configuration in configMap.
+func addLogShipperToPodSpec(
+	cluster *v1beta1.PostgresCluster, logs corev1.VolumeMount,
+	configMap *corev1.ConfigMap, template *corev1.PodTemplateSpec,
+) {
+	// The sidecar records its position in each file on the logs volume.
+	logs.ReadOnly = false
+
+	container := corev1.Container{
+		Name:            naming.ContainerLogShipper,
+		Image:           config.FluentBitContainerImage(cluster),
+		ImagePullPolicy: cluster.Spec.ImagePullPolicy,
+		Resources:       cluster.Spec.Logging.Shipper.Resources,
+		Command: []string{
+			"/fluent-bit/bin/fluent-bit", "--config", logShipperConfigPath + "/" + logShipperConfigKey,
+		},
+		SecurityContext: initialize.RestrictedSecurityContext(),
+		VolumeMounts: []corev1.VolumeMount{
+			logs,
+			{Name: "log-shipper-config", MountPath: logShipperConfigPath, ReadOnly: true},
+			{Name: "log-shipper-custom", MountPath: logShipperConfigPath + "/custom", ReadOnly: true},
+		},
+	}
+
+	template.Spec.Containers = append(template.Spec.Containers, container)
+	template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+		Name: "log-shipper-config",
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{{
+					ConfigMap: &corev1.ConfigMapProjection{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: configMap.Name,
+						},
+						Items: []corev1.KeyToPath{{
+							Key: logShipperConfigKey, Path: logShipperConfigKey,
+						}},
+					},
+				}},
+			},
+		},
+	}, corev1.Volume{
+		Name: "log-shipper-custom",
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: cluster.Spec.Logging.Shipper.Configuration,
+			},
+		},
+	})
+}
@@ -0,0 +1,144 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestLogShipperConfig(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Name = "hippo"
+
+	logs := corev1.VolumeMount{Name: "logs", MountPath: "/logs"}
+
+	assert.Equal(t, logShipperConfig(cluster, logs,
+		logShipperInput{Component: "postgres", Path: "/logs/*.csv"},
+		logShipperInput{Component: "other", Path: "/logs/other.log"},
+	), strings.TrimSpace(`
+[SERVICE]
+    Flush 5
+    Log_Level info
+
+[INPUT]
+    Name tail
+    Tag postgres
+    Path /logs/*.csv
+    DB /logs/fluent-bit.db
+    Refresh_Interval 5
+    Skip_Long_Lines On
+
+[FILTER]
+    Name record_modifier
+    Match postgres
+    Record cluster hippo
+    Record component postgres
+    Record pod ${HOSTNAME}
+
+[INPUT]
+    Name tail
+    Tag other
+    Path /logs/other.log
+    DB /logs/fluent-bit.db
+    Refresh_Interval 5
+    Skip_Long_Lines On
+
+[FILTER]
+    Name record_modifier
+    Match other
+    Record cluster hippo
+    Record component other
+    Record pod ${HOSTNAME}
+
+@INCLUDE /etc/fluent-bit/custom/*.conf
+	`)+"\n")
+}
+
+func TestAddLogShipperToPodSpec(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Name = "hippo"
+	cluster.Spec.ImagePullPolicy = corev1.PullAlways
+	assert.NilError(t, yaml.Unmarshal([]byte(`{
+		logging: {
+			shipper: {
+				image: fluent/fluent-bit:1.8,
+				configuration: [{ configMap: { name: outputs } }],
+				resources: { limits: { memory: 64Mi } },
+			},
+		},
+	}`), &cluster.Spec))
+	assert.Assert(t, logShipperEnabled(cluster))
+
+	configMap := new(corev1.ConfigMap)
+	configMap.Name = "hippo-config"
+
+	logs := corev1.VolumeMount{Name: "some-logs", MountPath: "/logs", ReadOnly: true}
+	addLogShipperToConfigMap(cluster, logs, configMap,
+		logShipperInput{Component: "postgres", Path: "/logs/*.log"})
+	assert.Assert(t, strings.Contains(configMap.Data["fluent-bit.conf"], "Path /logs/*.log"))
+
+	template := new(corev1.PodTemplateSpec)
+	addLogShipperToPodSpec(cluster, logs, configMap, template)
+
+	assert.Assert(t, marshalMatches(template.Spec, `
+containers:
+- command:
+  - /fluent-bit/bin/fluent-bit
+  - --config
+  - /etc/fluent-bit/fluent-bit.conf
+  image: fluent/fluent-bit:1.8
+  imagePullPolicy: Always
+  name: log-shipper
+  resources:
+    limits:
+      memory: 64Mi
+  securityContext:
+    allowPrivilegeEscalation: false
+    privileged: false
+    readOnlyRootFilesystem: true
+    runAsNonRoot: true
+  volumeMounts:
+  - mountPath: /logs
+    name: some-logs
+  - mountPath: /etc/fluent-bit
+    name: log-shipper-config
+    readOnly: true
+  - mountPath: /etc/fluent-bit/custom
+    name: log-shipper-custom
+    readOnly: true
+volumes:
+- name: log-shipper-config
+  projected:
+    sources:
+    - configMap:
+        items:
+        - key: fluent-bit.conf
+          path: fluent-bit.conf
+        name: hippo-config
+- name: log-shipper-custom
+  projected:
+    sources:
+    - configMap:
+        name: outputs
+	`))
+}
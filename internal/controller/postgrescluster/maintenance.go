@@ -0,0 +1,423 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/maintenance"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	pgpassword "github.com/crunchydata/postgres-operator/internal/postgres/password"
+	"github.com/crunchydata/postgres-operator/internal/util"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// maintenanceMountPath is where maintenance jobs find their SQL and the
+// certificate authority of the PostgreSQL server.
+const maintenanceMountPath = "/etc/maintenance"
+
+// reconcileMaintenance writes the objects necessary to run scheduled SQL
+// maintenance against the primary of cluster, and removes them when there is
+// no maintenance to run.
+func (r *Reconciler) reconcileMaintenance(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	instances *observedInstances, primaryCertificate *corev1.SecretProjection,
+) error {
+	secret, err := r.reconcileMaintenanceSecret(ctx, cluster)
+	if err == nil {
+		err = r.reconcileMaintenanceUser(ctx, cluster, instances, secret)
+	}
+	if err == nil {
+		err = r.reconcileMaintenanceJobs(ctx, cluster, secret, primaryCertificate)
+	}
+	return err
+}
+
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={get,create,delete,patch}
+
+// reconcileMaintenanceSecret writes the Secret containing the password of the
+// maintenance user. It deletes the Secret and returns nil when maintenance is
+// disabled.
+func (r *Reconciler) reconcileMaintenanceSecret(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (*corev1.Secret, error) {
+	existing := &corev1.Secret{ObjectMeta: naming.MaintenanceUserSecret(cluster)}
+	err := errors.WithStack(
+		r.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing))
+	if client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+
+	if !maintenance.Enabled(cluster) {
+		if err == nil {
+			err = errors.WithStack(r.deleteControlled(ctx, cluster, existing))
+		}
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	intent := &corev1.Secret{ObjectMeta: naming.MaintenanceUserSecret(cluster)}
+	intent.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+
+	intent.Annotations = naming.Merge(cluster.Spec.Metadata.GetAnnotationsOrNil())
+	intent.Labels = naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster: cluster.Name,
+			naming.LabelRole:    naming.RoleMaintenance,
+		})
+
+	intent.Data = make(map[string][]byte)
+	intent.Data["password"] = existing.Data["password"]
+	intent.Data["verifier"] = existing.Data["verifier"]
+
+	// Generate both the password and verifier when either is missing.
+	if len(intent.Data["password"]) == 0 || len(intent.Data["verifier"]) == 0 {
+		password, err := util.GeneratePassword(util.DefaultGeneratedPasswordLength)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		verifier, err := pgpassword.NewSCRAMPassword(password).Build()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		intent.Data["password"] = []byte(password)
+		intent.Data["verifier"] = []byte(verifier)
+	}
+
+	err = errors.WithStack(r.setControllerReference(cluster, intent))
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, intent))
+	}
+	if err == nil {
+		return intent, nil
+	}
+	return nil, err
+}
+
+// reconcileMaintenanceUser creates the maintenance user in PostgreSQL, keeps
+// its password and memberships up-to-date, and prevents it from logging in
+// when maintenance is disabled. Status.Maintenance.UserRevision is used to
+// limit how often SQL is executed.
+func (r *Reconciler) reconcileMaintenanceUser(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	instances *observedInstances, secret *corev1.Secret,
+) error {
+	// Nothing to do when maintenance was never enabled.
+	if secret == nil && cluster.Status.Maintenance == nil {
+		return nil
+	}
+
+	// Find the PostgreSQL instance that can execute SQL that writes system
+	// catalogs. When there is none, return early.
+	pod, _ := instances.writablePod(naming.ContainerDatabase)
+	if pod == nil {
+		return nil
+	}
+
+	action := func(ctx context.Context, exec postgres.Executor) error {
+		return maintenance.DisableInPostgreSQL(ctx, exec)
+	}
+	if secret != nil {
+		var users []string
+		for _, user := range cluster.Spec.Users {
+			users = append(users, string(user.Name))
+		}
+		if cluster.Spec.Users == nil {
+			// See reconcilePostgresUserSecrets.
+			users = append(users, cluster.Name)
+		}
+
+		action = func(ctx context.Context, exec postgres.Executor) error {
+			return maintenance.EnableInPostgreSQL(ctx, exec,
+				string(secret.Data["verifier"]), users)
+		}
+	}
+
+	revision, err := safeHash32(func(hasher io.Writer) error {
+		// Discard log messages about executing SQL.
+		// Nothing is being "executed" yet.
+		return action(logging.NewContext(ctx, logging.Discard()), func(
+			_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			_, err := io.Copy(hasher, stdin)
+			if err == nil {
+				_, err = fmt.Fprint(hasher, command)
+			}
+			return err
+		})
+	})
+
+	if err == nil && (cluster.Status.Maintenance == nil ||
+		cluster.Status.Maintenance.UserRevision != revision) {
+		// The user is out of date and needs to be updated.
+		// Include the revision hash in any log messages.
+		ctx := logging.NewContext(ctx, logging.FromContext(ctx).WithValues("revision", revision))
+
+		err = action(ctx, func(ctx context.Context, stdin io.Reader,
+			stdout, stderr io.Writer, command ...string) error {
+			return r.PodExec(ctx, pod.Namespace, pod.Name, naming.ContainerDatabase,
+				stdin, stdout, stderr, command...)
+		})
+
+		if err == nil && secret != nil {
+			cluster.Status.Maintenance = &v1beta1.PostgresMaintenanceStatus{
+				UserRevision: revision,
+			}
+		}
+		if err == nil && secret == nil {
+			cluster.Status.Maintenance = nil
+		}
+	}
+
+	return err
+}
+
+// +kubebuilder:rbac:groups="",resources="configmaps",verbs={get,create,delete,patch}
+// +kubebuilder:rbac:groups="batch",resources="cronjobs",verbs={list,create,delete,patch}
+
+// reconcileMaintenanceJobs writes a CronJob for every maintenance job of
+// cluster and the ConfigMap of their SQL. It deletes CronJobs that are no
+// longer specified.
+func (r *Reconciler) reconcileMaintenanceJobs(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	secret *corev1.Secret, primaryCertificate *corev1.SecretProjection,
+) error {
+	var jobs []v1beta1.PostgresMaintenanceJob
+	if secret != nil {
+		jobs = maintenance.Jobs(cluster)
+	}
+
+	existing := &batchv1beta1.CronJobList{}
+	selector, err := naming.AsSelector(metav1.LabelSelector{
+		MatchLabels: map[string]string{naming.LabelCluster: cluster.Name},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: naming.LabelMaintenanceJob, Operator: metav1.LabelSelectorOpExists},
+		},
+	})
+	if err == nil {
+		err = errors.WithStack(
+			r.listCronJobs(ctx, existing,
+				client.InNamespace(cluster.Namespace),
+				client.MatchingLabelsSelector{Selector: selector},
+			))
+	}
+
+	specified := make(map[string]bool, len(jobs))
+	for i := range jobs {
+		specified[jobs[i].Name] = true
+	}
+	for i := range existing.Items {
+		if cronjob := &existing.Items[i]; err == nil &&
+			!specified[cronjob.Labels[naming.LabelMaintenanceJob]] {
+			err = errors.WithStack(client.IgnoreNotFound(
+				r.deleteControlled(ctx, cluster, r.cronJobObject(cronjob))))
+		}
+	}
+
+	configmap := &corev1.ConfigMap{ObjectMeta: naming.MaintenanceConfigMap(cluster)}
+	configmap.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+
+	if len(jobs) == 0 {
+		if err == nil {
+			err = errors.WithStack(client.IgnoreNotFound(
+				r.Client.Get(ctx, client.ObjectKeyFromObject(configmap), configmap)))
+		}
+		if err == nil && configmap.UID != "" {
+			err = errors.WithStack(client.IgnoreNotFound(
+				r.deleteControlled(ctx, cluster, configmap)))
+		}
+		return err
+	}
+
+	configmap.Annotations = naming.Merge(cluster.Spec.Metadata.GetAnnotationsOrNil())
+	configmap.Labels = naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster: cluster.Name,
+			naming.LabelRole:    naming.RoleMaintenance,
+		})
+	configmap.Data = make(map[string]string, len(jobs))
+
+	valid := make([]*v1beta1.PostgresMaintenanceJob, 0, len(jobs))
+	for i := range jobs {
+		script, err := maintenance.Script(&jobs[i])
+		if err == nil {
+			_, err = cronScheduleInZone(jobs[i].Schedule, jobs[i].TimeZone, time.Now())
+		}
+		if err != nil {
+			r.Recorder.Event(cluster, corev1.EventTypeWarning, "InvalidMaintenanceJob", err.Error())
+			continue
+		}
+		configmap.Data[jobs[i].Name+".sql"] = script
+		valid = append(valid, &jobs[i])
+	}
+
+	if err == nil {
+		err = errors.WithStack(r.setControllerReference(cluster, configmap))
+	}
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, configmap))
+	}
+
+	for _, job := range valid {
+		var cronjob *batchv1beta1.CronJob
+		if err == nil {
+			cronjob, err = r.generateMaintenanceCronJob(cluster, job, secret, primaryCertificate)
+		}
+		if err == nil {
+			err = errors.WithStack(r.applyCronJob(ctx, cronjob))
+		}
+	}
+
+	return err
+}
+
+// generateMaintenanceCronJob returns a CronJob that runs the SQL of job with
+// psql against the primary of cluster.
+func (r *Reconciler) generateMaintenanceCronJob(
+	cluster *v1beta1.PostgresCluster, job *v1beta1.PostgresMaintenanceJob,
+	secret *corev1.Secret, primaryCertificate *corev1.SecretProjection,
+) (*batchv1beta1.CronJob, error) {
+	cronjob := &batchv1beta1.CronJob{ObjectMeta: naming.MaintenanceCronJob(cluster, job.Name)}
+	cronjob.SetGroupVersionKind(batchv1beta1.SchemeGroupVersion.WithKind("CronJob"))
+
+	cronjob.Annotations = naming.Merge(cluster.Spec.Metadata.GetAnnotationsOrNil())
+	cronjob.Labels = naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster:        cluster.Name,
+			naming.LabelRole:           naming.RoleMaintenance,
+			naming.LabelMaintenanceJob: job.Name,
+		})
+
+	primary := naming.ClusterPrimaryService(cluster)
+	script := maintenanceMountPath + "/" + job.Name + ".sql"
+
+	// Connect to the primary Service over TLS using the password of the
+	// maintenance user. The server certificate must be signed by the authority
+	// of the cluster.
+	// - https://www.postgresql.org/docs/current/libpq-envars.html
+	container := corev1.Container{
+		Name:  naming.ContainerMaintenance,
+		Image: config.PostgresContainerImage(cluster),
+		Command: []string{
+			"psql", "--no-psqlrc", "--echo-errors",
+			"--set=ON_ERROR_STOP=1", "--file=" + script,
+		},
+		Env: []corev1.EnvVar{
+			{Name: "PGHOST", Value: primary.Name + "." + primary.Namespace + ".svc"},
+			{Name: "PGPORT", Value: fmt.Sprint(*cluster.Spec.Port)},
+			{Name: "PGDATABASE", Value: string(job.Database)},
+			{Name: "PGUSER", Value: maintenance.User},
+			{Name: "PGPASSWORD", ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+					Key:                  "password",
+				},
+			}},
+			{Name: "PGAPPNAME", Value: "maintenance-" + job.Name},
+			{Name: "PGSSLMODE", Value: "verify-ca"},
+			{Name: "PGSSLROOTCERT", Value: maintenanceMountPath + "/ca.crt"},
+		},
+		ImagePullPolicy: cluster.Spec.ImagePullPolicy,
+		Resources:       job.Resources,
+		SecurityContext: initialize.RestrictedSecurityContext(),
+		VolumeMounts: []corev1.VolumeMount{{
+			Name:      "maintenance",
+			MountPath: maintenanceMountPath,
+			ReadOnly:  true,
+		}},
+	}
+
+	volume := corev1.Volume{Name: "maintenance"}
+	volume.Projected = &corev1.ProjectedVolumeSource{
+		Sources: []corev1.VolumeProjection{
+			{ConfigMap: &corev1.ConfigMapProjection{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: naming.MaintenanceConfigMap(cluster).Name,
+				},
+				Items: []corev1.KeyToPath{{Key: job.Name + ".sql", Path: job.Name + ".sql"}},
+			}},
+			{Secret: &corev1.SecretProjection{
+				LocalObjectReference: primaryCertificate.LocalObjectReference,
+				Items:                []corev1.KeyToPath{{Key: rootCertFile, Path: rootCertFile}},
+			}},
+		},
+	}
+
+	// Suspend the CronJob when PostgreSQL is shutdown, read-only, or being
+	// repaired. Any jobs that have already started will continue.
+	suspend := (cluster.Spec.Shutdown != nil && *cluster.Spec.Shutdown) ||
+		cluster.StandbyEnabled() ||
+		repairStopsCluster(cluster)
+
+	// CronJobs of this Kubernetes version interpret schedules in UTC.
+	schedule, err := cronScheduleInZone(job.Schedule, job.TimeZone, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	cronjob.Spec = batchv1beta1.CronJobSpec{
+		Schedule:          schedule,
+		Suspend:           &suspend,
+		ConcurrencyPolicy: batchv1beta1.ForbidConcurrent,
+		JobTemplate: batchv1beta1.JobTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: cronjob.Annotations,
+				Labels:      cronjob.Labels,
+			},
+			Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: cronjob.Annotations,
+						Labels:      cronjob.Labels,
+					},
+					Spec: corev1.PodSpec{
+						Affinity: architectureAffinity(config.ImageArchitectures(cluster), nil),
+
+						// The job talks only to PostgreSQL.
+						AutomountServiceAccountToken: initialize.Bool(false),
+						Containers:                   []corev1.Container{container},
+						EnableServiceLinks:           initialize.Bool(false),
+						ImagePullSecrets:             cluster.Spec.ImagePullSecrets,
+						RestartPolicy:                corev1.RestartPolicyNever,
+						SecurityContext:              initialize.RestrictedPodSecurityContext(),
+						Volumes:                      []corev1.Volume{volume},
+					},
+				},
+			},
+		},
+	}
+	setPodNameResolution(cluster, &cronjob.Spec.JobTemplate.Spec.Template.Spec)
+
+	err = errors.WithStack(r.setControllerReference(cluster, cronjob))
+
+	return cronjob, err
+}
@@ -0,0 +1,208 @@
+//go:build envtest
+// +build envtest
+
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestGenerateMaintenanceCronJob(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.Spec.Port = initialize.Int32(5432)
+	cluster.Spec.Image = "postgres-image"
+
+	job := &v1beta1.PostgresMaintenanceJob{
+		Name:     "nightly",
+		Schedule: "0 3 * * *",
+		Task:     "vacuumAnalyze",
+		Database: "app",
+	}
+	secret := &corev1.Secret{ObjectMeta: naming.MaintenanceUserSecret(cluster)}
+	certificate := &corev1.SecretProjection{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "hippo-cluster-cert"},
+	}
+
+	cronjob, err := reconciler.generateMaintenanceCronJob(cluster, job, secret, certificate)
+	assert.NilError(t, err)
+
+	assert.Equal(t, cronjob.Name, "hippo-maintenance-nightly")
+	assert.Equal(t, cronjob.Labels[naming.LabelCluster], "hippo")
+	assert.Equal(t, cronjob.Labels[naming.LabelMaintenanceJob], "nightly")
+	assert.Equal(t, cronjob.Spec.Schedule, "0 3 * * *")
+	assert.Equal(t, *cronjob.Spec.Suspend, false)
+
+	pod := cronjob.Spec.JobTemplate.Spec.Template.Spec
+	assert.Equal(t, pod.RestartPolicy, corev1.RestartPolicyNever)
+	assert.Equal(t, *pod.AutomountServiceAccountToken, false)
+	assert.Equal(t, len(pod.Containers), 1)
+
+	assert.Assert(t, marshalMatches(pod.Containers[0].Command, `
+- psql
+- --no-psqlrc
+- --echo-errors
+- --set=ON_ERROR_STOP=1
+- --file=/etc/maintenance/nightly.sql
+	`))
+	assert.Assert(t, marshalMatches(pod.Containers[0].Env, `
+- name: PGHOST
+  value: hippo-primary.ns1.svc
+- name: PGPORT
+  value: "5432"
+- name: PGDATABASE
+  value: app
+- name: PGUSER
+  value: _crunchymaintenance
+- name: PGPASSWORD
+  valueFrom:
+    secretKeyRef:
+      key: password
+      name: hippo-maintenance
+- name: PGAPPNAME
+  value: maintenance-nightly
+- name: PGSSLMODE
+  value: verify-ca
+- name: PGSSLROOTCERT
+  value: /etc/maintenance/ca.crt
+	`))
+	assert.Assert(t, marshalMatches(pod.Volumes, `
+- name: maintenance
+  projected:
+    sources:
+    - configMap:
+        items:
+        - key: nightly.sql
+          path: nightly.sql
+        name: hippo-maintenance
+    - secret:
+        items:
+        - key: ca.crt
+          path: ca.crt
+        name: hippo-cluster-cert
+	`))
+
+	t.Run("Shutdown", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Shutdown = initialize.Bool(true)
+
+		cronjob, err := reconciler.generateMaintenanceCronJob(cluster, job, secret, certificate)
+		assert.NilError(t, err)
+		assert.Equal(t, *cronjob.Spec.Suspend, true)
+	})
+
+	t.Run("Standby", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Standby = &v1beta1.PostgresStandbySpec{Enabled: true}
+
+		cronjob, err := reconciler.generateMaintenanceCronJob(cluster, job, secret, certificate)
+		assert.NilError(t, err)
+		assert.Equal(t, *cronjob.Spec.Suspend, true)
+	})
+
+	t.Run("TimeZone", func(t *testing.T) {
+		job := job.DeepCopy()
+		job.TimeZone = "Asia/Tokyo"
+
+		cronjob, err := reconciler.generateMaintenanceCronJob(cluster, job, secret, certificate)
+		assert.NilError(t, err)
+		assert.Equal(t, cronjob.Spec.Schedule, "0 18 * * *")
+
+		job.TimeZone = "Mars/Olympus_Mons"
+		_, err = reconciler.generateMaintenanceCronJob(cluster, job, secret, certificate)
+		assert.ErrorContains(t, err, "invalid time zone")
+	})
+}
+
+func TestReconcileMaintenanceUser(t *testing.T) {
+	ctx := context.Background()
+
+	var called int
+	reconciler := &Reconciler{
+		PodExec: func(_ context.Context, namespace, pod, container string, stdin io.Reader, stdout,
+			stderr io.Writer, command ...string) error {
+			called++
+			return nil
+		},
+	}
+
+	instances := &observedInstances{forCluster: []*Instance{{
+		Name: "daisy",
+		Pods: []*corev1.Pod{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "daisy-pod",
+				Annotations: map[string]string{"status": `{"role":"master"}`},
+			},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  naming.ContainerDatabase,
+					State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				}},
+			},
+		}},
+		Runner: &appsv1.StatefulSet{},
+	}}}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Name = "hippo"
+	secret := &corev1.Secret{Data: map[string][]byte{"verifier": []byte("blah")}}
+
+	t.Run("NeverEnabled", func(t *testing.T) {
+		assert.NilError(t, reconciler.reconcileMaintenanceUser(ctx, cluster, instances, nil))
+		assert.Equal(t, called, 0)
+		assert.Assert(t, cluster.Status.Maintenance == nil)
+	})
+
+	t.Run("Enable", func(t *testing.T) {
+		assert.NilError(t, reconciler.reconcileMaintenanceUser(ctx, cluster, instances, secret))
+		assert.Equal(t, called, 1)
+		assert.Assert(t, cluster.Status.Maintenance != nil)
+		assert.Assert(t, cluster.Status.Maintenance.UserRevision != "")
+
+		// Nothing changed, so nothing is executed.
+		assert.NilError(t, reconciler.reconcileMaintenanceUser(ctx, cluster, instances, secret))
+		assert.Equal(t, called, 1)
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		assert.NilError(t, reconciler.reconcileMaintenanceUser(ctx, cluster, instances, nil))
+		assert.Equal(t, called, 2)
+		assert.Assert(t, cluster.Status.Maintenance == nil)
+	})
+}
@@ -0,0 +1,72 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// ConditionMemoryGuardrail is the type used in a condition to indicate that
+// PostgreSQL may use more memory than the limit of an instance set. Its message
+// lists those instance sets.
+const ConditionMemoryGuardrail = "MemoryGuardrail"
+
+// EventMemoryGuardrail is the event reason used when PostgreSQL may use more
+// memory than the limit of an instance set.
+const EventMemoryGuardrail = "MemoryGuardrail"
+
+// reconcileMemoryGuardrails reports in the status of cluster whether memory
+// settings are likely to get PostgreSQL killed. An event is recorded when the
+// problems change. These are also checked by the validating webhook, when it
+// is installed.
+func (r *Reconciler) reconcileMemoryGuardrails(cluster *v1beta1.PostgresCluster) {
+	problems := postgres.MemoryGuardrails(cluster)
+
+	if len(problems) == 0 {
+		if meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionMemoryGuardrail) {
+			meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+				ObservedGeneration: cluster.GetGeneration(),
+				Type:               ConditionMemoryGuardrail,
+				Status:             metav1.ConditionFalse,
+				Reason:             "WithinLimits",
+				Message:            "PostgreSQL memory settings fit within the limits",
+			})
+		}
+		return
+	}
+
+	message := strings.Join(problems, "; ")
+
+	if condition := meta.FindStatusCondition(cluster.Status.Conditions,
+		ConditionMemoryGuardrail); condition == nil ||
+		condition.Status != metav1.ConditionTrue || condition.Message != message {
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, EventMemoryGuardrail, message)
+	}
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		ObservedGeneration: cluster.GetGeneration(),
+		Type:               ConditionMemoryGuardrail,
+		Status:             metav1.ConditionTrue,
+		Reason:             EventMemoryGuardrail,
+		Message:            message,
+	})
+}
@@ -0,0 +1,76 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestReconcileMemoryGuardrails(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{Name: "one"}}
+	cluster.Spec.InstanceSets[0].Resources.Limits = corev1.ResourceList{
+		corev1.ResourceMemory: resource.MustParse("256Mi"),
+	}
+	cluster.Spec.Patroni = new(v1beta1.PatroniSpec)
+	cluster.Spec.Patroni.DynamicConfiguration = runtime.RawExtension{
+		Raw: []byte(`{"postgresql":{"parameters":{"shared_buffers":"512MB"}}}`),
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	r := &Reconciler{Recorder: recorder}
+
+	r.reconcileMemoryGuardrails(cluster)
+	assert.Assert(t, meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionMemoryGuardrail))
+	assert.Equal(t, len(recorder.Events), 1)
+	assert.Assert(t, strings.Contains(<-recorder.Events, `instance set "one"`))
+
+	// The event is recorded only once.
+	r.reconcileMemoryGuardrails(cluster)
+	assert.Equal(t, len(recorder.Events), 0)
+
+	// Another event is recorded when the problems change.
+	cluster.Spec.InstanceSets = append(cluster.Spec.InstanceSets, cluster.Spec.InstanceSets[0])
+	cluster.Spec.InstanceSets[1].Name = "two"
+	r.reconcileMemoryGuardrails(cluster)
+	assert.Equal(t, len(recorder.Events), 1)
+	assert.Assert(t, strings.Contains(<-recorder.Events, `instance set "two"`))
+
+	// The condition resolves once the settings fit.
+	cluster.Spec.Patroni.DynamicConfiguration.Raw = []byte(
+		`{"postgresql":{"parameters":{"shared_buffers":"64MB","max_connections":20}}}`)
+	r.reconcileMemoryGuardrails(cluster)
+	assert.Assert(t, !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionMemoryGuardrail))
+	assert.Equal(t, len(recorder.Events), 0)
+
+	t.Run("NoProblems", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Status.Conditions = nil
+
+		r.reconcileMemoryGuardrails(cluster)
+		assert.Assert(t, meta.FindStatusCondition(cluster.Status.Conditions, ConditionMemoryGuardrail) == nil)
+	})
+}
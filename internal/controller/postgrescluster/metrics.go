@@ -0,0 +1,82 @@
+package postgrescluster
+
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// driftDetectedTotal counts the times something other than this controller
+// changed fields this controller applied. See Reconciler.watchDrift.
+var driftDetectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "postgres_operator",
+	Name:      "drift_detected_total",
+	Help:      "Number of times an operator-managed field was modified by another field manager.",
+}, []string{"kind"})
+
+// volumeUsageWarningsTotal counts the times a PostgreSQL volume filled past its
+// warning percent. See Reconciler.reconcileVolumeUsage.
+var volumeUsageWarningsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "postgres_operator",
+	Name:      "volume_usage_warnings_total",
+	Help:      "Number of times a PostgreSQL volume filled past its warning percent.",
+}, []string{"volume"})
+
+// reconcileErrorsTotal counts the times reconciling a PostgresCluster failed,
+// by class of error. See classifyReconcileError.
+var reconcileErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "postgres_operator",
+	Name:      "reconcile_errors_total",
+	Help:      "Number of times reconciling a PostgresCluster failed, by class of error.",
+}, []string{"namespace", "postgrescluster", "class"})
+
+// pgbackrestLastBackupTimestamp is the time the newest backup of each type
+// finished in each pgBackRest repository. See Reconciler.reconcileStanzaHealth.
+var pgbackrestLastBackupTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "postgres_operator",
+	Name:      "pgbackrest_last_backup_timestamp_seconds",
+	Help:      "Unix time the newest pgBackRest backup of a type finished in a repository.",
+}, []string{"namespace", "postgrescluster", "repo", "type"})
+
+// pgbackrestRepoSizeBytes is the size of the backups in each pgBackRest
+// repository. See Reconciler.reconcileStanzaHealth.
+var pgbackrestRepoSizeBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "postgres_operator",
+	Name:      "pgbackrest_repo_size_bytes",
+	Help:      "Number of bytes stored for the pgBackRest backups in a repository, excluding WAL.",
+}, []string{"namespace", "postgrescluster", "repo"})
+
+// pgbackrestLastArchivedTimestamp is the time PostgreSQL last archived a WAL
+// file to pgBackRest. See Reconciler.reconcileStanzaHealth.
+var pgbackrestLastArchivedTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "postgres_operator",
+	Name:      "pgbackrest_last_archived_wal_timestamp_seconds",
+	Help:      "Unix time PostgreSQL last archived a WAL file to pgBackRest.",
+}, []string{"namespace", "postgrescluster"})
+
+func init() {
+	// Register with the same registry as controller-runtime so these are
+	// served by the manager's metrics endpoint.
+	metrics.Registry.MustRegister(
+		driftDetectedTotal,
+		pgbackrestLastArchivedTimestamp,
+		pgbackrestLastBackupTimestamp,
+		pgbackrestRepoSizeBytes,
+		reconcileErrorsTotal,
+		volumeUsageWarningsTotal,
+	)
+}
@@ -0,0 +1,170 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// EventNodeFailureRecovery is the event reason used when a Pod stuck
+// terminating on a failed node is forcefully deleted.
+const EventNodeFailureRecovery = "NodeFailureRecovery"
+
+// nodeNotReadySince returns when node stopped being ready. It returns nil when
+// node is ready or does not report its readiness.
+func nodeNotReadySince(node *corev1.Node) *metav1.Time {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady && condition.Status != corev1.ConditionTrue {
+			return condition.LastTransitionTime.DeepCopy()
+		}
+	}
+	return nil
+}
+
+// leaderLeaseRemaining returns how long the Patroni leader lock in leader is
+// held by member. It returns zero when member does not hold the lock or the
+// lock has expired. The lock is considered held indefinitely when its renew
+// time or TTL cannot be parsed.
+// - https://github.com/zalando/patroni/blob/v2.1.1/patroni/dcs/kubernetes.py
+func leaderLeaseRemaining(leader metav1.Object, member string, now time.Time) (time.Duration, bool) {
+	annotations := leader.GetAnnotations()
+	if annotations["leader"] != member {
+		return 0, true
+	}
+
+	renewed, err := time.Parse(time.RFC3339Nano, annotations["renewTime"])
+	if err != nil {
+		return 0, false
+	}
+	ttl, err := strconv.Atoi(annotations["ttl"])
+	if err != nil {
+		return 0, false
+	}
+
+	if remaining := renewed.Add(time.Duration(ttl) * time.Second).Sub(now); remaining > 0 {
+		return remaining, true
+	}
+	return 0, true
+}
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
+// +kubebuilder:rbac:groups="",resources=endpoints,verbs=get
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get
+// +kubebuilder:rbac:groups="",resources=pods,verbs=delete
+
+// reconcileNodeFailureRecovery forcefully deletes instance Pods that are stuck
+// terminating on a node that has not been ready for the duration in their
+// instance set's recoverAfterNodeFailureSeconds. The kubelet of such a node
+// cannot confirm that the Pod stopped, so its StatefulSet never recreates it.
+// The Pod of the Patroni leader is deleted only after the leader lock expires;
+// Patroni on that node can no longer renew it, and a replica is promoted.
+func (r *Reconciler) reconcileNodeFailureRecovery(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, observed *observedInstances,
+) (reconcile.Result, error) {
+	log := logging.FromContext(ctx)
+
+	var leader client.Object
+	var result reconcile.Result
+	requeue := func(remaining time.Duration) {
+		if result.RequeueAfter == 0 || remaining < result.RequeueAfter {
+			result.RequeueAfter = remaining
+		}
+	}
+
+	for _, instance := range observed.forCluster {
+		if instance.Spec == nil || instance.Spec.RecoverAfterNodeFailureSeconds == nil {
+			continue
+		}
+		threshold := time.Duration(*instance.Spec.RecoverAfterNodeFailureSeconds) * time.Second
+
+		for _, pod := range instance.Pods {
+			if pod.DeletionTimestamp == nil || pod.Spec.NodeName == "" {
+				continue
+			}
+
+			// Nodes are read directly from the API rather than cached; see
+			// observeZones.
+			node := &corev1.Node{}
+			{
+				object := &unstructured.Unstructured{}
+				object.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Node"))
+				err := r.Client.Get(ctx, client.ObjectKey{Name: pod.Spec.NodeName}, object)
+				if err == nil {
+					err = runtime.DefaultUnstructuredConverter.FromUnstructured(
+						object.UnstructuredContent(), node)
+				}
+				if err != nil {
+					log.V(1).Info("unable to read node readiness",
+						"node", pod.Spec.NodeName, "error", err.Error())
+					continue
+				}
+			}
+
+			since := nodeNotReadySince(node)
+			if since == nil {
+				continue
+			}
+			if remaining := time.Until(since.Add(threshold)); remaining > 0 {
+				requeue(remaining)
+				continue
+			}
+
+			// Fence the leader: wait until Patroni on the failed node can no
+			// longer act as primary.
+			if leader == nil {
+				leader, _, _ = patroniDistributedConfiguration(cluster)
+				err := errors.WithStack(client.IgnoreNotFound(
+					r.Client.Get(ctx, client.ObjectKeyFromObject(leader), leader)))
+				if err != nil {
+					return result, err
+				}
+			}
+			if remaining, ok := leaderLeaseRemaining(leader, pod.Name, time.Now()); !ok {
+				log.Info("unable to determine Patroni leader lock expiration", "pod", pod.Name)
+				continue
+			} else if remaining > 0 {
+				requeue(remaining)
+				continue
+			}
+
+			err := errors.WithStack(client.IgnoreNotFound(
+				r.Client.Delete(ctx, pod, client.GracePeriodSeconds(0),
+					client.Preconditions{UID: &pod.UID})))
+			if err != nil {
+				return result, err
+			}
+
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, EventNodeFailureRecovery,
+				"Forcefully deleted Pod %q of instance %q; node %q has not been ready since %v",
+				pod.Name, instance.Name, pod.Spec.NodeName, since.UTC().Format(time.RFC3339))
+		}
+	}
+
+	return result, nil
+}
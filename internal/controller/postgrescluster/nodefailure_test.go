@@ -0,0 +1,217 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestNodeNotReadySince(t *testing.T) {
+	earlier := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+
+	node := &corev1.Node{}
+	assert.Assert(t, nodeNotReadySince(node) == nil)
+
+	node.Status.Conditions = []corev1.NodeCondition{{
+		Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastTransitionTime: earlier,
+	}}
+	assert.Assert(t, nodeNotReadySince(node) == nil)
+
+	node.Status.Conditions[0].Status = corev1.ConditionUnknown
+	assert.DeepEqual(t, nodeNotReadySince(node), &earlier)
+}
+
+func TestLeaderLeaseRemaining(t *testing.T) {
+	now := time.Date(2021, time.September, 1, 12, 0, 0, 0, time.UTC)
+	leader := &corev1.Endpoints{}
+	leader.Annotations = map[string]string{
+		"leader":    "hippo-00-aaaa-0",
+		"renewTime": "2021-09-01T11:59:50.123456+00:00",
+		"ttl":       "30",
+	}
+
+	remaining, ok := leaderLeaseRemaining(leader, "hippo-00-bbbb-0", now)
+	assert.Assert(t, ok)
+	assert.Equal(t, remaining, time.Duration(0))
+
+	remaining, ok = leaderLeaseRemaining(leader, "hippo-00-aaaa-0", now)
+	assert.Assert(t, ok)
+	assert.Equal(t, remaining, 20123456*time.Microsecond)
+
+	remaining, ok = leaderLeaseRemaining(leader, "hippo-00-aaaa-0", now.Add(time.Minute))
+	assert.Assert(t, ok)
+	assert.Equal(t, remaining, time.Duration(0))
+
+	leader.Annotations["ttl"] = "nope"
+	_, ok = leaderLeaseRemaining(leader, "hippo-00-aaaa-0", now)
+	assert.Assert(t, !ok)
+}
+
+func TestReconcileNodeFailureRecovery(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{
+		Name: "00", RecoverAfterNodeFailureSeconds: initialize.Int32(300),
+	}}
+
+	newNode := func(ready bool, since time.Duration) *corev1.Node {
+		node := &corev1.Node{}
+		node.Name = "node1"
+		node.Status.Conditions = []corev1.NodeCondition{{
+			Type: corev1.NodeReady, Status: corev1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-since)),
+		}}
+		if !ready {
+			node.Status.Conditions[0].Status = corev1.ConditionUnknown
+		}
+		return node
+	}
+
+	newPod := func() *corev1.Pod {
+		pod := &corev1.Pod{}
+		pod.Namespace, pod.Name, pod.UID = "ns1", "hippo-00-aaaa-0", "pod-uid"
+		pod.Labels = map[string]string{naming.LabelRole: naming.RolePatroniLeader}
+		pod.Spec.NodeName = "node1"
+		pod.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+		return pod
+	}
+
+	newLeader := func(member string, renewed time.Time) *corev1.Endpoints {
+		leader := &corev1.Endpoints{ObjectMeta: naming.PatroniLeaderEndpoints(cluster)}
+		leader.Annotations = map[string]string{
+			"leader":    member,
+			"renewTime": renewed.UTC().Format(time.RFC3339Nano),
+			"ttl":       "30",
+		}
+		return leader
+	}
+
+	setup := func(objects ...client.Object) (*Reconciler, *record.FakeRecorder) {
+		recorder := record.NewFakeRecorder(10)
+		return &Reconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects...).Build(),
+			Recorder: recorder,
+		}, recorder
+	}
+
+	observe := func(pod *corev1.Pod) *observedInstances {
+		return &observedInstances{forCluster: []*Instance{{
+			Name: "hippo-00-aaaa", Pods: []*corev1.Pod{pod},
+			Spec: &cluster.Spec.InstanceSets[0],
+		}}}
+	}
+
+	exists := func(t testing.TB, r *Reconciler, pod *corev1.Pod) bool {
+		err := r.Client.Get(ctx, client.ObjectKeyFromObject(pod), &corev1.Pod{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		assert.NilError(t, err)
+		return true
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		pod := newPod()
+		r, _ := setup(pod, newNode(false, time.Hour))
+
+		result, err := r.reconcileNodeFailureRecovery(ctx, cluster, &observedInstances{
+			forCluster: []*Instance{{Pods: []*corev1.Pod{pod}, Spec: &v1beta1.PostgresInstanceSetSpec{}}},
+		})
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.Assert(t, exists(t, r, pod))
+	})
+
+	t.Run("NodeReady", func(t *testing.T) {
+		pod := newPod()
+		r, _ := setup(pod, newNode(true, time.Hour))
+
+		result, err := r.reconcileNodeFailureRecovery(ctx, cluster, observe(pod))
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.Assert(t, exists(t, r, pod))
+	})
+
+	t.Run("Recent", func(t *testing.T) {
+		pod := newPod()
+		r, _ := setup(pod, newNode(false, time.Minute))
+
+		result, err := r.reconcileNodeFailureRecovery(ctx, cluster, observe(pod))
+		assert.NilError(t, err)
+		assert.Assert(t, result.RequeueAfter > 3*time.Minute, "got %v", result.RequeueAfter)
+		assert.Assert(t, result.RequeueAfter <= 4*time.Minute, "got %v", result.RequeueAfter)
+		assert.Assert(t, exists(t, r, pod))
+	})
+
+	t.Run("LeaderLockHeld", func(t *testing.T) {
+		pod := newPod()
+		r, _ := setup(pod, newNode(false, time.Hour), newLeader(pod.Name, time.Now()))
+
+		result, err := r.reconcileNodeFailureRecovery(ctx, cluster, observe(pod))
+		assert.NilError(t, err)
+		assert.Assert(t, result.RequeueAfter > 0)
+		assert.Assert(t, result.RequeueAfter <= 30*time.Second, "got %v", result.RequeueAfter)
+		assert.Assert(t, exists(t, r, pod))
+	})
+
+	t.Run("LeaderLockExpired", func(t *testing.T) {
+		pod := newPod()
+		r, recorder := setup(pod, newNode(false, time.Hour),
+			newLeader(pod.Name, time.Now().Add(-time.Hour)))
+
+		result, err := r.reconcileNodeFailureRecovery(ctx, cluster, observe(pod))
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.Assert(t, !exists(t, r, pod))
+		assert.Assert(t, strings.Contains(<-recorder.Events, EventNodeFailureRecovery))
+	})
+
+	t.Run("Replica", func(t *testing.T) {
+		pod := newPod()
+		pod.Labels[naming.LabelRole] = naming.RolePatroniReplica
+		r, recorder := setup(pod, newNode(false, time.Hour),
+			newLeader("hippo-00-bbbb-0", time.Now()))
+
+		result, err := r.reconcileNodeFailureRecovery(ctx, cluster, observe(pod))
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.Assert(t, !exists(t, r, pod))
+		assert.Assert(t, strings.Contains(<-recorder.Events, "node1"))
+	})
+}
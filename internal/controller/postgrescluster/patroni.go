@@ -17,11 +17,13 @@ package postgrescluster
 
 import (
 	"context"
+	"encoding/json"
 	"io"
-	"time"
+	"sort"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -36,6 +38,7 @@ import (
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=deletecollection
 // +kubebuilder:rbac:groups="",resources=endpoints,verbs=deletecollection
 
 func (r *Reconciler) deletePatroniArtifacts(
@@ -45,6 +48,7 @@ func (r *Reconciler) deletePatroniArtifacts(
 	// as Patroni creates them. Would their events cause too many reconciles?
 	// Foreground deletion may force us to adopt and set finalizers anyway.
 
+	// Delete both kinds of DCS objects in case the kind changed at some point.
 	selector, err := naming.AsSelector(naming.ClusterPatronis(cluster))
 	if err == nil {
 		err = errors.WithStack(
@@ -53,11 +57,71 @@ func (r *Reconciler) deletePatroniArtifacts(
 				client.MatchingLabelsSelector{Selector: selector},
 			))
 	}
+	if err == nil {
+		err = errors.WithStack(
+			r.Client.DeleteAllOf(ctx, &corev1.ConfigMap{},
+				client.InNamespace(cluster.Namespace),
+				client.MatchingLabelsSelector{Selector: selector},
+			))
+	}
 
 	return err
 }
 
-// +kubebuilder:rbac:groups="",resources=services,verbs=create;patch
+// patroniDistributedConfiguration returns empty objects of the kind Patroni
+// uses for the DCS of cluster: its leader lock, its configuration, and its
+// failover key. Patroni stores the same annotations on either kind.
+// - https://github.com/zalando/patroni/blob/v2.1.1/patroni/dcs/kubernetes.py
+func patroniDistributedConfiguration(
+	cluster *v1beta1.PostgresCluster,
+) (leader, config, failover client.Object) {
+	if patroni.ClusterUsesConfigMaps(cluster) {
+		return &corev1.ConfigMap{ObjectMeta: naming.PatroniLeaderConfigMap(cluster)},
+			&corev1.ConfigMap{ObjectMeta: naming.PatroniDistributedConfiguration(cluster)},
+			&corev1.ConfigMap{ObjectMeta: naming.PatroniTrigger(cluster)}
+	}
+	return &corev1.Endpoints{ObjectMeta: naming.PatroniLeaderEndpoints(cluster)},
+		&corev1.Endpoints{ObjectMeta: naming.PatroniDistributedConfiguration(cluster)},
+		&corev1.Endpoints{ObjectMeta: naming.PatroniTrigger(cluster)}
+}
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=create;patch
+
+// reconcilePatroniAuthentication writes the Secret that contains credentials
+// for the Patroni REST API of cluster.
+func (r *Reconciler) reconcilePatroniAuthentication(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) error {
+	existing := &corev1.Secret{ObjectMeta: naming.PatroniAuthentication(cluster)}
+	err := errors.WithStack(client.IgnoreNotFound(
+		r.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing)))
+
+	intent := &corev1.Secret{ObjectMeta: naming.PatroniAuthentication(cluster)}
+	intent.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+
+	intent.Annotations = naming.Merge(cluster.Spec.Metadata.GetAnnotationsOrNil())
+	intent.Labels = naming.Merge(cluster.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster: cluster.Name,
+		})
+
+	intent.Type = corev1.SecretTypeOpaque
+
+	if err == nil {
+		err = errors.WithStack(r.setControllerReference(cluster, intent))
+	}
+	if err == nil {
+		err = patroni.AuthenticationSecret(ctx, existing, intent)
+	}
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, intent))
+	}
+	return err
+}
+
+// +kubebuilder:rbac:groups="",resources=services,verbs=get
+// +kubebuilder:rbac:groups="",resources=services,verbs=create;delete;patch
 
 // reconcilePatroniDistributedConfiguration sets labels and ownership on the
 // objects Patroni creates for its distributed configuration.
@@ -74,6 +138,16 @@ func (r *Reconciler) reconcilePatroniDistributedConfiguration(
 	dcsService := &corev1.Service{ObjectMeta: naming.PatroniDistributedConfiguration(cluster)}
 	dcsService.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Service"))
 
+	// ConfigMaps need no Service. Remove the one created for Endpoints, if any.
+	if patroni.ClusterUsesConfigMaps(cluster) {
+		err := errors.WithStack(client.IgnoreNotFound(
+			r.Client.Get(ctx, client.ObjectKeyFromObject(dcsService), dcsService)))
+		if err == nil && dcsService.UID != "" {
+			err = errors.WithStack(r.deleteControlled(ctx, cluster, dcsService))
+		}
+		return err
+	}
+
 	err := errors.WithStack(r.setControllerReference(cluster, dcsService))
 
 	dcsService.Annotations = naming.Merge(
@@ -131,8 +205,8 @@ func (r *Reconciler) reconcilePatroniDynamicConfiguration(
 	// NOTE(cbandy): Despite the guards above, calling PodExec may still fail
 	// due to a missing or stopped container.
 
-	exec := func(_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
-		return r.PodExec(pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+	exec := func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+		return r.PodExec(ctx, pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
 	}
 
 	// Deserialize the schemaless field. There will be no error because the
@@ -144,12 +218,83 @@ func (r *Reconciler) reconcilePatroniDynamicConfiguration(
 
 	configuration = patroni.DynamicConfiguration(cluster, configuration, pgHBAs, pgParameters)
 
-	return errors.WithStack(
+	// Patroni stores its dynamic configuration as JSON in an annotation of
+	// its DCS configuration. Compare the two after encoding and decoding ours.
+	_, dcs, _ := patroniDistributedConfiguration(cluster)
+	err := errors.WithStack(client.IgnoreNotFound(
+		r.Client.Get(ctx, client.ObjectKeyFromObject(dcs), dcs)))
+
+	var stored, intended interface{}
+	_ = json.Unmarshal([]byte(dcs.GetAnnotations()["config"]), &stored)
+	if b, e := json.Marshal(configuration); e == nil {
+		_ = json.Unmarshal(b, &intended)
+	}
+	if err != nil || equality.Semantic.DeepEqual(stored, intended) {
+		return err
+	}
+
+	// Patroni applies a new configuration in its next loop. Ask every member
+	// to reload now so that parameters that do not require a restart take
+	// effect within seconds. See reconcilePatroniPendingRestart.
+	err = errors.WithStack(
 		patroni.Executor(exec).ReplaceConfiguration(ctx, configuration))
+	if err == nil {
+		err = errors.WithStack(
+			patroni.Executor(exec).ReloadConfiguration(ctx, naming.PatroniScope(cluster)))
+	}
+	return err
+}
+
+// +kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+
+// reconcilePatroniPendingRestart populates cluster.Status.Patroni with the
+// PostgreSQL parameters that changed but take effect only after a restart.
+// Patroni reports when an instance is pending restart; PostgreSQL reports which
+// parameters are.
+func (r *Reconciler) reconcilePatroniPendingRestart(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) error {
+	var pending []string
+	seen := make(map[string]bool)
+
+	for _, instance := range instances.forCluster {
+		running, known := instance.IsRunning(naming.ContainerDatabase)
+		if !running || !known || len(instance.Pods) == 0 ||
+			!patroni.PodPendingRestart(instance.Pods[0]) {
+			continue
+		}
+
+		pod := instance.Pods[0]
+		exec := func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+			return r.PodExec(ctx, pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+		}
+
+		names, err := postgres.PendingRestart(ctx, exec)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				pending = append(pending, name)
+			}
+		}
+	}
+	sort.Strings(pending)
+
+	if cluster.Status.Patroni == nil && len(pending) > 0 {
+		cluster.Status.Patroni = new(v1beta1.PatroniStatus)
+	}
+	if cluster.Status.Patroni != nil {
+		cluster.Status.Patroni.PendingRestart = pending
+	}
+	return nil
 }
 
 // generatePatroniLeaderLeaseService returns a v1.Service that exposes the
-// Patroni leader when Patroni is using Endpoints for its leader elections.
+// Patroni leader. When Patroni is using Endpoints for its leader elections, it
+// manages the Endpoints of this Service. Otherwise, the Service selects the
+// Pod that Patroni labels as its leader.
 func (r *Reconciler) generatePatroniLeaderLeaseService(
 	cluster *v1beta1.PostgresCluster) (*corev1.Service, error,
 ) {
@@ -169,6 +314,12 @@ func (r *Reconciler) generatePatroniLeaderLeaseService(
 	// Patroni will ensure that they always route to the elected leader.
 	// - https://docs.k8s.io/concepts/services-networking/service/#services-without-selectors
 	service.Spec.Selector = nil
+	if patroni.ClusterUsesConfigMaps(cluster) {
+		service.Spec.Selector = map[string]string{
+			naming.LabelCluster: cluster.Name,
+			naming.LabelRole:    naming.RolePatroniLeader,
+		}
+	}
 	if cluster.Spec.Service != nil {
 		service.Spec.Type = corev1.ServiceType(cluster.Spec.Service.Type)
 	} else {
@@ -192,13 +343,14 @@ func (r *Reconciler) generatePatroniLeaderLeaseService(
 // +kubebuilder:rbac:groups="",resources="services",verbs={create,patch}
 
 // reconcilePatroniLeaderLease sets labels and ownership on the objects Patroni
-// creates for its leader elections. When Patroni is using Endpoints for this,
-// the returned Service resolves to the elected leader. Otherwise, it is nil.
+// creates for its leader elections. The returned Service resolves to the
+// elected leader.
 func (r *Reconciler) reconcilePatroniLeaderLease(
 	ctx context.Context, cluster *v1beta1.PostgresCluster,
 ) (*corev1.Service, error) {
 	// When using Endpoints for DCS, Patroni needs a Service to ensure that the
-	// Endpoints object is not removed by Kubernetes at startup.
+	// Endpoints object is not removed by Kubernetes at startup. When using
+	// ConfigMaps, the Service selects the leader by its role label.
 	// - https://releases.k8s.io/v1.16.0/pkg/controller/endpoint/endpoints_controller.go#L547
 	// - https://releases.k8s.io/v1.20.0/pkg/controller/endpoint/endpoints_controller.go#L580
 	service, err := r.generatePatroniLeaderLeaseService(cluster)
@@ -208,6 +360,7 @@ func (r *Reconciler) reconcilePatroniLeaderLease(
 	return service, err
 }
 
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
 // +kubebuilder:rbac:groups="",resources=endpoints,verbs=get
 
 // reconcilePatroniStatus populates cluster.Status.Patroni with observations.
@@ -225,33 +378,165 @@ func (r *Reconciler) reconcilePatroniStatus(
 		}
 	}
 
-	dcs := &corev1.Endpoints{ObjectMeta: naming.PatroniDistributedConfiguration(cluster)}
+	_, dcs, _ := patroniDistributedConfiguration(cluster)
 	err := errors.WithStack(client.IgnoreNotFound(
 		r.Client.Get(ctx, client.ObjectKeyFromObject(dcs), dcs)))
 
 	if err == nil {
-		if dcs.Annotations["initialize"] != "" {
+		if dcs.GetAnnotations()["initialize"] != "" {
 			// After bootstrap, Patroni writes the cluster system identifier to DCS.
-			cluster.Status.Patroni = &v1beta1.PatroniStatus{
-				SystemIdentifier: dcs.Annotations["initialize"],
+			if cluster.Status.Patroni == nil {
+				cluster.Status.Patroni = new(v1beta1.PatroniStatus)
 			}
+			cluster.Status.Patroni.SystemIdentifier = dcs.GetAnnotations()["initialize"]
 		} else if readyInstance {
 			// While we typically expect a value for the initialize key to be present in the
 			// Endpoints above by the time the StatefulSet for any instance indicates "ready"
 			// (since Patroni writes this value after successful cluster bootstrap, at which time
 			// the initial primary should transition to "ready"), sometimes this is not the case
-			// and the "initialize" key is not yet present.  Therefore, if a "ready" instance
-			// is detected in the cluster we assume this is the case, and simply log a message and
-			// requeue in order to try again until the expected value is found.
-			log.Info("detected ready instance but no initialize value")
-			result.RequeueAfter = 1 * time.Second
-			return result, nil
+			// and the "initialize" key is not yet present. There is no need to requeue; the
+			// DCS objects are watched and the cluster is queued once Patroni writes the value.
+			// See Reconciler.watchClusterLabel.
+			log.V(1).Info("detected ready instance but no initialize value")
 		}
 	}
 
 	return result, err
 }
 
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
+// +kubebuilder:rbac:groups="",resources=endpoints,verbs=get
+// +kubebuilder:rbac:groups="",resources=pods,verbs=patch
+
+// reconcileInstanceRoleLabels repairs the Patroni role labels on instance Pods
+// when they disagree with the leader that Patroni elected. A Pod that cannot
+// reach the Kubernetes API after losing its leader lock keeps the "master"
+// label until it is corrected here, and Services that select that label would
+// continue to send traffic to it.
+func (r *Reconciler) reconcileInstanceRoleLabels(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	observedInstances *observedInstances,
+) error {
+	log := logging.FromContext(ctx)
+
+	// Patroni stores the name of the current leader in an annotation on the
+	// leader lock. Member names are Pod names.
+	leader, _, _ := patroniDistributedConfiguration(cluster)
+	err := errors.WithStack(client.IgnoreNotFound(
+		r.Client.Get(ctx, client.ObjectKeyFromObject(leader), leader)))
+	leaderName := leader.GetAnnotations()["leader"]
+
+	for _, instance := range observedInstances.forCluster {
+		for _, pod := range instance.Pods {
+			if err != nil {
+				return err
+			}
+
+			reported := patroni.PodRole(pod)
+			reportsLeader := reported == "master" || reported == "standby_leader"
+
+			var desired string
+			switch pod.Labels[naming.LabelRole] {
+			case naming.RolePatroniLeader:
+				if pod.Name != leaderName || (reported != "" && !reportsLeader) {
+					desired = naming.RolePatroniReplica
+				}
+			case naming.RolePatroniReplica:
+				if pod.Name == leaderName && reportsLeader {
+					desired = naming.RolePatroniLeader
+				}
+			}
+			if desired == "" {
+				continue
+			}
+
+			before := pod.DeepCopy()
+			pod.Labels[naming.LabelRole] = desired
+			err = errors.WithStack(r.patch(ctx, pod, client.MergeFrom(before)))
+
+			if err == nil {
+				log.V(1).Info("repaired role label", "pod", pod.Name, "role", desired)
+				r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "RoleLabelRepaired",
+					"Changed role of Pod %q from %q to %q; Patroni leader is %q",
+					pod.Name, before.Labels[naming.LabelRole], desired, leaderName)
+			}
+		}
+	}
+
+	return err
+}
+
+// +kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+
+// reconcilePatroniSwitchover performs a switchover when the "trigger-switchover"
+// annotation of cluster differs from the value recorded in its status. It does
+// nothing unless switchovers are enabled in the spec.
+func (r *Reconciler) reconcilePatroniSwitchover(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	observedInstances *observedInstances,
+) error {
+	trigger := cluster.GetAnnotations()[naming.PatroniSwitchover]
+	spec := cluster.Spec.Patroni
+
+	if spec == nil || spec.Switchover == nil || !spec.Switchover.Enabled || trigger == "" {
+		return nil
+	}
+	if cluster.Status.Patroni != nil && cluster.Status.Patroni.Switchover != nil &&
+		*cluster.Status.Patroni.Switchover == trigger {
+		return nil
+	}
+
+	// Patroni member names are Pod names.
+	var primary, candidate *corev1.Pod
+	for _, instance := range observedInstances.forCluster {
+		writable, known := instance.IsWritable()
+		switch {
+		case !known:
+		case writable:
+			primary = instance.Pods[0]
+		case spec.Switchover.TargetInstance != nil &&
+			instance.Name == *spec.Switchover.TargetInstance:
+			candidate = instance.Pods[0]
+		}
+	}
+
+	if primary == nil {
+		// Wait for a primary; the annotation is checked again next reconcile.
+		return nil
+	}
+	if spec.Switchover.TargetInstance != nil && candidate == nil {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "InvalidSwitchoverTarget",
+			"Instance %q is not a replica in this cluster", *spec.Switchover.TargetInstance)
+		return nil
+	}
+
+	exec := func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+		return r.PodExec(ctx, primary.Namespace, primary.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+	}
+
+	var next string
+	if candidate != nil {
+		next = candidate.Name
+	}
+
+	success, err := patroni.Executor(exec).ChangePrimaryAndWait(ctx, primary.Name, next)
+	if err = errors.WithStack(err); err == nil && !success {
+		err = errors.New("unable to switchover")
+	}
+
+	if err == nil {
+		if cluster.Status.Patroni == nil {
+			cluster.Status.Patroni = new(v1beta1.PatroniStatus)
+		}
+		cluster.Status.Patroni.Switchover = &trigger
+
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "Switchover",
+			"Moved the primary from %q", primary.Name)
+	}
+
+	return err
+}
+
 // reconcileReplicationSecret creates a secret containing the TLS
 // certificate, key and CA certificate for use with the replication and
 // pg_rewind accounts in Postgres.
@@ -21,11 +21,12 @@ package postgrescluster
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel"
@@ -35,13 +36,18 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -98,6 +104,22 @@ ownerReferences:
 		assert.Equal(t, service.Spec.Type, corev1.ServiceTypeClusterIP)
 	})
 
+	t.Run("ConfigMaps", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Patroni = &v1beta1.PatroniSpec{
+			Kubernetes: &v1beta1.PatroniKubernetes{UseConfigMaps: initialize.Bool(true)},
+		}
+
+		service, err := reconciler.generatePatroniLeaderLeaseService(cluster)
+		assert.NilError(t, err)
+
+		// Selects the Pod that Patroni labels as its leader.
+		assert.DeepEqual(t, service.Spec.Selector, map[string]string{
+			"postgres-operator.crunchydata.com/cluster": "pg2",
+			"postgres-operator.crunchydata.com/role":    "master",
+		})
+	})
+
 	t.Run("AnnotationsLabels", func(t *testing.T) {
 		cluster := cluster.DeepCopy()
 		cluster.Spec.Metadata = &v1beta1.Metadata{
@@ -444,15 +466,16 @@ func TestReconcilePatroniStatus(t *testing.T) {
 	assert.NilError(t, tClient.Create(ctx, ns))
 	t.Cleanup(func() { assert.Check(t, tClient.Delete(ctx, ns)) })
 
+	// Nothing requeues while waiting for Patroni to write the annotation; the
+	// Endpoints are watched instead.
 	testsCases := []struct {
-		requeueExpected bool
 		readyReplicas   int
 		writeAnnotation bool
 	}{
-		{requeueExpected: false, readyReplicas: 1, writeAnnotation: true},
-		{requeueExpected: true, readyReplicas: 1, writeAnnotation: false},
-		{requeueExpected: false, readyReplicas: 0, writeAnnotation: false},
-		{requeueExpected: false, readyReplicas: 0, writeAnnotation: false},
+		{readyReplicas: 1, writeAnnotation: true},
+		{readyReplicas: 1, writeAnnotation: false},
+		{readyReplicas: 0, writeAnnotation: false},
+		{readyReplicas: 0, writeAnnotation: false},
 	}
 
 	for i, tc := range testsCases {
@@ -460,13 +483,308 @@ func TestReconcilePatroniStatus(t *testing.T) {
 			postgresCluster, observedInstances := createResources(i, tc.readyReplicas,
 				tc.writeAnnotation)
 			result, err := r.reconcilePatroniStatus(ctx, postgresCluster, observedInstances)
-			if tc.requeueExpected {
-				assert.NilError(t, err)
-				assert.Assert(t, result.RequeueAfter == 1*time.Second)
-			} else {
-				assert.NilError(t, err)
-				assert.DeepEqual(t, result, reconcile.Result{})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, result, reconcile.Result{})
+		})
+	}
+}
+
+func TestReconcileInstanceRoleLabels(t *testing.T) {
+	ctx := context.Background()
+
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+
+	leader := &corev1.Endpoints{ObjectMeta: naming.PatroniLeaderEndpoints(cluster)}
+	leader.Annotations = map[string]string{"leader": "hippo-new-0"}
+
+	pod := func(name, label, status string) *corev1.Pod {
+		pod := &corev1.Pod{}
+		pod.Namespace, pod.Name = "ns1", name
+		pod.Labels = map[string]string{
+			naming.LabelCluster: "hippo",
+			naming.LabelRole:    label,
+		}
+		pod.Annotations = map[string]string{"status": status}
+		return pod
+	}
+	observe := func(pods ...*corev1.Pod) *observedInstances {
+		instance := &Instance{Pods: pods}
+		return &observedInstances{forCluster: []*Instance{instance}}
+	}
+	role := func(cc client.Client, name string) string {
+		pod := &corev1.Pod{}
+		assert.NilError(t, cc.Get(ctx, client.ObjectKey{Namespace: "ns1", Name: name}, pod))
+		return pod.Labels[naming.LabelRole]
+	}
+
+	t.Run("NoLeader", func(t *testing.T) {
+		old := pod("hippo-old-0", "master", `{"role":"master"}`)
+		cc := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(old).Build()
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Client: cc, Recorder: recorder}
+
+		assert.NilError(t, reconciler.reconcileInstanceRoleLabels(ctx, cluster, observe(old)))
+		assert.Equal(t, role(cc, "hippo-old-0"), "replica")
+		assert.Equal(t, len(recorder.Events), 1)
+	})
+
+	t.Run("Agree", func(t *testing.T) {
+		primary := pod("hippo-new-0", "master", `{"role":"master"}`)
+		replica := pod("hippo-old-0", "replica", `{"role":"replica"}`)
+		cc := fake.NewClientBuilder().WithScheme(testScheme).
+			WithObjects(leader, primary, replica).Build()
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Client: cc, Recorder: recorder}
+
+		assert.NilError(t, reconciler.reconcileInstanceRoleLabels(ctx, cluster, observe(primary, replica)))
+		assert.Equal(t, role(cc, "hippo-new-0"), "master")
+		assert.Equal(t, role(cc, "hippo-old-0"), "replica")
+		assert.Equal(t, len(recorder.Events), 0)
+	})
+
+	t.Run("Failover", func(t *testing.T) {
+		// The old primary could not update its own labels; the new leader has
+		// promoted but its label has not caught up.
+		promoted := pod("hippo-new-0", "replica", `{"role":"master"}`)
+		demoted := pod("hippo-old-0", "master", `{"role":"master"}`)
+		cc := fake.NewClientBuilder().WithScheme(testScheme).
+			WithObjects(leader, promoted, demoted).Build()
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Client: cc, Recorder: recorder}
+
+		assert.NilError(t, reconciler.reconcileInstanceRoleLabels(ctx, cluster, observe(promoted, demoted)))
+		assert.Equal(t, role(cc, "hippo-new-0"), "master")
+		assert.Equal(t, role(cc, "hippo-old-0"), "replica")
+		assert.Equal(t, len(recorder.Events), 2)
+	})
+
+	t.Run("NotYetPromoted", func(t *testing.T) {
+		// Patroni holds the leader lock but has not reported a promotion.
+		promoting := pod("hippo-new-0", "replica", `{"role":"replica"}`)
+		cc := fake.NewClientBuilder().WithScheme(testScheme).
+			WithObjects(leader, promoting).Build()
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Client: cc, Recorder: recorder}
+
+		assert.NilError(t, reconciler.reconcileInstanceRoleLabels(ctx, cluster, observe(promoting)))
+		assert.Equal(t, role(cc, "hippo-new-0"), "replica")
+		assert.Equal(t, len(recorder.Events), 0)
+	})
+
+	t.Run("ConfigMaps", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Patroni = &v1beta1.PatroniSpec{
+			Kubernetes: &v1beta1.PatroniKubernetes{UseConfigMaps: initialize.Bool(true)},
+		}
+
+		// Patroni stores the same annotations on its leader ConfigMap.
+		leader := &corev1.ConfigMap{ObjectMeta: naming.PatroniLeaderConfigMap(cluster)}
+		leader.Annotations = map[string]string{"leader": "hippo-new-0"}
+
+		promoted := pod("hippo-new-0", "replica", `{"role":"master"}`)
+		demoted := pod("hippo-old-0", "master", `{"role":"master"}`)
+		cc := fake.NewClientBuilder().WithScheme(testScheme).
+			WithObjects(leader, promoted, demoted).Build()
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &Reconciler{Client: cc, Recorder: recorder}
+
+		assert.NilError(t, reconciler.reconcileInstanceRoleLabels(ctx, cluster, observe(promoted, demoted)))
+		assert.Equal(t, role(cc, "hippo-new-0"), "master")
+		assert.Equal(t, role(cc, "hippo-old-0"), "replica")
+	})
+}
+
+func TestReconcilePatroniSwitchover(t *testing.T) {
+	ctx := context.Background()
+
+	var commands [][]string
+	stdout := "Successfully switched over"
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &Reconciler{
+		Recorder: recorder,
+		PodExec: func(_ context.Context, namespace, pod, container string, stdin io.Reader, out,
+			stderr io.Writer, command ...string) error {
+			assert.Equal(t, pod, "hippo-a-0")
+			commands = append(commands, command)
+			_, err := io.WriteString(out, stdout)
+			return err
+		},
+	}
+
+	pod := func(name, role string) *corev1.Pod {
+		pod := &corev1.Pod{}
+		pod.Namespace, pod.Name = "ns1", name
+		pod.Annotations = map[string]string{"status": `{"role":"` + role + `"}`}
+		return pod
+	}
+	instances := &observedInstances{forCluster: []*Instance{
+		{Name: "hippo-a", Pods: []*corev1.Pod{pod("hippo-a-0", "master")}},
+		{Name: "hippo-b", Pods: []*corev1.Pod{pod("hippo-b-0", "replica")}},
+	}}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Annotations = map[string]string{naming.PatroniSwitchover: "one"}
+
+	t.Run("Disabled", func(t *testing.T) {
+		assert.NilError(t, reconciler.reconcilePatroniSwitchover(ctx, cluster, instances))
+		assert.Equal(t, len(commands), 0)
+	})
+
+	cluster.Spec.Patroni = &v1beta1.PatroniSpec{
+		Switchover: &v1beta1.PatroniSwitchover{Enabled: true},
+	}
+
+	t.Run("AnyReplica", func(t *testing.T) {
+		assert.NilError(t, reconciler.reconcilePatroniSwitchover(ctx, cluster, instances))
+		assert.Equal(t, len(commands), 1)
+		assert.DeepEqual(t, commands[0], []string{
+			"patronictl", "switchover", "--scheduled=now", "--force",
+			"--master=hippo-a-0", "--candidate=",
+		})
+		assert.Equal(t, *cluster.Status.Patroni.Switchover, "one")
+		assert.Assert(t, strings.HasPrefix(<-recorder.Events, "Normal Switchover"))
+
+		// The same annotation value does nothing.
+		assert.NilError(t, reconciler.reconcilePatroniSwitchover(ctx, cluster, instances))
+		assert.Equal(t, len(commands), 1)
+	})
+
+	t.Run("Target", func(t *testing.T) {
+		cluster.Annotations[naming.PatroniSwitchover] = "two"
+		cluster.Spec.Patroni.Switchover.TargetInstance = initialize.String("hippo-b")
+
+		assert.NilError(t, reconciler.reconcilePatroniSwitchover(ctx, cluster, instances))
+		assert.Equal(t, len(commands), 2)
+		assert.Equal(t, commands[1][5], "--candidate=hippo-b-0")
+		assert.Equal(t, *cluster.Status.Patroni.Switchover, "two")
+		<-recorder.Events
+	})
+
+	t.Run("InvalidTarget", func(t *testing.T) {
+		cluster.Annotations[naming.PatroniSwitchover] = "three"
+		cluster.Spec.Patroni.Switchover.TargetInstance = initialize.String("hippo-a")
+
+		assert.NilError(t, reconciler.reconcilePatroniSwitchover(ctx, cluster, instances))
+		assert.Equal(t, len(commands), 2)
+		assert.Equal(t, *cluster.Status.Patroni.Switchover, "two")
+		assert.Assert(t, strings.HasPrefix(<-recorder.Events, "Warning InvalidSwitchoverTarget"))
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		cluster.Spec.Patroni.Switchover.TargetInstance = nil
+		stdout = "Switchover failed"
+
+		err := reconciler.reconcilePatroniSwitchover(ctx, cluster, instances)
+		assert.ErrorContains(t, err, "unable to switchover")
+		assert.Equal(t, *cluster.Status.Patroni.Switchover, "two")
+	})
+}
+
+func TestReconcilePatroniDynamicConfiguration(t *testing.T) {
+	ctx := context.Background()
+
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Default()
+	cluster.Status.Patroni = &v1beta1.PatroniStatus{SystemIdentifier: "12345"}
+
+	pod := &corev1.Pod{}
+	pod.Namespace, pod.Name = "ns1", "hippo-a-0"
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  naming.ContainerDatabase,
+		State: corev1.ContainerState{Running: new(corev1.ContainerStateRunning)},
+	}}
+	instances := &observedInstances{forCluster: []*Instance{
+		{Name: "hippo-a", Pods: []*corev1.Pod{pod}},
+	}}
+
+	var commands []string
+	var stored string
+	reconciler := &Reconciler{
+		PodExec: func(_ context.Context, namespace, pod, container string, stdin io.Reader, _,
+			_ io.Writer, command ...string) error {
+			commands = append(commands, strings.Join(command, " "))
+			if stdin != nil {
+				b, err := ioutil.ReadAll(stdin)
+				stored = string(b)
+				return err
 			}
+			return nil
+		},
+	}
+
+	t.Run("Changed", func(t *testing.T) {
+		reconciler.Client = fake.NewClientBuilder().WithScheme(testScheme).Build()
+		commands = nil
+
+		assert.NilError(t, reconciler.reconcilePatroniDynamicConfiguration(
+			ctx, cluster, instances, postgres.HBAs{}, postgres.Parameters{}))
+		assert.DeepEqual(t, commands, []string{
+			"patronictl edit-config --replace=- --force",
+			"patronictl reload hippo-ha --force",
 		})
+	})
+
+	t.Run("Unchanged", func(t *testing.T) {
+		dcs := &corev1.Endpoints{ObjectMeta: naming.PatroniDistributedConfiguration(cluster)}
+		dcs.Annotations = map[string]string{"config": stored}
+		reconciler.Client = fake.NewClientBuilder().WithScheme(testScheme).WithObjects(dcs).Build()
+		commands = nil
+
+		assert.NilError(t, reconciler.reconcilePatroniDynamicConfiguration(
+			ctx, cluster, instances, postgres.HBAs{}, postgres.Parameters{}))
+		assert.Equal(t, len(commands), 0)
+	})
+}
+
+func TestReconcilePatroniPendingRestart(t *testing.T) {
+	ctx := context.Background()
+
+	pod := func(name, status string) *corev1.Pod {
+		pod := &corev1.Pod{}
+		pod.Namespace, pod.Name = "ns1", name
+		pod.Annotations = map[string]string{"status": status}
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  naming.ContainerDatabase,
+			State: corev1.ContainerState{Running: new(corev1.ContainerStateRunning)},
+		}}
+		return pod
 	}
+
+	var pods []string
+	reconciler := &Reconciler{
+		PodExec: func(_ context.Context, namespace, pod, container string, _ io.Reader, stdout,
+			_ io.Writer, command ...string) error {
+			pods = append(pods, pod)
+			_, err := io.WriteString(stdout, "shared_buffers\nmax_connections\n")
+			return err
+		},
+	}
+
+	cluster := &v1beta1.PostgresCluster{}
+	instances := &observedInstances{forCluster: []*Instance{
+		{Name: "hippo-a", Pods: []*corev1.Pod{pod("hippo-a-0", `{"role":"master"}`)}},
+		{Name: "hippo-b", Pods: []*corev1.Pod{pod("hippo-b-0", `{"role":"replica","pending_restart":true}`)}},
+	}}
+
+	assert.NilError(t, reconciler.reconcilePatroniPendingRestart(ctx, cluster, instances))
+	assert.DeepEqual(t, pods, []string{"hippo-b-0"})
+	assert.DeepEqual(t, cluster.Status.Patroni.PendingRestart,
+		[]string{"max_connections", "shared_buffers"})
+
+	// The list is cleared once instances restart.
+	instances.forCluster[1].Pods[0].Annotations["status"] = `{"role":"replica"}`
+	assert.NilError(t, reconciler.reconcilePatroniPendingRestart(ctx, cluster, instances))
+	assert.Assert(t, cluster.Status.Patroni.PendingRestart == nil)
 }
@@ -16,11 +16,13 @@ package postgrescluster
 */
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -38,6 +40,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -77,6 +80,14 @@ const (
 	// and in-place pgBackRest restore is in progress
 	ConditionPGBackRestRestoreProgressing = "PGBackRestoreProgressing"
 
+	// ConditionStanzasHealthy is the type used in a condition to indicate whether or not the
+	// pgBackRest stanza exists and matches PostgreSQL in every repository
+	ConditionStanzasHealthy = "PGBackRestStanzasHealthy"
+
+	// ConditionBackupsVerified is the type used in a condition to indicate whether or not the
+	// newest full backup in every repository passed verification, allowing older backups to expire
+	ConditionBackupsVerified = "PGBackRestBackupsVerified"
+
 	// EventRepoHostNotFound is used to indicate that a pgBackRest repository was not
 	// found when reconciling
 	EventRepoHostNotFound = "RepoDeploymentNotFound"
@@ -89,14 +100,45 @@ const (
 	// stanzas for the repositories in a PostgreSQL cluster
 	EventUnableToCreateStanzas = "UnableToCreateStanzas"
 
+	// EventStanzaConflict is the event reason utilized when another PostgreSQL cluster writes
+	// to the same pgBackRest stanza in a shared repository
+	EventStanzaConflict = "StanzaConflict"
+
 	// EventStanzasCreated is the event reason utilized when a pgBackRest stanza create command
 	// completes successfully
 	EventStanzasCreated = "StanzasCreated"
 
+	// EventStanzaRepaired is the event reason utilized when the operator recreates or upgrades
+	// a pgBackRest stanza that was missing or did not match PostgreSQL
+	EventStanzaRepaired = "StanzaRepaired"
+
+	// EventUnableToRepairStanza is the event reason utilized when the operator detects a missing
+	// or mismatched pgBackRest stanza but cannot repair it
+	EventUnableToRepairStanza = "UnableToRepairStanza"
+
+	// EventBackupVerified is the event reason utilized when the newest full backup in a
+	// repository passes verification and older backups are expired
+	EventBackupVerified = "BackupVerified"
+
+	// EventBackupVerificationFailed is the event reason utilized when the newest full backup in
+	// a repository fails verification and expiry is suspended
+	EventBackupVerificationFailed = "BackupVerificationFailed"
+
 	// EventUnableToCreatePGBackRestCronJob is the event reason utilized when a pgBackRest backup
 	// CronJob fails to create successfully
 	EventUnableToCreatePGBackRestCronJob = "UnableToCreatePGBackRestCronJob"
 
+	// EventBackupFailed is the event reason utilized when a manual or scheduled pgBackRest
+	// backup Job fails
+	EventBackupFailed = "BackupFailed"
+
+	// EventRestoreComplete is the event reason utilized when a pgBackRest restore Job
+	// completes successfully
+	EventRestoreComplete = "RestoreComplete"
+
+	// EventRestoreFailed is the event reason utilized when a pgBackRest restore Job fails
+	EventRestoreFailed = "RestoreFailed"
+
 	// ReasonReadyForRestore is the reason utilized within ConditionPGBackRestRestoreProgressing
 	// to indicate that the restore Job can proceed because the cluster is now ready to be
 	// restored (i.e. it has been properly prepared for a restore).
@@ -121,8 +163,13 @@ type RepoResources struct {
 	replicaCreateBackupJobs []*batchv1.Job
 	hosts                   []*appsv1.StatefulSet
 	pvcs                    []*corev1.PersistentVolumeClaim
+	standbyPVCs             []*corev1.PersistentVolumeClaim
 	sshConfig               *corev1.ConfigMap
 	sshSecret               *corev1.Secret
+
+	// relocating indicates that a repository is being copied to a new volume
+	// and that the repository host should be stopped until it is done
+	relocating bool
 }
 
 // applyRepoHostIntent ensures the pgBackRest repository host StatefulSet is synchronized with the
@@ -200,8 +247,8 @@ func (r *Reconciler) getPGBackRestResources(ctx context.Context,
 		Version: appsv1.SchemeGroupVersion.Version,
 		Kind:    "StatefulSetList",
 	}, {
-		Group:   batchv1beta1.SchemeGroupVersion.Group,
-		Version: batchv1beta1.SchemeGroupVersion.Version,
+		Group:   r.cronJobGroupVersion().Group,
+		Version: r.cronJobGroupVersion().Version,
 		Kind:    "CronJobList",
 	}}
 
@@ -239,6 +286,7 @@ func (r *Reconciler) getPGBackRestResources(ctx context.Context,
 	return repoResources, nil
 }
 
+// +kubebuilder:rbac:groups="",resources=configmaps;secrets,verbs=delete
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=delete
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=delete
 // +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=delete
@@ -263,11 +311,26 @@ func (r *Reconciler) cleanupRepoResources(ctx context.Context,
 		// determines whether or not it should be deleted according to the current PostgresCluster
 		// spec
 		switch {
+		case hasLabel(naming.LabelPGBackRestStandby):
+			// Keep the standby repository host, and its copy of each "volume" repository,
+			// while a standby is enabled.
+			if pgbackrest.StandbyRepoHostEnabled(postgresCluster) {
+				copied := owned.GetLabels()[naming.LabelPGBackRestStandby]
+				for _, repo := range postgresCluster.Spec.Backups.PGBackRest.Repos {
+					if copied == "" || (repo.Volume != nil && repo.Name == copied) {
+						ownedNoDelete = append(ownedNoDelete, owned)
+						delete = false
+						break
+					}
+				}
+			}
 		case hasLabel(naming.LabelPGBackRestConfig):
-			// Simply add the things we never want to delete (e.g. the pgBackRest configuration)
-			// to the slice and do not delete
-			ownedNoDelete = append(ownedNoDelete, owned)
-			delete = false
+			// Simply add the things we never want to delete while backups are enabled (e.g. the
+			// pgBackRest configuration) to the slice and do not delete
+			if pgbackrest.BackupsEnabled(postgresCluster) {
+				ownedNoDelete = append(ownedNoDelete, owned)
+				delete = false
+			}
 		case hasLabel(naming.LabelPGBackRestDedicated):
 			// If a dedicated repo host resource and a dedicated repo host is enabled, then
 			// add to the slice and do not delete.
@@ -320,8 +383,9 @@ func (r *Reconciler) cleanupRepoResources(ctx context.Context,
 			}
 		}
 
-		// If nothing has specified that the resource should not be deleted, then delete
-		if delete {
+		// If nothing has specified that the resource should not be deleted, then delete.
+		// Retained resources are left alone.
+		if delete && !retained(&ownedResources[i]) {
 			if err := r.Client.Delete(ctx, &ownedResources[i],
 				client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
 				return []unstructured.Unstructured{}, errors.WithStack(err)
@@ -394,7 +458,12 @@ func unstructuredToRepoResources(postgresCluster *v1beta1.PostgresCluster, kind
 			return errors.WithStack(err)
 		}
 		for i := range pvcList.Items {
-			repoResources.pvcs = append(repoResources.pvcs, &pvcList.Items[i])
+			// copies of repositories on a standby repository host are kept apart
+			if _, ok := pvcList.Items[i].Labels[naming.LabelPGBackRestStandby]; ok {
+				repoResources.standbyPVCs = append(repoResources.standbyPVCs, &pvcList.Items[i])
+			} else {
+				repoResources.pvcs = append(repoResources.pvcs, &pvcList.Items[i])
+			}
 		}
 	case "SecretList":
 		var secretList corev1.SecretList
@@ -416,7 +485,10 @@ func unstructuredToRepoResources(postgresCluster *v1beta1.PostgresCluster, kind
 			return errors.WithStack(err)
 		}
 		for i := range stsList.Items {
-			repoResources.hosts = append(repoResources.hosts, &stsList.Items[i])
+			// the standby repository host is not one of the repository hosts
+			if _, ok := stsList.Items[i].Labels[naming.LabelPGBackRestStandby]; !ok {
+				repoResources.hosts = append(repoResources.hosts, &stsList.Items[i])
+			}
 		}
 	case "CronJobList":
 		var cronList batchv1beta1.CronJobList
@@ -455,6 +527,21 @@ func (r *Reconciler) setScheduledJobStatus(ctx context.Context,
 
 	// TODO(tjmoore4): PGBackRestScheduledBackupStatus can likely be combined with
 	// PGBackRestJobStatus as they both contain most of the same information
+	// Jobs that were finished before are not reported again.
+	var previous []v1beta1.PGBackRestScheduledBackupStatus
+	if postgresCluster.Status.PGBackRest != nil {
+		previous = postgresCluster.Status.PGBackRest.ScheduledBackups
+	}
+	finishedBefore := func(sbs v1beta1.PGBackRestScheduledBackupStatus) bool {
+		for _, status := range previous {
+			if status.Finished && status.CronJobName == sbs.CronJobName &&
+				status.StartTime.Equal(sbs.StartTime) {
+				return true
+			}
+		}
+		return false
+	}
+
 	scheduledStatus := []v1beta1.PGBackRestScheduledBackupStatus{}
 	for _, job := range jobList.Items {
 		// we only care about the scheduled backup Jobs created by the
@@ -472,6 +559,14 @@ func (r *Reconciler) setScheduledJobStatus(ctx context.Context,
 			sbs.Succeeded = job.Status.Succeeded
 			sbs.Failed = job.Status.Failed
 
+			completed, failed := jobCompleted(&job), jobFailed(&job)
+			if failed && !finishedBefore(sbs) {
+				r.Recorder.Eventf(postgresCluster, corev1.EventTypeWarning, EventBackupFailed,
+					"Scheduled %s backup to %s did not complete successfully; see Job %q",
+					sbs.Type, sbs.RepoName, job.Name)
+			}
+			sbs.Finished = completed || failed
+
 			scheduledStatus = append(scheduledStatus, sbs)
 		}
 	}
@@ -534,6 +629,9 @@ func (r *Reconciler) generateRepoHostIntent(postgresCluster *v1beta1.PostgresClu
 			repo.Spec.Template.Spec.PriorityClassName = *repoHost.PriorityClassName
 		}
 	}
+	repo.Spec.Template.Spec.Affinity = architectureAffinity(
+		config.ImageArchitectures(postgresCluster), repo.Spec.Template.Spec.Affinity)
+	setPodNameResolution(postgresCluster, &repo.Spec.Template.Spec)
 
 	// if default pod scheduling is not explicitly disabled, add the default
 	// pod topology spread constraints
@@ -553,8 +651,10 @@ func (r *Reconciler) generateRepoHostIntent(postgresCluster *v1beta1.PostgresClu
 	// https://github.com/kubernetes/kubernetes/issues/88456
 	repo.Spec.Template.Spec.ImagePullSecrets = postgresCluster.Spec.ImagePullSecrets
 
-	// if the cluster is set to be shutdown, stop repohost pod
-	if postgresCluster.Spec.Shutdown != nil && *postgresCluster.Spec.Shutdown {
+	// if the cluster is set to be shutdown, or a repository is being copied to
+	// a new volume, stop repohost pod
+	if (postgresCluster.Spec.Shutdown != nil && *postgresCluster.Spec.Shutdown) ||
+		repoResources.relocating {
 		repo.Spec.Replicas = initialize.Int32(0)
 	} else {
 		// the cluster should not be shutdown, set this value to 1
@@ -640,10 +740,32 @@ func (r *Reconciler) generateRepoVolumeIntent(postgresCluster *v1beta1.PostgresC
 			Kind:       "PersistentVolumeClaim",
 		},
 		ObjectMeta: meta,
-		Spec:       *spec,
+		Spec:       *spec.DeepCopy(),
 	}
 
-	// set ownership references
+	// The storage class of a PVC cannot change and its size cannot decrease.
+	// Keep both for an existing volume until Reconciler.relocateRepoVolumes
+	// replaces it.
+	for _, pvc := range repoResources.pvcs {
+		if pvc.GetName() != meta.Name {
+			continue
+		}
+		repoVol.Spec.StorageClassName = pvc.Spec.StorageClassName
+
+		want, requested := repoVol.Spec.Resources.Requests[corev1.ResourceStorage]
+		have, exists := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		if requested && exists && want.Cmp(have) < 0 {
+			repoVol.Spec.Resources.Requests[corev1.ResourceStorage] = have
+		}
+	}
+
+	// set ownership references unless the existing volume is retained, so that
+	// backups outlive the cluster
+	for _, pvc := range repoResources.pvcs {
+		if pvc.GetName() == meta.Name && retained(pvc) {
+			return repoVol, nil
+		}
+	}
 	if err := controllerutil.SetControllerReference(postgresCluster, repoVol,
 		r.Client.Scheme()); err != nil {
 		return nil, err
@@ -657,9 +779,19 @@ func generateBackupJobSpecIntent(postgresCluster *v1beta1.PostgresCluster, selec
 	containerName, repoName, serviceAccountName, configName string,
 	labels, annotations map[string]string, opts ...string) (*batchv1.JobSpec, error) {
 
+	return generatePGBackRestJobSpecIntent(postgresCluster, "backup", selector,
+		containerName, repoName, serviceAccountName, configName, labels, annotations, opts...)
+}
+
+// generatePGBackRestJobSpecIntent generates a JobSpec for a Job that runs the pgBackRest
+// command in the container of the Pod matched by selector, e.g. "backup" or "verify".
+func generatePGBackRestJobSpecIntent(postgresCluster *v1beta1.PostgresCluster, command,
+	selector, containerName, repoName, serviceAccountName, configName string,
+	labels, annotations map[string]string, opts ...string) (*batchv1.JobSpec, error) {
+
 	repoIndex := regexRepoIndex.FindString(repoName)
 	cmdOpts := []string{
-		"--stanza=" + pgbackrest.DefaultStanzaName,
+		"--stanza=" + pgbackrest.StanzaName(postgresCluster),
 		"--repo=" + repoIndex,
 	}
 	cmdOpts = append(cmdOpts, opts...)
@@ -667,7 +799,7 @@ func generateBackupJobSpecIntent(postgresCluster *v1beta1.PostgresCluster, selec
 	container := corev1.Container{
 		Command: []string{"/opt/crunchy/bin/pgbackrest"},
 		Env: []corev1.EnvVar{
-			{Name: "COMMAND", Value: "backup"},
+			{Name: "COMMAND", Value: command},
 			{Name: "COMMAND_OPTS", Value: strings.Join(cmdOpts, " ")},
 			{Name: "COMPARE_HASH", Value: "true"},
 			{Name: "CONTAINER", Value: containerName},
@@ -706,6 +838,16 @@ func generateBackupJobSpecIntent(postgresCluster *v1beta1.PostgresCluster, selec
 			*postgresCluster.Spec.Backups.PGBackRest.Jobs.PriorityClassName
 	}
 
+	// set the scheduling constraints, if any exist
+	if jobs := postgresCluster.Spec.Backups.PGBackRest.Jobs; jobs != nil {
+		jobSpec.Template.Spec.Affinity = jobs.Affinity
+		jobSpec.Template.Spec.NodeSelector = jobs.NodeSelector
+		jobSpec.Template.Spec.Tolerations = jobs.Tolerations
+	}
+	jobSpec.Template.Spec.Affinity = architectureAffinity(
+		config.ImageArchitectures(postgresCluster), jobSpec.Template.Spec.Affinity)
+	setPodNameResolution(postgresCluster, &jobSpec.Template.Spec)
+
 	// Set the image pull secrets, if any exist.
 	// This is set here rather than using the service account due to the lack
 	// of propagation to existing pods when the CRD is updated:
@@ -723,43 +865,29 @@ func generateBackupJobSpecIntent(postgresCluster *v1beta1.PostgresCluster, selec
 
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=list;delete
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=list;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
 // +kubebuilder:rbac:groups="",resources=endpoints,verbs=get
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=list
 
 // observeRestoreEnv observes the current Kubernetes environment to obtain any resources applicable
 // to performing pgBackRest restores (e.g. when initializing a new cluster using an existing
 // pgBackRest backup, or when restoring in-place).  This includes finding any existing Endpoints
-// created by Patroni (i.e. DCS, leader and failover Endpoints), while then also finding any existing
-// restore Jobs and then updating pgBackRest restore status accordingly.
+// or ConfigMaps created by Patroni (i.e. DCS, leader and failover objects), while then also finding
+// any existing restore Jobs and then updating pgBackRest restore status accordingly.
 func (r *Reconciler) observeRestoreEnv(ctx context.Context,
-	cluster *v1beta1.PostgresCluster) ([]corev1.Endpoints, *batchv1.Job, error) {
-
-	// lookup the various patroni endpoints
-	leaderEP, dcsEP, failoverEP := corev1.Endpoints{}, corev1.Endpoints{}, corev1.Endpoints{}
-	currentEndpoints := []corev1.Endpoints{}
-	if err := r.Client.Get(ctx, naming.AsObjectKey(naming.PatroniLeaderEndpoints(cluster)),
-		&leaderEP); err != nil {
-		if !apierrors.IsNotFound(err) {
-			return nil, nil, errors.WithStack(err)
-		}
-	} else {
-		currentEndpoints = append(currentEndpoints, leaderEP)
-	}
-	if err := r.Client.Get(ctx, naming.AsObjectKey(naming.PatroniDistributedConfiguration(cluster)),
-		&dcsEP); err != nil {
-		if !apierrors.IsNotFound(err) {
-			return nil, nil, errors.WithStack(err)
-		}
-	} else {
-		currentEndpoints = append(currentEndpoints, dcsEP)
-	}
-	if err := r.Client.Get(ctx, naming.AsObjectKey(naming.PatroniTrigger(cluster)),
-		&failoverEP); err != nil {
-		if !apierrors.IsNotFound(err) {
-			return nil, nil, errors.WithStack(err)
+	cluster *v1beta1.PostgresCluster) ([]client.Object, *batchv1.Job, error) {
+
+	// lookup the various patroni endpoints or configmaps
+	currentDCS := []client.Object{}
+	leader, config, failover := patroniDistributedConfiguration(cluster)
+	for _, object := range []client.Object{leader, config, failover} {
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(object), object); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, nil, errors.WithStack(err)
+			}
+		} else {
+			currentDCS = append(currentDCS, object)
 		}
-	} else {
-		currentEndpoints = append(currentEndpoints, failoverEP)
 	}
 
 	restoreJobs := &batchv1.JobList{}
@@ -793,6 +921,12 @@ func (r *Reconciler) observeRestoreEnv(ctx context.Context,
 			}
 		}
 
+		if completed || failed {
+			if err := r.recordRestore(ctx, cluster, restoreJob, completed); err != nil {
+				return nil, nil, err
+			}
+		}
+
 		// update the data source initialized condition if the Job has finished running, and is
 		// therefore in a completed or failed
 		if completed {
@@ -844,7 +978,100 @@ func (r *Reconciler) observeRestoreEnv(ctx context.Context,
 		}
 	}
 
-	return currentEndpoints, restoreJob, nil
+	return currentDCS, restoreJob, nil
+}
+
+// restoreHistoryLimit is the number of finished restores kept in the status of
+// a PostgresCluster.
+const restoreHistoryLimit = 10
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=list
+
+// recordRestore adds the finished restore Job to the restore history of cluster,
+// dropping the oldest entries beyond restoreHistoryLimit. The backup set comes
+// from the termination message of the Job's Pod; see pgbackrest.RestoreCommand.
+func (r *Reconciler) recordRestore(ctx context.Context,
+	cluster *v1beta1.PostgresCluster, job *batchv1.Job, succeeded bool) error {
+
+	if cluster.Status.PGBackRest == nil || cluster.Status.PGBackRest.Restore == nil {
+		return nil
+	}
+
+	// the Job is recorded only once
+	id := cluster.Status.PGBackRest.Restore.ID
+	for _, record := range cluster.Status.PGBackRest.RestoreHistory {
+		if record.ID == id && record.StartTime.Equal(job.Status.StartTime) {
+			return nil
+		}
+	}
+
+	record := v1beta1.PGBackRestRestoreRecord{
+		ID:         id,
+		StartTime:  job.Status.StartTime,
+		FinishTime: job.Status.CompletionTime,
+		Succeeded:  succeeded,
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			record.FinishTime = condition.LastTransitionTime.DeepCopy()
+		}
+	}
+
+	// an in-place restore is identified by the annotation; otherwise the restore
+	// populated a new cluster from its data source
+	var dataSource *v1beta1.PostgresClusterDataSource
+	if id == cluster.GetAnnotations()[naming.PGBackRestRestore] {
+		if cluster.Spec.Backups.PGBackRest.Restore != nil {
+			dataSource = cluster.Spec.Backups.PGBackRest.Restore.PostgresClusterDataSource
+		}
+	} else if cluster.Spec.DataSource != nil {
+		dataSource = cluster.Spec.DataSource.PostgresCluster
+		if dataSource == nil && cluster.Spec.DataSource.PGBackRest != nil {
+			dataSource = restoreDataSource(cluster.Spec.DataSource.PGBackRest)
+		}
+	}
+	if dataSource != nil {
+		record.ClusterName = dataSource.ClusterName
+		record.ClusterNamespace = dataSource.ClusterNamespace
+		record.RepoName = dataSource.RepoName
+		record.Options = append([]string(nil), dataSource.Options...)
+	}
+
+	if succeeded {
+		selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		pods := &corev1.PodList{}
+		if err := r.Client.List(ctx, pods, client.InNamespace(job.Namespace),
+			client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return errors.WithStack(err)
+		}
+		for _, pod := range pods.Items {
+			for _, status := range pod.Status.ContainerStatuses {
+				if status.Name == naming.PGBackRestRestoreContainerName &&
+					status.State.Terminated != nil && status.State.Terminated.ExitCode == 0 {
+					record.BackupSet = strings.TrimSpace(status.State.Terminated.Message)
+				}
+			}
+		}
+	}
+
+	history := append(cluster.Status.PGBackRest.RestoreHistory, record)
+	if len(history) > restoreHistoryLimit {
+		history = history[len(history)-restoreHistoryLimit:]
+	}
+	cluster.Status.PGBackRest.RestoreHistory = history
+
+	if succeeded {
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, EventRestoreComplete,
+			"Restore %q completed successfully", id)
+	} else {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, EventRestoreFailed,
+			"Restore %q did not complete successfully; see Job %q", id, job.Name)
+	}
+
+	return nil
 }
 
 // +kubebuilder:rbac:groups="",resources=endpoints,verbs=delete
@@ -853,12 +1080,12 @@ func (r *Reconciler) observeRestoreEnv(ctx context.Context,
 
 // prepareForRestore is responsible for reconciling an in place restore for the PostgresCluster.
 // This includes setting a "PreparingForRestore" condition, and then removing all existing
-// instance runners, as well as any Endpoints created by Patroni.  And once the cluster is no
+// instance runners, as well as any Endpoints or ConfigMaps created by Patroni.  And once the cluster is no
 // longer running, the "PostgresDataInitialized" condition is removed, which will cause the
 // cluster to re-bootstrap using a restored data directory.
 func (r *Reconciler) prepareForRestore(ctx context.Context,
 	cluster *v1beta1.PostgresCluster, observed *observedInstances,
-	currentEndpoints []corev1.Endpoints, restoreJob *batchv1.Job, restoreID string) error {
+	currentDCS []client.Object, restoreJob *batchv1.Job, restoreID string) error {
 
 	setPreparingClusterCondition := func(resource string) {
 		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
@@ -871,7 +1098,12 @@ func (r *Reconciler) prepareForRestore(ctx context.Context,
 		})
 	}
 
-	cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{}
+	// keep the restore history while resetting everything else
+	var history []v1beta1.PGBackRestRestoreRecord
+	if cluster.Status.PGBackRest != nil {
+		history = cluster.Status.PGBackRest.RestoreHistory
+	}
+	cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{RestoreHistory: history}
 	cluster.Status.PGBackRest.Restore = &v1beta1.PGBackRestJobStatus{
 		ID: restoreID,
 	}
@@ -941,7 +1173,7 @@ func (r *Reconciler) prepareForRestore(ctx context.Context,
 	}
 
 	// if everything is gone, proceed with re-bootstrapping the cluster via an in-place restore
-	if len(currentEndpoints) == 0 {
+	if len(currentDCS) == 0 {
 		if len(cluster.Status.Conditions) > 0 {
 			// TODO: remove guard with move to controller-runtime 0.9.0 https://issue.k8s.io/99714
 			meta.RemoveStatusCondition(&cluster.Status.Conditions, ConditionPostgresDataInitialized)
@@ -963,9 +1195,9 @@ func (r *Reconciler) prepareForRestore(ctx context.Context,
 	}
 
 	setPreparingClusterCondition("removing DCS")
-	// delete any Endpoints
-	for i := range currentEndpoints {
-		if err := r.Client.Delete(ctx, &currentEndpoints[i]); client.IgnoreNotFound(err) != nil {
+	// delete any Endpoints or ConfigMaps
+	for i := range currentDCS {
+		if err := r.Client.Delete(ctx, currentDCS[i]); client.IgnoreNotFound(err) != nil {
 			return errors.WithStack(err)
 		}
 	}
@@ -1016,7 +1248,7 @@ func (r *Reconciler) reconcileRestoreJob(ctx context.Context,
 	// combine options provided by user in the spec with those populated by the operator for a
 	// successful restore
 	opts := append(options, []string{
-		"--stanza=" + pgbackrest.DefaultStanzaName, "--pg1-path=" + pgdata,
+		"--stanza=" + pgbackrest.StanzaName(sourceCluster), "--pg1-path=" + pgdata,
 		"--repo=" + regexRepoIndex.FindString(repoName)}...)
 	var deltaOptFound bool
 	for _, opt := range opts {
@@ -1155,11 +1387,13 @@ func (r *Reconciler) generateRestoreJobIntent(cluster *v1beta1.PostgresCluster,
 				}},
 				RestartPolicy: corev1.RestartPolicyNever,
 				Volumes:       volumes,
-				Affinity:      dataSource.Affinity,
+				Affinity:      architectureAffinity(config.ImageArchitectures(cluster), dataSource.Affinity),
+				NodeSelector:  dataSource.NodeSelector,
 				Tolerations:   dataSource.Tolerations,
 			},
 		},
 	}
+	setPodNameResolution(cluster, &job.Spec.Template.Spec)
 
 	// Set the image pull secrets, if any exist.
 	// This is set here rather than using the service account due to the lack
@@ -1198,12 +1432,33 @@ func (r *Reconciler) reconcilePGBackRest(ctx context.Context,
 	// add some additional context about what component is being reconciled
 	log := logging.FromContext(ctx).WithValues("reconciler", "pgBackRest")
 
+	// When no repositories are defined, backups are disabled. Delete any pgBackRest resources
+	// that remain from when they were enabled and clear the pgBackRest status.
+	if !pgbackrest.BackupsEnabled(postgresCluster) {
+		if _, err := r.getPGBackRestResources(ctx, postgresCluster); err != nil {
+			return reconcile.Result{}, errors.WithStack(err)
+		}
+		postgresCluster.Status.PGBackRest = nil
+		forgetBackupMetrics(postgresCluster.Namespace, postgresCluster.Name)
+		// TODO: remove guard with move to controller-runtime 0.9.0 https://issue.k8s.io/99714
+		if len(postgresCluster.Status.Conditions) > 0 {
+			meta.RemoveStatusCondition(&postgresCluster.Status.Conditions, ConditionRepoHostReady)
+			meta.RemoveStatusCondition(&postgresCluster.Status.Conditions, ConditionReplicaCreate)
+		}
+		return reconcile.Result{}, nil
+	}
+
 	// if nil, create the pgBackRest status that will be updated when reconciling various
 	// pgBackRest resources
 	if postgresCluster.Status.PGBackRest == nil {
 		postgresCluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{}
 	}
 
+	// keep the name of the stanza so that it does not change with the default
+	if postgresCluster.Spec.Backups.PGBackRest.Stanza == "" {
+		postgresCluster.Status.PGBackRest.Stanza = pgbackrest.StanzaName(postgresCluster)
+	}
+
 	// create the Result that will be updated while reconciling any/all pgBackRest resources
 	result := reconcile.Result{}
 
@@ -1216,6 +1471,12 @@ func (r *Reconciler) reconcilePGBackRest(ctx context.Context,
 		return reconcile.Result{}, errors.WithStack(err)
 	}
 
+	// move repositories to new volumes when their volumes cannot change in place
+	if err := r.relocateRepoVolumes(ctx, postgresCluster, repoResources); err != nil {
+		log.Error(err, "unable to relocate pgBackRest repo volumes")
+		result = updateReconcileResult(result, reconcile.Result{Requeue: true})
+	}
+
 	var repoHost *appsv1.StatefulSet
 	var repoHostName string
 	dedicatedEnabled := pgbackrest.DedicatedRepoHostEnabled(postgresCluster)
@@ -1228,6 +1489,13 @@ func (r *Reconciler) reconcilePGBackRest(ctx context.Context,
 			return result, nil
 		}
 		repoHostName = repoHost.GetName()
+
+		// reconcile the standby repository host, if any
+		if err := r.reconcileStandbyRepoHost(ctx, postgresCluster, repoHostName,
+			repoResources); err != nil {
+			log.Error(err, "unable to reconcile pgBackRest standby repo host")
+			result = updateReconcileResult(result, reconcile.Result{Requeue: true})
+		}
 	} else if len(postgresCluster.Status.Conditions) > 0 {
 		// TODO: remove guard above with move to controller-runtime 0.9.0 https://issue.k8s.io/99714
 		// remove the dedicated repo host status if a dedicated host is not enabled
@@ -1252,6 +1520,11 @@ func (r *Reconciler) reconcilePGBackRest(ctx context.Context,
 		return result, nil
 	}
 
+	// archiving and backups cannot happen while a repository is being copied
+	if repoResources.relocating {
+		return updateReconcileResult(result, reconcile.Result{RequeueAfter: 10 * time.Second}), nil
+	}
+
 	// gather instance names and reconcile all pgbackrest configuration and secrets
 	instanceNames := []string{}
 	for _, instance := range instances.forCluster {
@@ -1266,6 +1539,14 @@ func (r *Reconciler) reconcilePGBackRest(ctx context.Context,
 		result = updateReconcileResult(result, reconcile.Result{Requeue: true})
 	}
 
+	// make sure every pod has the current credentials, e.g. after keys are rotated
+	if credentialsResult, err := r.reconcilePGBackRestCredentials(ctx, postgresCluster); err != nil {
+		log.Error(err, "unable to reconcile pgBackRest credentials")
+		result = updateReconcileResult(result, reconcile.Result{Requeue: true})
+	} else {
+		result = updateReconcileResult(result, credentialsResult)
+	}
+
 	// reconcile the RBAC required to run pgBackRest Jobs (e.g. for backups)
 	sa, err := r.reconcilePGBackRestRBAC(ctx, postgresCluster)
 	if err != nil {
@@ -1298,6 +1579,19 @@ func (r *Reconciler) reconcilePGBackRest(ctx context.Context,
 		log.Info("pgBackRest config hash mismatch detected, requeuing to reattempt stanza create")
 		result = updateReconcileResult(result, reconcile.Result{RequeueAfter: 10 * time.Second})
 	}
+	// verify that stanzas still exist and match PostgreSQL, e.g. after a repository was
+	// wiped or PostgreSQL was upgraded, and repair them as needed
+	if next, err := r.reconcileStanzaHealth(ctx, postgresCluster, instances); err != nil {
+		log.Error(err, "unable to verify stanzas")
+		result = updateReconcileResult(result, reconcile.Result{RequeueAfter: 10 * time.Second})
+	} else {
+		result = updateReconcileResult(result, next)
+	}
+	// verify the newest full backups before their repositories expire older ones
+	if err := r.reconcileBackupVerification(ctx, postgresCluster, sa); err != nil {
+		log.Error(err, "unable to verify backups")
+		result = updateReconcileResult(result, reconcile.Result{RequeueAfter: 10 * time.Second})
+	}
 	// reconcile the pgBackRest backup CronJobs
 	requeue := r.reconcileScheduledBackups(ctx, postgresCluster, sa)
 	// If the pgBackRest backup CronJob reconciliation function has encountered an error, requeue
@@ -1334,9 +1628,11 @@ func (r *Reconciler) reconcilePGBackRest(ctx context.Context,
 
 // reconcilePostgresClusterDataSource is responsible for reconciling a PostgresCluster data source.
 // This is specifically done by running a pgBackRest restore to populate a PostgreSQL data volume
-// for the PostgresCluster being reconciled using the backups of another PostgresCluster.
+// for the PostgresCluster being reconciled using the backups of another PostgresCluster. When
+// cloudDataSource is not nil, the backups are in that repository instead; see restoreDataSource.
 func (r *Reconciler) reconcilePostgresClusterDataSource(ctx context.Context,
 	cluster *v1beta1.PostgresCluster, dataSource *v1beta1.PostgresClusterDataSource,
+	cloudDataSource *v1beta1.PGBackRestDataSource,
 	configHash string, clusterVolumes []corev1.PersistentVolumeClaim) error {
 
 	// grab cluster, namespaces and repo name information from the data source
@@ -1384,6 +1680,15 @@ func (r *Reconciler) reconcilePostgresClusterDataSource(ctx context.Context,
 			errors.New("unable to determine the proper instance set for the restore"))
 	}
 
+	// A restore Job populates a data volume before any instance Pod exists, so
+	// there is nothing to restore into when that volume is ephemeral.
+	if instanceSet.Ephemeral != nil {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "InvalidDataSource",
+			"Instance set %q stores data in ephemeral volumes, which cannot be restored by pgBackRest",
+			instanceSet.Name)
+		return nil
+	}
+
 	// If the cluster is already bootstrapped, or if the bootstrap Job is complete, then
 	// nothing to do.  However, also ensure the "data sources initialized" condition is set
 	// to true if for some reason it doesn't exist (e.g. if it was deleted since the
@@ -1411,7 +1716,19 @@ func (r *Reconciler) reconcilePostgresClusterDataSource(ctx context.Context,
 	// If the source cluster is not the same as the current cluster, then look it up.
 	sourceCluster := &v1beta1.PostgresCluster{}
 	var sourceClusterInstance string
-	if sourceClusterName == cluster.GetName() && sourceClusterNamespace == cluster.GetNamespace() {
+	if cloudDataSource != nil {
+		if cloudDataSource.Repo.Volume != nil {
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "InvalidDataSource",
+				"Repo %q in spec.dataSource.pgbackrest must be an Azure, GCS, or S3 repository",
+				cloudDataSource.Repo.Name)
+			return nil
+		}
+		sourceCluster = restoreSourceCluster(cluster, cloudDataSource)
+		if err := r.reconcileCloudRestoreConfiguration(ctx, cluster, sourceCluster,
+			instanceName); err != nil {
+			return err
+		}
+	} else if sourceClusterName == cluster.GetName() && sourceClusterNamespace == cluster.GetNamespace() {
 		sourceCluster = cluster.DeepCopy()
 		sourceClusterInstance = instanceName
 		instance := &Instance{Name: sourceClusterInstance}
@@ -1489,12 +1806,76 @@ func (r *Reconciler) reconcilePostgresClusterDataSource(ctx context.Context,
 	return nil
 }
 
+// restoreDataSource returns the options of a restore from the repository in
+// source. Its ClusterName is empty; see restoreSourceCluster.
+func restoreDataSource(source *v1beta1.PGBackRestDataSource) *v1beta1.PostgresClusterDataSource {
+	return &v1beta1.PostgresClusterDataSource{
+		RepoName:          source.Repo.Name,
+		Options:           source.Options,
+		Resources:         source.Resources,
+		Affinity:          source.Affinity,
+		NodeSelector:      source.NodeSelector,
+		PriorityClassName: source.PriorityClassName,
+		Tolerations:       source.Tolerations,
+	}
+}
+
+// restoreSourceCluster returns a PostgresCluster that describes the repository
+// in source as though it were the source cluster of a restore. It is named like
+// the copy made by copyRestoreConfiguration.
+func restoreSourceCluster(
+	cluster *v1beta1.PostgresCluster, source *v1beta1.PGBackRestDataSource,
+) *v1beta1.PostgresCluster {
+	sourceCluster := cluster.DeepCopy()
+	sourceCluster.Name = cluster.GetName() + "-restore"
+	sourceCluster.Spec.Backups.PGBackRest = v1beta1.PGBackRestArchive{
+		Configuration: source.Configuration,
+		Global:        source.Global,
+		Image:         cluster.Spec.Backups.PGBackRest.Image,
+		Metadata:      cluster.Spec.Backups.PGBackRest.Metadata,
+		Repos:         []v1beta1.PGBackRestRepo{*source.Repo.DeepCopy()},
+		Stanza:        source.Stanza,
+	}
+	if sourceCluster.Spec.Backups.PGBackRest.Stanza == "" {
+		sourceCluster.Spec.Backups.PGBackRest.Stanza = pgbackrest.DefaultStanzaName
+	}
+	return sourceCluster
+}
+
+// reconcileCloudRestoreConfiguration writes the pgBackRest configuration of
+// sourceCluster, from restoreSourceCluster, for the restore Job of cluster.
+func (r *Reconciler) reconcileCloudRestoreConfiguration(ctx context.Context,
+	cluster, sourceCluster *v1beta1.PostgresCluster, instanceName string) error {
+
+	// label and own the configuration according to the PostgresCluster being created
+	owned := &corev1.ConfigMap{ObjectMeta: naming.PGBackRestConfig(sourceCluster)}
+	if err := r.setOwnerReference(cluster, owned); err != nil {
+		return errors.WithStack(err)
+	}
+	overrideMetadata := &metav1.ObjectMeta{
+		Annotations: naming.Merge(
+			cluster.Spec.Metadata.GetAnnotationsOrNil(),
+			cluster.Spec.Backups.PGBackRest.Metadata.GetAnnotationsOrNil()),
+		Labels: naming.Merge(cluster.Spec.Metadata.GetLabelsOrNil(),
+			cluster.Spec.Backups.PGBackRest.Metadata.GetLabelsOrNil(),
+			naming.PGBackRestRestoreConfigLabels(cluster.GetName())),
+		OwnerReferences: owned.OwnerReferences,
+	}
+
+	return errors.WithStack(r.reconcilePGBackRestConfig(ctx, sourceCluster, overrideMetadata,
+		"", "", naming.ClusterPodService(cluster).Name, cluster.GetNamespace(),
+		[]string{instanceName}, nil))
+}
+
 // copyRestoreConfiguration copies pgBackRest configuration from another cluster for use by
 // the current PostgresCluster (e.g. when restoring across namespaces, and the configuration
 // for the source cluster needs to be copied into the PostgresCluster's local namespace).
 func (r *Reconciler) copyRestoreConfiguration(ctx context.Context,
 	cluster, sourceCluster *v1beta1.PostgresCluster, sourceClusterInstance string) error {
 
+	// The stanza may be named after the source cluster; keep it.
+	sourceCluster.Spec.Backups.PGBackRest.Stanza = pgbackrest.StanzaName(sourceCluster)
+
 	origSourceCluster := sourceCluster.DeepCopy()
 	sourceCluster.ObjectMeta.Name = cluster.GetName() + "-restore"
 	sourceCluster.ObjectMeta.Namespace = cluster.GetNamespace()
@@ -1739,11 +2120,9 @@ func (r *Reconciler) reconcileDedicatedRepoHost(ctx context.Context,
 	}()
 	var isCreate bool
 	if len(repoResources.hosts) == 0 {
-		name := fmt.Sprintf("%s-%s", postgresCluster.GetName(), "repo-host")
 		repoResources.hosts = append(repoResources.hosts, &appsv1.StatefulSet{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: name,
-			}})
+			ObjectMeta: naming.PGBackRestRepoHost(postgresCluster),
+		})
 		isCreate = true
 	} else {
 		sort.Slice(repoResources.hosts, func(i, j int) bool {
@@ -1768,6 +2147,198 @@ func (r *Reconciler) reconcileDedicatedRepoHost(ctx context.Context,
 	return repoHost, nil
 }
 
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=create;patch
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=create;patch
+
+// reconcileStandbyRepoHost is responsible for reconciling the standby pgBackRest repository
+// host, and the volumes it uses to keep a copy of each "volume" repository, when one is enabled.
+// Resources that remain from when a standby was enabled are deleted by cleanupRepoResources.
+func (r *Reconciler) reconcileStandbyRepoHost(ctx context.Context,
+	postgresCluster *v1beta1.PostgresCluster, repoHostName string,
+	repoResources *RepoResources) error {
+
+	if !pgbackrest.StandbyRepoHostEnabled(postgresCluster) {
+		return nil
+	}
+
+	for _, repo := range postgresCluster.Spec.Backups.PGBackRest.Repos {
+		if repo.Volume == nil {
+			continue
+		}
+		pvc, err := r.generateStandbyRepoVolumeIntent(postgresCluster, repo, repoResources)
+		if err == nil {
+			err = r.handlePersistentVolumeClaimError(postgresCluster,
+				errors.WithStack(r.apply(ctx, pvc)))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	standby, err := r.generateStandbyRepoHostIntent(ctx, postgresCluster, repoHostName, repoResources)
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, standby))
+	}
+	return err
+}
+
+// generateStandbyRepoVolumeIntent returns the volume in which the standby repository host
+// keeps a copy of repo. The storage class and size of an existing volume are kept.
+func (r *Reconciler) generateStandbyRepoVolumeIntent(postgresCluster *v1beta1.PostgresCluster,
+	repo v1beta1.PGBackRestRepo, repoResources *RepoResources,
+) (*corev1.PersistentVolumeClaim, error) {
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: naming.PGBackRestStandbyRepoVolume(postgresCluster, repo.Name),
+		Spec:       *repo.Volume.VolumeClaimSpec.DeepCopy(),
+	}
+	pvc.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"))
+
+	pvc.Annotations = naming.Merge(
+		postgresCluster.Spec.Metadata.GetAnnotationsOrNil(),
+		postgresCluster.Spec.Backups.PGBackRest.Metadata.GetAnnotationsOrNil())
+	pvc.Labels = naming.Merge(
+		postgresCluster.Spec.Metadata.GetLabelsOrNil(),
+		postgresCluster.Spec.Backups.PGBackRest.Metadata.GetLabelsOrNil(),
+		naming.PGBackRestStandbyVolumeLabels(postgresCluster.Name, repo.Name))
+
+	for _, existing := range repoResources.standbyPVCs {
+		if existing.Name != pvc.Name {
+			continue
+		}
+		pvc.Spec.StorageClassName = existing.Spec.StorageClassName
+
+		want, requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		have, exists := existing.Spec.Resources.Requests[corev1.ResourceStorage]
+		if requested && exists && want.Cmp(have) < 0 {
+			pvc.Spec.Resources.Requests[corev1.ResourceStorage] = have
+		}
+	}
+
+	err := errors.WithStack(controllerutil.SetControllerReference(postgresCluster, pvc,
+		r.Client.Scheme()))
+
+	return pvc, err
+}
+
+// generateStandbyRepoHostIntent returns the StatefulSet of the standby repository host. It is
+// the repository host with its own volumes and a container that periodically copies every
+// "volume" repository from the repository host over SSH. It prefers a zone other than the
+// one of the repository host.
+func (r *Reconciler) generateStandbyRepoHostIntent(ctx context.Context,
+	postgresCluster *v1beta1.PostgresCluster, repoHostName string,
+	repoResources *RepoResources,
+) (*appsv1.StatefulSet, error) {
+
+	// mount the standby volumes in place of the repository volumes
+	standbyResources := &RepoResources{relocating: repoResources.relocating}
+	repoNames := []string{}
+	for _, repo := range postgresCluster.Spec.Backups.PGBackRest.Repos {
+		if repo.Volume != nil {
+			repoNames = append(repoNames, repo.Name)
+			standbyResources.pvcs = append(standbyResources.pvcs, &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   naming.PGBackRestStandbyRepoVolume(postgresCluster, repo.Name).Name,
+					Labels: map[string]string{naming.LabelPGBackRestRepo: repo.Name},
+				},
+			})
+		}
+	}
+
+	meta := naming.PGBackRestStandbyRepoHost(postgresCluster)
+	standby, err := r.generateRepoHostIntent(postgresCluster, meta.Name, standbyResources)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := naming.Merge(
+		postgresCluster.Spec.Metadata.GetLabelsOrNil(),
+		postgresCluster.Spec.Backups.PGBackRest.Metadata.GetLabelsOrNil(),
+		naming.PGBackRestStandbyLabels(postgresCluster.GetName()))
+	standby.Labels = labels
+	standby.Spec.Selector = &metav1.LabelSelector{
+		MatchLabels: naming.PGBackRestStandbyLabels(postgresCluster.GetName()),
+	}
+	standby.Spec.Template.Labels = labels
+
+	// prefer a zone other than the one of the repository host
+	template := &standby.Spec.Template.Spec
+	if template.Affinity == nil {
+		template.Affinity = &corev1.Affinity{}
+	}
+	if template.Affinity.PodAntiAffinity == nil {
+		template.Affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+	template.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+		template.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+		corev1.WeightedPodAffinityTerm{
+			Weight: 100,
+			PodAffinityTerm: corev1.PodAffinityTerm{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: naming.PGBackRestDedicatedLabels(postgresCluster.GetName()),
+				},
+				TopologyKey: corev1.LabelTopologyZone,
+			},
+		})
+
+	// copy every "volume" repository from the repository host using the same
+	// environment, SSH configuration, and volumes as the pgBackRest container
+	interval := int32(300)
+	if i := postgresCluster.Spec.Backups.PGBackRest.RepoHost.Standby.IntervalSeconds; i != nil {
+		interval = *i
+	}
+	host := repoHostName + "-0." + naming.ClusterPodService(postgresCluster).Name + "." +
+		postgresCluster.GetNamespace() + ".svc." + naming.KubernetesClusterDomain(ctx)
+
+	for _, container := range template.Containers {
+		if container.Name == naming.PGBackRestRepoContainerName {
+			template.Containers = append(template.Containers, corev1.Container{
+				Command: append([]string{
+					"bash", "-c", "--", standbyRepoSyncScript, "repo-sync",
+					fmt.Sprint(interval), host,
+				}, repoNames...),
+				Env:             container.Env,
+				Image:           container.Image,
+				ImagePullPolicy: container.ImagePullPolicy,
+				Name:            naming.ContainerPGBackRestStandbySync,
+				Resources:       container.Resources,
+				SecurityContext: initialize.RestrictedSecurityContext(),
+				VolumeMounts:    container.VolumeMounts,
+			})
+			break
+		}
+	}
+
+	return standby, nil
+}
+
+// standbyRepoSyncScript copies repositories from a repository host forever. Its
+// arguments are the seconds to wait between copies, the repository host, and the
+// names of the repositories. Each copy transfers the files that changed since
+// the previous copy began, then removes files that are gone from the repository
+// host, e.g. after backups expire.
+const standbyRepoSyncScript = `
+set -o pipefail
+declare -r interval="$1" host="$2"; shift 2
+declare -A since=()
+while true; do
+  for repo in "$@"; do
+    directory="/pgbackrest/${repo}"
+    started="$(date +%s)"
+    if ssh "${host}" "tar --create --directory='${directory}' --newer-mtime='@${since[${repo}]:-0}' ." |
+      tar --extract --directory="${directory}" &&
+      remote="$(ssh "${host}" "cd '${directory}' && find . -mindepth 1 | sort")"
+    then
+      (cd "${directory}" && find . -mindepth 1 | sort | comm -13 <(echo "${remote}") - |
+        xargs --no-run-if-empty --delimiter='\n' rm -rf --)
+      since[${repo}]="${started}"
+      echo "$(date --iso-8601=seconds) copied ${repo}"
+    fi
+  done
+  sleep "${interval}"
+done
+`
+
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=create;patch;delete
 
 // reconcileManualBackup is responsible for reconciling pgBackRest backups that are initiated
@@ -1806,6 +2377,11 @@ func (r *Reconciler) reconcileManualBackup(ctx context.Context,
 					Reason:             "ManualBackupFailed",
 					Message:            "Manual backup did not complete successfully",
 				})
+				if !manualStatus.Finished {
+					r.Recorder.Eventf(postgresCluster, corev1.EventTypeWarning, EventBackupFailed,
+						"Manual backup %q did not complete successfully; see Job %q",
+						backupID, currentBackupJob.Name)
+				}
 			}
 
 			// update the manual backup status based on the current status of the manual backup Job
@@ -2213,48 +2789,330 @@ func (r *Reconciler) reconcileRepos(ctx context.Context,
 	return replicaCreateRepoName, nil
 }
 
-// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list
-// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+// repoVolumeOutdated returns whether or not pvc has a storage class other than
+// the one specified for repo or requests more storage than repo does. Neither
+// can change in place.
+func repoVolumeOutdated(repo *v1beta1.RepoPVC, pvc *corev1.PersistentVolumeClaim) bool {
+	if pvc == nil {
+		return false
+	}
 
-// reconcileStanzaCreate is responsible for ensuring stanzas are properly created for the
-// pgBackRest repositories configured for a PostgresCluster.  If the bool returned from this
-// function is false, this indicates that a pgBackRest config hash mismatch was identified that
-// prevented the "pgbackrest stanza-create" command from running (with a config has mismatch
-// indicating that pgBackRest configuration as stored in the pgBackRest ConfigMap has not yet
-// propagated to the Pod).
-func (r *Reconciler) reconcileStanzaCreate(ctx context.Context,
-	postgresCluster *v1beta1.PostgresCluster,
-	instances *observedInstances, configHash string) (bool, error) {
+	class := repo.VolumeClaimSpec.StorageClassName
+	if class != nil && (pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName != *class) {
+		return true
+	}
 
-	// ensure conditions are set before returning as needed by subsequent reconcile functions
-	defer func() {
-		var replicaCreateRepoStatus *v1beta1.RepoStatus
-		if len(postgresCluster.Spec.Backups.PGBackRest.Repos) == 0 {
-			return
+	want, requested := repo.VolumeClaimSpec.Resources.Requests[corev1.ResourceStorage]
+	have, exists := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	return requested && exists && want.Cmp(have) < 0
+}
+
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=create;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;create;patch;delete
+
+// relocateRepoVolumes moves pgBackRest repositories to new volumes when their
+// storage class changes or their requested size decreases. It sets the
+// relocating field of repoResources while the repository host should be stopped.
+//
+// A volume of the new class and size is created next to the current one. With
+// the "copy" relocation, the repository host is stopped and a Job copies the
+// repository to the new volume. Once that Job completes, or right away with the
+// "reseed" relocation, the current volume is deleted and the repository host
+// moves to the new one.
+func (r *Reconciler) relocateRepoVolumes(ctx context.Context,
+	postgresCluster *v1beta1.PostgresCluster, repoResources *RepoResources) error {
+
+	repoPVCNames := getRepoPVCNames(postgresCluster, repoResources.pvcs)
+
+	for i := range postgresCluster.Spec.Backups.PGBackRest.Repos {
+		repo := &postgresCluster.Spec.Backups.PGBackRest.Repos[i]
+		if repo.Volume == nil {
+			continue
 		}
-		replicaCreateRepoName := postgresCluster.Spec.Backups.PGBackRest.Repos[0].Name
-		for i, r := range postgresCluster.Status.PGBackRest.Repos {
-			if r.Name == replicaCreateRepoName {
-				replicaCreateRepoStatus = &postgresCluster.Status.PGBackRest.Repos[i]
-				break
+
+		var current, target *corev1.PersistentVolumeClaim
+		for _, pvc := range repoResources.pvcs {
+			_, relocation := pvc.Labels[naming.LabelPGBackRestRelocation]
+			switch {
+			case pvc.Labels[naming.LabelPGBackRestRepo] != repo.Name:
+			case pvc.Name == repoPVCNames[repo.Name]:
+				current = pvc
+			case relocation && pvc.DeletionTimestamp == nil:
+				target = pvc
 			}
 		}
 
-		replicaCreateRepoReady := metav1.Condition{
-			ObservedGeneration: postgresCluster.GetGeneration(),
-			Type:               ConditionReplicaRepoReady,
+		job := &batchv1.Job{ObjectMeta: naming.PGBackRestRepoRelocationJob(postgresCluster, repo.Name)}
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(job), job); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return errors.WithStack(err)
+			}
+			job = nil
 		}
-		if replicaCreateRepoStatus == nil {
-			replicaCreateRepoReady.Status = metav1.ConditionUnknown
-			replicaCreateRepoReady.Reason = "RepoStatusMissing"
-			replicaCreateRepoReady.Message = "Status is missing for the replica creation repo"
-		} else if replicaCreateRepoStatus.StanzaCreated {
-			replicaCreateRepoReady.Status = metav1.ConditionTrue
-			replicaCreateRepoReady.Reason = "StanzaCreated"
-			replicaCreateRepoReady.Message = "pgBackRest replica create repo is ready for " +
-				"backups"
-		} else {
-			replicaCreateRepoReady.Status = metav1.ConditionFalse
+
+		// Clean up after a relocation that is done or no longer wanted. Start
+		// over when the spec changed again during a relocation.
+		if !repoVolumeOutdated(repo.Volume, current) || repoVolumeOutdated(repo.Volume, target) {
+			if target != nil {
+				if err := r.Client.Delete(ctx, target,
+					client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+					return errors.WithStack(client.IgnoreNotFound(err))
+				}
+			}
+			if job != nil {
+				if err := r.Client.Delete(ctx, job,
+					client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+					return errors.WithStack(client.IgnoreNotFound(err))
+				}
+			}
+			continue
+		}
+
+		pvc, err := r.generateRepoVolumeRelocationIntent(postgresCluster, repo, current, target)
+		if err == nil {
+			err = errors.WithStack(r.apply(ctx, pvc))
+		}
+		if err := r.handlePersistentVolumeClaimError(postgresCluster, err); err != nil {
+			return err
+		}
+		if target == nil {
+			r.Recorder.Eventf(postgresCluster, corev1.EventTypeNormal, "RepoVolumeRelocation",
+				"Created volume %q to replace volume %q of repository %q",
+				pvc.Name, current.Name, repo.Name)
+		}
+
+		if repo.Volume.Relocation == "reseed" || (job != nil && jobCompleted(job)) {
+			if err := r.Client.Delete(ctx, current,
+				client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+				return errors.WithStack(client.IgnoreNotFound(err))
+			}
+			r.Recorder.Eventf(postgresCluster, corev1.EventTypeNormal, "RepoVolumeRelocation",
+				"Moved repository %q from volume %q to volume %q",
+				repo.Name, current.Name, pvc.Name)
+
+			// Forget the deleted volume so the repository host moves to the new one.
+			for j := range repoResources.pvcs {
+				if repoResources.pvcs[j] == current {
+					repoResources.pvcs = append(repoResources.pvcs[:j], repoResources.pvcs[j+1:]...)
+					break
+				}
+			}
+			continue
+		}
+
+		// Leave a failed copy in place so it is not retried endlessly. Deleting
+		// the Job tries again.
+		if job != nil && jobFailed(job) {
+			r.Recorder.Eventf(postgresCluster, corev1.EventTypeWarning, "RepoVolumeRelocationFailed",
+				"Unable to copy repository %q to volume %q; delete Job %q to try again",
+				repo.Name, pvc.Name, job.Name)
+			continue
+		}
+
+		repoResources.relocating = true
+
+		// Wait for the repository host to stop before copying the repository.
+		stopped := true
+		for _, host := range repoResources.hosts {
+			if host.Status.Replicas > 0 {
+				stopped = false
+			}
+		}
+		if !stopped || job != nil {
+			continue
+		}
+
+		job, err = r.generateRepoRelocationJobIntent(postgresCluster, repo.Name, current, pvc)
+		if err == nil {
+			err = errors.WithStack(r.apply(ctx, job))
+		}
+		if err != nil {
+			return err
+		}
+		r.Recorder.Eventf(postgresCluster, corev1.EventTypeNormal, "RepoVolumeRelocation",
+			"Copying repository %q from volume %q to volume %q",
+			repo.Name, current.Name, pvc.Name)
+	}
+
+	return nil
+}
+
+// generateRepoVolumeRelocationIntent returns the volume that replaces current
+// as the volume of repo. It alternates between the default name and another
+// so that a volume can be relocated more than once.
+func (r *Reconciler) generateRepoVolumeRelocationIntent(
+	postgresCluster *v1beta1.PostgresCluster, repo *v1beta1.PGBackRestRepo,
+	current, target *corev1.PersistentVolumeClaim,
+) (*corev1.PersistentVolumeClaim, error) {
+
+	meta := naming.PGBackRestRepoVolume(postgresCluster, repo.Name)
+	if current.Name == meta.Name {
+		meta = naming.PGBackRestRepoVolumeRelocation(postgresCluster, repo.Name)
+	}
+	if target != nil {
+		meta.Name = target.Name
+	}
+
+	meta.Annotations = naming.Merge(
+		postgresCluster.Spec.Metadata.GetAnnotationsOrNil(),
+		postgresCluster.Spec.Backups.PGBackRest.Metadata.GetAnnotationsOrNil())
+	meta.Labels = naming.Merge(
+		postgresCluster.Spec.Metadata.GetLabelsOrNil(),
+		postgresCluster.Spec.Backups.PGBackRest.Metadata.GetLabelsOrNil(),
+		naming.PGBackRestRepoVolumeLabels(postgresCluster.GetName(), repo.Name),
+		map[string]string{
+			naming.LabelPGBackRestRelocation: "",
+		})
+
+	pvc := &corev1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "PersistentVolumeClaim",
+		},
+		ObjectMeta: meta,
+		Spec:       *repo.Volume.VolumeClaimSpec.DeepCopy(),
+	}
+
+	err := errors.WithStack(controllerutil.SetControllerReference(postgresCluster, pvc,
+		r.Client.Scheme()))
+
+	return pvc, err
+}
+
+// generateRepoRelocationJobIntent returns a Job that copies the repository in
+// volume source to volume target.
+func (r *Reconciler) generateRepoRelocationJobIntent(
+	postgresCluster *v1beta1.PostgresCluster, repoName string,
+	source, target *corev1.PersistentVolumeClaim,
+) (*batchv1.Job, error) {
+
+	job := &batchv1.Job{ObjectMeta: naming.PGBackRestRepoRelocationJob(postgresCluster, repoName)}
+	job.SetGroupVersionKind(batchv1.SchemeGroupVersion.WithKind("Job"))
+
+	job.Annotations = naming.Merge(
+		postgresCluster.Spec.Metadata.GetAnnotationsOrNil(),
+		postgresCluster.Spec.Backups.PGBackRest.Metadata.GetAnnotationsOrNil())
+	job.Labels = naming.Merge(
+		postgresCluster.Spec.Metadata.GetLabelsOrNil(),
+		postgresCluster.Spec.Backups.PGBackRest.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster:              postgresCluster.Name,
+			naming.LabelPGBackRestRelocation: repoName,
+		})
+
+	// Copy everything, including the "archive" and "backup" directories, and
+	// keep ownership and permissions. Copying again after a failure overwrites
+	// what was copied before.
+	script := `cp --archive --verbose /pgbackrest/source/. /pgbackrest/target/`
+
+	container := corev1.Container{
+		Command:         []string{"bash", "-ceu", "--", script},
+		Image:           config.PGBackRestContainerImage(postgresCluster),
+		ImagePullPolicy: postgresCluster.Spec.ImagePullPolicy,
+		Name:            naming.ContainerJobRelocatePGBackRestRepo,
+		SecurityContext: initialize.RestrictedSecurityContext(),
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "source", MountPath: "/pgbackrest/source", ReadOnly: true},
+			{Name: "target", MountPath: "/pgbackrest/target"},
+		},
+	}
+	if repoHost := postgresCluster.Spec.Backups.PGBackRest.RepoHost; repoHost != nil {
+		container.Resources = repoHost.Resources
+	}
+
+	job.Spec = batchv1.JobSpec{
+		Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: job.Labels, Annotations: job.Annotations},
+			Spec: corev1.PodSpec{
+				Containers:      []corev1.Container{container},
+				SecurityContext: postgres.PodSecurityContext(postgresCluster),
+				// Set RestartPolicy to "Never" since we want a new Pod to be created by the Job
+				// controller when there is a failure (instead of the container simply restarting).
+				RestartPolicy: corev1.RestartPolicyNever,
+				// This Job does not make Kubernetes API calls. Use the default
+				// ServiceAccount and do not mount its credentials.
+				AutomountServiceAccountToken: initialize.Bool(false),
+				Volumes: []corev1.Volume{{
+					Name: "source",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: source.Name,
+							ReadOnly:  true,
+						},
+					},
+				}, {
+					Name: "target",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: target.Name,
+						},
+					},
+				}},
+			},
+		},
+	}
+
+	// Run where the repository host can.
+	if repoHost := postgresCluster.Spec.Backups.PGBackRest.RepoHost; repoHost != nil {
+		job.Spec.Template.Spec.Affinity = repoHost.Affinity
+		job.Spec.Template.Spec.Tolerations = repoHost.Tolerations
+		if repoHost.PriorityClassName != nil {
+			job.Spec.Template.Spec.PriorityClassName = *repoHost.PriorityClassName
+		}
+	}
+	job.Spec.Template.Spec.Affinity = architectureAffinity(
+		config.ImageArchitectures(postgresCluster), job.Spec.Template.Spec.Affinity)
+	job.Spec.Template.Spec.ImagePullSecrets = postgresCluster.Spec.ImagePullSecrets
+	setPodNameResolution(postgresCluster, &job.Spec.Template.Spec)
+
+	err := errors.WithStack(controllerutil.SetControllerReference(postgresCluster, job,
+		r.Client.Scheme()))
+
+	return job, err
+}
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+
+// reconcileStanzaCreate is responsible for ensuring stanzas are properly created for the
+// pgBackRest repositories configured for a PostgresCluster.  If the bool returned from this
+// function is false, this indicates that a pgBackRest config hash mismatch was identified that
+// prevented the "pgbackrest stanza-create" command from running (with a config has mismatch
+// indicating that pgBackRest configuration as stored in the pgBackRest ConfigMap has not yet
+// propagated to the Pod).
+func (r *Reconciler) reconcileStanzaCreate(ctx context.Context,
+	postgresCluster *v1beta1.PostgresCluster,
+	instances *observedInstances, configHash string) (bool, error) {
+
+	// ensure conditions are set before returning as needed by subsequent reconcile functions
+	defer func() {
+		var replicaCreateRepoStatus *v1beta1.RepoStatus
+		if len(postgresCluster.Spec.Backups.PGBackRest.Repos) == 0 {
+			return
+		}
+		replicaCreateRepoName := postgresCluster.Spec.Backups.PGBackRest.Repos[0].Name
+		for i, r := range postgresCluster.Status.PGBackRest.Repos {
+			if r.Name == replicaCreateRepoName {
+				replicaCreateRepoStatus = &postgresCluster.Status.PGBackRest.Repos[i]
+				break
+			}
+		}
+
+		replicaCreateRepoReady := metav1.Condition{
+			ObservedGeneration: postgresCluster.GetGeneration(),
+			Type:               ConditionReplicaRepoReady,
+		}
+		if replicaCreateRepoStatus == nil {
+			replicaCreateRepoReady.Status = metav1.ConditionUnknown
+			replicaCreateRepoReady.Reason = "RepoStatusMissing"
+			replicaCreateRepoReady.Message = "Status is missing for the replica creation repo"
+		} else if replicaCreateRepoStatus.StanzaCreated {
+			replicaCreateRepoReady.Status = metav1.ConditionTrue
+			replicaCreateRepoReady.Reason = "StanzaCreated"
+			replicaCreateRepoReady.Message = "pgBackRest replica create repo is ready for " +
+				"backups"
+		} else {
+			replicaCreateRepoReady.Status = metav1.ConditionFalse
 			replicaCreateRepoReady.Reason = "StanzaNotCreated"
 			replicaCreateRepoReady.Message = "pgBackRest replica create repo is not ready " +
 				"for backups"
@@ -2301,13 +3159,34 @@ func (r *Reconciler) reconcileStanzaCreate(ctx context.Context,
 		return false, nil
 	}
 
+	// a fenced cluster no longer writes to its repositories
+	if pgbackrest.ArchiveFenced(postgresCluster) {
+		return false, nil
+	}
+
+	// do not create a stanza that another cluster writes to; their backups and
+	// WAL would be mixed together
+	if conflicts, err := r.stanzaConflicts(ctx, postgresCluster); err != nil {
+		return false, err
+	} else if len(conflicts) > 0 {
+		r.Recorder.Eventf(postgresCluster, corev1.EventTypeWarning, EventStanzaConflict,
+			"pgBackRest stanza %q is also used by %s; set a different "+
+				"spec.backups.pgbackrest.stanza", pgbackrest.StanzaName(postgresCluster),
+			strings.Join(conflicts, ", "))
+		return false, nil
+	}
+
 	// create a pgBackRest executor and attempt stanza creation
 	exec := func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer,
 		command ...string) error {
-		return r.PodExec(postgresCluster.GetNamespace(), writableInstanceName,
+		return r.PodExec(ctx, postgresCluster.GetNamespace(), writableInstanceName,
 			naming.ContainerDatabase, stdin, stdout, stderr, command...)
 	}
-	configHashMismatch, err := pgbackrest.Executor(exec).StanzaCreate(ctx, configHash)
+	configHashMismatch, err := pgbackrest.Executor(exec).StanzaCreate(ctx,
+		pgbackrest.StanzaName(postgresCluster), configHash)
+	if err != nil && r.upgradeStanza(ctx, postgresCluster, exec) {
+		err = nil
+	}
 	if err != nil {
 		// record and log any errors resulting from running the stanza-create command
 		r.Recorder.Event(postgresCluster, corev1.EventTypeWarning, EventUnableToCreateStanzas,
@@ -2335,6 +3214,489 @@ func (r *Reconciler) reconcileStanzaCreate(ctx context.Context,
 	return false, nil
 }
 
+// upgradeStanza runs the pgBackRest "stanza-upgrade" command when the stanza of postgresCluster
+// exists in every repository and differs from PostgreSQL only by its major version. This is the
+// case for a stanza written by the cluster that postgresCluster was migrated from when it ran an
+// older version of PostgreSQL. It returns whether or not the stanza was upgraded.
+func (r *Reconciler) upgradeStanza(ctx context.Context,
+	postgresCluster *v1beta1.PostgresCluster, exec pgbackrest.Executor) bool {
+
+	var systemIdentifier string
+	if postgresCluster.Status.Patroni != nil {
+		systemIdentifier = postgresCluster.Status.Patroni.SystemIdentifier
+	}
+	version := strconv.Itoa(postgresCluster.Spec.PostgresVersion)
+	stanza := pgbackrest.StanzaName(postgresCluster)
+
+	info, err := exec.StanzaInfo(ctx, stanza)
+	if err != nil || len(info) == 0 || systemIdentifier == "" {
+		return false
+	}
+
+	mismatched := false
+	for _, repo := range info {
+		if repo.Code != 0 || repo.SystemIdentifier != systemIdentifier {
+			return false
+		}
+		if repo.Version != version {
+			mismatched = true
+		}
+	}
+	if !mismatched {
+		return false
+	}
+
+	if err := exec.StanzaUpgrade(ctx, stanza); err != nil {
+		r.Recorder.Event(postgresCluster, corev1.EventTypeWarning, EventUnableToRepairStanza,
+			err.Error())
+		return false
+	}
+
+	r.Recorder.Eventf(postgresCluster, corev1.EventTypeNormal, EventStanzaRepaired,
+		"pgBackRest stanza %q upgraded to PostgreSQL %s", stanza, version)
+	return true
+}
+
+// stanzaCheckInterval is how often the stanzas of a healthy PostgresCluster are verified.
+const stanzaCheckInterval = 5 * time.Minute
+
+// reconcileStanzaHealth runs "pgbackrest info" on the primary to verify that the stanza of
+// postgresCluster still exists in every repository and matches PostgreSQL. A missing stanza is
+// marked as not created so that reconcileStanzaCreate creates it again. A stanza that belongs to
+// an older PostgreSQL version is upgraded. A stanza with another system identifier belongs to
+// another PostgreSQL cluster, so it is reported and left alone. Stanzas are verified at most once
+// per stanzaCheckInterval while they are healthy, and every reconcile otherwise.
+func (r *Reconciler) reconcileStanzaHealth(ctx context.Context,
+	postgresCluster *v1beta1.PostgresCluster,
+	instances *observedInstances) (reconcile.Result, error) {
+
+	status := postgresCluster.Status.PGBackRest
+	if status == nil || len(status.Repos) == 0 {
+		return reconcile.Result{}, nil
+	}
+
+	// standby clusters only read from their repository, and fenced clusters
+	// no longer write to it
+	if postgresCluster.StandbyEnabled() || pgbackrest.ArchiveFenced(postgresCluster) {
+		return reconcile.Result{}, nil
+	}
+
+	// verify only after every stanza was created, and only on a writable instance
+	var writableInstanceName string
+	for _, instance := range instances.forCluster {
+		if writable, known := instance.IsWritable(); writable && known {
+			writableInstanceName = instance.Name + "-0"
+			break
+		}
+	}
+	for _, repoStatus := range status.Repos {
+		if !repoStatus.StanzaCreated {
+			return reconcile.Result{}, nil
+		}
+	}
+	if writableInstanceName == "" {
+		return reconcile.Result{}, nil
+	}
+
+	healthy := meta.IsStatusConditionTrue(postgresCluster.Status.Conditions, ConditionStanzasHealthy)
+	if healthy && status.StanzaCheckTime != nil &&
+		time.Since(status.StanzaCheckTime.Time) < stanzaCheckInterval {
+		return reconcile.Result{
+			RequeueAfter: stanzaCheckInterval - time.Since(status.StanzaCheckTime.Time),
+		}, nil
+	}
+
+	condition := metav1.Condition{
+		ObservedGeneration: postgresCluster.GetGeneration(),
+		Type:               ConditionStanzasHealthy,
+		Status:             metav1.ConditionTrue,
+		Reason:             "StanzasHealthy",
+		Message:            "pgBackRest stanza exists and matches PostgreSQL in every repository",
+	}
+	defer func() { meta.SetStatusCondition(&postgresCluster.Status.Conditions, condition) }()
+
+	exec := func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer,
+		command ...string) error {
+		return r.PodExec(ctx, postgresCluster.GetNamespace(), writableInstanceName,
+			naming.ContainerDatabase, stdin, stdout, stderr, command...)
+	}
+	stanza := pgbackrest.StanzaName(postgresCluster)
+
+	info, err := pgbackrest.Executor(exec).StanzaInfo(ctx, stanza)
+	if err != nil {
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = "StanzaInfoFailed"
+		condition.Message = "Unable to run pgbackrest info"
+		return reconcile.Result{}, err
+	}
+
+	observeBackupMetrics(postgresCluster, info)
+	observeBackupVerification(postgresCluster, info)
+
+	if last, err := postgres.LastArchivedTime(ctx, postgres.Executor(exec)); err != nil {
+		logging.FromContext(ctx).Error(err, "unable to query pg_stat_archiver")
+	} else if !last.IsZero() {
+		pgbackrestLastArchivedTimestamp.WithLabelValues(
+			postgresCluster.Namespace, postgresCluster.Name).Set(float64(last.Unix()))
+	}
+
+	var systemIdentifier string
+	if postgresCluster.Status.Patroni != nil {
+		systemIdentifier = postgresCluster.Status.Patroni.SystemIdentifier
+	}
+	version := strconv.Itoa(postgresCluster.Spec.PostgresVersion)
+
+	var missing, foreign, mismatched []string
+	for _, repo := range info {
+		switch {
+		case repo.Code == pgbackrest.StanzaCodeMissingPath,
+			repo.Code == pgbackrest.StanzaCodeMissingData:
+			missing = append(missing, repo.Repo)
+		case repo.SystemIdentifier != "" && systemIdentifier != "" &&
+			repo.SystemIdentifier != systemIdentifier:
+			foreign = append(foreign, repo.Repo)
+		case repo.Version != "" && repo.Version != version:
+			mismatched = append(mismatched, repo.Repo)
+		}
+	}
+
+	now := metav1.Now()
+	status.StanzaCheckTime = &now
+
+	if len(missing) > 0 {
+		// create the stanza again during the next reconcile
+		for i := range status.Repos {
+			for _, name := range missing {
+				if status.Repos[i].Name == name {
+					status.Repos[i].StanzaCreated = false
+				}
+			}
+		}
+
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "StanzaMissing"
+		condition.Message = fmt.Sprintf("pgBackRest stanza %q is missing from %s; creating it again",
+			stanza, strings.Join(missing, ", "))
+		r.Recorder.Event(postgresCluster, corev1.EventTypeWarning, EventStanzaRepaired, condition.Message)
+
+		return reconcile.Result{Requeue: true}, nil
+	}
+
+	if len(foreign) > 0 {
+		// Another PostgreSQL cluster writes to the same repository, e.g. one
+		// shared or copied from elsewhere. Upgrading the stanza would take the
+		// repository from it, so leave it alone. See stanzaConflicts.
+		message := fmt.Sprintf("pgBackRest stanza %q in %s belongs to another PostgreSQL cluster; "+
+			"its system identifier is not %s. Use a different repository or "+
+			"spec.backups.pgbackrest.stanza", stanza, strings.Join(foreign, ", "), systemIdentifier)
+
+		if previous := meta.FindStatusCondition(postgresCluster.Status.Conditions,
+			ConditionStanzasHealthy); previous == nil || previous.Message != message {
+			r.Recorder.Event(postgresCluster, corev1.EventTypeWarning, EventStanzaConflict, message)
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "StanzaConflict"
+		condition.Message = message
+
+		return reconcile.Result{}, nil
+	}
+
+	if len(mismatched) > 0 {
+		if err := pgbackrest.Executor(exec).StanzaUpgrade(ctx, stanza); err != nil {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "StanzaMismatch"
+			condition.Message = fmt.Sprintf("pgBackRest stanza %q does not match PostgreSQL in %s",
+				stanza, strings.Join(mismatched, ", "))
+			r.Recorder.Event(postgresCluster, corev1.EventTypeWarning, EventUnableToRepairStanza,
+				condition.Message+": "+err.Error())
+			return reconcile.Result{}, err
+		}
+
+		r.Recorder.Eventf(postgresCluster, corev1.EventTypeNormal, EventStanzaRepaired,
+			"pgBackRest stanza %q upgraded to match PostgreSQL in %s",
+			stanza, strings.Join(mismatched, ", "))
+	}
+
+	return reconcile.Result{RequeueAfter: stanzaCheckInterval}, nil
+}
+
+// observeBackupMetrics exports the backups of cluster in each repository as
+// reported by the pgBackRest "info" command.
+func observeBackupMetrics(cluster *v1beta1.PostgresCluster, info []pgbackrest.StanzaRepoInfo) {
+	for _, repo := range info {
+		for backupType, stop := range repo.LastBackups {
+			pgbackrestLastBackupTimestamp.WithLabelValues(
+				cluster.Namespace, cluster.Name, repo.Repo, backupType).Set(float64(stop.Unix()))
+		}
+		pgbackrestRepoSizeBytes.WithLabelValues(
+			cluster.Namespace, cluster.Name, repo.Repo).Set(float64(repo.Size))
+	}
+}
+
+// forgetBackupMetrics removes the backup metrics of a PostgresCluster that no
+// longer exists or no longer has backups so they are no longer exported.
+func forgetBackupMetrics(namespace, name string) {
+	pgbackrestLastArchivedTimestamp.DeleteLabelValues(namespace, name)
+
+	for i := 1; i <= 4; i++ {
+		repo := "repo" + strconv.Itoa(i)
+		pgbackrestRepoSizeBytes.DeleteLabelValues(namespace, name, repo)
+		for _, backupType := range []string{"full", "diff", "incr"} {
+			pgbackrestLastBackupTimestamp.DeleteLabelValues(namespace, name, repo, backupType)
+		}
+	}
+}
+
+// observeBackupVerification records the newest full backup in each repository
+// as reported by the pgBackRest "info" command. A backup that was not seen
+// before is pending verification by reconcileBackupVerification.
+func observeBackupVerification(cluster *v1beta1.PostgresCluster, info []pgbackrest.StanzaRepoInfo) {
+	verify := cluster.Spec.Backups.PGBackRest.VerifyBeforeExpire
+	if verify == nil || !*verify {
+		return
+	}
+
+	for _, repo := range info {
+		for i := range cluster.Status.PGBackRest.Repos {
+			status := &cluster.Status.PGBackRest.Repos[i]
+			if status.Name != repo.Repo || repo.LastFullBackup == "" {
+				continue
+			}
+			if status.Verification == nil || status.Verification.Backup != repo.LastFullBackup {
+				status.Verification = &v1beta1.RepoVerificationStatus{
+					Backup: repo.LastFullBackup,
+					Result: "Pending",
+				}
+			}
+		}
+	}
+}
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;create;patch;delete
+
+// reconcileBackupVerification verifies the newest full backup in each repository of
+// postgresCluster when "verifyBeforeExpire" is enabled. Automatic expiry is disabled
+// in that case, so a Job runs "pgbackrest verify" on a pending backup and, when it
+// succeeds, "pgbackrest expire" applies the retention options of the repository.
+// When that Job fails, nothing in the repository expires until a newer full backup
+// passes verification.
+func (r *Reconciler) reconcileBackupVerification(ctx context.Context,
+	postgresCluster *v1beta1.PostgresCluster, serviceAccount *corev1.ServiceAccount) error {
+
+	status := postgresCluster.Status.PGBackRest
+	verify := postgresCluster.Spec.Backups.PGBackRest.VerifyBeforeExpire
+	if verify == nil || !*verify || status == nil {
+		if status != nil {
+			for i := range status.Repos {
+				status.Repos[i].Verification = nil
+			}
+		}
+		// TODO: remove guard with move to controller-runtime 0.9.0 https://issue.k8s.io/99714
+		if len(postgresCluster.Status.Conditions) > 0 {
+			meta.RemoveStatusCondition(&postgresCluster.Status.Conditions, ConditionBackupsVerified)
+		}
+		return nil
+	}
+
+	var invalid []string
+	for i := range status.Repos {
+		repoName := status.Repos[i].Name
+		verification := status.Repos[i].Verification
+		if verification == nil || verification.Result == "Valid" {
+			continue
+		}
+		if verification.Result == "Invalid" {
+			invalid = append(invalid, repoName)
+			continue
+		}
+
+		job := &batchv1.Job{ObjectMeta: naming.PGBackRestVerifyJob(postgresCluster, repoName)}
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(job), job); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return errors.WithStack(err)
+			}
+			job = nil
+		}
+
+		// Replace a Job that verified some other backup. The Job is created
+		// again once it is gone.
+		if job != nil && job.Annotations[naming.PGBackRestVerifyBackup] != verification.Backup {
+			if err := r.Client.Delete(ctx, job,
+				client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+				return errors.WithStack(client.IgnoreNotFound(err))
+			}
+			continue
+		}
+
+		switch {
+		case job == nil:
+			job, err := r.generateBackupVerifyJobIntent(postgresCluster, repoName,
+				verification.Backup, serviceAccount)
+			if err == nil {
+				err = errors.WithStack(r.apply(ctx, job))
+			}
+			if err != nil {
+				return err
+			}
+
+		case jobFailed(job):
+			verification.Result = "Invalid"
+			invalid = append(invalid, repoName)
+			r.Recorder.Eventf(postgresCluster, corev1.EventTypeWarning, EventBackupVerificationFailed,
+				"Backup %q in repository %q failed verification; older backups will not expire",
+				verification.Backup, repoName)
+
+		case jobCompleted(job):
+			if err := r.expireRepo(ctx, postgresCluster, repoName); err != nil {
+				return err
+			}
+			verification.Result = "Valid"
+			r.Recorder.Eventf(postgresCluster, corev1.EventTypeNormal, EventBackupVerified,
+				"Backup %q in repository %q passed verification; older backups expired",
+				verification.Backup, repoName)
+		}
+	}
+
+	condition := metav1.Condition{
+		ObservedGeneration: postgresCluster.GetGeneration(),
+		Type:               ConditionBackupsVerified,
+		Status:             metav1.ConditionTrue,
+		Reason:             "BackupsValid",
+		Message:            "The newest full backup in every repository passed verification",
+	}
+	if len(invalid) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "BackupInvalid"
+		condition.Message = fmt.Sprintf("Expiry is suspended in %s: the newest full backup "+
+			"failed verification", strings.Join(invalid, ", "))
+	}
+	meta.SetStatusCondition(&postgresCluster.Status.Conditions, condition)
+
+	return nil
+}
+
+// generateBackupVerifyJobIntent returns a Job that runs "pgbackrest verify" on
+// backup in the repository repoName.
+func (r *Reconciler) generateBackupVerifyJobIntent(postgresCluster *v1beta1.PostgresCluster,
+	repoName, backup string, serviceAccount *corev1.ServiceAccount) (*batchv1.Job, error) {
+
+	selector, containerName, err := getPGBackRestExecSelector(postgresCluster, repoName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// set the name of the pgbackrest config file that will be mounted to the Job
+	configName := pgbackrest.CMInstanceKey
+	if containerName == naming.PGBackRestRepoContainerName {
+		configName = pgbackrest.CMRepoKey
+	}
+
+	job := &batchv1.Job{ObjectMeta: naming.PGBackRestVerifyJob(postgresCluster, repoName)}
+	job.SetGroupVersionKind(batchv1.SchemeGroupVersion.WithKind("Job"))
+
+	job.Annotations = naming.Merge(
+		postgresCluster.Spec.Metadata.GetAnnotationsOrNil(),
+		postgresCluster.Spec.Backups.PGBackRest.Metadata.GetAnnotationsOrNil(),
+		map[string]string{
+			naming.PGBackRestVerifyBackup: backup,
+		})
+	job.Labels = naming.Merge(
+		postgresCluster.Spec.Metadata.GetLabelsOrNil(),
+		postgresCluster.Spec.Backups.PGBackRest.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster:          postgresCluster.Name,
+			naming.LabelPGBackRestVerify: repoName,
+		})
+
+	spec, err := generatePGBackRestJobSpecIntent(postgresCluster, "verify", selector.String(),
+		containerName, repoName, serviceAccount.GetName(), configName,
+		job.Labels, job.Annotations, "--set="+backup)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	job.Spec = *spec
+
+	err = errors.WithStack(controllerutil.SetControllerReference(postgresCluster, job,
+		r.Client.Scheme()))
+
+	return job, err
+}
+
+// expireRepo runs "pgbackrest expire" in the container that has access to the
+// repository repoName.
+func (r *Reconciler) expireRepo(ctx context.Context,
+	postgresCluster *v1beta1.PostgresCluster, repoName string) error {
+
+	selector, containerName, err := getPGBackRestExecSelector(postgresCluster, repoName)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.Client.List(ctx, pods,
+		client.InNamespace(postgresCluster.GetNamespace()),
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var podName string
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == corev1.PodRunning {
+			podName = pods.Items[i].Name
+			break
+		}
+	}
+	if podName == "" {
+		return errors.Errorf("no running Pod to expire backups in repository %q", repoName)
+	}
+
+	exec := func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer,
+		command ...string) error {
+		return r.PodExec(ctx, postgresCluster.GetNamespace(), podName, containerName,
+			stdin, stdout, stderr, command...)
+	}
+
+	return pgbackrest.Executor(exec).Expire(ctx, pgbackrest.StanzaName(postgresCluster), repoName)
+}
+
+// +kubebuilder:rbac:groups=postgres-operator.crunchydata.com,resources=postgresclusters,verbs=list
+
+// stanzaConflicts returns the namespace and name of other PostgresClusters that
+// write to a stanza of cluster in a shared cloud repository. Standby clusters
+// only read from their repository and are ignored.
+func (r *Reconciler) stanzaConflicts(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) ([]string, error) {
+	isStandby := func(c *v1beta1.PostgresCluster) bool {
+		return c.StandbyEnabled()
+	}
+
+	locations := sets.NewString(pgbackrest.StanzaLocations(cluster)...)
+	if locations.Len() == 0 || isStandby(cluster) {
+		return nil, nil
+	}
+
+	clusters := &v1beta1.PostgresClusterList{}
+	if err := errors.WithStack(r.Client.List(ctx, clusters)); err != nil {
+		return nil, err
+	}
+
+	var conflicts []string
+	for i := range clusters.Items {
+		other := &clusters.Items[i]
+		if other.UID == cluster.UID || isStandby(other) {
+			continue
+		}
+		if locations.HasAny(pgbackrest.StanzaLocations(other)...) {
+			conflicts = append(conflicts, other.Namespace+"/"+other.Name)
+		}
+	}
+
+	return conflicts, nil
+}
+
 // getPGBackRestExecSelector returns a selector and container name that allows the proper
 // Pod (along with a specific container within it) to be found within the Kubernetes
 // cluster as needed to exec into the container and run a pgBackRest command.
@@ -2617,16 +3979,26 @@ func (r *Reconciler) reconcilePGBackRestCronJob(
 		return errors.WithStack(err)
 	}
 
-	// Suspend cronjobs when shutdown or read-only. Any jobs that have already
-	// started will continue.
+	// Suspend cronjobs when shutdown, read-only, or being repaired. Any jobs that
+	// have already started will continue.
 	// - https://docs.k8s.io/reference/kubernetes-api/workload-resources/cron-job-v1beta1/#CronJobSpec
 	suspend := (cluster.Spec.Shutdown != nil && *cluster.Spec.Shutdown) ||
-		(cluster.Spec.Standby != nil && cluster.Spec.Standby.Enabled)
+		cluster.StandbyEnabled() ||
+		repairStopsCluster(cluster)
+
+	// CronJobs of this Kubernetes version interpret schedules in UTC, so convert
+	// from the time zone of the repo's schedules.
+	utcSchedule, err := cronScheduleInZone(*schedule, repo.BackupSchedules.TimeZone, time.Now())
+	if err != nil {
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, EventUnableToCreatePGBackRestCronJob,
+			err.Error())
+		return err
+	}
 
 	pgBackRestCronJob := &batchv1beta1.CronJob{
 		ObjectMeta: objectmeta,
 		Spec: batchv1beta1.CronJobSpec{
-			Schedule: *schedule,
+			Schedule: utcSchedule,
 			Suspend:  &suspend,
 			JobTemplate: batchv1beta1.JobTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
@@ -2650,7 +4022,7 @@ func (r *Reconciler) reconcilePGBackRestCronJob(
 	err = errors.WithStack(r.setControllerReference(cluster, pgBackRestCronJob))
 
 	if err == nil {
-		err = r.apply(ctx, pgBackRestCronJob)
+		err = r.applyCronJob(ctx, pgBackRestCronJob)
 	}
 	if err != nil {
 		// record and log any errors resulting from trying to create the pgBackRest backup CronJob
@@ -2660,3 +4032,113 @@ func (r *Reconciler) reconcilePGBackRestCronJob(
 	}
 	return err
 }
+
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={get}
+// +kubebuilder:rbac:groups="",resources="pods",verbs={list,patch}
+// +kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+
+// reconcilePGBackRestCredentials waits for every running Pod of cluster to
+// mount the current contents of the Secrets in its pgBackRest configuration,
+// such as the keys of cloud repositories after they are rotated. pgBackRest
+// reads these files every time it runs, so Pods do not need to restart. The
+// kubelet refreshes mounted files eventually; annotating a Pod prompts it to
+// do so sooner.
+func (r *Reconciler) reconcilePGBackRestCredentials(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (reconcile.Result, error) {
+	if cluster.Status.PGBackRest == nil {
+		return reconcile.Result{}, nil
+	}
+
+	var err error
+	secrets := make(map[string]*corev1.Secret)
+	for _, name := range pgbackrest.CredentialSecrets(cluster) {
+		secret := &corev1.Secret{}
+		if err == nil {
+			err = errors.WithStack(client.IgnoreNotFound(r.Client.Get(ctx,
+				client.ObjectKey{Namespace: cluster.Namespace, Name: name}, secret)))
+		}
+		if err == nil && secret.ResourceVersion != "" {
+			secrets[name] = secret
+		}
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	revision := pgbackrest.CredentialsRevision(cluster, secrets)
+	previous := cluster.Status.PGBackRest.CredentialsRevision
+	if previous == revision {
+		return reconcile.Result{}, nil
+	}
+
+	pods := &corev1.PodList{}
+	if revision != "" {
+		err = errors.WithStack(r.Client.List(ctx, pods,
+			client.InNamespace(cluster.Namespace),
+			client.MatchingLabels{naming.LabelCluster: cluster.Name}))
+	}
+
+	log := logging.FromContext(ctx)
+	pending := false
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+
+		// Look for a running container that mounts the configuration.
+		container := ""
+		for _, c := range pod.Spec.Containers {
+			for _, mount := range c.VolumeMounts {
+				if mount.Name == pgbackrest.ConfigVol && container == "" {
+					container = c.Name
+				}
+			}
+		}
+		running := false
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == container {
+				running = status.State.Running != nil
+			}
+		}
+		// Pods of Jobs, such as backups, start with the files that are current
+		// at the time and do not run for long.
+		if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "Job" {
+			continue
+		}
+		if err != nil || !running || pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		var stdout, stderr bytes.Buffer
+		err = errors.WithStack(r.PodExec(ctx, pod.Namespace, pod.Name, container,
+			nil, &stdout, &stderr,
+			pgbackrest.CredentialsCommand(cluster, secrets, revision)...))
+
+		if err == nil && strings.TrimSpace(stdout.String()) == "current" {
+			log.V(1).Info("pgBackRest credentials are current", "pod", pod.Name, "revision", revision)
+		} else if err == nil {
+			// The files in the Pod are not yet current.
+			pending = true
+			if pod.Annotations[naming.PGBackRestCredentialsRevision] != revision {
+				before := pod.DeepCopy()
+				if pod.Annotations == nil {
+					pod.Annotations = map[string]string{}
+				}
+				pod.Annotations[naming.PGBackRestCredentialsRevision] = revision
+				err = errors.WithStack(r.patch(ctx, pod, client.MergeFrom(before)))
+			}
+		}
+	}
+
+	if err == nil && pending {
+		return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+	if err == nil {
+		if previous != "" && revision != "" {
+			r.Recorder.Event(cluster, corev1.EventTypeNormal, "PGBackRestCredentialsUpdated",
+				"Every running pod has the current pgBackRest credentials")
+		}
+		cluster.Status.PGBackRest.CredentialsRevision = revision
+	}
+	return reconcile.Result{}, err
+}
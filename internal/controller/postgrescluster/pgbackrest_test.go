@@ -29,6 +29,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"go.opentelemetry.io/otel"
 	"gotest.tools/v3/assert"
 	appsv1 "k8s.io/api/apps/v1"
@@ -47,7 +48,10 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -768,12 +772,12 @@ func TestReconcileStanzaCreate(t *testing.T) {
 		},
 	}})
 
-	stanzaCreateFail := func(namespace, pod, container string, stdin io.Reader, stdout,
+	stanzaCreateFail := func(_ context.Context, namespace, pod, container string, stdin io.Reader, stdout,
 		stderr io.Writer, command ...string) error {
 		return errors.New("fake stanza create failed")
 	}
 
-	stanzaCreateSuccess := func(namespace, pod, container string, stdin io.Reader, stdout,
+	stanzaCreateSuccess := func(_ context.Context, namespace, pod, container string, stdin io.Reader, stdout,
 		stderr io.Writer, command ...string) error {
 		return nil
 	}
@@ -2202,7 +2206,7 @@ func TestReconcilePostgresClusterDataSource(t *testing.T) {
 				if tc.dataSource != nil {
 					pgclusterDataSource = tc.dataSource.PostgresCluster
 				}
-				err := r.reconcilePostgresClusterDataSource(ctx, cluster, pgclusterDataSource,
+				err := r.reconcilePostgresClusterDataSource(ctx, cluster, pgclusterDataSource, nil,
 					"testhash", nil)
 				assert.NilError(t, err)
 
@@ -2330,6 +2334,40 @@ func TestGenerateBackupJobIntent(t *testing.T) {
 		})
 	})
 
+	t.Run("Scheduling", func(t *testing.T) {
+		cluster := &v1beta1.PostgresCluster{}
+		cluster.Spec.Backups.PGBackRest.Jobs = &v1beta1.BackupJobs{
+			Affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+							MatchExpressions: []corev1.NodeSelectorRequirement{{
+								Key:      "key",
+								Operator: "Exist",
+							}},
+						}},
+					},
+				},
+			},
+			NodeSelector: map[string]string{"node.kubernetes.io/lifecycle": "spot"},
+			Tolerations: []corev1.Toleration{{
+				Key:      "spot",
+				Operator: "Exist",
+			}},
+		}
+		job, err := generateBackupJobSpecIntent(
+			cluster,
+			"", "", "", "", "",
+			nil, nil,
+		)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, job.Template.Spec.Affinity,
+			cluster.Spec.Backups.PGBackRest.Jobs.Affinity)
+		assert.DeepEqual(t, job.Template.Spec.NodeSelector,
+			map[string]string{"node.kubernetes.io/lifecycle": "spot"})
+		assert.DeepEqual(t, job.Template.Spec.Tolerations,
+			cluster.Spec.Backups.PGBackRest.Jobs.Tolerations)
+	})
 }
 
 func TestGenerateRepoHostIntent(t *testing.T) {
@@ -2399,6 +2437,7 @@ func TestGenerateRestoreJobIntent(t *testing.T) {
 				},
 			},
 		},
+		NodeSelector: map[string]string{"disktype": "ssd"},
 		Tolerations: []corev1.Toleration{{
 			Key:      "key",
 			Operator: "Exist",
@@ -2522,6 +2561,10 @@ func TestGenerateRestoreJobIntent(t *testing.T) {
 							assert.DeepEqual(t, job.Spec.Template.Spec.Affinity,
 								dataSource.Affinity)
 						})
+						t.Run("NodeSelector", func(t *testing.T) {
+							assert.DeepEqual(t, job.Spec.Template.Spec.NodeSelector,
+								map[string]string{"disktype": "ssd"})
+						})
 						t.Run("Tolerations", func(t *testing.T) {
 							assert.DeepEqual(t, job.Spec.Template.Spec.Tolerations,
 								dataSource.Tolerations)
@@ -2970,7 +3013,11 @@ func TestPrepareForRestore(t *testing.T) {
 				if tc.fakeObserved != nil {
 					fakeObserved = tc.fakeObserved
 				}
-				assert.NilError(t, r.prepareForRestore(ctx, cluster, fakeObserved, endpoints,
+				dcs := []client.Object{}
+				for i := range endpoints {
+					dcs = append(dcs, &endpoints[i])
+				}
+				assert.NilError(t, r.prepareForRestore(ctx, cluster, fakeObserved, dcs,
 					job, restoreID))
 
 				var primaryInstance *Instance
@@ -3362,3 +3409,1001 @@ func TestSetScheduledJobStatus(t *testing.T) {
 		assert.Assert(t, len(postgresCluster.Status.PGBackRest.ScheduledBackups) == 0)
 	})
 }
+
+func TestStanzaConflicts(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NilError(t, v1beta1.AddToScheme(scheme))
+
+	newCluster := func(namespace, name, stanza string) *v1beta1.PostgresCluster {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Namespace, cluster.Name = namespace, name
+		cluster.UID = types.UID(namespace + name)
+		cluster.Spec.Backups.PGBackRest.Stanza = stanza
+		cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{
+			Name: "repo1", S3: &v1beta1.RepoS3{Bucket: "shared", Endpoint: "s3"},
+		}}
+		return cluster
+	}
+
+	first := newCluster("ns1", "hippo", "")
+	second := newCluster("ns2", "hippo", "")
+	unique := newCluster("ns3", "rhino", "auto")
+	standby := newCluster("ns4", "hippo", "")
+	standby.Spec.Standby = &v1beta1.PostgresStandbySpec{Enabled: true, RepoName: "repo1"}
+
+	r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(first, second, unique, standby).Build()}
+
+	conflicts, err := r.stanzaConflicts(ctx, first)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, conflicts, []string{"ns2/hippo"})
+
+	conflicts, err = r.stanzaConflicts(ctx, unique)
+	assert.NilError(t, err)
+	assert.Assert(t, len(conflicts) == 0)
+
+	conflicts, err = r.stanzaConflicts(ctx, standby)
+	assert.NilError(t, err)
+	assert.Assert(t, len(conflicts) == 0, "expected standby to be ignored")
+}
+
+func TestReconcileStanzaHealth(t *testing.T) {
+	ctx := context.Background()
+
+	var commands [][]string
+	var output string
+	var failUpgrade bool
+	recorder := record.NewFakeRecorder(10)
+	r := &Reconciler{
+		Recorder: recorder,
+		PodExec: func(_ context.Context, namespace, pod, container string, stdin io.Reader, stdout,
+			stderr io.Writer, command ...string) error {
+			assert.Equal(t, pod, "hippo-00-abcd-0")
+			if command[0] == "psql" {
+				_, err := io.WriteString(stdout, "1633222900\n")
+				return err
+			}
+			commands = append(commands, command)
+			if command[1] == "stanza-upgrade" && failUpgrade {
+				return errors.New("boom")
+			}
+			_, err := io.WriteString(stdout, output)
+			return err
+		},
+	}
+
+	instances := &observedInstances{forCluster: []*Instance{{
+		Name: "hippo-00-abcd",
+		Pods: []*corev1.Pod{{ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"status": `{"role":"master"}`},
+		}}},
+	}}}
+
+	info := func(code int, systemID, version string) string {
+		return `[{"name":"db","db":[{"id":1,"repo-key":1,"system-id":` + systemID +
+			`,"version":"` + version + `"}],"repo":[{"key":1,"status":{"code":` +
+			strconv.Itoa(code) + `,"message":"x"}}]}]`
+	}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.PostgresVersion = 13
+	cluster.Status.Patroni = &v1beta1.PatroniStatus{SystemIdentifier: "12345"}
+	cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+		Repos: []v1beta1.RepoStatus{{Name: "repo1", StanzaCreated: true}},
+	}
+	t.Cleanup(func() { forgetBackupMetrics("ns1", "hippo") })
+
+	t.Run("Healthy", func(t *testing.T) {
+		output = info(0, "12345", "13")
+
+		result, err := r.reconcileStanzaHealth(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, stanzaCheckInterval)
+		assert.Equal(t, len(commands), 1)
+		assert.DeepEqual(t, commands[0], []string{"pgbackrest", "info", "--stanza=db", "--output=json"})
+		assert.Assert(t, meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionStanzasHealthy))
+		assert.Assert(t, cluster.Status.PGBackRest.StanzaCheckTime != nil)
+
+		// Repositories and archiving are exported as metrics.
+		assert.Equal(t, testutil.ToFloat64(
+			pgbackrestRepoSizeBytes.WithLabelValues("ns1", "hippo", "repo1")), float64(0))
+		assert.Equal(t, testutil.ToFloat64(
+			pgbackrestLastArchivedTimestamp.WithLabelValues("ns1", "hippo")), float64(1633222900))
+
+		// Healthy stanzas are not verified again until the interval passes.
+		result, err = r.reconcileStanzaHealth(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Assert(t, result.RequeueAfter > 0 && result.RequeueAfter <= stanzaCheckInterval)
+		assert.Equal(t, len(commands), 1)
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		cluster.Status.PGBackRest.StanzaCheckTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+		output = info(pgbackrest.StanzaCodeMissingPath, "0", "")
+
+		result, err := r.reconcileStanzaHealth(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Assert(t, result.Requeue)
+		assert.Equal(t, len(commands), 2)
+		assert.Assert(t, !cluster.Status.PGBackRest.Repos[0].StanzaCreated)
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionStanzasHealthy)
+		assert.Equal(t, condition.Status, metav1.ConditionFalse)
+		assert.Equal(t, condition.Reason, "StanzaMissing")
+		assert.Assert(t, strings.Contains(<-recorder.Events, EventStanzaRepaired))
+
+		// Nothing is verified until the stanza is created again.
+		_, err = r.reconcileStanzaHealth(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, len(commands), 2)
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		cluster.Status.PGBackRest.Repos[0].StanzaCreated = true
+		output = info(0, "12345", "12")
+
+		result, err := r.reconcileStanzaHealth(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, stanzaCheckInterval)
+		assert.Equal(t, len(commands), 4)
+		assert.DeepEqual(t, commands[3], []string{"pgbackrest", "stanza-upgrade", "--stanza=db"})
+		assert.Assert(t, meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionStanzasHealthy))
+		assert.Assert(t, strings.Contains(<-recorder.Events, EventStanzaRepaired))
+	})
+
+	t.Run("AnotherCluster", func(t *testing.T) {
+		cluster.Status.PGBackRest.StanzaCheckTime = nil
+		output = info(0, "99999", "12")
+		before := len(commands)
+
+		result, err := r.reconcileStanzaHealth(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+
+		// The stanza of another cluster is not upgraded.
+		assert.Equal(t, len(commands), before+1)
+		assert.Equal(t, commands[before][1], "info")
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionStanzasHealthy)
+		assert.Equal(t, condition.Status, metav1.ConditionFalse)
+		assert.Equal(t, condition.Reason, "StanzaConflict")
+		assert.Assert(t, strings.Contains(condition.Message, "another PostgreSQL cluster"))
+		assert.Assert(t, strings.Contains(<-recorder.Events, EventStanzaConflict))
+
+		// The event is not repeated while nothing changes.
+		_, err = r.reconcileStanzaHealth(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, len(commands), before+2)
+		assert.Equal(t, len(recorder.Events), 0)
+	})
+
+	t.Run("UpgradeFails", func(t *testing.T) {
+		cluster.Status.PGBackRest.StanzaCheckTime = nil
+		output, failUpgrade = info(0, "12345", "12"), true
+
+		_, err := r.reconcileStanzaHealth(ctx, cluster, instances)
+		assert.ErrorContains(t, err, "boom")
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionStanzasHealthy)
+		assert.Equal(t, condition.Status, metav1.ConditionFalse)
+		assert.Equal(t, condition.Reason, "StanzaMismatch")
+		assert.Assert(t, strings.Contains(<-recorder.Events, EventUnableToRepairStanza))
+	})
+
+	t.Run("Standby", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Standby = &v1beta1.PostgresStandbySpec{Enabled: true}
+		before := len(commands)
+
+		_, err := r.reconcileStanzaHealth(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, len(commands), before)
+	})
+
+	t.Run("Fenced", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Status.PGBackRest.StanzaCheckTime = nil
+		cluster.Spec.Backups.PGBackRest.Archive = &v1beta1.PGBackRestArchivePush{
+			Fenced: initialize.Bool(true),
+		}
+		before := len(commands)
+
+		_, err := r.reconcileStanzaHealth(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, len(commands), before)
+	})
+}
+
+func TestUpgradeStanza(t *testing.T) {
+	ctx := context.Background()
+
+	var commands [][]string
+	var output string
+	recorder := record.NewFakeRecorder(10)
+	r := &Reconciler{Recorder: recorder}
+	exec := func(_ context.Context, stdin io.Reader, stdout, stderr io.Writer,
+		command ...string) error {
+		commands = append(commands, command)
+		_, err := io.WriteString(stdout, output)
+		return err
+	}
+
+	info := func(code int, systemID, version string) string {
+		return `[{"name":"db","db":[{"id":1,"repo-key":1,"system-id":` + systemID +
+			`,"version":"` + version + `"}],"repo":[{"key":1,"status":{"code":` +
+			strconv.Itoa(code) + `,"message":"x"}}]}]`
+	}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.PostgresVersion = 14
+	cluster.Status.Patroni = &v1beta1.PatroniStatus{SystemIdentifier: "12345"}
+
+	for _, tt := range []struct {
+		name, output string
+		upgraded     bool
+	}{
+		{name: "Matches", output: info(0, "12345", "14")},
+		{name: "Missing", output: info(pgbackrest.StanzaCodeMissingData, "12345", "13")},
+		{name: "OtherDatabase", output: info(0, "99999", "13")},
+		{name: "OlderVersion", output: info(0, "12345", "13"), upgraded: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			commands, output = nil, tt.output
+
+			assert.Equal(t, r.upgradeStanza(ctx, cluster, exec), tt.upgraded)
+			if tt.upgraded {
+				assert.Equal(t, len(commands), 2)
+				assert.DeepEqual(t, commands[1], []string{"pgbackrest", "stanza-upgrade", "--stanza=ns1_hippo"})
+				assert.Assert(t, strings.Contains(<-recorder.Events, EventStanzaRepaired))
+			} else {
+				assert.Equal(t, len(commands), 1)
+			}
+		})
+	}
+}
+
+func TestRecordRestore(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NilError(t, corev1.AddToScheme(scheme))
+
+	job := &batchv1.Job{}
+	job.Namespace, job.Name = "ns1", "hippo-pgbackrest-restore"
+	job.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"controller-uid": "abc"}}
+	job.Status.StartTime = &metav1.Time{Time: time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)}
+	job.Status.CompletionTime = &metav1.Time{Time: time.Date(2021, 10, 1, 12, 5, 0, 0, time.UTC)}
+
+	pod := &corev1.Pod{}
+	pod.Namespace, pod.Name = "ns1", "hippo-pgbackrest-restore-xyz"
+	pod.Labels = map[string]string{"controller-uid": "abc"}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name: naming.PGBackRestRestoreContainerName,
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+			Message: "20211001-010000F\n",
+		}},
+	}}
+
+	r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Annotations = map[string]string{naming.PGBackRestRestore: "one"}
+	cluster.Spec.Backups.PGBackRest.Restore = &v1beta1.PGBackRestRestore{
+		Enabled: initialize.Bool(true),
+		PostgresClusterDataSource: &v1beta1.PostgresClusterDataSource{
+			RepoName: "repo1",
+			Options:  []string{"--type=time", `--target="2021-10-01 00:00:00+00"`},
+		},
+	}
+	cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+		Restore: &v1beta1.PGBackRestJobStatus{ID: "one"},
+	}
+
+	assert.NilError(t, r.recordRestore(ctx, cluster, job, true))
+	assert.Assert(t, marshalMatches(cluster.Status.PGBackRest.RestoreHistory, `
+- backupSet: 20211001-010000F
+  finishTime: "2021-10-01T12:05:00Z"
+  id: one
+  options:
+  - --type=time
+  - --target="2021-10-01 00:00:00+00"
+  repoName: repo1
+  startTime: "2021-10-01T12:00:00Z"
+  succeeded: true
+	`))
+
+	t.Run("Once", func(t *testing.T) {
+		assert.NilError(t, r.recordRestore(ctx, cluster, job, true))
+		assert.Equal(t, len(cluster.Status.PGBackRest.RestoreHistory), 1)
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Status.PGBackRest.Restore.ID = "two"
+
+		failed := job.DeepCopy()
+		failed.Status.CompletionTime = nil
+		failed.Status.Conditions = []batchv1.JobCondition{{
+			Type: batchv1.JobFailed, Status: corev1.ConditionTrue,
+			LastTransitionTime: metav1.Time{Time: time.Date(2021, 10, 1, 12, 1, 0, 0, time.UTC)},
+		}}
+
+		assert.NilError(t, r.recordRestore(ctx, cluster, failed, false))
+		record := cluster.Status.PGBackRest.RestoreHistory[1]
+		assert.Equal(t, record.ID, "two")
+		assert.Equal(t, record.Succeeded, false)
+		assert.Equal(t, record.BackupSet, "")
+		assert.Equal(t, record.FinishTime.Minute(), 1)
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		for i := 0; i < restoreHistoryLimit+2; i++ {
+			cluster.Status.PGBackRest.Restore.ID = fmt.Sprint(i)
+			assert.NilError(t, r.recordRestore(ctx, cluster, job, true))
+		}
+		history := cluster.Status.PGBackRest.RestoreHistory
+		assert.Equal(t, len(history), restoreHistoryLimit)
+		assert.Equal(t, history[len(history)-1].ID, fmt.Sprint(restoreHistoryLimit+1))
+	})
+}
+
+func TestObserveBackupMetrics(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "rhino"
+	t.Cleanup(func() { forgetBackupMetrics("ns1", "rhino") })
+
+	observeBackupMetrics(cluster, []pgbackrest.StanzaRepoInfo{{
+		Repo: "repo2",
+		LastBackups: map[string]time.Time{
+			"full": time.Unix(1633222900, 0),
+			"diff": time.Unix(1633309300, 0),
+		},
+		Size: 2120,
+	}})
+
+	assert.Equal(t, testutil.ToFloat64(
+		pgbackrestLastBackupTimestamp.WithLabelValues("ns1", "rhino", "repo2", "full")), float64(1633222900))
+	assert.Equal(t, testutil.ToFloat64(
+		pgbackrestLastBackupTimestamp.WithLabelValues("ns1", "rhino", "repo2", "diff")), float64(1633309300))
+	assert.Equal(t, testutil.ToFloat64(
+		pgbackrestRepoSizeBytes.WithLabelValues("ns1", "rhino", "repo2")), float64(2120))
+
+	forgetBackupMetrics("ns1", "rhino")
+	assert.Equal(t, testutil.CollectAndCount(pgbackrestRepoSizeBytes), 0)
+}
+
+func TestGenerateRepoVolumeIntentRetained(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+	r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.UID = "cluster-uid"
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{
+		Name: "repo1", Volume: &v1beta1.RepoPVC{},
+	}}
+
+	existing := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:      "hippo-repo1",
+		Namespace: "ns1",
+		Labels:    naming.PGBackRestRepoVolumeLabels("hippo", "repo1"),
+	}}
+	resources := &RepoResources{pvcs: []*corev1.PersistentVolumeClaim{existing}}
+
+	pvc, err := r.generateRepoVolumeIntent(cluster, &corev1.PersistentVolumeClaimSpec{}, "repo1", resources)
+	assert.NilError(t, err)
+	assert.Assert(t, metav1.IsControlledBy(pvc, cluster))
+
+	// A retained volume has no owner so that it outlives the cluster.
+	existing.Annotations = map[string]string{naming.Retain: "true"}
+	pvc, err = r.generateRepoVolumeIntent(cluster, &corev1.PersistentVolumeClaimSpec{}, "repo1", resources)
+	assert.NilError(t, err)
+	assert.Equal(t, pvc.Name, "hippo-repo1")
+	assert.Equal(t, len(pvc.OwnerReferences), 0)
+}
+
+func TestGenerateRepoVolumeIntentUnchangeable(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+	r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{
+		Name: "repo1", Volume: &v1beta1.RepoPVC{},
+	}}
+
+	existing := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:      "hippo-repo1",
+		Namespace: "ns1",
+		Labels:    naming.PGBackRestRepoVolumeLabels("hippo", "repo1"),
+	}}
+	existing.Spec.StorageClassName = initialize.String("slow")
+	existing.Spec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceStorage: resource.MustParse("2Gi"),
+	}
+	resources := &RepoResources{pvcs: []*corev1.PersistentVolumeClaim{existing}}
+
+	spec := &corev1.PersistentVolumeClaimSpec{StorageClassName: initialize.String("fast")}
+	spec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceStorage: resource.MustParse("1Gi"),
+	}
+
+	// The class and size of the existing volume are kept.
+	pvc, err := r.generateRepoVolumeIntent(cluster, spec, "repo1", resources)
+	assert.NilError(t, err)
+	assert.Equal(t, *pvc.Spec.StorageClassName, "slow")
+	assert.Equal(t, pvc.Spec.Resources.Requests.Storage().String(), "2Gi")
+
+	// The spec is not changed.
+	assert.Equal(t, *spec.StorageClassName, "fast")
+	assert.Equal(t, spec.Resources.Requests.Storage().String(), "1Gi")
+
+	// The volume can grow.
+	spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("3Gi")
+	pvc, err = r.generateRepoVolumeIntent(cluster, spec, "repo1", resources)
+	assert.NilError(t, err)
+	assert.Equal(t, pvc.Spec.Resources.Requests.Storage().String(), "3Gi")
+}
+
+func TestRepoVolumeOutdated(t *testing.T) {
+	repo := &v1beta1.RepoPVC{}
+	pvc := &corev1.PersistentVolumeClaim{}
+	pvc.Spec.StorageClassName = initialize.String("slow")
+	pvc.Spec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceStorage: resource.MustParse("2Gi"),
+	}
+
+	assert.Assert(t, !repoVolumeOutdated(repo, nil))
+	assert.Assert(t, !repoVolumeOutdated(repo, pvc))
+
+	repo.VolumeClaimSpec.StorageClassName = initialize.String("slow")
+	assert.Assert(t, !repoVolumeOutdated(repo, pvc))
+
+	repo.VolumeClaimSpec.StorageClassName = initialize.String("fast")
+	assert.Assert(t, repoVolumeOutdated(repo, pvc))
+
+	repo.VolumeClaimSpec.StorageClassName = nil
+	repo.VolumeClaimSpec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceStorage: resource.MustParse("3Gi"),
+	}
+	assert.Assert(t, !repoVolumeOutdated(repo, pvc), "expected to grow in place")
+
+	repo.VolumeClaimSpec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("1Gi")
+	assert.Assert(t, repoVolumeOutdated(repo, pvc))
+}
+
+func TestGenerateRepoRelocationIntent(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+	r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{
+		Name: "repo1", Volume: &v1beta1.RepoPVC{},
+	}}
+	cluster.Spec.Backups.PGBackRest.Repos[0].Volume.VolumeClaimSpec.StorageClassName =
+		initialize.String("fast")
+	repo := &cluster.Spec.Backups.PGBackRest.Repos[0]
+
+	current := &corev1.PersistentVolumeClaim{}
+	current.Name = "hippo-repo1"
+
+	t.Run("Volume", func(t *testing.T) {
+		pvc, err := r.generateRepoVolumeRelocationIntent(cluster, repo, current, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, pvc.Name, "hippo-repo1-relocated")
+		assert.Equal(t, *pvc.Spec.StorageClassName, "fast")
+		assert.Assert(t, metav1.IsControlledBy(pvc, cluster))
+
+		_, relocation := pvc.Labels[naming.LabelPGBackRestRelocation]
+		assert.Assert(t, relocation)
+		assert.Equal(t, pvc.Labels[naming.LabelPGBackRestRepo], "repo1")
+
+		// The name alternates so a volume can be relocated again.
+		pvc, err = r.generateRepoVolumeRelocationIntent(cluster, repo, pvc, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, pvc.Name, "hippo-repo1")
+
+		// The name of an existing target is kept.
+		target := &corev1.PersistentVolumeClaim{}
+		target.Name = "some-other"
+		pvc, err = r.generateRepoVolumeRelocationIntent(cluster, repo, current, target)
+		assert.NilError(t, err)
+		assert.Equal(t, pvc.Name, "some-other")
+	})
+
+	t.Run("Job", func(t *testing.T) {
+		target := &corev1.PersistentVolumeClaim{}
+		target.Name = "hippo-repo1-relocated"
+
+		job, err := r.generateRepoRelocationJobIntent(cluster, "repo1", current, target)
+		assert.NilError(t, err)
+		assert.Equal(t, job.Name, "hippo-repo1-relocate")
+		assert.Assert(t, metav1.IsControlledBy(job, cluster))
+		assert.Equal(t, job.Labels[naming.LabelPGBackRestRelocation], "repo1")
+
+		// The Job is not one of the pgBackRest resources that are cleaned up.
+		assert.Assert(t, !naming.PGBackRestSelector("hippo").Matches(labels.Set(job.Labels)))
+
+		spec := job.Spec.Template.Spec
+		assert.Equal(t, spec.RestartPolicy, corev1.RestartPolicyNever)
+		assert.Equal(t, spec.Volumes[0].PersistentVolumeClaim.ClaimName, "hippo-repo1")
+		assert.Assert(t, spec.Volumes[0].PersistentVolumeClaim.ReadOnly)
+		assert.Equal(t, spec.Volumes[1].PersistentVolumeClaim.ClaimName, "hippo-repo1-relocated")
+		assert.Assert(t, strings.Contains(spec.Containers[0].Command[3],
+			"/pgbackrest/source/. /pgbackrest/target/"))
+	})
+}
+
+func TestRelocateRepoVolumesCleanup(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{
+		Name: "repo1", Volume: &v1beta1.RepoPVC{},
+	}}
+	cluster.Spec.Backups.PGBackRest.Repos[0].Volume.VolumeClaimSpec.StorageClassName =
+		initialize.String("fast")
+
+	current := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:      "hippo-repo1",
+		Namespace: "ns1",
+		Labels:    naming.PGBackRestRepoVolumeLabels("hippo", "repo1"),
+	}}
+	current.Spec.StorageClassName = initialize.String("fast")
+
+	target := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:      "hippo-repo1-relocated",
+		Namespace: "ns1",
+		Labels: labels.Merge(naming.PGBackRestRepoVolumeLabels("hippo", "repo1"),
+			map[string]string{naming.LabelPGBackRestRelocation: ""}),
+	}}
+	target.Spec.StorageClassName = initialize.String("slow")
+
+	job := &batchv1.Job{ObjectMeta: naming.PGBackRestRepoRelocationJob(cluster, "repo1")}
+
+	r := &Reconciler{
+		Client: fake.NewClientBuilder().WithScheme(testScheme).
+			WithObjects(current, target, job).Build(),
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	// The current volume already has the class in the spec, so the relocation
+	// is no longer wanted.
+	resources := &RepoResources{pvcs: []*corev1.PersistentVolumeClaim{current, target}}
+	assert.NilError(t, r.relocateRepoVolumes(ctx, cluster, resources))
+	assert.Assert(t, !resources.relocating)
+
+	err := r.Client.Get(ctx, client.ObjectKeyFromObject(target), target)
+	assert.Assert(t, apierrors.IsNotFound(err), "expected NotFound, got %v", err)
+	err = r.Client.Get(ctx, client.ObjectKeyFromObject(job), job)
+	assert.Assert(t, apierrors.IsNotFound(err), "expected NotFound, got %v", err)
+	assert.NilError(t, r.Client.Get(ctx, client.ObjectKeyFromObject(current), current))
+}
+
+func TestGenerateStandbyRepoHostIntent(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+	r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.Backups.PGBackRest.RepoHost = &v1beta1.PGBackRestRepoHost{
+		Standby: &v1beta1.PGBackRestStandbyRepoHost{IntervalSeconds: initialize.Int32(90)},
+	}
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{
+		{Name: "repo1", Volume: &v1beta1.RepoPVC{}},
+		{Name: "repo2", S3: &v1beta1.RepoS3{}},
+		{Name: "repo3", Volume: &v1beta1.RepoPVC{}},
+	}
+
+	t.Run("Volume", func(t *testing.T) {
+		spec := &cluster.Spec.Backups.PGBackRest.Repos[0].Volume.VolumeClaimSpec
+		spec.StorageClassName = initialize.String("fast")
+
+		pvc, err := r.generateStandbyRepoVolumeIntent(cluster,
+			cluster.Spec.Backups.PGBackRest.Repos[0], &RepoResources{})
+		assert.NilError(t, err)
+		assert.Equal(t, pvc.Name, "hippo-repo1-standby")
+		assert.Equal(t, *pvc.Spec.StorageClassName, "fast")
+		assert.Equal(t, pvc.Labels[naming.LabelPGBackRestStandby], "repo1")
+		assert.Assert(t, metav1.IsControlledBy(pvc, cluster))
+
+		// The volume is not mistaken for the repository itself.
+		assert.Assert(t, !naming.PGBackRestRepoVolumeLabels("hippo", "repo1").AsSelector().
+			Matches(labels.Set(pvc.Labels)))
+
+		// The storage class of an existing volume is kept.
+		existing := pvc.DeepCopy()
+		existing.Spec.StorageClassName = initialize.String("slow")
+		pvc, err = r.generateStandbyRepoVolumeIntent(cluster,
+			cluster.Spec.Backups.PGBackRest.Repos[0],
+			&RepoResources{standbyPVCs: []*corev1.PersistentVolumeClaim{existing}})
+		assert.NilError(t, err)
+		assert.Equal(t, *pvc.Spec.StorageClassName, "slow")
+	})
+
+	t.Run("StatefulSet", func(t *testing.T) {
+		sts, err := r.generateStandbyRepoHostIntent(ctx, cluster, "hippo-repo-host",
+			&RepoResources{})
+		assert.NilError(t, err)
+		assert.Equal(t, sts.Name, "hippo-repo-host-standby")
+		assert.Assert(t, metav1.IsControlledBy(sts, cluster))
+
+		// The standby is not selected as the repository host.
+		dedicated := naming.PGBackRestDedicatedSelector("hippo")
+		assert.Assert(t, !dedicated.Matches(labels.Set(sts.Labels)))
+		assert.Assert(t, !dedicated.Matches(labels.Set(sts.Spec.Template.Labels)))
+		assert.DeepEqual(t, sts.Spec.Selector.MatchLabels,
+			map[string]string(naming.PGBackRestStandbyLabels("hippo")))
+
+		// It mounts its own copy of each repository volume.
+		claims := []string{}
+		for _, volume := range sts.Spec.Template.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				claims = append(claims, volume.PersistentVolumeClaim.ClaimName)
+			}
+		}
+		assert.DeepEqual(t, claims, []string{"hippo-repo1-standby", "hippo-repo3-standby"})
+
+		// It prefers another zone than the repository host.
+		terms := sts.Spec.Template.Spec.Affinity.PodAntiAffinity.
+			PreferredDuringSchedulingIgnoredDuringExecution
+		assert.Equal(t, len(terms), 1)
+		assert.Equal(t, terms[0].PodAffinityTerm.TopologyKey, "topology.kubernetes.io/zone")
+		assert.DeepEqual(t, terms[0].PodAffinityTerm.LabelSelector.MatchLabels,
+			map[string]string(naming.PGBackRestDedicatedLabels("hippo")))
+
+		var sync *corev1.Container
+		for i := range sts.Spec.Template.Spec.Containers {
+			if sts.Spec.Template.Spec.Containers[i].Name == naming.ContainerPGBackRestStandbySync {
+				sync = &sts.Spec.Template.Spec.Containers[i]
+			}
+		}
+		assert.Assert(t, sync != nil)
+		assert.DeepEqual(t, sync.Command[4:6], []string{"repo-sync", "90"})
+		assert.Assert(t, strings.HasPrefix(sync.Command[6], "hippo-repo-host-0.hippo-pods.ns1.svc."),
+			"got %q", sync.Command[6])
+		assert.DeepEqual(t, sync.Command[7:], []string{"repo1", "repo3"})
+		assert.Assert(t, len(sync.VolumeMounts) > 0)
+	})
+}
+
+func TestCleanupRepoResourcesStandby(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.Backups.PGBackRest.RepoHost = &v1beta1.PGBackRestRepoHost{
+		Standby: &v1beta1.PGBackRestStandbyRepoHost{},
+	}
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{
+		{Name: "repo1", Volume: &v1beta1.RepoPVC{}},
+	}
+
+	object := func(kind, name string, labels map[string]string) unstructured.Unstructured {
+		u := unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind(kind)
+		u.SetNamespace("ns1")
+		u.SetName(name)
+		u.SetLabels(labels)
+		return u
+	}
+
+	host := object("ConfigMap", "host", naming.PGBackRestStandbyLabels("hippo"))
+	repo1 := object("ConfigMap", "repo1", naming.PGBackRestStandbyVolumeLabels("hippo", "repo1"))
+	repo2 := object("ConfigMap", "repo2", naming.PGBackRestStandbyVolumeLabels("hippo", "repo2"))
+
+	r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).
+		WithRuntimeObjects(host.DeepCopy(), repo1.DeepCopy(), repo2.DeepCopy()).Build()}
+
+	// The copy of a repository that no longer exists is deleted.
+	kept, err := r.cleanupRepoResources(ctx, cluster,
+		[]unstructured.Unstructured{host, repo1, repo2})
+	assert.NilError(t, err)
+	assert.Equal(t, len(kept), 2)
+	assert.Equal(t, kept[0].GetName(), "host")
+	assert.Equal(t, kept[1].GetName(), "repo1")
+
+	// Everything is deleted once the standby is disabled.
+	cluster.Spec.Backups.PGBackRest.RepoHost.Standby = nil
+	kept, err = r.cleanupRepoResources(ctx, cluster, []unstructured.Unstructured{host, repo1})
+	assert.NilError(t, err)
+	assert.Equal(t, len(kept), 0)
+}
+
+func TestObserveBackupVerification(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+		Repos: []v1beta1.RepoStatus{{Name: "repo1"}, {Name: "repo2"}},
+	}
+	info := []pgbackrest.StanzaRepoInfo{
+		{Repo: "repo1", LastFullBackup: "20211003-010000F"},
+		{Repo: "repo2"},
+	}
+
+	// Nothing is recorded unless enabled.
+	observeBackupVerification(cluster, info)
+	assert.Assert(t, cluster.Status.PGBackRest.Repos[0].Verification == nil)
+
+	cluster.Spec.Backups.PGBackRest.VerifyBeforeExpire = initialize.Bool(true)
+	observeBackupVerification(cluster, info)
+	assert.DeepEqual(t, cluster.Status.PGBackRest.Repos[0].Verification,
+		&v1beta1.RepoVerificationStatus{Backup: "20211003-010000F", Result: "Pending"})
+	assert.Assert(t, cluster.Status.PGBackRest.Repos[1].Verification == nil)
+
+	// The result of the same backup is kept.
+	cluster.Status.PGBackRest.Repos[0].Verification.Result = "Invalid"
+	observeBackupVerification(cluster, info)
+	assert.Equal(t, cluster.Status.PGBackRest.Repos[0].Verification.Result, "Invalid")
+
+	// A newer backup is verified again.
+	info[0].LastFullBackup = "20211004-010000F"
+	observeBackupVerification(cluster, info)
+	assert.DeepEqual(t, cluster.Status.PGBackRest.Repos[0].Verification,
+		&v1beta1.RepoVerificationStatus{Backup: "20211004-010000F", Result: "Pending"})
+}
+
+func TestReconcileBackupVerification(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	newCluster := func() *v1beta1.PostgresCluster {
+		cluster := &v1beta1.PostgresCluster{}
+		cluster.Namespace, cluster.Name = "ns1", "hippo"
+		cluster.Spec.Backups.PGBackRest.VerifyBeforeExpire = initialize.Bool(true)
+		cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{
+			Name: "repo1", S3: &v1beta1.RepoS3{Bucket: "b", Endpoint: "e", Region: "r"},
+		}}
+		cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+			Repos: []v1beta1.RepoStatus{{
+				Name: "repo1",
+				Verification: &v1beta1.RepoVerificationStatus{
+					Backup: "20211003-010000F", Result: "Pending",
+				},
+			}},
+		}
+		return cluster
+	}
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "hippo-pgbackrest"}}
+
+	t.Run("Disabled", func(t *testing.T) {
+		cluster := newCluster()
+		cluster.Spec.Backups.PGBackRest.VerifyBeforeExpire = nil
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			Type: ConditionBackupsVerified, Status: metav1.ConditionTrue, Reason: "BackupsValid",
+		})
+
+		r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+		assert.NilError(t, r.reconcileBackupVerification(ctx, cluster, sa))
+		assert.Assert(t, cluster.Status.PGBackRest.Repos[0].Verification == nil)
+		assert.Assert(t, meta.FindStatusCondition(cluster.Status.Conditions,
+			ConditionBackupsVerified) == nil)
+	})
+
+	t.Run("DisabledWithoutConditions", func(t *testing.T) {
+		cluster := newCluster()
+		cluster.Spec.Backups.PGBackRest.VerifyBeforeExpire = nil
+		cluster.Status.PGBackRest = nil
+		assert.Assert(t, cluster.Status.Conditions == nil)
+
+		r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+		assert.NilError(t, r.reconcileBackupVerification(ctx, cluster, sa))
+		assert.Assert(t, cluster.Status.Conditions == nil)
+	})
+
+	t.Run("Pending", func(t *testing.T) {
+		cluster := newCluster()
+		r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+
+		job, err := r.generateBackupVerifyJobIntent(cluster, "repo1", "20211003-010000F", sa)
+		assert.NilError(t, err)
+		assert.Equal(t, job.Name, "hippo-repo1-verify")
+		assert.Assert(t, metav1.IsControlledBy(job, cluster))
+		assert.Equal(t, job.Labels[naming.LabelPGBackRestVerify], "repo1")
+		assert.Equal(t, job.Annotations[naming.PGBackRestVerifyBackup], "20211003-010000F")
+
+		// The Job is not one of the pgBackRest resources that are cleaned up.
+		assert.Assert(t, !naming.PGBackRestSelector("hippo").Matches(labels.Set(job.Labels)))
+
+		env := job.Spec.Template.Spec.Containers[0].Env
+		assert.DeepEqual(t, env[0], corev1.EnvVar{Name: "COMMAND", Value: "verify"})
+		assert.Assert(t, strings.Contains(env[1].Value, "--set=20211003-010000F"), env[1].Value)
+		assert.Equal(t, job.Spec.Template.Spec.ServiceAccountName, "hippo-pgbackrest")
+	})
+
+	t.Run("Outdated", func(t *testing.T) {
+		cluster := newCluster()
+		job := &batchv1.Job{ObjectMeta: naming.PGBackRestVerifyJob(cluster, "repo1")}
+		job.Annotations = map[string]string{naming.PGBackRestVerifyBackup: "20211002-010000F"}
+
+		r := &Reconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(job).Build(),
+		}
+		assert.NilError(t, r.reconcileBackupVerification(ctx, cluster, sa))
+
+		err := r.Client.Get(ctx, client.ObjectKeyFromObject(job), job)
+		assert.Assert(t, apierrors.IsNotFound(err), "expected NotFound, got %v", err)
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		cluster := newCluster()
+		job := &batchv1.Job{ObjectMeta: naming.PGBackRestVerifyJob(cluster, "repo1")}
+		job.Annotations = map[string]string{naming.PGBackRestVerifyBackup: "20211003-010000F"}
+		job.Status.Conditions = []batchv1.JobCondition{{
+			Type: batchv1.JobFailed, Status: corev1.ConditionTrue,
+		}}
+
+		recorder := record.NewFakeRecorder(1)
+		r := &Reconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(job).Build(),
+			Recorder: recorder,
+			PodExec: func(context.Context, string, string, string, io.Reader, io.Writer, io.Writer, ...string) error {
+				t.Fatal("expected no expire")
+				return nil
+			},
+		}
+		assert.NilError(t, r.reconcileBackupVerification(ctx, cluster, sa))
+		assert.Equal(t, cluster.Status.PGBackRest.Repos[0].Verification.Result, "Invalid")
+		assert.Assert(t, strings.Contains(<-recorder.Events, EventBackupVerificationFailed))
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionBackupsVerified)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionFalse)
+		assert.Equal(t, condition.Reason, "BackupInvalid")
+		assert.Assert(t, strings.Contains(condition.Message, "repo1"))
+	})
+
+	t.Run("Completed", func(t *testing.T) {
+		cluster := newCluster()
+		job := &batchv1.Job{ObjectMeta: naming.PGBackRestVerifyJob(cluster, "repo1")}
+		job.Annotations = map[string]string{naming.PGBackRestVerifyBackup: "20211003-010000F"}
+		job.Status.Conditions = []batchv1.JobCondition{{
+			Type: batchv1.JobComplete, Status: corev1.ConditionTrue,
+		}}
+
+		primary := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns1", Name: "hippo-instance-abcd-0",
+			Labels: map[string]string{
+				naming.LabelCluster:  "hippo",
+				naming.LabelInstance: "hippo-instance-abcd",
+				naming.LabelRole:     naming.RolePatroniLeader,
+			},
+		}}
+		primary.Status.Phase = corev1.PodRunning
+
+		var pod, container string
+		var command []string
+		r := &Reconciler{
+			Client: fake.NewClientBuilder().WithScheme(testScheme).
+				WithObjects(job, primary).Build(),
+			Recorder: record.NewFakeRecorder(1),
+			PodExec: func(_ context.Context, _, p, c string, _ io.Reader, _, _ io.Writer, cmd ...string) error {
+				pod, container, command = p, c, cmd
+				return nil
+			},
+		}
+		assert.NilError(t, r.reconcileBackupVerification(ctx, cluster, sa))
+		assert.Equal(t, pod, "hippo-instance-abcd-0")
+		assert.Equal(t, container, naming.ContainerDatabase)
+		assert.DeepEqual(t, command, []string{"pgbackrest", "expire", "--stanza=db", "--repo=1"})
+		assert.Equal(t, cluster.Status.PGBackRest.Repos[0].Verification.Result, "Valid")
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionBackupsVerified)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionTrue)
+	})
+}
+
+func TestReconcilePGBackRestCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.Backups.PGBackRest.Configuration = []corev1.VolumeProjection{{
+		Secret: &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "s3-keys"},
+		},
+	}}
+	cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{}
+
+	secret := &corev1.Secret{}
+	secret.Namespace, secret.Name = "ns1", "s3-keys"
+	secret.Data = map[string][]byte{"s3.conf": []byte("one")}
+
+	pod := &corev1.Pod{}
+	pod.Namespace, pod.Name = "ns1", "hippo-repo-host-0"
+	pod.Labels = map[string]string{naming.LabelCluster: "hippo"}
+	pod.Spec.Containers = []corev1.Container{{
+		Name:         naming.PGBackRestRepoContainerName,
+		VolumeMounts: []corev1.VolumeMount{{Name: pgbackrest.ConfigVol}},
+	}}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  naming.PGBackRestRepoContainerName,
+		State: corev1.ContainerState{Running: new(corev1.ContainerStateRunning)},
+	}}
+
+	revision := func() string {
+		return pgbackrest.CredentialsRevision(cluster,
+			map[string]*corev1.Secret{secret.Name: secret})
+	}
+
+	stdout := ""
+	var calls int
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &Reconciler{
+		Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(secret, pod).Build(),
+		Recorder: recorder,
+		PodExec: func(_ context.Context, namespace, pod, container string, _ io.Reader, out, _ io.Writer, command ...string) error {
+			calls++
+			assert.Equal(t, container, naming.PGBackRestRepoContainerName)
+			assert.Equal(t, command[len(command)-1], revision())
+			_, err := io.WriteString(out, stdout)
+			return err
+		},
+	}
+
+	// The Pod started with the current files.
+	stdout = "current\n"
+	result, err := reconciler.reconcilePGBackRestCredentials(ctx, cluster)
+	assert.NilError(t, err)
+	assert.Equal(t, result, reconcile.Result{})
+	assert.Equal(t, cluster.Status.PGBackRest.CredentialsRevision, revision())
+	assert.Equal(t, calls, 1)
+	assert.Equal(t, len(recorder.Events), 0)
+
+	// Nothing happens until the Secret changes.
+	_, err = reconciler.reconcilePGBackRestCredentials(ctx, cluster)
+	assert.NilError(t, err)
+	assert.Equal(t, calls, 1)
+
+	// The keys are rotated, but the files in the Pod are not yet current.
+	secret.Data["s3.conf"] = []byte("two")
+	assert.NilError(t, reconciler.Client.Update(ctx, secret))
+
+	stdout = ""
+	result, err = reconciler.reconcilePGBackRestCredentials(ctx, cluster)
+	assert.NilError(t, err)
+	assert.Assert(t, result.RequeueAfter > 0)
+	assert.Assert(t, cluster.Status.PGBackRest.CredentialsRevision != revision())
+	assert.Equal(t, calls, 2)
+
+	stored := &corev1.Pod{}
+	assert.NilError(t, reconciler.Client.Get(ctx, client.ObjectKeyFromObject(pod), stored))
+	assert.Equal(t, stored.Annotations[naming.PGBackRestCredentialsRevision], revision())
+
+	// The kubelet refreshes the files.
+	stdout = "current\n"
+	result, err = reconciler.reconcilePGBackRestCredentials(ctx, cluster)
+	assert.NilError(t, err)
+	assert.Equal(t, result, reconcile.Result{})
+	assert.Equal(t, cluster.Status.PGBackRest.CredentialsRevision, revision())
+	assert.Equal(t, calls, 3)
+	assert.Assert(t, strings.Contains(<-recorder.Events, "PGBackRestCredentialsUpdated"))
+}
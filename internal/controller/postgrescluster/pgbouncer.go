@@ -16,9 +16,12 @@
 package postgrescluster
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
@@ -27,7 +30,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/crunchydata/postgres-operator/internal/config"
 	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/logging"
 	"github.com/crunchydata/postgres-operator/internal/naming"
@@ -42,13 +47,17 @@ func (r *Reconciler) reconcilePGBouncer(
 	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
 	primaryCertificate *corev1.SecretProjection,
 	root *pki.RootCertificateAuthority,
-) error {
+) (reconcile.Result, error) {
 	var (
 		configmap *corev1.ConfigMap
+		result    reconcile.Result
 		secret    *corev1.Secret
 	)
 
 	service, err := r.reconcilePGBouncerService(ctx, cluster)
+	if err == nil {
+		err = r.reconcilePGBouncerPoolServices(ctx, cluster)
+	}
 	if err == nil {
 		configmap, err = r.reconcilePGBouncerConfigMap(ctx, cluster)
 	}
@@ -61,7 +70,10 @@ func (r *Reconciler) reconcilePGBouncer(
 	if err == nil {
 		err = r.reconcilePGBouncerInPostgreSQL(ctx, cluster, instances, secret)
 	}
-	return err
+	if err == nil {
+		result, err = r.reconcilePGBouncerReload(ctx, cluster, configmap, secret)
+	}
+	return result, err
 }
 
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
@@ -101,6 +113,10 @@ func (r *Reconciler) reconcilePGBouncerConfigMap(
 	if err == nil {
 		pgbouncer.ConfigMap(cluster, configmap)
 	}
+	if err == nil && logShipperEnabled(cluster) && pgbouncer.LogFileEnabled(cluster) {
+		addLogShipperToConfigMap(cluster, pgbouncer.LogVolumeMount(), configmap,
+			logShipperInput{Component: "pgbouncer", Path: pgbouncer.LogFilePattern()})
+	}
 	if err == nil {
 		err = errors.WithStack(r.apply(ctx, configmap))
 	}
@@ -177,8 +193,8 @@ func (r *Reconciler) reconcilePGBouncerInPostgreSQL(
 
 	if err == nil {
 		ctx := logging.NewContext(ctx, logging.FromContext(ctx).WithValues("revision", revision))
-		err = action(ctx, func(_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
-			return r.PodExec(pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+		err = action(ctx, func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+			return r.PodExec(ctx, pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
 		})
 	}
 	if err == nil {
@@ -313,12 +329,122 @@ func (r *Reconciler) reconcilePGBouncerService(
 		return nil, client.IgnoreNotFound(err)
 	}
 
+	// After a blue/green switch, select the PgBouncer of the cluster that
+	// replaced this one, when it has one.
+	if err == nil {
+		var green *v1beta1.PostgresCluster
+		if green, err = r.blueGreenReplacement(ctx, cluster); err == nil && green != nil &&
+			green.Spec.Proxy != nil && green.Spec.Proxy.PGBouncer != nil {
+			service.Spec.Selector = map[string]string{
+				naming.LabelCluster: green.Name,
+				naming.LabelRole:    naming.RolePGBouncer,
+			}
+		}
+	}
+
 	if err == nil {
 		err = errors.WithStack(r.apply(ctx, service))
 	}
 	return service, err
 }
 
+// generatePGBouncerPoolService returns a v1.Service that exposes pool of the
+// PgBouncer pods.
+func (r *Reconciler) generatePGBouncerPoolService(
+	cluster *v1beta1.PostgresCluster, pool v1beta1.PGBouncerPoolSpec,
+) (*corev1.Service, error) {
+	service := &corev1.Service{ObjectMeta: naming.ClusterPGBouncerPool(cluster, pool.Name)}
+	service.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Service"))
+
+	service.Annotations = naming.Merge(
+		cluster.Spec.Metadata.GetAnnotationsOrNil(),
+		cluster.Spec.Proxy.PGBouncer.Metadata.GetAnnotationsOrNil())
+	service.Labels = naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		cluster.Spec.Proxy.PGBouncer.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster:       cluster.Name,
+			naming.LabelRole:          naming.RolePGBouncer,
+			naming.LabelPGBouncerPool: pool.Name,
+		})
+
+	// Select the same Pods as the PgBouncer Service.
+	service.Spec.Selector = map[string]string{
+		naming.LabelCluster: cluster.Name,
+		naming.LabelRole:    naming.RolePGBouncer,
+	}
+	if spec := pool.Service; spec != nil {
+		service.Spec.Type = corev1.ServiceType(spec.Type)
+	} else {
+		service.Spec.Type = corev1.ServiceTypeClusterIP
+	}
+
+	// The ContainerPort of a pool has no name, so the TargetPort is its number.
+	service.Spec.Ports = []corev1.ServicePort{{
+		Name:       naming.PortPGBouncer,
+		Port:       pool.Port,
+		Protocol:   corev1.ProtocolTCP,
+		TargetPort: intstr.FromInt(int(pool.Port)),
+	}}
+
+	err := errors.WithStack(r.setControllerReference(cluster, service))
+
+	return service, err
+}
+
+// +kubebuilder:rbac:groups="",resources="services",verbs={list}
+// +kubebuilder:rbac:groups="",resources="services",verbs={create,delete,patch}
+
+// reconcilePGBouncerPoolServices writes a Service for each PgBouncer pool and
+// deletes those of pools that are no longer specified.
+func (r *Reconciler) reconcilePGBouncerPoolServices(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) error {
+	var pools []v1beta1.PGBouncerPoolSpec
+	if cluster.Spec.Proxy != nil && cluster.Spec.Proxy.PGBouncer != nil {
+		pools = cluster.Spec.Proxy.PGBouncer.Pools
+	}
+
+	existing := &corev1.ServiceList{}
+	selector, err := naming.AsSelector(metav1.LabelSelector{
+		MatchLabels: map[string]string{naming.LabelCluster: cluster.Name},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: naming.LabelPGBouncerPool, Operator: metav1.LabelSelectorOpExists},
+		},
+	})
+	if err == nil {
+		err = errors.WithStack(
+			r.Client.List(ctx, existing,
+				client.InNamespace(cluster.Namespace),
+				client.MatchingLabelsSelector{Selector: selector},
+			))
+	}
+
+	specified := make(map[string]bool, len(pools))
+	for i := range pools {
+		specified[pools[i].Name] = true
+	}
+	for i := range existing.Items {
+		if service := &existing.Items[i]; err == nil &&
+			!specified[service.Labels[naming.LabelPGBouncerPool]] {
+			err = errors.WithStack(client.IgnoreNotFound(
+				r.deleteControlled(ctx, cluster, service)))
+		}
+	}
+
+	for i := range pools {
+		var service *corev1.Service
+		if err == nil {
+			service, err = r.generatePGBouncerPoolService(cluster, pools[i])
+		}
+		if err == nil {
+			err = errors.WithStack(r.apply(ctx, service))
+		}
+	}
+
+	return err
+}
+
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=create;delete;patch
 
@@ -424,7 +550,9 @@ func (r *Reconciler) reconcilePGBouncerDeployment(
 	}
 
 	// Use scheduling constraints from the cluster spec.
-	deploy.Spec.Template.Spec.Affinity = cluster.Spec.Proxy.PGBouncer.Affinity
+	deploy.Spec.Template.Spec.Affinity = architectureAffinity(
+		config.ImageArchitectures(cluster), cluster.Spec.Proxy.PGBouncer.Affinity)
+	setPodNameResolution(cluster, &deploy.Spec.Template.Spec)
 	deploy.Spec.Template.Spec.Tolerations = cluster.Spec.Proxy.PGBouncer.Tolerations
 
 	if cluster.Spec.Proxy.PGBouncer.PriorityClassName != nil {
@@ -468,9 +596,87 @@ func (r *Reconciler) reconcilePGBouncerDeployment(
 	if err == nil {
 		pgbouncer.Pod(cluster, configmap, primaryCertificate, secret, &deploy.Spec.Template.Spec)
 	}
+	if err == nil && logShipperEnabled(cluster) && pgbouncer.LogFileEnabled(cluster) {
+		addLogShipperToPodSpec(cluster, pgbouncer.LogVolumeMount(), configmap, &deploy.Spec.Template)
+	}
 	if err == nil {
 		err = errors.WithStack(r.apply(ctx, deploy))
 	}
 
 	return err
 }
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=list;patch
+// +kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+
+// reconcilePGBouncerReload tells every running PgBouncer Pod to reload its
+// configuration files when they change, so that changes take effect without
+// replacing Pods or resetting connections. Pods that started after the change
+// already have the current files. The kubelet refreshes mounted files
+// eventually; annotating a Pod prompts it to do so sooner.
+func (r *Reconciler) reconcilePGBouncerReload(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	configmap *corev1.ConfigMap, secret *corev1.Secret,
+) (reconcile.Result, error) {
+	if cluster.Spec.Proxy == nil || cluster.Spec.Proxy.PGBouncer == nil ||
+		configmap == nil || secret == nil {
+		return reconcile.Result{}, nil
+	}
+
+	revision := pgbouncer.ConfigRevision(cluster, configmap, secret)
+	if cluster.Status.Proxy.PGBouncer.ConfigRevision == revision {
+		return reconcile.Result{}, nil
+	}
+
+	pods := &corev1.PodList{}
+	err := errors.WithStack(r.Client.List(ctx, pods,
+		client.InNamespace(cluster.Namespace),
+		client.MatchingLabels{
+			naming.LabelCluster: cluster.Name,
+			naming.LabelRole:    naming.RolePGBouncer,
+		}))
+
+	log := logging.FromContext(ctx)
+	pending := false
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		running := false
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == naming.ContainerPGBouncerConfig {
+				running = status.State.Running != nil
+			}
+		}
+		if err != nil || !running || pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		var stdout, stderr bytes.Buffer
+		err = errors.WithStack(r.PodExec(ctx, pod.Namespace, pod.Name,
+			naming.ContainerPGBouncerConfig, nil, &stdout, &stderr,
+			pgbouncer.ReloadCommand(cluster, revision)...))
+
+		if err == nil && strings.TrimSpace(stdout.String()) == "reloaded" {
+			log.V(1).Info("reloaded PgBouncer", "pod", pod.Name, "revision", revision)
+		} else if err == nil {
+			// The files in the Pod are not yet current.
+			pending = true
+			if pod.Annotations[naming.PGBouncerConfigRevision] != revision {
+				before := pod.DeepCopy()
+				if pod.Annotations == nil {
+					pod.Annotations = map[string]string{}
+				}
+				pod.Annotations[naming.PGBouncerConfigRevision] = revision
+				err = errors.WithStack(r.patch(ctx, pod, client.MergeFrom(before)))
+			}
+		}
+	}
+
+	if err == nil && pending {
+		return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+	if err == nil {
+		cluster.Status.Proxy.PGBouncer.ConfigRevision = revision
+	}
+	return reconcile.Result{}, err
+}
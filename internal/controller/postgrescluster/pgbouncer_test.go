@@ -20,6 +20,7 @@ package postgrescluster
 
 import (
 	"context"
+	"io"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -30,9 +31,15 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/pgbouncer"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -365,3 +372,139 @@ func TestReconcilePGBouncerDeployment(t *testing.T) {
 	})
 
 }
+
+func TestReconcilePGBouncerReload(t *testing.T) {
+	ctx := context.Background()
+
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{PGBouncer: &v1beta1.PGBouncerPodSpec{}}
+
+	configmap := &corev1.ConfigMap{Data: map[string]string{"pgbouncer.ini": "one"}}
+	secret := &corev1.Secret{Data: map[string][]byte{"pgbouncer-users.txt": []byte("two")}}
+
+	pod := &corev1.Pod{}
+	pod.Namespace, pod.Name = "ns1", "hippo-pgbouncer-abc"
+	pod.Labels = map[string]string{
+		naming.LabelCluster: "hippo",
+		naming.LabelRole:    naming.RolePGBouncer,
+	}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  naming.ContainerPGBouncerConfig,
+		State: corev1.ContainerState{Running: new(corev1.ContainerStateRunning)},
+	}}
+
+	stdout := ""
+	var calls int
+	reconciler := &Reconciler{
+		Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(pod).Build(),
+		PodExec: func(_ context.Context, namespace, pod, container string, _ io.Reader, out, _ io.Writer, command ...string) error {
+			calls++
+			assert.Equal(t, container, naming.ContainerPGBouncerConfig)
+			assert.Equal(t, command[len(command)-1], pgbouncer.ConfigRevision(cluster, configmap, secret))
+			_, err := io.WriteString(out, stdout)
+			return err
+		},
+	}
+
+	// The files in the Pod are not yet current.
+	result, err := reconciler.reconcilePGBouncerReload(ctx, cluster, configmap, secret)
+	assert.NilError(t, err)
+	assert.Assert(t, result.RequeueAfter > 0)
+	assert.Equal(t, cluster.Status.Proxy.PGBouncer.ConfigRevision, "")
+	assert.Equal(t, calls, 1)
+
+	stored := &corev1.Pod{}
+	assert.NilError(t, reconciler.Client.Get(ctx, client.ObjectKeyFromObject(pod), stored))
+	assert.Equal(t, stored.Annotations[naming.PGBouncerConfigRevision],
+		pgbouncer.ConfigRevision(cluster, configmap, secret))
+
+	// PgBouncer reloads once they are.
+	stdout = "reloaded\n"
+	result, err = reconciler.reconcilePGBouncerReload(ctx, cluster, configmap, secret)
+	assert.NilError(t, err)
+	assert.Equal(t, result, reconcile.Result{})
+	assert.Equal(t, cluster.Status.Proxy.PGBouncer.ConfigRevision,
+		pgbouncer.ConfigRevision(cluster, configmap, secret))
+	assert.Equal(t, calls, 2)
+
+	// Nothing happens until the configuration changes again.
+	_, err = reconciler.reconcilePGBouncerReload(ctx, cluster, configmap, secret)
+	assert.NilError(t, err)
+	assert.Equal(t, calls, 2)
+}
+
+func TestReconcilePGBouncerPoolServices(t *testing.T) {
+	ctx := context.Background()
+
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name, cluster.UID = "ns1", "hippo", "uid1"
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{PGBouncer: &v1beta1.PGBouncerPodSpec{}}
+
+	reconciler := &Reconciler{
+		Client: fake.NewClientBuilder().WithScheme(testScheme).Build(),
+	}
+
+	t.Run("Generate", func(t *testing.T) {
+		service, err := reconciler.generatePGBouncerPoolService(cluster,
+			v1beta1.PGBouncerPoolSpec{
+				Name: "tx", Port: 6432,
+				Service: &v1beta1.ServiceSpec{Type: "LoadBalancer"},
+			})
+		assert.NilError(t, err)
+
+		assert.Assert(t, marshalMatches(service.ObjectMeta, `
+creationTimestamp: null
+labels:
+  postgres-operator.crunchydata.com/cluster: hippo
+  postgres-operator.crunchydata.com/pgbouncer-pool: tx
+  postgres-operator.crunchydata.com/role: pgbouncer
+name: hippo-pgbouncer-tx
+namespace: ns1
+ownerReferences:
+- apiVersion: postgres-operator.crunchydata.com/v1beta1
+  blockOwnerDeletion: true
+  controller: true
+  kind: PostgresCluster
+  name: hippo
+  uid: uid1
+		`))
+		assert.Assert(t, marshalMatches(service.Spec, `
+ports:
+- name: pgbouncer
+  port: 6432
+  protocol: TCP
+  targetPort: 6432
+selector:
+  postgres-operator.crunchydata.com/cluster: hippo
+  postgres-operator.crunchydata.com/role: pgbouncer
+type: LoadBalancer
+		`))
+	})
+
+	t.Run("DeleteUnspecified", func(t *testing.T) {
+		stale, err := reconciler.generatePGBouncerPoolService(cluster,
+			v1beta1.PGBouncerPoolSpec{Name: "old", Port: 6432})
+		assert.NilError(t, err)
+		assert.NilError(t, reconciler.Client.Create(ctx, stale))
+
+		// Services that the cluster does not control remain.
+		other := stale.DeepCopy()
+		other.Name, other.OwnerReferences, other.ResourceVersion = "mine", nil, ""
+		assert.NilError(t, reconciler.Client.Create(ctx, other))
+
+		assert.NilError(t, reconciler.reconcilePGBouncerPoolServices(ctx, cluster))
+
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(stale), stale)
+		assert.Assert(t, apierrors.IsNotFound(err), "expected NotFound, got %v", err)
+		assert.NilError(t, reconciler.Client.Get(ctx, client.ObjectKeyFromObject(other), other))
+	})
+}
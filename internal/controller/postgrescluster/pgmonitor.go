@@ -142,8 +142,8 @@ func (r *Reconciler) reconcilePGMonitorExporter(ctx context.Context,
 		ctx := logging.NewContext(ctx, logging.FromContext(ctx).WithValues("revision", revision))
 
 		if pgmonitor.ExporterEnabled(cluster) {
-			exec := func(_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
-				return r.PodExec(writablePod.Namespace, writablePod.Name, naming.ContainerPGMonitorExporter, stdin, stdout, stderr, command...)
+			exec := func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+				return r.PodExec(ctx, writablePod.Namespace, writablePod.Name, naming.ContainerPGMonitorExporter, stdin, stdout, stderr, command...)
 			}
 			setup, _, err = pgmonitor.Executor(exec).GetExporterSetupSQL(ctx, cluster.Spec.PostgresVersion)
 		}
@@ -151,9 +151,9 @@ func (r *Reconciler) reconcilePGMonitorExporter(ctx context.Context,
 		// Apply the necessary SQL and record its hash in cluster.Status
 
 		if err == nil {
-			err = action(ctx, func(_ context.Context, stdin io.Reader,
+			err = action(ctx, func(ctx context.Context, stdin io.Reader,
 				stdout, stderr io.Writer, command ...string) error {
-				return r.PodExec(writablePod.Namespace, writablePod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+				return r.PodExec(ctx, writablePod.Namespace, writablePod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
 			})
 		}
 		if err == nil {
@@ -273,7 +273,7 @@ func addPGMonitorExporterToInstancePodSpec(
 			{Name: "CONFIG_DIR", Value: "/opt/cpm/conf"},
 			{Name: "POSTGRES_EXPORTER_PORT", Value: fmt.Sprint(exporterPort)},
 			{Name: "PGBACKREST_INFO_THROTTLE_MINUTES", Value: "10"},
-			{Name: "PG_STAT_STATEMENTS_LIMIT", Value: "20"},
+			{Name: "PG_STAT_STATEMENTS_LIMIT", Value: fmt.Sprint(pgmonitor.TopStatements(cluster))},
 			{Name: "PG_STAT_STATEMENTS_THROTTLE_MINUTES", Value: "-1"},
 			{Name: "EXPORTER_PG_HOST", Value: exporterHost},
 			{Name: "EXPORTER_PG_PORT", Value: fmt.Sprint(*cluster.Spec.Port)},
@@ -308,6 +308,12 @@ func addPGMonitorExporterToInstancePodSpec(
 		}},
 	}
 
+	// Pass any generated queries to the exporter
+	if queries := pgmonitor.StatisticsQueries(cluster); queries != "" {
+		exporterContainer.Env = append(exporterContainer.Env,
+			corev1.EnvVar{Name: "EXPORTER_EXTRA_QUERIES", Value: queries})
+	}
+
 	template.Spec.Containers = append(template.Spec.Containers, exporterContainer)
 
 	// add custom exporter config volume
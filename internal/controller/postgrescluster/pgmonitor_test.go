@@ -182,6 +182,24 @@ func TestAddPGMonitorExporterToInstancePodSpec(t *testing.T) {
 		}
 		assert.Assert(t, foundConfigMount)
 	})
+
+	t.Run("QueryStatistics", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Monitoring.QueryStatistics = &v1beta1.QueryStatisticsSpec{
+			TopStatements: initialize.Int32(5),
+		}
+		template := &corev1.PodTemplateSpec{}
+
+		assert.NilError(t, addPGMonitorExporterToInstancePodSpec(cluster, template))
+
+		container := getContainerWithName(template.Spec.Containers, naming.ContainerPGMonitorExporter)
+		env := map[string]string{}
+		for _, v := range container.Env {
+			env[v.Name] = v.Value
+		}
+		assert.Equal(t, env["PG_STAT_STATEMENTS_LIMIT"], "5")
+		assert.Equal(t, env["EXPORTER_EXTRA_QUERIES"], pgmonitor.StatisticsQueries(cluster))
+	})
 }
 
 func TestReconcilePGMonitorExporterSetupErrors(t *testing.T) {
@@ -338,7 +356,7 @@ func TestReconcilePGMonitorExporterSetupErrors(t *testing.T) {
 			ctx := context.Background()
 			var called bool
 			reconciler := &Reconciler{
-				PodExec: func(namespace, pod, container string, stdin io.Reader, stdout,
+				PodExec: func(_ context.Context, namespace, pod, container string, stdin io.Reader, stdout,
 					stderr io.Writer, command ...string) error {
 					called = true
 					return nil
@@ -361,7 +379,7 @@ func TestReconcilePGMonitorExporter(t *testing.T) {
 	ctx := context.Background()
 	var called bool
 	reconciler := &Reconciler{
-		PodExec: func(namespace, pod, container string, stdin io.Reader, stdout,
+		PodExec: func(_ context.Context, namespace, pod, container string, stdin io.Reader, stdout,
 			stderr io.Writer, command ...string) error {
 			called = true
 			return nil
@@ -447,7 +465,7 @@ func TestReconcilePGMonitorExporterStatus(t *testing.T) {
 			)
 
 			reconciler := &Reconciler{
-				PodExec: func(namespace, pod, container string, stdin io.Reader, stdout,
+				PodExec: func(_ context.Context, namespace, pod, container string, stdin io.Reader, stdout,
 					stderr io.Writer, command ...string) error {
 					called = true
 					return nil
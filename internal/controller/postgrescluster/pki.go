@@ -21,8 +21,13 @@ import (
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/crunchydata/postgres-operator/internal/bluegreen"
 	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/pki"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
@@ -35,6 +40,13 @@ const (
 	rootCertFile    = "ca.crt"
 )
 
+// certManagerCertificate is the kind of cert-manager object that requests and
+// renews a certificate from an issuer.
+// - https://cert-manager.io/docs/concepts/certificate/
+var certManagerCertificate = schema.GroupVersionKind{
+	Group: "cert-manager.io", Version: "v1", Kind: "Certificate",
+}
+
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=create;patch
 
@@ -137,6 +149,13 @@ func (r *Reconciler) reconcileClusterCertificate(
 	leaf.DNSNames = naming.ServiceDNSNames(ctx, primaryService)
 	leaf.CommonName = leaf.DNSNames[0] // FQDN
 
+	// Green takes over the primary Service of blue in a blue/green switch.
+	if bluegreen.Enabled(cluster) {
+		leaf.DNSNames = append(leaf.DNSNames, naming.ServiceDNSNames(ctx, &corev1.Service{
+			ObjectMeta: naming.ClusterPrimaryService(bluegreen.Source(cluster)),
+		})...)
+	}
+
 	if data, ok := existing.Data[keyCertificate]; err == nil && ok {
 		leaf.Certificate, err = pki.ParseCertificate(data)
 		err = errors.WithStack(err)
@@ -267,3 +286,155 @@ func clusterCertSecretProjection(certificate *corev1.Secret) *corev1.SecretProje
 		},
 	}
 }
+
+// postgresUserCertificate populates intent with a client certificate for the
+// PostgreSQL user in intent, signed by rootCACert. The certificate in existing
+// is kept unless it is 'bad' for any reason.
+func (*Reconciler) postgresUserCertificate(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	existing, intent *corev1.Secret, rootCACert *pki.RootCertificateAuthority,
+) error {
+	var err error
+	username := string(intent.Data["user"])
+
+	// PostgreSQL compares the common name of a client certificate to the user
+	// name when authenticating with the "cert" method.
+	// - https://www.postgresql.org/docs/current/auth-cert.html
+	leaf := pki.NewLeafCertificate("", nil, nil)
+	leaf.DNSNames = []string{username}
+	leaf.CommonName = username
+
+	if existing != nil {
+		if data, ok := existing.Data[clusterCertFile]; err == nil && ok {
+			leaf.Certificate, err = pki.ParseCertificate(data)
+			err = errors.WithStack(err)
+		}
+		if data, ok := existing.Data[clusterKeyFile]; err == nil && ok {
+			leaf.PrivateKey, err = pki.ParsePrivateKey(data)
+			err = errors.WithStack(err)
+		}
+	}
+
+	// if there is an error or the leaf certificate is bad, generate a new one
+	if err != nil || pki.LeafCertIsBad(ctx, leaf, rootCACert, cluster.Namespace) {
+		err = errors.WithStack(leaf.Generate(rootCACert))
+	}
+
+	if err == nil {
+		intent.Data[clusterCertFile], err = leaf.Certificate.MarshalText()
+		err = errors.WithStack(err)
+	}
+	if err == nil {
+		intent.Data[clusterKeyFile], err = leaf.PrivateKey.MarshalText()
+		err = errors.WithStack(err)
+	}
+	if err == nil {
+		intent.Data[rootCertFile], err = rootCACert.Certificate.MarshalText()
+		err = errors.WithStack(err)
+	}
+
+	return err
+}
+
+// +kubebuilder:rbac:groups="cert-manager.io",resources="certificates",verbs={get,create,patch}
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+// postgresUserCertificateRequest writes a cert-manager Certificate for the
+// PostgreSQL user in intent and copies the client certificate it issues into
+// intent. The certificate is absent from intent until cert-manager issues it.
+func (r *Reconciler) postgresUserCertificateRequest(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, intent *corev1.Secret,
+) error {
+	username := string(intent.Data["user"])
+	issuer := cluster.Spec.Authentication.ClientCertificates.IssuerRef
+
+	kind, group := issuer.Kind, issuer.Group
+	if kind == "" {
+		kind = "Issuer"
+	}
+	if group == "" {
+		group = certManagerCertificate.Group
+	}
+
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(certManagerCertificate)
+	objectMeta := naming.PostgresUserCertificate(cluster, username)
+	certificate.SetNamespace(objectMeta.Namespace)
+	certificate.SetName(objectMeta.Name)
+	certificate.SetAnnotations(cluster.Spec.Metadata.GetAnnotationsOrNil())
+	certificate.SetLabels(naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster:      cluster.Name,
+			naming.LabelPostgresUser: username,
+		}))
+
+	// PostgreSQL compares the common name of a client certificate to the user
+	// name when authenticating with the "cert" method.
+	// - https://www.postgresql.org/docs/current/auth-cert.html
+	// - https://cert-manager.io/docs/reference/api-docs/#cert-manager.io/v1.CertificateSpec
+	certificate.Object["spec"] = map[string]interface{}{
+		"commonName": username,
+		"secretName": objectMeta.Name,
+		"usages":     []interface{}{"client auth", "digital signature", "key encipherment"},
+		"issuerRef": map[string]interface{}{
+			"name":  issuer.Name,
+			"kind":  kind,
+			"group": group,
+		},
+	}
+
+	err := errors.WithStack(r.setControllerReference(cluster, certificate))
+	if err == nil {
+		err = errors.WithStack(r.patch(ctx, certificate, client.Apply, client.ForceOwnership))
+	}
+
+	// Copy the certificate once cert-manager has written it. Until then, the
+	// user Secret has no certificate at all.
+	issued := &corev1.Secret{ObjectMeta: objectMeta}
+	if err == nil {
+		err = errors.WithStack(client.IgnoreNotFound(
+			r.Client.Get(ctx, client.ObjectKeyFromObject(issued), issued)))
+	}
+	if err == nil && len(issued.Data[clusterCertFile]) > 0 && len(issued.Data[clusterKeyFile]) > 0 {
+		intent.Data[clusterCertFile] = issued.Data[clusterCertFile]
+		intent.Data[clusterKeyFile] = issued.Data[clusterKeyFile]
+		intent.Data[rootCertFile] = issued.Data[rootCertFile]
+	}
+
+	return err
+}
+
+// +kubebuilder:rbac:groups="cert-manager.io",resources="certificates",verbs={list,delete}
+
+// deletePostgresUserCertificateRequests deletes the cert-manager Certificates
+// of cluster that are not for one of the users in keep. Nothing happens when
+// cert-manager is not installed.
+func (r *Reconciler) deletePostgresUserCertificateRequests(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, keep sets.String,
+) error {
+	certificates := &unstructured.UnstructuredList{}
+	certificates.SetGroupVersionKind(
+		certManagerCertificate.GroupVersion().WithKind("CertificateList"))
+
+	err := r.Client.List(ctx, certificates,
+		client.InNamespace(cluster.Namespace),
+		client.MatchingLabels{naming.LabelCluster: cluster.Name},
+	)
+	if meta.IsNoMatchError(err) {
+		return nil
+	}
+	err = errors.WithStack(err)
+
+	for i := range certificates.Items {
+		certificate := &certificates.Items[i]
+		username, ok := certificate.GetLabels()[naming.LabelPostgresUser]
+
+		if err == nil && ok && !keep.Has(username) {
+			err = errors.WithStack(client.IgnoreNotFound(
+				r.deleteControlled(ctx, cluster, certificate)))
+		}
+	}
+
+	return err
+}
@@ -533,3 +533,43 @@ func createInstanceSecrets(
 
 	return intent, existing, err
 }
+
+func TestPostgresUserCertificate(t *testing.T) {
+	ctx := context.Background()
+	reconciler := &Reconciler{}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+
+	root := pki.NewRootCertificateAuthority()
+	assert.NilError(t, root.Generate())
+
+	intent := &corev1.Secret{Data: map[string][]byte{"user": []byte("app")}}
+	assert.NilError(t, reconciler.postgresUserCertificate(ctx, cluster, nil, intent, root))
+
+	leaf, err := pki.ParseCertificate(intent.Data["tls.crt"])
+	assert.NilError(t, err)
+	parsed, err := x509.ParseCertificate(leaf.Certificate)
+	assert.NilError(t, err)
+	assert.Equal(t, parsed.Subject.CommonName, "app")
+
+	rootPEM, err := root.Certificate.MarshalText()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, intent.Data["ca.crt"], rootPEM)
+
+	t.Run("KeepExisting", func(t *testing.T) {
+		next := &corev1.Secret{Data: map[string][]byte{"user": []byte("app")}}
+		assert.NilError(t, reconciler.postgresUserCertificate(ctx, cluster, intent, next, root))
+		assert.DeepEqual(t, next.Data["tls.crt"], intent.Data["tls.crt"])
+		assert.DeepEqual(t, next.Data["tls.key"], intent.Data["tls.key"])
+	})
+
+	t.Run("ReplaceOtherAuthority", func(t *testing.T) {
+		other := pki.NewRootCertificateAuthority()
+		assert.NilError(t, other.Generate())
+
+		next := &corev1.Secret{Data: map[string][]byte{"user": []byte("app")}}
+		assert.NilError(t, reconciler.postgresUserCertificate(ctx, cluster, intent, next, other))
+		assert.Assert(t, !bytes.Equal(next.Data["tls.crt"], intent.Data["tls.crt"]))
+	})
+}
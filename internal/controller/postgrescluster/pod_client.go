@@ -16,20 +16,37 @@
 package postgrescluster
 
 import (
+	"context"
 	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	spdystream "k8s.io/apimachinery/pkg/util/httpstream/spdy"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+	utilexec "k8s.io/client-go/util/exec"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
 )
 
 // podExecutor runs command on container in pod in namespace. Non-nil streams
-// (stdin, stdout, and stderr) are attached the to the remote process.
+// (stdin, stdout, and stderr) are attached the to the remote process. It stops
+// the remote process and returns when ctx is done.
 type podExecutor func(
-	namespace, pod, container string,
+	ctx context.Context, namespace, pod, container string,
 	stdin io.Reader, stdout, stderr io.Writer, command ...string,
 ) error
 
@@ -45,7 +62,7 @@ func newPodExecutor(config *rest.Config) (podExecutor, error) {
 	client, err := newPodClient(config)
 
 	return func(
-		namespace, pod, container string,
+		ctx context.Context, namespace, pod, container string,
 		stdin io.Reader, stdout, stderr io.Writer, command ...string,
 	) error {
 		request := client.Post().
@@ -59,8 +76,13 @@ func newPodExecutor(config *rest.Config) (podExecutor, error) {
 				Stderr:    stderr != nil,
 			}, scheme.ParameterCodec)
 
-		exec, err := remotecommand.NewSPDYExecutor(config, "POST", request.URL())
+		transport, upgrader, err := podExecTransport(ctx, config)
 
+		var exec remotecommand.Executor
+		if err == nil {
+			exec, err = remotecommand.NewSPDYExecutorForTransports(
+				transport, upgrader, "POST", request.URL())
+		}
 		if err == nil {
 			err = exec.Stream(remotecommand.StreamOptions{
 				Stdin:  stdin,
@@ -68,7 +90,238 @@ func newPodExecutor(config *rest.Config) (podExecutor, error) {
 				Stderr: stderr,
 			})
 		}
+		if err != nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			err = podExecError{err}
+		}
 
 		return err
 	}, err
 }
+
+// podExecTransport returns a round tripper and upgrader like those of
+// spdy.RoundTripperFor that stop connecting and close their connection when
+// ctx is done. Errors that happen before the connection is established are
+// marked as podExecConnectError.
+func podExecTransport(ctx context.Context, config *rest.Config) (
+	http.RoundTripper, spdy.Upgrader, error,
+) {
+	tlsConfig, err := rest.TLSConfigFor(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	proxy := http.ProxyFromEnvironment
+	if config.Proxy != nil {
+		proxy = config.Proxy
+	}
+
+	// The dialer of the upgrade ignores the context of its request while
+	// negotiating TLS, so give it the deadline of ctx, if any.
+	upgrade := spdystream.NewRoundTripperWithProxy(tlsConfig, true, false, proxy)
+	upgrade.Dialer = &net.Dialer{}
+	if deadline, ok := ctx.Deadline(); ok {
+		upgrade.Dialer.Deadline = deadline
+	}
+
+	wrapper, err := rest.HTTPWrappersForConfig(config, upgrade)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return podExecRoundTripper{ctx: ctx, next: wrapper},
+		podExecUpgrader{ctx: ctx, next: upgrade}, nil
+}
+
+// podExecConnectError is the error of a command that could not connect to its
+// container. The command did not start.
+type podExecConnectError struct{ error }
+
+func (e podExecConnectError) Unwrap() error { return e.error }
+
+type podExecRoundTripper struct {
+	ctx  context.Context
+	next http.RoundTripper
+}
+
+type podExecUpgrader struct {
+	ctx  context.Context
+	next spdy.Upgrader
+}
+
+func (t podExecRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := t.next.RoundTrip(request.WithContext(t.ctx))
+	if err != nil {
+		err = podExecConnectError{err}
+	}
+	return response, err
+}
+
+func (u podExecUpgrader) NewConnection(response *http.Response) (httpstream.Connection, error) {
+	conn, err := u.next.NewConnection(response)
+	if err == nil {
+		// Close the connection when ctx is done. The stream stops and
+		// closes it otherwise, which ends this goroutine.
+		go func() {
+			select {
+			case <-u.ctx.Done():
+				_ = conn.Close()
+			case <-conn.CloseChan():
+			}
+		}()
+	}
+	return conn, err
+}
+
+// podExecGuard runs commands in containers according to a config.ExecPolicy so
+// that a container that cannot be reached does not block a reconcile worker.
+// Every attempt is logged. An attempt that runs longer than the timeout is
+// stopped. A command that could not connect to its container is attempted
+// again. After too many failures in a row, the commands of a cluster are
+// refused for a while.
+type podExecGuard struct {
+	exec    podExecutor
+	policy  config.ExecPolicy
+	cluster func(namespace, pod string) string
+	now     func() time.Time
+
+	mutex    sync.Mutex
+	failures map[string]int
+	refusing map[string]time.Time
+}
+
+func newPodExecGuard(
+	exec podExecutor, policy config.ExecPolicy,
+	cluster func(namespace, pod string) string,
+) *podExecGuard {
+	return &podExecGuard{
+		exec:     exec,
+		policy:   policy,
+		cluster:  cluster,
+		now:      time.Now,
+		failures: make(map[string]int),
+		refusing: make(map[string]time.Time),
+	}
+}
+
+// Exec is a podExecutor.
+func (g *podExecGuard) Exec(
+	ctx context.Context, namespace, pod, container string,
+	stdin io.Reader, stdout, stderr io.Writer, command ...string,
+) error {
+	key := g.cluster(namespace, pod)
+	log := logging.FromContext(ctx).WithValues(
+		"cluster", key, "pod", pod, "container", container,
+		"command", truncateCommand(command, 256))
+
+	for attempt := 1; ; attempt++ {
+		if until, refusing := g.refusal(key); refusing {
+			log.Info("Refusing command after too many failures", "until", until)
+			return podExecError{errors.Errorf(
+				"refusing commands in %q until %v after %d failures in a row",
+				key, until.Format(time.RFC3339), g.policy.BreakerThreshold)}
+		}
+
+		started := g.now()
+		timedOut, err := g.attempt(ctx, namespace, pod, container,
+			stdin, stdout, stderr, command...)
+
+		// A command that exited, successfully or not, reached its container.
+		var exit utilexec.ExitError
+		reached := err == nil || errors.As(err, &exit)
+		g.record(key, reached)
+
+		if err != nil {
+			log.Info("Ran command", "attempt", attempt,
+				"duration", g.now().Sub(started).String(), "error", err.Error())
+		} else {
+			log.Info("Ran command", "attempt", attempt,
+				"duration", g.now().Sub(started).String())
+		}
+		if timedOut {
+			log.Info("Stopped command that did not finish", "timeout", g.policy.Timeout.String())
+		}
+
+		// Try again only when the command could not connect to its container
+		// so it did not start.
+		var unstarted podExecConnectError
+		if !errors.As(err, &unstarted) || ctx.Err() != nil || attempt > g.policy.Retries {
+			return err
+		}
+	}
+}
+
+// attempt runs command once and stops it after the timeout of the policy. It
+// returns whether or not the command was stopped for taking too long.
+func (g *podExecGuard) attempt(
+	ctx context.Context, namespace, pod, container string,
+	stdin io.Reader, stdout, stderr io.Writer, command ...string,
+) (bool, error) {
+	if g.policy.Timeout <= 0 {
+		return false, g.exec(ctx, namespace, pod, container, stdin, stdout, stderr, command...)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, g.policy.Timeout)
+	defer cancel()
+
+	err := g.exec(attemptCtx, namespace, pod, container, stdin, stdout, stderr, command...)
+	if errors.Is(attemptCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		return true, podExecError{errors.Errorf(
+			"command did not finish within %v", g.policy.Timeout)}
+	}
+	return false, err
+}
+
+// record counts the failures of commands in the cluster identified by key.
+func (g *podExecGuard) record(key string, reached bool) {
+	if g.policy.BreakerThreshold <= 0 {
+		return
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if reached {
+		delete(g.failures, key)
+		delete(g.refusing, key)
+		return
+	}
+
+	g.failures[key]++
+	if g.failures[key] >= g.policy.BreakerThreshold {
+		g.refusing[key] = g.now().Add(g.policy.BreakerCooldown)
+	}
+}
+
+// refusal returns whether or not commands in the cluster identified by key
+// are being refused, and until when.
+func (g *podExecGuard) refusal(key string) (time.Time, bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	until, ok := g.refusing[key]
+	return until, ok && g.now().Before(until)
+}
+
+// podCluster returns the namespace and cluster of pod, or the namespace and
+// name of pod when it does not belong to a cluster.
+func (r *Reconciler) podCluster(namespace, name string) string {
+	pod := &corev1.Pod{}
+	err := r.Client.Get(context.Background(),
+		client.ObjectKey{Namespace: namespace, Name: name}, pod)
+
+	if cluster := pod.Labels[naming.LabelCluster]; err == nil && cluster != "" {
+		return namespace + "/" + cluster
+	}
+	return namespace + "/" + name
+}
+
+// truncateCommand returns command as one string of at most size bytes.
+func truncateCommand(command []string, size int) string {
+	s := strings.Join(command, " ")
+	if len(s) > size {
+		s = s[:size] + "..."
+	}
+	return s
+}
@@ -0,0 +1,198 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"gotest.tools/v3/assert"
+	utilexec "k8s.io/client-go/util/exec"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+)
+
+func TestPodExecGuard(t *testing.T) {
+	ctx := context.Background()
+	cluster := func(namespace, pod string) string { return namespace + "/hippo" }
+
+	t.Run("Success", func(t *testing.T) {
+		calls := 0
+		guard := newPodExecGuard(func(
+			_ context.Context, namespace, pod, container string,
+			stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			calls++
+			assert.Equal(t, namespace, "ns1")
+			assert.Equal(t, pod, "pod1")
+			assert.Equal(t, container, "database")
+			assert.DeepEqual(t, command, []string{"echo", "hi"})
+			assert.Assert(t, stdin == nil)
+			assert.Assert(t, stderr == nil)
+			_, err := stdout.Write([]byte("hi"))
+			return err
+		}, config.ExecPolicy{Timeout: time.Minute, Retries: 2}, cluster)
+
+		var stdout bytes.Buffer
+		assert.NilError(t, guard.Exec(ctx, "ns1", "pod1", "database", nil, &stdout, nil, "echo", "hi"))
+		assert.Equal(t, stdout.String(), "hi")
+		assert.Equal(t, calls, 1)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		finished := false
+		calls := 0
+		guard := newPodExecGuard(func(
+			ctx context.Context, _, _, _ string, _ io.Reader, _, _ io.Writer, _ ...string,
+		) error {
+			calls++
+			<-ctx.Done()
+			finished = true
+			return podExecError{ctx.Err()}
+		}, config.ExecPolicy{Timeout: 10 * time.Millisecond, Retries: 2}, cluster)
+
+		err := guard.Exec(ctx, "ns1", "pod1", "database", nil, nil, nil, "sleep")
+		assert.ErrorContains(t, err, "did not finish within 10ms")
+		assert.Assert(t, errors.As(err, new(podExecError)))
+
+		// The command is stopped before Exec returns.
+		assert.Assert(t, finished)
+		assert.Equal(t, calls, 1, "expected no retry")
+	})
+
+	t.Run("RetryNotConnected", func(t *testing.T) {
+		var inputs []string
+		guard := newPodExecGuard(func(
+			_ context.Context, _, _, _ string, stdin io.Reader, _, _ io.Writer, _ ...string,
+		) error {
+			if len(inputs) == 0 {
+				inputs = append(inputs, "")
+				return podExecError{podExecConnectError{errors.New("connection refused")}}
+			}
+			b, _ := ioutil.ReadAll(stdin)
+			inputs = append(inputs, string(b))
+			return nil
+		}, config.ExecPolicy{Retries: 2}, cluster)
+
+		assert.NilError(t, guard.Exec(ctx, "ns1", "pod1", "database",
+			strings.NewReader("SELECT 1"), nil, nil, "psql"))
+		assert.DeepEqual(t, inputs, []string{"", "SELECT 1"})
+	})
+
+	t.Run("NoRetryAfterConnect", func(t *testing.T) {
+		calls := 0
+		guard := newPodExecGuard(func(
+			_ context.Context, _, _, _ string, _ io.Reader, _, _ io.Writer, _ ...string,
+		) error {
+			calls++
+			return podExecError{errors.New("connection reset")}
+		}, config.ExecPolicy{Retries: 2}, cluster)
+
+		// The command may have run even though it sent nothing.
+		assert.ErrorContains(t,
+			guard.Exec(ctx, "ns1", "pod1", "database", nil, new(bytes.Buffer), nil, "psql"),
+			"connection reset")
+		assert.Equal(t, calls, 1)
+	})
+
+	t.Run("NoRetryAfterCancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		calls := 0
+		guard := newPodExecGuard(func(
+			ctx context.Context, _, _, _ string, _ io.Reader, _, _ io.Writer, _ ...string,
+		) error {
+			calls++
+			return podExecError{podExecConnectError{ctx.Err()}}
+		}, config.ExecPolicy{Retries: 2}, cluster)
+
+		err := guard.Exec(ctx, "ns1", "pod1", "database", nil, nil, nil, "psql")
+		assert.Assert(t, errors.Is(err, context.Canceled), "got %v", err)
+		assert.Equal(t, calls, 1)
+	})
+
+	t.Run("NoRetryAfterExit", func(t *testing.T) {
+		calls := 0
+		guard := newPodExecGuard(func(
+			_ context.Context, _, _, _ string, _ io.Reader, _, _ io.Writer, _ ...string,
+		) error {
+			calls++
+			return podExecError{utilexec.CodeExitError{Err: errors.New("exit 1"), Code: 1}}
+		}, config.ExecPolicy{Retries: 2, BreakerThreshold: 1, BreakerCooldown: time.Hour}, cluster)
+
+		for i := 0; i < 3; i++ {
+			assert.ErrorContains(t,
+				guard.Exec(ctx, "ns1", "pod1", "database", nil, nil, nil, "false"), "exit 1")
+		}
+		assert.Equal(t, calls, 3, "expected no retries and no refusals")
+	})
+
+	t.Run("Breaker", func(t *testing.T) {
+		now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+		calls := map[string]int{}
+		fail := true
+		guard := newPodExecGuard(func(
+			_ context.Context, namespace, _, _ string, _ io.Reader, _, _ io.Writer, _ ...string,
+		) error {
+			calls[namespace]++
+			if fail {
+				return podExecError{errors.New("no route to host")}
+			}
+			return nil
+		}, config.ExecPolicy{BreakerThreshold: 2, BreakerCooldown: time.Minute}, cluster)
+		guard.now = func() time.Time { return now }
+
+		assert.ErrorContains(t, guard.Exec(ctx, "ns1", "pod1", "database", nil, nil, nil), "no route")
+		assert.ErrorContains(t, guard.Exec(ctx, "ns1", "pod2", "database", nil, nil, nil), "no route")
+
+		// Commands in the same cluster are refused.
+		err := guard.Exec(ctx, "ns1", "pod1", "database", nil, nil, nil)
+		assert.ErrorContains(t, err, "refusing commands")
+		assert.Assert(t, errors.As(err, new(podExecError)))
+		assert.Equal(t, calls["ns1"], 2)
+
+		// Other clusters are not affected.
+		assert.ErrorContains(t, guard.Exec(ctx, "ns2", "pod1", "database", nil, nil, nil), "no route")
+		assert.Equal(t, calls["ns2"], 1)
+
+		// One attempt is allowed after the cooldown. Another failure refuses again.
+		now = now.Add(time.Minute)
+		assert.ErrorContains(t, guard.Exec(ctx, "ns1", "pod1", "database", nil, nil, nil), "no route")
+		assert.ErrorContains(t, guard.Exec(ctx, "ns1", "pod1", "database", nil, nil, nil), "refusing")
+		assert.Equal(t, calls["ns1"], 3)
+
+		// A success resets everything.
+		now = now.Add(time.Minute)
+		fail = false
+		assert.NilError(t, guard.Exec(ctx, "ns1", "pod1", "database", nil, nil, nil))
+		fail = true
+		assert.ErrorContains(t, guard.Exec(ctx, "ns1", "pod1", "database", nil, nil, nil), "no route")
+		assert.ErrorContains(t, guard.Exec(ctx, "ns1", "pod1", "database", nil, nil, nil), "no route")
+		assert.Equal(t, calls["ns1"], 6)
+	})
+}
+
+func TestTruncateCommand(t *testing.T) {
+	assert.Equal(t, truncateCommand([]string{"echo", "hi"}, 10), "echo hi")
+	assert.Equal(t, truncateCommand([]string{"bash", "-c", "long script"}, 10), "bash -c lo...")
+}
@@ -0,0 +1,247 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// ConditionPodSecurityViolation is the type used in a condition to indicate
+// that instance Pods violate the Pod Security Standard that the namespace
+// enforces and would be rejected. Its message lists the violations.
+const ConditionPodSecurityViolation = "PodSecurityViolation"
+
+// EventPodSecurityViolation is the event reason used when instance Pods
+// violate the Pod Security Standard that the namespace enforces.
+const EventPodSecurityViolation = "PodSecurityViolation"
+
+// labelPodSecurityEnforce is the namespace label that sets the Pod Security
+// Standard that Kubernetes enforces on Pods in that namespace.
+// - https://docs.k8s.io/concepts/security/pod-security-admission/
+const labelPodSecurityEnforce = "pod-security.kubernetes.io/enforce"
+
+var (
+	// podSecurityBaselineCapabilities are the capabilities that the
+	// "baseline" standard allows containers to add.
+	podSecurityBaselineCapabilities = sets.NewString(
+		"AUDIT_WRITE", "CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "KILL",
+		"MKNOD", "NET_BIND_SERVICE", "SETFCAP", "SETGID", "SETPCAP", "SETUID",
+		"SYS_CHROOT",
+	)
+
+	// podSecurityRestrictedVolumes are the kinds of volumes that the
+	// "restricted" standard allows.
+	podSecurityRestrictedVolumes = sets.NewString(
+		"configMap", "csi", "downwardAPI", "emptyDir", "ephemeral",
+		"persistentVolumeClaim", "projected", "secret",
+	)
+)
+
+// podSecurityViolations returns the ways that pod violates the Pod Security
+// Standard named level. Only the "baseline" and "restricted" standards have
+// requirements.
+// - https://docs.k8s.io/concepts/security/pod-security-standards/
+func podSecurityViolations(level string, pod *corev1.PodSpec) []string {
+	if level != "baseline" && level != "restricted" {
+		return nil
+	}
+	restricted := level == "restricted"
+	violations := sets.NewString()
+
+	if pod.HostNetwork || pod.HostPID || pod.HostIPC {
+		violations.Insert("pod must not share host namespaces")
+	}
+
+	for _, volume := range pod.Volumes {
+		kind := volumeKind(volume.VolumeSource)
+		if kind == "hostPath" || (restricted && !podSecurityRestrictedVolumes.Has(kind)) {
+			violations.Insert(fmt.Sprintf("volume %q must not be %s", volume.Name, kind))
+		}
+	}
+
+	podNonRoot, podSeccomp := false, false
+	if security := pod.SecurityContext; security != nil {
+		podNonRoot = security.RunAsNonRoot != nil && *security.RunAsNonRoot
+		podSeccomp = security.SeccompProfile != nil
+		if podSeccomp && security.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			violations.Insert("pod must not have an unconfined seccomp profile")
+		}
+		if restricted && security.RunAsUser != nil && *security.RunAsUser == 0 {
+			violations.Insert("pod must not run as root")
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, pod.InitContainers...), pod.Containers...)
+	for _, container := range containers {
+		name := fmt.Sprintf("container %q", container.Name)
+
+		for _, port := range container.Ports {
+			if port.HostPort != 0 {
+				violations.Insert(name + " must not use host ports")
+			}
+		}
+
+		security := container.SecurityContext
+		if security == nil {
+			security = &corev1.SecurityContext{}
+		}
+
+		if security.Privileged != nil && *security.Privileged {
+			violations.Insert(name + " must not be privileged")
+		}
+		if security.Capabilities != nil {
+			for _, add := range security.Capabilities.Add {
+				if (restricted && add != "NET_BIND_SERVICE") ||
+					!podSecurityBaselineCapabilities.Has(string(add)) {
+					violations.Insert(name + " must not add capability " + string(add))
+				}
+			}
+		}
+		if security.SeccompProfile != nil &&
+			security.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			violations.Insert(name + " must not have an unconfined seccomp profile")
+		}
+
+		if !restricted {
+			continue
+		}
+
+		if security.AllowPrivilegeEscalation == nil || *security.AllowPrivilegeEscalation {
+			violations.Insert(name + " must not allow privilege escalation")
+		}
+		if !podNonRoot && (security.RunAsNonRoot == nil || !*security.RunAsNonRoot) {
+			violations.Insert(name + " must run as non-root")
+		}
+		if security.RunAsUser != nil && *security.RunAsUser == 0 {
+			violations.Insert(name + " must not run as root")
+		}
+		if !podSeccomp && security.SeccompProfile == nil {
+			violations.Insert(name + " must have a seccomp profile")
+		}
+
+		dropsAll := false
+		if security.Capabilities != nil {
+			for _, drop := range security.Capabilities.Drop {
+				dropsAll = dropsAll || drop == "ALL"
+			}
+		}
+		if !dropsAll {
+			violations.Insert(name + " must drop ALL capabilities")
+		}
+	}
+
+	return violations.List()
+}
+
+// volumeKind returns the name of the field that is set in source, e.g.
+// "emptyDir" or "hostPath".
+func volumeKind(source corev1.VolumeSource) string {
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&source)
+	if err != nil || len(object) == 0 {
+		return ""
+	}
+	kinds := make([]string, 0, len(object))
+	for kind := range object {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds[0]
+}
+
+// +kubebuilder:rbac:groups="",resources="namespaces",verbs={get}
+
+// reconcilePodSecurity reports in the status of cluster whether its instance
+// Pods satisfy the Pod Security Standard that its namespace enforces. The
+// namespace is read directly from the API rather than cached. Namespaces are
+// cluster-scoped, so reading one is forbidden when the operator is installed
+// with a namespaced Role; the condition is then Unknown.
+func (r *Reconciler) reconcilePodSecurity(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, observed *observedInstances,
+) {
+	namespace := &unstructured.Unstructured{}
+	namespace.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
+
+	err := r.Client.Get(ctx, client.ObjectKey{Name: cluster.Namespace}, namespace)
+	if apierrors.IsForbidden(err) {
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			ObservedGeneration: cluster.GetGeneration(),
+			Type:               ConditionPodSecurityViolation,
+			Status:             metav1.ConditionUnknown,
+			Reason:             "Forbidden",
+			Message: fmt.Sprintf("Unable to read the Pod Security Standard of namespace %q. "+
+				"This requires permission to get namespaces, which only the cluster-wide installation has.",
+				cluster.Namespace),
+		})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).V(1).Info("unable to read namespace Pod Security Standard",
+			"namespace", cluster.Namespace, "error", err.Error())
+		return
+	}
+	level := namespace.GetLabels()[labelPodSecurityEnforce]
+
+	violations := sets.NewString()
+	for _, instance := range observed.forCluster {
+		if instance.Runner != nil {
+			violations.Insert(podSecurityViolations(level, &instance.Runner.Spec.Template.Spec)...)
+		}
+	}
+
+	if violations.Len() == 0 {
+		if condition := meta.FindStatusCondition(cluster.Status.Conditions,
+			ConditionPodSecurityViolation); condition != nil &&
+			condition.Status != metav1.ConditionFalse {
+			meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+				ObservedGeneration: cluster.GetGeneration(),
+				Type:               ConditionPodSecurityViolation,
+				Status:             metav1.ConditionFalse,
+				Reason:             "PodSecurityStandardMet",
+				Message:            "Instance Pods satisfy the Pod Security Standard of the namespace",
+			})
+		}
+		return
+	}
+
+	message := fmt.Sprintf("Namespace %q enforces the %q Pod Security Standard: %s",
+		cluster.Namespace, level, strings.Join(violations.List(), "; "))
+
+	if !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionPodSecurityViolation) {
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, EventPodSecurityViolation, message)
+	}
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		ObservedGeneration: cluster.GetGeneration(),
+		Type:               ConditionPodSecurityViolation,
+		Status:             metav1.ConditionTrue,
+		Reason:             EventPodSecurityViolation,
+		Message:            message,
+	})
+}
@@ -0,0 +1,173 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestPodSecurityViolations(t *testing.T) {
+	pod := &corev1.PodSpec{
+		SecurityContext: initialize.RestrictedPodSecurityContext(),
+		Containers: []corev1.Container{{
+			Name: "database", SecurityContext: initialize.RestrictedSecurityContext(),
+		}},
+		Volumes: []corev1.Volume{{
+			Name: "data",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{},
+			},
+		}},
+	}
+
+	assert.Assert(t, podSecurityViolations("", pod) == nil)
+	assert.Assert(t, podSecurityViolations("privileged", pod) == nil)
+	assert.DeepEqual(t, podSecurityViolations("baseline", pod), []string{})
+	assert.DeepEqual(t, podSecurityViolations("restricted", pod), []string{
+		`container "database" must drop ALL capabilities`,
+		`container "database" must have a seccomp profile`,
+	})
+
+	t.Run("Restricted", func(t *testing.T) {
+		pod := pod.DeepCopy()
+		pod.SecurityContext.SeccompProfile = &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		}
+		pod.Containers[0].SecurityContext.Capabilities = &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		}
+		assert.DeepEqual(t, podSecurityViolations("restricted", pod), []string{})
+	})
+
+	t.Run("Baseline", func(t *testing.T) {
+		pod := pod.DeepCopy()
+		pod.HostNetwork = true
+		pod.Volumes = append(pod.Volumes, corev1.Volume{
+			Name: "host",
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{Path: "/"},
+			},
+		})
+		pod.Containers[0].SecurityContext.Privileged = initialize.Bool(true)
+		pod.Containers[0].SecurityContext.Capabilities = &corev1.Capabilities{
+			Add: []corev1.Capability{"CHOWN", "SYS_ADMIN"},
+		}
+
+		assert.DeepEqual(t, podSecurityViolations("baseline", pod), []string{
+			`container "database" must not add capability SYS_ADMIN`,
+			`container "database" must not be privileged`,
+			`pod must not share host namespaces`,
+			`volume "host" must not be hostPath`,
+		})
+	})
+}
+
+func TestReconcilePodSecurity(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+
+	sts := &appsv1.StatefulSet{}
+	sts.Spec.Template.Spec.SecurityContext = initialize.RestrictedPodSecurityContext()
+	sts.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name: "database", SecurityContext: initialize.RestrictedSecurityContext(),
+	}}
+	observed := &observedInstances{forCluster: []*Instance{{Name: "hippo-00-aaaa", Runner: sts}}}
+
+	namespace := &corev1.Namespace{}
+	namespace.Name = "ns1"
+	namespace.Labels = map[string]string{labelPodSecurityEnforce: "restricted"}
+
+	recorder := record.NewFakeRecorder(10)
+	r := &Reconciler{
+		Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(namespace).Build(),
+		Recorder: recorder,
+	}
+
+	r.reconcilePodSecurity(ctx, cluster, observed)
+
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionPodSecurityViolation)
+	assert.Assert(t, condition != nil)
+	assert.Assert(t, strings.Contains(condition.Message, `"restricted"`), "got %q", condition.Message)
+	assert.Assert(t, strings.Contains(condition.Message, "must drop ALL capabilities"))
+	assert.Assert(t, strings.Contains(<-recorder.Events, EventPodSecurityViolation))
+
+	// The condition resolves once Pods satisfy the standard.
+	sts.Spec.Template.Spec.SecurityContext.SeccompProfile = &corev1.SeccompProfile{
+		Type: corev1.SeccompProfileTypeRuntimeDefault,
+	}
+	sts.Spec.Template.Spec.Containers[0].SecurityContext.Capabilities = &corev1.Capabilities{
+		Drop: []corev1.Capability{"ALL"},
+	}
+	r.reconcilePodSecurity(ctx, cluster, observed)
+	assert.Assert(t, !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionPodSecurityViolation))
+
+	t.Run("Forbidden", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		forbidden := &Reconciler{
+			Client: forbiddenGetClient{Client: r.Client}, Recorder: recorder,
+		}
+		forbidden.reconcilePodSecurity(ctx, cluster, observed)
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionPodSecurityViolation)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionUnknown)
+		assert.Equal(t, condition.Reason, "Forbidden")
+		assert.Assert(t, strings.Contains(condition.Message, "cluster-wide"), "got %q", condition.Message)
+
+		// The condition resolves once the namespace can be read.
+		r.reconcilePodSecurity(ctx, cluster, observed)
+		condition = meta.FindStatusCondition(cluster.Status.Conditions, ConditionPodSecurityViolation)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionFalse)
+	})
+
+	t.Run("NoNamespace", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Namespace = "missing"
+		r.reconcilePodSecurity(ctx, cluster, observed)
+		assert.Assert(t, !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionPodSecurityViolation))
+	})
+}
+
+// forbiddenGetClient is a client.Client that is not permitted to get anything.
+type forbiddenGetClient struct{ client.Client }
+
+func (forbiddenGetClient) Get(_ context.Context, key client.ObjectKey, _ client.Object) error {
+	return apierrors.NewForbidden(schema.GroupResource{}, key.Name, nil)
+}
@@ -18,12 +18,16 @@ package postgrescluster
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net"
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
@@ -32,11 +36,20 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/crunchydata/postgres-operator/internal/bluegreen"
+	"github.com/crunchydata/postgres-operator/internal/config"
 	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/maintenance"
 	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/pgaudit"
+	"github.com/crunchydata/postgres-operator/internal/pgcron"
+	"github.com/crunchydata/postgres-operator/internal/pgmonitor"
+	"github.com/crunchydata/postgres-operator/internal/pgpartman"
+	"github.com/crunchydata/postgres-operator/internal/pgvector"
+	"github.com/crunchydata/postgres-operator/internal/pki"
 	"github.com/crunchydata/postgres-operator/internal/postgis"
 	"github.com/crunchydata/postgres-operator/internal/postgres"
 	pgpassword "github.com/crunchydata/postgres-operator/internal/postgres/password"
@@ -66,6 +79,13 @@ func (r *Reconciler) generatePostgresUserSecret(
 	intent.Data["port"] = []byte(port)
 	intent.Data["user"] = []byte(username)
 
+	// Populate the Secret with the keys of the Service Binding Specification.
+	// Those that match libpq keywords are above.
+	// - https://github.com/servicebinding/spec#well-known-secret-entries
+	intent.Data["type"] = []byte("postgresql")
+	intent.Data["provider"] = []byte("crunchydata")
+	intent.Data["username"] = []byte(username)
+
 	// Use the existing password and verifier. Generate both when either is missing.
 	if existing != nil {
 		intent.Data["password"] = existing.Data["password"]
@@ -96,12 +116,27 @@ func (r *Reconciler) generatePostgresUserSecret(
 		database := string(spec.Databases[0])
 
 		intent.Data["dbname"] = []byte(database)
+		intent.Data["database"] = []byte(database)
 		intent.Data["uri"] = []byte((&url.URL{
 			Scheme: "postgresql",
 			User:   url.UserPassword(username, string(intent.Data["password"])),
 			Host:   net.JoinHostPort(hostname, port),
 			Path:   database,
 		}).String())
+
+		// The JDBC driver requires a different URI scheme and does not accept
+		// credentials in the authority component.
+		// - https://jdbc.postgresql.org/documentation/use/#connecting-to-the-database
+		query := url.Values{}
+		query.Set("user", username)
+		query.Set("password", string(intent.Data["password"]))
+
+		intent.Data["jdbc-uri"] = []byte((&url.URL{
+			Scheme:   "jdbc:postgresql",
+			Host:     net.JoinHostPort(hostname, port),
+			Path:     database,
+			RawQuery: query.Encode(),
+		}).String())
 	}
 
 	// When PgBouncer is enabled, include values for connecting through it.
@@ -122,6 +157,21 @@ func (r *Reconciler) generatePostgresUserSecret(
 				Host:   net.JoinHostPort(hostname, port),
 				Path:   database,
 			}).String())
+
+			// PgBouncer in transaction mode cannot use the server-side prepared
+			// statements of the JDBC driver, so disable them.
+			// - https://jdbc.postgresql.org/documentation/use/#connection-parameters
+			query := url.Values{}
+			query.Set("user", username)
+			query.Set("password", string(intent.Data["password"]))
+			query.Set("prepareThreshold", "0")
+
+			intent.Data["pgbouncer-jdbc-uri"] = []byte((&url.URL{
+				Scheme:   "jdbc:postgresql",
+				Host:     net.JoinHostPort(hostname, port),
+				Path:     database,
+				RawQuery: query.Encode(),
+			}).String())
 		}
 	}
 
@@ -134,7 +184,12 @@ func (r *Reconciler) generatePostgresUserSecret(
 			naming.LabelPostgresUser: username,
 		})
 
-	err := errors.WithStack(r.setControllerReference(cluster, intent))
+	// A retained Secret has no owner so that its credentials outlive the
+	// cluster. Applying it without a reference removes any that exists.
+	var err error
+	if existing == nil || !retained(existing) {
+		err = errors.WithStack(r.setControllerReference(cluster, intent))
+	}
 
 	return intent, err
 }
@@ -142,6 +197,7 @@ func (r *Reconciler) generatePostgresUserSecret(
 // reconcilePostgresDatabases creates databases inside of PostgreSQL.
 func (r *Reconciler) reconcilePostgresDatabases(
 	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+	rootCA *pki.RootCertificateAuthority,
 ) error {
 	const container = naming.ContainerDatabase
 	var podExecutor postgres.Executor
@@ -154,11 +210,7 @@ func (r *Reconciler) reconcilePostgresDatabases(
 	}
 
 	ctx = logging.NewContext(ctx, logging.FromContext(ctx).WithValues("pod", pod.Name))
-	podExecutor = func(
-		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
-	) error {
-		return r.PodExec(pod.Namespace, pod.Name, container, stdin, stdout, stderr, command...)
-	}
+	podExecutor = r.postgresExecutor(cluster, pod, rootCA)
 
 	// Gather the list of database that should exist in PostgreSQL.
 
@@ -186,12 +238,44 @@ func (r *Reconciler) reconcilePostgresDatabases(
 			}
 		}
 	}
+	specs := make(map[string]v1beta1.PostgresDatabaseSpec, len(cluster.Spec.Databases))
+	for _, database := range cluster.Spec.Databases {
+		databases.Insert(string(database.Name))
+		specs[string(database.Name)] = database
+	}
 
-	// Calculate a hash of the SQL that should be executed in PostgreSQL.
+	// pg_cron is installed into one database, so be sure that it exists.
+	if spec := pgcron.Enabled(cluster); spec != nil {
+		databases.Insert(string(spec.Database))
+	}
+
+	// pg_partman is installed into some databases, so be sure that they exist.
+	databases.Insert(pgpartman.Databases(cluster)...)
+
+	// Databases of spec.users that are not also in spec.databases have only
+	// a name.
+	databaseSpecs := func(names ...string) []v1beta1.PostgresDatabaseSpec {
+		out := make([]v1beta1.PostgresDatabaseSpec, len(names))
+		for i, name := range names {
+			out[i] = v1beta1.PostgresDatabaseSpec{Name: v1beta1.PostgresIdentifier(name)}
+			if spec, ok := specs[name]; ok {
+				out[i] = spec
+			}
+		}
+		return out
+	}
 
-	var pgAuditOK, postgisInstallOK bool
-	create := func(ctx context.Context, exec postgres.Executor) error {
-		if pgAuditOK = pgaudit.EnableInPostgreSQL(ctx, exec) == nil; !pgAuditOK {
+	// Gather the extensions that should be enabled in PostgreSQL. Each might
+	// fail until PostgreSQL restarts.
+
+	type extension struct {
+		name   string
+		enable func(context.Context, postgres.Executor) error
+		failed func()
+	}
+	extensions := []extension{{
+		name: "pgaudit", enable: pgaudit.EnableInPostgreSQL,
+		failed: func() {
 			// pgAudit can only be enabled after its shared library is loaded,
 			// but early versions of PGO do not load it automatically. Assume
 			// that an error here is because the cluster started during one of
@@ -200,34 +284,103 @@ func (r *Reconciler) reconcilePostgresDatabases(
 			// reporting pending restarts, consider returning this error instead.
 			r.Recorder.Event(cluster, corev1.EventTypeWarning, "pgAuditDisabled",
 				"Unable to install pgAudit; try restarting PostgreSQL")
+		},
+	}}
+
+	// Enabling PostGIS extensions is a one-way operation
+	// e.g., you can take a PostgresCluster and turn it into a PostGISCluster,
+	// but you cannot reverse the process, as that would potentially remove an extension
+	// that is being used by some database/tables
+	//
+	// PostGIS is updated by SQL that must execute in the image of its new
+	// version, so wait for the writable instance to run that image.
+	var image string
+	for _, container := range pod.Spec.Containers {
+		if container.Name == naming.ContainerDatabase {
+			image = container.Image
 		}
-
-		// Enabling PostGIS extensions is a one-way operation
-		// e.g., you can take a PostgresCluster and turn it into a PostGISCluster,
-		// but you cannot reverse the process, as that would potentially remove an extension
-		// that is being used by some database/tables
-		if cluster.Spec.PostGISVersion != "" {
-			if postgisInstallOK = postgis.EnableInPostgreSQL(ctx, exec) == nil; !postgisInstallOK {
+	}
+	if version := cluster.Spec.PostGISVersion; version != "" &&
+		image == config.PostgresContainerImage(cluster) {
+		extensions = append(extensions, extension{
+			name: "postgis",
+			enable: func(ctx context.Context, exec postgres.Executor) error {
+				return postgis.EnableInPostgreSQL(ctx, exec, version)
+			},
+			failed: func() {
 				// TODO(benjb): Investigate under what conditions postgis would fail install
 				r.Recorder.Event(cluster, corev1.EventTypeWarning, "PostGISDisabled",
 					"Unable to install PostGIS")
-			}
-		}
+			},
+		})
+	}
 
-		return postgres.CreateDatabasesInPostgreSQL(ctx, exec, databases.List())
+	// pg_stat_statements can only be created after its shared library is
+	// loaded, which requires a restart. Assume that an error here is
+	// because that restart has not happened yet.
+	if pgmonitor.StatementsEnabled(cluster) {
+		extensions = append(extensions, extension{
+			name: "pg_stat_statements", enable: pgmonitor.EnableStatementsInPostgreSQL,
+			failed: func() {
+				r.Recorder.Event(cluster, corev1.EventTypeWarning, "PGStatStatementsDisabled",
+					"Unable to install pg_stat_statements; try restarting PostgreSQL")
+			},
+		})
 	}
 
-	revision, err := safeHash32(func(hasher io.Writer) error {
-		// Discard log messages about executing SQL.
-		return create(logging.NewContext(ctx, logging.Discard()), func(
-			_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
-		) error {
-			_, err := fmt.Fprint(hasher, command)
-			if err == nil && stdin != nil {
-				_, err = io.Copy(hasher, stdin)
-			}
-			return err
+	// pgvector needs no restart. An error here is likely a version that is
+	// not in the image or is older than the one installed.
+	if spec := pgvector.Enabled(cluster); spec != nil {
+		extensions = append(extensions, extension{
+			name: "pgvector",
+			enable: func(ctx context.Context, exec postgres.Executor) error {
+				return pgvector.EnableInPostgreSQL(ctx, exec, spec)
+			},
+			failed: func() {
+				r.Recorder.Event(cluster, corev1.EventTypeWarning, "PGVectorDisabled",
+					"Unable to install or update pgvector; check that the image has its version")
+			},
+		})
+	}
+
+	// pg_partman needs no restart. Its maintenance user may not exist yet, so
+	// it is created here and granted privileges in every database of spec.
+	if spec := pgpartman.Enabled(cluster); spec != nil {
+		extensions = append(extensions, extension{
+			name: "pg_partman",
+			enable: func(ctx context.Context, exec postgres.Executor) error {
+				return pgpartman.EnableInPostgreSQL(ctx, exec, spec, maintenance.User)
+			},
+			failed: func() {
+				r.Recorder.Event(cluster, corev1.EventTypeWarning, "PGPartmanDisabled",
+					"Unable to install or update pg_partman; check that the image has it")
+			},
+		})
+	}
+
+	// pg_cron can only be installed after its shared library is loaded, which
+	// requires a restart. Its jobs are kept in the same SQL so they change
+	// whenever the spec does.
+	if spec := pgcron.Enabled(cluster); spec != nil {
+		extensions = append(extensions, extension{
+			name: "pg_cron",
+			enable: func(ctx context.Context, exec postgres.Executor) error {
+				return pgcron.EnableInPostgreSQL(ctx, exec, spec)
+			},
+			failed: func() {
+				r.Recorder.Event(cluster, corev1.EventTypeWarning, "PGCronDisabled",
+					"Unable to install pg_cron or its jobs; try restarting PostgreSQL")
+			},
 		})
+	}
+
+	// Calculate a hash of the SQL that should be executed in PostgreSQL.
+
+	revision, err := sqlChecksum(ctx, func(ctx context.Context, exec postgres.Executor) error {
+		for _, extension := range extensions {
+			_ = extension.enable(ctx, exec)
+		}
+		return postgres.CreateDatabasesInPostgreSQL(ctx, exec, databaseSpecs(databases.List()...))
 	})
 
 	if err == nil && revision == cluster.Status.DatabaseRevision {
@@ -238,30 +391,193 @@ func (r *Reconciler) reconcilePostgresDatabases(
 		return nil
 	}
 
-	// Apply the necessary SQL and record its hash in cluster.Status. Include
-	// the hash in any log messages.
+	// Something changed. Execute only the SQL of each extension and database
+	// that differs from what was last applied. Include the hash in any log
+	// messages.
 
+	var applied appliedSQL
 	if err == nil {
-		log := logging.FromContext(ctx).WithValues("revision", revision)
-		err = errors.WithStack(create(logging.NewContext(ctx, log), podExecutor))
+		applied, err = r.getAppliedSQL(ctx, cluster)
 	}
-	if err == nil && pgAuditOK && postgisInstallOK {
+	ctx = logging.NewContext(ctx, logging.FromContext(ctx).WithValues("revision", revision))
+
+	enabled := make(map[string]string, len(extensions))
+	extensionsOK := true
+	for i := 0; err == nil && i < len(extensions); i++ {
+		var checksum string
+		if checksum, err = sqlChecksum(ctx, extensions[i].enable); err != nil {
+			break
+		}
+		if checksum != applied.checksum("extension", extensions[i].name) &&
+			extensions[i].enable(ctx, podExecutor) != nil {
+			extensions[i].failed()
+			extensionsOK = false
+			continue
+		}
+		enabled[extensions[i].name] = checksum
+	}
+
+	created := make(map[string]string, databases.Len())
+	var pending []string
+	for _, database := range databases.List() {
+		if err != nil {
+			break
+		}
+		names := databaseSpecs(database)
+		created[database], err = sqlChecksum(ctx, func(ctx context.Context, exec postgres.Executor) error {
+			return postgres.CreateDatabasesInPostgreSQL(ctx, exec, names)
+		})
+		if err == nil && created[database] != applied.checksum("database", database) {
+			pending = append(pending, database)
+		}
+	}
+	if err == nil && len(pending) > 0 {
+		err = errors.WithStack(postgres.CreateDatabasesInPostgreSQL(ctx, podExecutor, databaseSpecs(pending...)))
+	}
+
+	// Record what was applied, even when an extension failed, so that only
+	// the failed extension executes again.
+	if err == nil {
+		applied.replace("extension", enabled)
+		applied.replace("database", created)
+		err = r.setAppliedSQL(ctx, cluster, applied)
+	}
+	if err == nil && extensionsOK {
 		cluster.Status.DatabaseRevision = revision
 	}
 
 	return err
 }
 
+// +kubebuilder:rbac:groups="",resources="configmaps",verbs={get}
+
+// reconcilePostgresDatabaseInitSQL executes the initialization SQL of each
+// database in cluster.Spec.Databases exactly once. The checksum of that SQL is
+// stored in cluster.Status.Databases so it is not executed again.
+func (r *Reconciler) reconcilePostgresDatabaseInitSQL(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) error {
+	// Remember which databases have already been initialized.
+	initialized := make(map[v1beta1.PostgresIdentifier]string)
+	for _, status := range cluster.Status.Databases {
+		initialized[status.Name] = status.InitSQLChecksum
+	}
+
+	// Keep the status of databases that still have initialization SQL. Others
+	// are forgotten so their SQL can be executed when it is specified again.
+	var status []v1beta1.PostgresDatabaseStatus
+	var pending []v1beta1.PostgresDatabaseSpec
+	for _, database := range cluster.Spec.Databases {
+		if database.InitSQLConfigMapRef == nil {
+			continue
+		}
+		if checksum, ok := initialized[database.Name]; ok {
+			status = append(status, v1beta1.PostgresDatabaseStatus{
+				Name: database.Name, InitSQLChecksum: checksum,
+			})
+		} else {
+			pending = append(pending, database)
+		}
+	}
+	cluster.Status.Databases = status
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// Find the PostgreSQL instance that can execute SQL that writes system
+	// catalogs. When there is none, return early.
+	pod, _ := instances.writablePod(naming.ContainerDatabase)
+	if pod == nil {
+		return nil
+	}
+
+	ctx = logging.NewContext(ctx, logging.FromContext(ctx).WithValues("pod", pod.Name))
+	podExecutor := postgres.Executor(func(
+		ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		return r.PodExec(ctx, pod.Namespace, pod.Name, naming.ContainerDatabase,
+			stdin, stdout, stderr, command...)
+	})
+
+	var err error
+	for _, database := range pending {
+		ref := database.InitSQLConfigMapRef
+		configmap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace, Name: ref.Name,
+		}}
+
+		err = errors.WithStack(
+			r.Client.Get(ctx, client.ObjectKeyFromObject(configmap), configmap))
+
+		data, ok := configmap.Data[ref.Key]
+		if err == nil && !ok {
+			err = errors.Errorf("ConfigMap %q did not contain expected key: %s", ref.Name, ref.Key)
+		}
+		if err != nil {
+			if ref.Optional != nil && *ref.Optional {
+				err = nil
+				continue
+			}
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "InvalidDatabaseInitSQL",
+				"Unable to initialize database %q: %v", database.Name, err)
+			return err
+		}
+
+		var checksum string
+		checksum, err = safeHash32(func(hasher io.Writer) error {
+			_, err := io.WriteString(hasher, data)
+			return err
+		})
+
+		if err == nil {
+			log := logging.FromContext(ctx).WithValues(
+				"database", database.Name, "configmap", ref.Name, "key", ref.Key)
+
+			var stdout, stderr string
+			stdout, stderr, err = podExecutor.ExecInDatabase(logging.NewContext(ctx, log),
+				string(database.Name), strings.NewReader(data),
+				map[string]string{
+					"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+				})
+
+			log.V(1).Info("executed database init SQL", "stdout", stdout, "stderr", stderr)
+			err = errors.WithStack(err)
+		}
+		if err != nil {
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "DatabaseInitSQLFailed",
+				"Unable to initialize database %q: %v", database.Name, err)
+			return err
+		}
+
+		cluster.Status.Databases = append(cluster.Status.Databases,
+			v1beta1.PostgresDatabaseStatus{Name: database.Name, InitSQLChecksum: checksum})
+	}
+
+	return err
+}
+
 // reconcilePostgresUsers writes the objects necessary to manage users and their
-// passwords in PostgreSQL.
+// passwords in PostgreSQL. It requeues while any client certificate is waiting
+// to be issued.
 func (r *Reconciler) reconcilePostgresUsers(
 	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
-) error {
-	users, secrets, err := r.reconcilePostgresUserSecrets(ctx, cluster)
+	rootCA *pki.RootCertificateAuthority,
+) (reconcile.Result, error) {
+	var result reconcile.Result
+
+	users, secrets, err := r.reconcilePostgresUserSecrets(ctx, cluster, rootCA)
 	if err == nil {
-		err = r.reconcilePostgresUsersInPostgreSQL(ctx, cluster, instances, users, secrets)
+		err = r.reconcilePostgresUsersInPostgreSQL(ctx, cluster, instances, rootCA, users, secrets)
 	}
-	return err
+
+	for _, username := range postgres.ClientCertificateUsers(cluster) {
+		if secret, ok := secrets[username]; ok && len(secret.Data[clusterCertFile]) == 0 {
+			result.RequeueAfter = 10 * time.Second
+		}
+	}
+
+	return result, err
 }
 
 // +kubebuilder:rbac:groups="",resources="secrets",verbs={list}
@@ -269,10 +585,11 @@ func (r *Reconciler) reconcilePostgresUsers(
 
 // reconcilePostgresUserSecrets writes Secrets for the PostgreSQL users
 // specified in cluster and deletes existing Secrets that are not specified.
-// It returns the user specifications it acted on (because defaults) and the
-// Secrets it wrote.
+// Users that authenticate with client certificates also get one in their
+// Secret. It returns the user specifications it acted on (because defaults)
+// and the Secrets it wrote.
 func (r *Reconciler) reconcilePostgresUserSecrets(
-	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	ctx context.Context, cluster *v1beta1.PostgresCluster, rootCA *pki.RootCertificateAuthority,
 ) (
 	[]v1beta1.PostgresUserSpec, map[string]*corev1.Secret, error,
 ) {
@@ -355,6 +672,15 @@ func (r *Reconciler) reconcilePostgresUserSecrets(
 		}
 	}
 
+	// Some users authenticate with client certificates issued by the cluster
+	// certificate authority or by cert-manager.
+	var issuerRef *v1beta1.CertManagerIssuerReference
+	certificateUsers := sets.NewString(postgres.ClientCertificateUsers(cluster)...)
+	requested := sets.NewString()
+	if certificateUsers.Len() > 0 {
+		issuerRef = cluster.Spec.Authentication.ClientCertificates.IssuerRef
+	}
+
 	// Reconcile each PostgreSQL user in the cluster spec.
 	for userName, user := range userSpecs {
 		secret := userSecrets[userName]
@@ -365,14 +691,47 @@ func (r *Reconciler) reconcilePostgresUserSecrets(
 			secret = defaultSecret
 		}
 
+		existing := secret
+		if existing == nil && err == nil && bluegreen.Enabled(cluster) {
+			// The green cluster of a blue/green switch takes over the clients
+			// of blue, so start with the passwords of blue.
+			existing, err = r.blueGreenUserSecret(ctx, cluster, userName)
+		}
+
 		if err == nil {
-			userSecrets[userName], err = r.generatePostgresUserSecret(cluster, user, secret)
+			userSecrets[userName], err = r.generatePostgresUserSecret(cluster, user, existing)
+		}
+		if err == nil && certificateUsers.Has(userName) {
+			if issuerRef == nil {
+				err = r.postgresUserCertificate(ctx, cluster, secret, userSecrets[userName], rootCA)
+			} else {
+				err = r.postgresUserCertificateRequest(ctx, cluster, userSecrets[userName])
+				requested.Insert(userName)
+			}
 		}
 		if err == nil {
 			err = errors.WithStack(r.apply(ctx, userSecrets[userName]))
 		}
 	}
 
+	// Workloads bind to the Secret of the first user.
+	// - https://github.com/servicebinding/spec#provisioned-service
+	if err == nil {
+		cluster.Status.Binding = nil
+	}
+	if err == nil && len(specUsers) > 0 {
+		if secret, ok := userSecrets[string(specUsers[0].Name)]; ok {
+			cluster.Status.Binding = &v1beta1.PostgresClusterBinding{Name: secret.Name}
+		}
+	}
+
+	// Delete cert-manager Certificates for users that no longer need them.
+	// Those remaining when client certificates are disabled entirely are
+	// deleted along with the cluster.
+	if err == nil && certificateUsers.Len() > 0 {
+		err = r.deletePostgresUserCertificateRequests(ctx, cluster, requested)
+	}
+
 	return specUsers, userSecrets, err
 }
 
@@ -380,6 +739,7 @@ func (r *Reconciler) reconcilePostgresUserSecrets(
 // sets their options and database access as specified.
 func (r *Reconciler) reconcilePostgresUsersInPostgreSQL(
 	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+	rootCA *pki.RootCertificateAuthority,
 	specUsers []v1beta1.PostgresUserSpec, userSecrets map[string]*corev1.Secret,
 ) error {
 	const container = naming.ContainerDatabase
@@ -399,12 +759,7 @@ func (r *Reconciler) reconcilePostgresUsersInPostgreSQL(
 		if running && known && len(instance.Pods) > 0 {
 			pod := instance.Pods[0]
 			ctx = logging.NewContext(ctx, logging.FromContext(ctx).WithValues("pod", pod.Name))
-
-			podExecutor = func(
-				_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
-			) error {
-				return r.PodExec(pod.Namespace, pod.Name, container, stdin, stdout, stderr, command...)
-			}
+			podExecutor = r.postgresExecutor(cluster, pod, rootCA)
 			break
 		}
 	}
@@ -419,21 +774,36 @@ func (r *Reconciler) reconcilePostgresUsersInPostgreSQL(
 		verifiers[userName] = string(userSecrets[userName].Data["verifier"])
 	}
 
-	write := func(ctx context.Context, exec postgres.Executor) error {
-		return postgres.WriteUsersInPostgreSQL(ctx, exec, specUsers, verifiers)
-	}
+	writeUsers := func(users []v1beta1.PostgresUserSpec) func(context.Context, postgres.Executor) error {
+		return func(ctx context.Context, exec postgres.Executor) error {
+			err := postgres.WriteUsersInPostgreSQL(ctx, exec, users, verifiers)
+
+			// PostgreSQL v15 no longer allows every user to create objects in
+			// the "public" schema. Grant that to users in their databases.
+			if err == nil && cluster.Spec.PostgresVersion >= 15 {
+				err = postgres.GrantPublicSchemaInPostgreSQL(ctx, exec, users)
+			}
 
-	revision, err := safeHash32(func(hasher io.Writer) error {
-		// Discard log messages about executing SQL.
-		return write(logging.NewContext(ctx, logging.Discard()), func(
-			_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
-		) error {
-			_, err := fmt.Fprint(hasher, command)
-			if err == nil && stdin != nil {
-				_, err = io.Copy(hasher, stdin)
+			// Objects in a database belong to the users that specify it, so
+			// privileges depend on every user in the spec.
+			if err == nil {
+				err = postgres.GrantPrivilegesInPostgreSQL(ctx, exec, users, specUsers)
 			}
 			return err
-		})
+		}
+	}
+
+	// The operator user exists only when the operator connects over the network.
+	writeOperator := func(ctx context.Context, exec postgres.Executor) error {
+		return postgres.WriteOperatorUserInPostgreSQL(ctx, exec, r.ExecPolicy.SQLOverNetwork)
+	}
+
+	revision, err := sqlChecksum(ctx, func(ctx context.Context, exec postgres.Executor) error {
+		err := writeOperator(ctx, exec)
+		if err == nil {
+			err = writeUsers(specUsers)(ctx, exec)
+		}
+		return err
 	})
 
 	if err == nil && revision == cluster.Status.UsersRevision {
@@ -444,12 +814,60 @@ func (r *Reconciler) reconcilePostgresUsersInPostgreSQL(
 		return nil
 	}
 
-	// Apply the necessary SQL and record its hash in cluster.Status. Include
-	// the hash in any log messages.
+	// Something changed. Write only the users that differ from what was last
+	// applied. Include the hash in any log messages.
+
+	var applied appliedSQL
+	if err == nil {
+		applied, err = r.getAppliedSQL(ctx, cluster)
+	}
+	ctx = logging.NewContext(ctx, logging.FromContext(ctx).WithValues("revision", revision))
+
+	var operator string
+	if err == nil {
+		operator, err = sqlChecksum(ctx, writeOperator)
+	}
+	if err == nil && operator != applied.checksum("operator", postgres.OperatorUser) {
+		err = errors.WithStack(writeOperator(ctx, podExecutor))
+	}
+
+	written := make(map[string]string, len(specUsers))
+	var pending []v1beta1.PostgresUserSpec
+	for i := range specUsers {
+		if err != nil {
+			break
+		}
+		userName := string(specUsers[i].Name)
+		written[userName], err = sqlChecksum(ctx, writeUsers(specUsers[i:i+1]))
+		if err == nil && written[userName] != applied.checksum("user", userName) {
+			pending = append(pending, specUsers[i])
+		}
+	}
+
+	// Membership is granted only in roles that exist. Write users that are
+	// members of a pending user again so that they join it once it exists.
+	if err == nil && len(pending) > 0 {
+		names := sets.NewString()
+		for i := range pending {
+			names.Insert(string(pending[i].Name))
+		}
+		for i := range specUsers {
+			for _, role := range specUsers[i].MemberOf {
+				if names.Has(string(role)) && !names.Has(string(specUsers[i].Name)) {
+					names.Insert(string(specUsers[i].Name))
+					pending = append(pending, specUsers[i])
+				}
+			}
+		}
+	}
+	if err == nil && len(pending) > 0 {
+		err = errors.WithStack(writeUsers(pending)(ctx, podExecutor))
+	}
 
 	if err == nil {
-		log := logging.FromContext(ctx).WithValues("revision", revision)
-		err = errors.WithStack(write(logging.NewContext(ctx, log), podExecutor))
+		applied.replace("operator", map[string]string{postgres.OperatorUser: operator})
+		applied.replace("user", written)
+		err = r.setAppliedSQL(ctx, cluster, applied)
 	}
 	if err == nil {
 		cluster.Status.UsersRevision = revision
@@ -458,15 +876,164 @@ func (r *Reconciler) reconcilePostgresUsersInPostgreSQL(
 	return err
 }
 
+// postgresExecutor returns an Executor that runs SQL as a superuser in the
+// PostgreSQL of pod. It connects over the pod network as postgres.OperatorUser
+// with a client certificate signed by rootCA. When that is disabled or not
+// possible, it calls "psql" in the database container instead.
+func (r *Reconciler) postgresExecutor(
+	cluster *v1beta1.PostgresCluster, pod *corev1.Pod, rootCA *pki.RootCertificateAuthority,
+) postgres.Executor {
+	podExecutor := postgres.Executor(func(
+		ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		return r.PodExec(ctx, pod.Namespace, pod.Name, naming.ContainerDatabase,
+			stdin, stdout, stderr, command...)
+	})
+
+	// PostgreSQL trusts only the certificate authority of a custom TLS Secret.
+	if !r.ExecPolicy.SQLOverNetwork || cluster.Spec.CustomTLSSecret != nil ||
+		rootCA == nil || pod.Status.PodIP == "" {
+		return podExecutor
+	}
+
+	return func(
+		ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		leaf, err := r.operatorCertificate(rootCA)
+		if err != nil {
+			logging.FromContext(ctx).Error(err, "Unable to issue operator certificate")
+			return podExecutor(ctx, stdin, stdout, stderr, command...)
+		}
+
+		return postgres.NetworkExecutor{
+			Address: net.JoinHostPort(pod.Status.PodIP, fmt.Sprint(*cluster.Spec.Port)),
+			TLS:     postgresClientTLS(leaf, rootCA),
+			User:    postgres.OperatorUser,
+			Timeout: r.ExecPolicy.Timeout,
+		}.Executor(podExecutor)(ctx, stdin, stdout, stderr, command...)
+	}
+}
+
+// operatorCertificateRenewal is how long before it expires that the client
+// certificate of postgres.OperatorUser is replaced.
+const operatorCertificateRenewal = 24 * time.Hour
+
+// certificateCache keeps leaf certificates by root certificate authority.
+type certificateCache struct {
+	sync.Mutex
+	leaves map[string]*pki.LeafCertificate
+}
+
+// operatorCertificate returns a client certificate for postgres.OperatorUser
+// signed by rootCA. Generating a key and signature is expensive, so each is
+// issued once per root certificate authority and kept until it nears expiry
+// or the algorithm of pki.SetAlgorithm changes.
+func (r *Reconciler) operatorCertificate(
+	rootCA *pki.RootCertificateAuthority,
+) (*pki.LeafCertificate, error) {
+	leaf := pki.NewLeafCertificate(postgres.OperatorUser, nil, nil)
+	cache := r.operatorCertificates
+	if cache == nil {
+		return leaf, leaf.Generate(rootCA)
+	}
+
+	cache.Lock()
+	defer cache.Unlock()
+
+	// Keep certificates that can still be used; there is usually one.
+	for key, cached := range cache.leaves {
+		certificate, err := x509.ParseCertificate(cached.Certificate.Certificate)
+		if err != nil || cached.Algorithm != leaf.Algorithm ||
+			time.Until(certificate.NotAfter) < operatorCertificateRenewal {
+			delete(cache.leaves, key)
+		}
+	}
+
+	key := string(rootCA.Certificate.Certificate)
+	if cached, ok := cache.leaves[key]; ok {
+		return cached, nil
+	}
+
+	if err := leaf.Generate(rootCA); err != nil {
+		return nil, err
+	}
+	if cache.leaves == nil {
+		cache.leaves = make(map[string]*pki.LeafCertificate)
+	}
+	cache.leaves[key] = leaf
+	return leaf, nil
+}
+
+// postgresClientTLS returns a TLS configuration that presents leaf and
+// verifies that the server certificate is signed by rootCA, like "sslmode"
+// verify-ca. Instance certificates do not name Pod IP addresses.
+// - https://www.postgresql.org/docs/current/libpq-ssl.html
+func postgresClientTLS(leaf *pki.LeafCertificate, rootCA *pki.RootCertificateAuthority) *tls.Config {
+	roots := x509.NewCertPool()
+	if root, err := x509.ParseCertificate(rootCA.Certificate.Certificate); err == nil {
+		roots.AddCert(root)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{leaf.Certificate.Certificate},
+			PrivateKey:  leaf.PrivateKey.PrivateKey,
+		}},
+		MinVersion: tls.VersionTLS12,
+
+		// Skip the default verification, which compares names, and verify
+		// only the chain of certificates.
+		InsecureSkipVerify: true, // #nosec G402 VerifyPeerCertificate verifies the chain.
+		VerifyPeerCertificate: func(raw [][]byte, _ [][]*x509.Certificate) error {
+			certificates := make([]*x509.Certificate, len(raw))
+			for i := range raw {
+				var err error
+				if certificates[i], err = x509.ParseCertificate(raw[i]); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			if len(certificates) == 0 {
+				return errors.New("server did not present a certificate")
+			}
+
+			intermediates := x509.NewCertPool()
+			for _, certificate := range certificates[1:] {
+				intermediates.AddCert(certificate)
+			}
+			_, err := certificates[0].Verify(x509.VerifyOptions{
+				Roots: roots, Intermediates: intermediates,
+			})
+			return errors.WithStack(err)
+		},
+	}
+}
+
+// volumesRetainedWhenDeleted returns whether or not the volumes of instances
+// in set should outlive their PostgresCluster.
+func volumesRetainedWhenDeleted(set *v1beta1.PostgresInstanceSetSpec) bool {
+	return set != nil && set.PersistentVolumeClaimRetentionPolicy != nil &&
+		set.PersistentVolumeClaimRetentionPolicy.WhenDeleted == "Retain"
+}
+
+// volumesRetainedWhenScaled returns whether or not the volumes of instances
+// in set should be kept when set has fewer replicas.
+func volumesRetainedWhenScaled(set *v1beta1.PostgresInstanceSetSpec) bool {
+	return set != nil && set.PersistentVolumeClaimRetentionPolicy != nil &&
+		set.PersistentVolumeClaimRetentionPolicy.WhenScaled == "Retain"
+}
+
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=create;patch
 
 // reconcilePostgresDataVolume writes the PersistentVolumeClaim for instance's
-// PostgreSQL data volume.
+// PostgreSQL data volume. It returns nil when the data volume is ephemeral.
 func (r *Reconciler) reconcilePostgresDataVolume(
 	ctx context.Context, cluster *v1beta1.PostgresCluster,
 	instanceSpec *v1beta1.PostgresInstanceSetSpec, instance *appsv1.StatefulSet,
 	clusterVolumes []corev1.PersistentVolumeClaim,
 ) (*corev1.PersistentVolumeClaim, error) {
+	if instanceSpec.Ephemeral != nil {
+		return nil, nil
+	}
 
 	labelMap := map[string]string{
 		naming.LabelCluster:     cluster.Name,
@@ -492,7 +1059,10 @@ func (r *Reconciler) reconcilePostgresDataVolume(
 
 	pvc.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"))
 
-	err = errors.WithStack(r.setControllerReference(cluster, pvc))
+	// Volumes retained after the cluster is deleted have no owner reference.
+	if !volumesRetainedWhenDeleted(instanceSpec) {
+		err = errors.WithStack(r.setControllerReference(cluster, pvc))
+	}
 
 	pvc.Annotations = naming.Merge(
 		cluster.Spec.Metadata.GetAnnotationsOrNil(),
@@ -506,6 +1076,14 @@ func (r *Reconciler) reconcilePostgresDataVolume(
 
 	pvc.Spec = instanceSpec.DataVolumeClaimSpec
 
+	// The storage class of a PVC cannot change. Keep the class of an existing
+	// PVC until Reconciler.migrateInstanceStorage replaces its instance.
+	for i := range clusterVolumes {
+		if existingPVCName != "" && clusterVolumes[i].Name == existingPVCName {
+			pvc.Spec.StorageClassName = clusterVolumes[i].Spec.StorageClassName
+		}
+	}
+
 	if err == nil {
 		err = r.handlePersistentVolumeClaimError(cluster,
 			errors.WithStack(r.apply(ctx, pvc)))
@@ -579,7 +1157,7 @@ func (r *Reconciler) reconcilePostgresWALVolume(
 				// This assumes that $PGDATA matches the configured PostgreSQL "data_directory".
 				var stdout bytes.Buffer
 				err = errors.WithStack(r.PodExec(
-					observed.Pods[0].Namespace, observed.Pods[0].Name, naming.ContainerDatabase,
+					ctx, observed.Pods[0].Namespace, observed.Pods[0].Name, naming.ContainerDatabase,
 					nil, &stdout, nil, "bash", "-ceu", "--", `exec realpath "${PGDATA}/pg_wal"`))
 
 				walDirectory = strings.TrimRight(stdout.String(), "\n")
@@ -595,7 +1173,10 @@ func (r *Reconciler) reconcilePostgresWALVolume(
 		return pvc, err
 	}
 
-	err = errors.WithStack(r.setControllerReference(cluster, pvc))
+	// Volumes retained after the cluster is deleted have no owner reference.
+	if !volumesRetainedWhenDeleted(instanceSpec) {
+		err = errors.WithStack(r.setControllerReference(cluster, pvc))
+	}
 
 	pvc.Annotations = naming.Merge(
 		cluster.Spec.Metadata.GetAnnotationsOrNil(),
@@ -683,9 +1264,9 @@ func (r *Reconciler) reconcileDatabaseInitSQL(ctx context.Context,
 	}
 
 	podExecutor = func(
-		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
 	) error {
-		return r.PodExec(pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+		return r.PodExec(ctx, pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
 	}
 
 	// A writable pod executor has been found and we have the sql provided by
@@ -20,7 +20,12 @@ package postgrescluster
 
 import (
 	"context"
+	"crypto/tls"
 	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -32,11 +37,16 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/yaml"
 
 	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/pki"
 	"github.com/crunchydata/postgres-operator/internal/postgres"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -69,6 +79,23 @@ func TestGeneratePostgresUserSecret(t *testing.T) {
 		}
 	})
 
+	t.Run("Retained", func(t *testing.T) {
+		existing := &corev1.Secret{Data: map[string][]byte{
+			"password": []byte("kept"), "verifier": []byte("kept-verifier"),
+		}}
+		existing.Annotations = map[string]string{
+			"postgres-operator.crunchydata.com/retain": "true",
+		}
+
+		secret, err := reconciler.generatePostgresUserSecret(cluster, spec, existing)
+		assert.NilError(t, err)
+
+		if assert.Check(t, secret != nil) {
+			assert.Equal(t, len(secret.OwnerReferences), 0)
+			assert.Equal(t, string(secret.Data["password"]), "kept")
+		}
+	})
+
 	t.Run("Primary", func(t *testing.T) {
 		secret, err := reconciler.generatePostgresUserSecret(cluster, spec, nil)
 		assert.NilError(t, err)
@@ -80,6 +107,17 @@ func TestGeneratePostgresUserSecret(t *testing.T) {
 		}
 	})
 
+	t.Run("ServiceBinding", func(t *testing.T) {
+		secret, err := reconciler.generatePostgresUserSecret(cluster, spec, nil)
+		assert.NilError(t, err)
+
+		if assert.Check(t, secret != nil) {
+			assert.Equal(t, string(secret.Data["type"]), "postgresql")
+			assert.Equal(t, string(secret.Data["provider"]), "crunchydata")
+			assert.Equal(t, string(secret.Data["username"]), "some-user-name")
+		}
+	})
+
 	t.Run("Password", func(t *testing.T) {
 		// Generated when no existing Secret.
 		secret, err := reconciler.generatePostgresUserSecret(cluster, spec, nil)
@@ -124,6 +162,8 @@ func TestGeneratePostgresUserSecret(t *testing.T) {
 		if assert.Check(t, secret != nil) {
 			assert.Assert(t, secret.Data["dbname"] == nil)
 			assert.Assert(t, secret.Data["uri"] == nil)
+			assert.Assert(t, secret.Data["jdbc-uri"] == nil)
+			assert.Assert(t, secret.Data["database"] == nil)
 		}
 
 		// Present when specified.
@@ -134,8 +174,12 @@ func TestGeneratePostgresUserSecret(t *testing.T) {
 
 		if assert.Check(t, secret != nil) {
 			assert.Equal(t, string(secret.Data["dbname"]), "db1")
+			assert.Equal(t, string(secret.Data["database"]), "db1")
 			assert.Assert(t, cmp.Regexp(`postgresql://some-user-name:[^@]+@hippo2-primary.ns1.svc:9999/db1`,
 				string(secret.Data["uri"])))
+			assert.Assert(t, cmp.Regexp(`^jdbc:postgresql://hippo2-primary.ns1.svc:9999/db1`+
+				`[?]password=[^&]+&user=some-user-name$`,
+				string(secret.Data["jdbc-uri"])))
 		}
 
 		// Only the first in the list.
@@ -163,6 +207,7 @@ func TestGeneratePostgresUserSecret(t *testing.T) {
 			assert.Equal(t, string(secret.Data["pgbouncer-host"]), "hippo2-pgbouncer.ns1.svc")
 			assert.Equal(t, string(secret.Data["pgbouncer-port"]), "10220")
 			assert.Assert(t, secret.Data["pgbouncer-uri"] == nil)
+			assert.Assert(t, secret.Data["pgbouncer-jdbc-uri"] == nil)
 		}
 
 		// Includes a URI when possible.
@@ -175,6 +220,9 @@ func TestGeneratePostgresUserSecret(t *testing.T) {
 		if assert.Check(t, secret != nil) {
 			assert.Assert(t, cmp.Regexp(`postgresql://some-user-name:[^@]+@hippo2-pgbouncer.ns1.svc:10220/yes`,
 				string(secret.Data["pgbouncer-uri"])))
+			assert.Assert(t, cmp.Regexp(`^jdbc:postgresql://hippo2-pgbouncer.ns1.svc:10220/yes`+
+				`[?]password=[^&]+&prepareThreshold=0&user=some-user-name$`,
+				string(secret.Data["pgbouncer-jdbc-uri"])))
 		}
 	})
 }
@@ -235,6 +283,15 @@ volumeMode: Filesystem
 		`))
 	})
 
+	t.Run("EphemeralDataVolume", func(t *testing.T) {
+		spec := spec.DeepCopy()
+		spec.Ephemeral = &v1beta1.PostgresEphemeralVolumeSpec{}
+
+		pvc, err := reconciler.reconcilePostgresDataVolume(ctx, cluster, spec, instance, nil)
+		assert.NilError(t, err)
+		assert.Assert(t, pvc == nil)
+	})
+
 	t.Run("WALVolume", func(t *testing.T) {
 		observed := &Instance{}
 
@@ -303,7 +360,7 @@ volumeMode: Filesystem
 
 					expected := errors.New("flop")
 					reconciler.PodExec = func(
-						namespace, pod, container string,
+						_ context.Context, namespace, pod, container string,
 						_ io.Reader, _, _ io.Writer, command ...string,
 					) error {
 						assert.Equal(t, namespace, "pod-ns")
@@ -320,7 +377,7 @@ volumeMode: Filesystem
 
 					// Files are in the wrong place; expect no changes to the PVC.
 					reconciler.PodExec = func(
-						_, _, _ string, _ io.Reader, stdout, _ io.Writer, _ ...string,
+						_ context.Context, _, _, _ string, _ io.Reader, stdout, _ io.Writer, _ ...string,
 					) error {
 						assert.Assert(t, stdout != nil)
 						_, err := stdout.Write([]byte("some-place\n"))
@@ -343,7 +400,7 @@ volumeMode: Filesystem
 						new(corev1.ContainerStateRunning)
 
 					reconciler.PodExec = func(
-						_, _, _ string, _ io.Reader, stdout, _ io.Writer, _ ...string,
+						_ context.Context, _, _, _ string, _ io.Reader, stdout, _ io.Writer, _ ...string,
 					) error {
 						assert.Assert(t, stdout != nil)
 						_, err := stdout.Write([]byte(postgres.WALDirectory(cluster, spec) + "\n"))
@@ -383,7 +440,7 @@ func TestReconcileDatabaseInitSQL(t *testing.T) {
 
 		// Overwrite the PodExec function with a check to ensure the exec
 		// call would have been made
-		PodExec: func(namespace, pod, container string, stdin io.Reader, stdout,
+		PodExec: func(_ context.Context, namespace, pod, container string, stdin io.Reader, stdout,
 			stderr io.Writer, command ...string) error {
 			called = true
 			return nil
@@ -511,7 +568,7 @@ func TestReconcileDatabaseInitSQLConfigMap(t *testing.T) {
 
 		// Overwrite the PodExec function with a check to ensure the exec
 		// call would have been made
-		PodExec: func(namespace, pod, container string, stdin io.Reader, stdout,
+		PodExec: func(_ context.Context, namespace, pod, container string, stdin io.Reader, stdout,
 			stderr io.Writer, command ...string) error {
 			called = true
 			return nil
@@ -614,3 +671,237 @@ func TestReconcileDatabaseInitSQLConfigMap(t *testing.T) {
 		assert.Assert(t, called)
 	})
 }
+
+func TestReconcilePostgresDatabaseInitSQL(t *testing.T) {
+	ctx := context.Background()
+
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	configmap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "schema"},
+		Data:       map[string]string{"app.sql": "CREATE TABLE things (id int);"},
+	}
+
+	var executed []string
+	reconciler := &Reconciler{
+		Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(configmap).Build(),
+		Recorder: record.NewFakeRecorder(10),
+		PodExec: func(_ context.Context, namespace, pod, container string, stdin io.Reader, stdout,
+			stderr io.Writer, command ...string) error {
+			b, err := io.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Equal(t, string(b), "CREATE TABLE things (id int);")
+
+			// The database name is the first argument to the script.
+			executed = append(executed, command[5])
+			return nil
+		},
+	}
+
+	instances := &observedInstances{forCluster: []*Instance{{
+		Name: "daisy",
+		Pods: []*corev1.Pod{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "daisy-pod",
+				Annotations: map[string]string{"status": `{"role":"master"}`},
+			},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  naming.ContainerDatabase,
+					State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				}},
+			},
+		}},
+		Runner: &appsv1.StatefulSet{},
+	}}}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.Databases = []v1beta1.PostgresDatabaseSpec{
+		{Name: "plain"},
+		{Name: "app", InitSQLConfigMapRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "schema"},
+			Key:                  "app.sql",
+		}},
+	}
+
+	assert.NilError(t, reconciler.reconcilePostgresDatabaseInitSQL(ctx, cluster, instances))
+	assert.DeepEqual(t, executed, []string{"app"})
+	assert.Equal(t, len(cluster.Status.Databases), 1)
+	assert.Equal(t, cluster.Status.Databases[0].Name, v1beta1.PostgresIdentifier("app"))
+	assert.Assert(t, cluster.Status.Databases[0].InitSQLChecksum != "")
+
+	// The SQL is executed only once.
+	assert.NilError(t, reconciler.reconcilePostgresDatabaseInitSQL(ctx, cluster, instances))
+	assert.DeepEqual(t, executed, []string{"app"})
+
+	t.Run("Missing", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Databases = append(cluster.Spec.Databases, v1beta1.PostgresDatabaseSpec{
+			Name: "other", InitSQLConfigMapRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "schema"},
+				Key:                  "missing.sql",
+			},
+		})
+
+		err := reconciler.reconcilePostgresDatabaseInitSQL(ctx, cluster, instances)
+		assert.ErrorContains(t, err, "missing.sql")
+		assert.Equal(t, len(cluster.Status.Databases), 1)
+
+		cluster.Spec.Databases[2].InitSQLConfigMapRef.Optional = initialize.Bool(true)
+		assert.NilError(t, reconciler.reconcilePostgresDatabaseInitSQL(ctx, cluster, instances))
+		assert.Equal(t, len(cluster.Status.Databases), 1)
+	})
+
+	t.Run("Removed", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Databases[1].InitSQLConfigMapRef = nil
+
+		assert.NilError(t, reconciler.reconcilePostgresDatabaseInitSQL(ctx, cluster, instances))
+		assert.Assert(t, cluster.Status.Databases == nil)
+	})
+}
+
+func TestPostgresExecutor(t *testing.T) {
+	ctx := context.Background()
+	root := pki.NewRootCertificateAuthority()
+	assert.NilError(t, root.Generate())
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Port = initialize.Int32(5432)
+
+	pod := new(corev1.Pod)
+	pod.Namespace, pod.Name = "ns1", "pod1"
+
+	var calls []string
+	r := &Reconciler{PodExec: func(
+		_ context.Context, namespace, pod, container string,
+		stdin io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		b, err := ioutil.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Equal(t, namespace+"/"+pod+"/"+container, "ns1/pod1/database")
+		calls = append(calls, string(b))
+		return nil
+	}}
+
+	// Disabled by default.
+	_, _, err := r.postgresExecutor(cluster, pod, root).Exec(ctx, strings.NewReader("SELECT 1;"), nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, calls, []string{"SELECT 1;"})
+
+	// Commands fall back when PostgreSQL cannot be reached.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	t.Cleanup(func() { _ = listener.Close() })
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	number, err := strconv.Atoi(port)
+	assert.NilError(t, err)
+	pod.Status.PodIP = host
+	cluster.Spec.Port = initialize.Int32(int32(number))
+	r.ExecPolicy.SQLOverNetwork = true
+
+	_, _, err = r.postgresExecutor(cluster, pod, root).Exec(ctx, strings.NewReader("SELECT 2;"), nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, calls, []string{"SELECT 1;", "SELECT 2;"})
+
+	// Clusters with custom TLS do not connect at all.
+	cluster.Spec.CustomTLSSecret = &corev1.SecretProjection{}
+	executor := r.postgresExecutor(cluster, pod, root)
+	_ = listener.Close()
+	_, _, err = executor.Exec(ctx, strings.NewReader("SELECT 3;"), nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, calls, []string{"SELECT 1;", "SELECT 2;", "SELECT 3;"})
+}
+
+func TestOperatorCertificate(t *testing.T) {
+	root := pki.NewRootCertificateAuthority()
+	assert.NilError(t, root.Generate())
+	other := pki.NewRootCertificateAuthority()
+	assert.NilError(t, other.Generate())
+
+	// Without a cache, every call issues a certificate.
+	r := &Reconciler{}
+	first, err := r.operatorCertificate(root)
+	assert.NilError(t, err)
+	second, err := r.operatorCertificate(root)
+	assert.NilError(t, err)
+	assert.Assert(t, first != second)
+
+	// With a cache, each root certificate authority issues one.
+	r.operatorCertificates = new(certificateCache)
+	first, err = r.operatorCertificate(root)
+	assert.NilError(t, err)
+	assert.Equal(t, first.CommonName, postgres.OperatorUser)
+
+	second, err = r.operatorCertificate(root)
+	assert.NilError(t, err)
+	assert.Assert(t, first == second)
+
+	third, err := r.operatorCertificate(other)
+	assert.NilError(t, err)
+	assert.Assert(t, third != first)
+	assert.Equal(t, len(r.operatorCertificates.leaves), 2)
+
+	// Certificates of another algorithm are replaced.
+	first.Algorithm = pki.Algorithm{Key: "RSA-2048"}
+	second, err = r.operatorCertificate(root)
+	assert.NilError(t, err)
+	assert.Assert(t, first != second)
+}
+
+func TestPostgresClientTLS(t *testing.T) {
+	handshake := func(server, client *tls.Config) error {
+		a, b := net.Pipe()
+		defer a.Close()
+		defer b.Close()
+
+		go func() { _ = tls.Server(a, server).Handshake() }()
+		return tls.Client(b, client).Handshake()
+	}
+
+	root := pki.NewRootCertificateAuthority()
+	assert.NilError(t, root.Generate())
+	other := pki.NewRootCertificateAuthority()
+	assert.NilError(t, other.Generate())
+
+	client := pki.NewLeafCertificate(postgres.OperatorUser, nil, nil)
+	assert.NilError(t, client.Generate(root))
+	config := postgresClientTLS(client, root)
+
+	// The server name is not checked, but its certificate authority is.
+	for _, tt := range []struct {
+		signer *pki.RootCertificateAuthority
+		ok     bool
+	}{
+		{signer: root, ok: true},
+		{signer: other, ok: false},
+	} {
+		leaf := pki.NewLeafCertificate("some-other-name", []string{"some-other-name"}, nil)
+		assert.NilError(t, leaf.Generate(tt.signer))
+
+		err := handshake(&tls.Config{
+			Certificates: []tls.Certificate{{
+				Certificate: [][]byte{leaf.Certificate.Certificate},
+				PrivateKey:  leaf.PrivateKey.PrivateKey,
+			}},
+		}, config)
+		if tt.ok {
+			assert.NilError(t, err)
+		} else {
+			assert.ErrorContains(t, err, "unknown authority")
+		}
+	}
+}
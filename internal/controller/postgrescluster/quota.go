@@ -0,0 +1,266 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// ConditionInsufficientQuota is the type used in a condition to indicate that
+// new instances would exceed a ResourceQuota or LimitRange of the namespace.
+// Its message names the resources that do not fit.
+const ConditionInsufficientQuota = "InsufficientQuota"
+
+// EventInsufficientQuota is the event reason used when instances are not
+// created because they would exceed a ResourceQuota or LimitRange.
+const EventInsufficientQuota = "InsufficientQuota"
+
+// instanceVolumeClaims returns the specs of the PersistentVolumeClaims of
+// each instance of set.
+func instanceVolumeClaims(set *v1beta1.PostgresInstanceSetSpec) []*corev1.PersistentVolumeClaimSpec {
+	claims := []*corev1.PersistentVolumeClaimSpec{}
+	if set.Ephemeral == nil {
+		claims = append(claims, &set.DataVolumeClaimSpec)
+	} else if set.Ephemeral.VolumeClaimSpec != nil {
+		claims = append(claims, set.Ephemeral.VolumeClaimSpec)
+	}
+	if set.WALVolumeClaimSpec != nil {
+		claims = append(claims, set.WALVolumeClaimSpec)
+	}
+	return claims
+}
+
+// instanceQuotaUsage returns the quota that count new instances of set use
+// when volumes of them need new PersistentVolumeClaims. Only the database
+// container and the PostgreSQL volumes are counted, so this is a lower bound.
+// - https://kubernetes.io/docs/concepts/policy/resource-quotas/
+func instanceQuotaUsage(set *v1beta1.PostgresInstanceSetSpec, count, volumes int) corev1.ResourceList {
+	usage := corev1.ResourceList{}
+	if count <= 0 {
+		return usage
+	}
+
+	add := func(name corev1.ResourceName, quantity resource.Quantity, times int) {
+		total := usage[name]
+		for i := 0; i < times; i++ {
+			total.Add(quantity)
+		}
+		usage[name] = total
+	}
+
+	add(corev1.ResourcePods, *resource.NewQuantity(1, resource.DecimalSI), count)
+	add("count/statefulsets.apps", *resource.NewQuantity(1, resource.DecimalSI), count)
+
+	for name, quantity := range set.Resources.Requests {
+		switch name {
+		case corev1.ResourceCPU, corev1.ResourceMemory:
+			add(name, quantity, count)
+			add(corev1.ResourceName("requests."+name), quantity, count)
+		}
+	}
+	for name, quantity := range set.Resources.Limits {
+		switch name {
+		case corev1.ResourceCPU, corev1.ResourceMemory:
+			add(corev1.ResourceName("limits."+name), quantity, count)
+		}
+	}
+
+	for _, claim := range instanceVolumeClaims(set) {
+		storage := claim.Resources.Requests[corev1.ResourceStorage]
+		one := *resource.NewQuantity(1, resource.DecimalSI)
+
+		add(corev1.ResourcePersistentVolumeClaims, one, volumes)
+		add(corev1.ResourceRequestsStorage, storage, volumes)
+
+		if class := claim.StorageClassName; class != nil && *class != "" {
+			prefix := *class + ".storageclass.storage.k8s.io/"
+			add(corev1.ResourceName(prefix+string(corev1.ResourcePersistentVolumeClaims)), one, volumes)
+			add(corev1.ResourceName(prefix+string(corev1.ResourceRequestsStorage)), storage, volumes)
+		}
+	}
+
+	return usage
+}
+
+// insufficientQuota returns the resources in usage that do not fit in what
+// remains of quotas. Quotas with scopes apply to only some Pods and are skipped.
+func insufficientQuota(quotas []corev1.ResourceQuota, usage corev1.ResourceList) []string {
+	insufficient := map[string]bool{}
+
+	for _, quota := range quotas {
+		if len(quota.Spec.Scopes) > 0 || quota.Spec.ScopeSelector != nil {
+			continue
+		}
+		for name, quantity := range usage {
+			hard, ok := quota.Status.Hard[name]
+			if !ok {
+				hard, ok = quota.Spec.Hard[name]
+			}
+			if !ok {
+				continue
+			}
+
+			remaining := hard.DeepCopy()
+			remaining.Sub(quota.Status.Used[name])
+			if quantity.Cmp(remaining) > 0 {
+				insufficient[string(name)] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(insufficient))
+	for name := range insufficient {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// limitRangeViolations returns the limits in ranges that instances of set
+// would exceed. Only the database container and the PostgreSQL volumes are
+// checked.
+// - https://kubernetes.io/docs/concepts/policy/limit-range/
+func limitRangeViolations(ranges []corev1.LimitRange, set *v1beta1.PostgresInstanceSetSpec) []string {
+	violations := map[string]bool{}
+
+	check := func(kind string, item corev1.LimitRangeItem, requests, limits corev1.ResourceList) {
+		for name, max := range item.Max {
+			for _, list := range []corev1.ResourceList{requests, limits} {
+				if quantity, ok := list[name]; ok && quantity.Cmp(max) > 0 {
+					violations["max "+kind+" "+string(name)] = true
+				}
+			}
+		}
+		for name, min := range item.Min {
+			if quantity, ok := requests[name]; ok && quantity.Cmp(min) < 0 {
+				violations["min "+kind+" "+string(name)] = true
+			}
+		}
+	}
+
+	for _, lr := range ranges {
+		for _, item := range lr.Spec.Limits {
+			switch item.Type {
+			case corev1.LimitTypeContainer:
+				check("container", item, set.Resources.Requests, set.Resources.Limits)
+
+			case corev1.LimitTypePersistentVolumeClaim:
+				for _, claim := range instanceVolumeClaims(set) {
+					check("persistentvolumeclaim", item, claim.Resources.Requests, nil)
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(violations))
+	for name := range violations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// +kubebuilder:rbac:groups="",resources="limitranges",verbs={list}
+// +kubebuilder:rbac:groups="",resources="resourcequotas",verbs={list}
+
+// checkInstanceQuota reports in the status of cluster whether the new
+// instances in usage fit in the ResourceQuotas of its namespace and whether
+// every instance set satisfies its LimitRanges. It returns false when they do
+// not, so that nothing is created that the API would reject or leave pending.
+// These objects are read directly from the API rather than cached.
+func (r *Reconciler) checkInstanceQuota(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, usage corev1.ResourceList,
+) (bool, error) {
+	quotas := &corev1.ResourceQuotaList{}
+	ranges := &corev1.LimitRangeList{}
+
+	err := r.listUncached(ctx, cluster.Namespace, "ResourceQuotaList", quotas)
+	if err == nil {
+		err = r.listUncached(ctx, cluster.Namespace, "LimitRangeList", ranges)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var problems []string
+	if names := insufficientQuota(quotas.Items, usage); len(names) > 0 {
+		problems = append(problems, "insufficient quota: "+strings.Join(names, ", "))
+	}
+	for i := range cluster.Spec.InstanceSets {
+		set := &cluster.Spec.InstanceSets[i]
+		if names := limitRangeViolations(ranges.Items, set); len(names) > 0 {
+			problems = append(problems, "instance set "+set.Name+
+				" exceeds limit range: "+strings.Join(names, ", "))
+		}
+	}
+
+	if len(problems) == 0 {
+		if meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionInsufficientQuota) {
+			meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+				ObservedGeneration: cluster.GetGeneration(),
+				Type:               ConditionInsufficientQuota,
+				Status:             metav1.ConditionFalse,
+				Reason:             "QuotaSufficient",
+				Message:            "Instances fit in the quota of the namespace",
+			})
+		}
+		return true, nil
+	}
+
+	message := strings.Join(problems, "; ")
+	if !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionInsufficientQuota) {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, EventInsufficientQuota,
+			"Instances were not created: %s", message)
+	}
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		ObservedGeneration: cluster.GetGeneration(),
+		Type:               ConditionInsufficientQuota,
+		Status:             metav1.ConditionTrue,
+		Reason:             EventInsufficientQuota,
+		Message:            message,
+	})
+	return false, nil
+}
+
+// listUncached lists objects of kind in namespace directly from the API and
+// stores them in list. Listing as unstructured keeps the client from caching
+// and watching every object of kind.
+func (r *Reconciler) listUncached(
+	ctx context.Context, namespace, kind string, list client.ObjectList,
+) error {
+	uList := &unstructured.UnstructuredList{}
+	uList.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind(kind))
+
+	err := errors.WithStack(r.Client.List(ctx, uList, client.InNamespace(namespace)))
+	if err == nil {
+		err = errors.WithStack(runtime.DefaultUnstructuredConverter.FromUnstructured(
+			uList.UnstructuredContent(), list))
+	}
+	return err
+}
@@ -0,0 +1,184 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestInstanceQuotaUsage(t *testing.T) {
+	set := &v1beta1.PostgresInstanceSetSpec{Name: "00"}
+	set.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceCPU: resource.MustParse("500m"),
+	}
+	set.Resources.Limits = corev1.ResourceList{
+		corev1.ResourceMemory: resource.MustParse("1Gi"),
+	}
+	set.DataVolumeClaimSpec.StorageClassName = initialize.String("fast")
+	set.DataVolumeClaimSpec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceStorage: resource.MustParse("10Gi"),
+	}
+
+	assert.Equal(t, len(instanceQuotaUsage(set, 0, 0)), 0)
+
+	usage := instanceQuotaUsage(set, 2, 1)
+	for name, expected := range map[corev1.ResourceName]string{
+		"pods":                    "2",
+		"count/statefulsets.apps": "2",
+		"cpu":                     "1",
+		"requests.cpu":            "1",
+		"limits.memory":           "2Gi",
+		"persistentvolumeclaims":  "1",
+		"requests.storage":        "10Gi",
+		"fast.storageclass.storage.k8s.io/persistentvolumeclaims": "1",
+		"fast.storageclass.storage.k8s.io/requests.storage":       "10Gi",
+	} {
+		quantity := usage[name]
+		assert.Assert(t, quantity.Cmp(resource.MustParse(expected)) == 0,
+			"%s: expected %s, got %s", name, expected, quantity.String())
+	}
+	assert.Equal(t, len(usage), 9)
+
+	t.Run("WAL", func(t *testing.T) {
+		set := set.DeepCopy()
+		set.WALVolumeClaimSpec = &corev1.PersistentVolumeClaimSpec{}
+		set.WALVolumeClaimSpec.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceStorage: resource.MustParse("1Gi"),
+		}
+
+		usage := instanceQuotaUsage(set, 1, 1)
+		claims, storage := usage["persistentvolumeclaims"], usage["requests.storage"]
+		assert.Equal(t, claims.String(), "2")
+		assert.Equal(t, storage.String(), "11Gi")
+	})
+}
+
+func TestInsufficientQuota(t *testing.T) {
+	usage := corev1.ResourceList{
+		corev1.ResourcePods:            resource.MustParse("1"),
+		corev1.ResourceRequestsStorage: resource.MustParse("10Gi"),
+	}
+
+	assert.Equal(t, len(insufficientQuota(nil, usage)), 0)
+
+	quota := corev1.ResourceQuota{}
+	quota.Status.Hard = corev1.ResourceList{
+		corev1.ResourcePods:            resource.MustParse("10"),
+		corev1.ResourceRequestsStorage: resource.MustParse("20Gi"),
+		corev1.ResourceServices:        resource.MustParse("0"),
+	}
+	quota.Status.Used = corev1.ResourceList{
+		corev1.ResourcePods:            resource.MustParse("9"),
+		corev1.ResourceRequestsStorage: resource.MustParse("15Gi"),
+	}
+	assert.DeepEqual(t, insufficientQuota([]corev1.ResourceQuota{quota}, usage),
+		[]string{"requests.storage"})
+
+	t.Run("Scoped", func(t *testing.T) {
+		quota := quota.DeepCopy()
+		quota.Spec.Scopes = []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort}
+		assert.Equal(t, len(insufficientQuota([]corev1.ResourceQuota{*quota}, usage)), 0)
+	})
+}
+
+func TestLimitRangeViolations(t *testing.T) {
+	set := &v1beta1.PostgresInstanceSetSpec{Name: "00"}
+	set.Resources.Limits = corev1.ResourceList{
+		corev1.ResourceMemory: resource.MustParse("4Gi"),
+	}
+	set.DataVolumeClaimSpec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceStorage: resource.MustParse("1Gi"),
+	}
+
+	ranges := []corev1.LimitRange{{}}
+	ranges[0].Spec.Limits = []corev1.LimitRangeItem{
+		{
+			Type: corev1.LimitTypeContainer,
+			Max:  corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+		},
+		{
+			Type: corev1.LimitTypePersistentVolumeClaim,
+			Max:  corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("100Gi")},
+			Min:  corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("5Gi")},
+		},
+	}
+
+	assert.DeepEqual(t, limitRangeViolations(ranges, set), []string{
+		"max container memory", "min persistentvolumeclaim storage",
+	})
+	assert.Equal(t, len(limitRangeViolations(nil, set)), 0)
+}
+
+func TestCheckInstanceQuota(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{Name: "00"}}
+
+	quota := &corev1.ResourceQuota{}
+	quota.Namespace, quota.Name = "ns1", "storage"
+	quota.Spec.Hard = corev1.ResourceList{corev1.ResourceRequestsStorage: resource.MustParse("1Gi")}
+	quota.Status.Hard = quota.Spec.Hard
+
+	usage := corev1.ResourceList{corev1.ResourceRequestsStorage: resource.MustParse("10Gi")}
+
+	recorder := record.NewFakeRecorder(10)
+	r := &Reconciler{
+		Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(quota).Build(),
+		Recorder: recorder,
+	}
+
+	ok, err := r.checkInstanceQuota(ctx, cluster, usage)
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionInsufficientQuota)
+	assert.Assert(t, condition != nil)
+	assert.Equal(t, condition.Status, metav1.ConditionTrue)
+	assert.Equal(t, condition.Message, "insufficient quota: requests.storage")
+	assert.Assert(t, strings.Contains(<-recorder.Events, "requests.storage"))
+
+	// The event is not repeated.
+	ok, err = r.checkInstanceQuota(ctx, cluster, usage)
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+	assert.Equal(t, len(recorder.Events), 0)
+
+	// The condition resolves once instances fit.
+	ok, err = r.checkInstanceQuota(ctx, cluster, corev1.ResourceList{})
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Assert(t, !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionInsufficientQuota))
+}
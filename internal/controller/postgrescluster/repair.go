@@ -0,0 +1,377 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// The methods of spec.repair.
+const (
+	repairSingleUser = "singleUser"
+	repairResetWAL   = "resetWAL"
+	repairRewind     = "rewind"
+)
+
+// repairInProgress returns the status of the repair of cluster that has not
+// finished, if any.
+func repairInProgress(cluster *v1beta1.PostgresCluster) *v1beta1.PostgresRepairStatus {
+	if repair := cluster.Status.Repair; repair != nil && !repair.Finished {
+		return repair
+	}
+	return nil
+}
+
+// repairStopsCluster returns whether or not a repair in progress requires
+// every instance of cluster to stop. Instances stop as they do for shutdown,
+// with the primary last, so that it starts first once the repair is done.
+func repairStopsCluster(cluster *v1beta1.PostgresCluster) bool {
+	repair := repairInProgress(cluster)
+	return repair != nil && repair.Method != repairRewind
+}
+
+// repairStopsInstance returns whether or not a repair in progress requires only
+// the instance named instance to stop.
+func repairStopsInstance(cluster *v1beta1.PostgresCluster, instance string) bool {
+	repair := repairInProgress(cluster)
+	return repair != nil && repair.Method == repairRewind && repair.Instance == instance
+}
+
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs={get,create,delete,patch}
+
+// reconcileRepair starts the repair in spec.repair when the "repair"
+// annotation changes. Instances stop while the repair is in progress; see
+// repairStopsCluster and repairStopsInstance. Once they have, a Job runs
+// against the data volume of one instance. Its outcome is recorded in status
+// before the instances start again.
+func (r *Reconciler) reconcileRepair(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	observed *observedInstances, replicationSecret *corev1.Secret,
+	volumes []corev1.PersistentVolumeClaim,
+) error {
+	spec := cluster.Spec.Repair
+	annotation := cluster.GetAnnotations()[naming.Repair]
+	enabled := spec != nil && spec.Enabled != nil && *spec.Enabled
+
+	job := &batchv1.Job{ObjectMeta: naming.RepairJob(cluster)}
+	err := errors.WithStack(r.Client.Get(ctx, client.ObjectKeyFromObject(job), job))
+	if apierrors.IsNotFound(errors.Cause(err)) {
+		job, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Stop a repair in progress when it is disabled or when the annotation
+	// changes. Its Job is deleted, and the instances start again.
+	if repair := repairInProgress(cluster); repair != nil &&
+		(!enabled || repair.ID != annotation) {
+		if job != nil {
+			err = errors.WithStack(client.IgnoreNotFound(r.Client.Delete(ctx, job,
+				client.PropagationPolicy(metav1.DeletePropagationBackground))))
+		}
+		if err == nil {
+			r.finishRepair(cluster, repair, false, "Repair canceled")
+		}
+		return err
+	}
+
+	// Start a repair when the annotation identifies one that has not started.
+	if enabled && annotation != "" &&
+		(cluster.Status.Repair == nil || cluster.Status.Repair.ID != annotation) {
+
+		// Remove the Job of a previous repair first.
+		if job != nil {
+			return errors.WithStack(client.IgnoreNotFound(r.Client.Delete(ctx, job,
+				client.PropagationPolicy(metav1.DeletePropagationBackground))))
+		}
+
+		now := metav1.Now()
+		repair := &v1beta1.PostgresRepairStatus{
+			ID:        annotation,
+			Instance:  spec.Instance,
+			Method:    spec.Method,
+			StartTime: &now,
+		}
+		cluster.Status.Repair = repair
+
+		instance := observed.byName[spec.Instance]
+		switch {
+		case instance == nil:
+			r.finishRepair(cluster, repair, false,
+				fmt.Sprintf("Instance %q not found", spec.Instance))
+		case instanceDataVolume(spec.Instance, volumes) == nil:
+			r.finishRepair(cluster, repair, false,
+				fmt.Sprintf("Instance %q has no data volume", spec.Instance))
+		case spec.Method == repairSingleUser && strings.TrimSpace(spec.SQL) == "":
+			r.finishRepair(cluster, repair, false,
+				`Method "singleUser" requires sql`)
+		case spec.Method == repairRewind:
+			if primary, _ := instance.IsPrimary(); primary {
+				r.finishRepair(cluster, repair, false,
+					fmt.Sprintf("Instance %q is the primary; it cannot be rewound", spec.Instance))
+			}
+		}
+
+		// The primary stops last and starts first. Remember it now in case it
+		// stops before observeInstances sees it. When there is no primary, as
+		// may be the case with a damaged cluster, the startup instance stays
+		// as it is; a replica that starts first could take over as leader.
+		if repairStopsCluster(cluster) && cluster.Status.StartupInstance == "" {
+			for _, instance := range observed.forCluster {
+				if primary, known := instance.IsPrimary(); primary && known && instance.Spec != nil {
+					cluster.Status.StartupInstance = instance.Name
+					cluster.Status.StartupInstanceSet = instance.Spec.Name
+				}
+			}
+		}
+	}
+
+	repair := repairInProgress(cluster)
+	if repair == nil {
+		return nil
+	}
+
+	// Wait for instances to stop.
+	for _, instance := range observed.forCluster {
+		if len(instance.Pods) > 0 &&
+			(repairStopsCluster(cluster) || instance.Name == repair.Instance) {
+			return nil
+		}
+	}
+
+	if job == nil {
+		job, err = r.generateRepairJob(cluster, repair, replicationSecret, volumes)
+		if err == nil {
+			err = errors.WithStack(r.apply(ctx, job))
+		}
+		return err
+	}
+
+	if job.GetAnnotations()[naming.Repair] == repair.ID {
+		if jobCompleted(job) {
+			r.finishRepair(cluster, repair, true,
+				fmt.Sprintf("Repair of instance %q completed", repair.Instance))
+		} else if jobFailed(job) {
+			r.finishRepair(cluster, repair, false,
+				fmt.Sprintf("Repair of instance %q failed; see the logs of Job %q",
+					repair.Instance, job.Name))
+		}
+	}
+
+	return nil
+}
+
+// finishRepair records the outcome of repair in status and in an event.
+func (r *Reconciler) finishRepair(
+	cluster *v1beta1.PostgresCluster, repair *v1beta1.PostgresRepairStatus,
+	succeeded bool, message string,
+) {
+	now := metav1.Now()
+	repair.Finished = true
+	repair.Succeeded = succeeded
+	repair.Message = message
+	repair.CompletionTime = &now
+
+	if succeeded {
+		r.Recorder.Event(cluster, corev1.EventTypeNormal, "RepairSucceeded", message)
+	} else {
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, "RepairFailed", message)
+	}
+}
+
+// generateRepairJob returns a Job that runs the repair of cluster against the
+// data volume of the instance being repaired.
+func (r *Reconciler) generateRepairJob(
+	cluster *v1beta1.PostgresCluster, repair *v1beta1.PostgresRepairStatus,
+	replicationSecret *corev1.Secret, volumes []corev1.PersistentVolumeClaim,
+) (*batchv1.Job, error) {
+	spec := cluster.Spec.Repair
+
+	job := &batchv1.Job{ObjectMeta: naming.RepairJob(cluster)}
+	job.SetGroupVersionKind(batchv1.SchemeGroupVersion.WithKind("Job"))
+
+	job.Annotations = naming.Merge(
+		cluster.Spec.Metadata.GetAnnotationsOrNil(),
+		map[string]string{naming.Repair: repair.ID})
+	job.Labels = naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster: cluster.Name,
+			naming.LabelRole:    naming.RoleRepair,
+		})
+
+	var instanceSpec *v1beta1.PostgresInstanceSetSpec
+	if data := instanceDataVolume(repair.Instance, volumes); data != nil {
+		for i := range cluster.Spec.InstanceSets {
+			if cluster.Spec.InstanceSets[i].Name == data.Labels[naming.LabelInstanceSet] {
+				instanceSpec = &cluster.Spec.InstanceSets[i]
+			}
+		}
+		job.Spec.Template.Spec.Volumes = append(job.Spec.Template.Spec.Volumes, corev1.Volume{
+			Name: postgres.DataVolumeMount().Name,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: data.Name,
+				},
+			},
+		})
+	} else {
+		return nil, errors.Errorf("instance %q has no data volume", repair.Instance)
+	}
+
+	container := corev1.Container{
+		Name:            naming.ContainerRepair,
+		Env:             postgres.Environment(cluster),
+		Image:           config.PostgresContainerImage(cluster),
+		ImagePullPolicy: cluster.Spec.ImagePullPolicy,
+		Resources:       spec.Resources,
+		SecurityContext: initialize.RestrictedSecurityContext(),
+		VolumeMounts:    []corev1.VolumeMount{postgres.DataVolumeMount()},
+	}
+
+	// The "pg_wal" directory is a symbolic link when WAL files are on their
+	// own volume. Mount it at the same path.
+	for i := range volumes {
+		if volumes[i].Labels[naming.LabelInstance] == repair.Instance &&
+			volumes[i].Labels[naming.LabelRole] == naming.RolePostgresWAL &&
+			volumes[i].DeletionTimestamp == nil {
+			job.Spec.Template.Spec.Volumes = append(job.Spec.Template.Spec.Volumes, corev1.Volume{
+				Name: postgres.WALVolumeMount().Name,
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: volumes[i].Name,
+					},
+				},
+			})
+			container.VolumeMounts = append(container.VolumeMounts, postgres.WALVolumeMount())
+		}
+	}
+
+	switch repair.Method {
+	case repairSingleUser:
+		database := string(spec.Database)
+		if database == "" {
+			database = "postgres"
+		}
+		// Single-user mode reads commands from standard input. The "-j"
+		// flag ends each command with a semicolon and a blank line.
+		// - https://www.postgresql.org/docs/current/app-postgres.html#APP-POSTGRES-SINGLE-USER
+		container.Command = []string{"bash", "-ceu", "--",
+			`printf '%s\n' "${REPAIR_SQL}" | postgres --single -j -D "${PGDATA}" "$1"`,
+			"repair", database}
+		container.Env = append(container.Env, corev1.EnvVar{Name: "REPAIR_SQL", Value: spec.SQL})
+
+	case repairResetWAL:
+		container.Command = append([]string{"bash", "-ceu", "--",
+			`pg_resetwal "$@" "${PGDATA}"`, "repair"}, spec.Options...)
+
+	case repairRewind:
+		// Connect to the primary as the replication user using its client
+		// certificate. The key must be readable by only its owner, so copy
+		// the certificate files to a directory in memory first.
+		// - https://www.postgresql.org/docs/current/app-pgrewind.html
+		primary := naming.ClusterPrimaryService(cluster)
+		certificates := naming.CertMountPath + naming.ReplicationDirectory
+
+		container.Command = append([]string{"bash", "-ceu", "--", strings.Join([]string{
+			fmt.Sprintf(`install -D --mode=0600 -t %q %q/{%s,%s,%s}`,
+				naming.ReplicationTmp, certificates, naming.ReplicationCert,
+				naming.ReplicationPrivateKey, naming.ReplicationCACert),
+			`pg_rewind --target-pgdata="${PGDATA}" --source-server='dbname=postgres' --progress "$@"`,
+		}, "\n"), "repair"}, spec.Options...)
+		container.Env = []corev1.EnvVar{
+			{Name: "PGDATA", Value: postgres.ConfigDirectory(cluster)},
+			{Name: "PGHOST", Value: primary.Name + "." + primary.Namespace + ".svc"},
+			{Name: "PGPORT", Value: fmt.Sprint(*cluster.Spec.Port)},
+			{Name: "PGUSER", Value: postgres.ReplicationUser},
+			{Name: "PGSSLMODE", Value: "verify-ca"},
+			{Name: "PGSSLCERT", Value: naming.ReplicationTmp + "/" + naming.ReplicationCert},
+			{Name: "PGSSLKEY", Value: naming.ReplicationTmp + "/" + naming.ReplicationPrivateKey},
+			{Name: "PGSSLROOTCERT", Value: naming.ReplicationTmp + "/" + naming.ReplicationCACert},
+			{Name: "PGAPPNAME", Value: "repair"},
+		}
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "cert-volume",
+			MountPath: naming.CertMountPath,
+			ReadOnly:  true,
+		})
+		job.Spec.Template.Spec.Volumes = append(job.Spec.Template.Spec.Volumes, corev1.Volume{
+			Name: "cert-volume",
+			VolumeSource: corev1.VolumeSource{
+				Projected: &corev1.ProjectedVolumeSource{
+					Sources: []corev1.VolumeProjection{{
+						Secret: replicationCertSecretProjection(replicationSecret),
+					}},
+				},
+			},
+		})
+
+	default:
+		return nil, errors.Errorf("unknown repair method %q", repair.Method)
+	}
+
+	job.Spec.Template.Annotations = job.Annotations
+	job.Spec.Template.Labels = job.Labels
+	job.Spec.Template.Spec.Containers = []corev1.Container{container}
+
+	// A repair changes files that PostgreSQL depends on. Do not run it again
+	// automatically when it fails.
+	job.Spec.BackoffLimit = initialize.Int32(0)
+	job.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyNever
+
+	// Run near the data of the instance, like the instance itself.
+	if instanceSpec != nil {
+		job.Spec.Template.Spec.Affinity = instanceSpec.Affinity.DeepCopy()
+		for i := range instanceSpec.Tolerations {
+			job.Spec.Template.Spec.Tolerations = append(job.Spec.Template.Spec.Tolerations,
+				*instanceSpec.Tolerations[i].DeepCopy())
+		}
+	}
+	job.Spec.Template.Spec.Affinity = architectureAffinity(
+		config.ImageArchitectures(cluster), job.Spec.Template.Spec.Affinity)
+	setPodNameResolution(cluster, &job.Spec.Template.Spec)
+
+	// The repair does not make any Kubernetes API calls. Use the default
+	// ServiceAccount and do not mount its credentials.
+	job.Spec.Template.Spec.AutomountServiceAccountToken = initialize.Bool(false)
+	job.Spec.Template.Spec.ImagePullSecrets = cluster.Spec.ImagePullSecrets
+	job.Spec.Template.Spec.SecurityContext = postgres.PodSecurityContext(cluster)
+
+	addNSSWrapper(
+		config.PostgresContainerImage(cluster),
+		cluster.Spec.ImagePullPolicy,
+		&job.Spec.Template)
+	addTMPEmptyDir(&job.Spec.Template)
+
+	err := errors.WithStack(r.setControllerReference(cluster, job))
+	return job, err
+}
@@ -0,0 +1,343 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestRepairStops(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	assert.Assert(t, !repairStopsCluster(cluster))
+	assert.Assert(t, !repairStopsInstance(cluster, "one"))
+
+	cluster.Status.Repair = &v1beta1.PostgresRepairStatus{
+		Instance: "one", Method: repairResetWAL,
+	}
+	assert.Assert(t, repairStopsCluster(cluster))
+	assert.Assert(t, !repairStopsInstance(cluster, "one"))
+
+	cluster.Status.Repair.Method = repairRewind
+	assert.Assert(t, !repairStopsCluster(cluster))
+	assert.Assert(t, repairStopsInstance(cluster, "one"))
+	assert.Assert(t, !repairStopsInstance(cluster, "two"))
+
+	cluster.Status.Repair.Finished = true
+	assert.Assert(t, !repairStopsCluster(cluster))
+	assert.Assert(t, !repairStopsInstance(cluster, "one"))
+}
+
+func TestReconcileRepair(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	setup := func(objects ...client.Object) (*Reconciler, *record.FakeRecorder) {
+		recorder := record.NewFakeRecorder(10)
+		return &Reconciler{
+			Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects...).Build(),
+			Recorder: recorder,
+		}, recorder
+	}
+
+	newCluster := func() *v1beta1.PostgresCluster {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Namespace, cluster.Name = "ns1", "hippo"
+		cluster.Default()
+		cluster.Annotations = map[string]string{naming.Repair: "one"}
+		cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{Name: "00"}}
+		cluster.Spec.Repair = &v1beta1.PostgresRepairSpec{
+			Enabled:  initialize.Bool(true),
+			Instance: "hippo-00-abcd",
+			Method:   repairSingleUser,
+			SQL:      "VACUUM;",
+		}
+		return cluster
+	}
+
+	volumes := []corev1.PersistentVolumeClaim{{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "hippo-00-abcd-pgdata",
+			Labels: map[string]string{
+				naming.LabelInstance:    "hippo-00-abcd",
+				naming.LabelInstanceSet: "00",
+				naming.LabelRole:        naming.RolePostgresData,
+			},
+		},
+	}}
+
+	observe := func(cluster *v1beta1.PostgresCluster, pods ...corev1.Pod) *observedInstances {
+		return newObservedInstances(cluster, []appsv1.StatefulSet{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "hippo-00-abcd",
+				Labels: map[string]string{naming.LabelInstanceSet: "00"},
+			},
+		}}, pods)
+	}
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: "hippo-00-abcd-0",
+		Labels: map[string]string{
+			naming.LabelInstance:    "hippo-00-abcd",
+			naming.LabelInstanceSet: "00",
+		},
+	}}
+
+	t.Run("Disabled", func(t *testing.T) {
+		r, _ := setup()
+		cluster := newCluster()
+		cluster.Spec.Repair.Enabled = initialize.Bool(false)
+
+		assert.NilError(t, r.reconcileRepair(ctx, cluster, observe(cluster), nil, volumes))
+		assert.Assert(t, cluster.Status.Repair == nil)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, tt := range []struct {
+			mutate  func(*v1beta1.PostgresCluster)
+			message string
+		}{
+			{func(c *v1beta1.PostgresCluster) { c.Spec.Repair.Instance = "nope" }, "not found"},
+			{func(c *v1beta1.PostgresCluster) { c.Spec.Repair.SQL = " " }, "requires sql"},
+		} {
+			r, recorder := setup()
+			cluster := newCluster()
+			tt.mutate(cluster)
+
+			assert.NilError(t, r.reconcileRepair(ctx, cluster, observe(cluster), nil, volumes))
+			assert.Assert(t, cluster.Status.Repair != nil)
+			assert.Equal(t, cluster.Status.Repair.ID, "one")
+			assert.Assert(t, cluster.Status.Repair.Finished)
+			assert.Assert(t, !cluster.Status.Repair.Succeeded)
+			assert.Assert(t, strings.Contains(cluster.Status.Repair.Message, tt.message),
+				"got %q", cluster.Status.Repair.Message)
+			assert.Assert(t, strings.Contains(<-recorder.Events, "RepairFailed"))
+		}
+	})
+
+	t.Run("Stopping", func(t *testing.T) {
+		r, _ := setup()
+		cluster := newCluster()
+
+		assert.NilError(t, r.reconcileRepair(ctx, cluster, observe(cluster, pod), nil, volumes))
+		assert.Assert(t, repairInProgress(cluster) != nil)
+		assert.Assert(t, repairStopsCluster(cluster))
+
+		// Without a primary, the startup instance stays as it is.
+		assert.Equal(t, cluster.Status.StartupInstance, "")
+
+		// No Job while the instance is running.
+		err := r.Client.Get(ctx, client.ObjectKey{Namespace: "ns1", Name: "hippo-repair"}, &batchv1.Job{})
+		assert.Assert(t, err != nil)
+	})
+
+	t.Run("StoppingWithPrimary", func(t *testing.T) {
+		r, _ := setup()
+		cluster := newCluster()
+
+		primary := corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: "hippo-00-wxyz-0",
+			Labels: map[string]string{
+				naming.LabelInstance:    "hippo-00-wxyz",
+				naming.LabelInstanceSet: "00",
+				naming.LabelRole:        naming.RolePatroniLeader,
+			},
+		}}
+		observed := newObservedInstances(cluster, []appsv1.StatefulSet{
+			{ObjectMeta: metav1.ObjectMeta{
+				Name:   "hippo-00-abcd",
+				Labels: map[string]string{naming.LabelInstanceSet: "00"},
+			}},
+			{ObjectMeta: metav1.ObjectMeta{
+				Name:   "hippo-00-wxyz",
+				Labels: map[string]string{naming.LabelInstanceSet: "00"},
+			}},
+		}, []corev1.Pod{pod, primary})
+
+		assert.NilError(t, r.reconcileRepair(ctx, cluster, observed, nil, volumes))
+		assert.Assert(t, repairStopsCluster(cluster))
+
+		// The primary starts first, not the repaired replica.
+		assert.Equal(t, cluster.Status.StartupInstance, "hippo-00-wxyz")
+		assert.Equal(t, cluster.Status.StartupInstanceSet, "00")
+	})
+
+	t.Run("Finished", func(t *testing.T) {
+		job := &batchv1.Job{ObjectMeta: naming.RepairJob(newCluster())}
+		job.Annotations = map[string]string{naming.Repair: "one"}
+		job.Status.Conditions = []batchv1.JobCondition{{
+			Type: batchv1.JobComplete, Status: corev1.ConditionTrue,
+		}}
+
+		r, recorder := setup(job)
+		cluster := newCluster()
+		cluster.Status.Repair = &v1beta1.PostgresRepairStatus{
+			ID: "one", Instance: "hippo-00-abcd", Method: repairSingleUser,
+		}
+
+		assert.NilError(t, r.reconcileRepair(ctx, cluster, observe(cluster), nil, volumes))
+		assert.Assert(t, cluster.Status.Repair.Finished)
+		assert.Assert(t, cluster.Status.Repair.Succeeded)
+		assert.Assert(t, cluster.Status.Repair.CompletionTime != nil)
+		assert.Assert(t, strings.Contains(<-recorder.Events, "RepairSucceeded"))
+
+		// Nothing changes after that.
+		before := cluster.Status.Repair.DeepCopy()
+		assert.NilError(t, r.reconcileRepair(ctx, cluster, observe(cluster), nil, volumes))
+		assert.DeepEqual(t, cluster.Status.Repair, before)
+
+		// A new annotation deletes the old Job before starting again.
+		cluster.Annotations[naming.Repair] = "two"
+		assert.NilError(t, r.reconcileRepair(ctx, cluster, observe(cluster), nil, volumes))
+		assert.Equal(t, cluster.Status.Repair.ID, "one")
+		assert.NilError(t, r.reconcileRepair(ctx, cluster, observe(cluster, pod), nil, volumes))
+		assert.Equal(t, cluster.Status.Repair.ID, "two")
+		assert.Assert(t, repairInProgress(cluster) != nil)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		r, recorder := setup()
+		cluster := newCluster()
+		cluster.Status.Repair = &v1beta1.PostgresRepairStatus{
+			ID: "one", Instance: "hippo-00-abcd", Method: repairSingleUser,
+		}
+		cluster.Spec.Repair.Enabled = initialize.Bool(false)
+
+		assert.NilError(t, r.reconcileRepair(ctx, cluster, observe(cluster), nil, volumes))
+		assert.Assert(t, cluster.Status.Repair.Finished)
+		assert.Equal(t, cluster.Status.Repair.Message, "Repair canceled")
+		assert.Assert(t, strings.Contains(<-recorder.Events, "RepairFailed"))
+	})
+}
+
+func TestGenerateRepairJob(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+	r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).Build()}
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Default()
+	cluster.Spec.PostgresVersion = 13
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{Name: "00"}}
+	cluster.Spec.Repair = &v1beta1.PostgresRepairSpec{
+		Instance: "hippo-00-abcd",
+		Options:  []string{"--dry-run"},
+	}
+
+	volumes := []corev1.PersistentVolumeClaim{
+		{ObjectMeta: metav1.ObjectMeta{Name: "data", Labels: map[string]string{
+			naming.LabelInstance:    "hippo-00-abcd",
+			naming.LabelInstanceSet: "00",
+			naming.LabelRole:        naming.RolePostgresData,
+		}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "wal", Labels: map[string]string{
+			naming.LabelInstance:    "hippo-00-abcd",
+			naming.LabelInstanceSet: "00",
+			naming.LabelRole:        naming.RolePostgresWAL,
+		}}},
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "replication"}}
+
+	t.Run("ResetWAL", func(t *testing.T) {
+		repair := &v1beta1.PostgresRepairStatus{ID: "x", Instance: "hippo-00-abcd", Method: repairResetWAL}
+		job, err := r.generateRepairJob(cluster, repair, secret, volumes)
+		assert.NilError(t, err)
+
+		assert.Equal(t, job.Name, "hippo-repair")
+		assert.Equal(t, job.Annotations[naming.Repair], "x")
+		assert.Equal(t, job.Labels[naming.LabelRole], naming.RoleRepair)
+		assert.Equal(t, *job.Spec.BackoffLimit, int32(0))
+
+		// The Pods of this Job are not instances.
+		_, found := job.Spec.Template.Labels[naming.LabelInstance]
+		assert.Assert(t, !found)
+
+		container := job.Spec.Template.Spec.Containers[0]
+		assert.Equal(t, container.Name, "repair")
+		assert.DeepEqual(t, container.Command, []string{"bash", "-ceu", "--",
+			`pg_resetwal "$@" "${PGDATA}"`, "repair", "--dry-run"})
+
+		assert.Assert(t, marshalMatches(job.Spec.Template.Spec.Volumes[:2], `
+- name: postgres-data
+  persistentVolumeClaim:
+    claimName: data
+- name: postgres-wal
+  persistentVolumeClaim:
+    claimName: wal
+		`))
+	})
+
+	t.Run("SingleUser", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Repair.SQL = "VACUUM FREEZE;"
+		cluster.Spec.Repair.Database = "app"
+
+		repair := &v1beta1.PostgresRepairStatus{ID: "x", Instance: "hippo-00-abcd", Method: repairSingleUser}
+		job, err := r.generateRepairJob(cluster, repair, secret, volumes)
+		assert.NilError(t, err)
+
+		container := job.Spec.Template.Spec.Containers[0]
+		assert.Equal(t, container.Command[len(container.Command)-1], "app")
+		assert.Assert(t, strings.Contains(container.Command[3], "postgres --single"))
+		assert.Assert(t, marshalMatches(container.Env[3], `
+name: REPAIR_SQL
+value: VACUUM FREEZE;
+		`))
+	})
+
+	t.Run("Rewind", func(t *testing.T) {
+		repair := &v1beta1.PostgresRepairStatus{ID: "x", Instance: "hippo-00-abcd", Method: repairRewind}
+		job, err := r.generateRepairJob(cluster, repair, secret, volumes)
+		assert.NilError(t, err)
+
+		container := job.Spec.Template.Spec.Containers[0]
+		assert.Assert(t, strings.Contains(container.Command[3], "pg_rewind"))
+		assert.Equal(t, container.Command[len(container.Command)-1], "--dry-run")
+
+		env := map[string]string{}
+		for _, v := range container.Env {
+			env[v.Name] = v.Value
+		}
+		assert.Equal(t, env["PGHOST"], "hippo-primary.ns1.svc")
+		assert.Equal(t, env["PGUSER"], "_crunchyrepl")
+		assert.Equal(t, env["PGSSLMODE"], "verify-ca")
+	})
+
+	t.Run("NoVolume", func(t *testing.T) {
+		repair := &v1beta1.PostgresRepairStatus{ID: "x", Instance: "other", Method: repairResetWAL}
+		_, err := r.generateRepairJob(cluster, repair, secret, volumes)
+		assert.ErrorContains(t, err, "no data volume")
+	})
+}
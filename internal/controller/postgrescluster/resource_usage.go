@@ -0,0 +1,233 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+const (
+	// ConditionOverloaded is the type used in a condition to indicate that a
+	// PostgreSQL instance has been near its memory limit for some time.
+	ConditionOverloaded = "Overloaded"
+
+	// reasonMemoryPressure is the reason of a true ConditionOverloaded.
+	reasonMemoryPressure = "MemoryPressure"
+)
+
+// podMetricsKind is the kind of object in the Kubernetes Metrics API that
+// reports the usage of one Pod.
+// - https://github.com/kubernetes/metrics
+var podMetricsKind = schema.GroupVersionKind{
+	Group: "metrics.k8s.io", Version: "v1beta1", Kind: "PodMetrics",
+}
+
+// +kubebuilder:rbac:groups="metrics.k8s.io",resources="pods",verbs={get}
+
+// reconcileResourceUsage reads the memory and CPU usage of every running
+// instance of cluster at most once per interval and reports them in status.
+// ConditionOverloaded is true while any instance has stayed at or above the
+// configured percent of its memory limit for the configured duration.
+func (r *Reconciler) reconcileResourceUsage(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) (reconcile.Result, error) {
+	spec := cluster.Spec.ResourceUsage
+	if spec == nil {
+		for i := range cluster.Status.InstanceSets {
+			cluster.Status.InstanceSets[i].Resources = nil
+		}
+		// RemoveStatusCondition panics when there are no conditions.
+		if meta.FindStatusCondition(cluster.Status.Conditions, ConditionOverloaded) != nil {
+			meta.RemoveStatusCondition(&cluster.Status.Conditions, ConditionOverloaded)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	interval := 60 * time.Second
+	if spec.IntervalSeconds != nil {
+		interval = time.Duration(*spec.IntervalSeconds) * time.Second
+	}
+	threshold := int32(90)
+	if spec.MemoryPercent != nil {
+		threshold = *spec.MemoryPercent
+	}
+	sustained := 300 * time.Second
+	if spec.SustainedSeconds != nil {
+		sustained = time.Duration(*spec.SustainedSeconds) * time.Second
+	}
+
+	log := logging.FromContext(ctx)
+	now := metav1.Now()
+	var overloaded []string
+
+	for i := range cluster.Status.InstanceSets {
+		status := &cluster.Status.InstanceSets[i]
+
+		previous := make(map[string]v1beta1.PostgresResourceUsageStatus, len(status.Resources))
+		for _, usage := range status.Resources {
+			previous[usage.Pod] = usage
+		}
+
+		// Keep only the measurements of running instances.
+		var resources []v1beta1.PostgresResourceUsageStatus
+		for _, instance := range instances.bySet[status.Name] {
+			if running, known := instance.IsRunning(naming.ContainerDatabase); !running || !known {
+				continue
+			}
+
+			pod := instance.Pods[0]
+			usage, ok := previous[pod.Name]
+
+			// Measure each Pod no more often than the interval.
+			if !ok || now.Sub(usage.LastObservedTime.Time) >= interval {
+				measured, err := r.measureResourceUsage(ctx, pod)
+				if err != nil {
+					// Measurements are informational; try again next interval.
+					log.V(1).Info("unable to measure resources", "pod", pod.Name, "error", err.Error())
+				} else {
+					measured.HighMemorySince = usage.HighMemorySince
+					measured.LastObservedTime = now
+					usage, ok = measured, true
+				}
+			}
+			if !ok {
+				continue
+			}
+
+			// Remember when memory first reached the threshold.
+			if usage.MemoryLimit == nil || usage.MemoryPercent < threshold {
+				usage.HighMemorySince = nil
+			} else if usage.HighMemorySince == nil {
+				usage.HighMemorySince = &now
+			}
+
+			usage.Recommendation = ""
+			if usage.HighMemorySince != nil && now.Sub(usage.HighMemorySince.Time) >= sustained {
+				usage.Recommendation = memoryRecommendation(status.Name, usage.Memory)
+				overloaded = append(overloaded, fmt.Sprintf(
+					"Pod %q has used %d%% of its memory limit since %s. %s",
+					usage.Pod, usage.MemoryPercent,
+					usage.HighMemorySince.UTC().Format(time.RFC3339), usage.Recommendation))
+			}
+
+			resources = append(resources, usage)
+		}
+		sort.Slice(resources, func(i, j int) bool {
+			return resources[i].Pod < resources[j].Pod
+		})
+		status.Resources = resources
+	}
+
+	condition := metav1.Condition{
+		ObservedGeneration: cluster.GetGeneration(),
+		Type:               ConditionOverloaded,
+		Status:             metav1.ConditionFalse,
+		Reason:             "WithinLimits",
+		Message:            fmt.Sprintf("No instance is above %d%% of its memory limit", threshold),
+	}
+	if len(overloaded) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonMemoryPressure
+		condition.Message = strings.Join(overloaded, " ")
+
+		// Warn only when the condition becomes true, not every reconcile.
+		if !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionOverloaded) {
+			r.Recorder.Event(cluster, corev1.EventTypeWarning, reasonMemoryPressure, condition.Message)
+		}
+	}
+	meta.SetStatusCondition(&cluster.Status.Conditions, condition)
+
+	return reconcile.Result{RequeueAfter: interval}, nil
+}
+
+// measureResourceUsage reads the usage of the database container of pod from
+// the Kubernetes Metrics API.
+func (r *Reconciler) measureResourceUsage(
+	ctx context.Context, pod *corev1.Pod,
+) (v1beta1.PostgresResourceUsageStatus, error) {
+	usage := v1beta1.PostgresResourceUsageStatus{Pod: pod.Name}
+
+	metrics := &unstructured.Unstructured{}
+	metrics.SetGroupVersionKind(podMetricsKind)
+	err := errors.WithStack(
+		r.Client.Get(ctx, client.ObjectKeyFromObject(pod), metrics))
+
+	if err == nil {
+		usage.CPU, usage.Memory, err = parsePodMetrics(metrics.Object, naming.ContainerDatabase)
+	}
+
+	for _, container := range pod.Spec.Containers {
+		if limit, ok := container.Resources.Limits[corev1.ResourceMemory]; ok &&
+			container.Name == naming.ContainerDatabase && !limit.IsZero() {
+			usage.MemoryLimit = &limit
+			usage.MemoryPercent = int32(
+				(usage.Memory.Value()*100 + limit.Value() - 1) / limit.Value())
+		}
+	}
+
+	return usage, err
+}
+
+// parsePodMetrics returns the CPU and memory usage of container in a PodMetrics
+// object of the Kubernetes Metrics API.
+func parsePodMetrics(
+	object map[string]interface{}, container string,
+) (cpu, memory resource.Quantity, err error) {
+	containers, _, _ := unstructured.NestedSlice(object, "containers")
+	for _, item := range containers {
+		entry, _ := item.(map[string]interface{})
+		if name, _, _ := unstructured.NestedString(entry, "name"); name != container {
+			continue
+		}
+
+		values, _, _ := unstructured.NestedStringMap(entry, "usage")
+		if cpu, err = resource.ParseQuantity(values["cpu"]); err == nil {
+			memory, err = resource.ParseQuantity(values["memory"])
+		}
+		return cpu, memory, errors.WithStack(err)
+	}
+	return cpu, memory, errors.Errorf("no metrics for container %q", container)
+}
+
+// memoryRecommendation describes how to relieve an instance of set that uses
+// memory. The suggested limit leaves a quarter of it free.
+func memoryRecommendation(set string, memory resource.Quantity) string {
+	const unit = 64 << 20 // 64Mi
+
+	suggested := (memory.Value()*4/3 + unit - 1) / unit * unit
+	return fmt.Sprintf(
+		"Raise the memory limit of instance set %q to at least %s, "+
+			"or lower shared_buffers and work_mem.",
+		set, resource.NewQuantity(suggested, resource.BinarySI).String())
+}
@@ -0,0 +1,238 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestParsePodMetrics(t *testing.T) {
+	object := map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{
+				"name":  "other",
+				"usage": map[string]interface{}{"cpu": "1", "memory": "1Gi"},
+			},
+			map[string]interface{}{
+				"name":  "database",
+				"usage": map[string]interface{}{"cpu": "250m", "memory": "524288Ki"},
+			},
+		},
+	}
+
+	cpu, memory, err := parsePodMetrics(object, "database")
+	assert.NilError(t, err)
+	assert.Equal(t, cpu.String(), "250m")
+	assert.Equal(t, memory.Value(), int64(512<<20))
+
+	_, _, err = parsePodMetrics(object, "missing")
+	assert.ErrorContains(t, err, "no metrics")
+
+	_, _, err = parsePodMetrics(map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{
+				"name":  "database",
+				"usage": map[string]interface{}{"cpu": "x"},
+			},
+		},
+	}, "database")
+	assert.Assert(t, err != nil)
+}
+
+func TestMemoryRecommendation(t *testing.T) {
+	assert.Equal(t, memoryRecommendation("00", resource.MustParse("900Mi")),
+		`Raise the memory limit of instance set "00" to at least 1216Mi, `+
+			`or lower shared_buffers and work_mem.`)
+	assert.Equal(t, memoryRecommendation("00", resource.MustParse("3Gi")),
+		`Raise the memory limit of instance set "00" to at least 4Gi, `+
+			`or lower shared_buffers and work_mem.`)
+}
+
+func TestReconcileResourceUsage(t *testing.T) {
+	ctx := context.Background()
+
+	pod := &corev1.Pod{}
+	pod.Namespace, pod.Name = "ns1", "hippo-00-abcd-0"
+	pod.Spec.Containers = []corev1.Container{{
+		Name: naming.ContainerDatabase,
+		Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("1Gi"),
+		}},
+	}}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  naming.ContainerDatabase,
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}}
+
+	metrics := func(memory string) *unstructured.Unstructured {
+		object := &unstructured.Unstructured{Object: map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{
+					"name":  naming.ContainerDatabase,
+					"usage": map[string]interface{}{"cpu": "100m", "memory": memory},
+				},
+			},
+		}}
+		object.SetGroupVersionKind(podMetricsKind)
+		object.SetNamespace(pod.Namespace)
+		object.SetName(pod.Name)
+		return object
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &Reconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(),
+		Recorder: recorder,
+	}
+
+	instances := &observedInstances{bySet: map[string][]*Instance{
+		"00": {{Name: "hippo-00-abcd", Pods: []*corev1.Pod{pod}, Runner: &appsv1.StatefulSet{}}},
+	}}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Status.InstanceSets = []v1beta1.PostgresInstanceSetStatus{{Name: "00"}}
+
+	t.Run("Disabled", func(t *testing.T) {
+		result, err := reconciler.reconcileResourceUsage(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, time.Duration(0))
+		assert.Assert(t, cluster.Status.InstanceSets[0].Resources == nil)
+		assert.Assert(t, meta.FindStatusCondition(cluster.Status.Conditions, ConditionOverloaded) == nil)
+	})
+
+	cluster.Spec.ResourceUsage = &v1beta1.PostgresResourceUsageSpec{
+		IntervalSeconds:  initialize.Int32(30),
+		MemoryPercent:    initialize.Int32(90),
+		SustainedSeconds: initialize.Int32(120),
+	}
+
+	t.Run("Unavailable", func(t *testing.T) {
+		// There are no metrics for the Pod.
+		result, err := reconciler.reconcileResourceUsage(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, 30*time.Second)
+		assert.Equal(t, len(cluster.Status.InstanceSets[0].Resources), 0)
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionOverloaded)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionFalse)
+	})
+
+	t.Run("Measure", func(t *testing.T) {
+		assert.NilError(t, reconciler.Client.Create(ctx, metrics("512Mi")))
+
+		_, err := reconciler.reconcileResourceUsage(ctx, cluster, instances)
+		assert.NilError(t, err)
+
+		resources := cluster.Status.InstanceSets[0].Resources
+		assert.Equal(t, len(resources), 1)
+		assert.Equal(t, resources[0].Pod, pod.Name)
+		assert.Equal(t, resources[0].CPU.String(), "100m")
+		assert.Equal(t, resources[0].Memory.String(), "512Mi")
+		assert.Equal(t, resources[0].MemoryLimit.String(), "1Gi")
+		assert.Equal(t, resources[0].MemoryPercent, int32(50))
+		assert.Assert(t, resources[0].HighMemorySince == nil)
+		assert.Equal(t, resources[0].Recommendation, "")
+	})
+
+	t.Run("Overloaded", func(t *testing.T) {
+		assert.NilError(t, reconciler.Client.Delete(ctx, metrics("")))
+		assert.NilError(t, reconciler.Client.Create(ctx, metrics("950Mi")))
+
+		// Nothing is measured again until the interval passes.
+		_, err := reconciler.reconcileResourceUsage(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, cluster.Status.InstanceSets[0].Resources[0].MemoryPercent, int32(50))
+
+		cluster.Status.InstanceSets[0].Resources[0].LastObservedTime =
+			metav1.NewTime(time.Now().Add(-time.Minute))
+
+		// High memory is remembered but not yet sustained.
+		_, err = reconciler.reconcileResourceUsage(ctx, cluster, instances)
+		assert.NilError(t, err)
+
+		resources := cluster.Status.InstanceSets[0].Resources
+		assert.Equal(t, resources[0].MemoryPercent, int32(93))
+		assert.Assert(t, resources[0].HighMemorySince != nil)
+		assert.Equal(t, resources[0].Recommendation, "")
+		assert.Assert(t, !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionOverloaded))
+		assert.Equal(t, len(recorder.Events), 0)
+
+		// Sustained high memory sets the condition and a recommendation.
+		since := metav1.NewTime(time.Now().Add(-5 * time.Minute))
+		resources[0].HighMemorySince = &since
+		resources[0].LastObservedTime = metav1.NewTime(time.Now().Add(-time.Minute))
+
+		_, err = reconciler.reconcileResourceUsage(ctx, cluster, instances)
+		assert.NilError(t, err)
+
+		resources = cluster.Status.InstanceSets[0].Resources
+		assert.Equal(t, resources[0].HighMemorySince.Unix(), since.Unix(), "expected unchanged")
+		assert.Assert(t, resources[0].Recommendation != "")
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionOverloaded)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionTrue)
+		assert.Equal(t, condition.Reason, "MemoryPressure")
+		assert.Assert(t, len(condition.Message) > 0)
+		assert.Equal(t, len(recorder.Events), 1)
+
+		// The event is emitted once.
+		resources[0].LastObservedTime = metav1.NewTime(time.Now().Add(-time.Minute))
+		_, err = reconciler.reconcileResourceUsage(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, len(recorder.Events), 1)
+	})
+
+	t.Run("Recovered", func(t *testing.T) {
+		assert.NilError(t, reconciler.Client.Delete(ctx, metrics("")))
+		assert.NilError(t, reconciler.Client.Create(ctx, metrics("256Mi")))
+		cluster.Status.InstanceSets[0].Resources[0].LastObservedTime =
+			metav1.NewTime(time.Now().Add(-time.Minute))
+
+		_, err := reconciler.reconcileResourceUsage(ctx, cluster, instances)
+		assert.NilError(t, err)
+
+		resources := cluster.Status.InstanceSets[0].Resources
+		assert.Assert(t, resources[0].HighMemorySince == nil)
+		assert.Equal(t, resources[0].Recommendation, "")
+		assert.Assert(t, !meta.IsStatusConditionTrue(cluster.Status.Conditions, ConditionOverloaded))
+	})
+
+	t.Run("Removed", func(t *testing.T) {
+		_, err := reconciler.reconcileResourceUsage(ctx, cluster, &observedInstances{})
+		assert.NilError(t, err)
+		assert.Equal(t, len(cluster.Status.InstanceSets[0].Resources), 0)
+	})
+}
@@ -0,0 +1,131 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/patroni"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+const (
+	// EventStandbyPromotionPending is the event reason used when a standby
+	// cluster waits for WAL replay to settle before it is promoted.
+	EventStandbyPromotionPending = "StandbyPromotionPending"
+
+	// EventStandbyPromoted is the event reason used when a standby cluster is
+	// promoted.
+	EventStandbyPromoted = "StandbyPromoted"
+)
+
+// holdStandbyPromotion returns whether or not the promotion of cluster waits
+// for the grace period in spec.standby. The rest of the reconcile treats
+// cluster as a standby while it does; see PostgresCluster.StandbyEnabled.
+func holdStandbyPromotion(cluster *v1beta1.PostgresCluster) bool {
+	return cluster.StandbyEnabled() && !cluster.Spec.Standby.Enabled
+}
+
+// reconcileStandbyPromotion tracks the standby status of cluster. While
+// promotion is held by holdStandbyPromotion, it records the WAL location
+// replayed by the standby leader. Once that has not changed for the grace
+// period, the former primary has stopped archiving and the status is removed
+// so that the next reconcile promotes cluster.
+func (r *Reconciler) reconcileStandbyPromotion(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	instances *observedInstances, holding bool,
+) (reconcile.Result, error) {
+	standby := cluster.Spec.Standby
+
+	if !holding {
+		if standby != nil && standby.Enabled {
+			cluster.Status.Standby = &v1beta1.PostgresStandbyStatus{}
+		} else if cluster.Status.Standby != nil {
+			cluster.Status.Standby = nil
+			forceStanzaCheck(cluster)
+			r.Recorder.Event(cluster, corev1.EventTypeNormal, EventStandbyPromoted,
+				"Promoting the standby cluster")
+		}
+		return reconcile.Result{}, nil
+	}
+
+	now := metav1.Now()
+	status := cluster.Status.Standby
+	grace := time.Duration(*standby.PromotionGracePeriodSeconds) * time.Second
+
+	if status.PromotionRequestTime == nil {
+		status.PromotionRequestTime = &now
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, EventStandbyPromotionPending,
+			"Waiting for WAL replay to stop for %v before promoting", grace)
+	}
+
+	var leader *corev1.Pod
+	for _, instance := range instances.forCluster {
+		for _, pod := range instance.Pods {
+			if running, known := instance.IsRunning(naming.ContainerDatabase); running &&
+				known && patroni.PodIsStandbyLeader(pod) {
+				leader = pod
+			}
+		}
+	}
+
+	// Check again in a little while when there is no standby leader.
+	if leader == nil {
+		return reconcile.Result{RequeueAfter: grace}, nil
+	}
+
+	exec := func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+		return r.PodExec(ctx, leader.Namespace, leader.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+	}
+
+	lsn, err := postgres.LastReplayedLocation(ctx, exec)
+	if err != nil {
+		return reconcile.Result{}, errors.WithStack(err)
+	}
+
+	if lsn != status.ReplayLSN || status.ReplayTime == nil {
+		status.ReplayLSN = lsn
+		status.ReplayTime = &now
+	}
+
+	if remaining := status.ReplayTime.Add(grace).Sub(now.Time); remaining > 0 {
+		return reconcile.Result{RequeueAfter: remaining}, nil
+	}
+
+	cluster.Status.Standby = nil
+	forceStanzaCheck(cluster)
+	r.Recorder.Eventf(cluster, corev1.EventTypeNormal, EventStandbyPromoted,
+		"WAL replay stopped at %q for %v; promoting the standby cluster", lsn, grace)
+	return reconcile.Result{Requeue: true}, nil
+}
+
+// forceStanzaCheck has reconcileStanzaHealth verify the stanzas of a cluster
+// that was just promoted rather than waiting for the next interval. A stanza
+// that no longer matches PostgreSQL is upgraded then.
+func forceStanzaCheck(cluster *v1beta1.PostgresCluster) {
+	if cluster.Status.PGBackRest != nil {
+		cluster.Status.PGBackRest.StanzaCheckTime = nil
+	}
+}
@@ -0,0 +1,178 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestHoldStandbyPromotion(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	assert.Assert(t, !holdStandbyPromotion(cluster))
+
+	cluster.Spec.Standby = &v1beta1.PostgresStandbySpec{Enabled: true}
+	cluster.Status.Standby = &v1beta1.PostgresStandbyStatus{}
+	assert.Assert(t, !holdStandbyPromotion(cluster))
+
+	// No grace period.
+	cluster.Spec.Standby.Enabled = false
+	assert.Assert(t, !holdStandbyPromotion(cluster))
+	assert.Assert(t, !cluster.Spec.Standby.Enabled)
+
+	cluster.Spec.Standby.PromotionGracePeriodSeconds = initialize.Int32(0)
+	assert.Assert(t, !holdStandbyPromotion(cluster))
+
+	// Never a standby.
+	cluster.Spec.Standby.PromotionGracePeriodSeconds = initialize.Int32(60)
+	cluster.Status.Standby = nil
+	assert.Assert(t, !holdStandbyPromotion(cluster))
+
+	cluster.Status.Standby = &v1beta1.PostgresStandbyStatus{}
+	assert.Assert(t, holdStandbyPromotion(cluster))
+	assert.Assert(t, !cluster.Spec.Standby.Enabled, "expected no change to spec")
+	assert.Assert(t, cluster.StandbyEnabled())
+}
+
+func TestReconcileStandbyPromotion(t *testing.T) {
+	ctx := context.Background()
+
+	newCluster := func() *v1beta1.PostgresCluster {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Namespace, cluster.Name = "ns1", "hippo"
+		cluster.Spec.Standby = &v1beta1.PostgresStandbySpec{
+			Enabled:                     true,
+			PromotionGracePeriodSeconds: initialize.Int32(60),
+		}
+		cluster.Status.Standby = &v1beta1.PostgresStandbyStatus{}
+		return cluster
+	}
+
+	leader := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "ns1",
+		Name:        "hippo-abcd-0",
+		Annotations: map[string]string{"status": `{"role":"standby_leader"}`},
+	}}
+	leader.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  naming.ContainerDatabase,
+		State: corev1.ContainerState{Running: new(corev1.ContainerStateRunning)},
+	}}
+	instances := &observedInstances{forCluster: []*Instance{{
+		Name: "hippo-abcd", Pods: []*corev1.Pod{leader},
+	}}}
+
+	setup := func(lsn string) (*Reconciler, *record.FakeRecorder) {
+		recorder := record.NewFakeRecorder(10)
+		return &Reconciler{
+			Recorder: recorder,
+			PodExec: func(
+				_ context.Context, namespace, pod, container string,
+				stdin io.Reader, stdout, stderr io.Writer, command ...string,
+			) error {
+				assert.Equal(t, pod, "hippo-abcd-0")
+				assert.Equal(t, container, naming.ContainerDatabase)
+				_, err := stdout.Write([]byte(lsn + "\n"))
+				return err
+			},
+		}, recorder
+	}
+
+	t.Run("Standby", func(t *testing.T) {
+		r, _ := setup("")
+		cluster := newCluster()
+		cluster.Status.Standby = nil
+
+		result, err := r.reconcileStandbyPromotion(ctx, cluster, instances, false)
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.DeepEqual(t, cluster.Status.Standby, &v1beta1.PostgresStandbyStatus{})
+	})
+
+	t.Run("Promoted", func(t *testing.T) {
+		r, recorder := setup("")
+		cluster := newCluster()
+		cluster.Spec.Standby.Enabled = false
+		cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+			StanzaCheckTime: &metav1.Time{Time: time.Now()},
+		}
+
+		result, err := r.reconcileStandbyPromotion(ctx, cluster, instances, false)
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{})
+		assert.Assert(t, cluster.Status.Standby == nil)
+		assert.Assert(t, cluster.Status.PGBackRest.StanzaCheckTime == nil,
+			"expected stanzas to be verified after promotion")
+		assert.Assert(t, strings.Contains(<-recorder.Events, EventStandbyPromoted))
+	})
+
+	t.Run("NoLeader", func(t *testing.T) {
+		r, recorder := setup("")
+		cluster := newCluster()
+		cluster.Spec.Standby.Enabled = false
+
+		result, err := r.reconcileStandbyPromotion(ctx, cluster,
+			&observedInstances{}, true)
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{RequeueAfter: time.Minute})
+		assert.Assert(t, cluster.Status.Standby.PromotionRequestTime != nil)
+		assert.Assert(t, strings.Contains(<-recorder.Events, EventStandbyPromotionPending))
+	})
+
+	t.Run("Replaying", func(t *testing.T) {
+		r, _ := setup("0/3000060")
+		cluster := newCluster()
+		cluster.Spec.Standby.Enabled = false
+		earlier := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+		cluster.Status.Standby.PromotionRequestTime = &earlier
+		cluster.Status.Standby.ReplayLSN = "0/3000000"
+		cluster.Status.Standby.ReplayTime = &earlier
+
+		result, err := r.reconcileStandbyPromotion(ctx, cluster, instances, true)
+		assert.NilError(t, err)
+		assert.Assert(t, result.RequeueAfter > 0)
+		assert.Equal(t, cluster.Status.Standby.ReplayLSN, "0/3000060")
+		assert.Assert(t, cluster.Status.Standby.ReplayTime.After(earlier.Time))
+	})
+
+	t.Run("Stopped", func(t *testing.T) {
+		r, recorder := setup("0/3000060")
+		cluster := newCluster()
+		cluster.Spec.Standby.Enabled = false
+		earlier := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+		cluster.Status.Standby.PromotionRequestTime = &earlier
+		cluster.Status.Standby.ReplayLSN = "0/3000060"
+		cluster.Status.Standby.ReplayTime = &earlier
+
+		result, err := r.reconcileStandbyPromotion(ctx, cluster, instances, true)
+		assert.NilError(t, err)
+		assert.Equal(t, result, reconcile.Result{Requeue: true})
+		assert.Assert(t, cluster.Status.Standby == nil)
+		assert.Assert(t, strings.Contains(<-recorder.Events, "0/3000060"))
+	})
+}
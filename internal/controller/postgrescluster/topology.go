@@ -36,3 +36,75 @@ func defaultTopologySpreadConstraints(selector metav1.LabelSelector) []corev1.To
 		},
 	}
 }
+
+// zoneSpreadConstraint returns a constraint that requires pods be scheduled
+// evenly across zones.
+func zoneSpreadConstraint(selector metav1.LabelSelector) corev1.TopologySpreadConstraint {
+	return corev1.TopologySpreadConstraint{
+		TopologyKey:       corev1.LabelTopologyZone,
+		WhenUnsatisfiable: corev1.DoNotSchedule,
+		LabelSelector:     &selector, MaxSkew: 1,
+	}
+}
+
+// zoneSpreadAffinity adds a strong preference to affinity for zones that do
+// not have pods matching primary. A replica that is synchronous should not
+// share a zone with its primary, but Patroni does not label those replicas;
+// this applies to all of them.
+func zoneSpreadAffinity(primary metav1.LabelSelector, affinity *corev1.Affinity) *corev1.Affinity {
+	if affinity == nil {
+		affinity = new(corev1.Affinity)
+	}
+	if affinity.PodAntiAffinity == nil {
+		affinity.PodAntiAffinity = new(corev1.PodAntiAffinity)
+	}
+
+	affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+		affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+		corev1.WeightedPodAffinityTerm{
+			Weight: 100,
+			PodAffinityTerm: corev1.PodAffinityTerm{
+				TopologyKey:   corev1.LabelTopologyZone,
+				LabelSelector: &primary,
+			},
+		})
+
+	return affinity
+}
+
+// architectureAffinity returns a copy of affinity that also requires nodes of
+// one of architectures. Node selector terms are ORed, so the requirement is
+// added to every term. When architectures is empty, affinity is returned as-is.
+func architectureAffinity(architectures []string, affinity *corev1.Affinity) *corev1.Affinity {
+	if len(architectures) == 0 {
+		return affinity
+	}
+
+	requirement := corev1.NodeSelectorRequirement{
+		Key:      corev1.LabelArchStable,
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   architectures,
+	}
+
+	affinity = affinity.DeepCopy()
+	if affinity == nil {
+		affinity = new(corev1.Affinity)
+	}
+	if affinity.NodeAffinity == nil {
+		affinity.NodeAffinity = new(corev1.NodeAffinity)
+	}
+	if affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = new(corev1.NodeSelector)
+	}
+
+	required := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(required.NodeSelectorTerms) == 0 {
+		required.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+	for i := range required.NodeSelectorTerms {
+		required.NodeSelectorTerms[i].MatchExpressions = append(
+			required.NodeSelectorTerms[i].MatchExpressions, requirement)
+	}
+
+	return affinity
+}
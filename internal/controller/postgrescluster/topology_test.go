@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -58,3 +59,130 @@ func TestDefaultTopologySpreadConstraints(t *testing.T) {
   whenUnsatisfiable: ScheduleAnyway
 	`))
 }
+
+func TestZoneSpreadConstraint(t *testing.T) {
+	constraint := zoneSpreadConstraint(metav1.LabelSelector{
+		MatchLabels: map[string]string{"basic": "stuff"},
+	})
+
+	// Entire selector, zone, and DoNotSchedule.
+	assert.Assert(t, marshalMatches(constraint, `
+labelSelector:
+  matchLabels:
+    basic: stuff
+maxSkew: 1
+topologyKey: topology.kubernetes.io/zone
+whenUnsatisfiable: DoNotSchedule
+	`))
+}
+
+func TestZoneSpreadAffinity(t *testing.T) {
+	primary := metav1.LabelSelector{MatchLabels: map[string]string{"role": "primary"}}
+
+	t.Run("Empty", func(t *testing.T) {
+		assert.Assert(t, marshalMatches(zoneSpreadAffinity(primary, nil), `
+podAntiAffinity:
+  preferredDuringSchedulingIgnoredDuringExecution:
+  - podAffinityTerm:
+      labelSelector:
+        matchLabels:
+          role: primary
+      topologyKey: topology.kubernetes.io/zone
+    weight: 100
+		`))
+	})
+
+	t.Run("Existing", func(t *testing.T) {
+		affinity := &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{},
+			PodAntiAffinity: &corev1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{
+					Weight: 1, PodAffinityTerm: corev1.PodAffinityTerm{TopologyKey: "custom"},
+				}},
+			},
+		}
+
+		result := zoneSpreadAffinity(primary, affinity)
+		assert.Assert(t, result.NodeAffinity != nil, "expected other affinity to remain")
+		assert.Assert(t, marshalMatches(result.PodAntiAffinity, `
+preferredDuringSchedulingIgnoredDuringExecution:
+- podAffinityTerm:
+    topologyKey: custom
+  weight: 1
+- podAffinityTerm:
+    labelSelector:
+      matchLabels:
+        role: primary
+    topologyKey: topology.kubernetes.io/zone
+  weight: 100
+		`))
+	})
+}
+
+func TestArchitectureAffinity(t *testing.T) {
+	t.Run("Any", func(t *testing.T) {
+		assert.Assert(t, architectureAffinity(nil, nil) == nil)
+
+		affinity := &corev1.Affinity{}
+		assert.Equal(t, architectureAffinity(nil, affinity), affinity)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		assert.Assert(t, marshalMatches(architectureAffinity([]string{"amd64", "arm64"}, nil), `
+nodeAffinity:
+  requiredDuringSchedulingIgnoredDuringExecution:
+    nodeSelectorTerms:
+    - matchExpressions:
+      - key: kubernetes.io/arch
+        operator: In
+        values:
+        - amd64
+        - arm64
+		`))
+	})
+
+	t.Run("Existing", func(t *testing.T) {
+		affinity := &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: "disk", Operator: corev1.NodeSelectorOpIn, Values: []string{"ssd"}},
+						}},
+						{MatchFields: []corev1.NodeSelectorRequirement{
+							{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"n1"}},
+						}},
+					},
+				},
+			},
+		}
+
+		result := architectureAffinity([]string{"arm64"}, affinity)
+		assert.Assert(t, marshalMatches(result.NodeAffinity, `
+requiredDuringSchedulingIgnoredDuringExecution:
+  nodeSelectorTerms:
+  - matchExpressions:
+    - key: disk
+      operator: In
+      values:
+      - ssd
+    - key: kubernetes.io/arch
+      operator: In
+      values:
+      - arm64
+  - matchExpressions:
+    - key: kubernetes.io/arch
+      operator: In
+      values:
+      - arm64
+    matchFields:
+    - key: metadata.name
+      operator: In
+      values:
+      - n1
+		`))
+
+		assert.Equal(t, len(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.
+			NodeSelectorTerms[0].MatchExpressions), 1, "expected no change to the original")
+	})
+}
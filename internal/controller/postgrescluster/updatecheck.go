@@ -0,0 +1,75 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// reconcileUpgradeAvailable populates cluster.Status.UpgradeAvailable when the
+// release index of r.UpdateCheck has a minor release of PostgreSQL that some
+// instance of cluster is not running. It returns when to check again. Nothing
+// changes when the index cannot be loaded.
+func (r *Reconciler) reconcileUpgradeAvailable(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) reconcile.Result {
+	if r.UpdateCheck == nil {
+		cluster.Status.UpgradeAvailable = nil
+		return reconcile.Result{}
+	}
+	next := reconcile.Result{RequeueAfter: r.UpdateCheck.Interval}
+
+	index, loaded, err := r.UpdateCheck.Index(ctx)
+	if err != nil {
+		logging.FromContext(ctx).V(1).Info("unable to load release index", "error", err.Error())
+		return next
+	}
+
+	release, ok := index.Latest(cluster)
+	current, observed := true, false
+	for _, instance := range instances.forCluster {
+		for _, pod := range instance.Pods {
+			for _, status := range pod.Status.ContainerStatuses {
+				if status.Name == naming.ContainerDatabase {
+					observed = true
+					current = current && release.Matches(status.Image, status.ImageID)
+				}
+			}
+		}
+	}
+	if !observed {
+		current = release.Matches(config.PostgresContainerImage(cluster), "")
+	}
+
+	cluster.Status.UpgradeAvailable = nil
+	if ok && !current {
+		checked := metav1.NewTime(loaded)
+		cluster.Status.UpgradeAvailable = &v1beta1.PostgresUpgradeAvailableStatus{
+			Version:   release.Version,
+			Image:     release.Image,
+			CheckTime: &checked,
+		}
+	}
+	return next
+}
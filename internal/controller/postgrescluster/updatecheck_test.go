@@ -0,0 +1,107 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/updatecheck"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestReconcileUpgradeAvailable(t *testing.T) {
+	ctx := context.Background()
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Spec.PostgresVersion = 16
+	cluster.Spec.Image = "example.com/postgres:16.1"
+	cluster.Status.UpgradeAvailable = &v1beta1.PostgresUpgradeAvailableStatus{Version: "old"}
+
+	instances := &observedInstances{}
+	r := &Reconciler{}
+
+	t.Run("Disabled", func(t *testing.T) {
+		result := r.reconcileUpgradeAvailable(ctx, cluster, instances)
+		assert.Equal(t, result.RequeueAfter, time.Duration(0))
+		assert.Assert(t, cluster.Status.UpgradeAvailable == nil)
+	})
+
+	index := `{"postgres": {"16": {"version": "16.2", "image": "example.com/postgres:16.2", "digest": "sha256:abc"}}}`
+	r.UpdateCheck = &updatecheck.Source{
+		Interval: time.Hour,
+		Load:     func(context.Context) ([]byte, error) { return []byte(index), nil },
+	}
+
+	t.Run("NoPods", func(t *testing.T) {
+		result := r.reconcileUpgradeAvailable(ctx, cluster, instances)
+		assert.Equal(t, result.RequeueAfter, time.Hour)
+		assert.Assert(t, cluster.Status.UpgradeAvailable != nil)
+		assert.Equal(t, cluster.Status.UpgradeAvailable.Version, "16.2")
+		assert.Equal(t, cluster.Status.UpgradeAvailable.Image, "example.com/postgres:16.2")
+		assert.Assert(t, cluster.Status.UpgradeAvailable.CheckTime != nil)
+	})
+
+	pod := &corev1.Pod{}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:    naming.ContainerDatabase,
+		Image:   "example.com/postgres:16.2",
+		ImageID: "example.com/postgres@sha256:abc",
+	}}
+	instances.forCluster = []*Instance{{Name: "hippo-00-abcd", Pods: []*corev1.Pod{pod}}}
+
+	t.Run("Current", func(t *testing.T) {
+		r.reconcileUpgradeAvailable(ctx, cluster, instances)
+		assert.Assert(t, cluster.Status.UpgradeAvailable == nil)
+	})
+
+	t.Run("Outdated", func(t *testing.T) {
+		pod := pod.DeepCopy()
+		pod.Status.ContainerStatuses[0].ImageID = "example.com/postgres@sha256:def"
+		instances := &observedInstances{forCluster: []*Instance{{Pods: []*corev1.Pod{pod}}}}
+
+		r.reconcileUpgradeAvailable(ctx, cluster, instances)
+		assert.Assert(t, cluster.Status.UpgradeAvailable != nil)
+		assert.Equal(t, cluster.Status.UpgradeAvailable.Version, "16.2")
+	})
+
+	t.Run("NotInIndex", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.PostgresVersion = 15
+
+		r.reconcileUpgradeAvailable(ctx, cluster, instances)
+		assert.Assert(t, cluster.Status.UpgradeAvailable == nil)
+	})
+
+	t.Run("LoadError", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Status.UpgradeAvailable = &v1beta1.PostgresUpgradeAvailableStatus{Version: "16.2"}
+
+		r := &Reconciler{UpdateCheck: &updatecheck.Source{
+			Interval: time.Hour,
+			Load:     func(context.Context) ([]byte, error) { return nil, errors.New("offline") },
+		}}
+		result := r.reconcileUpgradeAvailable(ctx, cluster, instances)
+		assert.Equal(t, result.RequeueAfter, time.Hour)
+		assert.Assert(t, cluster.Status.UpgradeAvailable != nil, "expected no change")
+	})
+}
@@ -28,6 +28,7 @@ import (
 
 	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 var tmpDirSizeLimit = resource.MustParse("16Mi")
@@ -117,7 +118,7 @@ func addNSSWrapper(image string, imagePullPolicy corev1.PullPolicy, template *co
 	for i, c := range template.Spec.Containers {
 		switch c.Name {
 		case naming.ContainerDatabase, naming.PGBackRestRepoContainerName,
-			naming.PGBackRestRestoreContainerName:
+			naming.PGBackRestRestoreContainerName, naming.ContainerRepair:
 			passwd := fmt.Sprintf(nssWrapperDir, "postgres", "passwd")
 			group := fmt.Sprintf(nssWrapperDir, "postgres", "group")
 			template.Spec.Containers[i].Env = append(template.Spec.Containers[i].Env, []corev1.EnvVar{
@@ -162,6 +163,50 @@ func addNSSWrapper(image string, imagePullPolicy corev1.PullPolicy, template *co
 	template.Spec.InitContainers = append(template.Spec.InitContainers, container)
 }
 
+// addInstanceEnvironment adds the environment variables of spec to the
+// database container of template. Variables that are already set, such as
+// those of the operator, do not change; their names are returned.
+func addInstanceEnvironment(
+	spec *v1beta1.PostgresInstanceSetSpec, template *corev1.PodTemplateSpec,
+) []string {
+	var ignored []string
+
+	for i := range template.Spec.Containers {
+		container := &template.Spec.Containers[i]
+		if container.Name != naming.ContainerDatabase {
+			continue
+		}
+
+		names := make(map[string]bool, len(container.Env))
+		for _, env := range container.Env {
+			names[env.Name] = true
+		}
+		for i := range spec.Env {
+			if names[spec.Env[i].Name] {
+				ignored = append(ignored, spec.Env[i].Name)
+			} else {
+				container.Env = append(container.Env, *spec.Env[i].DeepCopy())
+			}
+		}
+		for i := range spec.EnvFrom {
+			container.EnvFrom = append(container.EnvFrom, *spec.EnvFrom[i].DeepCopy())
+		}
+	}
+
+	return ignored
+}
+
+// setPodNameResolution copies the DNS policy, DNS configuration, and host
+// aliases of cluster to pod. Together they determine how pod resolves names.
+func setPodNameResolution(cluster *v1beta1.PostgresCluster, pod *corev1.PodSpec) {
+	pod.DNSPolicy = cluster.Spec.DNSPolicy
+	pod.DNSConfig = cluster.Spec.DNSConfig.DeepCopy()
+	pod.HostAliases = nil
+	for i := range cluster.Spec.HostAliases {
+		pod.HostAliases = append(pod.HostAliases, *cluster.Spec.HostAliases[i].DeepCopy())
+	}
+}
+
 // jobFailed returns "true" if the Job provided has failed.  Otherwise it returns "false".
 func jobFailed(job *batchv1.Job) bool {
 	conditions := job.Status.Conditions
@@ -28,7 +28,9 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 func TestSafeHash32(t *testing.T) {
@@ -472,3 +474,85 @@ func TestJobFailed(t *testing.T) {
 		})
 	}
 }
+
+func TestSetPodNameResolution(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	pod := &corev1.PodSpec{}
+
+	setPodNameResolution(cluster, pod)
+	assert.Equal(t, pod.DNSPolicy, corev1.DNSPolicy(""))
+	assert.Assert(t, pod.DNSConfig == nil)
+
+	cluster.Spec.DNSPolicy = corev1.DNSNone
+	cluster.Spec.DNSConfig = &corev1.PodDNSConfig{
+		Nameservers: []string{"169.254.20.10"},
+		Searches:    []string{"db.example.com"},
+		Options:     []corev1.PodDNSConfigOption{{Name: "ndots", Value: initialize.String("2")}},
+	}
+
+	setPodNameResolution(cluster, pod)
+	assert.Equal(t, pod.DNSPolicy, corev1.DNSNone)
+	assert.DeepEqual(t, pod.DNSConfig, cluster.Spec.DNSConfig)
+
+	pod.DNSConfig.Searches[0] = "changed"
+	assert.Equal(t, cluster.Spec.DNSConfig.Searches[0], "db.example.com", "expected a copy")
+
+	t.Run("HostAliases", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.HostAliases = []corev1.HostAlias{
+			{IP: "10.0.0.5", Hostnames: []string{"standby.example.com"}},
+		}
+
+		setPodNameResolution(cluster, pod)
+		assert.DeepEqual(t, pod.HostAliases, cluster.Spec.HostAliases)
+
+		pod.HostAliases[0].Hostnames[0] = "changed"
+		assert.Equal(t, cluster.Spec.HostAliases[0].Hostnames[0], "standby.example.com",
+			"expected a copy")
+
+		setPodNameResolution(&v1beta1.PostgresCluster{}, pod)
+		assert.Assert(t, pod.HostAliases == nil)
+	})
+}
+
+func TestAddInstanceEnvironment(t *testing.T) {
+	template := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Name: naming.ContainerDatabase, Env: []corev1.EnvVar{{Name: "PGDATA", Value: "/pgdata"}}},
+			{Name: naming.PGBackRestRepoContainerName},
+		},
+	}}
+
+	spec := &v1beta1.PostgresInstanceSetSpec{Name: "instance1"}
+	assert.Assert(t, addInstanceEnvironment(spec, template.DeepCopy()) == nil)
+
+	spec.Env = []corev1.EnvVar{
+		{Name: "HTTPS_PROXY", Value: "http://proxy:3128"},
+		{Name: "PGDATA", Value: "/elsewhere"},
+	}
+	spec.EnvFrom = []corev1.EnvFromSource{{
+		SecretRef: &corev1.SecretEnvSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "krb5"},
+		},
+	}}
+
+	ignored := addInstanceEnvironment(spec, template)
+	assert.DeepEqual(t, ignored, []string{"PGDATA"})
+
+	assert.Assert(t, marshalMatches(template.Spec.Containers[0], `
+env:
+- name: PGDATA
+  value: /pgdata
+- name: HTTPS_PROXY
+  value: http://proxy:3128
+envFrom:
+- secretRef:
+    name: krb5
+name: database
+resources: {}
+	`))
+	assert.Assert(t, marshalMatches(template.Spec.Containers[1], `
+name: pgbackrest
+resources: {}
+	`))
+}
@@ -0,0 +1,321 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+const (
+	// ConditionDegraded is the type used in a condition to indicate that the
+	// PostgresCluster is running with reduced protection of its data.
+	ConditionDegraded = "Degraded"
+
+	// reasonWALArchiveSacrificed is the reason of a true ConditionDegraded when
+	// completed WAL files are discarded rather than archived.
+	reasonWALArchiveSacrificed = "WALArchiveSacrificed"
+)
+
+// walArchiveSacrificed returns true when cluster should discard completed WAL
+// files rather than archive them. See Reconciler.reconcileWALArchiveSacrifice.
+func walArchiveSacrificed(cluster *v1beta1.PostgresCluster) bool {
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, ConditionDegraded)
+	return condition != nil &&
+		condition.Status == metav1.ConditionTrue &&
+		condition.Reason == reasonWALArchiveSacrificed
+}
+
+// +kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+
+// reconcileVolumeUsage measures the data and WAL volumes of every running
+// instance of cluster at most once per interval and reports them in status.
+// A Warning event is emitted when a volume fills past the configured percent.
+func (r *Reconciler) reconcileVolumeUsage(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) (reconcile.Result, error) {
+	spec := cluster.Spec.VolumeUsage
+	if spec == nil {
+		for i := range cluster.Status.InstanceSets {
+			cluster.Status.InstanceSets[i].Volumes = nil
+		}
+		return reconcile.Result{}, nil
+	}
+
+	interval := 300 * time.Second
+	if spec.IntervalSeconds != nil {
+		interval = time.Duration(*spec.IntervalSeconds) * time.Second
+	}
+	warning := int32(80)
+	if spec.WarningPercent != nil {
+		warning = *spec.WarningPercent
+	}
+
+	log := logging.FromContext(ctx)
+	now := metav1.Now()
+
+	for i := range cluster.Status.InstanceSets {
+		status := &cluster.Status.InstanceSets[i]
+
+		for _, instance := range instances.bySet[status.Name] {
+			if running, known := instance.IsRunning(naming.ContainerDatabase); !running || !known {
+				continue
+			}
+
+			pod := instance.Pods[0]
+			previous := make(map[string]v1beta1.PostgresVolumeUsageStatus)
+			for _, volume := range status.Volumes {
+				if volume.Pod == pod.Name {
+					previous[volume.Volume] = volume
+				}
+			}
+
+			// Measure each Pod no more often than the interval.
+			if volume, ok := previous[naming.RolePostgresData]; ok &&
+				now.Sub(volume.LastObservedTime.Time) < interval {
+				continue
+			}
+
+			measured, err := r.measureVolumeUsage(ctx, pod)
+			if err != nil {
+				// Measurements are informational; try again next interval.
+				log.V(1).Info("unable to measure volumes", "pod", pod.Name, "error", err.Error())
+				continue
+			}
+
+			// Replace the previous measurements of this Pod.
+			volumes := status.Volumes[:0]
+			for _, volume := range status.Volumes {
+				if volume.Pod != pod.Name {
+					volumes = append(volumes, volume)
+				}
+			}
+			for _, volume := range measured {
+				volume.LastObservedTime = now
+
+				// Warn only when a volume crosses the threshold, not every time
+				// it is measured above it.
+				if volume.UsedPercent >= warning &&
+					(previous[volume.Volume].Pod == "" || previous[volume.Volume].UsedPercent < warning) {
+					volumeUsageWarningsTotal.WithLabelValues(volume.Volume).Inc()
+					r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "VolumeLowSpace",
+						"Volume %q of Pod %q is %d%% full (%s of %s)",
+						volume.Volume, volume.Pod, volume.UsedPercent,
+						volume.Used.String(), volume.Capacity.String())
+				}
+				volumes = append(volumes, volume)
+			}
+			status.Volumes = volumes
+		}
+
+		sort.Slice(status.Volumes, func(i, j int) bool {
+			a, b := status.Volumes[i], status.Volumes[j]
+			return a.Pod < b.Pod || (a.Pod == b.Pod && a.Volume < b.Volume)
+		})
+	}
+
+	return reconcile.Result{RequeueAfter: interval}, nil
+}
+
+// measureVolumeUsage calls "df" in the database container of pod to measure
+// its PostgreSQL volumes.
+func (r *Reconciler) measureVolumeUsage(
+	ctx context.Context, pod *corev1.Pod,
+) ([]v1beta1.PostgresVolumeUsageStatus, error) {
+	kinds := []string{naming.RolePostgresData}
+	command := []string{"df", "--portability", "--block-size=1", postgres.DataVolumeMount().MountPath}
+
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == postgres.WALVolumeMount().Name {
+			kinds = append(kinds, naming.RolePostgresWAL)
+			command = append(command, postgres.WALVolumeMount().MountPath)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := errors.WithStack(r.PodExec(ctx, pod.Namespace, pod.Name, naming.ContainerDatabase,
+		nil, &stdout, &stderr, command...))
+	if err != nil {
+		return nil, errors.WithMessage(err, stderr.String())
+	}
+
+	measured, err := parseVolumeUsage(stdout.String(), kinds)
+	for i := range measured {
+		measured[i].Pod = pod.Name
+	}
+	return measured, err
+}
+
+// parseVolumeUsage interprets the output of "df --portability --block-size=1"
+// called with one path per kind of volume, in the same order.
+func parseVolumeUsage(output string, kinds []string) ([]v1beta1.PostgresVolumeUsageStatus, error) {
+	// The first line is a header. POSIX output has one line per path with six
+	// fields: filesystem, size, used, available, capacity, and mount point.
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != len(kinds)+1 {
+		return nil, errors.Errorf("unexpected df output: %q", output)
+	}
+
+	result := make([]v1beta1.PostgresVolumeUsageStatus, 0, len(kinds))
+	for i, kind := range kinds {
+		fields := strings.Fields(lines[i+1])
+		if len(fields) < 6 {
+			return nil, errors.Errorf("unexpected df output: %q", lines[i+1])
+		}
+
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		used, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		var percent int32
+		if size > 0 {
+			percent = int32((used*100 + size - 1) / size)
+		}
+
+		result = append(result, v1beta1.PostgresVolumeUsageStatus{
+			Volume:      kind,
+			Capacity:    *resource.NewQuantity(size, resource.BinarySI),
+			Used:        *resource.NewQuantity(used, resource.BinarySI),
+			UsedPercent: percent,
+		})
+	}
+	return result, nil
+}
+
+// +kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+
+// reconcileWALArchiveSacrifice protects the primary of cluster from filling its
+// WAL volume when WAL archiving is failing. When the volume is past the
+// configured percent and archiving is failing, it sets ConditionDegraded and
+// requeues so the next reconcile changes archive_command to discard WAL files.
+// Archiving is restored once usage falls below the warning percent.
+func (r *Reconciler) reconcileWALArchiveSacrifice(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) (reconcile.Result, error) {
+	spec := cluster.Spec.VolumeUsage
+	sacrificed := walArchiveSacrificed(cluster)
+
+	resume := func(message string) {
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			ObservedGeneration: cluster.GetGeneration(),
+			Type:               ConditionDegraded,
+			Status:             metav1.ConditionFalse,
+			Reason:             "WALArchiveResumed",
+			Message:            message,
+		})
+		r.Recorder.Event(cluster, corev1.EventTypeNormal, "WALArchiveResumed",
+			message+"; take a full backup to restore point-in-time recovery")
+	}
+
+	if spec == nil || spec.ArchiveSacrificePercent == nil {
+		if sacrificed {
+			resume("WAL archiving resumed because protection is disabled")
+			return reconcile.Result{Requeue: true}, nil
+		}
+		return reconcile.Result{}, nil
+	}
+
+	warning := int32(80)
+	if spec.WarningPercent != nil {
+		warning = *spec.WarningPercent
+	}
+
+	// Find the primary and the measurement of the volume that holds its WAL.
+	var primary *corev1.Pod
+	for _, instance := range instances.forCluster {
+		if writable, known := instance.IsWritable(); writable && known {
+			primary = instance.Pods[0]
+		}
+	}
+	if primary == nil {
+		return reconcile.Result{}, nil
+	}
+
+	var usage *v1beta1.PostgresVolumeUsageStatus
+	for i := range cluster.Status.InstanceSets {
+		for j, volume := range cluster.Status.InstanceSets[i].Volumes {
+			if volume.Pod != primary.Name {
+				continue
+			}
+			if volume.Volume == naming.RolePostgresWAL ||
+				(volume.Volume == naming.RolePostgresData && usage == nil) {
+				usage = &cluster.Status.InstanceSets[i].Volumes[j]
+			}
+		}
+	}
+	if usage == nil {
+		return reconcile.Result{}, nil
+	}
+
+	if sacrificed {
+		if usage.UsedPercent < warning {
+			resume(fmt.Sprintf("WAL archiving resumed on %q at %d%% full",
+				primary.Name, usage.UsedPercent))
+			return reconcile.Result{Requeue: true}, nil
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if usage.UsedPercent < *spec.ArchiveSacrificePercent {
+		return reconcile.Result{}, nil
+	}
+
+	exec := func(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+		return r.PodExec(ctx, primary.Namespace, primary.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+	}
+
+	failing, err := postgres.ArchiveFailing(ctx, exec)
+	if err = errors.WithStack(err); err == nil && failing {
+		message := fmt.Sprintf(
+			"WAL archiving is failing and volume %q of %q is %d%% full; discarding WAL files",
+			usage.Volume, primary.Name, usage.UsedPercent)
+
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			ObservedGeneration: cluster.GetGeneration(),
+			Type:               ConditionDegraded,
+			Status:             metav1.ConditionTrue,
+			Reason:             reasonWALArchiveSacrificed,
+			Message:            message,
+		})
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, reasonWALArchiveSacrificed, message)
+		return reconcile.Result{Requeue: true}, nil
+	}
+
+	return reconcile.Result{}, err
+}
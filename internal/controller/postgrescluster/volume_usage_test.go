@@ -0,0 +1,269 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestParseVolumeUsage(t *testing.T) {
+	output := strings.Join([]string{
+		"Filesystem     1-blocks       Used  Available Capacity Mounted on",
+		"/dev/sdb     1073741824  536870912  536870912      50% /pgdata",
+		"/dev/sdc      104857600  104857599          1     100% /pgwal",
+	}, "\n")
+
+	volumes, err := parseVolumeUsage(output, []string{"pgdata", "pgwal"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(volumes), 2)
+
+	assert.Equal(t, volumes[0].Volume, "pgdata")
+	assert.Equal(t, volumes[0].Capacity.String(), "1Gi")
+	assert.Equal(t, volumes[0].Used.String(), "512Mi")
+	assert.Equal(t, volumes[0].UsedPercent, int32(50))
+
+	assert.Equal(t, volumes[1].Volume, "pgwal")
+	assert.Equal(t, volumes[1].UsedPercent, int32(100), "expected rounding up")
+
+	_, err = parseVolumeUsage(output, []string{"pgdata"})
+	assert.ErrorContains(t, err, "unexpected")
+
+	_, err = parseVolumeUsage("header\n/dev/sdb x y", []string{"pgdata"})
+	assert.ErrorContains(t, err, "unexpected")
+}
+
+func TestReconcileVolumeUsage(t *testing.T) {
+	ctx := context.Background()
+
+	var calls [][]string
+	used := "536870912"
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &Reconciler{
+		Recorder: recorder,
+		PodExec: func(_ context.Context, namespace, pod, container string, stdin io.Reader, stdout,
+			stderr io.Writer, command ...string) error {
+			calls = append(calls, command)
+			_, err := io.WriteString(stdout, strings.Join([]string{
+				"Filesystem 1-blocks Used Available Capacity Mounted on",
+				"/dev/sdb 1073741824 " + used + " 0 0% /pgdata",
+				"/dev/sdc 1073741824 1024 0 0% /pgwal",
+			}, "\n"))
+			return err
+		},
+	}
+
+	pod := &corev1.Pod{}
+	pod.Namespace, pod.Name = "ns1", "hippo-00-abcd-0"
+	pod.Spec.Volumes = []corev1.Volume{{Name: postgres.WALVolumeMount().Name}}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  naming.ContainerDatabase,
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}}
+
+	instances := &observedInstances{bySet: map[string][]*Instance{
+		"00": {{Name: "hippo-00-abcd", Pods: []*corev1.Pod{pod}, Runner: &appsv1.StatefulSet{}}},
+	}}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Status.InstanceSets = []v1beta1.PostgresInstanceSetStatus{{Name: "00"}}
+
+	t.Run("Disabled", func(t *testing.T) {
+		result, err := reconciler.reconcileVolumeUsage(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, time.Duration(0))
+		assert.Equal(t, len(calls), 0)
+		assert.Assert(t, cluster.Status.InstanceSets[0].Volumes == nil)
+	})
+
+	cluster.Spec.VolumeUsage = &v1beta1.PostgresVolumeUsageSpec{
+		IntervalSeconds: initialize.Int32(60),
+		WarningPercent:  initialize.Int32(75),
+	}
+
+	t.Run("Measure", func(t *testing.T) {
+		result, err := reconciler.reconcileVolumeUsage(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, time.Minute)
+		assert.Equal(t, len(calls), 1)
+		assert.DeepEqual(t, calls[0],
+			[]string{"df", "--portability", "--block-size=1", "/pgdata", "/pgwal"})
+
+		volumes := cluster.Status.InstanceSets[0].Volumes
+		assert.Equal(t, len(volumes), 2)
+		assert.Equal(t, volumes[0].Pod, pod.Name)
+		assert.Equal(t, volumes[0].Volume, "pgdata")
+		assert.Equal(t, volumes[0].UsedPercent, int32(50))
+		assert.Equal(t, volumes[1].Volume, "pgwal")
+		assert.Equal(t, volumes[1].UsedPercent, int32(1))
+		assert.Equal(t, len(recorder.Events), 0)
+
+		// Nothing is measured again until the interval passes.
+		_, err = reconciler.reconcileVolumeUsage(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, len(calls), 1)
+	})
+
+	t.Run("Warning", func(t *testing.T) {
+		used = "858993459" // 80%
+		for i := range cluster.Status.InstanceSets[0].Volumes {
+			cluster.Status.InstanceSets[0].Volumes[i].LastObservedTime =
+				metav1.NewTime(time.Now().Add(-time.Hour))
+		}
+
+		_, err := reconciler.reconcileVolumeUsage(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, len(calls), 2)
+		assert.Equal(t, cluster.Status.InstanceSets[0].Volumes[0].UsedPercent, int32(80))
+
+		assert.Equal(t, len(recorder.Events), 1)
+		event := <-recorder.Events
+		assert.Assert(t, strings.HasPrefix(event, "Warning VolumeLowSpace"), "got %q", event)
+		assert.Assert(t, strings.Contains(event, `"pgdata"`))
+
+		// No more events while the volume stays above the threshold.
+		for i := range cluster.Status.InstanceSets[0].Volumes {
+			cluster.Status.InstanceSets[0].Volumes[i].LastObservedTime =
+				metav1.NewTime(time.Now().Add(-time.Hour))
+		}
+		_, err = reconciler.reconcileVolumeUsage(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Equal(t, len(calls), 3)
+		assert.Equal(t, len(recorder.Events), 0)
+	})
+}
+
+func TestReconcileWALArchiveSacrifice(t *testing.T) {
+	ctx := context.Background()
+
+	var calls int
+	failing := "t"
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &Reconciler{
+		Recorder: recorder,
+		PodExec: func(_ context.Context, namespace, pod, container string, stdin io.Reader, stdout,
+			stderr io.Writer, command ...string) error {
+			calls++
+			assert.Equal(t, pod, "hippo-00-abcd-0")
+			_, err := io.WriteString(stdout, failing+"\n")
+			return err
+		},
+	}
+
+	pod := &corev1.Pod{}
+	pod.Namespace, pod.Name = "ns1", "hippo-00-abcd-0"
+	pod.Annotations = map[string]string{"status": `{"role":"master"}`}
+
+	instances := &observedInstances{forCluster: []*Instance{
+		{Name: "hippo-00-abcd", Pods: []*corev1.Pod{pod}, Runner: &appsv1.StatefulSet{}},
+	}}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.VolumeUsage = &v1beta1.PostgresVolumeUsageSpec{
+		ArchiveSacrificePercent: initialize.Int32(90),
+	}
+	cluster.Status.InstanceSets = []v1beta1.PostgresInstanceSetStatus{{
+		Name: "00",
+		Volumes: []v1beta1.PostgresVolumeUsageStatus{
+			{Pod: pod.Name, Volume: "pgdata", UsedPercent: 10},
+			{Pod: pod.Name, Volume: "pgwal", UsedPercent: 50},
+		},
+	}}
+	wal := &cluster.Status.InstanceSets[0].Volumes[1]
+
+	t.Run("BelowThreshold", func(t *testing.T) {
+		result, err := reconciler.reconcileWALArchiveSacrifice(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Assert(t, !result.Requeue)
+		assert.Equal(t, calls, 0, "expected no query")
+		assert.Assert(t, !walArchiveSacrificed(cluster))
+	})
+
+	t.Run("ArchivingSucceeds", func(t *testing.T) {
+		wal.UsedPercent, failing = 95, "f"
+
+		result, err := reconciler.reconcileWALArchiveSacrifice(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Assert(t, !result.Requeue)
+		assert.Equal(t, calls, 1)
+		assert.Assert(t, !walArchiveSacrificed(cluster))
+		assert.Equal(t, len(recorder.Events), 0)
+	})
+
+	t.Run("Sacrifice", func(t *testing.T) {
+		wal.UsedPercent, failing = 95, "t"
+
+		result, err := reconciler.reconcileWALArchiveSacrifice(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Assert(t, result.Requeue)
+		assert.Equal(t, calls, 2)
+		assert.Assert(t, walArchiveSacrificed(cluster))
+
+		event := <-recorder.Events
+		assert.Assert(t, strings.HasPrefix(event, "Warning WALArchiveSacrificed"), "got %q", event)
+		assert.Assert(t, strings.Contains(event, `"pgwal"`))
+
+		// Archiving stays suspended until usage falls below the warning percent.
+		wal.UsedPercent = 85
+		result, err = reconciler.reconcileWALArchiveSacrifice(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Assert(t, !result.Requeue)
+		assert.Equal(t, calls, 2)
+		assert.Assert(t, walArchiveSacrificed(cluster))
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		wal.UsedPercent = 20
+
+		result, err := reconciler.reconcileWALArchiveSacrifice(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Assert(t, result.Requeue)
+		assert.Assert(t, !walArchiveSacrificed(cluster))
+
+		event := <-recorder.Events
+		assert.Assert(t, strings.HasPrefix(event, "Normal WALArchiveResumed"), "got %q", event)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		wal.UsedPercent = 95
+		_, err := reconciler.reconcileWALArchiveSacrifice(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Assert(t, walArchiveSacrificed(cluster))
+		<-recorder.Events
+
+		cluster.Spec.VolumeUsage.ArchiveSacrificePercent = nil
+		result, err := reconciler.reconcileWALArchiveSacrifice(ctx, cluster, instances)
+		assert.NilError(t, err)
+		assert.Assert(t, result.Requeue)
+		assert.Assert(t, !walArchiveSacrificed(cluster))
+	})
+}
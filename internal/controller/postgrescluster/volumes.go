@@ -130,6 +130,26 @@ func (r *Reconciler) observePersistentVolumeClaims(
 	return volumes.Items, err
 }
 
+// existingPGDataVolume returns the existing pgData volume to use in cluster,
+// if any. It is either defined in spec.dataSource.volumes or the volume of an
+// adopted Patroni leader.
+func existingPGDataVolume(cluster *v1beta1.PostgresCluster) *v1beta1.DataSourceVolume {
+	if cluster.Spec.DataSource == nil {
+		return nil
+	}
+	if cluster.Spec.DataSource.Volumes != nil &&
+		cluster.Spec.DataSource.Volumes.PGDataVolume != nil {
+		return cluster.Spec.DataSource.Volumes.PGDataVolume
+	}
+	if cluster.Spec.DataSource.Patroni != nil && cluster.Status.Adoption != nil {
+		return &v1beta1.DataSourceVolume{
+			PVCName:   cluster.Status.Adoption.PGDataVolume,
+			Directory: cluster.Spec.DataSource.Patroni.Directory,
+		}
+	}
+	return nil
+}
+
 // configureExistingPVCs configures the defined pgData, pg_wal and pgBackRest
 // repo volumes to be used by the PostgresCluster. In the case of existing
 // pgData volumes, an appropriate instance set name is defined that will be
@@ -143,9 +163,7 @@ func (r *Reconciler) configureExistingPVCs(
 
 	var err error
 
-	if cluster.Spec.DataSource != nil &&
-		cluster.Spec.DataSource.Volumes != nil &&
-		cluster.Spec.DataSource.Volumes.PGDataVolume != nil {
+	if existingPGDataVolume(cluster) != nil {
 		// If the startup instance name isn't set, use the instance set defined at position zero.
 		if cluster.Status.StartupInstance == "" {
 			set := &cluster.Spec.InstanceSets[0]
@@ -190,15 +208,13 @@ func (r *Reconciler) configureExistingPGVolumes(
 
 	// if the volume is already in the list, move on
 	for i := range volumes {
-		if cluster.Spec.DataSource.Volumes.PGDataVolume.
-			PVCName == volumes[i].Name {
+		if existingPGDataVolume(cluster).PVCName == volumes[i].Name {
 			return volumes, nil
 		}
 	}
 
 	if len(cluster.Spec.InstanceSets) > 0 {
-		if volName := cluster.Spec.DataSource.Volumes.
-			PGDataVolume.PVCName; volName != "" {
+		if volName := existingPGDataVolume(cluster).PVCName; volName != "" {
 			volume := &corev1.PersistentVolumeClaim{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      volName,
@@ -339,7 +355,7 @@ func (r *Reconciler) reconcileDirMoveJobs(ctx context.Context,
 	cluster *v1beta1.PostgresCluster) (bool, error) {
 
 	if cluster.Spec.DataSource != nil &&
-		cluster.Spec.DataSource.Volumes != nil {
+		(cluster.Spec.DataSource.Volumes != nil || existingPGDataVolume(cluster) != nil) {
 
 		moveJobs := &batchv1.JobList{}
 		if err := r.Client.List(ctx, moveJobs, &client.ListOptions{
@@ -351,15 +367,14 @@ func (r *Reconciler) reconcileDirMoveJobs(ctx context.Context,
 		var err error
 		var pgDataReturn, pgWALReturn, repoReturn bool
 
-		if cluster.Spec.DataSource.Volumes.PGDataVolume != nil &&
-			cluster.Spec.DataSource.Volumes.PGDataVolume.
-				Directory != "" &&
-			cluster.Spec.DataSource.Volumes.PGDataVolume.
-				PVCName != "" {
+		if pgData := existingPGDataVolume(cluster); pgData != nil &&
+			pgData.Directory != "" &&
+			pgData.PVCName != "" {
 			pgDataReturn, err = r.reconcileMovePGDataDir(ctx, cluster, moveJobs)
 		}
 
 		if err == nil &&
+			cluster.Spec.DataSource.Volumes != nil &&
 			cluster.Spec.DataSource.Volumes.PGWALVolume != nil &&
 			cluster.Spec.DataSource.Volumes.PGWALVolume.
 				Directory != "" &&
@@ -369,6 +384,7 @@ func (r *Reconciler) reconcileDirMoveJobs(ctx context.Context,
 		}
 
 		if err == nil &&
+			cluster.Spec.DataSource.Volumes != nil &&
 			cluster.Spec.DataSource.Volumes.PGBackRestVolume != nil &&
 			cluster.Spec.DataSource.Volumes.PGBackRestVolume.
 				Directory != "" &&
@@ -393,6 +409,7 @@ func (r *Reconciler) reconcileDirMoveJobs(ctx context.Context,
 func (r *Reconciler) reconcileMovePGDataDir(ctx context.Context,
 	cluster *v1beta1.PostgresCluster, moveJobs *batchv1.JobList) (bool, error) {
 
+	pgData := existingPGDataVolume(cluster)
 	moveDirJob := &batchv1.Job{}
 	moveDirJob.ObjectMeta = naming.MovePGDataDirJob(cluster)
 
@@ -435,9 +452,9 @@ func (r *Reconciler) reconcileMovePGDataDir(ctx context.Context,
     ls -lh "/pgdata"
     echo "PG Data directory preparation complete"
     `, cluster.Name,
-		cluster.Spec.DataSource.Volumes.PGDataVolume.PVCName,
-		cluster.Spec.DataSource.Volumes.PGDataVolume.Directory,
-		cluster.Spec.DataSource.Volumes.PGDataVolume.Directory,
+		pgData.PVCName,
+		pgData.Directory,
+		pgData.Directory,
 		strconv.Itoa(cluster.Spec.PostgresVersion),
 		strconv.Itoa(cluster.Spec.PostgresVersion))
 
@@ -470,8 +487,7 @@ func (r *Reconciler) reconcileMovePGDataDir(ctx context.Context,
 					Name: "postgres-data",
 					VolumeSource: corev1.VolumeSource{
 						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-							ClaimName: cluster.Spec.DataSource.Volumes.
-								PGDataVolume.PVCName,
+							ClaimName: pgData.PVCName,
 						},
 					}},
 				},
@@ -484,6 +500,10 @@ func (r *Reconciler) reconcileMovePGDataDir(ctx context.Context,
 		jobSpec.Template.Spec.PriorityClassName =
 			*cluster.Spec.InstanceSets[0].PriorityClassName
 	}
+	// require nodes that can run the image, if so configured
+	jobSpec.Template.Spec.Affinity = architectureAffinity(
+		config.ImageArchitectures(cluster), jobSpec.Template.Spec.Affinity)
+	setPodNameResolution(cluster, &jobSpec.Template.Spec)
 	moveDirJob.Spec = *jobSpec
 
 	// set gvk and ownership refs
@@ -596,6 +616,10 @@ func (r *Reconciler) reconcileMoveWALDir(ctx context.Context,
 		jobSpec.Template.Spec.PriorityClassName =
 			*cluster.Spec.InstanceSets[0].PriorityClassName
 	}
+	// require nodes that can run the image, if so configured
+	jobSpec.Template.Spec.Affinity = architectureAffinity(
+		config.ImageArchitectures(cluster), jobSpec.Template.Spec.Affinity)
+	setPodNameResolution(cluster, &jobSpec.Template.Spec)
 	moveDirJob.Spec = *jobSpec
 
 	// set gvk and ownership refs
@@ -712,6 +736,10 @@ func (r *Reconciler) reconcileMoveRepoDir(ctx context.Context,
 			jobSpec.Template.Spec.PriorityClassName = *repoHost.PriorityClassName
 		}
 	}
+	// require nodes that can run the image, if so configured
+	jobSpec.Template.Spec.Affinity = architectureAffinity(
+		config.ImageArchitectures(cluster), jobSpec.Template.Spec.Affinity)
+	setPodNameResolution(cluster, &jobSpec.Template.Spec)
 	moveDirJob.Spec = *jobSpec
 
 	// set gvk and ownership refs
@@ -807,18 +835,33 @@ func (r *Reconciler) handlePersistentVolumeClaimError(
 }
 
 // getRepoPVCNames returns a map containing the names of repo PVCs that have
-// the appropriate labels for each defined pgBackRest repo, if found.
+// the appropriate labels for each defined pgBackRest repo, if found. A volume
+// that is being deleted or that is still being filled during a relocation is
+// only returned when there is no other.
 func getRepoPVCNames(
 	cluster *v1beta1.PostgresCluster,
 	currentRepoPVCs []*corev1.PersistentVolumeClaim,
 ) map[string]string {
 
+	rank := func(pvc *corev1.PersistentVolumeClaim) int {
+		_, relocation := pvc.Labels[naming.LabelPGBackRestRelocation]
+		switch {
+		case pvc.DeletionTimestamp != nil:
+			return 0
+		case relocation:
+			return 1
+		default:
+			return 2
+		}
+	}
+
 	repoPVCs := make(map[string]string)
 	for _, repo := range cluster.Spec.Backups.PGBackRest.Repos {
+		best := -1
 		for _, pvc := range currentRepoPVCs {
-			if pvc.Labels[naming.LabelPGBackRestRepo] == repo.Name {
+			if pvc.Labels[naming.LabelPGBackRestRepo] == repo.Name && rank(pvc) > best {
 				repoPVCs[repo.Name] = pvc.GetName()
-				break
+				best = rank(pvc)
 			}
 		}
 	}
@@ -605,6 +605,32 @@ func TestGetPVCNameMethods(t *testing.T) {
 
 		assert.DeepEqual(t, getRepoPVCNames(cluster, repoPVCs2), expectedMap)
 	})
+
+	t.Run("prefer the repo PVC in use", func(t *testing.T) {
+		cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{
+			Name:   "testrepo1",
+			Volume: &v1beta1.RepoPVC{},
+		}}
+
+		relocated := repoPVC1.DeepCopy()
+		relocated.Name = "testrepovol1-relocated"
+		relocated.Labels[naming.LabelPGBackRestRelocation] = ""
+
+		assert.DeepEqual(t,
+			getRepoPVCNames(cluster, []*corev1.PersistentVolumeClaim{relocated, repoPVC1}),
+			map[string]string{"testrepo1": "testrepovol1"})
+
+		// The relocated volume is used once the other is being deleted.
+		deleted := repoPVC1.DeepCopy()
+		deleted.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+		assert.DeepEqual(t,
+			getRepoPVCNames(cluster, []*corev1.PersistentVolumeClaim{deleted, relocated}),
+			map[string]string{"testrepo1": "testrepovol1-relocated"})
+		assert.DeepEqual(t,
+			getRepoPVCNames(cluster, []*corev1.PersistentVolumeClaim{deleted}),
+			map[string]string{"testrepo1": "testrepovol1"})
+	})
 }
 
 func TestReconcileConfigureExistingPVCs(t *testing.T) {
@@ -16,14 +16,25 @@
 package postgrescluster
 
 import (
+	"context"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/crunchydata/postgres-operator/internal/kubeapi"
 	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/patroni"
+	"github.com/crunchydata/postgres-operator/internal/pgbackrest"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 // watchPods returns a handler.EventHandler for Pods.
@@ -43,7 +54,204 @@ func (*Reconciler) watchPods() handler.Funcs {
 					Namespace: e.ObjectNew.GetNamespace(),
 					Name:      cluster,
 				}})
+				return
+			}
+
+			// When the role of a Patroni pod changes, its role label may now
+			// disagree with the elected leader. Queue an event to repair
+			// labels quickly rather than waiting for the next resync.
+			if len(cluster) != 0 &&
+				(e.ObjectOld.GetLabels()[naming.LabelRole] != labels[naming.LabelRole] ||
+					patroni.PodRole(e.ObjectOld) != patroni.PodRole(e.ObjectNew)) {
+				q.Add(reconcile.Request{NamespacedName: client.ObjectKey{
+					Namespace: e.ObjectNew.GetNamespace(),
+					Name:      cluster,
+				}})
+				return
+			}
+
+			// When a pod becomes ready or stops being ready, steps that wait
+			// on it can proceed. Queue an event rather than polling.
+			if len(cluster) != 0 && podReady(e.ObjectOld) != podReady(e.ObjectNew) {
+				q.Add(reconcile.Request{NamespacedName: client.ObjectKey{
+					Namespace: e.ObjectNew.GetNamespace(),
+					Name:      cluster,
+				}})
+			}
+		},
+	}
+}
+
+// podReady returns whether or not object is a Pod with a true Ready condition.
+func podReady(object client.Object) bool {
+	if pod, ok := object.(*corev1.Pod); ok {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady {
+				return condition.Status == corev1.ConditionTrue
+			}
+		}
+	}
+	return false
+}
+
+// watchClusterLabel returns a handler.EventHandler for objects that are
+// related to a PostgresCluster but not controlled by it. When changed reports
+// a meaningful difference, it queues the PostgresCluster named by the cluster
+// label of the object.
+func (*Reconciler) watchClusterLabel(changed func(before, after client.Object) bool) handler.Funcs {
+	return handler.Funcs{
+		UpdateFunc: func(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			cluster := e.ObjectNew.GetLabels()[naming.LabelCluster]
+
+			if len(cluster) != 0 && changed(e.ObjectOld, e.ObjectNew) {
+				q.Add(reconcile.Request{NamespacedName: client.ObjectKey{
+					Namespace: e.ObjectNew.GetNamespace(),
+					Name:      cluster,
+				}})
 			}
 		},
 	}
 }
+
+// watchCredentialSecrets returns a handler.EventHandler for Secrets. When the
+// data of a Secret changes, it queues every PostgresCluster in the same
+// namespace that projects it into pgBackRest configuration or uses it for the
+// user mapping of a foreign server. These Secrets are not controlled by a
+// PostgresCluster.
+func (r *Reconciler) watchCredentialSecrets() handler.Funcs {
+	return handler.Funcs{
+		UpdateFunc: func(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			before, ok1 := e.ObjectOld.(*corev1.Secret)
+			after, ok2 := e.ObjectNew.(*corev1.Secret)
+			if !ok1 || !ok2 || equality.Semantic.DeepEqual(before.Data, after.Data) {
+				return
+			}
+
+			clusters := &v1beta1.PostgresClusterList{}
+			if err := r.Client.List(context.Background(), clusters,
+				client.InNamespace(after.Namespace)); err != nil {
+				return
+			}
+			for i := range clusters.Items {
+				names := append(pgbackrest.CredentialSecrets(&clusters.Items[i]),
+					foreignServerSecrets(&clusters.Items[i])...)
+				for _, name := range names {
+					if name == after.Name {
+						q.Add(reconcile.Request{
+							NamespacedName: client.ObjectKeyFromObject(&clusters.Items[i]),
+						})
+						break
+					}
+				}
+			}
+		},
+	}
+}
+
+// watchBlueGreen returns a handler.EventHandler for PostgresClusters. When a
+// cluster that replaces another through spec.blueGreen changes phase or is
+// deleted, it queues the cluster it replaces so that its Services follow.
+func (*Reconciler) watchBlueGreen() handler.Funcs {
+	source := func(object client.Object) (reconcile.Request, bool) {
+		cluster, ok := object.(*v1beta1.PostgresCluster)
+		if !ok || cluster.Spec.BlueGreen == nil {
+			return reconcile.Request{}, false
+		}
+		return reconcile.Request{NamespacedName: client.ObjectKey{
+			Namespace: cluster.Namespace,
+			Name:      cluster.Spec.BlueGreen.Source,
+		}}, true
+	}
+	phase := func(object client.Object) string {
+		if cluster, ok := object.(*v1beta1.PostgresCluster); ok && cluster.Status.BlueGreen != nil {
+			return cluster.Status.BlueGreen.Phase
+		}
+		return ""
+	}
+
+	return handler.Funcs{
+		UpdateFunc: func(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			if request, ok := source(e.ObjectNew); ok && phase(e.ObjectOld) != phase(e.ObjectNew) {
+				q.Add(request)
+			}
+		},
+		DeleteFunc: func(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+			if request, ok := source(e.Object); ok {
+				q.Add(request)
+			}
+		},
+	}
+}
+
+// patroniInitialized reports when Patroni records the system identifier of a
+// cluster in its DCS Endpoints or ConfigMaps. Patroni updates those objects
+// constantly; nothing else there matters to reconcile.
+func patroniInitialized(before, after client.Object) bool {
+	return before.GetAnnotations()["initialize"] != after.GetAnnotations()["initialize"]
+}
+
+// scheduledBackupChanged reports when a Job created by a pgBackRest backup
+// CronJob starts or finishes. Those Jobs are controlled by their CronJob.
+func scheduledBackupChanged(before, after client.Object) bool {
+	oldJob, ok1 := before.(*batchv1.Job)
+	newJob, ok2 := after.(*batchv1.Job)
+
+	return ok1 && ok2 &&
+		newJob.GetLabels()[naming.LabelPGBackRestCronJob] != "" &&
+		!equality.Semantic.DeepEqual(oldJob.Status, newJob.Status)
+}
+
+// watchDrift returns a handler.EventHandler for objects of kind that are
+// controlled by a PostgresCluster. When something other than this controller
+// takes ownership of fields that this controller applied, it emits a
+// "DriftDetected" event on the PostgresCluster. Those fields will be reverted
+// the next time the PostgresCluster is reconciled.
+func (r *Reconciler) watchDrift(kind string) handler.Funcs {
+	return handler.Funcs{
+		UpdateFunc: func(e event.UpdateEvent, _ workqueue.RateLimitingInterface) {
+			owner := metav1.GetControllerOfNoCopy(e.ObjectNew)
+			if owner == nil || owner.Kind != "PostgresCluster" ||
+				owner.APIVersion != v1beta1.GroupVersion.String() {
+				return
+			}
+
+			// Server-side apply moves ownership of a field to whoever updates
+			// it last. Look for fields that were ours before this change and
+			// now belong to another manager.
+			// - https://docs.k8s.io/reference/using-api/server-side-apply/#conflicts
+			before := kubeapi.ManagedFieldPaths(e.ObjectOld.GetManagedFields(), string(r.Owner))
+			after := kubeapi.ManagedFieldPaths(e.ObjectNew.GetManagedFields(), string(r.Owner))
+			lost := before.Difference(after)
+			if lost.Len() == 0 {
+				return
+			}
+
+			managers := sets.NewString()
+			for _, entry := range e.ObjectNew.GetManagedFields() {
+				if entry.Manager != string(r.Owner) &&
+					kubeapi.FieldsV1Paths(entry.FieldsV1).HasAny(lost.UnsortedList()...) {
+					managers.Insert(entry.Manager)
+				}
+			}
+			if managers.Len() == 0 {
+				// The fields were removed rather than taken. This happens when
+				// this controller stops applying them.
+				return
+			}
+
+			driftDetectedTotal.WithLabelValues(kind).Inc()
+
+			cluster := &v1beta1.PostgresCluster{}
+			cluster.SetGroupVersionKind(v1beta1.GroupVersion.WithKind("PostgresCluster"))
+			cluster.Namespace = e.ObjectNew.GetNamespace()
+			cluster.Name = owner.Name
+			cluster.UID = owner.UID
+
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "DriftDetected",
+				"%s %q was modified by %s; these fields will be reverted: %s",
+				kind, e.ObjectNew.GetName(),
+				strings.Join(managers.List(), ", "),
+				strings.Join(lost.List(), ", "))
+		},
+	}
+}
@@ -19,12 +19,21 @@ import (
 	"testing"
 
 	"gotest.tools/v3/assert"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 func TestWatchPodsUpdate(t *testing.T) {
@@ -89,4 +98,370 @@ func TestWatchPodsUpdate(t *testing.T) {
 	expected.Namespace = "some-ns"
 	expected.Name = "starfish"
 	assert.Equal(t, item, expected)
+	queue.Done(item)
+
+	// Patroni role label changed; one reconcile by label.
+	update(event.UpdateEvent{
+		ObjectOld: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "some-ns",
+				Labels: map[string]string{
+					"postgres-operator.crunchydata.com/cluster": "starfish",
+					"postgres-operator.crunchydata.com/role":    "replica",
+				},
+			},
+		},
+		ObjectNew: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "some-ns",
+				Labels: map[string]string{
+					"postgres-operator.crunchydata.com/cluster": "starfish",
+					"postgres-operator.crunchydata.com/role":    "master",
+				},
+			},
+		},
+	}, queue)
+	assert.Equal(t, queue.Len(), 1)
+
+	item, _ = queue.Get()
+	assert.Equal(t, item, expected)
+	queue.Done(item)
+
+	// Patroni reported a different role; one reconcile by label.
+	update(event.UpdateEvent{
+		ObjectOld: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "some-ns",
+				Annotations: map[string]string{"status": `{"role":"master"}`},
+				Labels: map[string]string{
+					"postgres-operator.crunchydata.com/cluster": "starfish",
+					"postgres-operator.crunchydata.com/role":    "master",
+				},
+			},
+		},
+		ObjectNew: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "some-ns",
+				Annotations: map[string]string{"status": `{"role":"replica"}`},
+				Labels: map[string]string{
+					"postgres-operator.crunchydata.com/cluster": "starfish",
+					"postgres-operator.crunchydata.com/role":    "master",
+				},
+			},
+		},
+	}, queue)
+	assert.Equal(t, queue.Len(), 1)
+
+	item, _ = queue.Get()
+	assert.Equal(t, item, expected)
+	queue.Done(item)
+
+	// Patroni status changed, but not its role; no reconcile.
+	update(event.UpdateEvent{
+		ObjectOld: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{"status": `{"role":"replica","xlog_location":1}`},
+				Labels: map[string]string{
+					"postgres-operator.crunchydata.com/cluster": "starfish",
+				},
+			},
+		},
+		ObjectNew: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{"status": `{"role":"replica","xlog_location":2}`},
+				Labels: map[string]string{
+					"postgres-operator.crunchydata.com/cluster": "starfish",
+				},
+			},
+		},
+	}, queue)
+	assert.Equal(t, queue.Len(), 0)
+
+	// Pod became ready; one reconcile by label.
+	update(event.UpdateEvent{
+		ObjectOld: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "some-ns",
+				Labels: map[string]string{
+					"postgres-operator.crunchydata.com/cluster": "starfish",
+				},
+			},
+		},
+		ObjectNew: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "some-ns",
+				Labels: map[string]string{
+					"postgres-operator.crunchydata.com/cluster": "starfish",
+				},
+			},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+			}},
+		},
+	}, queue)
+	assert.Equal(t, queue.Len(), 1)
+
+	item, _ = queue.Get()
+	assert.Equal(t, item, expected)
+	queue.Done(item)
+}
+
+func TestWatchClusterLabelUpdate(t *testing.T) {
+	queue := controllertest.Queue{Interface: workqueue.New()}
+	reconciler := &Reconciler{}
+
+	expected := reconcile.Request{}
+	expected.Namespace = "some-ns"
+	expected.Name = "starfish"
+
+	labels := map[string]string{"postgres-operator.crunchydata.com/cluster": "starfish"}
+
+	t.Run("Endpoints", func(t *testing.T) {
+		update := reconciler.watchClusterLabel(patroniInitialized).UpdateFunc
+
+		before := &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "some-ns", Labels: labels,
+			Annotations: map[string]string{"leader": "a", "renewTime": "1"},
+		}}
+
+		// Patroni renewed its lease; no reconcile.
+		after := before.DeepCopy()
+		after.Annotations["renewTime"] = "2"
+		update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+		assert.Equal(t, queue.Len(), 0)
+
+		// No cluster label; no reconcile.
+		after = before.DeepCopy()
+		after.Labels = nil
+		after.Annotations["initialize"] = "12345"
+		update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+		assert.Equal(t, queue.Len(), 0)
+
+		// Patroni initialized the cluster; one reconcile by label.
+		after = before.DeepCopy()
+		after.Annotations["initialize"] = "12345"
+		update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+		assert.Equal(t, queue.Len(), 1)
+
+		item, _ := queue.Get()
+		assert.Equal(t, item, expected)
+		queue.Done(item)
+	})
+
+	t.Run("Jobs", func(t *testing.T) {
+		update := reconciler.watchClusterLabel(scheduledBackupChanged).UpdateFunc
+
+		before := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "some-ns", Labels: labels,
+		}}
+
+		// Not a scheduled backup; no reconcile.
+		after := before.DeepCopy()
+		after.Status.Active = 1
+		update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+		assert.Equal(t, queue.Len(), 0)
+
+		before.Labels = map[string]string{
+			"postgres-operator.crunchydata.com/cluster":            "starfish",
+			"postgres-operator.crunchydata.com/pgbackrest-cronjob": "full",
+		}
+
+		// Metadata changed; no reconcile.
+		after = before.DeepCopy()
+		after.Annotations = map[string]string{"some": "thing"}
+		update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+		assert.Equal(t, queue.Len(), 0)
+
+		// Backup finished; one reconcile by label.
+		after = before.DeepCopy()
+		after.Status.Succeeded = 1
+		update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+		assert.Equal(t, queue.Len(), 1)
+
+		item, _ := queue.Get()
+		assert.Equal(t, item, expected)
+		queue.Done(item)
+	})
+}
+
+func TestWatchDriftUpdate(t *testing.T) {
+	queue := controllertest.Queue{Interface: workqueue.New()}
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &Reconciler{Owner: "pgo", Recorder: recorder}
+
+	update := reconciler.watchDrift("ConfigMap").UpdateFunc
+	assert.Assert(t, update != nil)
+
+	controller := metav1.OwnerReference{
+		APIVersion: "postgres-operator.crunchydata.com/v1beta1",
+		Kind:       "PostgresCluster",
+		Name:       "starfish",
+		Controller: initialize.Bool(true),
+	}
+	fields := func(manager, raw string) metav1.ManagedFieldsEntry {
+		return metav1.ManagedFieldsEntry{
+			Manager: manager, FieldsV1: &metav1.FieldsV1{Raw: []byte(raw)},
+		}
+	}
+
+	before := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "ns1", Name: "some-cm",
+		OwnerReferences: []metav1.OwnerReference{controller},
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			fields("pgo", `{"f:data":{"f:one":{},"f:two":{}}}`),
+		},
+	}}
+
+	t.Run("NotControlled", func(t *testing.T) {
+		old, after := before.DeepCopy(), before.DeepCopy()
+		old.OwnerReferences, after.OwnerReferences = nil, nil
+		after.ManagedFields = []metav1.ManagedFieldsEntry{
+			fields("kubectl", `{"f:data":{"f:one":{},"f:two":{}}}`),
+		}
+
+		update(event.UpdateEvent{ObjectOld: old, ObjectNew: after}, queue)
+		assert.Equal(t, len(recorder.Events), 0)
+	})
+
+	t.Run("NoChange", func(t *testing.T) {
+		update(event.UpdateEvent{ObjectOld: before, ObjectNew: before.DeepCopy()}, queue)
+		assert.Equal(t, len(recorder.Events), 0)
+	})
+
+	t.Run("Removed", func(t *testing.T) {
+		after := before.DeepCopy()
+		after.ManagedFields = []metav1.ManagedFieldsEntry{
+			fields("pgo", `{"f:data":{"f:one":{}}}`),
+		}
+
+		update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+		assert.Equal(t, len(recorder.Events), 0)
+	})
+
+	t.Run("Taken", func(t *testing.T) {
+		after := before.DeepCopy()
+		after.ManagedFields = []metav1.ManagedFieldsEntry{
+			fields("pgo", `{"f:data":{"f:one":{}}}`),
+			fields("kubectl-edit", `{"f:data":{"f:two":{}}}`),
+		}
+
+		update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+		assert.Equal(t, len(recorder.Events), 1)
+		assert.Equal(t, <-recorder.Events,
+			`Warning DriftDetected ConfigMap "some-cm" was modified by kubectl-edit;`+
+				` these fields will be reverted: f:data/f:two`)
+	})
+
+	// This handler only reports; the owner handler queues reconciles.
+	assert.Equal(t, queue.Len(), 0)
+}
+
+func TestWatchCredentialSecretsUpdate(t *testing.T) {
+	queue := controllertest.Queue{Interface: workqueue.New()}
+
+	testScheme := runtime.NewScheme()
+	assert.NilError(t, scheme.AddToScheme(testScheme))
+	assert.NilError(t, v1beta1.AddToScheme(testScheme))
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "some-ns", "starfish"
+	cluster.Spec.Backups.PGBackRest.Configuration = []corev1.VolumeProjection{{
+		Secret: &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "s3-keys"},
+		},
+	}}
+	other := &v1beta1.PostgresCluster{}
+	other.Namespace, other.Name = "some-ns", "octopus"
+	federated := &v1beta1.PostgresCluster{}
+	federated.Namespace, federated.Name = "some-ns", "squid"
+	federated.Spec.Extensions = &v1beta1.PostgresExtensionsSpec{
+		ForeignServers: []v1beta1.PostgresForeignServerSpec{{
+			Name: "remote", Database: "app", Wrapper: "postgres_fdw",
+			UserMappings: []v1beta1.PostgresUserMappingSpec{{User: "app", SecretName: "remote-login"}},
+		}},
+	}
+
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().
+		WithScheme(testScheme).WithObjects(cluster, other, federated).Build()}
+
+	update := reconciler.watchCredentialSecrets().UpdateFunc
+	assert.Assert(t, update != nil)
+
+	before := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "some-ns", Name: "s3-keys",
+	}, Data: map[string][]byte{"s3.conf": []byte("one")}}
+
+	// Metadata changed; no reconcile.
+	after := before.DeepCopy()
+	after.Annotations = map[string]string{"some": "thing"}
+	update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+	assert.Equal(t, queue.Len(), 0)
+
+	// Another Secret changed; no reconcile.
+	after = before.DeepCopy()
+	after.Name = "other"
+	after.Data["s3.conf"] = []byte("two")
+	update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+	assert.Equal(t, queue.Len(), 0)
+
+	// Keys rotated; one reconcile of the cluster that uses them.
+	after = before.DeepCopy()
+	after.Data["s3.conf"] = []byte("two")
+	update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+	assert.Equal(t, queue.Len(), 1)
+
+	item, _ := queue.Get()
+	assert.Equal(t, item, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(cluster)})
+	queue.Done(item)
+
+	// Credentials of a user mapping changed; one reconcile of that cluster.
+	before.Name = "remote-login"
+	after = before.DeepCopy()
+	after.Data["s3.conf"] = []byte("three")
+	update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+	assert.Equal(t, queue.Len(), 1)
+
+	item, _ = queue.Get()
+	assert.Equal(t, item, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(federated)})
+	queue.Done(item)
+}
+
+func TestWatchBlueGreen(t *testing.T) {
+	queue := controllertest.Queue{Interface: workqueue.New()}
+	reconciler := &Reconciler{}
+
+	update := reconciler.watchBlueGreen().UpdateFunc
+	assert.Assert(t, update != nil)
+
+	before := &v1beta1.PostgresCluster{}
+	before.Namespace, before.Name = "some-ns", "green"
+
+	// Not a blue/green switch; no reconcile.
+	after := before.DeepCopy()
+	after.Status.BlueGreen = &v1beta1.PostgresBlueGreenStatus{Phase: "Replicating"}
+	update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+	assert.Equal(t, queue.Len(), 0)
+
+	// Phase unchanged; no reconcile.
+	before.Spec.BlueGreen = &v1beta1.PostgresBlueGreenSpec{Source: "blue"}
+	before.Status.BlueGreen = &v1beta1.PostgresBlueGreenStatus{Phase: "Replicating"}
+	after = before.DeepCopy()
+	after.Status.BlueGreen.CutoverLSN = "0/4000060"
+	update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+	assert.Equal(t, queue.Len(), 0)
+
+	// Phase changed; one reconcile of blue.
+	after.Status.BlueGreen.Phase = "CutOver"
+	update(event.UpdateEvent{ObjectOld: before, ObjectNew: after}, queue)
+	assert.Equal(t, queue.Len(), 1)
+
+	item, _ := queue.Get()
+	assert.Equal(t, item, reconcile.Request{NamespacedName: client.ObjectKey{
+		Namespace: "some-ns", Name: "blue",
+	}})
+	queue.Done(item)
+
+	// Green deleted; one reconcile of blue.
+	reconciler.watchBlueGreen().Delete(event.DeleteEvent{Object: after}, queue)
+	assert.Equal(t, queue.Len(), 1)
 }
@@ -0,0 +1,121 @@
+package runtime
+
+/*
+Copyright 2021 Crunchy Data
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+
+	"github.com/crunchydata/postgres-operator/internal/naming"
+)
+
+// cacheSelectors are the label selectors of objects the manager caches, by
+// resource. Kubernetes often has many more Secrets and ConfigMaps than the
+// operator uses, and caching all of them can exhaust the operator's memory.
+// Every one the operator creates has the cluster label.
+var cacheSelectors = map[string]string{
+	"configmaps": naming.LabelCluster,
+	"secrets":    naming.LabelCluster,
+}
+
+// newCache is a cache.NewCacheFunc that lists and watches only the Secrets and
+// ConfigMaps that match cacheSelectors.
+//
+// NOTE: The cache of controller-runtime v0.8 has no option for selectors, so
+// this adds them to list and watch requests as they are sent.
+func newCache(config *rest.Config, options cache.Options) (cache.Cache, error) {
+	config = rest.CopyConfig(config)
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return selectorTransport{rt}
+	})
+	return cache.New(config, options)
+}
+
+// selectorTransport adds cacheSelectors to requests for collections of the
+// core API group that do not already have a label selector.
+type selectorTransport struct{ http.RoundTripper }
+
+func (t selectorTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	// Collections are "/api/v1/{resource}" and "/api/v1/namespaces/{ns}/{resource}".
+	parts := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+	collection := request.Method == http.MethodGet &&
+		len(parts) >= 3 && parts[0] == "api" && parts[1] == "v1" &&
+		(len(parts) == 3 || (len(parts) == 5 && parts[2] == "namespaces"))
+
+	if selector, ok := cacheSelectors[parts[len(parts)-1]]; collection && ok {
+		if query := request.URL.Query(); query.Get("labelSelector") == "" {
+			query.Set("labelSelector", selector)
+
+			request = request.Clone(request.Context())
+			request.URL.RawQuery = query.Encode()
+		}
+	}
+
+	return t.RoundTripper.RoundTrip(request)
+}
+
+// fallbackClientBuilder is a cluster.ClientBuilder for clients that read from
+// the cache first and then from the API when a Secret or ConfigMap is missing.
+// The cache holds only those that match cacheSelectors, but the operator also
+// reads some that are created by others, such as custom TLS certificates.
+type fallbackClientBuilder struct {
+	cluster.ClientBuilder
+}
+
+func (b fallbackClientBuilder) WithUncached(objects ...client.Object) cluster.ClientBuilder {
+	return fallbackClientBuilder{b.ClientBuilder.WithUncached(objects...)}
+}
+
+func (b fallbackClientBuilder) Build(
+	cache cache.Cache, config *rest.Config, options client.Options,
+) (client.Client, error) {
+	cached, err := b.ClientBuilder.Build(cache, config, options)
+	if err != nil {
+		return nil, err
+	}
+	live, err := client.New(config, options)
+	if err != nil {
+		return nil, err
+	}
+	return fallbackClient{Client: cached, live: live}, nil
+}
+
+// fallbackClient reads Secrets and ConfigMaps from live when they are not
+// found in Client.
+type fallbackClient struct {
+	client.Client
+	live client.Reader
+}
+
+func (c fallbackClient) Get(ctx context.Context, key client.ObjectKey, object client.Object) error {
+	err := c.Client.Get(ctx, key, object)
+
+	if apierrors.IsNotFound(err) {
+		switch object.(type) {
+		case *corev1.ConfigMap, *corev1.Secret:
+			err = c.live.Get(ctx, key, object)
+		}
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package runtime
+
+/*
+Copyright 2021 Crunchy Data
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestSelectorTransport(t *testing.T) {
+	var sent *http.Request
+	transport := selectorTransport{roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		sent = r
+		return &http.Response{}, nil
+	})}
+
+	for _, tt := range []struct {
+		method, url, query string
+	}{
+		// Collections of Secrets and ConfigMaps are filtered.
+		{"GET", "https://k8s/api/v1/secrets?watch=true",
+			"labelSelector=postgres-operator.crunchydata.com%2Fcluster&watch=true"},
+		{"GET", "https://k8s/api/v1/namespaces/ns1/configmaps?limit=500",
+			"labelSelector=postgres-operator.crunchydata.com%2Fcluster&limit=500"},
+
+		// Existing selectors are kept.
+		{"GET", "https://k8s/api/v1/namespaces/ns1/secrets?labelSelector=a%3Db",
+			"labelSelector=a%3Db"},
+
+		// Single objects, other resources, and other methods are not.
+		{"GET", "https://k8s/api/v1/namespaces/ns1/secrets/some-secret", ""},
+		{"GET", "https://k8s/api/v1/namespaces/ns1/pods", ""},
+		{"GET", "https://k8s/apis/apps/v1/namespaces/ns1/secrets", ""},
+		{"POST", "https://k8s/api/v1/namespaces/ns1/secrets", ""},
+	} {
+		request, err := http.NewRequest(tt.method, tt.url, nil)
+		assert.NilError(t, err)
+		original := request.URL.RawQuery
+
+		_, err = transport.RoundTrip(request)
+		assert.NilError(t, err)
+
+		if tt.query == "" {
+			assert.Equal(t, sent.URL.RawQuery, original, "%v %v", tt.method, tt.url)
+		} else {
+			assert.Equal(t, sent.URL.RawQuery, tt.query, "%v %v", tt.method, tt.url)
+		}
+		assert.Equal(t, request.URL.RawQuery, original, "expected no change to the original")
+	}
+}
+
+func TestFallbackClient(t *testing.T) {
+	ctx := context.Background()
+	scheme, err := CreatePostgresOperatorScheme()
+	assert.NilError(t, err)
+
+	custom := &corev1.Secret{}
+	custom.Namespace, custom.Name = "ns1", "custom-tls"
+	other := &corev1.Pod{}
+	other.Namespace, other.Name = "ns1", "some-pod"
+
+	c := fallbackClient{
+		Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		live:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(custom, other).Build(),
+	}
+
+	// Secrets missing from the cache are read from the API.
+	secret := &corev1.Secret{}
+	assert.NilError(t, c.Get(ctx, client.ObjectKeyFromObject(custom), secret))
+	assert.Equal(t, secret.Name, "custom-tls")
+
+	// Other kinds are not.
+	err = c.Get(ctx, client.ObjectKeyFromObject(other), &corev1.Pod{})
+	assert.Assert(t, apierrors.IsNotFound(err), "got %v", err)
+}
@@ -0,0 +1,137 @@
+package runtime
+
+/*
+Copyright 2021 Crunchy Data
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// clusterUsage is what one or more PostgresClusters count toward a ClusterQuota.
+type clusterUsage struct {
+	clusters, replicas, storage int64
+}
+
+// usageOf returns what cluster counts toward a ClusterQuota: itself, its
+// PostgreSQL instances, and the storage requested by the data and WAL volumes
+// of each instance and by its pgBackRest repository volumes.
+func usageOf(cluster *v1beta1.PostgresCluster) clusterUsage {
+	usage := clusterUsage{clusters: 1}
+
+	for _, set := range cluster.Spec.InstanceSets {
+		replicas := int64(1)
+		if set.Replicas != nil {
+			replicas = int64(*set.Replicas)
+		}
+
+		storage := set.DataVolumeClaimSpec.Resources.Requests.Storage().Value()
+		if set.WALVolumeClaimSpec != nil {
+			storage += set.WALVolumeClaimSpec.Resources.Requests.Storage().Value()
+		}
+
+		usage.replicas += replicas
+		usage.storage += replicas * storage
+	}
+
+	for _, repo := range cluster.Spec.Backups.PGBackRest.Repos {
+		if repo.Volume != nil {
+			usage.storage += repo.Volume.VolumeClaimSpec.Resources.Requests.Storage().Value()
+		}
+	}
+
+	return usage
+}
+
+// quotaScope returns a description of the namespace or team of cluster and
+// the options to list the PostgresClusters in it.
+func quotaScope(quota config.ClusterQuota, cluster *v1beta1.PostgresCluster) (string, []client.ListOption) {
+	if team, ok := cluster.Labels[quota.Label]; quota.Label != "" && ok {
+		return fmt.Sprintf("team %q", quota.Label+"="+team),
+			[]client.ListOption{client.MatchingLabels{quota.Label: team}}
+	}
+	return fmt.Sprintf("namespace %q", cluster.Namespace),
+		[]client.ListOption{client.InNamespace(cluster.Namespace)}
+}
+
+// checkQuota returns a message explaining how cluster exceeds quota, if it
+// does. When old is not nil, cluster is an update of old and is denied only
+// when it adds to a limit that is exceeded. This allows clusters admitted
+// before a limit was lowered to shrink or change in other ways.
+func checkQuota(
+	ctx context.Context, reader client.Reader, quota config.ClusterQuota,
+	cluster, old *v1beta1.PostgresCluster,
+) (string, error) {
+	scope, options := quotaScope(quota, cluster)
+
+	list := &v1beta1.PostgresClusterList{}
+	if err := reader.List(ctx, list, options...); err != nil {
+		return "", err
+	}
+
+	// Sum what the other clusters in the scope count. Clusters in the team
+	// scope have the label; those in a namespace scope do not.
+	var others clusterUsage
+	for i := range list.Items {
+		item := &list.Items[i]
+		if item.DeletionTimestamp != nil ||
+			(item.Namespace == cluster.Namespace && item.Name == cluster.Name) {
+			continue
+		}
+		if itemScope, _ := quotaScope(quota, item); itemScope != scope {
+			continue
+		}
+		usage := usageOf(item)
+		others.clusters += usage.clusters
+		others.replicas += usage.replicas
+		others.storage += usage.storage
+	}
+
+	var previous clusterUsage
+	if old != nil {
+		if oldScope, _ := quotaScope(quota, old); oldScope == scope {
+			previous = usageOf(old)
+		}
+	}
+
+	usage := usageOf(cluster)
+	exceeds := func(limit, other, value, previous int64) bool {
+		return limit > 0 && other+value > limit && value > previous
+	}
+
+	if exceeds(quota.Clusters, others.clusters, usage.clusters, previous.clusters) {
+		return fmt.Sprintf("PostgresCluster quota of %s exceeded: "+
+			"there would be %d clusters, limit is %d",
+			scope, others.clusters+usage.clusters, quota.Clusters), nil
+	}
+	if exceeds(quota.Replicas, others.replicas, usage.replicas, previous.replicas) {
+		return fmt.Sprintf("PostgresCluster quota of %s exceeded: "+
+			"there would be %d replicas, limit is %d",
+			scope, others.replicas+usage.replicas, quota.Replicas), nil
+	}
+	if exceeds(quota.Storage, others.storage, usage.storage, previous.storage) {
+		return fmt.Sprintf("PostgresCluster quota of %s exceeded: "+
+			"requested storage would be %s, limit is %s", scope,
+			resource.NewQuantity(others.storage+usage.storage, resource.BinarySI),
+			resource.NewQuantity(quota.Storage, resource.BinarySI)), nil
+	}
+	return "", nil
+}
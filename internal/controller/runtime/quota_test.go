@@ -0,0 +1,146 @@
+package runtime
+
+/*
+Copyright 2021 Crunchy Data
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestUsageOf(t *testing.T) {
+	storage := func(s string) corev1.PersistentVolumeClaimSpec {
+		return corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(s)},
+			},
+		}
+	}
+
+	cluster := new(v1beta1.PostgresCluster)
+	assert.Equal(t, usageOf(cluster), clusterUsage{clusters: 1})
+
+	wal := storage("1Gi")
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{
+		{Name: "one", DataVolumeClaimSpec: storage("2Gi")},
+		{Name: "two", Replicas: initialize.Int32(3),
+			DataVolumeClaimSpec: storage("1Gi"), WALVolumeClaimSpec: &wal},
+	}
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{
+		{Name: "repo1", Volume: &v1beta1.RepoPVC{VolumeClaimSpec: storage("10Gi")}},
+		{Name: "repo2", S3: &v1beta1.RepoS3{}},
+	}
+
+	assert.Equal(t, usageOf(cluster), clusterUsage{
+		clusters: 1, replicas: 4, storage: (2 + 3*2 + 10) << 30,
+	})
+}
+
+func TestGuardrailsHandleQuota(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NilError(t, v1beta1.AddToScheme(scheme))
+	decoder, err := admission.NewDecoder(scheme)
+	assert.NilError(t, err)
+
+	raw := func(cluster *v1beta1.PostgresCluster) runtime.RawExtension {
+		cluster.APIVersion = v1beta1.GroupVersion.String()
+		cluster.Kind = "PostgresCluster"
+		b, err := json.Marshal(cluster)
+		assert.NilError(t, err)
+		return runtime.RawExtension{Raw: b}
+	}
+	cluster := func(namespace, name string, replicas int32, labels map[string]string) *v1beta1.PostgresCluster {
+		c := new(v1beta1.PostgresCluster)
+		c.Namespace, c.Name, c.Labels = namespace, name, labels
+		c.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{
+			{Name: "00", Replicas: initialize.Int32(replicas)},
+		}
+		return c
+	}
+
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		cluster("ns1", "existing", 2, nil),
+		cluster("ns2", "blue", 2, map[string]string{"team": "blue"}),
+	).Build()
+
+	g := &guardrails{
+		decoder: decoder, mode: GuardrailWarn, reader: reader,
+		quota: config.ClusterQuota{Clusters: 2, Replicas: 4, Label: "team"},
+	}
+
+	create := func(c *v1beta1.PostgresCluster) admission.Response {
+		request := admission.Request{}
+		request.Operation = admissionv1.Create
+		request.Object = raw(c)
+		return g.Handle(ctx, request)
+	}
+
+	// There is room for one more cluster with two replicas in namespace ns1.
+	assert.Assert(t, create(cluster("ns1", "new", 2, nil)).Allowed)
+
+	response := create(cluster("ns1", "new", 3, nil))
+	assert.Assert(t, !response.Allowed)
+	assert.Equal(t, string(response.Result.Reason),
+		`PostgresCluster quota of namespace "ns1" exceeded: there would be 5 replicas, limit is 4`)
+
+	// Clusters of a team count together in every namespace.
+	assert.Assert(t, create(cluster("ns1", "new", 3, map[string]string{"team": "red"})).Allowed)
+
+	response = create(cluster("ns1", "new", 3, map[string]string{"team": "blue"}))
+	assert.Assert(t, !response.Allowed)
+	assert.Equal(t, string(response.Result.Reason),
+		`PostgresCluster quota of team "team=blue" exceeded: there would be 5 replicas, limit is 4`)
+
+	t.Run("Clusters", func(t *testing.T) {
+		assert.NilError(t, reader.Create(ctx, cluster("ns1", "another", 1, nil)))
+		t.Cleanup(func() { assert.NilError(t, reader.Delete(ctx, cluster("ns1", "another", 1, nil))) })
+
+		response := create(cluster("ns1", "new", 1, nil))
+		assert.Assert(t, !response.Allowed)
+		assert.Equal(t, string(response.Result.Reason),
+			`PostgresCluster quota of namespace "ns1" exceeded: there would be 3 clusters, limit is 2`)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		g := *g
+		g.quota.Replicas = 1
+
+		request := admission.Request{}
+		request.Operation = admissionv1.Update
+		request.OldObject = raw(cluster("ns1", "existing", 2, nil))
+
+		// A cluster that exceeds quota can shrink or stay the same.
+		request.Object = raw(cluster("ns1", "existing", 2, map[string]string{"other": "label"}))
+		assert.Assert(t, g.Handle(ctx, request).Allowed)
+
+		// It cannot grow.
+		request.Object = raw(cluster("ns1", "existing", 3, nil))
+		assert.Assert(t, !g.Handle(ctx, request).Allowed)
+	})
+}
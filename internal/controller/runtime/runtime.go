@@ -22,8 +22,12 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
 
+	"github.com/crunchydata/postgres-operator/internal/kubeapi"
+	v1 "github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -47,6 +51,10 @@ func CreateRuntimeManager(namespace string, config *rest.Config,
 		Namespace:  namespace, // if empty then watching all namespaces
 		SyncPeriod: &refreshInterval,
 		Scheme:     pgoScheme,
+
+		// Cache only the Secrets and ConfigMaps of PostgresClusters.
+		NewCache:      newCache,
+		ClientBuilder: fallbackClientBuilder{cluster.NewClientBuilder()},
 	}
 	if disableMetrics {
 		options.MetricsBindAddress = "0"
@@ -61,6 +69,16 @@ func CreateRuntimeManager(namespace string, config *rest.Config,
 	return mgr, nil
 }
 
+// AddConversionWebhook registers a webhook on mgr that converts between
+// versions of the PostgresCluster API. The webhook server reads its certificate
+// and key from the "tls.crt" and "tls.key" files in certDir.
+// - https://docs.k8s.io/tasks/extend-kubernetes/custom-resources/custom-resource-definition-versioning/#webhook-conversion
+func AddConversionWebhook(mgr manager.Manager, certDir string) {
+	server := mgr.GetWebhookServer()
+	server.CertDir = certDir
+	server.Register("/convert", &conversion.Webhook{})
+}
+
 // GetConfig creates a *rest.Config for talking to a Kubernetes API server.
 func GetConfig() (*rest.Config, error) { return config.GetConfig() }
 
@@ -77,10 +95,18 @@ func CreatePostgresOperatorScheme() (*runtime.Scheme, error) {
 		return nil, err
 	}
 
+	// add batch/v1 CronJobs, which are missing from the standard types
+	if err := kubeapi.AddCronJobToScheme(pgoScheme); err != nil {
+		return nil, err
+	}
+
 	// add custom resource types to the default scheme
 	if err := v1beta1.AddToScheme(pgoScheme); err != nil {
 		return nil, err
 	}
+	if err := v1.AddToScheme(pgoScheme); err != nil {
+		return nil, err
+	}
 
 	return pgoScheme, nil
 }
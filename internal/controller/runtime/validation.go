@@ -0,0 +1,386 @@
+package runtime
+
+/*
+Copyright 2021 Crunchy Data
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// GuardrailMode controls what happens when a PostgresCluster fails validation.
+type GuardrailMode string
+
+const (
+	// GuardrailWarn admits the PostgresCluster and returns its problems as warnings.
+	GuardrailWarn GuardrailMode = "warn"
+
+	// GuardrailReject denies the PostgresCluster.
+	GuardrailReject GuardrailMode = "reject"
+)
+
+// AddValidationWebhook registers a webhook on mgr that checks the PostgreSQL
+// memory parameters of a PostgresCluster against the memory limits of its
+// instance sets. It also denies a PostgresCluster with schedules, metadata,
+// or ports that the CRD schema cannot fully check, and one that exceeds quota.
+// The webhook server must already be configured with a certificate; see
+// AddConversionWebhook.
+func AddValidationWebhook(mgr manager.Manager, mode GuardrailMode, quota config.ClusterQuota) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err == nil {
+		mgr.GetWebhookServer().Register("/validate", &webhook.Admission{
+			Handler: &guardrails{
+				decoder: decoder, mode: mode,
+				quota: quota, reader: mgr.GetClient(),
+			},
+		})
+	}
+	return err
+}
+
+// guardrails is an admission.Handler that validates PostgresClusters.
+type guardrails struct {
+	decoder *admission.Decoder
+	mode    GuardrailMode
+
+	// quota limits the PostgresClusters of each namespace or team, which are
+	// read using reader.
+	quota  config.ClusterQuota
+	reader client.Reader
+}
+
+// Handle implements admission.Handler.
+func (g *guardrails) Handle(ctx context.Context, req admission.Request) admission.Response {
+	cluster := new(v1beta1.PostgresCluster)
+	if err := g.decoder.Decode(req, cluster); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// These mistakes would otherwise surface later as objects that cannot be
+	// created, so deny them regardless of mode. Check only a spec that changes
+	// so that clusters admitted before these checks can still be updated; the
+	// operator removes its finalizer with an update, for example.
+	specChanged, labelsChanged := true, true
+	var old *v1beta1.PostgresCluster
+	if req.Operation == admissionv1.Update {
+		old = new(v1beta1.PostgresCluster)
+		if err := g.decoder.DecodeRaw(req.OldObject, old); err == nil {
+			specChanged = !equality.Semantic.DeepEqual(old.Spec, cluster.Spec)
+			labelsChanged = !equality.Semantic.DeepEqual(old.Labels, cluster.Labels)
+		} else {
+			old = nil
+		}
+	}
+	if errs := validateSpec(cluster); specChanged && len(errs) > 0 {
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+
+	// Quotas apply regardless of mode, too. Labels can move a cluster to
+	// another team.
+	if g.quota.Enabled() && (specChanged || labelsChanged) && cluster.DeletionTimestamp == nil {
+		message, err := checkQuota(ctx, g.reader, g.quota, cluster, old)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if message != "" {
+			return admission.Denied(message)
+		}
+	}
+
+	problems := postgres.MemoryGuardrails(cluster)
+	if len(problems) > 0 && g.mode == GuardrailReject {
+		return admission.Denied(strings.Join(problems, "; "))
+	}
+	return admission.Allowed("").WithWarnings(problems...)
+}
+
+// validateSpec returns the problems in the spec of cluster that its CRD schema
+// cannot detect: the syntax of each Cron and pg_cron schedule, the keys and values of
+// metadata, the templates of databases, the locale of initdb, and whether ports collide.
+func validateSpec(cluster *v1beta1.PostgresCluster) field.ErrorList {
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+
+	metadata := func(path *field.Path, m *v1beta1.Metadata) {
+		if m != nil {
+			errs = append(errs, metav1validation.ValidateLabels(m.Labels, path.Child("labels"))...)
+			errs = append(errs, validation.ValidateAnnotations(m.Annotations, path.Child("annotations"))...)
+		}
+	}
+	schedule := func(path *field.Path, value *string) {
+		if value != nil {
+			if err := validateCronSchedule(*value); err != nil {
+				errs = append(errs, field.Invalid(path, *value, err.Error()))
+			}
+		}
+	}
+
+	metadata(spec.Child("metadata"), cluster.Spec.Metadata)
+	for i := range cluster.Spec.InstanceSets {
+		metadata(spec.Child("instances").Index(i).Child("metadata"),
+			cluster.Spec.InstanceSets[i].Metadata)
+	}
+
+	backrest := spec.Child("backups", "pgbackrest")
+	metadata(backrest.Child("metadata"), cluster.Spec.Backups.PGBackRest.Metadata)
+	for i, repo := range cluster.Spec.Backups.PGBackRest.Repos {
+		if repo.BackupSchedules != nil {
+			path := backrest.Child("repos").Index(i).Child("schedules")
+			schedule(path.Child("full"), repo.BackupSchedules.Full)
+			schedule(path.Child("differential"), repo.BackupSchedules.Differential)
+			schedule(path.Child("incremental"), repo.BackupSchedules.Incremental)
+		}
+	}
+
+	if cluster.Spec.Maintenance != nil {
+		for i := range cluster.Spec.Maintenance.Jobs {
+			schedule(spec.Child("maintenance", "jobs").Index(i).Child("schedule"),
+				&cluster.Spec.Maintenance.Jobs[i].Schedule)
+		}
+	}
+
+	if cluster.Spec.Extensions != nil && cluster.Spec.Extensions.PGPartman != nil {
+		for i := range cluster.Spec.Extensions.PGPartman.Databases {
+			schedule(spec.Child("extensions", "pgPartman", "databases").Index(i).Child("schedule"),
+				&cluster.Spec.Extensions.PGPartman.Databases[i].Schedule)
+		}
+	}
+
+	if cluster.Spec.Extensions != nil && cluster.Spec.Extensions.PGCron != nil {
+		path := spec.Child("extensions", "pgCron", "jobs")
+		for i, job := range cluster.Spec.Extensions.PGCron.Jobs {
+			if err := validatePGCronSchedule(job.Schedule); err != nil {
+				errs = append(errs, field.Invalid(path.Index(i).Child("schedule"), job.Schedule, err.Error()))
+			}
+		}
+	}
+
+	errs = append(errs, validateDatabaseTemplates(cluster)...)
+
+	// The ICU provider of initdb needs an ICU locale; otherwise bootstrap fails.
+	if config := cluster.Spec.Config; config != nil && config.InitDB != nil &&
+		config.InitDB.LocaleProvider == "icu" && config.InitDB.ICULocale == "" &&
+		cluster.Spec.PostgresVersion >= 15 {
+		errs = append(errs, field.Required(spec.Child("config", "initdb", "icuLocale"),
+			`required when localeProvider is "icu"`))
+	}
+
+	// PostgreSQL and Patroni run in the same Pod, so their ports must differ.
+	if cluster.Spec.Port != nil && cluster.Spec.Patroni != nil &&
+		cluster.Spec.Patroni.Port != nil && *cluster.Spec.Port == *cluster.Spec.Patroni.Port {
+		errs = append(errs, field.Duplicate(spec.Child("patroni", "port"), *cluster.Spec.Patroni.Port))
+	}
+
+	// Every PgBouncer process in a Pod listens on a different port.
+	if cluster.Spec.Proxy != nil && cluster.Spec.Proxy.PGBouncer != nil {
+		path := spec.Child("proxy", "pgBouncer")
+		metadata(path.Child("metadata"), cluster.Spec.Proxy.PGBouncer.Metadata)
+
+		ports := make(map[int32]bool)
+		if cluster.Spec.Proxy.PGBouncer.Port != nil {
+			ports[*cluster.Spec.Proxy.PGBouncer.Port] = true
+		}
+		for i, pool := range cluster.Spec.Proxy.PGBouncer.Pools {
+			if ports[pool.Port] {
+				errs = append(errs, field.Duplicate(path.Child("pools").Index(i).Child("port"), pool.Port))
+			}
+			ports[pool.Port] = true
+		}
+	}
+
+	return errs
+}
+
+// validateDatabaseTemplates returns the problems with the template of each
+// database in spec.databases. PostgreSQL fails to copy a database while other
+// sessions are connected to it, so templates cannot be databases that clients,
+// Patroni, pg_cron, or scheduled maintenance connect to. A template with a different locale than the
+// copy fails, too, unless it is "template0".
+// - https://www.postgresql.org/docs/current/manage-ag-templatedbs.html
+func validateDatabaseTemplates(cluster *v1beta1.PostgresCluster) field.ErrorList {
+	var errs field.ErrorList
+
+	connected := map[v1beta1.PostgresIdentifier]string{
+		"postgres": "PostgreSQL is managed through it",
+	}
+	if cluster.Spec.Users == nil {
+		// See reconcilePostgresDatabases.
+		connected[v1beta1.PostgresIdentifier(cluster.Name)] = "users connect to it"
+	}
+	for _, user := range cluster.Spec.Users {
+		for _, database := range user.Databases {
+			connected[database] = "users connect to it"
+		}
+	}
+	if cluster.Spec.Extensions != nil && cluster.Spec.Extensions.PGCron != nil {
+		connected[cluster.Spec.Extensions.PGCron.Database] = "pg_cron connects to it"
+	}
+	if cluster.Spec.Extensions != nil && cluster.Spec.Extensions.PGPartman != nil {
+		for _, database := range cluster.Spec.Extensions.PGPartman.Databases {
+			connected[database.Name] = "pg_partman maintenance connects to it"
+		}
+	}
+	if cluster.Spec.Maintenance != nil {
+		for _, job := range cluster.Spec.Maintenance.Jobs {
+			connected[job.Database] = "maintenance job " + job.Name + " connects to it"
+		}
+	}
+
+	path := field.NewPath("spec", "databases")
+	for i, database := range cluster.Spec.Databases {
+		template := database.Template
+		if template == "" {
+			continue
+		}
+		if template == database.Name {
+			errs = append(errs, field.Invalid(path.Index(i).Child("template"),
+				template, "a database cannot be its own template"))
+		} else if reason, ok := connected[template]; ok {
+			errs = append(errs, field.Invalid(path.Index(i).Child("template"),
+				template, "cannot copy a database while others are connected: "+reason))
+		} else if template != "template0" && (database.Locale != "" ||
+			database.LCCollate != "" || database.LCCtype != "" ||
+			database.LocaleProvider != "" || database.ICULocale != "") {
+			errs = append(errs, field.Invalid(path.Index(i).Child("template"),
+				template, `locale settings require "template0"`))
+		}
+	}
+	return errs
+}
+
+// cronFields are the ranges and names of the five fields of a Cron schedule
+// as understood by the CronJob controller.
+// - https://k8s.io/docs/concepts/workloads/controllers/cron-jobs/#cron-schedule-syntax
+var cronFields = [5]struct {
+	name     string
+	min, max int
+	names    []string
+}{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{
+		"jan", "feb", "mar", "apr", "may", "jun",
+		"jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 6, names: []string{
+		"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// validateCronSchedule returns an error when the CronJob controller cannot
+// parse schedule.
+func validateCronSchedule(schedule string) error {
+	schedule = strings.TrimSpace(schedule)
+
+	if strings.HasPrefix(schedule, "@every ") {
+		if _, err := time.ParseDuration(strings.TrimPrefix(schedule, "@every ")); err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+		return nil
+	}
+	if strings.HasPrefix(schedule, "@") {
+		switch schedule {
+		case "@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly":
+			return nil
+		}
+		return fmt.Errorf("unknown macro %q", schedule)
+	}
+
+	fields := strings.Fields(schedule)
+	if len(fields) != len(cronFields) {
+		return fmt.Errorf("expected %d fields, found %d", len(cronFields), len(fields))
+	}
+
+	for i, value := range fields {
+		bounds := cronFields[i]
+		number := func(s string) (int, bool) {
+			for j, name := range bounds.names {
+				if strings.EqualFold(s, name) {
+					return j + bounds.min, true
+				}
+			}
+			n, err := strconv.Atoi(s)
+			return n, err == nil && bounds.min <= n && n <= bounds.max
+		}
+
+		for _, part := range strings.Split(value, ",") {
+			if j := strings.Index(part, "/"); j >= 0 {
+				if step, err := strconv.Atoi(part[j+1:]); err != nil || step < 1 {
+					return fmt.Errorf("invalid step in %s field %q", bounds.name, value)
+				}
+				part = part[:j]
+			}
+
+			// "?" is the same as "*" in the day fields.
+			if part == "*" || (part == "?" && (i == 2 || i == 4)) {
+				continue
+			}
+
+			low, high := part, part
+			if j := strings.Index(part, "-"); j >= 0 {
+				low, high = part[:j], part[j+1:]
+			}
+			l, lok := number(low)
+			h, hok := number(high)
+			if !lok || !hok || l > h {
+				return fmt.Errorf("invalid %s field %q", bounds.name, value)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validatePGCronSchedule returns an error when pg_cron cannot parse schedule.
+// pg_cron understands most of what the CronJob controller does, intervals of
+// seconds, and "$" for the last day of the month.
+// - https://github.com/citusdata/pg_cron#what-is-pg_cron
+func validatePGCronSchedule(schedule string) error {
+	schedule = strings.TrimSpace(schedule)
+
+	if seconds := strings.TrimSuffix(schedule, " seconds"); seconds != schedule {
+		if n, err := strconv.Atoi(seconds); err != nil || n < 1 || n > 59 {
+			return fmt.Errorf("invalid interval %q; expected 1 to 59 seconds", schedule)
+		}
+		return nil
+	}
+	if strings.HasPrefix(schedule, "@every ") {
+		return fmt.Errorf("unknown macro %q", schedule)
+	}
+
+	// Check the last day of the month as though it were the 31st.
+	if fields := strings.Fields(schedule); len(fields) == len(cronFields) && fields[2] == "$" {
+		fields[2] = "31"
+		schedule = strings.Join(fields, " ")
+	}
+	return validateCronSchedule(schedule)
+}
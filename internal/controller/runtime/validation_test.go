@@ -0,0 +1,269 @@
+package runtime
+
+/*
+Copyright 2021 Crunchy Data
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestValidateCronSchedule(t *testing.T) {
+	// This is the pattern of schedules in the CRD.
+	pattern := regexp.MustCompile(`^(@[a-z]+( [0-9a-z.]+)?|\S+( +\S+){4})$`)
+
+	for _, valid := range []string{
+		"0 1 * * *",
+		"*/15 * * * *",
+		"0 0-6/2 1,15 * mon-fri",
+		"30 2 ? JAN sun",
+		"@daily",
+		"@every 90m",
+	} {
+		assert.NilError(t, validateCronSchedule(valid), "%q", valid)
+		assert.Assert(t, pattern.MatchString(valid), "%q", valid)
+	}
+
+	// Some mistakes are caught by the pattern.
+	for _, invalid := range []string{
+		"0 0 1 * * *",
+		"0 1 * *",
+		"daily",
+	} {
+		assert.Assert(t, validateCronSchedule(invalid) != nil, "%q", invalid)
+		assert.Assert(t, !pattern.MatchString(invalid), "%q", invalid)
+	}
+
+	// Others are caught only by the webhook.
+	for _, invalid := range []string{
+		"60 * * * *",
+		"0 24 * * *",
+		"0 0 0 * *",
+		"0 0 * 13 *",
+		"0 0 * * 7",
+		"0 0 * * funday",
+		"0 5-1 * * *",
+		"*/0 * * * *",
+		"? * * * *",
+		"@fortnightly",
+		"@every soon",
+	} {
+		assert.Assert(t, validateCronSchedule(invalid) != nil, "%q", invalid)
+	}
+}
+
+func TestValidatePGCronSchedule(t *testing.T) {
+	// This is the pattern of pg_cron schedules in the CRD.
+	pattern := regexp.MustCompile(`^(@[a-z]+|[0-9]+ seconds|\S+( +\S+){4})$`)
+
+	for _, valid := range []string{
+		"0 1 * * *",
+		"*/15 * * * *",
+		"0 12 $ * *",
+		"30 seconds",
+		"@daily",
+	} {
+		assert.NilError(t, validatePGCronSchedule(valid), "%q", valid)
+		assert.Assert(t, pattern.MatchString(valid), "%q", valid)
+	}
+
+	for _, invalid := range []string{
+		"0 seconds",
+		"60 seconds",
+		"@every 90m",
+		"0 0 * $ *",
+		"60 * * * *",
+	} {
+		assert.Assert(t, validatePGCronSchedule(invalid) != nil, "%q", invalid)
+	}
+}
+
+func TestValidateSpec(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{Name: "one"}}
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{Name: "repo1"}}
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{PGBouncer: &v1beta1.PGBouncerPodSpec{}}
+	cluster.Default()
+
+	assert.Assert(t, len(validateSpec(cluster)) == 0)
+
+	t.Run("Schedules", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Backups.PGBackRest.Repos[0].BackupSchedules = &v1beta1.PGBackRestBackupSchedules{
+			Full:        initialize.String("0 1 * * 0"),
+			Incremental: initialize.String("0 25 * * *"),
+		}
+		cluster.Spec.Maintenance = &v1beta1.PostgresMaintenanceSpec{
+			Jobs: []v1beta1.PostgresMaintenanceJob{{Name: "nightly", Schedule: "0 0 32 * *"}},
+		}
+		cluster.Spec.Extensions = &v1beta1.PostgresExtensionsSpec{
+			PGCron: &v1beta1.PGCronSpec{Database: "postgres", Jobs: []v1beta1.PGCronJob{
+				{Name: "ok", Schedule: "10 seconds", Command: "SELECT 1"},
+				{Name: "bad", Schedule: "90 seconds", Command: "SELECT 1"},
+			}},
+			PGPartman: &v1beta1.PGPartmanSpec{Databases: []v1beta1.PGPartmanDatabase{
+				{Name: "app", Schedule: "0 61 * * *"},
+			}},
+		}
+
+		errs := validateSpec(cluster)
+		assert.Equal(t, len(errs), 4, "%v", errs)
+		assert.Equal(t, errs[0].Field, "spec.backups.pgbackrest.repos[0].schedules.incremental")
+		assert.Equal(t, errs[1].Field, "spec.maintenance.jobs[0].schedule")
+		assert.Equal(t, errs[2].Field, "spec.extensions.pgPartman.databases[0].schedule")
+		assert.Equal(t, errs[3].Field, "spec.extensions.pgCron.jobs[1].schedule")
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Metadata = &v1beta1.Metadata{
+			Labels: map[string]string{"ok": "yes", "not ok": "yes"},
+		}
+		cluster.Spec.InstanceSets[0].Metadata = &v1beta1.Metadata{
+			Labels: map[string]string{"value": "has spaces"},
+		}
+		cluster.Spec.Proxy.PGBouncer.Metadata = &v1beta1.Metadata{
+			Annotations: map[string]string{"/empty-prefix": ""},
+		}
+
+		errs := validateSpec(cluster)
+		assert.Assert(t, len(errs) >= 3, "%v", errs)
+
+		fields := map[string]bool{}
+		for _, err := range errs {
+			fields[err.Field] = true
+		}
+		assert.Assert(t, fields["spec.metadata.labels"], "%v", errs)
+		assert.Assert(t, fields["spec.instances[0].metadata.labels"], "%v", errs)
+		assert.Assert(t, fields["spec.proxy.pgBouncer.metadata.annotations"], "%v", errs)
+	})
+
+	t.Run("Ports", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Patroni.Port = initialize.Int32(*cluster.Spec.Port)
+		cluster.Spec.Proxy.PGBouncer.Pools = []v1beta1.PGBouncerPoolSpec{
+			{Name: "a", Port: 6432},
+			{Name: "b", Port: *cluster.Spec.Proxy.PGBouncer.Port},
+			{Name: "c", Port: 6432},
+		}
+
+		errs := validateSpec(cluster)
+		assert.Equal(t, len(errs), 3, "%v", errs)
+		assert.Equal(t, errs[0].Field, "spec.patroni.port")
+		assert.Equal(t, errs[1].Field, "spec.proxy.pgBouncer.pools[1].port")
+		assert.Equal(t, errs[2].Field, "spec.proxy.pgBouncer.pools[2].port")
+	})
+
+	t.Run("Templates", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Users = []v1beta1.PostgresUserSpec{
+			{Name: "app", Databases: []v1beta1.PostgresIdentifier{"app"}},
+		}
+		cluster.Spec.Databases = []v1beta1.PostgresDatabaseSpec{
+			{Name: "base"},
+			{Name: "copy", Template: "base"},
+			{Name: "self", Template: "self"},
+			{Name: "busy", Template: "app"},
+			{Name: "admin", Template: "postgres"},
+			{Name: "sorted", Template: "base", Locale: "C"},
+			{Name: "pristine", Template: "template0", Locale: "C"},
+			{Name: "partitioned", Template: "events"},
+			{Name: "vacuumed", Template: "reports"},
+		}
+		cluster.Spec.Extensions = &v1beta1.PostgresExtensionsSpec{
+			PGPartman: &v1beta1.PGPartmanSpec{
+				Databases: []v1beta1.PGPartmanDatabase{{Name: "events", Schedule: "@hourly"}},
+			},
+		}
+		cluster.Spec.Maintenance = &v1beta1.PostgresMaintenanceSpec{
+			Jobs: []v1beta1.PostgresMaintenanceJob{
+				{Name: "nightly", Schedule: "0 3 * * *", Task: "vacuum", Database: "reports"},
+			},
+		}
+
+		errs := validateSpec(cluster)
+		assert.Equal(t, len(errs), 6, "%v", errs)
+		assert.Equal(t, errs[0].Field, "spec.databases[2].template")
+		assert.Equal(t, errs[1].Field, "spec.databases[3].template")
+		assert.Equal(t, errs[2].Field, "spec.databases[4].template")
+		assert.Equal(t, errs[3].Field, "spec.databases[5].template")
+		assert.Equal(t, errs[4].Field, "spec.databases[7].template")
+		assert.Equal(t, errs[5].Field, "spec.databases[8].template")
+	})
+	t.Run("InitDB", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.PostgresVersion = 16
+		cluster.Spec.Config = &v1beta1.PostgresConfigSpec{
+			InitDB: &v1beta1.PostgresInitDBSpec{LocaleProvider: "icu"},
+		}
+
+		errs := validateSpec(cluster)
+		assert.Equal(t, len(errs), 1, "%v", errs)
+		assert.Equal(t, errs[0].Field, "spec.config.initdb.icuLocale")
+
+		cluster.Spec.Config.InitDB.ICULocale = "en-US"
+		assert.Equal(t, len(validateSpec(cluster)), 0)
+	})
+}
+
+func TestGuardrailsHandleSpec(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NilError(t, v1beta1.AddToScheme(scheme))
+	decoder, err := admission.NewDecoder(scheme)
+	assert.NilError(t, err)
+
+	g := &guardrails{decoder: decoder, mode: GuardrailWarn}
+
+	raw := func(cluster *v1beta1.PostgresCluster) runtime.RawExtension {
+		cluster.APIVersion = v1beta1.GroupVersion.String()
+		cluster.Kind = "PostgresCluster"
+		b, err := json.Marshal(cluster)
+		assert.NilError(t, err)
+		return runtime.RawExtension{Raw: b}
+	}
+
+	bad := new(v1beta1.PostgresCluster)
+	bad.Spec.Metadata = &v1beta1.Metadata{Labels: map[string]string{"not ok": ""}}
+
+	// A new cluster with mistakes is denied.
+	request := admission.Request{}
+	request.Operation = admissionv1.Create
+	request.Object = raw(bad.DeepCopy())
+	assert.Assert(t, !g.Handle(context.Background(), request).Allowed)
+
+	// An update that leaves those mistakes in place is allowed.
+	changed := bad.DeepCopy()
+	changed.Finalizers = []string{"some-finalizer"}
+	request.Operation = admissionv1.Update
+	request.Object = raw(changed)
+	request.OldObject = raw(bad.DeepCopy())
+	assert.Assert(t, g.Handle(context.Background(), request).Allowed)
+
+	// An update to the spec is checked.
+	changed = bad.DeepCopy()
+	changed.Spec.Metadata.Annotations = map[string]string{"a": "b"}
+	request.Object = raw(changed)
+	assert.Assert(t, !g.Handle(context.Background(), request).Allowed)
+}
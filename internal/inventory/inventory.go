@@ -0,0 +1,272 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package inventory summarizes every PostgresCluster managed by the operator
+// so that dashboards can read one document rather than list and parse each
+// cluster in every namespace.
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// Path is where the operator serves the inventory on its metrics server.
+const Path = "/inventory"
+
+// These are the values of Cluster.HA.State.
+const (
+	// StateHealthy means there is a ready primary and every instance is ready.
+	StateHealthy = "Healthy"
+
+	// StateDegraded means there is a ready primary but some instances are not
+	// ready.
+	StateDegraded = "Degraded"
+
+	// StateDown means there is no ready primary.
+	StateDown = "Down"
+
+	// StateShutdown means the cluster is shut down on purpose.
+	StateShutdown = "Shutdown"
+)
+
+// Inventory lists the clusters managed by one operator.
+type Inventory struct {
+	// Time is when the clusters were listed.
+	Time time.Time `json:"time"`
+
+	Clusters []Cluster `json:"clusters"`
+}
+
+// Cluster summarizes one PostgresCluster.
+type Cluster struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// PostgresVersion is the major version of PostgreSQL in the spec.
+	PostgresVersion int `json:"postgresVersion"`
+
+	// Image is the PostgreSQL image the instances should run.
+	Image string `json:"image"`
+
+	// UpgradeAvailable is the newer minor release, if any. See
+	// v1beta1.PostgresClusterStatus.UpgradeAvailable.
+	UpgradeAvailable string `json:"upgradeAvailable,omitempty"`
+
+	InstanceSets []InstanceSet `json:"instanceSets"`
+
+	// Storage is the sum of the data volumes requested by every instance.
+	Storage *resource.Quantity `json:"storage,omitempty"`
+
+	Backup Backup `json:"backup"`
+	HA     HA     `json:"ha"`
+}
+
+// InstanceSet summarizes one instance set of a PostgresCluster.
+type InstanceSet struct {
+	Name string `json:"name"`
+
+	// Replicas is the number of instances in the spec.
+	Replicas int32 `json:"replicas"`
+
+	// ReadyReplicas is the number of instances ready to accept connections.
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// Storage is the data volume requested by each instance.
+	Storage *resource.Quantity `json:"storage,omitempty"`
+}
+
+// Backup summarizes the pgBackRest backups of a PostgresCluster.
+type Backup struct {
+	// Enabled is false when the cluster has no pgBackRest repositories.
+	Enabled bool `json:"enabled"`
+
+	// LastCompletionTime is when the newest successful backup finished,
+	// whether it was scheduled or manual.
+	LastCompletionTime *time.Time `json:"lastCompletionTime,omitempty"`
+
+	// AgeSeconds is the number of seconds between LastCompletionTime and
+	// Inventory.Time.
+	AgeSeconds *int64 `json:"ageSeconds,omitempty"`
+}
+
+// HA summarizes the availability of a PostgresCluster.
+type HA struct {
+	// State is one of Healthy, Degraded, Down, or Shutdown.
+	State string `json:"state"`
+
+	// Primary is the name of the Pod of the primary, if known.
+	Primary string `json:"primary,omitempty"`
+
+	// Instances and ReadyInstances are the totals of every instance set.
+	Instances      int32 `json:"instances"`
+	ReadyInstances int32 `json:"readyInstances"`
+}
+
+// Summarize returns the Cluster that describes cluster at now.
+func Summarize(cluster *v1beta1.PostgresCluster, now time.Time) Cluster {
+	summary := Cluster{
+		Namespace:       cluster.Namespace,
+		Name:            cluster.Name,
+		PostgresVersion: cluster.Spec.PostgresVersion,
+		Image:           config.PostgresContainerImage(cluster),
+		InstanceSets:    make([]InstanceSet, 0, len(cluster.Spec.InstanceSets)),
+	}
+
+	if upgrade := cluster.Status.UpgradeAvailable; upgrade != nil {
+		summary.UpgradeAvailable = upgrade.Version
+	}
+
+	status := make(map[string]v1beta1.PostgresInstanceSetStatus)
+	for _, set := range cluster.Status.InstanceSets {
+		status[set.Name] = set
+	}
+
+	var storage resource.Quantity
+	for _, spec := range cluster.Spec.InstanceSets {
+		set := InstanceSet{Name: spec.Name, Replicas: 1}
+		if spec.Replicas != nil {
+			set.Replicas = *spec.Replicas
+		}
+		set.ReadyReplicas = status[spec.Name].ReadyReplicas
+
+		if request, ok := spec.DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage]; ok {
+			set.Storage = &request
+			for i := int32(0); i < set.Replicas; i++ {
+				storage.Add(request)
+			}
+		}
+
+		for _, member := range status[spec.Name].Members {
+			if member.Role == "primary" && member.Ready {
+				summary.HA.Primary = member.Name
+			}
+		}
+
+		summary.HA.Instances += set.Replicas
+		summary.HA.ReadyInstances += set.ReadyReplicas
+		summary.InstanceSets = append(summary.InstanceSets, set)
+	}
+	if !storage.IsZero() {
+		summary.Storage = &storage
+	}
+
+	switch {
+	case cluster.Spec.Shutdown != nil && *cluster.Spec.Shutdown:
+		summary.HA.State = StateShutdown
+	case summary.HA.Primary == "":
+		summary.HA.State = StateDown
+	case summary.HA.ReadyInstances < summary.HA.Instances:
+		summary.HA.State = StateDegraded
+	default:
+		summary.HA.State = StateHealthy
+	}
+
+	summary.Backup.Enabled = len(cluster.Spec.Backups.PGBackRest.Repos) > 0
+	if last := lastBackup(cluster); last != nil {
+		age := int64(now.Sub(*last) / time.Second)
+		summary.Backup.LastCompletionTime = last
+		summary.Backup.AgeSeconds = &age
+	}
+
+	return summary
+}
+
+// lastBackup returns when the newest successful backup of cluster finished,
+// or nil when none has.
+func lastBackup(cluster *v1beta1.PostgresCluster) *time.Time {
+	status := cluster.Status.PGBackRest
+	if status == nil {
+		return nil
+	}
+
+	var last *time.Time
+	consider := func(completion *metav1.Time) {
+		if completion != nil && (last == nil || completion.Time.After(*last)) {
+			t := completion.Time.UTC()
+			last = &t
+		}
+	}
+	if status.ManualBackup != nil {
+		consider(status.ManualBackup.CompletionTime)
+	}
+	for i := range status.ScheduledBackups {
+		consider(status.ScheduledBackups[i].CompletionTime)
+	}
+	return last
+}
+
+// List returns the Inventory of the PostgresClusters in reader that belong to
+// operatorClass. See v1beta1.PostgresClusterSpec.OperatorClass.
+func List(ctx context.Context, reader client.Reader, operatorClass string) (*Inventory, error) {
+	clusters := &v1beta1.PostgresClusterList{}
+	if err := reader.List(ctx, clusters); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	inventory := &Inventory{
+		Time:     time.Now().UTC().Truncate(time.Second),
+		Clusters: make([]Cluster, 0, len(clusters.Items)),
+	}
+	for i := range clusters.Items {
+		if clusters.Items[i].Spec.OperatorClass == operatorClass {
+			inventory.Clusters = append(inventory.Clusters,
+				Summarize(&clusters.Items[i], inventory.Time))
+		}
+	}
+
+	// Sort by namespace then name so the document is stable between requests.
+	sort.Slice(inventory.Clusters, func(i, j int) bool {
+		a, b := inventory.Clusters[i], inventory.Clusters[j]
+		return a.Namespace < b.Namespace || (a.Namespace == b.Namespace && a.Name < b.Name)
+	})
+	return inventory, nil
+}
+
+// Handler returns an http.Handler that serves the Inventory of the
+// PostgresClusters in reader that belong to operatorClass as JSON.
+// +kubebuilder:rbac:groups="postgres-operator.crunchydata.com",resources="postgresclusters",verbs={list}
+func Handler(reader client.Reader, operatorClass string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		inventory, err := List(r.Context(), reader, operatorClass)
+		if err != nil {
+			logging.FromContext(r.Context()).Error(err, "unable to list clusters for inventory")
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(inventory)
+	})
+}
@@ -0,0 +1,162 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package inventory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestSummarize(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Spec.PostgresVersion = 16
+	cluster.Spec.Image = "postgres:16.1"
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{
+		Name:     "00",
+		Replicas: initialize.Int32(2),
+		DataVolumeClaimSpec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse("1Gi"),
+				},
+			},
+		},
+	}}
+
+	t.Run("Empty", func(t *testing.T) {
+		summary := Summarize(cluster, now)
+		assert.Equal(t, summary.Image, "postgres:16.1")
+		assert.Equal(t, summary.PostgresVersion, 16)
+		assert.Equal(t, summary.Storage.String(), "2Gi")
+		assert.Equal(t, summary.HA.State, StateDown)
+		assert.Equal(t, summary.HA.Instances, int32(2))
+		assert.Assert(t, !summary.Backup.Enabled)
+		assert.Assert(t, summary.Backup.LastCompletionTime == nil)
+	})
+
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{Name: "repo1"}}
+	cluster.Status.UpgradeAvailable = &v1beta1.PostgresUpgradeAvailableStatus{Version: "16.2"}
+	cluster.Status.InstanceSets = []v1beta1.PostgresInstanceSetStatus{{
+		Name:          "00",
+		ReadyReplicas: 1,
+		Members: []v1beta1.PostgresInstanceMemberStatus{
+			{Name: "hippo-00-abcd-0", Role: "primary", Ready: true},
+			{Name: "hippo-00-efgh-0", Role: "replica"},
+		},
+	}}
+	cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+		ManualBackup: &v1beta1.PGBackRestJobStatus{
+			CompletionTime: &metav1.Time{Time: now.Add(-3 * time.Hour)},
+		},
+		ScheduledBackups: []v1beta1.PGBackRestScheduledBackupStatus{
+			{CompletionTime: &metav1.Time{Time: now.Add(-time.Hour)}},
+			{CompletionTime: nil},
+		},
+	}
+
+	t.Run("Degraded", func(t *testing.T) {
+		summary := Summarize(cluster, now)
+		assert.Equal(t, summary.UpgradeAvailable, "16.2")
+		assert.Equal(t, summary.HA.State, StateDegraded)
+		assert.Equal(t, summary.HA.Primary, "hippo-00-abcd-0")
+		assert.Equal(t, summary.HA.ReadyInstances, int32(1))
+		assert.Assert(t, summary.Backup.Enabled)
+		assert.Equal(t, *summary.Backup.LastCompletionTime, now.Add(-time.Hour))
+		assert.Equal(t, *summary.Backup.AgeSeconds, int64(3600))
+	})
+
+	t.Run("Healthy", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Status.InstanceSets[0].ReadyReplicas = 2
+		assert.Equal(t, Summarize(cluster, now).HA.State, StateHealthy)
+	})
+
+	t.Run("Shutdown", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Shutdown = initialize.Bool(true)
+		assert.Equal(t, Summarize(cluster, now).HA.State, StateShutdown)
+	})
+}
+
+func TestHandler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NilError(t, v1beta1.AddToScheme(scheme))
+
+	cluster := func(namespace, name, class string) *v1beta1.PostgresCluster {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Namespace, cluster.Name = namespace, name
+		cluster.Spec.OperatorClass = class
+		cluster.Spec.PostgresVersion = 14
+		return cluster
+	}
+
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		cluster("ns2", "hippo", ""),
+		cluster("ns1", "rhino", ""),
+		cluster("ns1", "hippo", ""),
+		cluster("ns1", "staging", "staging"),
+	).Build()
+
+	t.Run("Get", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		Handler(reader, "").ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path, nil))
+
+		assert.Equal(t, recorder.Code, http.StatusOK)
+		assert.Equal(t, recorder.Header().Get("Content-Type"), "application/json")
+
+		var inventory Inventory
+		assert.NilError(t, json.Unmarshal(recorder.Body.Bytes(), &inventory))
+		assert.Assert(t, !inventory.Time.IsZero())
+
+		var names []string
+		for _, c := range inventory.Clusters {
+			names = append(names, c.Namespace+"/"+c.Name)
+		}
+		assert.DeepEqual(t, names, []string{"ns1/hippo", "ns1/rhino", "ns2/hippo"})
+	})
+
+	t.Run("OperatorClass", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		Handler(reader, "staging").ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path, nil))
+
+		var inventory Inventory
+		assert.NilError(t, json.Unmarshal(recorder.Body.Bytes(), &inventory))
+		assert.Equal(t, len(inventory.Clusters), 1)
+		assert.Equal(t, inventory.Clusters[0].Name, "staging")
+	})
+
+	t.Run("Method", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		Handler(reader, "").ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, Path, nil))
+		assert.Equal(t, recorder.Code, http.StatusMethodNotAllowed)
+	})
+}
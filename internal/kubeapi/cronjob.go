@@ -0,0 +1,65 @@
+package kubeapi
+
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// CronJobGroupVersion is the API of CronJobs in Kubernetes 1.21 and newer. The
+// batch/v1beta1 API is gone in Kubernetes 1.25.
+// - https://docs.k8s.io/reference/using-api/deprecation-guide/#cronjob-v125
+var CronJobGroupVersion = schema.GroupVersion{Group: "batch", Version: "v1"}
+
+// CronJob is a CronJob of the batch/v1 API. The fields of batch/v1 and
+// batch/v1beta1 CronJobs are the same, and the module of Kubernetes APIs used
+// here has only the latter.
+// - https://docs.k8s.io/reference/kubernetes-api/workload-resources/cron-job-v1/
+type CronJob struct{ batchv1beta1.CronJob }
+
+// CronJobList is a list of batch/v1 CronJobs. See CronJob.
+type CronJobList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CronJob `json:"items"`
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *CronJob) DeepCopyObject() runtime.Object {
+	return &CronJob{*in.CronJob.DeepCopy()}
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *CronJobList) DeepCopyObject() runtime.Object {
+	out := &CronJobList{TypeMeta: in.TypeMeta}
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]CronJob, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].CronJob.DeepCopyInto(&out.Items[i].CronJob)
+		}
+	}
+	return out
+}
+
+// AddCronJobToScheme adds the batch/v1 CronJob types to scheme.
+func AddCronJobToScheme(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(CronJobGroupVersion, &CronJob{}, &CronJobList{})
+	return nil
+}
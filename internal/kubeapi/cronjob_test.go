@@ -0,0 +1,62 @@
+package kubeapi
+
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+func TestCronJob(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NilError(t, AddCronJobToScheme(scheme))
+
+	gvk, err := apiutil.GVKForObject(&CronJob{}, scheme)
+	assert.NilError(t, err)
+	assert.Equal(t, gvk.String(), "batch/v1, Kind=CronJob")
+
+	gvk, err = apiutil.GVKForObject(&CronJobList{}, scheme)
+	assert.NilError(t, err)
+	assert.Equal(t, gvk.String(), "batch/v1, Kind=CronJobList")
+
+	cronjob := &CronJob{}
+	cronjob.SetGroupVersionKind(gvk.GroupVersion().WithKind("CronJob"))
+	cronjob.Name = "some-cronjob"
+	cronjob.Spec.Schedule = "@daily"
+
+	// Fields are the same as batch/v1beta1.
+	data, err := json.Marshal(cronjob)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(string(data),
+		`{"kind":"CronJob","apiVersion":"batch/v1","metadata":{"name":"some-cronjob",`))
+	assert.Assert(t, strings.Contains(string(data), `"spec":{"schedule":"@daily",`))
+
+	decoded, _, err := serializer.NewCodecFactory(scheme).
+		UniversalDeserializer().Decode(data, nil, nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, decoded, cronjob)
+
+	copied := cronjob.DeepCopyObject()
+	assert.DeepEqual(t, copied, cronjob)
+	cronjob.Spec.Schedule = "@hourly"
+	assert.Equal(t, copied.(*CronJob).Spec.Schedule, "@daily")
+}
@@ -0,0 +1,82 @@
+package kubeapi
+
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"github.com/pkg/errors"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+)
+
+// APIs are the versions of Kubernetes APIs that differ between releases of
+// Kubernetes. The zero value is the oldest of each. See DiscoverAPIs.
+type APIs struct {
+	// CronJob is the API of CronJobs: batch/v1 or batch/v1beta1.
+	CronJob schema.GroupVersion
+
+	// PodSecurityAdmission is true when Kubernetes enforces the Pod Security
+	// Standards of namespaces, Kubernetes 1.23 and newer. The "restricted"
+	// standard requires Pods to have a seccomp profile.
+	// - https://docs.k8s.io/concepts/security/pod-security-admission/
+	PodSecurityAdmission bool
+}
+
+// CronJobV1 returns true when CronJobs are in the batch/v1 API.
+func (a APIs) CronJobV1() bool { return a.CronJob == CronJobGroupVersion }
+
+// DiscoverAPIs asks Kubernetes which of its APIs to use.
+func DiscoverAPIs(client discovery.DiscoveryInterface) (APIs, error) {
+	apis := APIs{
+		CronJob: batchv1beta1.SchemeGroupVersion,
+	}
+
+	served := func(gv schema.GroupVersion, kind string) (bool, error) {
+		resources, err := client.ServerResourcesForGroupVersion(gv.String())
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		for _, r := range resources.APIResources {
+			if r.Kind == kind {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if ok, err := served(CronJobGroupVersion, "CronJob"); err != nil {
+		return apis, err
+	} else if ok {
+		apis.CronJob = CronJobGroupVersion
+	}
+
+	info, err := client.ServerVersion()
+	if err != nil {
+		return apis, errors.WithStack(err)
+	}
+	v, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return apis, errors.WithStack(err)
+	}
+	apis.PodSecurityAdmission = v.AtLeast(version.MustParseGeneric("1.23"))
+
+	return apis, nil
+}
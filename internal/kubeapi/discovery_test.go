@@ -0,0 +1,92 @@
+package kubeapi
+
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"errors"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+)
+
+// fakeDiscovery serves resources and reports version. Its other methods panic.
+type fakeDiscovery struct {
+	discovery.DiscoveryInterface
+	resources map[string][]metav1.APIResource
+	version   string
+	err       error
+}
+
+func (f fakeDiscovery) ServerResourcesForGroupVersion(gv string) (*metav1.APIResourceList, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if resources, ok := f.resources[gv]; ok {
+		return &metav1.APIResourceList{GroupVersion: gv, APIResources: resources}, nil
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{}, "")
+}
+
+func (f fakeDiscovery) ServerVersion() (*version.Info, error) {
+	return &version.Info{GitVersion: f.version}, nil
+}
+
+func TestDiscoverAPIs(t *testing.T) {
+	t.Run("Old", func(t *testing.T) {
+		apis, err := DiscoverAPIs(fakeDiscovery{version: "v1.18.20"})
+		assert.NilError(t, err)
+		assert.Equal(t, apis.CronJob.String(), "batch/v1beta1")
+		assert.Assert(t, !apis.CronJobV1())
+		assert.Assert(t, !apis.PodSecurityAdmission)
+	})
+
+	t.Run("New", func(t *testing.T) {
+		apis, err := DiscoverAPIs(fakeDiscovery{
+			version: "v1.25.3-gke.100",
+			resources: map[string][]metav1.APIResource{
+				"batch/v1": {{Kind: "Job"}, {Kind: "CronJob"}},
+			},
+		})
+		assert.NilError(t, err)
+		assert.Equal(t, apis.CronJob.String(), "batch/v1")
+		assert.Assert(t, apis.CronJobV1())
+		assert.Assert(t, apis.PodSecurityAdmission)
+	})
+
+	t.Run("Between", func(t *testing.T) {
+		apis, err := DiscoverAPIs(fakeDiscovery{
+			version: "v1.20.0",
+			resources: map[string][]metav1.APIResource{
+				"batch/v1": {{Kind: "Job"}},
+			},
+		})
+		assert.NilError(t, err)
+		assert.Equal(t, apis.CronJob.String(), "batch/v1beta1")
+		assert.Assert(t, !apis.PodSecurityAdmission)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		_, err := DiscoverAPIs(fakeDiscovery{err: errors.New("boom")})
+		assert.ErrorContains(t, err, "boom")
+	})
+
+	assert.Assert(t, !APIs{}.CronJobV1(), "expected the zero value to be the oldest")
+}
@@ -0,0 +1,71 @@
+package kubeapi
+
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// ManagedFieldPaths returns the fields owned by manager in entries. Each path
+// is the sequence of "FieldsV1" keys that lead to a field, joined by slashes.
+// - https://docs.k8s.io/reference/using-api/server-side-apply/#field-management
+func ManagedFieldPaths(entries []metav1.ManagedFieldsEntry, manager string) sets.String {
+	paths := sets.NewString()
+	for i := range entries {
+		if entries[i].Manager == manager {
+			paths.Insert(FieldsV1Paths(entries[i].FieldsV1).UnsortedList()...)
+		}
+	}
+	return paths
+}
+
+// FieldsV1Paths returns the paths of the fields in fields. A field that has
+// members is included only when it is marked as owned by itself, ".".
+// - https://releases.k8s.io/v1.20.0/staging/src/k8s.io/apimachinery/pkg/apis/meta/v1/types.go#L1234
+func FieldsV1Paths(fields *metav1.FieldsV1) sets.String {
+	paths := sets.NewString()
+	if fields == nil || len(fields.Raw) == 0 {
+		return paths
+	}
+
+	// Kubernetes validates this JSON; ignore anything that cannot be decoded.
+	var root map[string]interface{}
+	if err := json.Unmarshal(fields.Raw, &root); err != nil {
+		return paths
+	}
+
+	var walk func(prefix []string, node map[string]interface{})
+	walk = func(prefix []string, node map[string]interface{}) {
+		if len(node) == 0 && len(prefix) > 0 {
+			paths.Insert(strings.Join(prefix, "/"))
+		}
+		for key, value := range node {
+			if key == "." {
+				paths.Insert(strings.Join(prefix, "/"))
+				continue
+			}
+			child, _ := value.(map[string]interface{})
+			walk(append(prefix[:len(prefix):len(prefix)], key), child)
+		}
+	}
+	walk(nil, root)
+
+	return paths
+}
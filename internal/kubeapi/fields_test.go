@@ -0,0 +1,61 @@
+package kubeapi
+
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFieldsV1Paths(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, FieldsV1Paths(nil).Len(), 0)
+	assert.Equal(t, FieldsV1Paths(&metav1.FieldsV1{}).Len(), 0)
+	assert.Equal(t, FieldsV1Paths(&metav1.FieldsV1{Raw: []byte(`{`)}).Len(), 0)
+
+	paths := FieldsV1Paths(&metav1.FieldsV1{Raw: []byte(`{
+		"f:metadata": { "f:labels": { "f:a": {}, "f:b": {} } },
+		"f:spec": {
+			"f:ports": { "k:{\"port\":5432}": { ".": {}, "f:name": {} } }
+		}
+	}`)})
+
+	assert.DeepEqual(t, paths.List(), []string{
+		"f:metadata/f:labels/f:a",
+		"f:metadata/f:labels/f:b",
+		`f:spec/f:ports/k:{"port":5432}`,
+		`f:spec/f:ports/k:{"port":5432}/f:name`,
+	})
+}
+
+func TestManagedFieldPaths(t *testing.T) {
+	t.Parallel()
+
+	entries := []metav1.ManagedFieldsEntry{
+		{Manager: "one", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:x":{}}}`)}},
+		{Manager: "two", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:y":{}}}`)}},
+		{Manager: "one", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:z":{}}}`)}},
+	}
+
+	assert.DeepEqual(t, ManagedFieldPaths(entries, "one").List(),
+		[]string{"f:data/f:x", "f:data/f:z"})
+	assert.DeepEqual(t, ManagedFieldPaths(entries, "two").List(),
+		[]string{"f:data/f:y"})
+	assert.Equal(t, ManagedFieldPaths(entries, "three").Len(), 0)
+}
@@ -17,14 +17,22 @@ package logging
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
 
 	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
 	"github.com/wojas/genericr"
 	"go.opentelemetry.io/otel/trace"
 )
 
 var global = logr.Discard()
 
+// debug receives every entry of loggers from NewDebugContext. See SetDebugLogFunc.
+var debug = logr.Discard()
+
 // Discard returns a logr.Logger that discards all messages logged to it.
 func Discard() logr.Logger { return logr.DiscardLogger{} }
 
@@ -36,11 +44,153 @@ func SetLogFunc(verbosity int, log genericr.LogFunc) {
 	global = genericr.New(log).WithCaller(true).WithVerbosity(verbosity)
 }
 
+// SetDebugLogFunc replaces the logr.Logger of the debug stream with log that
+// gets called for every entry, regardless of verbosity. Before this is called,
+// the debug stream is a no-op. See NewDebugContext.
+func SetDebugLogFunc(log genericr.LogFunc) {
+	// Entries pass through tee, so skip it when looking for their callers.
+	debug = genericr.New(log).WithCaller(true).WithCallerDepth(1).WithName("debug")
+}
+
+// SetLogFuncNamed is like SetLogFunc but uses a different verbosity for
+// entries of named loggers. When any part of an entry's name is in names, its
+// verbosity is the value of the last such part.
+func SetLogFuncNamed(verbosity int, names map[string]int, log genericr.LogFunc) {
+	if len(names) == 0 {
+		SetLogFunc(verbosity, log)
+		return
+	}
+
+	highest := verbosity
+	for _, v := range names {
+		if v > highest {
+			highest = v
+		}
+	}
+
+	SetLogFunc(highest, func(input genericr.Entry) {
+		limit := verbosity
+		for _, part := range input.NameParts {
+			if v, ok := names[part]; ok {
+				limit = v
+			}
+		}
+		if input.Error != nil || input.Level <= limit {
+			log(input)
+		}
+	})
+}
+
+// ParseVerbosity interprets value as a logr.Logger verbosity. It accepts
+// non-negative integers and the words "info" (0) and "debug" (1).
+func ParseVerbosity(value string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "info":
+		return 0, nil
+	case "debug":
+		return 1, nil
+	}
+
+	v, err := strconv.Atoi(strings.TrimSpace(value))
+	if err == nil && v < 0 {
+		err = errors.Errorf("verbosity must not be negative: %d", v)
+	}
+	return v, errors.WithStack(err)
+}
+
+// ParseNamedVerbosity interprets value as a comma-separated list of logger
+// names and verbosity, e.g. "postgrescluster-controller=2,manager=info".
+func ParseNamedVerbosity(value string) (map[string]int, error) {
+	names := make(map[string]int)
+
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, errors.Errorf("expected name=verbosity, got %q", item)
+		}
+
+		v, err := ParseVerbosity(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		names[strings.TrimSpace(kv[0])] = v
+	}
+
+	return names, nil
+}
+
 // NewContext returns a copy of ctx containing logger. Retrieve it using FromContext.
 func NewContext(ctx context.Context, logger logr.Logger) context.Context {
 	return logr.NewContext(ctx, logger)
 }
 
+// NewRequestContext returns a copy of ctx containing a logger that identifies
+// one unit of work, such as a reconcile. The identifier is the OpenTelemetry
+// trace ID, when there is one, so that logs and traces can be correlated.
+func NewRequestContext(ctx context.Context) context.Context {
+	var id string
+
+	if sc := trace.SpanFromContext(ctx).SpanContext(); sc.HasTraceID() {
+		id = sc.TraceID.String()
+	} else {
+		var random [16]byte
+		_, _ = rand.Read(random[:])
+		id = hex.EncodeToString(random[:])
+	}
+
+	var log logr.Logger
+	if log = logr.FromContext(ctx); log == nil {
+		log = global
+	}
+
+	return NewContext(ctx, log.WithValues("requestid", id))
+}
+
+// NewDebugContext returns a copy of ctx containing a logger that also sends
+// every entry, at any verbosity, to the debug stream. The logger adds
+// keysAndValues to those entries so they can be told apart.
+func NewDebugContext(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	var log logr.Logger
+	if log = logr.FromContext(ctx); log == nil {
+		log = global
+	}
+
+	return NewContext(ctx, tee{log, debug.WithValues(keysAndValues...)})
+}
+
+// tee is a logr.Logger that sends entries to two others.
+type tee struct{ a, b logr.Logger }
+
+func (t tee) Enabled() bool { return t.a.Enabled() || t.b.Enabled() }
+
+func (t tee) Info(msg string, keysAndValues ...interface{}) {
+	if t.a.Enabled() {
+		t.a.Info(msg, keysAndValues...)
+	}
+	if t.b.Enabled() {
+		t.b.Info(msg, keysAndValues...)
+	}
+}
+
+func (t tee) Error(err error, msg string, keysAndValues ...interface{}) {
+	t.a.Error(err, msg, keysAndValues...)
+	t.b.Error(err, msg, keysAndValues...)
+}
+
+func (t tee) V(level int) logr.Logger { return tee{t.a.V(level), t.b.V(level)} }
+
+func (t tee) WithName(name string) logr.Logger {
+	return tee{t.a.WithName(name), t.b.WithName(name)}
+}
+
+func (t tee) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return tee{t.a.WithValues(keysAndValues...), t.b.WithValues(keysAndValues...)}
+}
+
 // FromContext returns the global logr.Logger or the one stored by a prior call
 // to NewContext.
 func FromContext(ctx context.Context) logr.Logger {
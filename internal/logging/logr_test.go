@@ -75,3 +75,98 @@ func TestSetLogFunc(t *testing.T) {
 	global.Info("called")
 	assert.DeepEqual(t, calls, []string{"called"})
 }
+
+func TestSetLogFuncNamed(t *testing.T) {
+	var calls []string
+
+	SetLogFuncNamed(1, map[string]int{"loud": 2, "quiet": 0}, func(input genericr.Entry) {
+		calls = append(calls, input.Message)
+	})
+
+	global.V(1).Info("debug")
+	global.V(2).Info("skipped")
+	global.WithName("loud").V(2).Info("loud")
+	global.WithName("loud").V(3).Info("skipped")
+	global.WithName("loud").WithName("quiet").V(1).Info("skipped")
+	global.WithName("quiet").Info("quiet")
+	global.WithName("other").V(1).Info("other")
+
+	assert.DeepEqual(t, calls, []string{"debug", "loud", "quiet", "other"})
+}
+
+func TestParseVerbosity(t *testing.T) {
+	for _, tt := range []struct {
+		value  string
+		expect int
+	}{
+		{"0", 0}, {"3", 3}, {"info", 0}, {"DEBUG", 1}, {" 2 ", 2},
+	} {
+		v, err := ParseVerbosity(tt.value)
+		assert.NilError(t, err, "%q", tt.value)
+		assert.Equal(t, v, tt.expect, "%q", tt.value)
+	}
+
+	for _, value := range []string{"", "-1", "loud"} {
+		_, err := ParseVerbosity(value)
+		assert.Assert(t, err != nil, "%q", value)
+	}
+}
+
+func TestParseNamedVerbosity(t *testing.T) {
+	names, err := ParseNamedVerbosity("")
+	assert.NilError(t, err)
+	assert.Equal(t, len(names), 0)
+
+	names, err = ParseNamedVerbosity("postgrescluster-controller=2, manager=info,")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, names, map[string]int{"postgrescluster-controller": 2, "manager": 0})
+
+	for _, value := range []string{"manager", "=1", "manager=loud"} {
+		_, err := ParseNamedVerbosity(value)
+		assert.Assert(t, err != nil, "%q", value)
+	}
+}
+
+func TestNewRequestContext(t *testing.T) {
+	var calls []map[string]interface{}
+
+	SetLogFunc(0, func(input genericr.Entry) {
+		calls = append(calls, input.FieldsMap())
+	})
+
+	// Random when there's no trace.
+	FromContext(NewRequestContext(context.Background())).Info("")
+	FromContext(NewRequestContext(context.Background())).Info("")
+	assert.Equal(t, len(calls[0]["requestid"].(string)), 32)
+	assert.Assert(t, calls[0]["requestid"] != calls[1]["requestid"])
+
+	ctx, span := oteltest.DefaultTracer().Start(context.Background(), "test-span")
+	defer span.End()
+
+	// OpenTelemetry trace ID when there is.
+	FromContext(NewRequestContext(ctx)).Info("")
+	assert.Equal(t, calls[2]["requestid"], span.SpanContext().TraceID.String())
+}
+
+func TestNewDebugContext(t *testing.T) {
+	var calls, debugged []string
+
+	SetLogFunc(0, func(input genericr.Entry) {
+		calls = append(calls, input.Message)
+	})
+	SetDebugLogFunc(func(input genericr.Entry) {
+		assert.Equal(t, input.FieldsMap()["cluster"], "hippo")
+		debugged = append(debugged, input.Message)
+	})
+	t.Cleanup(func() { debug = Discard() })
+
+	log := FromContext(NewDebugContext(context.Background(), "cluster", "hippo"))
+	log.Info("info")
+	log.V(5).Info("detail")
+	log.WithName("named").WithValues("k", "v").V(1).Info("named")
+	log.Error(nil, "error")
+
+	// Only the debug stream has entries above the global verbosity.
+	assert.DeepEqual(t, calls, []string{"info", "error"})
+	assert.DeepEqual(t, debugged, []string{"info", "detail", "named", "error"})
+}
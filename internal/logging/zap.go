@@ -0,0 +1,102 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package logging
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wojas/genericr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Zap creates a function that writes genericr.Entry to out using a zap
+// encoding, either "json" or "console". The resulting zapcore.Level depends
+// on Entry.Error and Entry.Level the same way as Logrus:
+//	- Entry.Error ≠ nil   → zapcore.ErrorLevel
+//	- Entry.Level < debug → zapcore.InfoLevel
+//	- Entry.Level ≥ debug → zapcore.DebugLevel
+func Zap(out io.Writer, version string, debug int, encoding string) genericr.LogFunc {
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	encoder := zapcore.NewJSONEncoder(config)
+	if encoding == "console" {
+		encoder = zapcore.NewConsoleEncoder(config)
+	}
+
+	root := zap.New(zapcore.NewCore(encoder, zapcore.AddSync(out), zapcore.DebugLevel)).
+		With(zap.String("version", version))
+
+	// Fields with these keys are renamed so they don't overwrite builtins.
+	reserved := map[string]bool{
+		config.LevelKey: true, config.TimeKey: true, config.NameKey: true,
+		config.MessageKey: true, "version": true,
+		"error": true, "file": true, "func": true,
+	}
+
+	_, module, _, _ := runtime.Caller(0)
+	module = strings.TrimSuffix(module, "internal/logging/zap.go")
+
+	return func(input genericr.Entry) {
+		fields := make([]zap.Field, 0, 3+len(input.Fields)/2)
+		frame := input.Caller
+		level := zapcore.InfoLevel
+		logger := root
+
+		if input.Level >= debug {
+			level = zapcore.DebugLevel
+		}
+		if input.Name != "" {
+			logger = logger.Named(input.Name)
+		}
+		for i := 0; i+1 < len(input.Fields); i += 2 {
+			key := fmt.Sprint(input.Fields[i])
+			if reserved[key] {
+				key = "fields." + key
+			}
+			fields = append(fields, zap.Any(key, input.Fields[i+1]))
+		}
+		if input.Error != nil {
+			fields = append(fields, zap.String("error", input.Error.Error()))
+			level = zapcore.ErrorLevel
+
+			var t interface{ StackTrace() errors.StackTrace }
+			if errors.As(input.Error, &t) {
+				if st := t.StackTrace(); len(st) > 0 {
+					frame, _ = runtime.CallersFrames([]uintptr{uintptr(st[0])}).Next()
+				}
+			}
+		}
+		if frame.File != "" {
+			filename := strings.TrimPrefix(frame.File, module)
+			fields = append(fields, zap.String("file", fmt.Sprintf("%s:%d", filename, frame.Line)))
+		}
+		if frame.Function != "" {
+			_, function := filepath.Split(frame.Function)
+			fields = append(fields, zap.String("func", function))
+		}
+
+		if checked := logger.Check(level, input.Message); checked != nil {
+			checked.Write(fields...)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/wojas/genericr"
+	"gotest.tools/v3/assert"
+)
+
+func TestZapJSON(t *testing.T) {
+	t.Parallel()
+
+	out := new(bytes.Buffer)
+	zap := Zap(out, "v1", 1, "json")
+
+	decode := func(t testing.TB) map[string]interface{} {
+		t.Helper()
+		var result map[string]interface{}
+		assert.NilError(t, json.Unmarshal(out.Bytes(), &result), "%s", out)
+		out.Reset()
+		return result
+	}
+
+	// Default level is INFO.
+	// Version field is always present.
+	zap(genericr.Entry{})
+	entry := decode(t)
+	assert.Equal(t, entry["level"], "info")
+	assert.Equal(t, entry["version"], "v1")
+
+	// Configured level or higher is DEBUG.
+	zap(genericr.Entry{Level: 1})
+	assert.Equal(t, decode(t)["level"], "debug")
+	zap(genericr.Entry{Level: 2})
+	assert.Equal(t, decode(t)["level"], "debug")
+
+	// Any error becomes ERROR level.
+	zap(genericr.Entry{Error: fmt.Errorf("%s", "dang")})
+	entry = decode(t)
+	assert.Equal(t, entry["level"], "error")
+	assert.Equal(t, entry["error"], "dang")
+
+	// A wrapped error includes one frame of its stack.
+	_, _, baseline, _ := runtime.Caller(0)
+	zap(genericr.Entry{Error: errors.New("dang")})
+	entry = decode(t)
+	assert.Equal(t, entry["file"], fmt.Sprintf("internal/logging/zap_test.go:%d", baseline+1))
+	assert.Equal(t, entry["func"], "logging.TestZapJSON")
+
+	zap(genericr.Entry{Name: "some.name", Message: "banana",
+		Fields: []interface{}{"k1", "str", "k2", 13, "k3", false}})
+	entry = decode(t)
+	assert.Equal(t, entry["logger"], "some.name")
+	assert.Equal(t, entry["msg"], "banana")
+	assert.Equal(t, entry["k1"], "str")
+	assert.Equal(t, entry["k2"], float64(13))
+	assert.Equal(t, entry["k3"], false)
+
+	// Fields don't overwrite builtins.
+	zap(genericr.Entry{
+		Message: "banana",
+		Error:   errors.New("dang"),
+		Fields: []interface{}{
+			"error", "not-err",
+			"level", "not-lvl",
+			"msg", "not-msg",
+		},
+	})
+	entry = decode(t)
+	assert.Equal(t, entry["level"], "error")
+	assert.Equal(t, entry["msg"], "banana")
+	assert.Equal(t, entry["error"], "dang")
+	assert.Equal(t, entry["fields.error"], "not-err")
+	assert.Equal(t, entry["fields.level"], "not-lvl")
+	assert.Equal(t, entry["fields.msg"], "not-msg")
+}
+
+func TestZapConsole(t *testing.T) {
+	t.Parallel()
+
+	out := new(bytes.Buffer)
+	zap := Zap(out, "v2", 1, "console")
+
+	zap(genericr.Entry{Level: 1, Message: "banana", Fields: []interface{}{"k1", "str"}})
+	assert.Assert(t, strings.Contains(out.String(), "\tdebug\tbanana\t"), "%q", out)
+	assert.Assert(t, strings.Contains(out.String(), `"k1": "str"`), "%q", out)
+	assert.Assert(t, strings.Contains(out.String(), `"version": "v2"`), "%q", out)
+}
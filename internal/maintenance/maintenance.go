@@ -0,0 +1,97 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"fmt"
+
+	"github.com/crunchydata/postgres-operator/internal/pgpartman"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+const (
+	// User is the PostgreSQL role that maintenance jobs connect as. It is not a
+	// superuser; it is a member of every user in the spec so it can maintain
+	// the objects they own.
+	User = "_crunchymaintenance"
+
+	// TaskVacuum, TaskAnalyze, TaskVacuumAnalyze, and TaskReindex are the
+	// built-in tasks of a maintenance job.
+	TaskVacuum        = "vacuum"
+	TaskAnalyze       = "analyze"
+	TaskVacuumAnalyze = "vacuumAnalyze"
+	TaskReindex       = "reindex"
+)
+
+// Enabled returns true when cluster has at least one maintenance job.
+func Enabled(cluster *v1beta1.PostgresCluster) bool {
+	return len(Jobs(cluster)) > 0
+}
+
+// Jobs returns the maintenance jobs of cluster: those in its spec followed by
+// those of extensions that need maintenance, like pg_partman.
+func Jobs(cluster *v1beta1.PostgresCluster) []v1beta1.PostgresMaintenanceJob {
+	var jobs []v1beta1.PostgresMaintenanceJob
+	if cluster.Spec.Maintenance != nil {
+		jobs = append(jobs, cluster.Spec.Maintenance.Jobs...)
+	}
+	return append(jobs, pgpartman.MaintenanceJobs(cluster)...)
+}
+
+// Script returns the psql script that job runs. It returns an error when job
+// does not have exactly one of a task or SQL.
+func Script(job *v1beta1.PostgresMaintenanceJob) (string, error) {
+	if (job.Task == "") == (job.SQL == "") {
+		return "", fmt.Errorf("maintenance job %q must have exactly one of task or sql", job.Name)
+	}
+	if job.SQL != "" {
+		return job.SQL, nil
+	}
+
+	switch job.Task {
+	case TaskVacuum:
+		// VACUUM without a table processes every table, skipping with a WARNING
+		// those that the current user cannot vacuum.
+		// - https://www.postgresql.org/docs/current/sql-vacuum.html
+		return `VACUUM;`, nil
+
+	case TaskAnalyze:
+		// - https://www.postgresql.org/docs/current/sql-analyze.html
+		return `ANALYZE;`, nil
+
+	case TaskVacuumAnalyze:
+		return `VACUUM (ANALYZE);`, nil
+
+	case TaskReindex:
+		// REINDEX DATABASE requires ownership of the database, so reindex each
+		// table the current user can. Every statement runs in its own
+		// transaction so that locks are held only while a table is reindexed.
+		// - https://www.postgresql.org/docs/current/sql-reindex.html
+		return `
+SELECT pg_catalog.format('REINDEX TABLE %I.%I', n.nspname, c.relname)
+  FROM pg_catalog.pg_class c
+  JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+ WHERE c.relkind IN ('r', 'm')
+   AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+   AND n.nspname NOT LIKE 'pg\_toast%'
+   AND pg_catalog.pg_has_role(c.relowner, 'USAGE')
+ ORDER BY n.nspname, c.relname
+\gexec
+`, nil
+	}
+
+	return "", fmt.Errorf("maintenance job %q has unknown task %q", job.Name, job.Task)
+}
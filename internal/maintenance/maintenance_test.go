@@ -0,0 +1,105 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestEnabled(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	assert.Assert(t, !Enabled(cluster))
+
+	cluster.Spec.Maintenance = &v1beta1.PostgresMaintenanceSpec{}
+	assert.Assert(t, !Enabled(cluster))
+
+	cluster.Spec.Maintenance.Jobs = []v1beta1.PostgresMaintenanceJob{{Name: "nightly"}}
+	assert.Assert(t, Enabled(cluster))
+
+	cluster.Spec.Maintenance = nil
+	cluster.Spec.Extensions = &v1beta1.PostgresExtensionsSpec{
+		PGPartman: &v1beta1.PGPartmanSpec{
+			Databases: []v1beta1.PGPartmanDatabase{{Name: "app"}},
+		},
+	}
+	assert.Assert(t, Enabled(cluster), "expected pg_partman maintenance")
+}
+
+func TestJobs(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	assert.Assert(t, Jobs(cluster) == nil)
+
+	cluster.Spec.Maintenance = &v1beta1.PostgresMaintenanceSpec{
+		Jobs: []v1beta1.PostgresMaintenanceJob{{Name: "nightly", Database: "app"}},
+	}
+	cluster.Spec.Extensions = &v1beta1.PostgresExtensionsSpec{
+		PGPartman: &v1beta1.PGPartmanSpec{
+			Databases: []v1beta1.PGPartmanDatabase{{Name: "app"}},
+		},
+	}
+
+	jobs := Jobs(cluster)
+	assert.Equal(t, len(jobs), 2)
+	assert.Equal(t, jobs[0].Name, "nightly")
+	assert.Assert(t, strings.HasPrefix(jobs[1].Name, "partman-"))
+	assert.Equal(t, len(cluster.Spec.Maintenance.Jobs), 1, "expected no change to spec")
+}
+
+func TestScript(t *testing.T) {
+	t.Run("Neither", func(t *testing.T) {
+		_, err := Script(&v1beta1.PostgresMaintenanceJob{Name: "x"})
+		assert.ErrorContains(t, err, "exactly one")
+	})
+
+	t.Run("Both", func(t *testing.T) {
+		_, err := Script(&v1beta1.PostgresMaintenanceJob{
+			Name: "x", Task: TaskVacuum, SQL: "SELECT 1;",
+		})
+		assert.ErrorContains(t, err, "exactly one")
+	})
+
+	t.Run("SQL", func(t *testing.T) {
+		script, err := Script(&v1beta1.PostgresMaintenanceJob{SQL: "CLUSTER;"})
+		assert.NilError(t, err)
+		assert.Equal(t, script, "CLUSTER;")
+	})
+
+	t.Run("Tasks", func(t *testing.T) {
+		for task, expected := range map[string]string{
+			TaskVacuum:        "VACUUM;",
+			TaskAnalyze:       "ANALYZE;",
+			TaskVacuumAnalyze: "VACUUM (ANALYZE);",
+		} {
+			script, err := Script(&v1beta1.PostgresMaintenanceJob{Task: task})
+			assert.NilError(t, err)
+			assert.Equal(t, script, expected)
+		}
+
+		script, err := Script(&v1beta1.PostgresMaintenanceJob{Task: TaskReindex})
+		assert.NilError(t, err)
+		assert.Assert(t, len(script) > 0)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := Script(&v1beta1.PostgresMaintenanceJob{Name: "x", Task: "cluster"})
+		assert.ErrorContains(t, err, `unknown task "cluster"`)
+	})
+}
@@ -0,0 +1,107 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+)
+
+// EnableInPostgreSQL creates the maintenance user, or updates its password to
+// verifier, and makes it a member of users so it can maintain their objects.
+// Users that do not exist yet are skipped.
+func EnableInPostgreSQL(
+	ctx context.Context, exec postgres.Executor, verifier string, users []string,
+) error {
+	log := logging.FromContext(ctx)
+
+	members, err := json.Marshal(users)
+	if err != nil {
+		return err
+	}
+
+	stdout, stderr, err := exec.Exec(ctx,
+		strings.NewReader(strings.Join([]string{
+			// Quiet NOTICE messages from IF EXISTS statements.
+			// - https://www.postgresql.org/docs/current/runtime-config-client.html
+			`SET client_min_messages = WARNING;`,
+
+			// Prevent unexpected dereferences by emptying "search_path".
+			`SET search_path TO '';`,
+
+			`BEGIN;`,
+
+			// Create the user when it does not exist. It can login and nothing
+			// else; it gets privileges only through its membership below.
+			// - https://www.postgresql.org/docs/current/sql-createrole.html
+			`SELECT pg_catalog.format('CREATE ROLE %I', :'username')`,
+			` WHERE NOT EXISTS (SELECT 1 FROM pg_catalog.pg_roles WHERE rolname = :'username')`,
+			`\gexec`,
+			`ALTER ROLE :"username" WITH LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE` +
+				` NOREPLICATION NOBYPASSRLS PASSWORD :'verifier';`,
+
+			// Grant membership in every user that exists. Members inherit the
+			// privileges of the roles they belong to, including ownership.
+			// - https://www.postgresql.org/docs/current/role-membership.html
+			`SELECT pg_catalog.format('GRANT %I TO %I', rolname, :'username')`,
+			`  FROM pg_catalog.pg_roles`,
+			` WHERE rolname IN (SELECT pg_catalog.json_array_elements_text(:'members'::json))`,
+			`   AND NOT rolsuper`,
+			` ORDER BY rolname`,
+			`\gexec`,
+
+			`COMMIT;`,
+		}, "\n")),
+		map[string]string{
+			"members":  string(members),
+			"username": User,
+			"verifier": verifier,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("enabled maintenance user", "stdout", stdout, "stderr", stderr)
+
+	return err
+}
+
+// DisableInPostgreSQL prevents the maintenance user from logging in. The user
+// is not dropped because other objects may depend on it.
+func DisableInPostgreSQL(ctx context.Context, exec postgres.Executor) error {
+	log := logging.FromContext(ctx)
+
+	stdout, stderr, err := exec.Exec(ctx,
+		strings.NewReader(strings.Join([]string{
+			`SELECT pg_catalog.format('ALTER ROLE %I NOLOGIN', :'username')`,
+			` WHERE EXISTS (SELECT 1 FROM pg_catalog.pg_roles WHERE rolname = :'username')`,
+			`\gexec`,
+		}, "\n")),
+		map[string]string{
+			"username": User,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("disabled maintenance user", "stdout", stdout, "stderr", stderr)
+
+	return err
+}
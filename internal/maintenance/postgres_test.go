@@ -0,0 +1,72 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestEnableInPostgreSQL(t *testing.T) {
+	expected := errors.New("whoops")
+	exec := func(
+		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		assert.Assert(t, stdout != nil, "should capture stdout")
+		assert.Assert(t, stderr != nil, "should capture stderr")
+
+		assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+			`--set=members=["app","rhino"]`))
+		assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+			`--set=username=_crunchymaintenance`))
+		assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+			`--set=verifier=SCRAM-SHA-256$secret`))
+
+		b, err := ioutil.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(string(b), `NOSUPERUSER`))
+		assert.Assert(t, strings.Contains(string(b), `GRANT %I TO %I`))
+
+		return expected
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, expected, EnableInPostgreSQL(ctx, exec,
+		"SCRAM-SHA-256$secret", []string{"app", "rhino"}))
+}
+
+func TestDisableInPostgreSQL(t *testing.T) {
+	expected := errors.New("whoops")
+	exec := func(
+		_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		b, err := ioutil.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(string(b), `NOLOGIN`))
+		assert.Assert(t, !strings.Contains(string(b), `DROP`))
+
+		return expected
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, expected, DisableInPostgreSQL(ctx, exec))
+}
@@ -0,0 +1,32 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package migration updates PostgresClusters and the objects they own that
+// were created by older versions of the operator. Migrations run once when
+// the operator starts, before any controller, and the level applied to each
+// cluster is recorded in an annotation.
+//
+// The controller finds existing objects by their labels, so migrations so far
+// only add labels that older operators did not set. The other differences do
+// not need one:
+//   - Instance StatefulSets keep the name they were created with. They are
+//     selected by the cluster and instance set labels, never by name.
+//   - User Secrets are rewritten on every reconcile with the current keys and
+//     the existing password. The controller reads the deprecated default user
+//     Secret when the current one is missing.
+//
+// Add a Migration when a change would leave existing objects unselected or
+// unreadable.
+package migration
@@ -0,0 +1,98 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// labelDeprecatedUserSecret adds a LabelRole to the deprecated default
+// PostgreSQL user secret so it can be selected like every other user secret.
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={get,patch}
+func labelDeprecatedUserSecret(
+	ctx context.Context, c client.Client, cluster *v1beta1.PostgresCluster,
+) error {
+	secret := &corev1.Secret{ObjectMeta: naming.DeprecatedPostgresUserSecret(cluster)}
+	err := c.Get(ctx, client.ObjectKeyFromObject(secret), secret)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Leave alone any secret that was not written by the operator.
+	labels := secret.GetLabels()
+	if labels[naming.LabelCluster] != cluster.Name ||
+		labels[naming.LabelPostgresUser] == "" || labels[naming.LabelRole] != "" {
+		return nil
+	}
+
+	before := secret.DeepCopy()
+	labels[naming.LabelRole] = naming.RolePostgresUser
+	return errors.WithStack(c.Patch(ctx, secret, client.MergeFrom(before)))
+}
+
+// labelDataVolumes adds a LabelData to PostgreSQL and pgBackRest volumes of
+// cluster that were created before that label existed.
+// +kubebuilder:rbac:groups="",resources="persistentvolumeclaims",verbs={list,patch}
+func labelDataVolumes(
+	ctx context.Context, c client.Client, cluster *v1beta1.PostgresCluster,
+) error {
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := c.List(ctx, pvcs,
+		client.InNamespace(cluster.Namespace),
+		client.MatchingLabels{naming.LabelCluster: cluster.Name},
+	); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		labels := pvc.GetLabels()
+
+		if _, ok := labels[naming.LabelData]; ok {
+			continue
+		}
+
+		var data string
+		switch labels[naming.LabelRole] {
+		case naming.RolePostgresData, naming.RolePostgresWAL:
+			data = naming.DataPostgres
+		}
+		if _, ok := labels[naming.LabelPGBackRestRepoVolume]; ok {
+			data = naming.DataPGBackRest
+		}
+		if data == "" {
+			continue
+		}
+
+		before := pvc.DeepCopy()
+		labels[naming.LabelData] = data
+		if err := c.Patch(ctx, pvc, client.MergeFrom(before)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
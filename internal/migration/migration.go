@@ -0,0 +1,138 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// Migration changes a PostgresCluster, or the objects it owns, from the form
+// written by an older operator to the form expected by this one.
+//
+// Clusters created by this operator are not annotated until the next time the
+// operator starts, so every Migration must be safe to apply more than once.
+type Migration struct {
+	// Level orders migrations. It must be greater than the Level of every
+	// migration before it and never change once released.
+	Level int
+
+	// Description explains the migration in log messages.
+	Description string
+
+	// Migrate applies the migration to cluster using c.
+	Migrate func(ctx context.Context, c client.Client, cluster *v1beta1.PostgresCluster) error
+}
+
+// Migrations is the ordered list of migrations that Run applies.
+var Migrations = []Migration{
+	{
+		Level:       1,
+		Description: "label the deprecated PostgreSQL user secret",
+		Migrate:     labelDeprecatedUserSecret,
+	},
+	{
+		Level:       2,
+		Description: "label PostgreSQL and pgBackRest volumes with their data",
+		Migrate:     labelDataVolumes,
+	},
+}
+
+// Level returns the migration level recorded on cluster. A cluster without the
+// annotation is at level zero.
+func Level(cluster *v1beta1.PostgresCluster) (int, error) {
+	value, ok := cluster.GetAnnotations()[naming.MigrationLevel]
+	if !ok {
+		return 0, nil
+	}
+	level, err := strconv.Atoi(value)
+	return level, errors.Wrapf(err, "invalid %s annotation", naming.MigrationLevel)
+}
+
+// Run applies pending migrations to every PostgresCluster of operatorClass in
+// namespace, or in all namespaces when namespace is empty. The level of each migration is
+// recorded on the cluster as soon as it succeeds. A cluster that fails to
+// migrate is logged and skipped so that it does not hold back the others.
+//
+// Run reads and writes directly, so c should not be backed by a cache that
+// has yet to start.
+// +kubebuilder:rbac:groups="postgres-operator.crunchydata.com",resources="postgresclusters",verbs={list,patch}
+func Run(ctx context.Context, c client.Client, namespace, operatorClass string) error {
+	clusters := &v1beta1.PostgresClusterList{}
+	if err := c.List(ctx, clusters, client.InNamespace(namespace)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for i := range clusters.Items {
+		cluster := &clusters.Items[i]
+		if cluster.Spec.OperatorClass != operatorClass {
+			continue
+		}
+		log := logging.FromContext(ctx).WithValues(
+			"namespace", cluster.Namespace, "name", cluster.Name)
+
+		if err := migrate(logging.NewContext(ctx, log), c, cluster, Migrations); err != nil {
+			log.Error(err, "unable to migrate PostgresCluster")
+		}
+	}
+	return nil
+}
+
+// migrate applies each migration in migrations that is above the level of
+// cluster, recording the level after each one.
+func migrate(
+	ctx context.Context, c client.Client,
+	cluster *v1beta1.PostgresCluster, migrations []Migration,
+) error {
+	log := logging.FromContext(ctx)
+
+	current, err := Level(cluster)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.Level <= current {
+			continue
+		}
+
+		log.Info("migrating PostgresCluster", "level", m.Level, "migration", m.Description)
+		if err := m.Migrate(ctx, c, cluster); err != nil {
+			return errors.Wrapf(err, "migration %d", m.Level)
+		}
+
+		before := cluster.DeepCopy()
+		annotations := cluster.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[naming.MigrationLevel] = strconv.Itoa(m.Level)
+		cluster.SetAnnotations(annotations)
+
+		if err := c.Patch(ctx, cluster, client.MergeFrom(before)); err != nil {
+			return errors.WithStack(err)
+		}
+		current = m.Level
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/controller/runtime"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func newClient(t *testing.T, objects ...client.Object) client.Client {
+	scheme, err := runtime.CreatePostgresOperatorScheme()
+	assert.NilError(t, err)
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+func TestLevel(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+
+	level, err := Level(cluster)
+	assert.NilError(t, err)
+	assert.Equal(t, level, 0)
+
+	cluster.Annotations = map[string]string{naming.MigrationLevel: "5"}
+	level, err = Level(cluster)
+	assert.NilError(t, err)
+	assert.Equal(t, level, 5)
+
+	cluster.Annotations[naming.MigrationLevel] = "five"
+	_, err = Level(cluster)
+	assert.ErrorContains(t, err, "migration-level")
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	cluster.Annotations = map[string]string{naming.MigrationLevel: "1"}
+
+	var applied []int
+	record := func(level int, err error) Migration {
+		return Migration{Level: level, Migrate: func(
+			context.Context, client.Client, *v1beta1.PostgresCluster,
+		) error {
+			applied = append(applied, level)
+			return err
+		}}
+	}
+
+	t.Run("Pending", func(t *testing.T) {
+		c := newClient(t, cluster.DeepCopy())
+		applied = nil
+
+		current := cluster.DeepCopy()
+		assert.NilError(t, migrate(ctx, c, current,
+			[]Migration{record(1, nil), record(2, nil), record(3, nil)}))
+		assert.DeepEqual(t, applied, []int{2, 3})
+
+		stored := &v1beta1.PostgresCluster{}
+		assert.NilError(t, c.Get(ctx, client.ObjectKeyFromObject(cluster), stored))
+		assert.Equal(t, stored.Annotations[naming.MigrationLevel], "3")
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		c := newClient(t, cluster.DeepCopy())
+		applied = nil
+
+		current := cluster.DeepCopy()
+		err := migrate(ctx, c, current,
+			[]Migration{record(2, nil), record(3, errors.New("boom")), record(4, nil)})
+		assert.ErrorContains(t, err, "migration 3")
+		assert.DeepEqual(t, applied, []int{2, 3})
+
+		// The level of the last successful migration is kept.
+		stored := &v1beta1.PostgresCluster{}
+		assert.NilError(t, c.Get(ctx, client.ObjectKeyFromObject(cluster), stored))
+		assert.Equal(t, stored.Annotations[naming.MigrationLevel], "2")
+	})
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+
+	secret := &corev1.Secret{ObjectMeta: naming.DeprecatedPostgresUserSecret(cluster)}
+	secret.Labels = map[string]string{
+		naming.LabelCluster:      "hippo",
+		naming.LabelPostgresUser: "hippo",
+	}
+
+	pgdata := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "ns1", Name: "hippo-00-abcd-pgdata",
+		Labels: map[string]string{
+			naming.LabelCluster: "hippo",
+			naming.LabelRole:    naming.RolePostgresData,
+		},
+	}}
+	repo := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "ns1", Name: "hippo-repo1",
+		Labels: map[string]string{
+			naming.LabelCluster:              "hippo",
+			naming.LabelPGBackRestRepoVolume: "",
+		},
+	}}
+	other := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "ns1", Name: "something-else",
+		Labels: map[string]string{naming.LabelCluster: "hippo"},
+	}}
+
+	c := newClient(t, cluster, secret, pgdata, repo, other)
+	assert.NilError(t, Run(ctx, c, "", ""))
+
+	stored := &v1beta1.PostgresCluster{}
+	assert.NilError(t, c.Get(ctx, client.ObjectKeyFromObject(cluster), stored))
+	assert.Equal(t, stored.Annotations[naming.MigrationLevel], "2")
+
+	assert.NilError(t, c.Get(ctx, client.ObjectKeyFromObject(secret), secret))
+	assert.Equal(t, secret.Labels[naming.LabelRole], naming.RolePostgresUser)
+
+	assert.NilError(t, c.Get(ctx, client.ObjectKeyFromObject(pgdata), pgdata))
+	assert.Equal(t, pgdata.Labels[naming.LabelData], naming.DataPostgres)
+
+	assert.NilError(t, c.Get(ctx, client.ObjectKeyFromObject(repo), repo))
+	assert.Equal(t, repo.Labels[naming.LabelData], naming.DataPGBackRest)
+
+	assert.NilError(t, c.Get(ctx, client.ObjectKeyFromObject(other), other))
+	_, ok := other.Labels[naming.LabelData]
+	assert.Assert(t, !ok)
+
+	// Running again changes nothing.
+	assert.NilError(t, Run(ctx, c, "ns1", ""))
+	assert.NilError(t, c.Get(ctx, client.ObjectKeyFromObject(cluster), stored))
+	assert.Equal(t, stored.Annotations[naming.MigrationLevel], "2")
+
+	t.Run("OperatorClass", func(t *testing.T) {
+		staging := &v1beta1.PostgresCluster{}
+		staging.Namespace, staging.Name = "ns1", "rhino"
+		staging.Spec.OperatorClass = "staging"
+
+		c := newClient(t, staging)
+		stored := &v1beta1.PostgresCluster{}
+		assert.NilError(t, Run(ctx, c, "", ""))
+		assert.NilError(t, c.Get(ctx, client.ObjectKeyFromObject(staging), stored))
+		assert.Equal(t, stored.Annotations[naming.MigrationLevel], "",
+			"expected clusters of other classes to be skipped")
+
+		assert.NilError(t, Run(ctx, c, "", "staging"))
+		assert.NilError(t, c.Get(ctx, client.ObjectKeyFromObject(staging), stored))
+		assert.Equal(t, stored.Annotations[naming.MigrationLevel], "2")
+	})
+}
@@ -18,9 +18,37 @@ package naming
 const (
 	annotationPrefix = labelPrefix
 
+	// AdoptPatroni is the annotation that confirms a StatefulSet of a Patroni cluster may be
+	// adopted by a PostgresCluster. The value is the name of that PostgresCluster.
+	AdoptPatroni = annotationPrefix + "adopt"
+
+	// DebugReconcile is an annotation that, when set to "true" on a PostgresCluster, sends
+	// every log entry of its reconciles, at any verbosity, to the debug log stream as well.
+	DebugReconcile = annotationPrefix + "debug-reconcile"
+
+	// Export is the annotation that is added to a PostgresCluster to export it. The value is a
+	// unique identifier (e.g. a timestamp) that is stored in the PostgresCluster status to track
+	// the export. A full backup is taken, and the objects of the cluster are copied into a
+	// ConfigMap without their owner references.
+	Export = annotationPrefix + "export"
+
 	// Finalizer marks an object to be garbage collected by this module.
 	Finalizer = annotationPrefix + "finalizer"
 
+	// ForceDelete is an annotation that, when set to "true" on a PostgresCluster being deleted,
+	// skips the rest of its teardown and removes its Finalizer. This is for when something
+	// teardown depends on is gone for good, e.g. the bucket of a cloud backup repository.
+	ForceDelete = annotationPrefix + "force-delete"
+
+	// MigrationLevel is the annotation that records the highest data migration
+	// applied to a PostgresCluster and the objects it owns. Clusters without it
+	// were created by an operator older than the migration framework.
+	MigrationLevel = annotationPrefix + "migration-level"
+
+	// Notifications is an annotation that, when set to "false" on a PostgresCluster, stops the
+	// operator from sending notifications of its events to the configured webhook.
+	Notifications = annotationPrefix + "notifications"
+
 	// PGBackRestBackup is the annotation that is added to a PostgresCluster to initiate a manual
 	// backup.  The value of the annotation will be a unique identifier for a backup Job (e.g. a
 	// timestamp), which will be stored in the PostgresCluster status to properly track completion
@@ -28,6 +56,10 @@ const (
 	// ID associated with a specific manual backup Job.
 	PGBackRestBackup = annotationPrefix + "pgbackrest-backup"
 
+	// PGBackRestVerifyBackup is the annotation on a Job that verifies a
+	// pgBackRest backup. The value is the label of that backup.
+	PGBackRestVerifyBackup = annotationPrefix + "pgbackrest-verify-backup"
+
 	// PGBackRestConfigHash is an annotation used to specify the hash value associated with a
 	// repo configuration as needed to detect configuration changes that invalidate running Jobs
 	// (and therefore must be recreated)
@@ -46,4 +78,32 @@ const (
 	// timestamp), which will be stored in the PostgresCluster status to properly track completion
 	// of the Job.
 	PGBackRestRestore = annotationPrefix + "pgbackrest-restore"
+
+	// PGBackRestCredentialsRevision is the annotation that is added to a Pod
+	// to record the revision of pgBackRest credentials it should have mounted.
+	// Changing it prompts the kubelet to refresh the configuration files in
+	// the Pod.
+	PGBackRestCredentialsRevision = annotationPrefix + "pgbackrest-credentials-revision"
+
+	// PGBouncerConfigRevision is the annotation that is added to a PgBouncer
+	// Pod to record the revision of configuration it should reload. Changing
+	// it prompts the kubelet to refresh the configuration files in the Pod.
+	PGBouncerConfigRevision = annotationPrefix + "pgbouncer-config-revision"
+
+	// Repair is the annotation that is added to a PostgresCluster to start the
+	// repair in spec.repair. The value is a unique identifier (e.g. a timestamp)
+	// that is stored in the PostgresCluster status to track the repair.
+	Repair = annotationPrefix + "repair"
+
+	// Retain is the annotation that is added to a user Secret or pgBackRest
+	// repository volume with a value of "true" to keep it when its
+	// PostgresCluster is deleted. PGO removes its owner reference and does
+	// not delete it.
+	Retain = annotationPrefix + "retain"
+
+	// PatroniSwitchover is the annotation that is added to a PostgresCluster to initiate a
+	// switchover when spec.patroni.switchover is enabled. The value is a unique identifier
+	// (e.g. a timestamp) that is stored in the PostgresCluster status when the switchover
+	// completes.
+	PatroniSwitchover = annotationPrefix + "trigger-switchover"
 )
@@ -23,9 +23,12 @@ import (
 )
 
 func TestAnnotationsValid(t *testing.T) {
+	assert.Assert(t, nil == validation.IsQualifiedName(AdoptPatroni))
+	assert.Assert(t, nil == validation.IsQualifiedName(Export))
 	assert.Assert(t, nil == validation.IsQualifiedName(Finalizer))
 	assert.Assert(t, nil == validation.IsQualifiedName(PGBackRestBackup))
 	assert.Assert(t, nil == validation.IsQualifiedName(PGBackRestConfigHash))
 	assert.Assert(t, nil == validation.IsQualifiedName(PGBackRestCurrentConfig))
 	assert.Assert(t, nil == validation.IsQualifiedName(PGBackRestRestore))
+	assert.Assert(t, nil == validation.IsQualifiedName(PatroniSwitchover))
 }
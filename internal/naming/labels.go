@@ -39,6 +39,10 @@ const (
 	// LabelData is used to identify Pods and Volumes store Postgres data.
 	LabelData = labelPrefix + "data"
 
+	// LabelMaintenanceJob identifies the scheduled SQL maintenance job an
+	// object is for.
+	LabelMaintenanceJob = labelPrefix + "maintenance-job"
+
 	// LabelMoveJob is used to identify a directory move Job.
 	LabelMoveJob = labelPrefix + "move-job"
 
@@ -74,6 +78,18 @@ const (
 
 	LabelPGBackRestCronJob = labelPrefix + "pgbackrest-cronjob"
 
+	// LabelPGBackRestRelocation identifies a pgBackRest repository volume that
+	// is being filled to replace the current one, and the Job that fills it.
+	LabelPGBackRestRelocation = labelPrefix + "pgbackrest-relocation"
+
+	// LabelPGBackRestVerify identifies the Job that verifies the newest full
+	// backup of a pgBackRest repository. The value is the name of the repository.
+	LabelPGBackRestVerify = labelPrefix + "pgbackrest-verify"
+
+	// LabelPGBackRestStandby is used to indicate that a resource is for the standby pgBackRest
+	// repository host. The value on a volume is the name of the repository it copies.
+	LabelPGBackRestStandby = labelPrefix + "pgbackrest-standby"
+
 	// LabelPGBackRestRestore is used to indicate that a Job or Pod is for a pgBackRest restore
 	LabelPGBackRestRestore = labelPrefix + "pgbackrest-restore"
 
@@ -81,6 +97,9 @@ const (
 	// resource (e.g. a ConfigMap or Secret) is for a pgBackRest restore
 	LabelPGBackRestRestoreConfig = labelPrefix + "pgbackrest-restore-config"
 
+	// LabelPGBouncerPool identifies the Service of an additional PgBouncer pool.
+	LabelPGBouncerPool = labelPrefix + "pgbouncer-pool"
+
 	// LabelPGMonitorDiscovery is the label added to Pods running the "exporter" container to
 	// support discovery by Prometheus according to pgMonitor configuration
 	LabelPGMonitorDiscovery = labelPrefix + "crunchy-postgres-exporter"
@@ -91,6 +110,9 @@ const (
 	// LabelStartupInstance is used to indicate the startup instance associated with a resource
 	LabelStartupInstance = labelPrefix + "startup-instance"
 
+	// LabelWorkload is the Patroni "workload" tag of the instance set a Pod belongs to.
+	LabelWorkload = labelPrefix + "workload"
+
 	RolePrimary = "primary"
 	RoleReplica = "replica"
 
@@ -116,6 +138,25 @@ const (
 
 	// RoleMonitoring is the LabelRole applied to Monitoring resources
 	RoleMonitoring = "monitoring"
+
+	// RoleMaintenance is the LabelRole applied to scheduled SQL maintenance
+	// resources.
+	RoleMaintenance = "maintenance"
+
+	// RoleAppliedSQL is the LabelRole applied to the ConfigMap that records
+	// SQL executed in PostgreSQL.
+	RoleAppliedSQL = "sql"
+
+	// RoleAudit is the LabelRole applied to the ConfigMap that records the
+	// changes the operator makes to the objects of a cluster.
+	RoleAudit = "audit"
+
+	// RoleRepair is the LabelRole applied to the Job that repairs an instance.
+	RoleRepair = "repair"
+
+	// RoleBlueGreen is the LabelRole applied to the Secret that green uses to
+	// connect to blue in a blue/green switch.
+	RoleBlueGreen = "bluegreen"
 )
 
 const (
@@ -126,6 +167,10 @@ const (
 	DataPGBackRest = "pgbackrest"
 )
 
+// WorkloadAnalytics is the LabelWorkload value of instances that serve
+// analytics queries.
+const WorkloadAnalytics = "analytics"
+
 // BackupJobType represents different types of backups (e.g. ad-hoc backups, scheduled backups,
 // the backup for pgBackRest replica creation, etc.)
 type BackupJobType string
@@ -137,6 +182,12 @@ const (
 	// BackupReplicaCreate is the backup type for the backup taken to enable pgBackRest replica
 	// creation
 	BackupReplicaCreate BackupJobType = "replica-create"
+
+	// BackupFinal is the backup type for the backup taken while a PostgresCluster is being deleted
+	BackupFinal BackupJobType = "final"
+
+	// BackupExport is the backup type for the backup taken when a PostgresCluster is exported
+	BackupExport BackupJobType = "export"
 )
 
 // Merge takes sets of labels and merges them. The last set
@@ -259,6 +310,21 @@ func PGBackRestDedicatedLabels(clusterName string) labels.Set {
 	return labels.Merge(commonLabels, operatorConfigLabels)
 }
 
+// PGBackRestStandbyLabels provides labels for a standby pgBackRest repository host
+func PGBackRestStandbyLabels(clusterName string) labels.Set {
+	return labels.Merge(PGBackRestLabels(clusterName), map[string]string{
+		LabelPGBackRestStandby: "",
+	})
+}
+
+// PGBackRestStandbyVolumeLabels provides labels for the volume of a standby pgBackRest
+// repository host that holds a copy of a repository
+func PGBackRestStandbyVolumeLabels(clusterName, repoName string) labels.Set {
+	return labels.Merge(PGBackRestLabels(clusterName), map[string]string{
+		LabelPGBackRestStandby: repoName,
+	})
+}
+
 // PGBackRestDedicatedSelector provides a selector for querying pgBackRest dedicated
 // repository host resources
 func PGBackRestDedicatedSelector(clusterName string) labels.Selector {
@@ -38,13 +38,18 @@ func TestLabelsValid(t *testing.T) {
 	assert.Assert(t, nil == validation.IsQualifiedName(LabelPGBackRestBackup))
 	assert.Assert(t, nil == validation.IsQualifiedName(LabelPGBackRestConfig))
 	assert.Assert(t, nil == validation.IsQualifiedName(LabelPGBackRestDedicated))
+	assert.Assert(t, nil == validation.IsQualifiedName(LabelPGBackRestRelocation))
 	assert.Assert(t, nil == validation.IsQualifiedName(LabelPGBackRestRepo))
 	assert.Assert(t, nil == validation.IsQualifiedName(LabelPGBackRestRepoVolume))
 	assert.Assert(t, nil == validation.IsQualifiedName(LabelPGBackRestRestore))
 	assert.Assert(t, nil == validation.IsQualifiedName(LabelPGBackRestRestoreConfig))
+	assert.Assert(t, nil == validation.IsQualifiedName(LabelPGBackRestStandby))
+	assert.Assert(t, nil == validation.IsQualifiedName(LabelPGBackRestVerify))
+	assert.Assert(t, nil == validation.IsQualifiedName(LabelPGBouncerPool))
 	assert.Assert(t, nil == validation.IsQualifiedName(LabelPGMonitorDiscovery))
 	assert.Assert(t, nil == validation.IsQualifiedName(LabelPostgresUser))
 	assert.Assert(t, nil == validation.IsQualifiedName(LabelStartupInstance))
+	assert.Assert(t, nil == validation.IsQualifiedName(LabelWorkload))
 }
 
 func TestLabelValuesValid(t *testing.T) {
@@ -58,6 +63,7 @@ func TestLabelValuesValid(t *testing.T) {
 	assert.Assert(t, nil == validation.IsValidLabelValue(RoleReplica))
 	assert.Assert(t, nil == validation.IsValidLabelValue(string(BackupReplicaCreate)))
 	assert.Assert(t, nil == validation.IsValidLabelValue(RoleMonitoring))
+	assert.Assert(t, nil == validation.IsValidLabelValue(WorkloadAnalytics))
 }
 
 func TestMerge(t *testing.T) {
@@ -197,6 +203,17 @@ func TestPGBackRestLabelFuncs(t *testing.T) {
 	pgBackRestDedicatedSelector := PGBackRestDedicatedSelector(clusterName)
 	assert.Check(t, pgBackRestDedicatedSelector.Matches(pgBackRestDedicatedLabels))
 
+	// verify the labels that identify standby pgBackRest repository host resources
+	pgBackRestStandbyLabels := PGBackRestStandbyLabels(clusterName)
+	assert.Equal(t, pgBackRestStandbyLabels.Get(LabelCluster), clusterName)
+	assert.Check(t, pgBackRestStandbyLabels.Has(LabelPGBackRest))
+	assert.Check(t, pgBackRestStandbyLabels.Has(LabelPGBackRestStandby))
+	assert.Check(t, !pgBackRestDedicatedSelector.Matches(pgBackRestStandbyLabels))
+
+	pgBackRestStandbyVolumeLabels := PGBackRestStandbyVolumeLabels(clusterName, repoName)
+	assert.Equal(t, pgBackRestStandbyVolumeLabels.Get(LabelPGBackRestStandby), repoName)
+	assert.Check(t, !pgBackRestStandbyVolumeLabels.Has(LabelPGBackRestRepo))
+
 	// verify the labels that identify pgBackRest repository volume resources
 	pgBackRestRepoVolumeLabels := PGBackRestRepoVolumeLabels(clusterName, repoName)
 	assert.Equal(t, pgBackRestRepoVolumeLabels.Get(LabelCluster), clusterName)
@@ -52,6 +52,15 @@ const (
 	// ContainerPGMonitorExporter is the name of a container running postgres_exporter
 	ContainerPGMonitorExporter = "exporter"
 
+	// ContainerLogShipper is the name of a container running Fluent Bit
+	ContainerLogShipper = "log-shipper"
+
+	// ContainerMaintenance is the name of a container running scheduled SQL maintenance
+	ContainerMaintenance = "maintenance"
+
+	// ContainerRepair is the name of a container repairing the data directory of an instance
+	ContainerRepair = "repair"
+
 	// ContainerJobMovePGDataDir is the name of the job container utilized to copy v4 Operator
 	// pgData directories to the v5 default location
 	ContainerJobMovePGDataDir = "pgdata-move-job"
@@ -61,6 +70,12 @@ const (
 	// ContainerJobMovePGBackRestRepoDir is the name of the job container utilized to copy v4
 	// Operator pgBackRest repo directories to the v5 default location
 	ContainerJobMovePGBackRestRepoDir = "repo-move-job"
+	// ContainerPGBackRestStandbySync is the name of the container that copies
+	// pgBackRest repositories to a standby repository host
+	ContainerPGBackRestStandbySync = "repo-sync"
+	// ContainerJobRelocatePGBackRestRepo is the name of the job container
+	// utilized to copy a pgBackRest repository to a new volume
+	ContainerJobRelocatePGBackRestRepo = "repo-relocate-job"
 )
 
 const (
@@ -138,21 +153,6 @@ const (
 
 	// PGBackRestSSHVolume is the name the SSH volume used when configuring SSH in a pgBackRest Pod
 	PGBackRestSSHVolume = "ssh"
-
-	// suffix used with postgrescluster name for associated configmap.
-	// for instance, if the cluster is named 'mycluster', the
-	// configmap will be named 'mycluster-pgbackrest-config'
-	cmNameSuffix = "%s-pgbackrest-config"
-
-	// suffix used with postgrescluster name for associated configmap.
-	// for instance, if the cluster is named 'mycluster', the
-	// configmap will be named 'mycluster-ssh-config'
-	sshCMNameSuffix = "%s-ssh-config"
-
-	// suffix used with postgrescluster name for associated secret.
-	// for instance, if the cluster is named 'mycluster', the
-	// secret will be named 'mycluster-ssh'
-	sshSecretNameSuffix = "%s-ssh"
 )
 
 // AsObjectKey converts the ObjectMeta API type to a client.ObjectKey.
@@ -166,7 +166,44 @@ func AsObjectKey(m metav1.ObjectMeta) client.ObjectKey {
 func ClusterConfigMap(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.Namespace,
-		Name:      cluster.Name + "-config",
+		Name:      clusterObjectName(cluster, "config", maxNameLength),
+	}
+}
+
+// ClusterAudit returns the ObjectMeta of the ConfigMap that records the
+// changes the operator makes to the objects of cluster.
+func ClusterAudit(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      clusterObjectName(cluster, "audit", maxNameLength),
+	}
+}
+
+// ClusterAuditArchive returns the ObjectMeta of a ConfigMap that keeps audit
+// records of cluster that no longer fit in the one of ClusterAudit. The id
+// distinguishes one such ConfigMap from another.
+func ClusterAuditArchive(cluster *v1beta1.PostgresCluster, id string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      clusterObjectName(cluster, "audit-"+id, maxNameLength),
+	}
+}
+
+// ClusterAppliedSQL returns the ObjectMeta necessary to lookup the ConfigMap
+// that records the checksums of SQL executed in cluster's PostgreSQL.
+func ClusterAppliedSQL(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      clusterObjectName(cluster, "sql", maxNameLength),
+	}
+}
+
+// ClusterExport returns the ObjectMeta of the ConfigMap that holds an export
+// of cluster. See naming.Export.
+func ClusterExport(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      clusterObjectName(cluster, "export", maxNameLength),
 	}
 }
 
@@ -175,7 +212,7 @@ func ClusterConfigMap(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 func ClusterInstanceRBAC(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.Namespace,
-		Name:      cluster.Name + "-instance",
+		Name:      clusterObjectName(cluster, "instance", maxNameLength),
 	}
 }
 
@@ -184,7 +221,16 @@ func ClusterInstanceRBAC(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 func ClusterPGBouncer(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.Namespace,
-		Name:      cluster.Name + "-pgbouncer",
+		Name:      clusterObjectName(cluster, "pgbouncer", maxLabelNameLength),
+	}
+}
+
+// ClusterPGBouncerPool returns the ObjectMeta necessary to lookup the Service
+// that exposes pool of cluster's PgBouncer proxy.
+func ClusterPGBouncerPool(cluster *v1beta1.PostgresCluster, pool string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      clusterObjectName(cluster, "pgbouncer-"+pool, maxLabelNameLength),
 	}
 }
 
@@ -197,7 +243,7 @@ func ClusterPodService(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	// likely to resolve.
 	return metav1.ObjectMeta{
 		Namespace: cluster.Namespace,
-		Name:      cluster.Name + "-pods",
+		Name:      clusterObjectName(cluster, "pods", maxLabelNameLength),
 	}
 }
 
@@ -206,7 +252,7 @@ func ClusterPodService(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 func ClusterPrimaryService(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.Namespace,
-		Name:      cluster.Name + "-primary",
+		Name:      clusterObjectName(cluster, "primary", maxLabelNameLength),
 	}
 }
 
@@ -215,7 +261,27 @@ func ClusterPrimaryService(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 func ClusterReplicaService(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.Namespace,
-		Name:      cluster.Name + "-replicas",
+		Name:      clusterObjectName(cluster, "replicas", maxLabelNameLength),
+	}
+}
+
+// ClusterAnalyticsService returns the ObjectMeta necessary to lookup the
+// Service that exposes PostgreSQL replicas of the "analytics" workload.
+func ClusterAnalyticsService(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      clusterObjectName(cluster, "analytics", maxLabelNameLength),
+	}
+}
+
+// InstanceSetService returns the ObjectMeta necessary to lookup the headless
+// Service that resolves to the ready members of set.
+func InstanceSetService(
+	cluster *v1beta1.PostgresCluster, set *v1beta1.PostgresInstanceSetSpec,
+) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      clusterObjectName(cluster, set.Name+"-members", maxLabelNameLength),
 	}
 }
 
@@ -225,7 +291,7 @@ func GenerateInstance(
 ) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.Namespace,
-		Name:      cluster.Name + "-" + set.Name + "-" + rand.String(4),
+		Name:      instanceName(cluster, set, rand.String(4)),
 	}
 }
 
@@ -243,10 +309,19 @@ func GenerateStartupInstance(
 
 	return metav1.ObjectMeta{
 		Namespace: cluster.Namespace,
-		Name:      cluster.Name + "-" + set.Name + "-" + suffix,
+		Name:      instanceName(cluster, set, suffix),
 	}
 }
 
+// instanceName returns the name of an instance of cluster and set that ends
+// with suffix. Instances are StatefulSets, so the name is shortened to fit.
+func instanceName(
+	cluster *v1beta1.PostgresCluster, set *v1beta1.PostgresInstanceSetSpec, suffix string,
+) string {
+	return clusterObjectName(cluster, set.Name,
+		maxControllerNameLength-len(suffix)-1) + "-" + suffix
+}
+
 // InstanceConfigMap returns the ObjectMeta necessary to lookup
 // instance's shared ConfigMap.
 func InstanceConfigMap(instance metav1.Object) metav1.ObjectMeta {
@@ -288,7 +363,53 @@ func InstancePostgresWALVolume(instance *appsv1.StatefulSet) metav1.ObjectMeta {
 func MonitoringUserSecret(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.Namespace,
-		Name:      cluster.Name + "-monitoring",
+		Name:      clusterObjectName(cluster, "monitoring", maxNameLength),
+	}
+}
+
+// MaintenanceUserSecret returns the ObjectMeta necessary to lookup the Secret
+// containing the password of the user that runs scheduled SQL maintenance.
+func MaintenanceUserSecret(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      clusterObjectName(cluster, "maintenance", maxNameLength),
+	}
+}
+
+// MaintenanceConfigMap returns the ObjectMeta necessary to lookup the
+// ConfigMap containing the SQL of scheduled maintenance jobs.
+func MaintenanceConfigMap(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      clusterObjectName(cluster, "maintenance", maxNameLength),
+	}
+}
+
+// MaintenanceCronJob returns the ObjectMeta for the CronJob of the scheduled
+// SQL maintenance job named jobName.
+func MaintenanceCronJob(cluster *v1beta1.PostgresCluster, jobName string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      clusterObjectName(cluster, "maintenance-"+jobName, maxControllerNameLength),
+	}
+}
+
+// BlueGreenSecret returns the ObjectMeta necessary to lookup the Secret
+// containing the password that cluster uses to replicate from the cluster it
+// replaces.
+func BlueGreenSecret(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      clusterObjectName(cluster, "bluegreen", maxNameLength),
+	}
+}
+
+// RepairJob returns the ObjectMeta for the Job that repairs an instance of
+// cluster.
+func RepairJob(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      clusterObjectName(cluster, "repair", maxLabelNameLength),
 	}
 }
 
@@ -297,7 +418,7 @@ func MonitoringUserSecret(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 func ReplicationClientCertSecret(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.Namespace,
-		Name:      cluster.Name + "-replication-cert",
+		Name:      clusterObjectName(cluster, "replication-cert", maxNameLength),
 	}
 }
 
@@ -311,6 +432,15 @@ func PatroniDistributedConfiguration(cluster *v1beta1.PostgresCluster) metav1.Ob
 	}
 }
 
+// PatroniAuthentication returns the ObjectMeta necessary to lookup the Secret
+// containing credentials for the Patroni REST API of cluster.
+func PatroniAuthentication(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      clusterObjectName(cluster, "patroni-auth", maxNameLength),
+	}
+}
+
 // PatroniLeaderConfigMap returns the ObjectMeta necessary to lookup the
 // ConfigMap created by Patroni for the leader election of cluster.
 // See Patroni DCS "leader_path".
@@ -331,9 +461,10 @@ func PatroniLeaderEndpoints(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta
 	}
 }
 
-// PatroniScope returns the "scope" Patroni uses for cluster.
+// PatroniScope returns the "scope" Patroni uses for cluster. It is also the
+// value of a label, so it is shortened when it would be too long for one.
 func PatroniScope(cluster *v1beta1.PostgresCluster) string {
-	return cluster.Name + "-ha"
+	return shorten(cluster.Name+"-ha", maxLabelNameLength)
 }
 
 // PatroniTrigger returns the ObjectMeta necessary to lookup the ConfigMap or
@@ -350,7 +481,7 @@ func PatroniTrigger(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 func PGBackRestConfig(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.GetNamespace(),
-		Name:      fmt.Sprintf(cmNameSuffix, cluster.GetName()),
+		Name:      clusterObjectName(cluster, "pgbackrest-config", maxNameLength),
 	}
 }
 
@@ -358,7 +489,7 @@ func PGBackRestConfig(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 // to create replicas using pgBackRest
 func PGBackRestBackupJob(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
-		Name:      cluster.GetName() + "-backup-" + rand.String(4),
+		Name:      clusterObjectName(cluster, "backup-"+rand.String(4), maxLabelNameLength),
 		Namespace: cluster.GetNamespace(),
 	}
 }
@@ -367,7 +498,36 @@ func PGBackRestBackupJob(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 func PGBackRestCronJob(cluster *v1beta1.PostgresCluster, backuptype, repoName string) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.GetNamespace(),
-		Name:      cluster.Name + "-pgbackrest-" + repoName + "-" + backuptype,
+		Name: clusterObjectName(cluster,
+			"pgbackrest-"+repoName+"-"+backuptype, maxControllerNameLength),
+	}
+}
+
+// PGBackRestRepoHost returns the ObjectMeta for a new pgBackRest repository
+// host StatefulSet
+func PGBackRestRepoHost(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.GetNamespace(),
+		Name:      clusterObjectName(cluster, "repo-host", maxControllerNameLength),
+	}
+}
+
+// PGBackRestStandbyRepoHost returns the ObjectMeta for the StatefulSet of a standby
+// pgBackRest repository host
+func PGBackRestStandbyRepoHost(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.GetNamespace(),
+		Name:      clusterObjectName(cluster, "repo-host-standby", maxControllerNameLength),
+	}
+}
+
+// PGBackRestStandbyRepoVolume returns the ObjectMeta for the volume of a standby
+// pgBackRest repository host that holds a copy of a repository
+func PGBackRestStandbyRepoVolume(cluster *v1beta1.PostgresCluster,
+	repoName string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      clusterObjectName(cluster, repoName+"-standby", maxNameLength),
+		Namespace: cluster.GetNamespace(),
 	}
 }
 
@@ -375,7 +535,16 @@ func PGBackRestCronJob(cluster *v1beta1.PostgresCluster, backuptype, repoName st
 func PGBackRestRestoreJob(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.GetNamespace(),
-		Name:      cluster.Name + "-pgbackrest-restore",
+		Name:      clusterObjectName(cluster, "pgbackrest-restore", maxLabelNameLength),
+	}
+}
+
+// PGBackRestVerifyJob returns the ObjectMeta for the Job that verifies the
+// newest full backup in the pgBackRest repository repoName.
+func PGBackRestVerifyJob(cluster *v1beta1.PostgresCluster, repoName string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      clusterObjectName(cluster, repoName+"-verify", maxLabelNameLength),
+		Namespace: cluster.GetNamespace(),
 	}
 }
 
@@ -384,7 +553,7 @@ func PGBackRestRestoreJob(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 func PGBackRestRBAC(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.Namespace,
-		Name:      cluster.Name + "-pgbackrest",
+		Name:      clusterObjectName(cluster, "pgbackrest", maxNameLength),
 	}
 }
 
@@ -392,7 +561,27 @@ func PGBackRestRBAC(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 func PGBackRestRepoVolume(cluster *v1beta1.PostgresCluster,
 	repoName string) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
-		Name:      fmt.Sprintf("%s-%s", cluster.GetName(), repoName),
+		Name:      clusterObjectName(cluster, repoName, maxNameLength),
+		Namespace: cluster.GetNamespace(),
+	}
+}
+
+// PGBackRestRepoVolumeRelocation returns the ObjectMeta for the volume that
+// replaces the default pgBackRest repository volume when it must be relocated.
+func PGBackRestRepoVolumeRelocation(cluster *v1beta1.PostgresCluster,
+	repoName string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      clusterObjectName(cluster, repoName+"-relocated", maxNameLength),
+		Namespace: cluster.GetNamespace(),
+	}
+}
+
+// PGBackRestRepoRelocationJob returns the ObjectMeta for the Job that copies a
+// pgBackRest repository to a new volume.
+func PGBackRestRepoRelocationJob(cluster *v1beta1.PostgresCluster,
+	repoName string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      clusterObjectName(cluster, repoName+"-relocate", maxLabelNameLength),
 		Namespace: cluster.GetNamespace(),
 	}
 }
@@ -400,7 +589,7 @@ func PGBackRestRepoVolume(cluster *v1beta1.PostgresCluster,
 // PGBackRestSSHConfig returns the ObjectMeta for a pgBackRest SSHD ConfigMap
 func PGBackRestSSHConfig(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
-		Name:      fmt.Sprintf(sshCMNameSuffix, cluster.GetName()),
+		Name:      clusterObjectName(cluster, "ssh-config", maxNameLength),
 		Namespace: cluster.GetNamespace(),
 	}
 }
@@ -408,7 +597,7 @@ func PGBackRestSSHConfig(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 // PGBackRestSSHSecret returns the ObjectMeta for a pgBackRest SSHD Secret
 func PGBackRestSSHSecret(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
-		Name:      fmt.Sprintf(sshSecretNameSuffix, cluster.GetName()),
+		Name:      clusterObjectName(cluster, "ssh", maxNameLength),
 		Namespace: cluster.GetNamespace(),
 	}
 }
@@ -428,7 +617,17 @@ func DeprecatedPostgresUserSecret(cluster *v1beta1.PostgresCluster) metav1.Objec
 func PostgresUserSecret(cluster *v1beta1.PostgresCluster, username string) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.Namespace,
-		Name:      cluster.Name + "-pguser-" + username,
+		Name:      userSecretName(cluster, username),
+	}
+}
+
+// PostgresUserCertificate returns the ObjectMeta necessary to lookup a
+// cert-manager Certificate, and the Secret it writes, containing a client
+// certificate for a PostgreSQL user.
+func PostgresUserCertificate(cluster *v1beta1.PostgresCluster, username string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      clusterObjectName(cluster, "pgcert-"+username, maxNameLength),
 	}
 }
 
@@ -437,7 +636,7 @@ func PostgresUserSecret(cluster *v1beta1.PostgresCluster, username string) metav
 func PostgresTLSSecret(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.Namespace,
-		Name:      cluster.Name + "-cluster-cert",
+		Name:      clusterObjectName(cluster, "cluster-cert", maxNameLength),
 	}
 }
 
@@ -445,7 +644,7 @@ func PostgresTLSSecret(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 func MovePGDataDirJob(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.GetNamespace(),
-		Name:      cluster.Name + "-move-pgdata-dir",
+		Name:      clusterObjectName(cluster, "move-pgdata-dir", maxLabelNameLength),
 	}
 }
 
@@ -453,7 +652,7 @@ func MovePGDataDirJob(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 func MovePGWALDirJob(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.GetNamespace(),
-		Name:      cluster.Name + "-move-pgwal-dir",
+		Name:      clusterObjectName(cluster, "move-pgwal-dir", maxLabelNameLength),
 	}
 }
 
@@ -461,6 +660,6 @@ func MovePGWALDirJob(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 func MovePGBackRestRepoDirJob(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: cluster.GetNamespace(),
-		Name:      cluster.Name + "-move-pgbackrest-repo-dir",
+		Name:      clusterObjectName(cluster, "move-pgbackrest-repo-dir", maxLabelNameLength),
 	}
 }
@@ -85,8 +85,13 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 
 	t.Run("ConfigMaps", func(t *testing.T) {
 		testUniqueAndValid(t, []test{
+			{"ClusterAppliedSQL", ClusterAppliedSQL(cluster)},
+			{"ClusterAudit", ClusterAudit(cluster)},
+			{"ClusterAuditArchive", ClusterAuditArchive(cluster, "0123abcd")},
 			{"ClusterConfigMap", ClusterConfigMap(cluster)},
+			{"ClusterExport", ClusterExport(cluster)},
 			{"ClusterPGBouncer", ClusterPGBouncer(cluster)},
+			{"MaintenanceConfigMap", MaintenanceConfigMap(cluster)},
 			{"PatroniDistributedConfiguration", PatroniDistributedConfiguration(cluster)},
 			{"PatroniLeaderConfigMap", PatroniLeaderConfigMap(cluster)},
 			{"PatroniTrigger", PatroniTrigger(cluster)},
@@ -101,6 +106,7 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 			{"PGBackRestCronJon", PGBackRestCronJob(cluster, "incr", "repo2")},
 			{"PGBackRestCronJon", PGBackRestCronJob(cluster, "diff", "repo3")},
 			{"PGBackRestCronJon", PGBackRestCronJob(cluster, "full", "repo4")},
+			{"MaintenanceCronJob", MaintenanceCronJob(cluster, "nightly")},
 		})
 	})
 
@@ -113,7 +119,10 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 	t.Run("Jobs", func(t *testing.T) {
 		testUniqueAndValid(t, []test{
 			{"PGBackRestBackupJob", PGBackRestBackupJob(cluster)},
+			{"PGBackRestRepoRelocationJob", PGBackRestRepoRelocationJob(cluster, repoName)},
 			{"PGBackRestRestoreJob", PGBackRestRestoreJob(cluster)},
+			{"PGBackRestVerifyJob", PGBackRestVerifyJob(cluster, repoName)},
+			{"RepairJob", RepairJob(cluster)},
 		})
 	})
 
@@ -135,10 +144,13 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 		names := testUniqueAndValid(t, []test{
 			{"ClusterPGBouncer", ClusterPGBouncer(cluster)},
 			{"DeprecatedPostgresUserSecret", DeprecatedPostgresUserSecret(cluster)},
+			{"PatroniAuthentication", PatroniAuthentication(cluster)},
 			{"PostgresTLSSecret", PostgresTLSSecret(cluster)},
 			{"ReplicationClientCertSecret", ReplicationClientCertSecret(cluster)},
 			{"PGBackRestSSHSecret", PGBackRestSSHSecret(cluster)},
 			{"MonitoringUserSecret", MonitoringUserSecret(cluster)},
+			{"MaintenanceUserSecret", MaintenanceUserSecret(cluster)},
+			{"BlueGreenSecret", BlueGreenSecret(cluster)},
 		})
 
 		t.Run("PostgresUserSecret", func(t *testing.T) {
@@ -152,6 +164,18 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 				assert.Assert(t, !strings.HasPrefix(name, prefix), "%q may collide", name)
 			}
 		})
+
+		t.Run("PostgresUserCertificate", func(t *testing.T) {
+			value := PostgresUserCertificate(cluster, "some-user")
+
+			assert.Equal(t, value.Namespace, cluster.Namespace)
+			assert.Assert(t, nil == validation.IsDNS1123Label(value.Name))
+
+			prefix := PostgresUserCertificate(cluster, "").Name
+			for _, name := range append(names.List(), PostgresUserSecret(cluster, "").Name) {
+				assert.Assert(t, !strings.HasPrefix(name, prefix), "%q may collide", name)
+			}
+		})
 	})
 
 	t.Run("ServiceAccounts", func(t *testing.T) {
@@ -164,9 +188,13 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 	t.Run("Services", func(t *testing.T) {
 		testUniqueAndValid(t, []test{
 			{"ClusterPGBouncer", ClusterPGBouncer(cluster)},
+			{"ClusterPGBouncerPool", ClusterPGBouncerPool(cluster, "session")},
 			{"ClusterPodService", ClusterPodService(cluster)},
 			{"ClusterPrimaryService", ClusterPrimaryService(cluster)},
+			{"ClusterAnalyticsService", ClusterAnalyticsService(cluster)},
 			{"ClusterReplicaService", ClusterReplicaService(cluster)},
+			{"InstanceSetService", InstanceSetService(cluster,
+				&v1beta1.PostgresInstanceSetSpec{Name: "some-set"})},
 			// Patroni can use Endpoints which relate directly to a Service.
 			{"PatroniDistributedConfiguration", PatroniDistributedConfiguration(cluster)},
 			{"PatroniLeaderEndpoints", PatroniLeaderEndpoints(cluster)},
@@ -177,10 +205,54 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 	t.Run("Volumes", func(t *testing.T) {
 		testUniqueAndValid(t, []test{
 			{"PGBackRestRepoVolume", PGBackRestRepoVolume(cluster, repoName)},
+			{"PGBackRestRepoVolumeRelocation", PGBackRestRepoVolumeRelocation(cluster, repoName)},
+			{"PGBackRestStandbyRepoVolume", PGBackRestStandbyRepoVolume(cluster, repoName)},
 		})
 	})
 }
 
+func TestLongNamesValid(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns1", Name: strings.Repeat("c", 63),
+		},
+	}
+	set := &v1beta1.PostgresInstanceSetSpec{Name: strings.Repeat("s", 40)}
+
+	for _, tt := range []struct {
+		name  string
+		value metav1.ObjectMeta
+		max   int
+	}{
+		{"ClusterPGBouncer", ClusterPGBouncer(cluster), 63},
+		{"ClusterPGBouncerPool", ClusterPGBouncerPool(cluster, strings.Repeat("p", 15)), 63},
+		{"ClusterPrimaryService", ClusterPrimaryService(cluster), 63},
+		{"InstanceSetService", InstanceSetService(cluster, set), 63},
+		{"GenerateInstance", GenerateInstance(cluster, set), 52},
+		{"GenerateStartupInstance", GenerateStartupInstance(cluster, set), 52},
+		{"MaintenanceCronJob", MaintenanceCronJob(cluster, "nightly"), 52},
+		{"PGBackRestCronJob", PGBackRestCronJob(cluster, "full", "repo1"), 52},
+		{"PGBackRestRepoHost", PGBackRestRepoHost(cluster), 52},
+		{"PGBackRestStandbyRepoHost", PGBackRestStandbyRepoHost(cluster), 52},
+		{"PGBackRestRepoRelocationJob", PGBackRestRepoRelocationJob(cluster, "repo1"), 63},
+		{"PGBackRestRestoreJob", PGBackRestRestoreJob(cluster), 63},
+		{"PGBackRestVerifyJob", PGBackRestVerifyJob(cluster, "repo1"), 63},
+		{"PostgresUserSecret", PostgresUserSecret(cluster, strings.Repeat("u", 63)), 253},
+		{"RepairJob", RepairJob(cluster), 63},
+	} {
+		assert.Assert(t, len(tt.value.Name) <= tt.max, "%v: %q", tt.name, tt.value.Name)
+		assert.Assert(t, nil == validation.IsDNS1123Subdomain(tt.value.Name), "%v", tt.name)
+	}
+
+	assert.Assert(t, len(PatroniScope(cluster)) <= 63)
+
+	// Instance names keep their random suffix.
+	assert.Assert(t, GenerateInstance(cluster, set).Name != GenerateInstance(cluster, set).Name)
+
+	// Names that fit are unchanged.
+	assert.Equal(t, PostgresUserSecret(cluster, "app").Name, cluster.Name+"-pguser-app")
+}
+
 func TestInstanceNamesUniqueAndValid(t *testing.T) {
 	instance := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
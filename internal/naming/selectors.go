@@ -110,7 +110,8 @@ func ClusterPostgresUsers(cluster string) metav1.LabelSelector {
 			LabelCluster: cluster,
 		},
 		MatchExpressions: []metav1.LabelSelectorRequirement{
-			// The now-deprecated default PostgreSQL user secret lacks a LabelRole.
+			// The now-deprecated default PostgreSQL user secret lacks a LabelRole
+			// until it is migrated.
 			// The existence of a LabelPostgresUser matches it and current secrets.
 			{Key: LabelPostgresUser, Operator: metav1.LabelSelectorOpExists},
 		},
@@ -0,0 +1,62 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package naming
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// maxNameLength is the longest name of most objects, such as Secrets and
+	// ConfigMaps.
+	// - https://docs.k8s.io/concepts/overview/working-with-objects/names/#dns-subdomain-names
+	maxNameLength = validation.DNS1123SubdomainMaxLength
+
+	// maxLabelNameLength is the longest name of objects whose names are also
+	// label values or hostnames, such as Services and Jobs.
+	// - https://docs.k8s.io/concepts/overview/working-with-objects/names/#dns-label-names
+	maxLabelNameLength = validation.DNS1123LabelMaxLength
+
+	// maxControllerNameLength is the longest name of StatefulSets and CronJobs.
+	// Kubernetes adds a suffix of up to 11 characters to these names in the
+	// labels of their Pods and the names of their Jobs.
+	maxControllerNameLength = maxLabelNameLength - 11
+
+	// hashLength is the number of characters that replace the end of a name
+	// that is too long.
+	hashLength = 8
+)
+
+// shorten returns name when it is max characters or fewer. Otherwise, it
+// returns a prefix of name followed by a hash of all of name. The result is
+// always the same for the same name, and it is always a valid name when name
+// contains only lowercase letters, digits, and hyphens.
+func shorten(name string, max int) string {
+	if len(name) <= max {
+		return name
+	}
+
+	// hash.Hash.Write never returns an error: https://pkg.go.dev/hash#Hash.
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(name))
+
+	prefix := strings.TrimRight(name[:max-hashLength-1], "-.")
+	return fmt.Sprintf("%s-%0*x", prefix, hashLength, hash.Sum32())
+}
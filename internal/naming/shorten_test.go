@@ -0,0 +1,46 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package naming
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestShorten(t *testing.T) {
+	// Short names are unchanged.
+	assert.Equal(t, shorten("hippo-primary", 63), "hippo-primary")
+	assert.Equal(t, shorten(strings.Repeat("x", 63), 63), strings.Repeat("x", 63))
+
+	long := strings.Repeat("x", 60) + "-primary"
+	short := shorten(long, 63)
+	assert.Equal(t, len(short), 63)
+	assert.Assert(t, strings.HasPrefix(short, strings.Repeat("x", 54)+"-"))
+	assert.Assert(t, nil == validation.IsDNS1123Label(short))
+
+	// The same name is always shortened the same way.
+	assert.Equal(t, shorten(long, 63), short)
+
+	// Names with the same prefix are shortened differently.
+	assert.Assert(t, shorten(strings.Repeat("x", 60)+"-replicas", 63) != short)
+
+	// Hyphens are not left next to the hash.
+	assert.Assert(t, !strings.Contains(
+		shorten(strings.Repeat("x", 53)+"--"+strings.Repeat("y", 20), 63), "--"))
+}
@@ -0,0 +1,183 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package naming
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// ObjectNameVariables are the values available to the template of generated
+// object names. See SetNameTemplates.
+type ObjectNameVariables struct {
+	// Cluster is the name of the PostgresCluster.
+	Cluster string
+
+	// Namespace is the namespace of the PostgresCluster.
+	Namespace string
+
+	// Suffix identifies one object of the PostgresCluster, such as "primary"
+	// or "pgbackrest-config".
+	Suffix string
+
+	// Name is the name generated without a template: Cluster, a hyphen, then
+	// Suffix.
+	Name string
+}
+
+// UserSecretNameVariables are the values available to the template of
+// generated user Secret names. See SetNameTemplates.
+type UserSecretNameVariables struct {
+	// Cluster is the name of the PostgresCluster.
+	Cluster string
+
+	// Namespace is the namespace of the PostgresCluster.
+	Namespace string
+
+	// User is the name of the PostgreSQL user.
+	User string
+
+	// Name is the name generated without a template.
+	Name string
+}
+
+var (
+	// objectNameTemplate and userSecretNameTemplate generate names when they
+	// are not nil. See SetNameTemplates.
+	objectNameTemplate     *template.Template
+	userSecretNameTemplate *template.Template
+)
+
+// SetNameTemplates changes how names are generated for the objects of every
+// PostgresCluster. The objects template is executed with ObjectNameVariables,
+// and the userSecrets template is executed with UserSecretNameVariables. An
+// empty template leaves those names unchanged. It returns an error when either
+// template cannot generate distinct, valid names.
+//
+// NOTE: Patroni derives the names of its objects from its scope; those are not
+// affected by templates.
+func SetNameTemplates(objects, userSecrets string) error {
+	var objectsTemplate, userSecretsTemplate *template.Template
+	var err error
+
+	if objects != "" {
+		objectsTemplate, err = parseNameTemplate("objects", objects,
+			func(cluster, suffix string) interface{} {
+				return ObjectNameVariables{
+					Cluster: cluster, Namespace: "ns1", Suffix: suffix,
+					Name: cluster + "-" + suffix,
+				}
+			})
+	}
+	if err == nil && userSecrets != "" {
+		userSecretsTemplate, err = parseNameTemplate("user secrets", userSecrets,
+			func(cluster, user string) interface{} {
+				return UserSecretNameVariables{
+					Cluster: cluster, Namespace: "ns1", User: user,
+					Name: cluster + "-pguser-" + user,
+				}
+			})
+	}
+	if err == nil {
+		objectNameTemplate, userSecretNameTemplate = objectsTemplate, userSecretsTemplate
+	}
+	return err
+}
+
+// parseNameTemplate parses text and executes it with a few values from sample.
+// It returns an error when any result is not a valid name or when different
+// values produce the same name.
+func parseNameTemplate(
+	name, text string, sample func(cluster, other string) interface{},
+) (*template.Template, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	seen := make(map[string]bool)
+	for _, values := range []interface{}{
+		sample("hippo", "primary"),
+		sample("hippo", "replicas"),
+		sample("rhino", "primary"),
+	} {
+		result, err := executeNameTemplate(t, values)
+		if err != nil {
+			return nil, err
+		}
+		if errs := validation.IsDNS1123Label(result); len(errs) > 0 {
+			return nil, errors.Errorf("%s template generated an invalid name %q: %s",
+				name, result, strings.Join(errs, "; "))
+		}
+		if seen[result] {
+			return nil, errors.Errorf("%s template generated %q more than once", name, result)
+		}
+		seen[result] = true
+	}
+
+	return t, nil
+}
+
+// executeNameTemplate returns the result of t with values.
+func executeNameTemplate(t *template.Template, values interface{}) (string, error) {
+	var b strings.Builder
+	err := t.Execute(&b, values)
+	return b.String(), errors.WithStack(err)
+}
+
+// clusterObjectName returns the name of an object of cluster identified by
+// suffix. It is cluster's name followed by suffix unless SetNameTemplates was
+// called with a template for objects. The name is shortened to max.
+func clusterObjectName(cluster *v1beta1.PostgresCluster, suffix string, max int) string {
+	values := ObjectNameVariables{
+		Cluster:   cluster.Name,
+		Namespace: cluster.Namespace,
+		Suffix:    suffix,
+		Name:      cluster.Name + "-" + suffix,
+	}
+	name := values.Name
+	if objectNameTemplate != nil {
+		// The template was checked by SetNameTemplates; fall back to the
+		// default should it fail anyway.
+		if result, err := executeNameTemplate(objectNameTemplate, values); err == nil {
+			name = result
+		}
+	}
+	return shorten(name, max)
+}
+
+// userSecretName returns the name of the Secret for username in cluster. See
+// SetNameTemplates.
+func userSecretName(cluster *v1beta1.PostgresCluster, username string) string {
+	values := UserSecretNameVariables{
+		Cluster:   cluster.Name,
+		Namespace: cluster.Namespace,
+		User:      username,
+		Name:      cluster.Name + "-pguser-" + username,
+	}
+	name := values.Name
+	if userSecretNameTemplate != nil {
+		if result, err := executeNameTemplate(userSecretNameTemplate, values); err == nil {
+			name = result
+		}
+	}
+	return shorten(name, maxNameLength)
+}
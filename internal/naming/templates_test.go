@@ -0,0 +1,72 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package naming
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestSetNameTemplates(t *testing.T) {
+	t.Cleanup(func() { assert.NilError(t, SetNameTemplates("", "")) })
+
+	cluster := &v1beta1.PostgresCluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "pg0"},
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		assert.NilError(t, SetNameTemplates("", ""))
+		assert.Equal(t, ClusterPrimaryService(cluster).Name, "pg0-primary")
+		assert.Equal(t, PostgresUserSecret(cluster, "app").Name, "pg0-pguser-app")
+	})
+
+	t.Run("Templates", func(t *testing.T) {
+		assert.NilError(t, SetNameTemplates(
+			"team-a-{{ .Name }}",
+			"{{ .Namespace }}-{{ .Cluster }}-{{ .User }}"))
+
+		assert.Equal(t, ClusterPrimaryService(cluster).Name, "team-a-pg0-primary")
+		assert.Equal(t, PGBackRestRepoHost(cluster).Name, "team-a-pg0-repo-host")
+		assert.Equal(t, PostgresUserSecret(cluster, "app").Name, "ns1-pg0-app")
+
+		// Patroni names are unchanged.
+		assert.Equal(t, PatroniScope(cluster), "pg0-ha")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.NilError(t, SetNameTemplates("x-{{ .Name }}", ""))
+
+		for _, tt := range []struct{ objects, users, message string }{
+			{"{{ .Name", "", "unclosed action"},
+			{"{{ .Unknown }}", "", "can't evaluate field Unknown"},
+			{"UPPER-{{ .Name }}", "", "invalid name"},
+			{"{{ .Cluster }}", "", "more than once"},
+			{"{{ .Suffix }}", "", "more than once"},
+			{"", "{{ .Cluster }}-{{ .Suffix }}", "can't evaluate field Suffix"},
+			{"", "{{ .User }}", "more than once"},
+		} {
+			assert.ErrorContains(t, SetNameTemplates(tt.objects, tt.users), tt.message,
+				"%q, %q", tt.objects, tt.users)
+		}
+
+		// Failures leave the prior templates in place.
+		assert.Equal(t, ClusterPrimaryService(cluster).Name, "x-pg0-primary")
+	})
+}
@@ -0,0 +1,157 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package notify sends some of the events the operator records to a webhook,
+// such as a Slack incoming webhook, so that teams without an alerting pipeline
+// still hear about failovers and failed backups.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+)
+
+// Notification is one event in the lifecycle of a cluster.
+type Notification struct {
+	Time time.Time `json:"time"`
+
+	// Type is the type of the event: "Normal" or "Warning".
+	Type string `json:"type"`
+
+	// Reason is the reason of the event, e.g. "BackupFailed".
+	Reason string `json:"reason"`
+
+	// Kind, Namespace, and Name identify the object of the event.
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	Message string `json:"message"`
+}
+
+// Notifier sends notifications to a webhook.
+type Notifier struct {
+	Client *http.Client
+	URL    string
+
+	events map[string]bool
+	render func(Notification) ([]byte, error)
+}
+
+// New returns a Notifier for the events in notifications, or those in
+// defaults when it has none. It returns nil when notifications are disabled.
+func New(notifications config.Notifications, defaults []string) (*Notifier, error) {
+	if !notifications.Enabled() {
+		return nil, nil
+	}
+
+	n := &Notifier{
+		Client: &http.Client{Timeout: 10 * time.Second},
+		URL:    notifications.URL,
+		events: make(map[string]bool),
+	}
+
+	events := notifications.Events
+	if len(events) == 0 {
+		events = defaults
+	}
+	for _, reason := range events {
+		n.events[reason] = true
+	}
+
+	switch {
+	case notifications.Template != "":
+		tmpl, err := template.New("notification").Funcs(template.FuncMap{
+			"json": func(v interface{}) (string, error) {
+				b, err := json.Marshal(v)
+				return string(b), err
+			},
+		}).Parse(notifications.Template)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		n.render = func(notification Notification) ([]byte, error) {
+			var buffer bytes.Buffer
+			err := tmpl.Execute(&buffer, notification)
+			return buffer.Bytes(), errors.WithStack(err)
+		}
+
+	case notifications.Format == "slack":
+		// Slack incoming webhooks accept a "text" field of mrkdwn.
+		// - https://api.slack.com/messaging/webhooks
+		n.render = func(notification Notification) ([]byte, error) {
+			emoji := ":information_source:"
+			if notification.Type == "Warning" {
+				emoji = ":warning:"
+			}
+			b, err := json.Marshal(map[string]string{
+				"text": fmt.Sprintf("%s *%s* %s/%s: %s", emoji, notification.Reason,
+					notification.Namespace, notification.Name, notification.Message),
+			})
+			return b, errors.WithStack(err)
+		}
+
+	default:
+		n.render = func(notification Notification) ([]byte, error) {
+			b, err := json.Marshal(notification)
+			return b, errors.WithStack(err)
+		}
+	}
+
+	return n, nil
+}
+
+// Wants returns whether or not n sends events with reason.
+func (n *Notifier) Wants(reason string) bool { return n != nil && n.events[reason] }
+
+// Send renders notification and POSTs it to the webhook. It returns an error
+// when the webhook does not respond with a 2xx status.
+func (n *Notifier) Send(ctx context.Context, notification Notification) error {
+	body, err := n.render(notification)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := n.Client.Do(request)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer response.Body.Close()
+
+	// Read some of the body so the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(response.Body, 4096))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return errors.Errorf("webhook responded %q", response.Status)
+	}
+	return nil
+}
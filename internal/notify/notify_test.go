@@ -0,0 +1,151 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package notify
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestNew(t *testing.T) {
+	n, err := New(config.Notifications{}, []string{"BackupFailed"})
+	assert.NilError(t, err)
+	assert.Assert(t, n == nil)
+	assert.Assert(t, !n.Wants("BackupFailed"))
+
+	n, err = New(config.Notifications{URL: "http://example.com"}, []string{"BackupFailed"})
+	assert.NilError(t, err)
+	assert.Assert(t, n.Wants("BackupFailed"))
+	assert.Assert(t, !n.Wants("Switchover"))
+
+	n, err = New(config.Notifications{
+		URL: "http://example.com", Events: []string{"Switchover"},
+	}, []string{"BackupFailed"})
+	assert.NilError(t, err)
+	assert.Assert(t, !n.Wants("BackupFailed"))
+	assert.Assert(t, n.Wants("Switchover"))
+
+	_, err = New(config.Notifications{URL: "http://example.com", Template: "{{ .Nope"}, nil)
+	assert.ErrorContains(t, err, "notification")
+}
+
+func TestSend(t *testing.T) {
+	ctx := context.Background()
+	notification := Notification{
+		Time:      time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
+		Type:      "Warning",
+		Reason:    "BackupFailed",
+		Kind:      "PostgresCluster",
+		Namespace: "ns1",
+		Name:      "hippo",
+		Message:   `Backup "full" failed`,
+	}
+
+	var body []byte
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Method, http.MethodPost)
+		assert.Equal(t, r.Header.Get("Content-Type"), "application/json")
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("JSON", func(t *testing.T) {
+		n, err := New(config.Notifications{URL: server.URL, Format: "json"}, nil)
+		assert.NilError(t, err)
+		assert.NilError(t, n.Send(ctx, notification))
+		assert.Equal(t, string(body), `{"time":"2024-03-01T12:00:00Z","type":"Warning",`+
+			`"reason":"BackupFailed","kind":"PostgresCluster","namespace":"ns1","name":"hippo",`+
+			`"message":"Backup \"full\" failed"}`)
+	})
+
+	t.Run("Slack", func(t *testing.T) {
+		n, err := New(config.Notifications{URL: server.URL, Format: "slack"}, nil)
+		assert.NilError(t, err)
+		assert.NilError(t, n.Send(ctx, notification))
+		assert.Equal(t, string(body),
+			`{"text":":warning: *BackupFailed* ns1/hippo: Backup \"full\" failed"}`)
+	})
+
+	t.Run("Template", func(t *testing.T) {
+		n, err := New(config.Notifications{
+			URL:      server.URL,
+			Template: `{"summary": {{ printf "%s/%s %s" .Namespace .Name .Message | json }}}`,
+		}, nil)
+		assert.NilError(t, err)
+		assert.NilError(t, n.Send(ctx, notification))
+		assert.Equal(t, string(body), `{"summary": "ns1/hippo Backup \"full\" failed"}`)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		status = http.StatusBadRequest
+		n, err := New(config.Notifications{URL: server.URL}, nil)
+		assert.NilError(t, err)
+		assert.ErrorContains(t, n.Send(ctx, notification), "400")
+	})
+}
+
+func TestRecorder(t *testing.T) {
+	next := record.NewFakeRecorder(10)
+	assert.Equal(t, NewRecorder(next, nil), record.EventRecorder(next))
+
+	n, err := New(config.Notifications{URL: "http://example.com"}, []string{"BackupFailed"})
+	assert.NilError(t, err)
+
+	sent := make(chan Notification, 10)
+	r := NewRecorder(next, n).(*recorder)
+	r.send = func(notification Notification) { sent <- notification }
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+
+	r.Event(cluster, "Normal", "Switchover", "not sent")
+	assert.Equal(t, <-next.Events, "Normal Switchover not sent")
+
+	r.Eventf(cluster, "Warning", "BackupFailed", "Backup %q failed", "full")
+	assert.Equal(t, <-next.Events, `Warning BackupFailed Backup "full" failed`)
+
+	notification := <-sent
+	assert.Equal(t, notification.Kind, "PostgresCluster")
+	assert.Equal(t, notification.Namespace, "ns1")
+	assert.Equal(t, notification.Name, "hippo")
+	assert.Equal(t, notification.Reason, "BackupFailed")
+	assert.Equal(t, notification.Message, `Backup "full" failed`)
+	assert.Assert(t, !notification.Time.IsZero())
+
+	// Clusters can opt out with an annotation.
+	cluster.Annotations = map[string]string{naming.Notifications: "false"}
+	r.Event(cluster, "Warning", "BackupFailed", "opted out")
+	assert.Equal(t, <-next.Events, "Warning BackupFailed opted out")
+
+	select {
+	case notification := <-sent:
+		t.Fatalf("unexpected notification: %v", notification)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+)
+
+// recorder is a record.EventRecorder that also sends the events its Notifier
+// wants.
+type recorder struct {
+	record.EventRecorder
+	notifier *Notifier
+
+	// send is called in its own goroutine for each notification.
+	send func(Notification)
+}
+
+// NewRecorder returns a record.EventRecorder that records events with next
+// and sends those that notifier wants. Events of objects with the
+// "notifications" annotation set to "false" are not sent. Notifications are
+// sent in the background so that a slow webhook does not hold up reconciling.
+func NewRecorder(next record.EventRecorder, notifier *Notifier) record.EventRecorder {
+	if notifier == nil {
+		return next
+	}
+	return &recorder{
+		EventRecorder: next,
+		notifier:      notifier,
+		send: func(notification Notification) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			if err := notifier.Send(ctx, notification); err != nil {
+				logging.FromContext(ctx).Error(err, "unable to send notification",
+					"reason", notification.Reason,
+					"namespace", notification.Namespace, "name", notification.Name)
+			}
+		},
+	}
+}
+
+func (r *recorder) notify(object runtime.Object, eventtype, reason, message string) {
+	if !r.notifier.Wants(reason) {
+		return
+	}
+
+	accessor, err := meta.Accessor(object)
+	if err != nil || accessor.GetAnnotations()[naming.Notifications] == "false" {
+		return
+	}
+
+	// Typed objects from the API often have an empty TypeMeta.
+	kind := object.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		kind = reflect.Indirect(reflect.ValueOf(object)).Type().Name()
+	}
+
+	go r.send(Notification{
+		Time:      time.Now().UTC(),
+		Type:      eventtype,
+		Reason:    reason,
+		Kind:      kind,
+		Namespace: accessor.GetNamespace(),
+		Name:      accessor.GetName(),
+		Message:   message,
+	})
+}
+
+func (r *recorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.EventRecorder.Event(object, eventtype, reason, message)
+	r.notify(object, eventtype, reason, message)
+}
+
+func (r *recorder) Eventf(
+	object runtime.Object, eventtype, reason, messageFmt string, args ...interface{},
+) {
+	r.EventRecorder.Eventf(object, eventtype, reason, messageFmt, args...)
+	r.notify(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *recorder) AnnotatedEventf(
+	object runtime.Object, annotations map[string]string,
+	eventtype, reason, messageFmt string, args ...interface{},
+) {
+	r.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
+	r.notify(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
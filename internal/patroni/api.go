@@ -34,6 +34,10 @@ type API interface {
 
 	// ReplaceConfiguration replaces Patroni's entire dynamic configuration.
 	ReplaceConfiguration(ctx context.Context, configuration map[string]interface{}) error
+
+	// ReloadConfiguration asks every member of the Patroni cluster named scope
+	// to apply its configuration now rather than in its next loop.
+	ReloadConfiguration(ctx context.Context, scope string) error
 }
 
 // Executor implements API by calling "patronictl".
@@ -91,3 +95,21 @@ func (exec Executor) ReplaceConfiguration(
 
 	return err
 }
+
+// ReloadConfiguration asks every member of the Patroni cluster named scope to
+// reload its configuration by calling "patronictl". Members apply any changes
+// to PostgreSQL parameters that do not require a restart.
+func (exec Executor) ReloadConfiguration(ctx context.Context, scope string) error {
+	var stdout, stderr bytes.Buffer
+
+	err := exec(ctx, nil, &stdout, &stderr,
+		"patronictl", "reload", scope, "--force")
+
+	log := logging.FromContext(ctx)
+	log.V(1).Info("reloaded configuration",
+		"stdout", stdout.String(),
+		"stderr", stderr.String(),
+	)
+
+	return err
+}
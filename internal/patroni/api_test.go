@@ -112,3 +112,22 @@ func TestExecutorReplaceConfiguration(t *testing.T) {
 
 	assert.Equal(t, expected, actual, "should call exec")
 }
+
+func TestExecutorReloadConfiguration(t *testing.T) {
+	expected := errors.New("bang")
+	exec := func(
+		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		assert.DeepEqual(t, command, strings.Fields(
+			`patronictl reload some-scope --force`,
+		))
+		assert.Assert(t, stdin == nil, "expected no stdin, got %T", stdin)
+		assert.Assert(t, stderr != nil, "should capture stderr")
+		assert.Assert(t, stdout != nil, "should capture stdout")
+		return expected
+	}
+
+	actual := Executor(exec).ReloadConfiguration(context.Background(), "some-scope")
+
+	assert.Equal(t, expected, actual, "should call exec")
+}
@@ -0,0 +1,96 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package patroni
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/util"
+)
+
+const (
+	authenticationConfigPath = "~postgres-operator_authentication.yaml"
+	authenticationFileKey    = "patroni-authentication.yaml"
+
+	authenticationPasswordKey = "password"
+	authenticationUsernameKey = "username"
+
+	// authenticationUsername is the user that Patroni requires for "unsafe"
+	// REST API endpoints and that `patronictl` presents when calling them.
+	authenticationUsername = "patroni"
+)
+
+// AuthenticationSecret populates outSecret with credentials for the Patroni
+// REST API. A password found in inExisting is kept; otherwise one is generated.
+func AuthenticationSecret(ctx context.Context,
+	inExisting *corev1.Secret, outSecret *corev1.Secret,
+) error {
+	initialize.ByteMap(&outSecret.Data)
+
+	password := string(inExisting.Data[authenticationPasswordKey])
+
+	var err error
+	if password == "" {
+		password, err = util.GeneratePassword(util.DefaultGeneratedPasswordLength)
+	}
+
+	// Patroni checks these credentials with HTTP basic authentication on any
+	// API endpoint that changes something, e.g. switchover and reload. Every
+	// instance is also a client of the others, and `patronictl` is a client of
+	// them all, so the same credentials go in the "restapi" and "ctl" sections.
+	// The "ctl" section is read by Patroni v2.1 and later; older versions of
+	// `patronictl` use "restapi.authentication".
+	// - https://github.com/zalando/patroni/blob/v2.1.0/docs/SETTINGS.rst#rest-api
+	var file []byte
+	if err == nil {
+		credentials := map[string]string{
+			"username": authenticationUsername,
+			"password": password,
+		}
+		file, err = yaml.Marshal(map[string]interface{}{
+			"restapi": map[string]interface{}{"authentication": credentials},
+			"ctl":     map[string]interface{}{"authentication": credentials},
+		})
+	}
+
+	if err == nil {
+		outSecret.Data[authenticationFileKey] = file
+		outSecret.Data[authenticationPasswordKey] = []byte(password)
+		outSecret.Data[authenticationUsernameKey] = []byte(authenticationUsername)
+	}
+
+	return err
+}
+
+// instanceAuthentication returns a projection of Patroni's REST API
+// credentials to include in the instance configuration volume.
+func instanceAuthentication(secretName string) []corev1.VolumeProjection {
+	return []corev1.VolumeProjection{{
+		Secret: &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{
+				Name: secretName,
+			},
+			Items: []corev1.KeyToPath{{
+				Key:  authenticationFileKey,
+				Path: authenticationConfigPath,
+			}},
+		},
+	}}
+}
@@ -0,0 +1,59 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package patroni
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestAuthenticationSecret(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	existing := new(corev1.Secret)
+	secret := new(corev1.Secret)
+
+	assert.NilError(t, AuthenticationSecret(ctx, existing, secret))
+	assert.Equal(t, string(secret.Data["username"]), "patroni")
+	assert.Assert(t, len(secret.Data["password"]) > 0)
+
+	var file struct {
+		RestAPI struct {
+			Authentication map[string]string
+		}
+		Ctl struct {
+			Authentication map[string]string
+		}
+	}
+	assert.NilError(t, yaml.Unmarshal(secret.Data["patroni-authentication.yaml"], &file))
+	assert.DeepEqual(t, file.RestAPI.Authentication, map[string]string{
+		"username": "patroni", "password": string(secret.Data["password"]),
+	})
+	assert.DeepEqual(t, file.Ctl.Authentication, file.RestAPI.Authentication)
+
+	t.Run("KeepsPassword", func(t *testing.T) {
+		existing := secret.DeepCopy()
+		again := new(corev1.Secret)
+
+		assert.NilError(t, AuthenticationSecret(ctx, existing, again))
+		assert.DeepEqual(t, again.Data, existing.Data)
+	})
+}
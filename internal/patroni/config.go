@@ -59,8 +59,8 @@ func clusterYAML(
 		// lifetime.
 		"scope": naming.PatroniScope(cluster),
 
-		// Use Kubernetes Endpoints for the distributed configuration store (DCS).
-		// These values cannot change during the cluster's lifetime.
+		// Use Kubernetes Endpoints or ConfigMaps for the distributed configuration
+		// store (DCS). These values cannot change during the cluster's lifetime.
 		//
 		// NOTE(cbandy): It *might* be possible to *carefully* change the role and
 		// scope labels, but there is no way to reconfigure all instances at once.
@@ -68,7 +68,7 @@ func clusterYAML(
 			"namespace":     cluster.Namespace,
 			"role_label":    naming.LabelRole,
 			"scope_label":   naming.LabelPatroni,
-			"use_endpoints": true,
+			"use_endpoints": !ClusterUsesConfigMaps(cluster),
 
 			// In addition to "scope_label" above, Patroni will add the following to
 			// every object it creates. It will also use these as filters when doing
@@ -135,6 +135,9 @@ func clusterYAML(
 			// - https://issue.k8s.io/92647
 			"verify_client": "optional",
 
+			// Credentials for those endpoints are kept in a Secret and merged from
+			// another file in the configuration directory. See AuthenticationSecret.
+
 			// TODO(cbandy): The next release of Patroni will allow more control over
 			// the TLS protocols/ciphers.
 			// Maybe "ciphers": "EECDH+AESGCM+FIPS:EDH+AESGCM+FIPS". Maybe add ":!DHE".
@@ -227,6 +230,14 @@ func DynamicConfiguration(
 			parameters[k] = v
 		}
 	}
+	// PostgreSQL does not start with parameters it does not recognize. Leave
+	// out those that were removed in the current version, so a cluster
+	// configured for an older version can be upgraded.
+	for k := range parameters {
+		if removedParameter(k, cluster.Spec.PostgresVersion) {
+			delete(parameters, k)
+		}
+	}
 	// Override the above with mandatory parameters.
 	if pgParameters.Mandatory != nil {
 		for k, v := range pgParameters.Mandatory.AsMap() {
@@ -266,10 +277,27 @@ func DynamicConfiguration(
 	}
 	postgresql["pg_hba"] = hba
 
-	// TODO(cbandy): explain this.
+	// Patroni uses pg_rewind to reattach a former primary after a failover
+	// unless it is disabled. It may also remove a data directory that cannot
+	// be reattached and create the replica again. These settings apply to
+	// every instance and take effect without a restart.
+	// - https://patroni.readthedocs.io/en/latest/yaml_configuration.html#postgresql
 	postgresql["use_pg_rewind"] = true
+	if rewind := cluster.Spec.Patroni.Rewind; rewind != nil {
+		if rewind.Enabled != nil {
+			postgresql["use_pg_rewind"] = *rewind.Enabled
+		}
+		if rewind.RemoveDataDirectoryOnFailure != nil {
+			postgresql["remove_data_directory_on_rewind_failure"] =
+				*rewind.RemoveDataDirectoryOnFailure
+		}
+		if rewind.RemoveDataDirectoryOnDivergedTimelines != nil {
+			postgresql["remove_data_directory_on_diverged_timelines"] =
+				*rewind.RemoveDataDirectoryOnDivergedTimelines
+		}
+	}
 
-	if cluster.Spec.Standby != nil && cluster.Spec.Standby.Enabled {
+	if cluster.StandbyEnabled() {
 		// Copy the "standby_cluster" section before making any changes.
 		standby := make(map[string]interface{})
 		if section, ok := root["standby_cluster"].(map[string]interface{}); ok {
@@ -448,10 +476,92 @@ func instanceConfigFiles(cluster, instance *corev1.ConfigMap) []corev1.VolumePro
 	}
 }
 
+// instanceTags returns the Patroni tags that are set in spec.
+// - https://patroni.readthedocs.io/en/latest/yaml_configuration.html#tags
+func instanceTags(spec *v1beta1.PatroniTags) map[string]interface{} {
+	tags := map[string]interface{}{}
+	if spec == nil {
+		return tags
+	}
+
+	for name, value := range map[string]*bool{
+		"nofailover":    spec.NoFailover,
+		"noloadbalance": spec.NoLoadBalance,
+		"nostream":      spec.NoStream,
+		"nosync":        spec.NoSync,
+	} {
+		if value != nil {
+			tags[name] = *value
+		}
+	}
+	if spec.Workload != "" {
+		tags["workload"] = spec.Workload
+	}
+	return tags
+}
+
+// memberParameters are PostgreSQL parameters that Patroni passes to every
+// member from its dynamic configuration. Patroni ignores them in the
+// configuration of individual members.
+// - https://patroni.readthedocs.io/en/latest/dynamic_configuration.html
+var memberParameters = []string{
+	"cluster_name", "hot_standby", "listen_addresses", "max_connections",
+	"max_locks_per_transaction", "max_prepared_transactions",
+	"max_replication_slots", "max_wal_senders", "max_worker_processes", "port",
+	"track_commit_timestamp", "wal_keep_segments", "wal_keep_size",
+	"wal_level", "wal_log_hints",
+}
+
+// removedParameters are PostgreSQL parameters and the major version that
+// removed them. Patroni renames "wal_keep_segments" itself.
+// - https://www.postgresql.org/docs/release/15.0/
+// - https://www.postgresql.org/docs/release/16.0/
+var removedParameters = map[string]int{
+	"stats_temp_directory":     15,
+	"force_parallel_mode":      16,
+	"promote_trigger_file":     16,
+	"vacuum_defer_cleanup_age": 16,
+}
+
+// removedParameter returns whether or not PostgreSQL version no longer has
+// the parameter name.
+func removedParameter(name string, version int) bool {
+	removed, ok := removedParameters[strings.ToLower(name)]
+	return ok && version >= removed
+}
+
+// instanceParameters returns the PostgreSQL parameters of instance that can
+// differ between members of cluster. Parameters with mandatory values in
+// pgParameters are left out so they keep the value of the dynamic configuration.
+// Parameters removed in the version of cluster are also left out.
+func instanceParameters(
+	cluster *v1beta1.PostgresCluster, instance *v1beta1.PostgresInstanceSetSpec,
+	pgParameters postgres.Parameters,
+) map[string]interface{} {
+	parameters := make(map[string]interface{}, len(instance.Parameters))
+	for name, value := range instance.Parameters {
+		parameters[strings.ToLower(name)] = value
+	}
+	for _, name := range memberParameters {
+		delete(parameters, name)
+	}
+	for name := range parameters {
+		if removedParameter(name, cluster.Spec.PostgresVersion) {
+			delete(parameters, name)
+		}
+	}
+	if pgParameters.Mandatory != nil {
+		for name := range pgParameters.Mandatory.AsMap() {
+			delete(parameters, name)
+		}
+	}
+	return parameters
+}
+
 // instanceYAML returns Patroni settings that apply to instance.
 func instanceYAML(
 	cluster *v1beta1.PostgresCluster, instance *v1beta1.PostgresInstanceSetSpec,
-	pgbackrestReplicaCreateCommand []string,
+	pgParameters postgres.Parameters, pgbackrestReplicaCreateCommand []string,
 ) (string, error) {
 	root := map[string]interface{}{
 		// Missing here is "name" which cannot be known until the instance Pod is
@@ -476,10 +586,7 @@ func instanceYAML(
 			// See the PATRONI_RESTAPI_LISTEN environment variable.
 		},
 
-		"tags": map[string]interface{}{
-			// TODO(cbandy): "nofailover"
-			// TODO(cbandy): "nosync"
-		},
+		"tags": instanceTags(instance.Tags),
 	}
 
 	postgresql := map[string]interface{}{
@@ -503,6 +610,12 @@ func instanceYAML(
 	}
 	root["postgresql"] = postgresql
 
+	// Parameters here take precedence over those in the dynamic configuration.
+	// - https://patroni.readthedocs.io/en/latest/dynamic_configuration.html
+	if parameters := instanceParameters(cluster, instance, pgParameters); len(parameters) > 0 {
+		postgresql["parameters"] = parameters
+	}
+
 	// The "basebackup" replica method is configured differently from others.
 	// Patroni prepends "--" before it calls `pg_basebackup`.
 	// - https://github.com/zalando/patroni/blob/v2.0.2/patroni/postgresql/bootstrap.py#L45
@@ -513,8 +626,13 @@ func instanceYAML(
 	methods := []string{"basebackup"}
 
 	// Prefer a pgBackRest method when it is available, and fallback to other
-	// methods when it fails.
-	if command := pgbackrestReplicaCreateCommand; len(command) > 0 {
+	// methods when it fails. This moves the work of copying files off the
+	// primary unless the spec asks for "basebackup" only.
+	command := pgbackrestReplicaCreateCommand
+	if cluster.Spec.Patroni != nil && cluster.Spec.Patroni.ReplicaCreateMethod == "basebackup" {
+		command = nil
+	}
+	if len(command) > 0 {
 
 		// Regardless of the "keep_data" setting below, Patroni deletes the
 		// data directory when all methods fail. pgBackRest will not restore
@@ -551,11 +669,13 @@ func instanceYAML(
 	postgresql["create_replica_methods"] = methods
 
 	if !ClusterBootstrapped(cluster) {
-		// if restore status exists, then a restore occurred an the "existing" method is used
+		// if restore status exists, then a restore occurred an the "existing" method is used;
+		// the same goes for existing and adopted data directories
 		if (cluster.Status.PGBackRest != nil && cluster.Status.PGBackRest.Restore != nil) ||
 			(cluster.Spec.DataSource != nil && cluster.Spec.DataSource.Volumes != nil &&
 				cluster.Spec.DataSource.Volumes.PGDataVolume != nil &&
-				cluster.Spec.DataSource.Volumes.PGDataVolume.Directory != "") {
+				cluster.Spec.DataSource.Volumes.PGDataVolume.Directory != "") ||
+			(cluster.Spec.DataSource != nil && cluster.Spec.DataSource.Patroni != nil) {
 			data_dir := postgres.DataDirectory(cluster)
 			root["bootstrap"] = map[string]interface{}{
 				"method": "existing",
@@ -575,26 +695,7 @@ func instanceYAML(
 				// The "initdb" bootstrap method is configured differently from others.
 				// Patroni prepends "--" before it calls `initdb`.
 				// - https://github.com/zalando/patroni/blob/v2.0.2/patroni/postgresql/bootstrap.py#L45
-				"initdb": []string{
-					// Enable checksums on data pages to help detect corruption of
-					// storage that would otherwise be silent. This also enables
-					// "wal_log_hints" which is a prerequisite for using `pg_rewind`.
-					// - https://www.postgresql.org/docs/current/app-initdb.html
-					// - https://www.postgresql.org/docs/current/app-pgrewind.html
-					// - https://www.postgresql.org/docs/current/runtime-config-wal.html
-					//
-					// The benefits of checksums in the Kubernetes storage landscape
-					// outweigh their negligible overhead, and enabling them later
-					// is costly. (Every file of the cluster must be rewritten.)
-					// PostgreSQL v12 introduced the `pg_checksums` utility which
-					// can cheaply disable them while PostgreSQL is stopped.
-					// - https://www.postgresql.org/docs/current/app-pgchecksums.html
-					"data-checksums",
-					"encoding=UTF8",
-
-					// NOTE(cbandy): The "--waldir" option was introduced in PostgreSQL v10.
-					"waldir=" + postgres.WALDirectory(cluster, instance),
-				},
+				"initdb": initdbOptions(cluster, instance),
 			}
 		}
 	}
@@ -603,6 +704,75 @@ func instanceYAML(
 	return string(append([]byte(yamlGeneratedWarning), b...)), err
 }
 
+// initdbOptions returns the options Patroni passes to `initdb` when it
+// bootstraps cluster on instance. Patroni prepends "--" to each one.
+// - https://www.postgresql.org/docs/current/app-initdb.html
+func initdbOptions(
+	cluster *v1beta1.PostgresCluster, instance *v1beta1.PostgresInstanceSetSpec,
+) []string {
+	spec := new(v1beta1.PostgresInitDBSpec)
+	if cluster.Spec.Config != nil && cluster.Spec.Config.InitDB != nil {
+		spec = cluster.Spec.Config.InitDB
+	}
+
+	var options []string
+
+	// Enable checksums on data pages to help detect corruption of
+	// storage that would otherwise be silent. This also enables
+	// "wal_log_hints" which is a prerequisite for using `pg_rewind`.
+	// - https://www.postgresql.org/docs/current/app-initdb.html
+	// - https://www.postgresql.org/docs/current/app-pgrewind.html
+	// - https://www.postgresql.org/docs/current/runtime-config-wal.html
+	//
+	// The benefits of checksums in the Kubernetes storage landscape
+	// outweigh their negligible overhead, and enabling them later
+	// is costly. (Every file of the cluster must be rewritten.)
+	// PostgreSQL v12 introduced the `pg_checksums` utility which
+	// can cheaply disable them while PostgreSQL is stopped.
+	// - https://www.postgresql.org/docs/current/app-pgchecksums.html
+	//
+	// Patroni sets "wal_log_hints" itself, so `pg_rewind` works when
+	// checksums are disabled in the spec.
+	if spec.DataChecksums == nil || *spec.DataChecksums {
+		options = append(options, "data-checksums")
+	}
+
+	encoding := "UTF8"
+	if spec.Encoding != "" {
+		encoding = spec.Encoding
+	}
+	options = append(options, "encoding="+encoding)
+
+	if spec.Locale != "" {
+		options = append(options, "locale="+spec.Locale)
+	}
+	if spec.LCCollate != "" {
+		options = append(options, "lc-collate="+spec.LCCollate)
+	}
+	if spec.LCCtype != "" {
+		options = append(options, "lc-ctype="+spec.LCCtype)
+	}
+
+	// NOTE: The "--locale-provider" and "--icu-locale" options were introduced
+	// in PostgreSQL v15.
+	if cluster.Spec.PostgresVersion >= 15 {
+		if spec.LocaleProvider != "" {
+			options = append(options, "locale-provider="+spec.LocaleProvider)
+		}
+		if spec.ICULocale != "" {
+			options = append(options, "icu-locale="+spec.ICULocale)
+		}
+	}
+
+	// NOTE: The "--wal-segsize" option was introduced in PostgreSQL v11.
+	if spec.WALSegmentSize != nil {
+		options = append(options, fmt.Sprintf("wal-segsize=%d", *spec.WALSegmentSize))
+	}
+
+	// NOTE(cbandy): The "--waldir" option was introduced in PostgreSQL v10.
+	return append(options, "waldir="+postgres.WALDirectory(cluster, instance))
+}
+
 // probeTiming returns a Probe with thresholds and timeouts set according to spec.
 func probeTiming(spec *v1beta1.PatroniSpec) *corev1.Probe {
 	// "Probes should be configured in such a way that they start failing about
@@ -27,6 +27,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 
+	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/postgres"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -88,6 +89,18 @@ scope: cluster-name-ha
 watchdog:
   mode: "off"
 	`)+"\n")
+
+	t.Run("ConfigMaps", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Patroni.Kubernetes = &v1beta1.PatroniKubernetes{
+			UseConfigMaps: new(bool),
+		}
+		*cluster.Spec.Patroni.Kubernetes.UseConfigMaps = true
+
+		data, err := clusterYAML(cluster, postgres.HBAs{}, postgres.Parameters{})
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(data, "\n  use_endpoints: false\n"), "got %q", data)
+	})
 }
 
 func TestDynamicConfiguration(t *testing.T) {
@@ -200,6 +213,37 @@ func TestDynamicConfiguration(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "postgresql: rewind spec overrides input",
+			cluster: &v1beta1.PostgresCluster{
+				Spec: v1beta1.PostgresClusterSpec{
+					Patroni: &v1beta1.PatroniSpec{
+						Rewind: &v1beta1.PatroniRewind{
+							Enabled:                                initialize.Bool(false),
+							RemoveDataDirectoryOnDivergedTimelines: initialize.Bool(true),
+						},
+					},
+				},
+			},
+			input: map[string]interface{}{
+				"postgresql": map[string]interface{}{
+					"remove_data_directory_on_rewind_failure":     "input",
+					"remove_data_directory_on_diverged_timelines": "overridden",
+				},
+			},
+			expected: map[string]interface{}{
+				"loop_wait": int32(10),
+				"ttl":       int32(30),
+				"postgresql": map[string]interface{}{
+					"parameters":    map[string]interface{}{},
+					"pg_hba":        []string{},
+					"use_pg_rewind": false,
+					"use_slots":     false,
+					"remove_data_directory_on_rewind_failure":     "input",
+					"remove_data_directory_on_diverged_timelines": true,
+				},
+			},
+		},
 		{
 			name: "postgresql.parameters: wrong-type is ignored",
 			input: map[string]interface{}{
@@ -658,13 +702,87 @@ func TestInstanceEnvironment(t *testing.T) {
 	})
 }
 
+func TestInitDBOptions(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{Spec: v1beta1.PostgresClusterSpec{PostgresVersion: 13}}
+	instance := new(v1beta1.PostgresInstanceSetSpec)
+
+	assert.DeepEqual(t, initdbOptions(cluster, instance), []string{
+		"data-checksums", "encoding=UTF8", "waldir=/pgdata/pg13_wal",
+	})
+
+	cluster.Spec.Config = &v1beta1.PostgresConfigSpec{
+		InitDB: &v1beta1.PostgresInitDBSpec{
+			DataChecksums:  initialize.Bool(false),
+			Encoding:       "SQL_ASCII",
+			Locale:         "en_US.UTF-8",
+			LCCollate:      "C",
+			LCCtype:        "C.UTF-8",
+			WALSegmentSize: initialize.Int32(64),
+		},
+	}
+	assert.DeepEqual(t, initdbOptions(cluster, instance), []string{
+		"encoding=SQL_ASCII", "locale=en_US.UTF-8", "lc-collate=C", "lc-ctype=C.UTF-8",
+		"wal-segsize=64", "waldir=/pgdata/pg13_wal",
+	})
+
+	// The locale provider is ignored before PostgreSQL 15.
+	cluster.Spec.Config.InitDB = &v1beta1.PostgresInitDBSpec{
+		LocaleProvider: "icu",
+		ICULocale:      "en-US",
+	}
+	assert.DeepEqual(t, initdbOptions(cluster, instance), []string{
+		"data-checksums", "encoding=UTF8", "waldir=/pgdata/pg13_wal",
+	})
+
+	cluster.Spec.PostgresVersion = 16
+	assert.DeepEqual(t, initdbOptions(cluster, instance), []string{
+		"data-checksums", "encoding=UTF8", "locale-provider=icu", "icu-locale=en-US",
+		"waldir=/pgdata/pg16_wal",
+	})
+}
+
+func TestRemovedParameters(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{Spec: v1beta1.PostgresClusterSpec{PostgresVersion: 14}}
+	cluster.Default()
+	instance := &v1beta1.PostgresInstanceSetSpec{Parameters: map[string]string{
+		"Stats_Temp_Directory": "/tmp", "promote_trigger_file": "/tmp/promote",
+	}}
+	configuration := map[string]interface{}{
+		"postgresql": map[string]interface{}{
+			"parameters": map[string]interface{}{
+				"stats_temp_directory": "/tmp", "vacuum_defer_cleanup_age": 10,
+			},
+		},
+	}
+	dynamic := func() map[string]interface{} {
+		root := DynamicConfiguration(cluster, configuration, postgres.HBAs{}, postgres.Parameters{})
+		return root["postgresql"].(map[string]interface{})["parameters"].(map[string]interface{})
+	}
+
+	assert.DeepEqual(t, dynamic(), map[string]interface{}{
+		"stats_temp_directory": "/tmp", "vacuum_defer_cleanup_age": 10,
+	})
+	assert.DeepEqual(t, instanceParameters(cluster, instance, postgres.Parameters{}),
+		map[string]interface{}{"stats_temp_directory": "/tmp", "promote_trigger_file": "/tmp/promote"})
+
+	cluster.Spec.PostgresVersion = 15
+	assert.DeepEqual(t, dynamic(), map[string]interface{}{"vacuum_defer_cleanup_age": 10})
+	assert.DeepEqual(t, instanceParameters(cluster, instance, postgres.Parameters{}),
+		map[string]interface{}{"promote_trigger_file": "/tmp/promote"})
+
+	cluster.Spec.PostgresVersion = 16
+	assert.DeepEqual(t, dynamic(), map[string]interface{}{})
+	assert.DeepEqual(t, instanceParameters(cluster, instance, postgres.Parameters{}),
+		map[string]interface{}{})
+}
+
 func TestInstanceYAML(t *testing.T) {
 	t.Parallel()
 
 	cluster := &v1beta1.PostgresCluster{Spec: v1beta1.PostgresClusterSpec{PostgresVersion: 12}}
 	instance := new(v1beta1.PostgresInstanceSetSpec)
 
-	data, err := instanceYAML(cluster, instance, nil)
+	data, err := instanceYAML(cluster, instance, postgres.Parameters{}, nil)
 	assert.NilError(t, err)
 	assert.Equal(t, data, strings.Trim(`
 # Generated by postgres-operator. DO NOT EDIT.
@@ -687,7 +805,7 @@ restapi: {}
 tags: {}
 	`, "\t\n")+"\n")
 
-	dataWithReplicaCreate, err := instanceYAML(cluster, instance, []string{"some", "backrest", "cmd"})
+	dataWithReplicaCreate, err := instanceYAML(cluster, instance, postgres.Parameters{}, []string{"some", "backrest", "cmd"})
 	assert.NilError(t, err)
 	assert.Equal(t, dataWithReplicaCreate, strings.Trim(`
 # Generated by postgres-operator. DO NOT EDIT.
@@ -716,6 +834,62 @@ postgresql:
 restapi: {}
 tags: {}
 	`, "\t\n")+"\n")
+
+	// The pgBackRest method is skipped when the spec asks for "basebackup".
+	cluster.Spec.Patroni = &v1beta1.PatroniSpec{ReplicaCreateMethod: "basebackup"}
+	dataWithBaseBackup, err := instanceYAML(cluster, instance, postgres.Parameters{}, []string{"some", "backrest", "cmd"})
+	assert.NilError(t, err)
+	assert.Equal(t, dataWithBaseBackup, data)
+	cluster.Spec.Patroni = nil
+
+	instance.Tags = &v1beta1.PatroniTags{
+		NoFailover:    initialize.Bool(true),
+		NoLoadBalance: initialize.Bool(true),
+		NoSync:        initialize.Bool(false),
+		Workload:      "analytics",
+	}
+	dataWithTags, err := instanceYAML(cluster, instance, postgres.Parameters{}, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasSuffix(dataWithTags, `
+tags:
+  nofailover: true
+  noloadbalance: true
+  nosync: false
+  workload: analytics
+`), "got:\n%s", dataWithTags)
+
+	// The data directory of an adopted Patroni cluster is used as it is.
+	cluster.Spec.DataSource = &v1beta1.DataSource{
+		Patroni: &v1beta1.PatroniDataSource{Directory: "pgroot/data"},
+	}
+	dataWithAdoption, err := instanceYAML(cluster, instance, postgres.Parameters{}, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(dataWithAdoption, `# Generated by postgres-operator. DO NOT EDIT.
+# Your changes will not be saved.
+bootstrap:
+  existing:
+    command: mv "/pgdata/pg12_bootstrap" "/pgdata/pg12"
+    no_params: "true"
+  method: existing
+`), "got:\n%s", dataWithAdoption)
+	cluster.Spec.DataSource = nil
+
+	// Parameters of the instance set apply to its members.
+	pgParameters := postgres.NewParameters()
+	pgParameters.Mandatory.Add("archive_mode", "on")
+	instance.Parameters = map[string]string{
+		"Work_Mem":             "64MB",
+		"hot_standby_feedback": "on",
+		"max_connections":      "1000",
+		"archive_mode":         "off",
+	}
+	dataWithParameters, err := instanceYAML(cluster, instance, pgParameters, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(dataWithParameters, `
+  parameters:
+    hot_standby_feedback: "on"
+    work_mem: 64MB
+`), "got:\n%s", dataWithParameters)
 }
 
 func TestPGBackRestCreateReplicaCommand(t *testing.T) {
@@ -733,7 +907,7 @@ func TestPGBackRestCreateReplicaCommand(t *testing.T) {
 	cluster := new(v1beta1.PostgresCluster)
 	instance := new(v1beta1.PostgresInstanceSetSpec)
 
-	data, err := instanceYAML(cluster, instance, []string{"some", "backrest", "cmd"})
+	data, err := instanceYAML(cluster, instance, postgres.Parameters{}, []string{"some", "backrest", "cmd"})
 	assert.NilError(t, err)
 
 	var parsed struct {
@@ -19,6 +19,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 
+	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -27,8 +28,6 @@ import (
 // +kubebuilder:rbac:namespace=patroni,groups="",resources=pods,verbs=list;watch
 // +kubebuilder:rbac:namespace=patroni,groups="",resources=pods,verbs=patch
 
-// TODO(cbandy): Separate these so that one can choose ConfigMap over Endpoints.
-
 // When using Endpoints for DCS, "create", "list", "patch", and "watch" are
 // required. Include "get" for good measure. The `patronictl scaffold` and
 // `patronictl remove` commands require "deletecollection".
@@ -43,24 +42,62 @@ import (
 // - https://github.com/openshift/origin/pull/9383
 // +kubebuilder:rbac:namespace=patroni,groups="",resources=endpoints/restricted,verbs=create
 
+// When using ConfigMaps for DCS, the same verbs are required of ConfigMaps.
+// +kubebuilder:rbac:namespace=patroni,groups="",resources=configmaps,verbs=get
+// +kubebuilder:rbac:namespace=patroni,groups="",resources=configmaps,verbs=create;deletecollection
+// +kubebuilder:rbac:namespace=patroni,groups="",resources=configmaps,verbs=list;watch
+// +kubebuilder:rbac:namespace=patroni,groups="",resources=configmaps,verbs=patch
+
 // Permissions returns the RBAC rules Patroni needs for cluster.
 func Permissions(cluster *v1beta1.PostgresCluster) []rbacv1.PolicyRule {
-	// TODO(cbandy): This must change when using ConfigMaps for DCS.
-
-	rules := make([]rbacv1.PolicyRule, 0, 4)
-
-	rules = append(rules, rbacv1.PolicyRule{
-		APIGroups: []string{corev1.SchemeGroupVersion.Group},
-		Resources: []string{"endpoints"},
-		Verbs:     []string{"create", "deletecollection", "get", "list", "patch", "watch"},
-	})
+	rules := make([]rbacv1.PolicyRule, 0, 5)
 
-	if cluster.Spec.OpenShift != nil && *cluster.Spec.OpenShift {
+	// Kubernetes cannot limit "create", "deletecollection", "list", or "watch"
+	// to particular names. Patroni reads and writes only the objects of its
+	// scope, so limit "get" and "patch" to those.
+	// - https://docs.k8s.io/reference/access-authn-authz/rbac/#referring-to-resources
+	if ClusterUsesConfigMaps(cluster) {
 		rules = append(rules, rbacv1.PolicyRule{
 			APIGroups: []string{corev1.SchemeGroupVersion.Group},
-			Resources: []string{"endpoints/restricted"},
-			Verbs:     []string{"create"},
+			Resources: []string{"configmaps"},
+			Verbs:     []string{"create", "deletecollection", "list", "watch"},
+		})
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{corev1.SchemeGroupVersion.Group},
+			Resources: []string{"configmaps"},
+			Verbs:     []string{"get", "patch"},
+			ResourceNames: []string{
+				naming.PatroniDistributedConfiguration(cluster).Name,
+				naming.PatroniTrigger(cluster).Name,
+				naming.PatroniLeaderConfigMap(cluster).Name,
+				naming.PatroniScope(cluster) + "-sync", // Patroni DCS "sync_path"
+			},
+		})
+	} else {
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{corev1.SchemeGroupVersion.Group},
+			Resources: []string{"endpoints"},
+			Verbs:     []string{"create", "deletecollection", "list", "watch"},
+		})
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{corev1.SchemeGroupVersion.Group},
+			Resources: []string{"endpoints"},
+			Verbs:     []string{"get", "patch"},
+			ResourceNames: []string{
+				naming.PatroniLeaderEndpoints(cluster).Name,
+				naming.PatroniDistributedConfiguration(cluster).Name,
+				naming.PatroniTrigger(cluster).Name,
+				naming.PatroniScope(cluster) + "-sync", // Patroni DCS "sync_path"
+			},
 		})
+
+		if cluster.Spec.OpenShift != nil && *cluster.Spec.OpenShift {
+			rules = append(rules, rbacv1.PolicyRule{
+				APIGroups: []string{corev1.SchemeGroupVersion.Group},
+				Resources: []string{"endpoints/restricted"},
+				Verbs:     []string{"create"},
+			})
+		}
 	}
 
 	rules = append(rules, rbacv1.PolicyRule{
@@ -73,11 +110,13 @@ func Permissions(cluster *v1beta1.PostgresCluster) []rbacv1.PolicyRule {
 	// NOTE(cbandy): The PostgresCluster controller already creates this Service;
 	// it might be possible to eliminate this permission if it also created the
 	// Endpoints.
-	rules = append(rules, rbacv1.PolicyRule{
-		APIGroups: []string{corev1.SchemeGroupVersion.Group},
-		Resources: []string{"services"},
-		Verbs:     []string{"create"},
-	})
+	if !ClusterUsesConfigMaps(cluster) {
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{corev1.SchemeGroupVersion.Group},
+			Resources: []string{"services"},
+			Verbs:     []string{"create"},
+		})
+	}
 
 	return rules
 }
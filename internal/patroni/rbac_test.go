@@ -39,6 +39,7 @@ func isUniqueAndSorted(slice []string) bool {
 
 func TestPermissions(t *testing.T) {
 	cluster := new(v1beta1.PostgresCluster)
+	cluster.Name = "hippo"
 	cluster.Default()
 
 	t.Run("Upstream", func(t *testing.T) {
@@ -57,10 +58,20 @@ func TestPermissions(t *testing.T) {
   verbs:
   - create
   - deletecollection
-  - get
   - list
-  - patch
   - watch
+- apiGroups:
+  - ""
+  resourceNames:
+  - hippo-ha
+  - hippo-ha-config
+  - hippo-ha-failover
+  - hippo-ha-sync
+  resources:
+  - endpoints
+  verbs:
+  - get
+  - patch
 - apiGroups:
   - ""
   resources:
@@ -79,9 +90,14 @@ func TestPermissions(t *testing.T) {
 		`, "\t\n")+"\n"))
 	})
 
-	t.Run("OpenShift", func(t *testing.T) {
+	t.Run("ConfigMaps", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
 		cluster.Spec.OpenShift = new(bool)
 		*cluster.Spec.OpenShift = true
+		cluster.Spec.Patroni.Kubernetes = &v1beta1.PatroniKubernetes{
+			UseConfigMaps: new(bool),
+		}
+		*cluster.Spec.Patroni.Kubernetes.UseConfigMaps = true
 
 		permissions := Permissions(cluster)
 		for _, rule := range permissions {
@@ -94,14 +110,69 @@ func TestPermissions(t *testing.T) {
 - apiGroups:
   - ""
   resources:
-  - endpoints
+  - configmaps
   verbs:
   - create
   - deletecollection
+  - list
+  - watch
+- apiGroups:
+  - ""
+  resourceNames:
+  - hippo-ha-config
+  - hippo-ha-failover
+  - hippo-ha-leader
+  - hippo-ha-sync
+  resources:
+  - configmaps
+  verbs:
+  - get
+  - patch
+- apiGroups:
+  - ""
+  resources:
+  - pods
+  verbs:
   - get
   - list
   - patch
   - watch
+		`, "\t\n")+"\n"))
+	})
+
+	t.Run("OpenShift", func(t *testing.T) {
+		cluster.Spec.OpenShift = new(bool)
+		*cluster.Spec.OpenShift = true
+
+		permissions := Permissions(cluster)
+		for _, rule := range permissions {
+			assert.Assert(t, isUniqueAndSorted(rule.APIGroups), "got %q", rule.APIGroups)
+			assert.Assert(t, isUniqueAndSorted(rule.Resources), "got %q", rule.Resources)
+			assert.Assert(t, isUniqueAndSorted(rule.Verbs), "got %q", rule.Verbs)
+		}
+
+		assert.Assert(t, marshalEquals(permissions, strings.Trim(`
+- apiGroups:
+  - ""
+  resources:
+  - endpoints
+  verbs:
+  - create
+  - deletecollection
+  - list
+  - watch
+- apiGroups:
+  - ""
+  resourceNames:
+  - hippo-ha
+  - hippo-ha-config
+  - hippo-ha-failover
+  - hippo-ha-sync
+  resources:
+  - endpoints
+  verbs:
+  - get
+  - patch
 - apiGroups:
   - ""
   resources:
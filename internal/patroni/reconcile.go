@@ -17,6 +17,7 @@ package patroni
 
 import (
 	"context"
+	"encoding/json"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
@@ -38,6 +39,14 @@ func ClusterBootstrapped(postgresCluster *v1beta1.PostgresCluster) bool {
 		postgresCluster.Status.Patroni.SystemIdentifier != "")
 }
 
+// ClusterUsesConfigMaps returns whether or not Patroni stores the leader lock
+// and configuration of cluster in ConfigMaps rather than Endpoints.
+func ClusterUsesConfigMaps(cluster *v1beta1.PostgresCluster) bool {
+	return cluster.Spec.Patroni != nil && cluster.Spec.Patroni.Kubernetes != nil &&
+		cluster.Spec.Patroni.Kubernetes.UseConfigMaps != nil &&
+		*cluster.Spec.Patroni.Kubernetes.UseConfigMaps
+}
+
 // ClusterConfigMap populates the shared ConfigMap with fields needed to run Patroni.
 func ClusterConfigMap(ctx context.Context,
 	inCluster *v1beta1.PostgresCluster,
@@ -59,6 +68,7 @@ func ClusterConfigMap(ctx context.Context,
 func InstanceConfigMap(ctx context.Context,
 	inCluster *v1beta1.PostgresCluster,
 	inInstanceSpec *v1beta1.PostgresInstanceSetSpec,
+	inParameters postgres.Parameters,
 	outInstanceConfigMap *corev1.ConfigMap,
 ) error {
 	var err error
@@ -68,7 +78,7 @@ func InstanceConfigMap(ctx context.Context,
 	command := pgbackrest.ReplicaCreateCommand(inCluster, inInstanceSpec)
 
 	outInstanceConfigMap.Data[configMapFileKey], err = instanceYAML(
-		inCluster, inInstanceSpec, command)
+		inCluster, inInstanceSpec, inParameters, command)
 
 	return err
 }
@@ -115,6 +125,13 @@ func InstancePod(ctx context.Context,
 
 	container.Command = []string{"patroni", configDirectory}
 
+	// When debugging startup, wait without Patroni or PostgreSQL so someone
+	// can exec into the container and repair the data directory.
+	debug := inInstanceSpec.StartupDebug != nil && *inInstanceSpec.StartupDebug
+	if debug {
+		container.Command = startupDebugCommand()
+	}
+
 	container.Env = mergeEnvVars(container.Env,
 		instanceEnvironment(inCluster, inClusterPodService, inPatroniLeaderService,
 			outInstancePod.Spec.Containers)...)
@@ -125,11 +142,12 @@ func InstancePod(ctx context.Context,
 	// Add our projections after those specified in the CR. Items later in the
 	// list take precedence over earlier items (that is, last write wins).
 	// - https://kubernetes.io/docs/concepts/storage/volumes/#projected
-	volume.Projected.Sources = append(append(append(
+	volume.Projected.Sources = append(append(append(append(
 		// TODO(cbandy): User config will come from the spec.
 		volume.Projected.Sources, []corev1.VolumeProjection(nil)...),
 		instanceConfigFiles(inClusterConfigMap, inInstanceConfigMap)...),
-		instanceCertificates(inInstanceCertificates)...)
+		instanceCertificates(inInstanceCertificates)...),
+		instanceAuthentication(naming.PatroniAuthentication(inCluster).Name)...)
 
 	outInstancePod.Spec.Volumes = mergeVolumes(outInstancePod.Spec.Volumes, volume)
 
@@ -139,11 +157,29 @@ func InstancePod(ctx context.Context,
 		ReadOnly:  true,
 	})
 
-	instanceProbes(inCluster, container)
+	if debug {
+		container.LivenessProbe = nil
+		container.ReadinessProbe = nil
+	} else {
+		instanceProbes(inCluster, container)
+	}
 
 	return nil
 }
 
+// startupDebugCommand returns a command that waits until it is signaled to
+// stop. It does nothing else.
+func startupDebugCommand() []string {
+	script := strings.Join([]string{
+		`echo 'Startup debugging is enabled; Patroni and PostgreSQL are not running.'`,
+		`echo 'Repair the data directory then unset "startupDebug" of this instance set.'`,
+		`trap 'exit 0' TERM INT`,
+		`while true; do sleep 5 & wait $!; done`,
+	}, "\n")
+
+	return []string{"bash", "-c", "--", script, "startup-debug"}
+}
+
 // instanceProbes adds Patroni liveness and readiness probes to container.
 func instanceProbes(cluster *v1beta1.PostgresCluster, container *corev1.Container) {
 
@@ -193,3 +229,50 @@ func PodIsStandbyLeader(pod metav1.Object) bool {
 	status := pod.GetAnnotations()["status"]
 	return strings.Contains(status, `"role":"standby_leader"`)
 }
+
+// PodRole returns the role that Patroni last reported for pod, e.g. "master",
+// "replica", or "standby_leader". It returns an empty string when pod is nil
+// or Patroni has not reported a role.
+func PodRole(pod metav1.Object) string {
+	if pod == nil {
+		return ""
+	}
+
+	// Patroni writes its member data as JSON to the "status" annotation.
+	var status struct {
+		Role string `json:"role"`
+	}
+	_ = json.Unmarshal([]byte(pod.GetAnnotations()["status"]), &status)
+	return status.Role
+}
+
+// PodState returns the state that Patroni last reported for PostgreSQL in pod,
+// e.g. "running", "starting", or "crashed". It returns an empty string when
+// pod is nil or Patroni has not reported a state.
+func PodState(pod metav1.Object) string {
+	if pod == nil {
+		return ""
+	}
+
+	// Patroni writes its member data as JSON to the "status" annotation.
+	var status struct {
+		State string `json:"state"`
+	}
+	_ = json.Unmarshal([]byte(pod.GetAnnotations()["status"]), &status)
+	return status.State
+}
+
+// PodPendingRestart returns whether or not Patroni last reported that
+// PostgreSQL in pod must restart for changes to its parameters to take effect.
+func PodPendingRestart(pod metav1.Object) bool {
+	if pod == nil {
+		return false
+	}
+
+	// Patroni writes its member data as JSON to the "status" annotation.
+	var status struct {
+		PendingRestart bool `json:"pending_restart"`
+	}
+	_ = json.Unmarshal([]byte(pod.GetAnnotations()["status"]), &status)
+	return status.PendingRestart
+}
@@ -24,6 +24,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/pki"
 	"github.com/crunchydata/postgres-operator/internal/postgres"
@@ -87,15 +88,15 @@ func TestInstanceConfigMap(t *testing.T) {
 	cluster := new(v1beta1.PostgresCluster)
 	instance := new(v1beta1.PostgresInstanceSetSpec)
 	config := new(corev1.ConfigMap)
-	data, _ := instanceYAML(cluster, instance, nil)
+	data, _ := instanceYAML(cluster, instance, postgres.Parameters{}, nil)
 
-	assert.NilError(t, InstanceConfigMap(ctx, cluster, instance, config))
+	assert.NilError(t, InstanceConfigMap(ctx, cluster, instance, postgres.Parameters{}, config))
 
 	assert.DeepEqual(t, config.Data["patroni.yaml"], data)
 
 	// No change when called again.
 	before := config.DeepCopy()
-	assert.NilError(t, InstanceConfigMap(ctx, cluster, instance, config))
+	assert.NilError(t, InstanceConfigMap(ctx, cluster, instance, postgres.Parameters{}, config))
 	assert.DeepEqual(t, config, before)
 }
 
@@ -205,6 +206,11 @@ volumes:
           path: ~postgres-operator/patroni.ca-roots
         - key: patroni.crt-combined
           path: ~postgres-operator/patroni.crt+key
+    - secret:
+        items:
+        - key: patroni-authentication.yaml
+          path: ~postgres-operator_authentication.yaml
+        name: some-such-patroni-auth
 `)+"\n"))
 
 	// No change when called again.
@@ -331,6 +337,33 @@ volumes:
 		assert.NilError(t, call())
 		assert.DeepEqual(t, template, before)
 	})
+
+	t.Run("StartupDebug", func(t *testing.T) {
+		instanceSpec := new(v1beta1.PostgresInstanceSetSpec)
+		instanceSpec.StartupDebug = initialize.Bool(true)
+		template := new(corev1.PodTemplateSpec)
+
+		assert.NilError(t, InstancePod(context.Background(),
+			cluster, clusterConfigMap, clusterPodService, patroniLeaderService,
+			instanceSpec, instanceCertficates, instanceConfigMap, template))
+
+		container := template.Spec.Containers[0]
+		assert.DeepEqual(t, container.Command[:3], []string{"bash", "-c", "--"})
+		assert.Assert(t, strings.Contains(container.Command[3], "sleep"))
+		assert.Assert(t, container.LivenessProbe == nil)
+		assert.Assert(t, container.ReadinessProbe == nil)
+
+		// Probes return when it is unset.
+		instanceSpec.StartupDebug = nil
+		assert.NilError(t, InstancePod(context.Background(),
+			cluster, clusterConfigMap, clusterPodService, patroniLeaderService,
+			instanceSpec, instanceCertficates, instanceConfigMap, template))
+
+		container = template.Spec.Containers[0]
+		assert.DeepEqual(t, container.Command, []string{"patroni", "/etc/patroni"})
+		assert.Assert(t, container.LivenessProbe != nil)
+		assert.Assert(t, container.ReadinessProbe != nil)
+	})
 }
 
 func TestPodIsStandbyLeader(t *testing.T) {
@@ -357,3 +390,52 @@ func TestPodIsStandbyLeader(t *testing.T) {
 	pod.Annotations["status"] = `{"role":"standby_leader"}`
 	assert.Assert(t, PodIsStandbyLeader(pod))
 }
+
+func TestPodRole(t *testing.T) {
+	// No object
+	assert.Equal(t, PodRole(nil), "")
+
+	// No annotations
+	pod := &corev1.Pod{}
+	assert.Equal(t, PodRole(pod), "")
+
+	// Not JSON
+	pod.Annotations = map[string]string{"status": `role`}
+	assert.Equal(t, PodRole(pod), "")
+
+	// No role
+	pod.Annotations["status"] = `{"state":"running"}`
+	assert.Equal(t, PodRole(pod), "")
+
+	pod.Annotations["status"] = `{"role":"master","state":"running","xlog_location":1234}`
+	assert.Equal(t, PodRole(pod), "master")
+
+	pod.Annotations["status"] = `{"role":"standby_leader"}`
+	assert.Equal(t, PodRole(pod), "standby_leader")
+}
+
+func TestPodState(t *testing.T) {
+	assert.Equal(t, PodState(nil), "")
+
+	pod := &corev1.Pod{}
+	assert.Equal(t, PodState(pod), "")
+
+	pod.Annotations = map[string]string{"status": `state`}
+	assert.Equal(t, PodState(pod), "")
+
+	pod.Annotations["status"] = `{"role":"replica","state":"crashed"}`
+	assert.Equal(t, PodState(pod), "crashed")
+}
+
+func TestPodPendingRestart(t *testing.T) {
+	assert.Assert(t, !PodPendingRestart(nil))
+
+	pod := &corev1.Pod{}
+	assert.Assert(t, !PodPendingRestart(pod))
+
+	pod.Annotations = map[string]string{"status": `{"role":"replica","state":"running"}`}
+	assert.Assert(t, !PodPendingRestart(pod))
+
+	pod.Annotations["status"] = `{"role":"replica","pending_restart":true}`
+	assert.Assert(t, PodPendingRestart(pod))
+}
@@ -102,17 +102,20 @@ func CreatePGBackRestConfigMapIntent(postgresCluster *v1beta1.PostgresCluster,
 	pgdataDir := postgres.DataDirectory(postgresCluster)
 	// Port will always be populated, since the API will set a default of 5432 if not provided
 	pgPort := *postgresCluster.Spec.Port
-	cm.Data[CMInstanceKey] = getConfigString(
-		populatePGInstanceConfigurationMap(serviceName, serviceNamespace, repoHostName,
-			pgdataDir, pgPort, postgresCluster.Spec.Backups.PGBackRest.Repos,
-			postgresCluster.Spec.Backups.PGBackRest.Global))
+	instanceConfig := populatePGInstanceConfigurationMap(serviceName, serviceNamespace,
+		repoHostName, pgdataDir, StanzaName(postgresCluster), pgPort,
+		postgresCluster.Spec.Backups.PGBackRest.Repos, globalConfiguration(postgresCluster))
+	instanceConfig["global:backup"] = backupConfiguration(postgresCluster)
+	instanceConfig["global:archive-push"] = archivePushConfiguration(postgresCluster)
+	cm.Data[CMInstanceKey] = getConfigString(instanceConfig)
 
 	if addDedicatedHost && repoHostName != "" {
-		cm.Data[CMRepoKey] = getConfigString(
-			populateRepoHostConfigurationMap(serviceName, serviceNamespace,
-				pgdataDir, pgPort, instanceNames,
-				postgresCluster.Spec.Backups.PGBackRest.Repos,
-				postgresCluster.Spec.Backups.PGBackRest.Global))
+		repoConfig := populateRepoHostConfigurationMap(serviceName, serviceNamespace,
+			pgdataDir, StanzaName(postgresCluster), pgPort, instanceNames,
+			postgresCluster.Spec.Backups.PGBackRest.Repos,
+			globalConfiguration(postgresCluster))
+		repoConfig["global:backup"] = backupConfiguration(postgresCluster)
+		cm.Data[CMRepoKey] = getConfigString(repoConfig)
 	}
 
 	cm.Data[ConfigHashKey] = configHash
@@ -207,6 +210,7 @@ func RestoreCommand(pgdata string, args ...string) []string {
 	const restoreScript = `declare -r pgdata="$1" opts="$2"
 install --directory --mode=0700 "${pgdata}"
 eval "pgbackrest restore ${opts}"
+sed -n 's/^backup-label="\(.*\)"$/\1/p' "${pgdata}/backup.manifest" > /dev/termination-log || true
 rm -f "${pgdata}/patroni.dynamic.json"
 export PGDATA="${pgdata}" PGHOST='/tmp'
 
@@ -244,9 +248,79 @@ mv "${pgdata}" "${pgdata}_bootstrap"`
 	return append([]string{"bash", "-ceu", "--", restoreScript, "-", pgdata}, args...)
 }
 
+// globalConfiguration returns the [global] options of cluster that come from
+// its spec. Options in the pgBackRest spec take precedence over all others.
+func globalConfiguration(cluster *v1beta1.PostgresCluster) map[string]string {
+	global := make(map[string]string)
+
+	if logging := cluster.Spec.Logging; logging != nil && logging.PGBackRest != nil {
+		if level := logging.PGBackRest.Console; level != "" {
+			global["log-level-console"] = level
+		}
+		if level := logging.PGBackRest.File; level != "" {
+			global["log-level-file"] = level
+		}
+	}
+
+	// The operator expires backups itself once the newest full backup passes
+	// verification. See the "verifyBeforeExpire" field.
+	if verify := cluster.Spec.Backups.PGBackRest.VerifyBeforeExpire; verify != nil && *verify {
+		global["expire-auto"] = "n"
+	}
+
+	for option, val := range cluster.Spec.Backups.PGBackRest.Global {
+		global[option] = val
+	}
+
+	return global
+}
+
+// backupConfiguration returns the [global:backup] options of cluster that
+// limit how quickly backups read and write. Command line options, such as
+// those of a manual backup, take precedence over these.
+func backupConfiguration(cluster *v1beta1.PostgresCluster) map[string]string {
+	backup := make(map[string]string)
+
+	if jobs := cluster.Spec.Backups.PGBackRest.Jobs; jobs != nil {
+		if jobs.BufferSize != "" {
+			backup["buffer-size"] = jobs.BufferSize
+		}
+		if jobs.IOTimeoutSeconds != nil {
+			backup["io-timeout"] = fmt.Sprint(*jobs.IOTimeoutSeconds)
+		}
+		if jobs.ProcessMax != nil {
+			backup["process-max"] = fmt.Sprint(*jobs.ProcessMax)
+		}
+	}
+
+	return backup
+}
+
+// archivePushConfiguration returns the [global:archive-push] options of
+// cluster that control how WAL files are compressed and queued. PostgreSQL runs
+// "archive-push" in instance Pods, so only their configuration needs these.
+func archivePushConfiguration(cluster *v1beta1.PostgresCluster) map[string]string {
+	archive := make(map[string]string)
+
+	if push := cluster.Spec.Backups.PGBackRest.Archive; push != nil {
+		if push.CompressType != "" {
+			archive["compress-type"] = push.CompressType
+		}
+		if push.CompressLevel != nil {
+			archive["compress-level"] = fmt.Sprint(*push.CompressLevel)
+		}
+		if push.QueueMax != nil {
+			archive["archive-push-queue-max"] = fmt.Sprint(push.QueueMax.Value())
+		}
+	}
+
+	return archive
+}
+
 // populatePGInstanceConfigurationMap returns a map representing the pgBackRest configuration for
 // a PostgreSQL instance
-func populatePGInstanceConfigurationMap(serviceName, serviceNamespace, repoHostName, pgdataDir string,
+func populatePGInstanceConfigurationMap(
+	serviceName, serviceNamespace, repoHostName, pgdataDir, stanza string,
 	pgPort int32, repos []v1beta1.PGBackRestRepo,
 	globalConfig map[string]string) map[string]map[string]string {
 
@@ -258,8 +332,8 @@ func populatePGInstanceConfigurationMap(serviceName, serviceNamespace, repoHostN
 		"stanza": {},
 	}
 
-	// set the default stanza name
-	pgBackRestConfig["stanza"]["name"] = DefaultStanzaName
+	// set the stanza name
+	pgBackRestConfig["stanza"]["name"] = stanza
 
 	// set global settings, which includes all repos
 	pgBackRestConfig["global"]["log-path"] = defaultLogPath
@@ -304,7 +378,7 @@ func populatePGInstanceConfigurationMap(serviceName, serviceNamespace, repoHostN
 
 // populateRepoHostConfigurationMap returns a map representing the pgBackRest configuration for
 // a pgBackRest dedicated repository host
-func populateRepoHostConfigurationMap(serviceName, serviceNamespace, pgdataDir string,
+func populateRepoHostConfigurationMap(serviceName, serviceNamespace, pgdataDir, stanza string,
 	pgPort int32, pgHosts []string, repos []v1beta1.PGBackRestRepo,
 	globalConfig map[string]string) map[string]map[string]string {
 
@@ -316,8 +390,8 @@ func populateRepoHostConfigurationMap(serviceName, serviceNamespace, pgdataDir s
 		"stanza": {},
 	}
 
-	// set the default stanza name
-	pgBackRestConfig["stanza"]["name"] = DefaultStanzaName
+	// set the stanza name
+	pgBackRestConfig["stanza"]["name"] = stanza
 
 	// set the config for the local repo host
 	pgBackRestConfig["global"]["log-path"] = defaultLogPath
@@ -364,6 +438,15 @@ func getConfigString(c map[string]map[string]string) string {
 		configString += fmt.Sprintf("%s=%s\n", k, c["global"][k])
 	}
 
+	for _, section := range []string{"global:archive-push", "global:backup"} {
+		if len(c[section]) > 0 {
+			configString += fmt.Sprintf("\n[%s]\n", section)
+			for _, k := range sortedKeys(c[section]) {
+				configString += fmt.Sprintf("%s=%s\n", k, c[section][k])
+			}
+		}
+	}
+
 	if c["stanza"]["name"] != "" {
 		configString += fmt.Sprintf("\n[%s]\n", c["stanza"]["name"])
 
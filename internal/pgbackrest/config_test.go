@@ -30,6 +30,7 @@ import (
 
 	"gotest.tools/v3/assert"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/rand"
@@ -332,3 +333,105 @@ func TestRestoreCommandPrettyYAML(t *testing.T) {
 	assert.Assert(t, strings.Contains(string(b), "\n- |"),
 		"expected literal block scalar, got:\n%s", b)
 }
+
+func TestGlobalConfiguration(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	assert.DeepEqual(t, globalConfiguration(cluster), map[string]string{})
+
+	cluster.Spec.Logging = &v1beta1.PostgresLoggingSpec{
+		PGBackRest: &v1beta1.PGBackRestLoggingSpec{Console: "detail", File: "off"},
+	}
+	assert.DeepEqual(t, globalConfiguration(cluster), map[string]string{
+		"log-level-console": "detail",
+		"log-level-file":    "off",
+	})
+
+	// Options in the pgBackRest spec come last.
+	cluster.Spec.Backups.PGBackRest.Global = map[string]string{
+		"log-level-file": "info",
+		"process-max":    "2",
+	}
+	assert.DeepEqual(t, globalConfiguration(cluster), map[string]string{
+		"log-level-console": "detail",
+		"log-level-file":    "info",
+		"process-max":       "2",
+	})
+
+	t.Run("VerifyBeforeExpire", func(t *testing.T) {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Spec.Backups.PGBackRest.VerifyBeforeExpire = initialize.Bool(false)
+		assert.DeepEqual(t, globalConfiguration(cluster), map[string]string{})
+
+		cluster.Spec.Backups.PGBackRest.VerifyBeforeExpire = initialize.Bool(true)
+		assert.DeepEqual(t, globalConfiguration(cluster), map[string]string{
+			"expire-auto": "n",
+		})
+	})
+}
+
+func TestBackupConfiguration(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	assert.DeepEqual(t, backupConfiguration(cluster), map[string]string{})
+
+	cluster.Spec.Backups.PGBackRest.Jobs = &v1beta1.BackupJobs{
+		BufferSize:       "256KiB",
+		IOTimeoutSeconds: initialize.Int32(120),
+		ProcessMax:       initialize.Int32(2),
+	}
+	assert.DeepEqual(t, backupConfiguration(cluster), map[string]string{
+		"buffer-size": "256KiB",
+		"io-timeout":  "120",
+		"process-max": "2",
+	})
+
+	t.Run("ConfigString", func(t *testing.T) {
+		assert.Equal(t, getConfigString(map[string]map[string]string{
+			"global":        {"log-path": "/tmp"},
+			"global:backup": backupConfiguration(cluster),
+		}), strings.TrimSpace(`
+[global]
+log-path=/tmp
+
+[global:backup]
+buffer-size=256KiB
+io-timeout=120
+process-max=2
+		`)+"\n")
+	})
+}
+
+func TestArchivePushConfiguration(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	assert.DeepEqual(t, archivePushConfiguration(cluster), map[string]string{})
+
+	queue := resource.MustParse("4Gi")
+	cluster.Spec.Backups.PGBackRest.Archive = &v1beta1.PGBackRestArchivePush{
+		CompressType:  "lz4",
+		CompressLevel: initialize.Int32(1),
+		QueueMax:      &queue,
+	}
+	assert.DeepEqual(t, archivePushConfiguration(cluster), map[string]string{
+		"archive-push-queue-max": "4294967296",
+		"compress-level":         "1",
+		"compress-type":          "lz4",
+	})
+
+	t.Run("ConfigString", func(t *testing.T) {
+		assert.Equal(t, getConfigString(map[string]map[string]string{
+			"global":              {"log-path": "/tmp"},
+			"global:archive-push": archivePushConfiguration(cluster),
+			"global:backup":       {"process-max": "2"},
+		}), strings.TrimSpace(`
+[global]
+log-path=/tmp
+
+[global:archive-push]
+archive-push-queue-max=4294967296
+compress-level=1
+compress-type=lz4
+
+[global:backup]
+process-max=2
+		`)+"\n")
+	})
+}
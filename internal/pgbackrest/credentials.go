@@ -0,0 +1,115 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgbackrest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// CredentialSecrets returns the names of the Secrets that cluster projects
+// into its pgBackRest configuration. These usually hold the keys of its cloud
+// repositories.
+func CredentialSecrets(cluster *v1beta1.PostgresCluster) []string {
+	var names []string
+	for _, projection := range cluster.Spec.Backups.PGBackRest.Configuration {
+		if projection.Secret != nil {
+			names = append(names, projection.Secret.Name)
+		}
+	}
+	return names
+}
+
+// credentialFiles returns the contents of the files that the Secrets of
+// cluster put in the configuration directory, keyed by absolute path, and
+// those paths in order. Secrets and keys that are missing are skipped. When
+// more than one Secret has the same path, the last one wins, just as in the
+// projected volume.
+func credentialFiles(
+	cluster *v1beta1.PostgresCluster, secrets map[string]*corev1.Secret,
+) (map[string][]byte, []string) {
+	files := make(map[string][]byte)
+	for _, projection := range cluster.Spec.Backups.PGBackRest.Configuration {
+		if projection.Secret == nil || secrets[projection.Secret.Name] == nil {
+			continue
+		}
+		secret := secrets[projection.Secret.Name]
+
+		// Without items, every key is a file named for the key.
+		items := projection.Secret.Items
+		if len(items) == 0 {
+			for key := range secret.Data {
+				items = append(items, corev1.KeyToPath{Key: key, Path: key})
+			}
+		}
+		for _, item := range items {
+			if data, ok := secret.Data[item.Key]; ok {
+				files[path.Join(ConfigDir, item.Path)] = data
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	return files, paths
+}
+
+// CredentialsRevision returns a value that changes when the files that the
+// Secrets of cluster put in the configuration directory change. It is the
+// SHA-256 of those files in the order of their paths. It is empty when there
+// are no such files.
+func CredentialsRevision(
+	cluster *v1beta1.PostgresCluster, secrets map[string]*corev1.Secret,
+) string {
+	files, paths := credentialFiles(cluster, secrets)
+	if len(paths) == 0 {
+		return ""
+	}
+
+	hash := sha256.New()
+	for _, p := range paths {
+		_, _ = hash.Write(files[p])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// CredentialsCommand returns a command that prints "current" when the files
+// mounted in a container match revision. pgBackRest reads its configuration
+// every time it runs, so nothing needs to be signaled once they do.
+func CredentialsCommand(
+	cluster *v1beta1.PostgresCluster, secrets map[string]*corev1.Secret, revision string,
+) []string {
+	const script = `
+if [ "$(cat "${@:1:$#-1}" | sha256sum)" = "${!#}  -" ]; then
+  echo current
+fi
+`
+	// The files are in the same order as in CredentialsRevision.
+	_, paths := credentialFiles(cluster, secrets)
+	command := []string{"bash", "-ceu", "--", script, "-"}
+	command = append(command, paths...)
+	return append(command, revision)
+}
@@ -0,0 +1,94 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgbackrest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestCredentialsRevision(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Backups.PGBackRest.Configuration = []corev1.VolumeProjection{
+		{ConfigMap: &corev1.ConfigMapProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "options"},
+		}},
+		{Secret: &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "s3-keys"},
+		}},
+		{Secret: &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "gcs-key"},
+			Items:                []corev1.KeyToPath{{Key: "key.json", Path: "gcs/key.json"}},
+		}},
+	}
+	assert.DeepEqual(t, CredentialSecrets(cluster), []string{"s3-keys", "gcs-key"})
+
+	secrets := map[string]*corev1.Secret{}
+	assert.Equal(t, CredentialsRevision(cluster, secrets), "")
+
+	secrets["s3-keys"] = &corev1.Secret{Data: map[string][]byte{
+		"s3.conf": []byte("[global]\n"),
+	}}
+	secrets["gcs-key"] = &corev1.Secret{Data: map[string][]byte{
+		"key.json": []byte("{}"), "other": []byte("x"),
+	}}
+
+	before := CredentialsRevision(cluster, secrets)
+	assert.Equal(t, len(before), 64)
+
+	// The revision matches what "sha256sum" prints for the files in order.
+	hash := sha256.Sum256([]byte("{}" + "[global]\n"))
+	assert.Equal(t, before, hex.EncodeToString(hash[:]))
+
+	// Keys that are not projected do not matter.
+	secrets["gcs-key"].Data["other"] = []byte("y")
+	assert.Equal(t, before, CredentialsRevision(cluster, secrets))
+
+	secrets["s3-keys"].Data["s3.conf"] = []byte("[global]\nrepo1-s3-key=new\n")
+	assert.Assert(t, before != CredentialsRevision(cluster, secrets))
+
+	command := CredentialsCommand(cluster, secrets, "abc")
+	assert.DeepEqual(t, command[:3], []string{"bash", "-ceu", "--"})
+	assert.DeepEqual(t, command[4:], []string{"-",
+		"/etc/pgbackrest/conf.d/gcs/key.json",
+		"/etc/pgbackrest/conf.d/s3.conf",
+		"abc",
+	})
+
+	shellcheck, err := exec.LookPath("shellcheck")
+	if err != nil {
+		t.Skip(`requires "shellcheck" executable`)
+	}
+
+	// Write out that inline script.
+	dir := t.TempDir()
+	file := filepath.Join(dir, "script.bash")
+	assert.NilError(t, ioutil.WriteFile(file, []byte(command[3]), 0o600))
+
+	// Expect shellcheck to be happy.
+	cmd := exec.Command(shellcheck, "--enable=all", file)
+	output, err := cmd.CombinedOutput()
+	assert.NilError(t, err, "%q\n%s", cmd.Args, output)
+}
@@ -18,8 +18,12 @@ package pgbackrest
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -35,12 +39,12 @@ type Executor func(
 	ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
 ) error
 
-// StanzaCreate runs the pgBackRest "stanza-create" command.  If the bool returned from this
+// StanzaCreate runs the pgBackRest "stanza-create" command for stanza.  If the bool returned from this
 // function is false, this indicates that a pgBackRest config hash mismatch was identified that
 // prevented the "pgbackrest stanza-create" command from running (with a config has mitmatch
 // indicating that pgBackRest configuration as stored in the cluster's pgBackRest ConfigMap has
 // not yet propagated to the Pod).
-func (exec Executor) StanzaCreate(ctx context.Context, configHash string) (bool, error) {
+func (exec Executor) StanzaCreate(ctx context.Context, stanza, configHash string) (bool, error) {
 
 	var stdout, stderr bytes.Buffer
 
@@ -57,7 +61,7 @@ else
 fi
 `
 	if err := exec(ctx, nil, &stdout, &stderr, "bash", "-ceu", "--",
-		script, "-", configHash, DefaultStanzaName, errMsgConfigHashMismatch); err != nil {
+		script, "-", configHash, stanza, errMsgConfigHashMismatch); err != nil {
 
 		// if the config hashes didn't match, return true and don't return an error since this is
 		// expected while waiting for config changes in ConfigMaps and Secrets to make it to the
@@ -71,3 +75,159 @@ fi
 
 	return false, nil
 }
+
+// StanzaRepoInfo describes a stanza in one repository as reported by the
+// pgBackRest "info" command.
+type StanzaRepoInfo struct {
+	// Repo is the name of the repository, e.g. "repo1".
+	Repo string
+
+	// Code and Message are the status of the stanza in Repo. Zero is "ok".
+	// - https://pgbackrest.org/command.html#command-info
+	Code    int
+	Message string
+
+	// SystemIdentifier and Version are the PostgreSQL cluster that currently
+	// writes to the stanza in Repo. They are empty when the stanza is missing.
+	SystemIdentifier string
+	Version          string
+
+	// LastBackups are the stop times of the newest backup of each type in
+	// Repo, by type: "full", "diff", or "incr". It is nil when there are none.
+	LastBackups map[string]time.Time
+
+	// LastFullBackup is the label of the newest full backup in Repo, e.g.
+	// "20211003-010000F". It is empty when there are none.
+	LastFullBackup string
+
+	// Size is the number of bytes stored for all the backups in Repo. It does
+	// not include the WAL archive.
+	Size int64
+}
+
+const (
+	// StanzaCodeMissingPath and StanzaCodeMissingData are the status codes of
+	// a stanza that no longer exists in a repository, e.g. after it was wiped.
+	StanzaCodeMissingPath = 1
+	StanzaCodeMissingData = 3
+)
+
+// StanzaInfo runs the pgBackRest "info" command for stanza and returns its
+// status in every repository.
+func (exec Executor) StanzaInfo(ctx context.Context, stanza string) ([]StanzaRepoInfo, error) {
+	var stdout, stderr bytes.Buffer
+
+	err := exec(ctx, nil, &stdout, &stderr,
+		"pgbackrest", "info", "--stanza="+stanza, "--output=json")
+	if err != nil {
+		return nil, errors.WithStack(fmt.Errorf("%w: %v", err, stderr.String()))
+	}
+
+	return parseStanzaInfo(stdout.Bytes())
+}
+
+// parseStanzaInfo interprets the JSON output of the pgBackRest "info" command.
+func parseStanzaInfo(output []byte) ([]StanzaRepoInfo, error) {
+	var stanzas []struct {
+		DB []struct {
+			ID       int         `json:"id"`
+			RepoKey  int         `json:"repo-key"`
+			SystemID json.Number `json:"system-id"`
+			Version  string      `json:"version"`
+		} `json:"db"`
+		Backup []struct {
+			Label    string `json:"label"`
+			Type     string `json:"type"`
+			Database struct {
+				RepoKey int `json:"repo-key"`
+			} `json:"database"`
+			Info struct {
+				Repository struct {
+					Delta int64 `json:"delta"`
+				} `json:"repository"`
+			} `json:"info"`
+			Timestamp struct {
+				Stop int64 `json:"stop"`
+			} `json:"timestamp"`
+		} `json:"backup"`
+		Repo []struct {
+			Key    int `json:"key"`
+			Status struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"status"`
+		} `json:"repo"`
+	}
+
+	if err := json.Unmarshal(output, &stanzas); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var result []StanzaRepoInfo
+	for _, stanza := range stanzas {
+		for _, repo := range stanza.Repo {
+			info := StanzaRepoInfo{
+				Repo:    "repo" + strconv.Itoa(repo.Key),
+				Code:    repo.Status.Code,
+				Message: repo.Status.Message,
+			}
+
+			// The PostgreSQL cluster with the highest ID is the current one.
+			current := -1
+			for _, db := range stanza.DB {
+				if db.RepoKey == repo.Key && db.ID > current {
+					current = db.ID
+					info.SystemIdentifier = db.SystemID.String()
+					info.Version = db.Version
+				}
+			}
+
+			for _, backup := range stanza.Backup {
+				if backup.Database.RepoKey != repo.Key {
+					continue
+				}
+				stop := time.Unix(backup.Timestamp.Stop, 0).UTC()
+				if info.LastBackups == nil {
+					info.LastBackups = make(map[string]time.Time)
+				}
+				if stop.After(info.LastBackups[backup.Type]) {
+					info.LastBackups[backup.Type] = stop
+					if backup.Type == "full" {
+						info.LastFullBackup = backup.Label
+					}
+				}
+				info.Size += backup.Info.Repository.Delta
+			}
+
+			result = append(result, info)
+		}
+	}
+	return result, nil
+}
+
+// StanzaUpgrade runs the pgBackRest "stanza-upgrade" command for stanza. This
+// is needed when the PostgreSQL version or system identifier no longer matches
+// the stanza, e.g. after a major upgrade.
+func (exec Executor) StanzaUpgrade(ctx context.Context, stanza string) error {
+	var stdout, stderr bytes.Buffer
+
+	err := exec(ctx, nil, &stdout, &stderr,
+		"pgbackrest", "stanza-upgrade", "--stanza="+stanza)
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%w: %v", err, stderr.String()))
+	}
+	return nil
+}
+
+// Expire runs the pgBackRest "expire" command for stanza in repoName. It
+// removes the backups and WAL that the retention options of repoName allow.
+func (exec Executor) Expire(ctx context.Context, stanza, repoName string) error {
+	var stdout, stderr bytes.Buffer
+
+	err := exec(ctx, nil, &stdout, &stderr, "pgbackrest", "expire",
+		"--stanza="+stanza, "--repo="+strings.TrimPrefix(repoName, "repo"))
+	if err != nil {
+		return errors.WithStack(fmt.Errorf("%w: %v", err, stderr.String()))
+	}
+	return nil
+}
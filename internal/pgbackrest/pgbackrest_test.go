@@ -17,11 +17,13 @@ package pgbackrest
 
 import (
 	"context"
+	"errors"
 	"io"
 	"io/ioutil"
 	"os/exec"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"gotest.tools/v3/assert"
 )
@@ -62,7 +64,7 @@ fi
 		return nil
 	}
 
-	configHashMismatch, err := Executor(stanzaExec).StanzaCreate(ctx, configHash)
+	configHashMismatch, err := Executor(stanzaExec).StanzaCreate(ctx, "db", configHash)
 	assert.NilError(t, err)
 	assert.Assert(t, !configHashMismatch)
 
@@ -77,3 +79,99 @@ fi
 	output, err := cmd.CombinedOutput()
 	assert.NilError(t, err, "%q\n%s", cmd.Args, output)
 }
+
+func TestStanzaInfo(t *testing.T) {
+	ctx := context.Background()
+
+	output := `[{
+  "archive": [],
+  "backup": [
+    {"database": {"id": 2, "repo-key": 1}, "info": {"repository": {"delta": 1000}},
+     "label": "20211001-010000F", "timestamp": {"start": 1633050000, "stop": 1633050100}, "type": "full"},
+    {"database": {"id": 2, "repo-key": 1}, "info": {"repository": {"delta": 20}},
+     "label": "20211001-010000F_20211002-010000I", "timestamp": {"start": 1633136400, "stop": 1633136500}, "type": "incr"},
+    {"database": {"id": 2, "repo-key": 1}, "info": {"repository": {"delta": 1100}},
+     "label": "20211003-010000F", "timestamp": {"start": 1633222800, "stop": 1633222900}, "type": "full"}
+  ],
+  "cipher": "none",
+  "db": [
+    {"id": 1, "repo-key": 1, "system-id": 6970977677138971000, "version": "12"},
+    {"id": 2, "repo-key": 1, "system-id": 6970977677138971135, "version": "13"},
+    {"id": 1, "repo-key": 2, "system-id": 6970977677138971135, "version": "13"}
+  ],
+  "name": "db",
+  "repo": [
+    {"cipher": "none", "key": 1, "status": {"code": 0, "message": "ok"}},
+    {"cipher": "none", "key": 2, "status": {"code": 2, "message": "no valid backups"}},
+    {"cipher": "none", "key": 3, "status": {"code": 1, "message": "missing stanza path"}}
+  ],
+  "status": {"code": 4, "message": "different across repos"}
+}]`
+
+	var command []string
+	info, err := Executor(func(_ context.Context, _ io.Reader, stdout, _ io.Writer, cmd ...string) error {
+		command = cmd
+		_, err := io.WriteString(stdout, output)
+		return err
+	}).StanzaInfo(ctx, "db")
+
+	assert.NilError(t, err)
+	assert.DeepEqual(t, command, []string{"pgbackrest", "info", "--stanza=db", "--output=json"})
+	assert.DeepEqual(t, info, []StanzaRepoInfo{
+		{Repo: "repo1", Code: 0, Message: "ok", SystemIdentifier: "6970977677138971135", Version: "13",
+			LastBackups: map[string]time.Time{
+				"full": time.Unix(1633222900, 0).UTC(),
+				"incr": time.Unix(1633136500, 0).UTC(),
+			},
+			LastFullBackup: "20211003-010000F",
+			Size:           2120,
+		},
+		{Repo: "repo2", Code: 2, Message: "no valid backups", SystemIdentifier: "6970977677138971135", Version: "13"},
+		{Repo: "repo3", Code: StanzaCodeMissingPath, Message: "missing stanza path"},
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		_, err := Executor(func(_ context.Context, _ io.Reader, _, stderr io.Writer, _ ...string) error {
+			_, _ = io.WriteString(stderr, "boom")
+			return errors.New("exit 1")
+		}).StanzaInfo(ctx, "db")
+		assert.ErrorContains(t, err, "boom")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := parseStanzaInfo([]byte(`{`))
+		assert.Assert(t, err != nil)
+	})
+}
+
+func TestStanzaUpgrade(t *testing.T) {
+	var command []string
+	err := Executor(func(_ context.Context, _ io.Reader, _, _ io.Writer, cmd ...string) error {
+		command = cmd
+		return nil
+	}).StanzaUpgrade(context.Background(), "db")
+
+	assert.NilError(t, err)
+	assert.DeepEqual(t, command, []string{"pgbackrest", "stanza-upgrade", "--stanza=db"})
+}
+
+func TestExpire(t *testing.T) {
+	ctx := context.Background()
+
+	var command []string
+	err := Executor(func(_ context.Context, _ io.Reader, _, _ io.Writer, cmd ...string) error {
+		command = cmd
+		return nil
+	}).Expire(ctx, "db", "repo2")
+
+	assert.NilError(t, err)
+	assert.DeepEqual(t, command, []string{"pgbackrest", "expire", "--stanza=db", "--repo=2"})
+
+	t.Run("Error", func(t *testing.T) {
+		err := Executor(func(_ context.Context, _ io.Reader, _, stderr io.Writer, _ ...string) error {
+			_, _ = io.WriteString(stderr, "boom")
+			return errors.New("exit 1")
+		}).Expire(ctx, "db", "repo1")
+		assert.ErrorContains(t, err, "boom")
+	})
+}
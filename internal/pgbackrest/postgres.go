@@ -31,21 +31,38 @@ func PostgreSQL(
 		outParameters.Mandatory = postgres.NewParameterSet()
 	}
 
+	// Without any repositories there is nowhere to send WAL files. Keep
+	// archiving enabled so that repositories can be added later without a
+	// restart, but discard every WAL file as soon as it is complete.
+	// - https://www.postgresql.org/docs/current/continuous-archiving.html
+	if !BackupsEnabled(inCluster) {
+		outParameters.Mandatory.Add("archive_mode", "on")
+		outParameters.Mandatory.Add("archive_command", "true")
+		return
+	}
+
 	// Send WAL files to all configured repositories when not in recovery.
 	// - https://pgbackrest.org/user-guide.html#quickstart/configure-archiving
 	// - https://pgbackrest.org/command.html#command-archive-push
 	// - https://www.postgresql.org/docs/current/runtime-config-wal.html
-	archive := `pgbackrest --stanza=` + DefaultStanzaName + ` archive-push "%p"`
+	archive := `pgbackrest --stanza=` + StanzaName(inCluster) + ` archive-push "%p"`
 	outParameters.Mandatory.Add("archive_mode", "on")
 	outParameters.Mandatory.Add("archive_command", archive)
 
+	// Fail every attempt to archive while fenced. PostgreSQL keeps each WAL
+	// file until it is archived, so nothing is lost when the fence is lifted.
+	// - https://www.postgresql.org/docs/current/continuous-archiving.html#BACKUP-ARCHIVING-WAL
+	if ArchiveFenced(inCluster) {
+		outParameters.Mandatory.Add("archive_command", "false")
+	}
+
 	// Fetch WAL files from any configured repository during recovery.
 	// - https://pgbackrest.org/command.html#command-archive-get
 	// - https://www.postgresql.org/docs/current/runtime-config-wal.html
-	restore := `pgbackrest --stanza=` + DefaultStanzaName + ` archive-get %f "%p"`
+	restore := `pgbackrest --stanza=` + StanzaName(inCluster) + ` archive-get %f "%p"`
 	outParameters.Mandatory.Add("restore_command", restore)
 
-	if inCluster.Spec.Standby != nil && inCluster.Spec.Standby.Enabled {
+	if inCluster.StandbyEnabled() {
 
 		// Fetch WAL files from the designated repository. The repository name
 		// is validated by the Kubernetes API, so it does not need to be quoted
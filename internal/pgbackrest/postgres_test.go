@@ -20,19 +20,31 @@ import (
 
 	"gotest.tools/v3/assert"
 
+	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/postgres"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 func TestPostgreSQLParameters(t *testing.T) {
 	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
 	parameters := new(postgres.Parameters)
 
+	// Without repositories, WAL files are discarded.
 	PostgreSQL(cluster, parameters)
 	assert.DeepEqual(t, parameters.Mandatory.AsMap(), map[string]string{
 		"archive_mode":    "on",
-		"archive_command": `pgbackrest --stanza=db archive-push "%p"`,
-		"restore_command": `pgbackrest --stanza=db archive-get %f "%p"`,
+		"archive_command": "true",
+	})
+
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{Name: "repo1"}}
+	parameters = new(postgres.Parameters)
+
+	PostgreSQL(cluster, parameters)
+	assert.DeepEqual(t, parameters.Mandatory.AsMap(), map[string]string{
+		"archive_mode":    "on",
+		"archive_command": `pgbackrest --stanza=ns1_hippo archive-push "%p"`,
+		"restore_command": `pgbackrest --stanza=ns1_hippo archive-get %f "%p"`,
 	})
 
 	cluster.Spec.Standby = &v1beta1.PostgresStandbySpec{
@@ -43,7 +55,23 @@ func TestPostgreSQLParameters(t *testing.T) {
 	PostgreSQL(cluster, parameters)
 	assert.DeepEqual(t, parameters.Mandatory.AsMap(), map[string]string{
 		"archive_mode":    "on",
-		"archive_command": `pgbackrest --stanza=db archive-push "%p"`,
-		"restore_command": `pgbackrest --stanza=db archive-get %f "%p" --repo=99`,
+		"archive_command": `pgbackrest --stanza=ns1_hippo archive-push "%p"`,
+		"restore_command": `pgbackrest --stanza=ns1_hippo archive-get %f "%p" --repo=99`,
+	})
+
+	t.Run("Fenced", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Standby = nil
+		cluster.Spec.Backups.PGBackRest.Archive = &v1beta1.PGBackRestArchivePush{
+			Fenced: initialize.Bool(true),
+		}
+		parameters := new(postgres.Parameters)
+
+		PostgreSQL(cluster, parameters)
+		assert.DeepEqual(t, parameters.Mandatory.AsMap(), map[string]string{
+			"archive_mode":    "on",
+			"archive_command": "false",
+			"restore_command": `pgbackrest --stanza=ns1_hippo archive-get %f "%p"`,
+		})
 	})
 }
@@ -135,6 +135,47 @@ func AddConfigsToPod(postgresCluster *v1beta1.PostgresCluster, template *corev1.
 	return nil
 }
 
+// AddSidecarSettingsToPod applies the pgBackRest sidecar settings of
+// postgresCluster to the SSHD container that AddSSHToPod added to template.
+// Its environment variables are also set in the SSH sessions it serves, which
+// is where pgBackRest runs for the repository host.
+func AddSidecarSettingsToPod(postgresCluster *v1beta1.PostgresCluster,
+	template *corev1.PodTemplateSpec) {
+
+	sidecars := postgresCluster.Spec.Backups.PGBackRest.Sidecars
+	if sidecars == nil || sidecars.PGBackRest == nil {
+		return
+	}
+	sidecar := sidecars.PGBackRest
+
+	names := make([]string, len(sidecar.Env))
+	for i := range sidecar.Env {
+		names[i] = sidecar.Env[i].Name
+	}
+
+	for i := range template.Spec.Containers {
+		container := &template.Spec.Containers[i]
+		if container.Name == naming.PGBackRestRepoContainerName {
+			container.Env = append(container.Env, sidecar.Env...)
+			container.Command = sshdCommand(sidecar.LogLevel,
+				logRetentionDays(postgresCluster, template), names...)
+		}
+	}
+}
+
+// logRetentionDays returns the number of days of pgBackRest log files that
+// the SSHD container of template keeps. Instance Pods rotate these files in
+// another container, so it is zero for them.
+func logRetentionDays(postgresCluster *v1beta1.PostgresCluster,
+	template *corev1.PodTemplateSpec) int32 {
+	for i := range template.Spec.Containers {
+		if template.Spec.Containers[i].Name == naming.ContainerDatabase {
+			return 0
+		}
+	}
+	return postgres.PGBackRestLogRetention(postgresCluster)
+}
+
 // AddSSHToPod populates a Pod template Spec with with the container and volumes needed to enable
 // SSH within a Pod.  It will also mount the SSH configuration to any additional containers specified.
 func AddSSHToPod(postgresCluster *v1beta1.PostgresCluster, template *corev1.PodTemplateSpec,
@@ -191,7 +232,7 @@ func AddSSHToPod(postgresCluster *v1beta1.PostgresCluster, template *corev1.PodT
 	// not necessary to run a full SSHD server, but the various SSH configs are still needed.
 	if enableSSHD {
 		container := corev1.Container{
-			Command:         []string{"/usr/sbin/sshd", "-D", "-e"},
+			Command:         sshdCommand("", logRetentionDays(postgresCluster, template)),
 			Image:           config.PGBackRestContainerImage(postgresCluster),
 			ImagePullPolicy: postgresCluster.Spec.ImagePullPolicy,
 			LivenessProbe: &corev1.Probe{
@@ -250,13 +291,13 @@ func ReplicaCreateCommand(
 	command := func(repoName string) []string {
 		return []string{
 			"pgbackrest", "restore", "--delta",
-			"--stanza=" + DefaultStanzaName,
+			"--stanza=" + StanzaName(cluster),
 			"--repo=" + strings.TrimPrefix(repoName, "repo"),
 			"--link-map=pg_wal=" + postgres.WALDirectory(cluster, instance),
 		}
 	}
 
-	if cluster.Spec.Standby != nil && cluster.Spec.Standby.Enabled {
+	if cluster.StandbyEnabled() {
 		// Patroni initializes standby clusters using the same command it uses
 		// for any replica. Assume the repository in the spec has a stanza
 		// and can be used to restore. The repository name is validated by the
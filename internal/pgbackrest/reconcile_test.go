@@ -17,6 +17,9 @@ package pgbackrest
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"gotest.tools/v3/assert"
@@ -337,6 +340,7 @@ func TestAddSSHToPod(t *testing.T) {
 					// verify proper resources are present and correct
 					assert.DeepEqual(t, c.Resources, resources)
 					assert.Equal(t, c.ImagePullPolicy, corev1.PullAlways)
+					assert.DeepEqual(t, c.Command, sshdCommand("", 0))
 				}
 				var foundVolumeMount bool
 				for _, vm := range c.VolumeMounts {
@@ -353,6 +357,66 @@ func TestAddSSHToPod(t *testing.T) {
 	}
 }
 
+func TestAddSidecarSettingsToPod(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	template := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Name: naming.ContainerDatabase},
+			{Name: naming.PGBackRestRepoContainerName, Command: sshdCommand("", 0)},
+		},
+	}}
+
+	// Nothing changes without settings.
+	AddSidecarSettingsToPod(cluster, template)
+	assert.DeepEqual(t, template.Spec.Containers[1].Command, sshdCommand("", 0))
+	assert.Assert(t, template.Spec.Containers[1].Env == nil)
+
+	cluster.Spec.Backups.PGBackRest.Sidecars = &v1beta1.PGBackRestSidecars{
+		PGBackRest: &v1beta1.PGBackRestSidecar{
+			Env: []corev1.EnvVar{
+				{Name: "PGBACKREST_IO_TIMEOUT", Value: "120"},
+				{Name: "TZ", Value: "UTC"},
+			},
+			LogLevel: "VERBOSE",
+		},
+	}
+	AddSidecarSettingsToPod(cluster, template)
+
+	sidecar := template.Spec.Containers[1]
+	assert.DeepEqual(t, sidecar.Env, cluster.Spec.Backups.PGBackRest.Sidecars.PGBackRest.Env)
+	assert.DeepEqual(t, sidecar.Command[4:], []string{
+		"sshd", "VERBOSE", "PGBACKREST_IO_TIMEOUT", "TZ",
+	})
+	assert.Assert(t, template.Spec.Containers[0].Env == nil)
+}
+
+func TestSSHDCommand(t *testing.T) {
+	shellcheck, err := exec.LookPath("shellcheck")
+	if err != nil {
+		t.Skip(`requires "shellcheck" executable`)
+	} else {
+		output, err := exec.Command(shellcheck, "--version").CombinedOutput()
+		assert.NilError(t, err)
+		t.Logf("using %q:\n%s", shellcheck, output)
+	}
+
+	command := sshdCommand("DEBUG", 7, "TZ")
+
+	// Expect a bash command with an inline script.
+	assert.DeepEqual(t, command[:3], []string{"bash", "-ceu", "--"})
+	assert.Assert(t, len(command) > 3)
+
+	// Write out that inline script.
+	dir := t.TempDir()
+	file := filepath.Join(dir, "script.bash")
+	assert.NilError(t, ioutil.WriteFile(file, []byte(command[3]), 0o600))
+
+	// Expect shellcheck to be happy.
+	cmd := exec.Command(shellcheck, "--enable=all", file)
+	output, err := cmd.CombinedOutput()
+	assert.NilError(t, err, "%q\n%s", cmd.Args, output)
+}
+
 func getContainerNames(containers []corev1.Container) []string {
 	names := make([]string, len(containers))
 	for i, c := range containers {
@@ -29,6 +29,7 @@ import (
 	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/pki"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -222,6 +223,33 @@ func SSHConfigVolumeAndMount(sshConfigMap *corev1.ConfigMap, sshSecret *corev1.S
 	container.VolumeMounts = mergeVolumeMounts(container.VolumeMounts, mount)
 }
 
+// sshdCommand returns an entrypoint that runs the SSHD service. Sessions do
+// not inherit the environment of SSHD, so the variables named in environment
+// are saved to a file in /tmp that every session reads. SSHD writes messages
+// at logLevel or INFO, when logLevel is empty. When logRetentionDays is
+// positive, the pgBackRest log files in /tmp are rotated while SSHD runs.
+func sshdCommand(logLevel string, logRetentionDays int32, environment ...string) []string {
+	script := `
+declare -r level="$1"
+shift
+for name in "$@"; do
+  if [[ -v "${name}" ]]; then printf 'export %s=%q\n' "${name}" "${!name}"; fi
+done > /tmp/sshd-environment
+/usr/sbin/sshd -D -e -o "LogLevel=${level}" &
+declare -r sshd=$!
+trap 'kill -TERM "${sshd}"' INT TERM
+exec {fd}<> <(:)
+while kill -0 "${sshd}" 2> /dev/null; do
+  read -r -t 5 -u "${fd}" || true` + postgres.RotateLogsScript(defaultLogPath, logRetentionDays) + `
+done
+wait "${sshd}"
+`
+	if logLevel == "" {
+		logLevel = "INFO"
+	}
+	return append([]string{"bash", "-ceu", "--", script, "sshd", logLevel}, environment...)
+}
+
 // getSSHDConfigString returns a string consisting of the basic required configuration
 // for the SSHD service
 func getSSHDConfigString() string {
@@ -229,8 +257,9 @@ func getSSHDConfigString() string {
 	// please note that the ForceCommand setting ensures nss_wrapper env vars are set when
 	// executing commands as required for OpenShift compatibility:
 	// https://access.redhat.com/articles/4859371
+	// It also sets the variables that sshdCommand saved, if any.
 	configString := `AuthorizedKeysFile /etc/ssh/id_ecdsa.pub
-ForceCommand NSS_WRAPPER_SUBDIR=postgres . /opt/crunchy/bin/nss_wrapper_env.sh && $SSH_ORIGINAL_COMMAND
+ForceCommand NSS_WRAPPER_SUBDIR=postgres . /opt/crunchy/bin/nss_wrapper_env.sh && { [ ! -f /tmp/sshd-environment ] || . /tmp/sshd-environment; } && $SSH_ORIGINAL_COMMAND
 HostKey /etc/ssh/id_ecdsa
 PasswordAuthentication no
 PermitRootLogin no
@@ -271,7 +300,7 @@ func getKeys() (sshKey, error) {
 	if err != nil {
 		return sshKey{}, err
 	}
-	keys.Public, err = getECDSAPublicKey(&pkiPriv.PrivateKey.PublicKey)
+	keys.Public, err = getECDSAPublicKey(&ecdsaPriv.PublicKey)
 	if err != nil {
 		return sshKey{}, err
 	}
@@ -0,0 +1,84 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgbackrest
+
+import (
+	"path"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// autoStanzaName is the value of the "stanza" field that names the stanza
+// after the namespace and name of the cluster.
+const autoStanzaName = "auto"
+
+// StanzaName returns the name of the pgBackRest stanza of cluster. Unless
+// otherwise specified, the stanza is named after the namespace and name of
+// cluster so that clusters sharing a cloud repository use different stanzas.
+// The name is kept in status so it does not change with the default. Clusters
+// that reported repositories before the name was kept use DefaultStanzaName.
+func StanzaName(cluster *v1beta1.PostgresCluster) string {
+	status := cluster.Status.PGBackRest
+
+	switch stanza := cluster.Spec.Backups.PGBackRest.Stanza; {
+	case stanza == autoStanzaName:
+		return uniqueStanzaName(cluster)
+	case stanza != "":
+		return stanza
+	case status != nil && status.Stanza != "":
+		return status.Stanza
+	case status != nil && len(status.Repos) > 0:
+		return DefaultStanzaName
+	default:
+		return uniqueStanzaName(cluster)
+	}
+}
+
+// uniqueStanzaName returns a stanza name that is different for every cluster
+// in the Kubernetes cluster. Namespaces and names cannot contain underscores.
+func uniqueStanzaName(cluster *v1beta1.PostgresCluster) string {
+	return cluster.Namespace + "_" + cluster.Name
+}
+
+// StanzaLocations returns a string that identifies the stanza of cluster in
+// each of its cloud repositories. Volume repositories belong to one cluster
+// and are not included. When two clusters have a location in common, they
+// write to the same stanza.
+func StanzaLocations(cluster *v1beta1.PostgresCluster) []string {
+	var locations []string
+	global := cluster.Spec.Backups.PGBackRest.Global
+	stanza := StanzaName(cluster)
+
+	for _, repo := range cluster.Spec.Backups.PGBackRest.Repos {
+		repoPath := defaultRepo1Path + repo.Name
+		if value, ok := global[repo.Name+"-path"]; ok {
+			repoPath = value
+		}
+		repoPath = path.Join("/", repoPath, stanza)
+
+		switch {
+		case repo.S3 != nil:
+			locations = append(locations,
+				"s3://"+repo.S3.Endpoint+"/"+repo.S3.Bucket+repoPath)
+		case repo.GCS != nil:
+			locations = append(locations, "gcs://"+repo.GCS.Bucket+repoPath)
+		case repo.Azure != nil:
+			locations = append(locations, "azure://"+repo.Azure.Container+repoPath)
+		}
+	}
+
+	return locations
+}
@@ -0,0 +1,90 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgbackrest
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestStanzaName(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name = "some-ns", "hippo"
+
+	assert.Equal(t, StanzaName(cluster), "some-ns_hippo")
+
+	cluster.Spec.Backups.PGBackRest.Stanza = "custom"
+	assert.Equal(t, StanzaName(cluster), "custom")
+
+	cluster.Spec.Backups.PGBackRest.Stanza = "auto"
+	assert.Equal(t, StanzaName(cluster), "some-ns_hippo")
+
+	t.Run("Status", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Backups.PGBackRest.Stanza = ""
+		cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{Stanza: "kept"}
+		assert.Equal(t, StanzaName(cluster), "kept")
+
+		cluster.Spec.Backups.PGBackRest.Stanza = "custom"
+		assert.Equal(t, StanzaName(cluster), "custom")
+	})
+
+	t.Run("Existing", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Backups.PGBackRest.Stanza = ""
+		cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+			Repos: []v1beta1.RepoStatus{{Name: "repo1", StanzaCreated: true}},
+		}
+		assert.Equal(t, StanzaName(cluster), "db")
+	})
+}
+
+func TestStanzaLocations(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name = "some-ns", "hippo"
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{
+		{Name: "repo1", Volume: &v1beta1.RepoPVC{}},
+		{Name: "repo2", S3: &v1beta1.RepoS3{Bucket: "bucket", Endpoint: "s3.example.com"}},
+		{Name: "repo3", GCS: &v1beta1.RepoGCS{Bucket: "bucket"}},
+		{Name: "repo4", Azure: &v1beta1.RepoAzure{Container: "container"}},
+	}
+
+	assert.DeepEqual(t, StanzaLocations(cluster), []string{
+		"s3://s3.example.com/bucket/pgbackrest/repo2/some-ns_hippo",
+		"gcs://bucket/pgbackrest/repo3/some-ns_hippo",
+		"azure://container/pgbackrest/repo4/some-ns_hippo",
+	})
+
+	t.Run("CustomPath", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Backups.PGBackRest.Global = map[string]string{
+			"repo2-path": "/shared/",
+		}
+
+		assert.DeepEqual(t, StanzaLocations(cluster)[0],
+			"s3://s3.example.com/bucket/shared/some-ns_hippo")
+	})
+
+	t.Run("VolumesOnly", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Backups.PGBackRest.Repos = cluster.Spec.Backups.PGBackRest.Repos[:1]
+
+		assert.Assert(t, StanzaLocations(cluster) == nil)
+	})
+}
@@ -30,6 +30,19 @@ import (
 // multi-repository solution implemented within pgBackRest
 const maxPGBackrestRepos = 4
 
+// BackupsEnabled determines whether or not pgBackRest is configured for the provided
+// PostgresCluster, i.e. whether or not any pgBackRest repositories are defined in its spec
+func BackupsEnabled(postgresCluster *v1beta1.PostgresCluster) bool {
+	return len(postgresCluster.Spec.Backups.PGBackRest.Repos) > 0
+}
+
+// ArchiveFenced returns whether or not postgresCluster is configured to stop
+// sending WAL files to its repositories.
+func ArchiveFenced(postgresCluster *v1beta1.PostgresCluster) bool {
+	archive := postgresCluster.Spec.Backups.PGBackRest.Archive
+	return archive != nil && archive.Fenced != nil && *archive.Fenced
+}
+
 // DedicatedRepoHostEnabled determines whether not a pgBackRest dedicated repository host is
 // enabled according to the provided PostgresCluster
 func DedicatedRepoHostEnabled(postgresCluster *v1beta1.PostgresCluster) bool {
@@ -41,6 +54,13 @@ func DedicatedRepoHostEnabled(postgresCluster *v1beta1.PostgresCluster) bool {
 	return false
 }
 
+// StandbyRepoHostEnabled determines whether not a standby pgBackRest repository host is
+// enabled according to the provided PostgresCluster
+func StandbyRepoHostEnabled(postgresCluster *v1beta1.PostgresCluster) bool {
+	repoHost := postgresCluster.Spec.Backups.PGBackRest.RepoHost
+	return DedicatedRepoHostEnabled(postgresCluster) && repoHost != nil && repoHost.Standby != nil
+}
+
 // CalculateConfigHashes calculates hashes for any external pgBackRest repository configuration
 // present in the PostgresCluster spec (e.g. configuration for Azure, GCR and/or S3 repositories).
 // Additionally it returns a hash of the hashes for each external repository.
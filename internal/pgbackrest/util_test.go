@@ -24,9 +24,70 @@ import (
 	"gotest.tools/v3/assert"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
+func TestBackupsEnabled(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	assert.Assert(t, !BackupsEnabled(cluster))
+
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{Name: "repo1"}}
+	assert.Assert(t, BackupsEnabled(cluster))
+}
+
+func TestArchiveFenced(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	assert.Assert(t, !ArchiveFenced(cluster))
+
+	cluster.Spec.Backups.PGBackRest.Archive = &v1beta1.PGBackRestArchivePush{}
+	assert.Assert(t, !ArchiveFenced(cluster))
+
+	cluster.Spec.Backups.PGBackRest.Archive.Fenced = initialize.Bool(false)
+	assert.Assert(t, !ArchiveFenced(cluster))
+
+	cluster.Spec.Backups.PGBackRest.Archive.Fenced = initialize.Bool(true)
+	assert.Assert(t, ArchiveFenced(cluster))
+}
+
+func TestDedicatedRepoHostEnabled(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	assert.Assert(t, !DedicatedRepoHostEnabled(cluster))
+
+	// Clusters with only cloud repositories back up directly from instances.
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{
+		{Name: "repo1", S3: &v1beta1.RepoS3{}},
+		{Name: "repo2", GCS: &v1beta1.RepoGCS{}},
+		{Name: "repo3", Azure: &v1beta1.RepoAzure{}},
+	}
+	assert.Assert(t, !DedicatedRepoHostEnabled(cluster))
+
+	// A repository volume needs a host to mount it.
+	cluster.Spec.Backups.PGBackRest.Repos = append(cluster.Spec.Backups.PGBackRest.Repos,
+		v1beta1.PGBackRestRepo{Name: "repo4", Volume: &v1beta1.RepoPVC{}})
+	assert.Assert(t, DedicatedRepoHostEnabled(cluster))
+}
+
+func TestStandbyRepoHostEnabled(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Backups.PGBackRest.RepoHost = &v1beta1.PGBackRestRepoHost{
+		Standby: &v1beta1.PGBackRestStandbyRepoHost{},
+	}
+
+	// A standby copies repository volumes, so it needs at least one.
+	cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{
+		{Name: "repo1", S3: &v1beta1.RepoS3{}},
+	}
+	assert.Assert(t, !StandbyRepoHostEnabled(cluster))
+
+	cluster.Spec.Backups.PGBackRest.Repos = append(cluster.Spec.Backups.PGBackRest.Repos,
+		v1beta1.PGBackRestRepo{Name: "repo2", Volume: &v1beta1.RepoPVC{}})
+	assert.Assert(t, StandbyRepoHostEnabled(cluster))
+
+	cluster.Spec.Backups.PGBackRest.RepoHost.Standby = nil
+	assert.Assert(t, !StandbyRepoHostEnabled(cluster))
+}
+
 func TestCalculateConfigHashes(t *testing.T) {
 
 	hashFunc := func(opts []string) (string, error) {
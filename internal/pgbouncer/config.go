@@ -16,13 +16,17 @@
 package pgbouncer
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -33,6 +37,10 @@ const (
 	emptyFileAbsolutePath = configDirectory + "/" + emptyFileProjectionPath
 	iniFileAbsolutePath   = configDirectory + "/" + iniFileProjectionPath
 
+	logDirectory        = "/var/log/pgbouncer"
+	logFileAbsolutePath = logDirectory + "/pgbouncer.log"
+	logFilePattern      = logDirectory + "/*.log"
+
 	authFileProjectionPath  = "~postgres-operator/users.txt"
 	emptyFileProjectionPath = "pgbouncer.ini"
 	iniFileProjectionPath   = "~postgres-operator.ini"
@@ -71,6 +79,14 @@ func (vs iniValueSet) String() string {
 	return b.String()
 }
 
+// iniBool returns the PgBouncer representation of b.
+func iniBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
 // authFileContents returns a PgBouncer user database.
 func authFileContents(password string) []byte {
 	// > There should be at least 2 fields, surrounded by double quotes.
@@ -135,6 +151,22 @@ func clusterINI(cluster *v1beta1.PostgresCluster) string {
 		"unix_socket_dir": "",
 	}
 
+	// Apply logging settings from the spec.
+	if logging := cluster.Spec.Logging; logging != nil && logging.PGBouncer != nil {
+		if logging.PGBouncer.Destination == "file" {
+			global["logfile"] = logFileAbsolutePath
+		}
+		if v := logging.PGBouncer.LogConnections; v != nil {
+			global["log_connections"] = iniBool(*v)
+		}
+		if v := logging.PGBouncer.LogDisconnections; v != nil {
+			global["log_disconnections"] = iniBool(*v)
+		}
+		if v := logging.PGBouncer.StatsPeriod; v != nil {
+			global["stats_period"] = fmt.Sprint(*v)
+		}
+	}
+
 	// Override the above with any specified settings.
 	for k, v := range cluster.Spec.Proxy.PGBouncer.Config.Global {
 		global[k] = v
@@ -143,6 +175,23 @@ func clusterINI(cluster *v1beta1.PostgresCluster) string {
 	// Prevent the user from bypassing the main configuration file.
 	global["conffile"] = iniFileAbsolutePath
 
+	// Enforce the TLS policy of the cluster on both sides of PgBouncer.
+	// - https://www.pgbouncer.org/config.html#tls-settings
+	if policy := postgres.TLSPolicy(cluster); policy != nil {
+		if policy.MinVersion != "" {
+			protocols := "tlsv1.2,tlsv1.3"
+			if policy.MinVersion == "TLSv1.3" {
+				protocols = "tlsv1.3"
+			}
+			global["client_tls_protocols"] = protocols
+			global["server_tls_protocols"] = protocols
+		}
+		if policy.Ciphers != "" {
+			global["client_tls_ciphers"] = policy.Ciphers
+			global["server_tls_ciphers"] = policy.Ciphers
+		}
+	}
+
 	// Use a wildcard to automatically create connection pools based on database
 	// names. These pools connect to cluster's primary service. The service name
 	// is an RFC 1123 DNS label so it does not need to be quoted nor escaped.
@@ -193,10 +242,53 @@ func clusterINI(cluster *v1beta1.PostgresCluster) string {
 	return result
 }
 
+// poolConfigMapKey returns the ConfigMap key of the configuration file of pool.
+func poolConfigMapKey(pool string) string { return "pgbouncer-" + pool + ".ini" }
+
+// poolFileProjectionPath returns the path of the configuration file of pool
+// within the configuration volume.
+func poolFileProjectionPath(pool string) string {
+	return "~postgres-operator-" + pool + ".ini"
+}
+
+// poolFileAbsolutePath returns the absolute path of the configuration file
+// of pool.
+func poolFileAbsolutePath(pool string) string {
+	return configDirectory + "/" + poolFileProjectionPath(pool)
+}
+
+// poolINI returns the configuration file of a PgBouncer process that serves
+// pool. It includes the main configuration file and then replaces the port and
+// pool mode, so that every pool has the same databases, users, and TLS.
+func poolINI(cluster *v1beta1.PostgresCluster, pool v1beta1.PGBouncerPoolSpec) string {
+	global := iniValueSet{
+		"listen_port": fmt.Sprint(pool.Port),
+
+		// Reload this file rather than the main one.
+		"conffile": poolFileAbsolutePath(pool.Name),
+	}
+
+	if len(pool.PoolMode) > 0 {
+		global["pool_mode"] = pool.PoolMode
+	}
+
+	// Each process writes its own log file.
+	if LogFileEnabled(cluster) {
+		global["logfile"] = logDirectory + "/pgbouncer-" + pool.Name + ".log"
+	}
+
+	// The main file ends in a section other than "pgbouncer", so start that
+	// section again before applying the above.
+	return iniGeneratedWarning +
+		"\n[pgbouncer]" +
+		"\n%include " + iniFileAbsolutePath +
+		"\n\n[pgbouncer]\n" + global.String()
+}
+
 // podConfigFiles returns projections of PgBouncer's configuration files to
 // include in the configuration volume.
 func podConfigFiles(
-	config v1beta1.PGBouncerConfiguration,
+	config v1beta1.PGBouncerConfiguration, pools []v1beta1.PGBouncerPoolSpec,
 	configmap *corev1.ConfigMap, secret *corev1.Secret,
 ) []corev1.VolumeProjection {
 	// Start with an empty file at /etc/pgbouncer/pgbouncer.ini. This file can
@@ -221,16 +313,24 @@ func podConfigFiles(
 	projections = append(projections, config.Files...)
 
 	// Add our non-empty configurations last so that they take precedence.
+	items := []corev1.KeyToPath{{
+		Key:  iniFileConfigMapKey,
+		Path: iniFileProjectionPath,
+	}}
+	for _, pool := range pools {
+		items = append(items, corev1.KeyToPath{
+			Key:  poolConfigMapKey(pool.Name),
+			Path: poolFileProjectionPath(pool.Name),
+		})
+	}
+
 	projections = append(projections, []corev1.VolumeProjection{
 		{
 			ConfigMap: &corev1.ConfigMapProjection{
 				LocalObjectReference: corev1.LocalObjectReference{
 					Name: configmap.Name,
 				},
-				Items: []corev1.KeyToPath{{
-					Key:  iniFileConfigMapKey,
-					Path: iniFileProjectionPath,
-				}},
+				Items: items,
 			},
 		},
 		{
@@ -275,3 +375,39 @@ done
 
 	return []string{"bash", "-ceu", "--", wrapper, name, configDirectory}
 }
+
+// ConfigRevision returns a value that changes when the configuration files or
+// the user database in configmap and secret change. See ReloadCommand.
+func ConfigRevision(
+	cluster *v1beta1.PostgresCluster, configmap *corev1.ConfigMap, secret *corev1.Secret,
+) string {
+	hash := sha256.New()
+	_, _ = io.WriteString(hash, configmap.Data[iniFileConfigMapKey])
+	for _, pool := range cluster.Spec.Proxy.PGBouncer.Pools {
+		_, _ = io.WriteString(hash, configmap.Data[poolConfigMapKey(pool.Name)])
+	}
+	_, _ = hash.Write(secret.Data[authFileSecretKey])
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// ReloadCommand returns a command that signals PgBouncer to reload its
+// configuration files once the files mounted in its Pod match revision. It
+// prints "reloaded" when it does. The signal is the same as the RELOAD command
+// of the admin console, which cannot be reached without a password because
+// Unix sockets are disabled. It must run in a container that mounts the
+// configuration volume and shares a process namespace with PgBouncer. Every
+// PgBouncer process in the Pod, including those of pools, receives the signal.
+// - https://www.pgbouncer.org/usage.html#signals
+func ReloadCommand(cluster *v1beta1.PostgresCluster, revision string) []string {
+	const script = `
+if [ "$(cat "${@:1:$#-1}" | sha256sum)" = "${!#}  -" ]; then
+  pkill --signal HUP --exact pgbouncer && echo reloaded
+fi
+`
+	// The files are in the same order as in ConfigRevision.
+	command := []string{"bash", "-ceu", "--", script, "-", iniFileAbsolutePath}
+	for _, pool := range cluster.Spec.Proxy.PGBouncer.Pools {
+		command = append(command, poolFileAbsolutePath(pool.Name))
+	}
+	return append(command, authFileAbsolutePath, revision)
+}
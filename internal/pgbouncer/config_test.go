@@ -16,6 +16,8 @@
 package pgbouncer
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"io/ioutil"
 	"os/exec"
 	"path/filepath"
@@ -27,6 +29,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 
+	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -143,6 +146,94 @@ app = mode=rad
 		cluster.Spec.Proxy.PGBouncer.Config.Global["conffile"] = "too-far"
 		assert.Assert(t, !strings.Contains(clusterINI(cluster), "too-far"))
 	})
+
+	t.Run("Logging", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Config = v1beta1.PGBouncerConfiguration{}
+		cluster.Spec.Logging = &v1beta1.PostgresLoggingSpec{
+			PGBouncer: &v1beta1.PGBouncerLoggingSpec{
+				Destination:       "file",
+				LogConnections:    initialize.Bool(false),
+				LogDisconnections: initialize.Bool(true),
+				StatsPeriod:       initialize.Int32(300),
+			},
+		}
+
+		ini := clusterINI(cluster)
+		assert.Assert(t, strings.Contains(ini, "\nlog_connections = 0\n"), "got %q", ini)
+		assert.Assert(t, strings.Contains(ini, "\nlog_disconnections = 1\n"), "got %q", ini)
+		assert.Assert(t, strings.Contains(ini, "\nlogfile = /var/log/pgbouncer/pgbouncer.log\n"), "got %q", ini)
+		assert.Assert(t, strings.Contains(ini, "\nstats_period = 300\n"), "got %q", ini)
+
+		// Settings in the PgBouncer spec take precedence.
+		cluster.Spec.Proxy.PGBouncer.Config.Global = map[string]string{"stats_period": "10"}
+		assert.Assert(t, strings.Contains(clusterINI(cluster), "\nstats_period = 10\n"))
+	})
+
+	t.Run("TLSPolicy", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Config = v1beta1.PGBouncerConfiguration{}
+		cluster.Spec.Config = &v1beta1.PostgresConfigSpec{
+			TLS: &v1beta1.PostgresTLSSpec{
+				MinVersion: "TLSv1.3",
+				Ciphers:    "HIGH:!aNULL",
+			},
+		}
+
+		ini := clusterINI(cluster)
+		assert.Assert(t, strings.Contains(ini, "\nclient_tls_protocols = tlsv1.3\n"), "got %q", ini)
+		assert.Assert(t, strings.Contains(ini, "\nserver_tls_protocols = tlsv1.3\n"), "got %q", ini)
+		assert.Assert(t, strings.Contains(ini, "\nclient_tls_ciphers = HIGH:!aNULL\n"), "got %q", ini)
+		assert.Assert(t, strings.Contains(ini, "\nserver_tls_ciphers = HIGH:!aNULL\n"), "got %q", ini)
+
+		cluster.Spec.Config.TLS.MinVersion = "TLSv1.2"
+		ini = clusterINI(cluster)
+		assert.Assert(t, strings.Contains(ini, "\nclient_tls_protocols = tlsv1.2,tlsv1.3\n"), "got %q", ini)
+
+		// The policy cannot be weakened by settings in the PgBouncer spec.
+		cluster.Spec.Proxy.PGBouncer.Config.Global = map[string]string{"client_tls_protocols": "all"}
+		assert.Assert(t, !strings.Contains(clusterINI(cluster), "= all\n"))
+	})
+}
+
+func TestPoolINI(t *testing.T) {
+	t.Parallel()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+
+	pool := v1beta1.PGBouncerPoolSpec{Name: "tx", Port: 6432, PoolMode: "transaction"}
+
+	assert.Equal(t, poolINI(cluster, pool), strings.Trim(`
+# Generated by postgres-operator. DO NOT EDIT.
+# Your changes will not be saved.
+
+[pgbouncer]
+%include /etc/pgbouncer/~postgres-operator.ini
+
+[pgbouncer]
+conffile = /etc/pgbouncer/~postgres-operator-tx.ini
+listen_port = 6432
+pool_mode = transaction
+	`, "\t\n")+"\n")
+
+	t.Run("DefaultPoolMode", func(t *testing.T) {
+		pool := pool
+		pool.PoolMode = ""
+		assert.Assert(t, !strings.Contains(poolINI(cluster, pool), "pool_mode"))
+	})
+
+	t.Run("Logging", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Logging = &v1beta1.PostgresLoggingSpec{
+			PGBouncer: &v1beta1.PGBouncerLoggingSpec{Destination: "file"},
+		}
+
+		ini := poolINI(cluster, pool)
+		assert.Assert(t, strings.Contains(ini, "\nlogfile = /var/log/pgbouncer/pgbouncer-tx.log\n"), "got %q", ini)
+		assert.Assert(t, filepath.Base(LogFilePattern()) == "*.log")
+	})
 }
 
 func TestPodConfigFiles(t *testing.T) {
@@ -153,7 +244,7 @@ func TestPodConfigFiles(t *testing.T) {
 	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "some-shh"}}
 
 	t.Run("Default", func(t *testing.T) {
-		projections := podConfigFiles(config, configmap, secret)
+		projections := podConfigFiles(config, nil, configmap, secret)
 		assert.Assert(t, marshalEquals(projections, strings.Trim(`
 - configMap:
     items:
@@ -186,7 +277,7 @@ func TestPodConfigFiles(t *testing.T) {
 			}},
 		}
 
-		projections := podConfigFiles(config, configmap, secret)
+		projections := podConfigFiles(config, nil, configmap, secret)
 		assert.Assert(t, marshalEquals(projections, strings.Trim(`
 - configMap:
     items:
@@ -212,6 +303,23 @@ func TestPodConfigFiles(t *testing.T) {
     name: some-shh
 		`, "\t\n")+"\n"))
 	})
+
+	t.Run("Pools", func(t *testing.T) {
+		pools := []v1beta1.PGBouncerPoolSpec{{Name: "one"}, {Name: "two"}}
+
+		projections := podConfigFiles(v1beta1.PGBouncerConfiguration{}, pools, configmap, secret)
+		assert.Assert(t, marshalEquals(projections[1], strings.Trim(`
+configMap:
+  items:
+  - key: pgbouncer.ini
+    path: ~postgres-operator.ini
+  - key: pgbouncer-one.ini
+    path: ~postgres-operator-one.ini
+  - key: pgbouncer-two.ini
+    path: ~postgres-operator-two.ini
+  name: some-cm
+		`, "\t\n")+"\n"))
+	})
 }
 
 func TestReloadCommand(t *testing.T) {
@@ -240,3 +348,73 @@ func TestReloadCommand(t *testing.T) {
 	output, err := cmd.CombinedOutput()
 	assert.NilError(t, err, "%q\n%s", cmd.Args, output)
 }
+
+func TestConfigRevision(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+
+	configmap := &corev1.ConfigMap{Data: map[string]string{"pgbouncer.ini": "[pgbouncer]\n"}}
+	secret := &corev1.Secret{Data: map[string][]byte{"pgbouncer-users.txt": []byte(`"a" "b"` + "\n")}}
+
+	before := ConfigRevision(cluster, configmap, secret)
+	assert.Equal(t, len(before), 64)
+	assert.Equal(t, before, ConfigRevision(cluster, configmap, secret))
+
+	// The revision matches what "sha256sum" prints for both files together.
+	hash := sha256.Sum256([]byte("[pgbouncer]\n" + `"a" "b"` + "\n"))
+	assert.Equal(t, before, hex.EncodeToString(hash[:]))
+
+	// Other keys do not matter.
+	configmap.Data["pgbouncer-empty"] = "x"
+	assert.Equal(t, before, ConfigRevision(cluster, configmap, secret))
+
+	secret.Data["pgbouncer-users.txt"] = []byte(`"a" "c"` + "\n")
+	assert.Assert(t, before != ConfigRevision(cluster, configmap, secret))
+
+	t.Run("Pools", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Pools = []v1beta1.PGBouncerPoolSpec{{Name: "tx"}}
+		configmap := configmap.DeepCopy()
+		configmap.Data["pgbouncer-tx.ini"] = "[tx]\n"
+
+		// The pool file is between the main file and the user database.
+		hash := sha256.Sum256([]byte("[pgbouncer]\n" + "[tx]\n" + `"a" "c"` + "\n"))
+		assert.Equal(t, ConfigRevision(cluster, configmap, secret), hex.EncodeToString(hash[:]))
+	})
+}
+
+func TestReloadCommandRevision(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+
+	command := ReloadCommand(cluster, "abc")
+	assert.DeepEqual(t, command[:3], []string{"bash", "-ceu", "--"})
+	assert.DeepEqual(t, command[4:], []string{"-",
+		"/etc/pgbouncer/~postgres-operator.ini",
+		"/etc/pgbouncer/~postgres-operator/users.txt",
+		"abc",
+	})
+
+	cluster.Spec.Proxy.PGBouncer.Pools = []v1beta1.PGBouncerPoolSpec{{Name: "tx"}}
+	assert.DeepEqual(t, ReloadCommand(cluster, "abc")[4:], []string{"-",
+		"/etc/pgbouncer/~postgres-operator.ini",
+		"/etc/pgbouncer/~postgres-operator-tx.ini",
+		"/etc/pgbouncer/~postgres-operator/users.txt",
+		"abc",
+	})
+
+	shellcheck, err := exec.LookPath("shellcheck")
+	if err != nil {
+		t.Skip(`requires "shellcheck" executable`)
+	}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "script.bash")
+	assert.NilError(t, ioutil.WriteFile(file, []byte(command[3]), 0o600))
+
+	cmd := exec.Command(shellcheck, "--enable=all", "--shell=bash", file)
+	output, err := cmd.CombinedOutput()
+	assert.NilError(t, err, "%q\n%s", cmd.Args, output)
+}
@@ -22,6 +22,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 
+	"github.com/crunchydata/postgres-operator/internal/bluegreen"
 	"github.com/crunchydata/postgres-operator/internal/config"
 	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/naming"
@@ -44,6 +45,10 @@ func ConfigMap(
 
 	outConfigMap.Data[emptyConfigMapKey] = ""
 	outConfigMap.Data[iniFileConfigMapKey] = clusterINI(inCluster)
+
+	for _, pool := range inCluster.Spec.Proxy.PGBouncer.Pools {
+		outConfigMap.Data[poolConfigMapKey(pool.Name)] = poolINI(inCluster, pool)
+	}
 }
 
 // Secret populates the PgBouncer Secret.
@@ -86,6 +91,13 @@ func Secret(ctx context.Context,
 		leaf.DNSNames = naming.ServiceDNSNames(ctx, inService)
 		leaf.CommonName = leaf.DNSNames[0] // FQDN
 
+		// Green takes over the PgBouncer Service of blue in a blue/green switch.
+		if bluegreen.Enabled(inCluster) {
+			leaf.DNSNames = append(leaf.DNSNames, naming.ServiceDNSNames(ctx, &corev1.Service{
+				ObjectMeta: naming.ClusterPGBouncer(bluegreen.Source(inCluster)),
+			})...)
+		}
+
 		if err == nil {
 			var parse error
 			if data, ok := inSecret.Data[certFrontendSecretKey]; parse == nil && ok {
@@ -144,7 +156,8 @@ func Pod(
 	configVol := corev1.Volume{Name: "pgbouncer-config"}
 	configVol.Projected = &corev1.ProjectedVolumeSource{
 		Sources: podConfigFiles(
-			inCluster.Spec.Proxy.PGBouncer.Config, inConfigMap, inSecret),
+			inCluster.Spec.Proxy.PGBouncer.Config, inCluster.Spec.Proxy.PGBouncer.Pools,
+			inConfigMap, inSecret),
 	}
 
 	container := corev1.Container{
@@ -221,7 +234,64 @@ func Pod(
 
 	outPod.Containers = []corev1.Container{container, reloader}
 
+	// Run another PgBouncer process for each pool. These have the same image,
+	// resources, and files as the first.
+	for _, pool := range inCluster.Spec.Proxy.PGBouncer.Pools {
+		process := *container.DeepCopy()
+		process.Name = poolContainerName(pool.Name)
+		process.Command = []string{"pgbouncer", poolFileAbsolutePath(pool.Name)}
+		process.Ports = []corev1.ContainerPort{{
+			ContainerPort: pool.Port,
+			Protocol:      corev1.ProtocolTCP,
+		}}
+		outPod.Containers = append(outPod.Containers, process)
+	}
+
 	outPod.Volumes = []corev1.Volume{backend, configVol, frontend}
+
+	// Write the log file to a separate volume when requested.
+	if LogFileEnabled(inCluster) {
+		logVolumeMount := LogVolumeMount()
+		logVolume := corev1.Volume{
+			Name: logVolumeMount.Name,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		}
+		if inCluster.Spec.Logging.Volume != nil {
+			logVolume.EmptyDir.SizeLimit = inCluster.Spec.Logging.Volume.SizeLimit
+		}
+
+		for i := range outPod.Containers {
+			if outPod.Containers[i].Name != naming.ContainerPGBouncerConfig {
+				outPod.Containers[i].VolumeMounts = append(outPod.Containers[i].VolumeMounts, logVolumeMount)
+			}
+		}
+		outPod.Volumes = append(outPod.Volumes, logVolume)
+	}
+}
+
+// LogFileEnabled returns whether or not PgBouncer of cluster writes a log file.
+func LogFileEnabled(cluster *v1beta1.PostgresCluster) bool {
+	return cluster.Spec.Proxy != nil && cluster.Spec.Proxy.PGBouncer != nil &&
+		cluster.Spec.Logging != nil && cluster.Spec.Logging.PGBouncer != nil &&
+		cluster.Spec.Logging.PGBouncer.Destination == "file"
+}
+
+// LogFilePattern returns a glob that matches the PgBouncer log files.
+func LogFilePattern() string {
+	return logFilePattern
+}
+
+// poolContainerName returns the name of the container that runs PgBouncer for
+// pool.
+func poolContainerName(pool string) string {
+	return naming.ContainerPGBouncer + "-pool-" + pool
+}
+
+// LogVolumeMount returns the name and mount path of the PgBouncer log volume.
+func LogVolumeMount() corev1.VolumeMount {
+	return corev1.VolumeMount{Name: "pgbouncer-logs", MountPath: logDirectory}
 }
 
 // PostgreSQL populates outHBAs with any records needed to run PgBouncer.
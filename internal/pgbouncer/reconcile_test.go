@@ -57,6 +57,19 @@ func TestConfigMap(t *testing.T) {
 	before := config.DeepCopy()
 	ConfigMap(cluster, config)
 	assert.DeepEqual(t, before, config)
+
+	t.Run("Pools", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Pools = []v1beta1.PGBouncerPoolSpec{
+			{Name: "tx", Port: 6432, PoolMode: "transaction"},
+		}
+
+		config := new(corev1.ConfigMap)
+		ConfigMap(cluster, config)
+
+		assert.DeepEqual(t, config.Data["pgbouncer-tx.ini"],
+			poolINI(cluster, cluster.Spec.Proxy.PGBouncer.Pools[0]))
+	})
 }
 
 func TestSecret(t *testing.T) {
@@ -437,6 +450,65 @@ volumes:
           path: p1
         name: tls-name`, "\t\n")+"\n"))
 	})
+
+	t.Run("LogFile", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Logging = &v1beta1.PostgresLoggingSpec{
+			PGBouncer: &v1beta1.PGBouncerLoggingSpec{Destination: "file"},
+			Volume: &v1beta1.PostgresLogVolumeSpec{
+				SizeLimit: resource.NewQuantity(1<<20, resource.BinarySI),
+			},
+		}
+		assert.Assert(t, LogFileEnabled(cluster))
+
+		pod := new(corev1.PodSpec)
+		Pod(cluster, configMap, primaryCertificate, secret, pod)
+
+		mounts := pod.Containers[0].VolumeMounts
+		assert.Assert(t, marshalEquals(mounts[len(mounts)-1], strings.Trim(`
+mountPath: /var/log/pgbouncer
+name: pgbouncer-logs
+		`, "\t\n")+"\n"))
+		assert.Assert(t, marshalEquals(pod.Volumes[len(pod.Volumes)-1], strings.Trim(`
+emptyDir:
+  sizeLimit: 1Mi
+name: pgbouncer-logs
+		`, "\t\n")+"\n"))
+	})
+
+	t.Run("Pools", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Logging = &v1beta1.PostgresLoggingSpec{
+			PGBouncer: &v1beta1.PGBouncerLoggingSpec{Destination: "file"},
+		}
+		cluster.Spec.Proxy.PGBouncer.Pools = []v1beta1.PGBouncerPoolSpec{
+			{Name: "tx", Port: 6432, PoolMode: "transaction"},
+		}
+
+		pod := new(corev1.PodSpec)
+		Pod(cluster, configMap, primaryCertificate, secret, pod)
+
+		assert.Equal(t, len(pod.Containers), 3)
+		assert.Equal(t, pod.Containers[1].Name, "pgbouncer-config")
+
+		// The pool runs with its own file and port, and otherwise matches the
+		// first PgBouncer container.
+		pool := pod.Containers[2]
+		assert.Equal(t, pool.Name, "pgbouncer-pool-tx")
+		assert.DeepEqual(t, pool.Command,
+			[]string{"pgbouncer", "/etc/pgbouncer/~postgres-operator-tx.ini"})
+		assert.Assert(t, marshalEquals(pool.Ports, strings.Trim(`
+- containerPort: 6432
+  protocol: TCP
+		`, "\t\n")+"\n"))
+		assert.Equal(t, pool.Image, pod.Containers[0].Image)
+		assert.DeepEqual(t, pool.Resources, pod.Containers[0].Resources)
+		assert.DeepEqual(t, pool.VolumeMounts, pod.Containers[0].VolumeMounts)
+
+		// Both write log files.
+		mounts := pool.VolumeMounts
+		assert.Equal(t, mounts[len(mounts)-1].Name, "pgbouncer-logs")
+	})
 }
 
 func TestPostgreSQL(t *testing.T) {
@@ -0,0 +1,140 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgcron
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// JobPrefix is prepended to the name of every job that the operator keeps in
+// the cron.job table. Jobs without it belong to someone else.
+const JobPrefix = "pgo-"
+
+// Enabled returns the pg_cron specification of cluster, or nil when pg_cron
+// is not enabled.
+func Enabled(cluster *v1beta1.PostgresCluster) *v1beta1.PGCronSpec {
+	if cluster.Spec.Extensions == nil {
+		return nil
+	}
+	return cluster.Spec.Extensions.PGCron
+}
+
+// When the pg_cron shared library is not loaded, the extension cannot be
+// installed. The "CREATE EXTENSION" command fails with an error, "unrecognized
+// configuration parameter…" or "can only create extension in database…" when
+// cron.database_name names a different database.
+
+// EnableInPostgreSQL installs pg_cron into the database of spec and makes the
+// jobs in the cron.job table that have JobPrefix match the jobs of spec.
+func EnableInPostgreSQL(ctx context.Context, exec postgres.Executor, spec *v1beta1.PGCronSpec) error {
+	log := logging.FromContext(ctx)
+
+	type job struct {
+		Name     string `json:"name"`
+		Schedule string `json:"schedule"`
+		Command  string `json:"command"`
+		Database string `json:"database,omitempty"`
+	}
+	jobs := make([]job, 0, len(spec.Jobs))
+	inDatabase := false
+	for _, j := range spec.Jobs {
+		inDatabase = inDatabase || j.Database != ""
+		jobs = append(jobs, job{
+			Name:     JobPrefix + j.Name,
+			Schedule: j.Schedule,
+			Command:  j.Command,
+			Database: string(j.Database),
+		})
+	}
+	encoded, err := json.Marshal(jobs)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	script := []string{
+		// Quiet NOTICE messages from IF NOT EXISTS statements.
+		// - https://www.postgresql.org/docs/current/runtime-config-client.html
+		`SET client_min_messages = WARNING;`,
+		`CREATE EXTENSION IF NOT EXISTS pg_cron;`,
+
+		// Change all the jobs or none of them. Parse the jobs from their
+		// psql variable.
+		`BEGIN;`,
+		`CREATE TEMPORARY TABLE pgo_cron_jobs ON COMMIT DROP AS`,
+		` SELECT name, schedule, command, database FROM pg_catalog.jsonb_to_recordset(:'jobs'::jsonb)`,
+		` AS input(name text, schedule text, command text, database text);`,
+
+		// Remove jobs of the operator that are no longer specified, then
+		// add or change the others. Jobs are unique by name and user.
+		// - https://github.com/citusdata/pg_cron#what-is-pg_cron
+		`SELECT pg_catalog.count(cron.unschedule(jobid)) AS unscheduled FROM cron.job`,
+		` WHERE username = CURRENT_USER AND jobname LIKE (:'prefix' || '%')`,
+		` AND jobname NOT IN (SELECT name FROM pgo_cron_jobs);`,
+
+		// Jobs without a database run in the database of pg_cron.
+		`SELECT pg_catalog.count(cron.schedule(name, schedule, command))`,
+		` AS scheduled FROM pgo_cron_jobs WHERE database IS NULL;`,
+	}
+	if inDatabase {
+		// This function was added in pg_cron 1.4, so call it only when a job
+		// has a database.
+		// - https://github.com/citusdata/pg_cron/releases/tag/v1.4.0
+		script = append(script,
+			`SELECT pg_catalog.count(cron.schedule_in_database(name, schedule, command, database))`,
+			` AS in_database FROM pgo_cron_jobs WHERE database IS NOT NULL;`,
+		)
+	}
+	script = append(script, `COMMIT;`)
+
+	stdout, stderr, err := exec.ExecInDatabase(ctx, string(spec.Database),
+		strings.NewReader(strings.Join(script, "\n")),
+		map[string]string{
+			"jobs":   string(encoded),
+			"prefix": JobPrefix,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("enabled pg_cron", "stdout", stdout, "stderr", stderr)
+
+	return err
+}
+
+// PostgreSQLParameters sets the parameters required by pg_cron, if enabled.
+func PostgreSQLParameters(cluster *v1beta1.PostgresCluster, outParameters *postgres.Parameters) {
+	spec := Enabled(cluster)
+	if spec == nil {
+		return
+	}
+
+	// Load the shared library when PostgreSQL starts and tell its background
+	// worker where to find the cron.job table.
+	// PostgreSQL must be restarted when changing these values.
+	// - https://github.com/citusdata/pg_cron#setting-up-pg_cron
+	shared := outParameters.Mandatory.Value("shared_preload_libraries")
+	outParameters.Mandatory.Add("shared_preload_libraries",
+		strings.TrimPrefix(shared+",pg_cron", ","))
+	outParameters.Mandatory.Add("cron.database_name", string(spec.Database))
+}
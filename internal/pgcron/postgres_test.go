@@ -0,0 +1,130 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgcron
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestEnableInPostgreSQL(t *testing.T) {
+	spec := &v1beta1.PGCronSpec{
+		Database: "postgres",
+		Jobs: []v1beta1.PGCronJob{
+			{Name: "vacuum", Schedule: "0 3 * * *", Command: "VACUUM"},
+			{Name: "purge", Schedule: "30 seconds", Command: "DELETE FROM events", Database: "app"},
+		},
+	}
+
+	expected := errors.New("whoops")
+	exec := func(
+		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		assert.Assert(t, stdout != nil, "should capture stdout")
+		assert.Assert(t, stderr != nil, "should capture stderr")
+
+		// The database is the first argument after the script.
+		assert.Assert(t, len(command) > 5)
+		assert.Equal(t, command[5], "postgres")
+		assert.DeepEqual(t, command[6:], []string{
+			`--set=ON_ERROR_STOP=on`,
+			`--set=QUIET=on`,
+			`--set=jobs=[` +
+				`{"name":"pgo-vacuum","schedule":"0 3 * * *","command":"VACUUM"},` +
+				`{"name":"pgo-purge","schedule":"30 seconds","command":"DELETE FROM events","database":"app"}]`,
+			`--set=prefix=pgo-`,
+		})
+
+		b, err := ioutil.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.HasPrefix(string(b), `SET client_min_messages = WARNING;
+CREATE EXTENSION IF NOT EXISTS pg_cron;
+BEGIN;`), "got %q", string(b))
+		assert.Assert(t, strings.Contains(string(b), `cron.unschedule(jobid)`))
+		assert.Assert(t, strings.Contains(string(b), `cron.schedule(name, schedule, command)`))
+		assert.Assert(t, strings.Contains(string(b), `cron.schedule_in_database(name, schedule, command, database)`))
+		assert.Assert(t, strings.HasSuffix(string(b), "\nCOMMIT;"))
+
+		return expected
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, expected, EnableInPostgreSQL(ctx, exec, spec))
+
+	t.Run("NoJobs", func(t *testing.T) {
+		exec := func(
+			_ context.Context, _ io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			// An empty list unschedules every job of the operator.
+			assert.Assert(t, strings.Contains(strings.Join(command, "\n"), `--set=jobs=[]`))
+			return nil
+		}
+		assert.NilError(t, EnableInPostgreSQL(ctx, exec, &v1beta1.PGCronSpec{Database: "postgres"}))
+	})
+
+	t.Run("WithoutDatabases", func(t *testing.T) {
+		exec := func(
+			_ context.Context, stdin io.Reader, _, _ io.Writer, _ ...string,
+		) error {
+			// Jobs without a database work with pg_cron before 1.4.
+			b, err := ioutil.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Assert(t, strings.Contains(string(b), `cron.schedule(name, schedule, command)`))
+			assert.Assert(t, !strings.Contains(string(b), `cron.schedule_in_database`))
+			return nil
+		}
+		assert.NilError(t, EnableInPostgreSQL(ctx, exec, &v1beta1.PGCronSpec{
+			Database: "postgres",
+			Jobs: []v1beta1.PGCronJob{
+				{Name: "vacuum", Schedule: "0 3 * * *", Command: "VACUUM"},
+			},
+		}))
+	})
+}
+
+func TestPostgreSQLParameters(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	parameters := postgres.Parameters{
+		Mandatory: postgres.NewParameterSet(),
+	}
+
+	// Nothing when pg_cron is not enabled.
+	PostgreSQLParameters(cluster, &parameters)
+	assert.DeepEqual(t, parameters.Mandatory.AsMap(), map[string]string{})
+
+	cluster.Spec.Extensions = &v1beta1.PostgresExtensionsSpec{
+		PGCron: &v1beta1.PGCronSpec{Database: "app"},
+	}
+
+	// Appended when not empty.
+	parameters.Mandatory.Add("shared_preload_libraries", "pgaudit")
+	PostgreSQLParameters(cluster, &parameters)
+
+	assert.Assert(t, parameters.Default == nil)
+	assert.DeepEqual(t, parameters.Mandatory.AsMap(), map[string]string{
+		"cron.database_name":       "app",
+		"shared_preload_libraries": "pgaudit,pg_cron",
+	})
+}
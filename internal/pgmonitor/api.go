@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/crunchydata/postgres-operator/internal/logging"
@@ -29,15 +30,38 @@ type Executor func(
 	ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
 ) error
 
+// newestExporterVersion is the newest PostgreSQL version that every supported
+// exporter image has setup.sql and queries for.
+const newestExporterVersion = 14
+
 // GetExporterSQL takes the PostgreSQL version and returns the corresponding
-// setup.sql file that is defined in the exporter container
+// setup.sql file that is defined in the exporter container. Images that predate
+// a version of PostgreSQL have nothing for it, so the newest setup.sql that is
+// not newer than version is used instead.
 func (exec Executor) GetExporterSetupSQL(ctx context.Context, version int) (string, string, error) {
 	log := logging.FromContext(ctx)
 
 	var stdout, stderr bytes.Buffer
 	var sql string
-	err := exec(ctx, nil, &stdout, &stderr,
-		[]string{"cat", fmt.Sprintf("/opt/cpm/conf/pg%d/setup.sql", version)}...)
+	var err error
+
+	if version <= newestExporterVersion {
+		err = exec(ctx, nil, &stdout, &stderr,
+			[]string{"cat", fmt.Sprintf("/opt/cpm/conf/pg%d/setup.sql", version)}...)
+	} else {
+		const script = `
+for version; do
+  file="/opt/cpm/conf/pg${version}/setup.sql"
+  if [[ -f "${file}" ]]; then exec cat "${file}"; fi
+done
+exec cat "/opt/cpm/conf/pg$1/setup.sql"
+`
+		command := []string{"bash", "-ceu", "--", script, "-"}
+		for v := version; v >= newestExporterVersion; v-- {
+			command = append(command, strconv.Itoa(v))
+		}
+		err = exec(ctx, nil, &stdout, &stderr, command...)
+	}
 
 	log.V(1).Info("sql received from exporter", "stdout", stdout.String(), "stderr", stderr.String())
 
@@ -48,6 +48,22 @@ func TestExecutorGetExporterSetupSQL(t *testing.T) {
 		assert.Assert(t, called)
 	})
 
+	t.Run("NewerVersion", func(t *testing.T) {
+		called := false
+		exec := func(
+			ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			called = true
+			assert.DeepEqual(t, command[:3], []string{"bash", "-ceu", "--"})
+			assert.DeepEqual(t, command[4:], []string{"-", "16", "15", "14"})
+			assert.Assert(t, strings.Contains(command[3], "/opt/cpm/conf/pg${version}/setup.sql"))
+			return nil
+		}
+
+		_, _, _ = Executor(exec).GetExporterSetupSQL(context.Background(), 16)
+		assert.Assert(t, called)
+	})
+
 	t.Run("Error", func(t *testing.T) {
 		expected := errors.New("boom")
 		_, _, actual := Executor(func(
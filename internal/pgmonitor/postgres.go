@@ -63,6 +63,8 @@ func PostgreSQLParameters(inCluster *v1beta1.PostgresCluster, outParameters *pos
 		outParameters.Mandatory.Add("pgnodemx.kdapi_path",
 			postgres.DownwardAPIVolumeMount().MountPath)
 	}
+
+	statisticsParameters(inCluster, outParameters)
 }
 
 // DisableExporterInPostgreSQL disables the exporter configuration in PostgreSQL.
@@ -0,0 +1,165 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgmonitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// StatementsEnabled returns true when pg_stat_statements should be loaded and
+// created in every database of cluster.
+func StatementsEnabled(cluster *v1beta1.PostgresCluster) bool {
+	if cluster.Spec.Monitoring == nil || cluster.Spec.Monitoring.QueryStatistics == nil {
+		return false
+	}
+	enabled := cluster.Spec.Monitoring.QueryStatistics.PGStatStatements
+	return enabled == nil || *enabled
+}
+
+// TopStatements returns the number of statements the exporter reports in each
+// ranking.
+func TopStatements(cluster *v1beta1.PostgresCluster) int32 {
+	if cluster.Spec.Monitoring != nil &&
+		cluster.Spec.Monitoring.QueryStatistics != nil &&
+		cluster.Spec.Monitoring.QueryStatistics.TopStatements != nil {
+		return *cluster.Spec.Monitoring.QueryStatistics.TopStatements
+	}
+	return 20
+}
+
+// EnableStatementsInPostgreSQL creates the pg_stat_statements extension in
+// every database.
+func EnableStatementsInPostgreSQL(ctx context.Context, exec postgres.Executor) error {
+	log := logging.FromContext(ctx)
+
+	stdout, stderr, err := exec.ExecInAllDatabases(ctx,
+		// Quiet the NOTICE from IF EXISTS, and create the extension.
+		// - https://www.postgresql.org/docs/current/runtime-config-client.html
+		`SET client_min_messages = WARNING; CREATE EXTENSION IF NOT EXISTS pg_stat_statements;`,
+		map[string]string{
+			"ON_ERROR_STOP": "on", // Abort when any one command fails.
+			"QUIET":         "on", // Do not print successful commands to stdout.
+		})
+
+	log.V(1).Info("enabled pg_stat_statements", "stdout", stdout, "stderr", stderr)
+
+	return err
+}
+
+// statisticsParameters sets the parameters of the query statistics spec.
+func statisticsParameters(inCluster *v1beta1.PostgresCluster, outParameters *postgres.Parameters) {
+	if inCluster.Spec.Monitoring == nil || inCluster.Spec.Monitoring.QueryStatistics == nil {
+		return
+	}
+	spec := inCluster.Spec.Monitoring.QueryStatistics
+
+	if StatementsEnabled(inCluster) {
+		shared := outParameters.Mandatory.Value("shared_preload_libraries")
+		if !strings.Contains(","+shared+",", ",pg_stat_statements,") {
+			outParameters.Mandatory.Add("shared_preload_libraries",
+				strings.TrimPrefix(shared+",pg_stat_statements", ","))
+		}
+	}
+	if spec.TrackIOTiming != nil {
+		outParameters.Mandatory.Add("track_io_timing", map[bool]string{
+			false: "off", true: "on",
+		}[*spec.TrackIOTiming])
+	}
+	if spec.LogMinDurationStatement != "" {
+		outParameters.Mandatory.Add("log_min_duration_statement", spec.LogMinDurationStatement)
+	}
+}
+
+// StatisticsQueries returns postgres_exporter queries that rank statements of
+// cluster and report cache hit ratios. The result is empty when there is
+// nothing to report.
+// - https://github.com/prometheus-community/postgres_exporter#adding-new-metrics-via-a-config-file
+func StatisticsQueries(cluster *v1beta1.PostgresCluster) string {
+	if cluster.Spec.Monitoring == nil || cluster.Spec.Monitoring.QueryStatistics == nil {
+		return ""
+	}
+
+	var queries strings.Builder
+
+	queries.WriteString(`
+ccp_database_cache_hit:
+  query: "SELECT datname AS dbname, CASE WHEN blks_hit + blks_read = 0 THEN 1 ELSE blks_hit::float8 / (blks_hit + blks_read) END AS ratio FROM pg_catalog.pg_stat_database WHERE datname IS NOT NULL"
+  metrics:
+    - dbname:
+        usage: "LABEL"
+        description: "Name of the database"
+    - ratio:
+        usage: "GAUGE"
+        description: "Fraction of blocks read from shared buffers rather than disk since statistics were reset"
+`)
+
+	if StatementsEnabled(cluster) {
+		// The execution time columns were renamed in PostgreSQL 13.
+		// - https://www.postgresql.org/docs/13/release-13.html
+		mean, total := "mean_exec_time", "total_exec_time"
+		if cluster.Spec.PostgresVersion < 13 {
+			mean, total = "mean_time", "total_time"
+		}
+
+		fmt.Fprintf(&queries, `
+ccp_statements_top_mean:
+  query: "SELECT s.queryid::text AS queryid, d.datname AS dbname, r.rolname AS role, s.%[1]s AS mean_time_ms, s.calls, CASE WHEN s.shared_blks_hit + s.shared_blks_read = 0 THEN 1 ELSE s.shared_blks_hit::float8 / (s.shared_blks_hit + s.shared_blks_read) END AS cache_hit_ratio FROM pg_stat_statements s JOIN pg_catalog.pg_database d ON d.oid = s.dbid JOIN pg_catalog.pg_roles r ON r.oid = s.userid WHERE s.queryid IS NOT NULL ORDER BY s.%[1]s DESC LIMIT %[3]d"
+  metrics:
+    - queryid:
+        usage: "LABEL"
+        description: "Internal hash of the statement"
+    - dbname:
+        usage: "LABEL"
+        description: "Name of the database"
+    - role:
+        usage: "LABEL"
+        description: "Name of the user that executed the statement"
+    - mean_time_ms:
+        usage: "GAUGE"
+        description: "Mean execution time of the statement in milliseconds"
+    - calls:
+        usage: "GAUGE"
+        description: "Number of times the statement was executed"
+    - cache_hit_ratio:
+        usage: "GAUGE"
+        description: "Fraction of blocks the statement read from shared buffers rather than disk"
+
+ccp_statements_top_total:
+  query: "SELECT s.queryid::text AS queryid, d.datname AS dbname, r.rolname AS role, s.%[2]s AS total_time_ms FROM pg_stat_statements s JOIN pg_catalog.pg_database d ON d.oid = s.dbid JOIN pg_catalog.pg_roles r ON r.oid = s.userid WHERE s.queryid IS NOT NULL ORDER BY s.%[2]s DESC LIMIT %[3]d"
+  metrics:
+    - queryid:
+        usage: "LABEL"
+        description: "Internal hash of the statement"
+    - dbname:
+        usage: "LABEL"
+        description: "Name of the database"
+    - role:
+        usage: "LABEL"
+        description: "Name of the user that executed the statement"
+    - total_time_ms:
+        usage: "GAUGE"
+        description: "Total execution time of the statement in milliseconds"
+`, mean, total, TopStatements(cluster))
+	}
+
+	return strings.TrimPrefix(queries.String(), "\n")
+}
@@ -0,0 +1,144 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgmonitor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestStatementsEnabled(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	assert.Assert(t, !StatementsEnabled(cluster))
+	assert.Equal(t, TopStatements(cluster), int32(20))
+
+	cluster.Spec.Monitoring = new(v1beta1.MonitoringSpec)
+	assert.Assert(t, !StatementsEnabled(cluster))
+
+	cluster.Spec.Monitoring.QueryStatistics = new(v1beta1.QueryStatisticsSpec)
+	assert.Assert(t, StatementsEnabled(cluster))
+
+	cluster.Spec.Monitoring.QueryStatistics.PGStatStatements = initialize.Bool(false)
+	assert.Assert(t, !StatementsEnabled(cluster))
+
+	cluster.Spec.Monitoring.QueryStatistics.TopStatements = initialize.Int32(5)
+	assert.Equal(t, TopStatements(cluster), int32(5))
+}
+
+func TestEnableStatementsInPostgreSQL(t *testing.T) {
+	expected := errors.New("whoops")
+	exec := func(
+		_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		b, err := ioutil.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(string(b),
+			`SET client_min_messages = WARNING; CREATE EXTENSION IF NOT EXISTS pg_stat_statements;`))
+		return expected
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, expected, EnableStatementsInPostgreSQL(ctx, exec))
+}
+
+func TestStatisticsParameters(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	assert.NilError(t, yaml.Unmarshal([]byte(`{
+		monitoring: {
+			queryStatistics: { trackIOTiming: true, logMinDurationStatement: 250ms },
+		},
+	}`), &cluster.Spec))
+
+	parameters := postgres.NewParameters()
+	PostgreSQLParameters(cluster, &parameters)
+
+	libraries, _ := parameters.Mandatory.Get("shared_preload_libraries")
+	assert.Equal(t, libraries, "pg_stat_statements")
+
+	timing, _ := parameters.Mandatory.Get("track_io_timing")
+	assert.Equal(t, timing, "on")
+
+	duration, _ := parameters.Mandatory.Get("log_min_duration_statement")
+	assert.Equal(t, duration, "250ms")
+
+	t.Run("WithExporter", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Monitoring.PGMonitor = &v1beta1.PGMonitorSpec{
+			Exporter: &v1beta1.ExporterSpec{},
+		}
+
+		parameters := postgres.NewParameters()
+		PostgreSQLParameters(cluster, &parameters)
+
+		// The library is loaded only once.
+		libraries, _ := parameters.Mandatory.Get("shared_preload_libraries")
+		assert.Equal(t, libraries, "pg_stat_statements,pgnodemx")
+	})
+
+	t.Run("WithoutStatements", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Monitoring.QueryStatistics.PGStatStatements = initialize.Bool(false)
+
+		parameters := postgres.NewParameters()
+		PostgreSQLParameters(cluster, &parameters)
+		assert.Assert(t, !parameters.Mandatory.Has("shared_preload_libraries"))
+	})
+}
+
+func TestStatisticsQueries(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.PostgresVersion = 12
+	assert.Equal(t, StatisticsQueries(cluster), "")
+
+	cluster.Spec.Monitoring = &v1beta1.MonitoringSpec{
+		QueryStatistics: &v1beta1.QueryStatisticsSpec{
+			TopStatements: initialize.Int32(7),
+		},
+	}
+
+	parse := func(t *testing.T, queries string) map[string]interface{} {
+		var out map[string]interface{}
+		assert.NilError(t, yaml.Unmarshal([]byte(queries), &out))
+		return out
+	}
+
+	queries := StatisticsQueries(cluster)
+	parsed := parse(t, queries)
+	assert.Assert(t, parsed["ccp_database_cache_hit"] != nil)
+	assert.Assert(t, parsed["ccp_statements_top_mean"] != nil)
+	assert.Assert(t, parsed["ccp_statements_top_total"] != nil)
+	assert.Assert(t, strings.Contains(queries, "s.mean_time AS"))
+	assert.Assert(t, strings.Contains(queries, "LIMIT 7"))
+
+	cluster.Spec.PostgresVersion = 13
+	assert.Assert(t, strings.Contains(StatisticsQueries(cluster), "s.mean_exec_time AS"))
+
+	cluster.Spec.Monitoring.QueryStatistics.PGStatStatements = initialize.Bool(false)
+	parsed = parse(t, StatisticsQueries(cluster))
+	assert.Assert(t, parsed["ccp_database_cache_hit"] != nil)
+	assert.Assert(t, parsed["ccp_statements_top_mean"] == nil)
+}
@@ -0,0 +1,149 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgpartman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// Schema is where pg_partman is installed in every database.
+const Schema = "partman"
+
+// Enabled returns the pg_partman specification of cluster, or nil when
+// pg_partman is not enabled.
+func Enabled(cluster *v1beta1.PostgresCluster) *v1beta1.PGPartmanSpec {
+	if cluster.Spec.Extensions == nil {
+		return nil
+	}
+	return cluster.Spec.Extensions.PGPartman
+}
+
+// Databases returns the names of the databases in which pg_partman is
+// installed.
+func Databases(cluster *v1beta1.PostgresCluster) []string {
+	var databases []string
+	if spec := Enabled(cluster); spec != nil {
+		for _, database := range spec.Databases {
+			databases = append(databases, string(database.Name))
+		}
+	}
+	return databases
+}
+
+// MaintenanceJobs returns one maintenance job for every database of cluster
+// in which pg_partman is installed. Each one calls run_maintenance_proc(),
+// which creates and drops partitions of every table in part_config.
+// - https://github.com/pgpartman/pg_partman/blob/master/doc/pg_partman.md
+func MaintenanceJobs(cluster *v1beta1.PostgresCluster) []v1beta1.PostgresMaintenanceJob {
+	spec := Enabled(cluster)
+	if spec == nil {
+		return nil
+	}
+
+	jobs := make([]v1beta1.PostgresMaintenanceJob, 0, len(spec.Databases))
+	for _, database := range spec.Databases {
+		// Database names do not fit into Kubernetes metadata, so name the job
+		// after a hash of the database.
+		hash := fnv.New32()
+		_, _ = hash.Write([]byte(database.Name))
+
+		schedule := database.Schedule
+		if schedule == "" {
+			schedule = "@hourly"
+		}
+
+		jobs = append(jobs, v1beta1.PostgresMaintenanceJob{
+			Name:      fmt.Sprintf("partman-%08x", hash.Sum32()),
+			Schedule:  schedule,
+			TimeZone:  database.TimeZone,
+			SQL:       `CALL ` + Schema + `.run_maintenance_proc();`,
+			Database:  database.Name,
+			Resources: database.Resources,
+		})
+	}
+	return jobs
+}
+
+// EnableInPostgreSQL installs pg_partman into the databases of spec, updates
+// it, and allows user to run its maintenance. The user is created without
+// LOGIN when it does not exist yet. pg_partman has a background worker, but
+// it is not used, so there is no shared library to preload.
+func EnableInPostgreSQL(
+	ctx context.Context, exec postgres.Executor, spec *v1beta1.PGPartmanSpec, user string,
+) error {
+	log := logging.FromContext(ctx)
+
+	names := make([]string, 0, len(spec.Databases))
+	for _, database := range spec.Databases {
+		names = append(names, string(database.Name))
+	}
+	databases, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+
+	stdout, stderr, err := exec.ExecInDatabasesFromQuery(ctx,
+		strings.Join([]string{
+			`SELECT datname FROM pg_catalog.pg_database`,
+			` WHERE datname IN (SELECT pg_catalog.json_array_elements_text(:'databases'::json))`,
+			` ORDER BY datname`,
+		}, "\n"),
+		strings.Join([]string{
+			// Quiet NOTICE messages from IF NOT EXISTS and UPDATE statements.
+			// - https://www.postgresql.org/docs/current/runtime-config-client.html
+			`SET client_min_messages = WARNING;`,
+
+			// pg_partman expects its own schema.
+			// - https://github.com/pgpartman/pg_partman#installation
+			`CREATE SCHEMA IF NOT EXISTS :"schema";`,
+			`CREATE EXTENSION IF NOT EXISTS pg_partman SCHEMA :"schema";`,
+			`ALTER EXTENSION pg_partman UPDATE;`,
+
+			// Roles are shared by every database. Create the user when it does
+			// not exist so that it can be granted privileges.
+			`SELECT pg_catalog.format('CREATE ROLE %I', :'username')`,
+			` WHERE NOT EXISTS (SELECT 1 FROM pg_catalog.pg_roles WHERE rolname = :'username')`,
+			`\gexec`,
+
+			// Run maintenance with the privileges that pg_partman recommends
+			// for a role that is not a superuser.
+			// - https://github.com/pgpartman/pg_partman#installation
+			`GRANT ALL ON SCHEMA :"schema" TO :"username";`,
+			`GRANT ALL ON ALL TABLES IN SCHEMA :"schema" TO :"username";`,
+			`GRANT EXECUTE ON ALL FUNCTIONS IN SCHEMA :"schema" TO :"username";`,
+			`GRANT EXECUTE ON ALL PROCEDURES IN SCHEMA :"schema" TO :"username";`,
+		}, "\n"),
+		map[string]string{
+			"databases": string(databases),
+			"schema":    Schema,
+			"username":  user,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("enabled pg_partman", "stdout", stdout, "stderr", stderr)
+
+	return err
+}
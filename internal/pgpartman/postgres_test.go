@@ -0,0 +1,100 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgpartman
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestMaintenanceJobs(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	assert.Assert(t, MaintenanceJobs(cluster) == nil)
+	assert.Assert(t, Databases(cluster) == nil)
+
+	cluster.Spec.Extensions = &v1beta1.PostgresExtensionsSpec{
+		PGPartman: &v1beta1.PGPartmanSpec{Databases: []v1beta1.PGPartmanDatabase{
+			{Name: "app"},
+			{
+				Name: "Other Database", Schedule: "*/15 * * * *", TimeZone: "Asia/Tokyo",
+				Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("100m"),
+				}},
+			},
+		}},
+	}
+	assert.DeepEqual(t, Databases(cluster), []string{"app", "Other Database"})
+
+	jobs := MaintenanceJobs(cluster)
+	assert.Equal(t, len(jobs), 2)
+
+	assert.Equal(t, jobs[0].Database, v1beta1.PostgresIdentifier("app"))
+	assert.Equal(t, jobs[0].Schedule, "@hourly", "expected a default")
+	assert.Equal(t, jobs[0].SQL, `CALL partman.run_maintenance_proc();`)
+
+	assert.Equal(t, jobs[1].Database, v1beta1.PostgresIdentifier("Other Database"))
+	assert.Equal(t, jobs[1].Schedule, "*/15 * * * *")
+	assert.Equal(t, jobs[1].TimeZone, "Asia/Tokyo")
+	assert.Equal(t, jobs[1].Resources.Limits.Cpu().String(), "100m")
+
+	// Names fit into Kubernetes metadata and differ by database.
+	for _, job := range jobs {
+		assert.Assert(t, strings.HasPrefix(job.Name, "partman-"))
+		assert.Assert(t, len(job.Name) <= 20, "got %q", job.Name)
+	}
+	assert.Assert(t, jobs[0].Name != jobs[1].Name)
+}
+
+func TestEnableInPostgreSQL(t *testing.T) {
+	expected := errors.New("whoops")
+	exec := func(
+		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		assert.Assert(t, stdout != nil, "should capture stdout")
+		assert.Assert(t, stderr != nil, "should capture stderr")
+
+		assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+			`json_array_elements_text(:'databases'::json)`,
+		), "expected only some databases")
+		assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+			`--set=databases=["app","other"]`))
+		assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+			`--set=username=_crunchymaintenance`))
+
+		b, err := ioutil.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(string(b), `CREATE EXTENSION IF NOT EXISTS pg_partman SCHEMA :"schema";`))
+		assert.Assert(t, strings.Contains(string(b), `ALTER EXTENSION pg_partman UPDATE;`))
+		assert.Assert(t, strings.Contains(string(b), `GRANT EXECUTE ON ALL PROCEDURES`))
+
+		return expected
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, expected, EnableInPostgreSQL(ctx, exec, &v1beta1.PGPartmanSpec{
+		Databases: []v1beta1.PGPartmanDatabase{{Name: "app"}, {Name: "other"}},
+	}, "_crunchymaintenance"))
+}
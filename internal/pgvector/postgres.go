@@ -0,0 +1,97 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgvector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// ProfileANN is the value of PGVectorSpec.Profile that tunes PostgreSQL for
+// building approximate nearest neighbor indexes.
+const ProfileANN = "ANN"
+
+// Enabled returns the pgvector specification of cluster, or nil when pgvector
+// is not enabled.
+func Enabled(cluster *v1beta1.PostgresCluster) *v1beta1.PGVectorSpec {
+	if cluster.Spec.Extensions == nil {
+		return nil
+	}
+	return cluster.Spec.Extensions.PGVector
+}
+
+// EnableInPostgreSQL installs pgvector into every database and updates it to
+// the version of spec, if any. pgvector has no shared library to preload.
+func EnableInPostgreSQL(ctx context.Context, exec postgres.Executor, spec *v1beta1.PGVectorSpec) error {
+	log := logging.FromContext(ctx)
+
+	// The extension is named "vector".
+	// - https://github.com/pgvector/pgvector#installation
+	create, update := `CREATE EXTENSION IF NOT EXISTS vector`, `ALTER EXTENSION vector UPDATE`
+	variables := map[string]string{
+		"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+		"QUIET":         "on", // Do not print successful statements to stdout.
+	}
+	if spec.Version != "" {
+		create, update = create+` VERSION :'version'`, update+` TO :'version'`
+		variables["version"] = spec.Version
+	}
+
+	stdout, stderr, err := exec.ExecInAllDatabases(ctx,
+		strings.Join([]string{
+			// Quiet NOTICE messages from IF NOT EXISTS and UPDATE statements.
+			// - https://www.postgresql.org/docs/current/runtime-config-client.html
+			`SET client_min_messages = WARNING;`,
+			create + `;`,
+			update + `;`,
+		}, "\n"),
+		variables)
+
+	log.V(1).Info("enabled pgvector", "stdout", stdout, "stderr", stderr)
+
+	return err
+}
+
+// PostgreSQLParameters sets the default parameters of the pgvector profile,
+// if any. Call it after postgres.AutoTuneParameters so these take precedence.
+func PostgreSQLParameters(cluster *v1beta1.PostgresCluster, outParameters *postgres.Parameters) {
+	spec := Enabled(cluster)
+	if spec == nil || spec.Profile != ProfileANN {
+		return
+	}
+
+	memory, cpus := postgres.SmallestLimits(cluster)
+
+	// Index builds are much faster when the whole graph fits in memory, so
+	// give maintenance operations an eighth of memory without the usual cap.
+	// Keep the PostgreSQL default of 64MB when that is more.
+	// - https://github.com/pgvector/pgvector#index-build-time
+	// - https://www.postgresql.org/docs/current/runtime-config-resource.html#GUC-MAINTENANCE-WORK-MEM
+	if kilobytes := memory >> 10; kilobytes/8 > 64<<10 {
+		outParameters.Default.Add("maintenance_work_mem", fmt.Sprintf("%dkB", kilobytes/8))
+	}
+
+	// Index builds can use half the CPUs. Two is the PostgreSQL default.
+	// - https://www.postgresql.org/docs/current/runtime-config-resource.html#GUC-MAX-PARALLEL-MAINTENANCE-WORKERS
+	if workers := cpus / 2; workers > 2 {
+		outParameters.Default.Add("max_parallel_maintenance_workers", fmt.Sprint(workers))
+	}
+}
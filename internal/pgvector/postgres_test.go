@@ -0,0 +1,107 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgvector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestEnableInPostgreSQL(t *testing.T) {
+	expected := errors.New("whoops")
+	statements := func(t *testing.T, sql string, variables ...string) postgres.Executor {
+		return func(
+			_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			assert.Assert(t, stdout != nil, "should capture stdout")
+			assert.Assert(t, stderr != nil, "should capture stderr")
+
+			assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+				`SELECT datname FROM pg_catalog.pg_database`,
+			), "expected all databases and templates")
+			assert.DeepEqual(t, command[6:], variables)
+
+			b, err := ioutil.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Equal(t, string(b), sql)
+
+			return expected
+		}
+	}
+
+	ctx := context.Background()
+
+	t.Run("Default", func(t *testing.T) {
+		exec := statements(t, `SET client_min_messages = WARNING;
+CREATE EXTENSION IF NOT EXISTS vector;
+ALTER EXTENSION vector UPDATE;`, "--set=ON_ERROR_STOP=on", "--set=QUIET=on")
+
+		assert.Equal(t, expected, EnableInPostgreSQL(ctx, exec, &v1beta1.PGVectorSpec{}))
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		exec := statements(t, `SET client_min_messages = WARNING;
+CREATE EXTENSION IF NOT EXISTS vector VERSION :'version';
+ALTER EXTENSION vector UPDATE TO :'version';`,
+			"--set=ON_ERROR_STOP=on", "--set=QUIET=on", "--set=version=0.5.1")
+
+		assert.Equal(t, expected, EnableInPostgreSQL(ctx, exec,
+			&v1beta1.PGVectorSpec{Version: "0.5.1"}))
+	})
+}
+
+func TestPostgreSQLParameters(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{{Name: "one"}}
+
+	parameters := postgres.NewParameters()
+	before := parameters.Default.AsMap()
+
+	// Nothing when pgvector is not enabled or has no profile.
+	PostgreSQLParameters(cluster, &parameters)
+	cluster.Spec.Extensions = &v1beta1.PostgresExtensionsSpec{PGVector: &v1beta1.PGVectorSpec{}}
+	PostgreSQLParameters(cluster, &parameters)
+	assert.DeepEqual(t, parameters.Default.AsMap(), before)
+
+	// Nothing when there are no limits.
+	cluster.Spec.Extensions.PGVector.Profile = ProfileANN
+	PostgreSQLParameters(cluster, &parameters)
+	assert.DeepEqual(t, parameters.Default.AsMap(), before)
+
+	cluster.Spec.InstanceSets[0].Resources.Limits = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("8"),
+		corev1.ResourceMemory: resource.MustParse("32Gi"),
+	}
+	parameters.Default.Add("maintenance_work_mem", "2GB")
+	PostgreSQLParameters(cluster, &parameters)
+
+	value, _ := parameters.Default.Get("maintenance_work_mem")
+	assert.Equal(t, value, "4194304kB")
+	value, _ = parameters.Default.Get("max_parallel_maintenance_workers")
+	assert.Equal(t, value, "4")
+	assert.Assert(t, !parameters.Mandatory.Has("shared_preload_libraries"))
+}
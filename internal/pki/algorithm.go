@@ -0,0 +1,132 @@
+package pki
+
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// Algorithm describes the keys and signatures of the certificates this package
+// generates. The zero value is ECDSA keys on the P-256 curve and signatures
+// using SHA-384. Every supported combination is approved by FIPS 140-2.
+type Algorithm struct {
+	// Key is the kind and size of private keys: "ECDSA-P256", "ECDSA-P384",
+	// "ECDSA-P521", "RSA-2048", "RSA-3072", or "RSA-4096".
+	Key string
+
+	// Hash is the hash function of signatures: "SHA256", "SHA384", or "SHA512".
+	Hash string
+}
+
+// defaultAlgorithm is the Algorithm of new certificate authorities and leaf
+// certificates. See SetAlgorithm.
+var defaultAlgorithm Algorithm
+
+// SetAlgorithm changes the keys and signatures of certificates generated from
+// now on. An empty key or hash leaves that part unchanged from the zero value
+// of Algorithm. It returns an error when either is not supported.
+//
+// Certificates generated with a different Algorithm are "bad" according to
+// RootCAIsBad and LeafCertIsBad, so they are replaced.
+func SetAlgorithm(key, hash string) error {
+	algorithm := Algorithm{Key: strings.ToUpper(key), Hash: strings.ToUpper(hash)}
+
+	if _, err := algorithm.generator(); err != nil {
+		return err
+	}
+	if algorithm.signatureAlgorithm() == x509.UnknownSignatureAlgorithm {
+		return fmt.Errorf("%w: signature hash %q", ErrUnsupportedAlgorithm, hash)
+	}
+
+	defaultAlgorithm = algorithm
+	return nil
+}
+
+// generator returns a function that generates private keys of a.
+func (a Algorithm) generator() (func() (crypto.Signer, error), error) {
+	ecdsaKey := func(curve elliptic.Curve) func() (crypto.Signer, error) {
+		return func() (crypto.Signer, error) { return ecdsa.GenerateKey(curve, rand.Reader) }
+	}
+	rsaKey := func(bits int) func() (crypto.Signer, error) {
+		return func() (crypto.Signer, error) { return rsa.GenerateKey(rand.Reader, bits) }
+	}
+
+	switch a.Key {
+	case "", "ECDSA-P256":
+		return ecdsaKey(elliptic.P256()), nil
+	case "ECDSA-P384":
+		return ecdsaKey(elliptic.P384()), nil
+	case "ECDSA-P521":
+		return ecdsaKey(elliptic.P521()), nil
+	case "RSA-2048":
+		return rsaKey(2048), nil
+	case "RSA-3072":
+		return rsaKey(3072), nil
+	case "RSA-4096":
+		return rsaKey(4096), nil
+	}
+	return nil, fmt.Errorf("%w: key %q", ErrUnsupportedAlgorithm, a.Key)
+}
+
+// isRSA reports whether a uses RSA keys rather than ECDSA keys.
+func (a Algorithm) isRSA() bool { return strings.HasPrefix(a.Key, "RSA-") }
+
+// signatureAlgorithm returns the algorithm of signatures made by keys of a.
+func (a Algorithm) signatureAlgorithm() x509.SignatureAlgorithm {
+	var ecdsaAlgorithm, rsaAlgorithm x509.SignatureAlgorithm
+
+	switch a.Hash {
+	case "SHA256":
+		ecdsaAlgorithm, rsaAlgorithm = x509.ECDSAWithSHA256, x509.SHA256WithRSA
+	case "", "SHA384":
+		ecdsaAlgorithm, rsaAlgorithm = x509.ECDSAWithSHA384, x509.SHA384WithRSA
+	case "SHA512":
+		ecdsaAlgorithm, rsaAlgorithm = x509.ECDSAWithSHA512, x509.SHA512WithRSA
+	default:
+		return x509.UnknownSignatureAlgorithm
+	}
+
+	if a.isRSA() {
+		return rsaAlgorithm
+	}
+	return ecdsaAlgorithm
+}
+
+// matches reports whether the public key of certificate is the kind and size
+// of a and whether certificate is signed by the signature algorithm of a.
+func (a Algorithm) matches(certificate *x509.Certificate) bool {
+	key := ""
+	switch public := certificate.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		key = "ECDSA-" + strings.ReplaceAll(public.Curve.Params().Name, "-", "")
+	case *rsa.PublicKey:
+		key = fmt.Sprintf("RSA-%d", public.N.BitLen())
+	}
+
+	expected := a.Key
+	if expected == "" {
+		expected = "ECDSA-P256"
+	}
+	return key == expected &&
+		certificate.SignatureAlgorithm == a.signatureAlgorithm()
+}
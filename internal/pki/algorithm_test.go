@@ -0,0 +1,97 @@
+package pki
+
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestSetAlgorithm(t *testing.T) {
+	t.Cleanup(func() { defaultAlgorithm = Algorithm{} })
+
+	assert.NilError(t, SetAlgorithm("", ""))
+	assert.Equal(t, defaultAlgorithm, Algorithm{})
+
+	assert.NilError(t, SetAlgorithm("rsa-3072", "sha256"))
+	assert.Equal(t, defaultAlgorithm, Algorithm{Key: "RSA-3072", Hash: "SHA256"})
+	assert.Equal(t, NewRootCertificateAuthority().Algorithm, defaultAlgorithm)
+	assert.Equal(t, NewLeafCertificate("", nil, nil).Algorithm, defaultAlgorithm)
+
+	for _, tt := range []struct{ key, hash string }{
+		{key: "RSA-1024"},
+		{key: "ECDSA-P224"},
+		{key: "Ed25519"},
+		{hash: "SHA1"},
+		{hash: "MD5"},
+	} {
+		err := SetAlgorithm(tt.key, tt.hash)
+		assert.Assert(t, errors.Is(err, ErrUnsupportedAlgorithm), "%+v: %v", tt, err)
+	}
+
+	// The previous algorithm remains after an error.
+	assert.Equal(t, defaultAlgorithm, Algorithm{Key: "RSA-3072", Hash: "SHA256"})
+}
+
+func TestAlgorithmRSA(t *testing.T) {
+	ctx := context.Background()
+
+	root := NewRootCertificateAuthority()
+	root.Algorithm = Algorithm{Key: "RSA-2048", Hash: "SHA512"}
+	assert.NilError(t, root.Generate())
+	assert.Assert(t, !RootCAIsBad(root))
+
+	leaf := NewLeafCertificate("hippo", []string{"hippo"}, nil)
+	leaf.Algorithm = root.Algorithm
+	assert.NilError(t, leaf.Generate(root))
+	assert.Assert(t, !LeafCertIsBad(ctx, leaf, root, "ns1"))
+
+	certificate, err := x509.ParseCertificate(leaf.Certificate.Certificate)
+	assert.NilError(t, err)
+	assert.Equal(t, certificate.SignatureAlgorithm, x509.SHA512WithRSA)
+	assert.Equal(t, certificate.PublicKey.(*rsa.PublicKey).N.BitLen(), 2048)
+
+	t.Run("Encoding", func(t *testing.T) {
+		text, err := root.PrivateKey.MarshalText()
+		assert.NilError(t, err)
+
+		parsed, err := ParsePrivateKey(text)
+		assert.NilError(t, err)
+		assert.Assert(t, parsed.PrivateKey.(*rsa.PrivateKey).Equal(root.PrivateKey.PrivateKey))
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		// Certificates are bad when their key or signature algorithm differ.
+		for _, algorithm := range []Algorithm{
+			{},
+			{Key: "RSA-3072", Hash: "SHA512"},
+			{Key: "RSA-2048", Hash: "SHA256"},
+		} {
+			changedRoot := *root
+			changedRoot.Algorithm = algorithm
+			assert.Assert(t, RootCAIsBad(&changedRoot), "%+v", algorithm)
+
+			changedLeaf := *leaf
+			changedLeaf.Algorithm = algorithm
+			assert.Assert(t, LeafCertIsBad(ctx, &changedLeaf, root, "ns1"), "%+v", algorithm)
+		}
+	})
+}
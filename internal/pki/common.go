@@ -16,8 +16,7 @@ package pki
 */
 
 import (
-	"crypto/ecdsa"
-	"crypto/elliptic"
+	"crypto"
 	"crypto/rand"
 	"crypto/x509"
 	"math/big"
@@ -39,12 +38,16 @@ const (
 	serialNumberBits = 128
 )
 
-// generateKey generates a ECDSA keypair using a P-256 curve. This curve is
-// roughly equivalent to a RSA 3072 bit key, but requires less bits to achieve
-// the equivalent cryptographic strength. Additionally, ECDSA is FIPS 140-2
-// compliant.
-func generateKey() (*ecdsa.PrivateKey, error) {
-	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+// generateKey generates a private key of algorithm. By default, this is a ECDSA
+// keypair using a P-256 curve. This curve is roughly equivalent to a RSA 3072
+// bit key, but requires less bits to achieve the equivalent cryptographic
+// strength. Additionally, ECDSA is FIPS 140-2 compliant.
+func generateKey(algorithm Algorithm) (crypto.Signer, error) {
+	generate, err := algorithm.generator()
+	if err != nil {
+		return nil, err
+	}
+	return generate()
 }
 
 // generateSerialNumber generates a random serial number that can be used for
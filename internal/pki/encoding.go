@@ -16,7 +16,9 @@ package pki
 */
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -28,8 +30,12 @@ const (
 	pemCertificateType = "CERTIFICATE"
 
 	// pemPrivateKeyType is part of the PEM header that identifies the private
-	// key. This is presently hard coded to ECDSA keys
+	// key as an ECDSA key
 	pemPrivateKeyType = "EC PRIVATE KEY"
+
+	// pemRSAPrivateKeyType is part of the PEM header that identifies the
+	// private key as an RSA key
+	pemRSAPrivateKeyType = "RSA PRIVATE KEY"
 )
 
 // Certificate is a higher-level structure that encapsulates the x509 machinery
@@ -70,10 +76,11 @@ func (c *Certificate) UnmarshalText(data []byte) error {
 	return nil
 }
 
-// PrivateKey encapsulates functionality around marshalling a ECDSA private key.
+// PrivateKey encapsulates functionality around marshalling a ECDSA or RSA
+// private key.
 type PrivateKey struct {
-	// PrivateKey is the private key
-	PrivateKey *ecdsa.PrivateKey
+	// PrivateKey is the private key, either *ecdsa.PrivateKey or *rsa.PrivateKey
+	PrivateKey crypto.Signer
 
 	// marshalECPrivateKey turns a ECDSA private key into DER format, which is an
 	// intermediate form prior to turning it into a PEM block
@@ -82,12 +89,22 @@ type PrivateKey struct {
 
 // MarshalText encodes the private key in PEM format
 func (c *PrivateKey) MarshalText() ([]byte, error) {
+	// RSA keys are written in PKCS #1 format
+	if key, ok := c.PrivateKey.(*rsa.PrivateKey); ok {
+		return c.marshalPrivateKey(pemRSAPrivateKeyType, x509.MarshalPKCS1PrivateKey(key)), nil
+	}
+
 	if c.marshalECPrivateKey == nil {
 		return []byte{}, fmt.Errorf("%w: marshalECPrivateKey", ErrFunctionNotImplemented)
 	}
 
+	key, ok := c.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return []byte{}, fmt.Errorf("%w: %T", ErrUnsupportedAlgorithm, c.PrivateKey)
+	}
+
 	// first, convert private key to DER format
-	der, err := c.marshalECPrivateKey(c.PrivateKey)
+	der, err := c.marshalECPrivateKey(key)
 
 	if err != nil {
 		return []byte{}, err
@@ -95,7 +112,7 @@ func (c *PrivateKey) MarshalText() ([]byte, error) {
 
 	// encode the private key. in the future, once PKCS #8 encryption is supported
 	// in go, we can encrypt the private key
-	return c.marshalPrivateKey(der), nil
+	return c.marshalPrivateKey(pemPrivateKeyType, der), nil
 }
 
 // UnmarshalText decodes a private key from PEM format
@@ -107,32 +124,44 @@ func (c *PrivateKey) UnmarshalText(data []byte) error {
 		return fmt.Errorf("%w: malformed data", ErrInvalidPEM)
 	}
 
-	// if the type of the PEM block is not private key, return an error
-	if block.Type != pemPrivateKeyType {
-		return fmt.Errorf("%w: not type %s", ErrInvalidPEM, pemPrivateKeyType)
-	}
-
 	// store the DER; in the future, this is where we would decrypt the DER once
 	// PKCS #8 encryption is supported in Go
 	der := block.Bytes
 
-	// determine if the data actually represents a ECDSA private key
-	privateKey, err := x509.ParseECPrivateKey(der)
+	switch block.Type {
+	case pemPrivateKeyType:
+		// determine if the data actually represents a ECDSA private key
+		privateKey, err := x509.ParseECPrivateKey(der)
 
-	if err != nil {
-		return fmt.Errorf("%w: not a valid ECDSA private key", ErrInvalidPEM)
-	}
+		if err != nil {
+			return fmt.Errorf("%w: not a valid ECDSA private key", ErrInvalidPEM)
+		}
+
+		// everything checks out, we have a ECDSA private key
+		c.PrivateKey = privateKey
+
+	case pemRSAPrivateKeyType:
+		// determine if the data actually represents a RSA private key
+		privateKey, err := x509.ParsePKCS1PrivateKey(der)
 
-	// everything checks out, we have a ECDSA private key
-	c.PrivateKey = privateKey
+		if err != nil {
+			return fmt.Errorf("%w: not a valid RSA private key", ErrInvalidPEM)
+		}
+
+		c.PrivateKey = privateKey
+
+	default:
+		// the type of the PEM block is not private key, return an error
+		return fmt.Errorf("%w: not type %s", ErrInvalidPEM, pemPrivateKeyType)
+	}
 
 	return nil
 }
 
 // marshalPrivateKey encodes a private key in PEM format
-func (c *PrivateKey) marshalPrivateKey(der []byte) []byte {
+func (c *PrivateKey) marshalPrivateKey(blockType string, der []byte) []byte {
 	block := &pem.Block{
-		Type:  pemPrivateKeyType,
+		Type:  blockType,
 		Bytes: der,
 	}
 
@@ -141,7 +170,7 @@ func (c *PrivateKey) marshalPrivateKey(der []byte) []byte {
 
 // NewPrivateKey performs the setup for creating a new private key, including
 // any functions that need to be created
-func NewPrivateKey(key *ecdsa.PrivateKey) *PrivateKey {
+func NewPrivateKey(key crypto.Signer) *PrivateKey {
 	return &PrivateKey{
 		PrivateKey:          key,
 		marshalECPrivateKey: marshalECPrivateKey,
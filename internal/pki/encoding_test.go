@@ -201,7 +201,7 @@ func TestParsePrivateKey(t *testing.T) {
 		expected := generatePrivateKey()
 
 		t.Run("plaintext", func(t *testing.T) {
-			b, _ := x509.MarshalECPrivateKey(expected.PrivateKey)
+			b, _ := x509.MarshalECPrivateKey(expected.PrivateKey.(*ecdsa.PrivateKey))
 			encoded := pem.EncodeToMemory(&pem.Block{Bytes: b, Type: pemPrivateKeyType})
 
 			privateKey, err := ParsePrivateKey(encoded)
@@ -269,7 +269,7 @@ func TestPrivateKey(t *testing.T) {
 					t.Fatalf("expected valid ECDSA key, got error: %s", err.Error())
 				}
 
-				if !privateKey.PrivateKey.Equal(decodedKey) {
+				if !privateKey.PrivateKey.(*ecdsa.PrivateKey).Equal(decodedKey) {
 					t.Fatalf("expected private key to match pem encoded key")
 				}
 			})
@@ -309,7 +309,7 @@ func TestPrivateKey(t *testing.T) {
 		t.Run("plaintext", func(t *testing.T) {
 			t.Run("valid", func(t *testing.T) {
 				// manually marshal the private key
-				b, _ := x509.MarshalECPrivateKey(expected.PrivateKey)
+				b, _ := x509.MarshalECPrivateKey(expected.PrivateKey.(*ecdsa.PrivateKey))
 				encoded := pem.EncodeToMemory(&pem.Block{Bytes: b, Type: pemPrivateKeyType})
 				pk := &PrivateKey{}
 
@@ -31,4 +31,8 @@ var (
 
 	// ErrInvalidPEM s returned if encoded data is not a valid PEM block
 	ErrInvalidPEM = errors.New("invalid pem encoded data")
+
+	// ErrUnsupportedAlgorithm is returned if a key or signature algorithm is
+	// not one that can be used for certificates
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
 )
@@ -17,7 +17,7 @@ package pki
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"crypto"
 	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -56,11 +56,14 @@ type LeafCertificate struct {
 	// PrivateKey is the private key portion of the leaf certificate
 	PrivateKey *PrivateKey
 
-	// generateKey generates an ECDSA keypair
-	generateKey func() (*ecdsa.PrivateKey, error)
+	// Algorithm is the kind of key of the leaf certificate
+	Algorithm Algorithm
+
+	// generateKey generates a keypair of an Algorithm
+	generateKey func(Algorithm) (crypto.Signer, error)
 
 	// generateCertificate generates a X509 certificate return in DER format
-	generateCertificate func(*ecdsa.PrivateKey, *big.Int, *RootCertificateAuthority, string, []string, []net.IP) ([]byte, error)
+	generateCertificate func(crypto.Signer, *big.Int, *RootCertificateAuthority, string, []string, []net.IP) ([]byte, error)
 
 	// generateSerialNumber creates a unique serial number to assign to the
 	// certificate
@@ -80,7 +83,7 @@ func (c *LeafCertificate) Generate(rootCA *RootCertificateAuthority) error {
 	}
 
 	// generate a private key
-	privateKey, err := c.generateKey()
+	privateKey, err := c.generateKey(c.Algorithm)
 
 	if err != nil {
 		return err
@@ -109,7 +112,8 @@ func (c *LeafCertificate) Generate(rootCA *RootCertificateAuthority) error {
 }
 
 // LeafCertIsBad checks at least one leaf cert has been generated, the basic constraints
-// are valid and it has been verified with the root certpool
+// are valid, its key is of the Algorithm of leaf, and it has been verified with the
+// root certpool
 //
 // TODO(tjmoore4): Currently this will return 'true' if any of the parsed certs
 // fail a given check. For scenarios where multiple certs may be returned, such
@@ -161,6 +165,12 @@ func LeafCertIsBad(
 			return true
 		}
 
+		// a leaf cert is bad if its key or signature are not what is
+		// configured, so that it is replaced when the algorithm changes
+		if !leaf.Algorithm.matches(cert) {
+			return true
+		}
+
 		// verify leaf cert
 		_, verifyError := cert.Verify(x509.VerifyOptions{
 			DNSName: cert.DNSNames[0],
@@ -181,9 +191,11 @@ func LeafCertIsBad(
 // identity of a particular instance
 //
 // Accepts arguments for the common name (CN), the DNS names and the IP
-// Addresses that will be represented by this certificate
+// Addresses that will be represented by this certificate. It uses the Algorithm
+// of SetAlgorithm.
 func NewLeafCertificate(commonName string, dnsNames []string, ipAddresses []net.IP) *LeafCertificate {
 	return &LeafCertificate{
+		Algorithm:            defaultAlgorithm,
 		CommonName:           commonName,
 		DNSNames:             dnsNames,
 		IPAddresses:          ipAddresses,
@@ -193,9 +205,9 @@ func NewLeafCertificate(commonName string, dnsNames []string, ipAddresses []net.
 	}
 }
 
-// generateLeafCertificate creates a x509 certificate with a ECDSA
-// signature using the SHA-384 algorithm
-func generateLeafCertificate(privateKey *ecdsa.PrivateKey, serialNumber *big.Int,
+// generateLeafCertificate creates a x509 certificate signed by rootCA using
+// the signature algorithm of rootCA, which is ECDSA with SHA-384 by default
+func generateLeafCertificate(privateKey crypto.Signer, serialNumber *big.Int,
 	rootCA *RootCertificateAuthority, commonName string, dnsNames []string, ipAddresses []net.IP) ([]byte, error) {
 	// first, ensure that the root certificate can be turned into a x509
 	// Certificate object so it can be used as the parent certificate when
@@ -221,7 +233,7 @@ func generateLeafCertificate(privateKey *ecdsa.PrivateKey, serialNumber *big.Int
 		NotBefore:             now.Add(beforeInterval),
 		NotAfter:              now.Add(defaultCertificateExpiration),
 		SerialNumber:          serialNumber,
-		SignatureAlgorithm:    certificateSignatureAlgorithm,
+		SignatureAlgorithm:    rootCA.Algorithm.signatureAlgorithm(),
 		Subject: pkix.Name{
 			CommonName: commonName,
 		},
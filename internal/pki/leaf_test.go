@@ -17,6 +17,7 @@ package pki
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/x509"
@@ -87,7 +88,7 @@ func TestLeafCertificate(t *testing.T) {
 				t.Fatalf("expected valid x509 ceriticate, actual %s", err.Error())
 			}
 
-			if !cert.PrivateKey.PrivateKey.PublicKey.Equal(x509Certificate.PublicKey) {
+			if !cert.PrivateKey.PrivateKey.(*ecdsa.PrivateKey).PublicKey.Equal(x509Certificate.PublicKey) {
 				t.Fatalf("expected public key from stored key to match public key on certificate")
 			}
 
@@ -251,7 +252,7 @@ func TestLeafCertificate(t *testing.T) {
 				cert := &LeafCertificate{
 					CommonName:           commonName,
 					generateCertificate:  generateLeafCertificate,
-					generateKey:          func() (*ecdsa.PrivateKey, error) { return nil, errors.New(msg) },
+					generateKey:          func(Algorithm) (crypto.Signer, error) { return nil, errors.New(msg) },
 					generateSerialNumber: generateSerialNumber,
 				}
 
@@ -278,7 +279,7 @@ func TestLeafCertificate(t *testing.T) {
 				msg := "cannot generate certificate"
 				cert := &LeafCertificate{
 					CommonName: commonName,
-					generateCertificate: func(*ecdsa.PrivateKey, *big.Int, *RootCertificateAuthority, string, []string, []net.IP) ([]byte, error) {
+					generateCertificate: func(crypto.Signer, *big.Int, *RootCertificateAuthority, string, []string, []net.IP) ([]byte, error) {
 						return nil, errors.New(msg)
 					},
 					generateKey:          generateKey,
@@ -441,7 +442,7 @@ func TestLeafCertIsBad(t *testing.T) {
 }
 
 // generateLeafCertificateInvalidConstraint creates a x509 certificate with BasicConstraintsValid set to false
-func generateLeafCertificateInvalidConstraint(privateKey *ecdsa.PrivateKey, serialNumber *big.Int,
+func generateLeafCertificateInvalidConstraint(privateKey crypto.Signer, serialNumber *big.Int,
 	rootCA *RootCertificateAuthority, commonName string, dnsNames []string, ipAddresses []net.IP) ([]byte, error) {
 	// first, ensure that the root certificate can be turned into a x509
 	// Certificate object so it can be used as the parent certificate when
@@ -479,7 +480,7 @@ func generateLeafCertificateInvalidConstraint(privateKey *ecdsa.PrivateKey, seri
 }
 
 // generateLeafCertificateExpired creates a x509 certificate that is expired
-func generateLeafCertificateExpired(privateKey *ecdsa.PrivateKey, serialNumber *big.Int,
+func generateLeafCertificateExpired(privateKey crypto.Signer, serialNumber *big.Int,
 	rootCA *RootCertificateAuthority, commonName string, dnsNames []string, ipAddresses []net.IP) ([]byte, error) {
 	// first, ensure that the root certificate can be turned into a x509
 	// Certificate object so it can be used as the parent certificate when
@@ -27,7 +27,7 @@ package pki
 */
 
 import (
-	"crypto/ecdsa"
+	"crypto"
 	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -58,11 +58,14 @@ type RootCertificateAuthority struct {
 	// PrivateKey is the private key portion of the certificate authority
 	PrivateKey *PrivateKey
 
-	// generateKey generates an ECDSA keypair
-	generateKey func() (*ecdsa.PrivateKey, error)
+	// Algorithm is the kind of key and signature of the certificate authority
+	Algorithm Algorithm
+
+	// generateKey generates a keypair of an Algorithm
+	generateKey func(Algorithm) (crypto.Signer, error)
 
 	// generateCertificate generates a X509 certificate return in DER format
-	generateCertificate func(*ecdsa.PrivateKey, *big.Int) ([]byte, error)
+	generateCertificate func(crypto.Signer, *big.Int, Algorithm) ([]byte, error)
 
 	// generateSerialNumber creates a unique serial number to assign to the
 	// certificate
@@ -77,7 +80,7 @@ func (ca *RootCertificateAuthority) Generate() error {
 	}
 
 	// generate a private key
-	if privateKey, err := ca.generateKey(); err != nil {
+	if privateKey, err := ca.generateKey(ca.Algorithm); err != nil {
 		return err
 	} else {
 		ca.PrivateKey = NewPrivateKey(privateKey)
@@ -91,7 +94,7 @@ func (ca *RootCertificateAuthority) Generate() error {
 	}
 
 	// generate a certificate
-	if certificate, err := ca.generateCertificate(ca.PrivateKey.PrivateKey, serialNumber); err != nil {
+	if certificate, err := ca.generateCertificate(ca.PrivateKey.PrivateKey, serialNumber, ca.Algorithm); err != nil {
 		return err
 	} else {
 		ca.Certificate = &Certificate{Certificate: certificate}
@@ -101,9 +104,11 @@ func (ca *RootCertificateAuthority) Generate() error {
 }
 
 // NewRootCertificateAuthority generates a new root certificate authority
-// that can be used to issue leaf certificates
+// that can be used to issue leaf certificates. It uses the Algorithm of
+// SetAlgorithm.
 func NewRootCertificateAuthority() *RootCertificateAuthority {
 	return &RootCertificateAuthority{
+		Algorithm:            defaultAlgorithm,
 		generateCertificate:  generateRootCertificate,
 		generateKey:          generateKey,
 		generateSerialNumber: generateSerialNumber,
@@ -130,7 +135,7 @@ func ParseRootCertificateAuthority(privateKey, certificate []byte) (*RootCertifi
 }
 
 // RootCAIsBad checks that at least one root CA has been generated and that
-// all returned certs are CAs and not expired
+// all returned certs are CAs, not expired, and of the Algorithm of root
 //
 // TODO(tjmoore4): Currently this will return 'true' if any of the parsed certs
 // fail a given check. For scenarios where multiple certs may be returned, such
@@ -161,6 +166,12 @@ func RootCAIsBad(root *RootCertificateAuthority) bool {
 		if time.Now().After(cert.NotAfter) || time.Now().Before(cert.NotBefore) {
 			return true
 		}
+
+		// if its key or signature are not what is configured, so that the
+		// root CA is replaced when the algorithm changes
+		if !root.Algorithm.matches(cert) {
+			return true
+		}
 	}
 
 	// checks passed, cert is good
@@ -168,9 +179,9 @@ func RootCAIsBad(root *RootCertificateAuthority) bool {
 
 }
 
-// generateRootCertificate creates a x509 certificate with a ECDSA signature using
-// the SHA-384 algorithm
-func generateRootCertificate(privateKey *ecdsa.PrivateKey, serialNumber *big.Int) ([]byte, error) {
+// generateRootCertificate creates a x509 certificate with a signature of
+// algorithm, which is ECDSA using SHA-384 by default
+func generateRootCertificate(privateKey crypto.Signer, serialNumber *big.Int, algorithm Algorithm) ([]byte, error) {
 	// prepare the certificate. set the validity time to the predefined range
 	now := time.Now()
 	template := &x509.Certificate{
@@ -181,7 +192,7 @@ func generateRootCertificate(privateKey *ecdsa.PrivateKey, serialNumber *big.Int
 		NotBefore:             now.Add(beforeInterval),
 		NotAfter:              now.Add(defaultRootCAExpiration),
 		SerialNumber:          serialNumber,
-		SignatureAlgorithm:    certificateSignatureAlgorithm,
+		SignatureAlgorithm:    algorithm.signatureAlgorithm(),
 		Subject: pkix.Name{
 			CommonName: rootCAName,
 		},
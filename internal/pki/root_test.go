@@ -16,6 +16,7 @@ package pki
 */
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/x509"
@@ -167,7 +168,7 @@ func TestRootCertificateAuthority(t *testing.T) {
 				t.Fatalf("expected valid x509 ceriticate, actual %s", err.Error())
 			}
 
-			if !ca.PrivateKey.PrivateKey.PublicKey.Equal(x509Certificate.PublicKey) {
+			if !ca.PrivateKey.PrivateKey.(*ecdsa.PrivateKey).PublicKey.Equal(x509Certificate.PublicKey) {
 				t.Fatalf("expected public keys to match")
 			}
 
@@ -226,7 +227,7 @@ func TestRootCertificateAuthority(t *testing.T) {
 				msg := "cannot generate private key"
 				ca := &RootCertificateAuthority{}
 				ca.generateCertificate = generateRootCertificate
-				ca.generateKey = func() (*ecdsa.PrivateKey, error) { return nil, errors.New(msg) }
+				ca.generateKey = func(Algorithm) (crypto.Signer, error) { return nil, errors.New(msg) }
 				ca.generateSerialNumber = generateSerialNumber
 
 				if err := ca.Generate(); err.Error() != msg {
@@ -249,7 +250,7 @@ func TestRootCertificateAuthority(t *testing.T) {
 			t.Run("cannot generate certificate", func(t *testing.T) {
 				msg := "cannot generate certificate"
 				ca := &RootCertificateAuthority{}
-				ca.generateCertificate = func(*ecdsa.PrivateKey, *big.Int) ([]byte, error) { return nil, errors.New(msg) }
+				ca.generateCertificate = func(crypto.Signer, *big.Int, Algorithm) ([]byte, error) { return nil, errors.New(msg) }
 				ca.generateKey = generateKey
 				ca.generateSerialNumber = generateSerialNumber
 
@@ -321,7 +322,7 @@ func TestRootCAIsBad(t *testing.T) {
 
 // generateRootCertificateBadCA creates a root certificate that is not
 // configured as a CA
-func generateRootCertificateBadCA(privateKey *ecdsa.PrivateKey, serialNumber *big.Int) ([]byte, error) {
+func generateRootCertificateBadCA(privateKey crypto.Signer, serialNumber *big.Int, _ Algorithm) ([]byte, error) {
 	// prepare the certificate. set the validity time to the predefined range
 	now := time.Now()
 	template := &x509.Certificate{
@@ -343,7 +344,7 @@ func generateRootCertificateBadCA(privateKey *ecdsa.PrivateKey, serialNumber *bi
 }
 
 // generateRootCertificateExpired creates a root certificate that is already expired
-func generateRootCertificateExpired(privateKey *ecdsa.PrivateKey, serialNumber *big.Int) ([]byte, error) {
+func generateRootCertificateExpired(privateKey crypto.Signer, serialNumber *big.Int, _ Algorithm) ([]byte, error) {
 	// prepare the certificate. set the validity time to the predefined range
 	now := time.Now()
 	template := &x509.Certificate{
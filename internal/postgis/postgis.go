@@ -17,34 +17,72 @@ package postgis
 
 import (
 	"context"
+	"strconv"
 	"strings"
 
 	"github.com/crunchydata/postgres-operator/internal/logging"
 	"github.com/crunchydata/postgres-operator/internal/postgres"
 )
 
-// EnableInPostgreSQL installs triggers for the following extensions into every database:
+// major returns the major version number of a PostGIS version, e.g. 3 of
+// "3.1", or zero when it cannot be parsed.
+func major(version string) int {
+	n, _ := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	return n
+}
+
+// EnableInPostgreSQL installs the following extensions into every database:
 //  - postgis
+//  - postgis_raster, since PostGIS 3
 //  - postgis_topology
 //  - fuzzystrmatch
 //  - postgis_tiger_geocoder
-func EnableInPostgreSQL(ctx context.Context, exec postgres.Executor) error {
+//
+// It also updates any that are older than the PostGIS of the image. The
+// version is passed to psql so that this runs again when it changes.
+func EnableInPostgreSQL(ctx context.Context, exec postgres.Executor, version string) error {
 	log := logging.FromContext(ctx)
 
+	statements := []string{
+		// Quiet NOTICE messages from IF NOT EXISTS statements.
+		// - https://www.postgresql.org/docs/current/runtime-config-client.html
+		`SET client_min_messages = WARNING;`,
+
+		`CREATE EXTENSION IF NOT EXISTS postgis;`,
+	}
+	if major(version) >= 3 {
+		// Update every PostGIS extension first. Raster support is a separate
+		// extension since PostGIS 3, and this splits it from a postgis that
+		// was created by PostGIS 2. Creating it before then fails because its
+		// functions already belong to postgis.
+		// - https://postgis.net/docs/manual-3.0/release_notes.html
+		// - https://postgis.net/docs/PostGIS_Extensions_Upgrade.html
+		statements = append(statements,
+			`SELECT postgis_extensions_upgrade();`,
+			`CREATE EXTENSION IF NOT EXISTS postgis_raster;`,
+		)
+	}
+	statements = append(statements,
+		`CREATE EXTENSION IF NOT EXISTS postgis_topology;`,
+		`CREATE EXTENSION IF NOT EXISTS fuzzystrmatch;`,
+		`CREATE EXTENSION IF NOT EXISTS postgis_tiger_geocoder;`,
+	)
+	if major(version) < 3 {
+		// - https://postgis.net/docs/manual-2.5/postgis_installation.html#upgrading
+		statements = append(statements,
+			`ALTER EXTENSION postgis UPDATE;`,
+			`ALTER EXTENSION postgis_topology UPDATE;`,
+			`ALTER EXTENSION postgis_tiger_geocoder UPDATE;`,
+		)
+	}
+
 	stdout, stderr, err := exec.ExecInAllDatabases(ctx,
-		strings.Join([]string{
-			// Quiet NOTICE messages from IF NOT EXISTS statements.
-			// - https://www.postgresql.org/docs/current/runtime-config-client.html
-			`SET client_min_messages = WARNING;`,
-
-			`CREATE EXTENSION IF NOT EXISTS postgis;`,
-			`CREATE EXTENSION IF NOT EXISTS postgis_topology;`,
-			`CREATE EXTENSION IF NOT EXISTS fuzzystrmatch;`,
-			`CREATE EXTENSION IF NOT EXISTS postgis_tiger_geocoder;`,
-		}, "\n"),
+		strings.Join(statements, "\n"),
 		map[string]string{
 			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
 			"QUIET":         "on", // Do not print successful statements to stdout.
+
+			"postgis_version": version,
 		})
 
 	log.V(1).Info("enabled PostGIS and related extensions", "stdout", stdout, "stderr", stderr)
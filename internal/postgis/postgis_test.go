@@ -24,31 +24,59 @@ import (
 	"testing"
 
 	"gotest.tools/v3/assert"
+
+	"github.com/crunchydata/postgres-operator/internal/postgres"
 )
 
 func TestEnableInPostgreSQL(t *testing.T) {
 	expected := errors.New("whoops")
-	exec := func(
-		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
-	) error {
-		assert.Assert(t, stdout != nil, "should capture stdout")
-		assert.Assert(t, stderr != nil, "should capture stderr")
-
-		assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
-			`SELECT datname FROM pg_catalog.pg_database`,
-		), "expected all databases and templates")
-
-		b, err := ioutil.ReadAll(stdin)
-		assert.NilError(t, err)
-		assert.Equal(t, string(b), `SET client_min_messages = WARNING;
+	statements := func(t *testing.T, version, sql string) postgres.Executor {
+		return func(
+			_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			assert.Assert(t, stdout != nil, "should capture stdout")
+			assert.Assert(t, stderr != nil, "should capture stderr")
+
+			assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+				`SELECT datname FROM pg_catalog.pg_database`,
+			), "expected all databases and templates")
+
+			// The version is passed so the SQL changes with it.
+			assert.Assert(t, strings.Contains(strings.Join(command, "\n"),
+				`--set=postgis_version=`+version))
+
+			b, err := ioutil.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Equal(t, string(b), sql)
+
+			return expected
+		}
+	}
+
+	ctx := context.Background()
+
+	t.Run("PostGIS3", func(t *testing.T) {
+		exec := statements(t, "3.1", `SET client_min_messages = WARNING;
 CREATE EXTENSION IF NOT EXISTS postgis;
+SELECT postgis_extensions_upgrade();
+CREATE EXTENSION IF NOT EXISTS postgis_raster;
 CREATE EXTENSION IF NOT EXISTS postgis_topology;
 CREATE EXTENSION IF NOT EXISTS fuzzystrmatch;
 CREATE EXTENSION IF NOT EXISTS postgis_tiger_geocoder;`)
 
-		return expected
-	}
+		assert.Equal(t, expected, EnableInPostgreSQL(ctx, exec, "3.1"))
+	})
 
-	ctx := context.Background()
-	assert.Equal(t, expected, EnableInPostgreSQL(ctx, exec))
+	t.Run("PostGIS2", func(t *testing.T) {
+		exec := statements(t, "2.5", `SET client_min_messages = WARNING;
+CREATE EXTENSION IF NOT EXISTS postgis;
+CREATE EXTENSION IF NOT EXISTS postgis_topology;
+CREATE EXTENSION IF NOT EXISTS fuzzystrmatch;
+CREATE EXTENSION IF NOT EXISTS postgis_tiger_geocoder;
+ALTER EXTENSION postgis UPDATE;
+ALTER EXTENSION postgis_topology UPDATE;
+ALTER EXTENSION postgis_tiger_geocoder UPDATE;`)
+
+		assert.Equal(t, expected, EnableInPostgreSQL(ctx, exec, "2.5"))
+	})
 }
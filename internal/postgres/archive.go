@@ -0,0 +1,85 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArchiveFailing returns true when the most recent attempt to archive a WAL
+// file failed, according to pg_stat_archiver. It must be called on a primary.
+// - https://www.postgresql.org/docs/current/monitoring-stats.html#MONITORING-PG-STAT-ARCHIVER-VIEW
+func ArchiveFailing(ctx context.Context, exec Executor) (bool, error) {
+	stdout, _, err := exec.Exec(ctx, strings.NewReader(`
+\pset format unaligned
+\pset tuples_only on
+SELECT last_failed_time IS NOT NULL
+   AND last_failed_time > COALESCE(last_archived_time, '-infinity')
+  FROM pg_catalog.pg_stat_archiver;
+`),
+		map[string]string{
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	return strings.TrimSpace(stdout) == "t", err
+}
+
+// LastArchivedTime returns the time PostgreSQL last archived a WAL file,
+// according to pg_stat_archiver. It returns the zero time when PostgreSQL has
+// never archived a file. It must be called on a primary.
+func LastArchivedTime(ctx context.Context, exec Executor) (time.Time, error) {
+	stdout, _, err := exec.Exec(ctx, strings.NewReader(`
+\pset format unaligned
+\pset tuples_only on
+SELECT EXTRACT(epoch FROM last_archived_time)::bigint
+  FROM pg_catalog.pg_stat_archiver;
+`),
+		map[string]string{
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	var last time.Time
+	if seconds := strings.TrimSpace(stdout); err == nil && seconds != "" {
+		var epoch int64
+		if epoch, err = strconv.ParseInt(seconds, 10, 64); err == nil {
+			last = time.Unix(epoch, 0).UTC()
+		}
+	}
+	return last, err
+}
+
+// LastReplayedLocation returns the WAL location that PostgreSQL last replayed
+// during recovery, e.g. "0/3000148". It returns an empty string when nothing
+// has been replayed. It must be called on a standby.
+// - https://www.postgresql.org/docs/current/functions-admin.html#FUNCTIONS-RECOVERY-INFO-TABLE
+func LastReplayedLocation(ctx context.Context, exec Executor) (string, error) {
+	stdout, _, err := exec.Exec(ctx, strings.NewReader(`
+\pset format unaligned
+\pset tuples_only on
+SELECT pg_catalog.pg_last_wal_replay_lsn();
+`),
+		map[string]string{
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	return strings.TrimSpace(stdout), err
+}
@@ -0,0 +1,134 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestArchiveFailing(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Arguments", func(t *testing.T) {
+		expected := errors.New("pass-through")
+		exec := func(
+			_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			b, err := ioutil.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Assert(t, strings.Contains(string(b), "pg_stat_archiver"))
+			assert.Assert(t, strings.Contains(strings.Join(command, " "), "--set=QUIET=on"))
+			return expected
+		}
+
+		failing, err := ArchiveFailing(ctx, exec)
+		assert.Equal(t, expected, err)
+		assert.Assert(t, !failing)
+	})
+
+	for _, tt := range []struct {
+		stdout   string
+		expected bool
+	}{
+		{stdout: "t\n", expected: true},
+		{stdout: "f\n", expected: false},
+		{stdout: "", expected: false},
+	} {
+		exec := func(
+			_ context.Context, _ io.Reader, stdout, _ io.Writer, _ ...string,
+		) error {
+			_, err := io.WriteString(stdout, tt.stdout)
+			return err
+		}
+
+		failing, err := ArchiveFailing(ctx, exec)
+		assert.NilError(t, err)
+		assert.Equal(t, failing, tt.expected, "stdout: %q", tt.stdout)
+	}
+}
+
+func TestLastArchivedTime(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		stdout   string
+		expected time.Time
+	}{
+		{stdout: "1633222900\n", expected: time.Unix(1633222900, 0).UTC()},
+		{stdout: "\n", expected: time.Time{}},
+	} {
+		exec := func(
+			_ context.Context, stdin io.Reader, stdout, _ io.Writer, _ ...string,
+		) error {
+			b, err := ioutil.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Assert(t, strings.Contains(string(b), "pg_stat_archiver"))
+
+			_, err = io.WriteString(stdout, tt.stdout)
+			return err
+		}
+
+		last, err := LastArchivedTime(ctx, exec)
+		assert.NilError(t, err)
+		assert.Equal(t, last, tt.expected, "stdout: %q", tt.stdout)
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := LastArchivedTime(ctx, func(
+			_ context.Context, _ io.Reader, stdout, _ io.Writer, _ ...string,
+		) error {
+			_, err := io.WriteString(stdout, "nope")
+			return err
+		})
+		assert.Assert(t, err != nil)
+	})
+}
+
+func TestLastReplayedLocation(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		stdout   string
+		expected string
+	}{
+		{stdout: "0/3000148\n", expected: "0/3000148"},
+		{stdout: "\n", expected: ""},
+	} {
+		exec := func(
+			_ context.Context, stdin io.Reader, stdout, _ io.Writer, command ...string,
+		) error {
+			b, err := ioutil.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Assert(t, strings.Contains(string(b), "pg_last_wal_replay_lsn"))
+			assert.Assert(t, strings.Contains(strings.Join(command, " "), "--set=QUIET=on"))
+
+			_, err = io.WriteString(stdout, tt.stdout)
+			return err
+		}
+
+		location, err := LastReplayedLocation(ctx, exec)
+		assert.NilError(t, err)
+		assert.Equal(t, location, tt.expected, "stdout: %q", tt.stdout)
+	}
+}
@@ -104,9 +104,16 @@ func Environment(cluster *v1beta1.PostgresCluster) []corev1.EnvVar {
 }
 
 // reloadCommand returns an entrypoint that convinces PostgreSQL to reload
-// certificate files when they change. The process will appear as name in `ps`
-// and `top`.
-func reloadCommand(name string) []string {
+// certificate files when they change. When logRetentionDays is positive, it
+// also rotates the pgBackRest log files of the Pod. When pruneServerLogs is
+// true, it also deletes old PostgreSQL log files from the log volume. The
+// process will appear as name in `ps` and `top`.
+func reloadCommand(name string, logRetentionDays int32, pruneServerLogs bool) []string {
+	var prune string
+	if pruneServerLogs {
+		prune = PruneLogsScript(logMountPath, serverLogRetentionDays)
+	}
+
 	// Use a Bash loop to periodically check the mtime of the mounted
 	// certificate volume. When it changes, copy the replication certificate,
 	// signal PostgreSQL, and print the observed timestamp.
@@ -138,7 +145,7 @@ while read -r -t 5 -u "${fd}" || true; do
   then
     exec {fd}>&- && exec {fd}<> <(:)
     stat --format='Loaded certificates dated %%y' "${directory}"
-  fi
+  fi%s%s
 done
 `,
 		naming.CertMountPath,
@@ -146,6 +153,8 @@ done
 		naming.ReplicationCertPath,
 		naming.ReplicationPrivateKeyPath,
 		naming.ReplicationCACertPath,
+		RotateLogsScript("/tmp", logRetentionDays),
+		prune,
 	)
 
 	// Elide the above script from `ps` and `top` by wrapping it in a function
@@ -19,14 +19,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
 
 	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 // CreateDatabasesInPostgreSQL calls exec to create databases that do not exist
-// in PostgreSQL.
+// in PostgreSQL. The locale settings of each database apply only when it is
+// created.
 func CreateDatabasesInPostgreSQL(
-	ctx context.Context, exec Executor, databases []string,
+	ctx context.Context, exec Executor, databases []v1beta1.PostgresDatabaseSpec,
 ) error {
 	log := logging.FromContext(ctx)
 
@@ -49,20 +55,60 @@ CREATE TEMPORARY TABLE input (id serial, data json);
 	encoder := json.NewEncoder(&sql)
 	encoder.SetEscapeHTML(false)
 
-	for i := range databases {
+	for _, spec := range templatesFirst(databases) {
+		data := map[string]interface{}{"database": spec.Name}
+
+		// Only set fields are encoded so that the SQL of a database without
+		// locale settings is unchanged. A database with a locale different
+		// from "template1" must be copied from "template0".
+		// - https://www.postgresql.org/docs/current/manage-ag-templatedbs.html
+		for key, value := range map[string]string{
+			"locale":         spec.Locale,
+			"lcCollate":      spec.LCCollate,
+			"lcCtype":        spec.LCCtype,
+			"localeProvider": spec.LocaleProvider,
+			"icuLocale":      spec.ICULocale,
+		} {
+			if value != "" {
+				data[key] = value
+				data["template"] = "template0"
+			}
+		}
+		if _, locale := data["template"]; !locale && spec.Template != "" {
+			data["template"] = spec.Template
+		} else if locale && spec.Template != "" && spec.Template != "template0" && err == nil {
+			err = errors.Errorf(`database %q: locale settings require template "template0", not %q`,
+				spec.Name, spec.Template)
+		}
+
 		if err == nil {
-			err = encoder.Encode(map[string]interface{}{
-				"database": databases[i],
-			})
+			err = encoder.Encode(data)
 		}
 	}
 	_, _ = sql.WriteString(`\.` + "\n")
+	if err != nil {
+		return err
+	}
 
-	// Create databases that do not already exist.
+	// Create databases that do not already exist. Options that are missing
+	// from the input are NULL and left out by "concat_ws".
 	// - https://www.postgresql.org/docs/current/sql-createdatabase.html
 	_, _ = sql.WriteString(`
-SELECT pg_catalog.format('CREATE DATABASE %I',
-       pg_catalog.json_extract_path_text(input.data, 'database'))
+SELECT pg_catalog.concat_ws(' ',
+       pg_catalog.format('CREATE DATABASE %I',
+       pg_catalog.json_extract_path_text(input.data, 'database')),
+       'TEMPLATE ' || pg_catalog.quote_ident(
+       pg_catalog.json_extract_path_text(input.data, 'template')),
+       'LOCALE ' || pg_catalog.quote_literal(
+       pg_catalog.json_extract_path_text(input.data, 'locale')),
+       'LC_COLLATE ' || pg_catalog.quote_literal(
+       pg_catalog.json_extract_path_text(input.data, 'lcCollate')),
+       'LC_CTYPE ' || pg_catalog.quote_literal(
+       pg_catalog.json_extract_path_text(input.data, 'lcCtype')),
+       'LOCALE_PROVIDER ' || pg_catalog.quote_ident(
+       pg_catalog.json_extract_path_text(input.data, 'localeProvider')),
+       'ICU_LOCALE ' || pg_catalog.quote_literal(
+       pg_catalog.json_extract_path_text(input.data, 'icuLocale')))
   FROM input
  WHERE NOT EXISTS (
        SELECT 1 FROM pg_catalog.pg_database
@@ -71,7 +117,7 @@ SELECT pg_catalog.format('CREATE DATABASE %I',
 \gexec
 `)
 
-	stdout, stderr, err := exec.Exec(ctx, &sql,
+	stdout, stderr, err := exec.Exec(networkScript(ctx), &sql,
 		map[string]string{
 			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
 			"QUIET":         "on", // Do not print successful statements to stdout.
@@ -81,3 +127,75 @@ SELECT pg_catalog.format('CREATE DATABASE %I',
 
 	return err
 }
+
+// templatesFirst returns databases in an order that creates each template
+// before the databases that are copied from it. Otherwise, the order is kept.
+func templatesFirst(databases []v1beta1.PostgresDatabaseSpec) []v1beta1.PostgresDatabaseSpec {
+	waiting := make(map[v1beta1.PostgresIdentifier]bool, len(databases))
+	for i := range databases {
+		waiting[databases[i].Name] = true
+	}
+
+	ordered := make([]v1beta1.PostgresDatabaseSpec, 0, len(databases))
+	for len(ordered) < len(databases) {
+		before := len(ordered)
+		for i := range databases {
+			spec := databases[i]
+			if waiting[spec.Name] && (spec.Template == spec.Name || !waiting[spec.Template]) {
+				ordered = append(ordered, spec)
+				waiting[spec.Name] = false
+			}
+		}
+
+		// Templates that copy one another cannot be ordered; keep the rest.
+		if len(ordered) == before {
+			for i := range databases {
+				if waiting[databases[i].Name] {
+					ordered = append(ordered, databases[i])
+					waiting[databases[i].Name] = false
+				}
+			}
+		}
+	}
+	return ordered
+}
+
+// CollationVersionMismatches returns the names of databases whose collations
+// were defined by a different version of the collation library than the one
+// PostgreSQL now uses, sorted. This happens when the operating system or ICU
+// in the PostgreSQL image changes, and indexes that depend on those collations
+// may be corrupt. PostgreSQL v15 also tracks the version of the default
+// collation of each database.
+// - https://www.postgresql.org/docs/current/sql-altercollation.html#SQL-ALTERCOLLATION-NOTES
+func CollationVersionMismatches(
+	ctx context.Context, exec Executor, postgresVersion int,
+) ([]string, error) {
+	mismatch := `SELECT 1 FROM pg_catalog.pg_collation` +
+		` WHERE collversion IS NOT NULL` +
+		` AND collversion IS DISTINCT FROM pg_catalog.pg_collation_actual_version(oid)`
+
+	if postgresVersion >= 15 {
+		mismatch += ` UNION ALL SELECT 1 FROM pg_catalog.pg_database` +
+			` WHERE datname = pg_catalog.current_database()` +
+			` AND datcollversion IS NOT NULL` +
+			` AND datcollversion IS DISTINCT FROM pg_catalog.pg_database_collation_actual_version(oid)`
+	}
+
+	stdout, _, err := exec.ExecInAllDatabases(ctx, `
+\pset format unaligned
+\pset tuples_only on
+SELECT pg_catalog.current_database() WHERE EXISTS (`+mismatch+`);
+`,
+		map[string]string{
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	var names []string
+	if err == nil {
+		// Database names can contain spaces but not newlines.
+		names = strings.FieldsFunc(stdout, func(r rune) bool { return r == '\n' })
+		sort.Strings(names)
+	}
+	return names, err
+}
@@ -25,6 +25,8 @@ import (
 
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/assert/cmp"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 func TestCreateDatabasesInPostgreSQL(t *testing.T) {
@@ -67,8 +69,21 @@ CREATE TEMPORARY TABLE input (id serial, data json);
 \copy input (data) from stdin with (format text)
 \.
 
-SELECT pg_catalog.format('CREATE DATABASE %I',
-       pg_catalog.json_extract_path_text(input.data, 'database'))
+SELECT pg_catalog.concat_ws(' ',
+       pg_catalog.format('CREATE DATABASE %I',
+       pg_catalog.json_extract_path_text(input.data, 'database')),
+       'TEMPLATE ' || pg_catalog.quote_ident(
+       pg_catalog.json_extract_path_text(input.data, 'template')),
+       'LOCALE ' || pg_catalog.quote_literal(
+       pg_catalog.json_extract_path_text(input.data, 'locale')),
+       'LC_COLLATE ' || pg_catalog.quote_literal(
+       pg_catalog.json_extract_path_text(input.data, 'lcCollate')),
+       'LC_CTYPE ' || pg_catalog.quote_literal(
+       pg_catalog.json_extract_path_text(input.data, 'lcCtype')),
+       'LOCALE_PROVIDER ' || pg_catalog.quote_ident(
+       pg_catalog.json_extract_path_text(input.data, 'localeProvider')),
+       'ICU_LOCALE ' || pg_catalog.quote_literal(
+       pg_catalog.json_extract_path_text(input.data, 'icuLocale')))
   FROM input
  WHERE NOT EXISTS (
        SELECT 1 FROM pg_catalog.pg_database
@@ -82,7 +97,7 @@ SELECT pg_catalog.format('CREATE DATABASE %I',
 		assert.NilError(t, CreateDatabasesInPostgreSQL(ctx, exec, nil))
 		assert.Equal(t, calls, 1)
 
-		assert.NilError(t, CreateDatabasesInPostgreSQL(ctx, exec, []string{}))
+		assert.NilError(t, CreateDatabasesInPostgreSQL(ctx, exec, []v1beta1.PostgresDatabaseSpec{}))
 		assert.Equal(t, calls, 2)
 	})
 
@@ -105,8 +120,115 @@ SELECT pg_catalog.format('CREATE DATABASE %I',
 		}
 
 		assert.NilError(t, CreateDatabasesInPostgreSQL(ctx, exec,
-			[]string{"white space", "eXaCtLy"},
+			[]v1beta1.PostgresDatabaseSpec{{Name: "white space"}, {Name: "eXaCtLy"}},
+		))
+		assert.Equal(t, calls, 1)
+	})
+
+	t.Run("Locale", func(t *testing.T) {
+		calls := 0
+		exec := func(
+			_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			calls++
+
+			b, err := ioutil.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Assert(t, contains(string(b), `
+\copy input (data) from stdin with (format text)
+{"database":"plain"}
+{"database":"libc","lcCollate":"C","locale":"en_US.UTF-8","template":"template0"}
+{"database":"icu","icuLocale":"und-u-ks-level2","localeProvider":"icu","template":"template0"}
+\.
+`))
+			return nil
+		}
+
+		assert.NilError(t, CreateDatabasesInPostgreSQL(ctx, exec,
+			[]v1beta1.PostgresDatabaseSpec{
+				{Name: "plain"},
+				{Name: "libc", Locale: "en_US.UTF-8", LCCollate: "C"},
+				{Name: "icu", LocaleProvider: "icu", ICULocale: "und-u-ks-level2"},
+			},
+		))
+		assert.Equal(t, calls, 1)
+	})
+
+	t.Run("Template", func(t *testing.T) {
+		calls := 0
+		exec := func(
+			_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			calls++
+
+			b, err := ioutil.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Assert(t, contains(string(b), `
+\copy input (data) from stdin with (format text)
+{"database":"other"}
+{"database":"base"}
+{"database":"tenant-template","template":"base"}
+{"database":"tenant-a","template":"tenant-template"}
+{"database":"tenant-b","template":"tenant-template"}
+\.
+`))
+			return nil
+		}
+
+		// Templates in the list are created before their copies.
+		assert.NilError(t, CreateDatabasesInPostgreSQL(ctx, exec,
+			[]v1beta1.PostgresDatabaseSpec{
+				{Name: "tenant-a", Template: "tenant-template"},
+				{Name: "other"},
+				{Name: "tenant-b", Template: "tenant-template"},
+				{Name: "tenant-template", Template: "base"},
+				{Name: "base"},
+			},
 		))
 		assert.Equal(t, calls, 1)
+
+		t.Run("Locale", func(t *testing.T) {
+			exec := func(context.Context, io.Reader, io.Writer, io.Writer, ...string) error {
+				t.Fatal("expected no call to exec")
+				return nil
+			}
+
+			// Locale settings cannot be copied from any other template.
+			assert.ErrorContains(t, CreateDatabasesInPostgreSQL(ctx, exec,
+				[]v1beta1.PostgresDatabaseSpec{
+					{Name: "base"},
+					{Name: "libc", Locale: "en_US.UTF-8", Template: "base"},
+				},
+			), `database "libc": locale settings require template "template0"`)
+		})
 	})
 }
+
+func TestCollationVersionMismatches(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		version  int
+		database bool
+	}{
+		{version: 14, database: false},
+		{version: 15, database: true},
+	} {
+		exec := func(
+			_ context.Context, stdin io.Reader, stdout, _ io.Writer, command ...string,
+		) error {
+			b, err := ioutil.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Assert(t, strings.Contains(string(b), `pg_collation_actual_version`))
+			assert.Equal(t, tt.database,
+				strings.Contains(string(b), `pg_database_collation_actual_version`))
+
+			_, err = io.WriteString(stdout, "white space\napp\n")
+			return err
+		}
+
+		databases, err := CollationVersionMismatches(ctx, exec, tt.version)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, databases, []string{"app", "white space"})
+	}
+}
@@ -23,6 +23,25 @@ import (
 	"strings"
 )
 
+const (
+	// execInDatabasesScript calls "psql" once for every database returned by
+	// the query in its first argument. Standard input is passed to each call.
+	execInDatabasesScript = `
+sql_target=$(< /dev/stdin)
+sql_databases="$1"
+shift 1
+
+databases=$(psql "$@" -Xw -Aqt --file=- <<< "${sql_databases}")
+while IFS= read -r database; do
+	PGDATABASE="${database}" psql "$@" -Xw --file=- <<< "${sql_target}"
+done <<< "${databases}"
+`
+
+	// execInDatabaseScript calls "psql" in the database named by its first
+	// argument.
+	execInDatabaseScript = `PGDATABASE="$1" exec psql "${@:2}" -Xw --file=-`
+)
+
 // Executor provides methods for calling "psql".
 type Executor func(
 	ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
@@ -95,20 +114,35 @@ func (exec Executor) ExecInDatabasesFromQuery(
 	// - https://golang.org/ref/spec#For_range
 	sort.Strings(args[1:])
 
-	const script = `
-sql_target=$(< /dev/stdin)
-sql_databases="$1"
-shift 1
-
-databases=$(psql "$@" -Xw -Aqt --file=- <<< "${sql_databases}")
-while IFS= read -r database; do
-	PGDATABASE="${database}" psql "$@" -Xw --file=- <<< "${sql_target}"
-done <<< "${databases}"
-`
-
 	// Execute the script with some error handling enabled.
 	var stdout, stderr bytes.Buffer
 	err := exec(ctx, stdin, &stdout, &stderr,
-		append([]string{"bash", "-ceu", "--", script, "-"}, args...)...)
+		append([]string{"bash", "-ceu", "--", execInDatabasesScript, "-"}, args...)...)
+	return stdout.String(), stderr.String(), err
+}
+
+// ExecInDatabase uses "bash" and "psql" to execute sql in database. The sql
+// statement(s) are passed via stdin and may contain psql variables that are
+// assigned from the variables map.
+// - https://www.postgresql.org/docs/current/app-psql.html#APP-PSQL-VARIABLES
+func (exec Executor) ExecInDatabase(
+	ctx context.Context, database string, sql io.Reader, variables map[string]string,
+) (string, string, error) {
+	// Pass the database name as the first argument and assign it to the
+	// PGDATABASE environment variable so that it is not interpreted as a
+	// connection string. Remaining arguments are passed through to `psql`.
+	args := []string{database}
+	for k, v := range variables {
+		args = append(args, "--set="+k+"="+v)
+	}
+
+	// The map iteration above is nondeterministic. Sort the variable arguments
+	// so that calls to exec are deterministic.
+	// - https://golang.org/ref/spec#For_range
+	sort.Strings(args[1:])
+
+	var stdout, stderr bytes.Buffer
+	err := exec(ctx, sql, &stdout, &stderr,
+		append([]string{"bash", "-ceu", "--", execInDatabaseScript, "-"}, args...)...)
 	return stdout.String(), stderr.String(), err
 }
@@ -208,3 +208,38 @@ done <<< "${databases}"
 		}).ExecInDatabasesFromQuery(context.Background(), "", "", nil)
 	})
 }
+
+func TestExecutorExecInDatabase(t *testing.T) {
+	expected := errors.New("boom")
+	fn := func(
+		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		b, err := ioutil.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Equal(t, string(b), `statements; to run;`)
+
+		assert.DeepEqual(t, command, []string{
+			"bash", "-ceu", "--",
+			`PGDATABASE="$1" exec psql "${@:2}" -Xw --file=-`,
+			"-",
+			"host=127.0.0.1",
+			"--set=CASE=sEnSiTiVe",
+			"--set=different=vars",
+		})
+
+		_, _ = io.WriteString(stdout, "some stdout")
+		_, _ = io.WriteString(stderr, "and stderr")
+		return expected
+	}
+
+	stdout, stderr, err := Executor(fn).ExecInDatabase(
+		context.Background(), "host=127.0.0.1",
+		strings.NewReader(`statements; to run;`), map[string]string{
+			"different": "vars",
+			"CASE":      "sEnSiTiVe",
+		})
+
+	assert.Equal(t, expected, err, "expected function to be called")
+	assert.Equal(t, stdout, "some stdout")
+	assert.Equal(t, stderr, "and stderr")
+}
@@ -0,0 +1,215 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// ForeignCredentials are the remote credentials of a user mapping.
+type ForeignCredentials struct {
+	User     string
+	Password string
+}
+
+// WriteForeignServersInPostgreSQL calls exec to create the extension of each
+// foreign data wrapper, the foreign servers that do not exist, and their user
+// mappings in the databases of servers. It updates the options of servers and
+// replaces their user mappings. The credentials of each user mapping are in
+// credentials by Secret name; mappings without credentials are skipped. The
+// databases and local users must already exist.
+func WriteForeignServersInPostgreSQL(
+	ctx context.Context, exec Executor,
+	servers []v1beta1.PostgresForeignServerSpec, credentials map[string]ForeignCredentials,
+) error {
+	log := logging.FromContext(ctx)
+
+	type mapping struct {
+		User       v1beta1.PostgresIdentifier `json:"user"`
+		RemoteUser string                     `json:"remote_user"`
+		Password   string                     `json:"password"`
+	}
+	type server struct {
+		Mappings []mapping                  `json:"mappings,omitempty"`
+		Name     v1beta1.PostgresIdentifier `json:"name"`
+		Options  map[string]string          `json:"options,omitempty"`
+		Wrapper  string                     `json:"wrapper"`
+	}
+
+	// Group the servers by database.
+	inputs := make(map[string][]server)
+	for i := range servers {
+		spec := servers[i]
+		input := server{Name: spec.Name, Options: spec.Options, Wrapper: spec.Wrapper}
+		for _, m := range spec.UserMappings {
+			if c, ok := credentials[m.SecretName]; ok {
+				input.Mappings = append(input.Mappings, mapping{
+					User: m.User, RemoteUser: c.User, Password: c.Password,
+				})
+			}
+		}
+		inputs[string(spec.Database)] = append(inputs[string(spec.Database)], input)
+	}
+
+	// The map iteration above is nondeterministic. Sort the databases so that
+	// calls to exec are deterministic.
+	// - https://golang.org/ref/spec#For_range
+	databases := make([]string, 0, len(inputs))
+	for database := range inputs {
+		databases = append(databases, database)
+	}
+	sort.Strings(databases)
+
+	var err error
+	for _, database := range databases {
+		var sql bytes.Buffer
+
+		// Quiet the NOTICE from IF EXISTS and IF NOT EXISTS.
+		// - https://www.postgresql.org/docs/current/runtime-config-client.html
+		_, _ = sql.WriteString(`SET client_min_messages = WARNING;`)
+
+		// Fill a temporary table with the JSON of the servers. Credentials are
+		// passed this way so they do not appear in any command line.
+		// "\copy" reads from subsequent lines until the special line "\.".
+		// - https://www.postgresql.org/docs/current/app-psql.html#APP-PSQL-META-COMMANDS-COPY
+		_, _ = sql.WriteString(`
+CREATE TEMPORARY TABLE input (id serial, data json);
+\copy input (data) from stdin with (format text)
+`)
+		// The text format of COPY treats backslash as an escape character, so
+		// double those that JSON uses for its own escapes.
+		// - https://www.postgresql.org/docs/current/sql-copy.html
+		var line bytes.Buffer
+		encoder := json.NewEncoder(&line)
+		encoder.SetEscapeHTML(false)
+
+		for i := range inputs[database] {
+			if err == nil {
+				line.Reset()
+				err = encoder.Encode(inputs[database][i])
+				_, _ = sql.Write(bytes.ReplaceAll(line.Bytes(), []byte(`\`), []byte(`\\`)))
+			}
+		}
+		_, _ = sql.WriteString(`\.` + "\n")
+
+		// Change everything in a transaction so that no session sees a server
+		// without its user mappings.
+		_, _ = sql.WriteString(`BEGIN;`)
+
+		// Create the extension of each wrapper in the default schema.
+		// - https://www.postgresql.org/docs/current/sql-createextension.html
+		_, _ = sql.WriteString(`
+SELECT DISTINCT pg_catalog.format('CREATE EXTENSION IF NOT EXISTS %I',
+       pg_catalog.json_extract_path_text(input.data, 'wrapper'))
+  FROM input
+\gexec
+`)
+
+		// Prevent unexpected dereferences by emptying "search_path". The
+		// "pg_catalog" schema is still searched.
+		// - https://www.postgresql.org/docs/current/runtime-config-client.html#GUC-SEARCH-PATH
+		_, _ = sql.WriteString(`SET LOCAL search_path TO '';`)
+
+		// Create servers that do not already exist.
+		// - https://www.postgresql.org/docs/current/sql-createserver.html
+		_, _ = sql.WriteString(`
+SELECT pg_catalog.format('CREATE SERVER %I FOREIGN DATA WRAPPER %I',
+       pg_catalog.json_extract_path_text(input.data, 'name'),
+       pg_catalog.json_extract_path_text(input.data, 'wrapper'))
+  FROM input
+ WHERE NOT EXISTS (
+       SELECT 1 FROM pg_catalog.pg_foreign_server
+       WHERE srvname = pg_catalog.json_extract_path_text(input.data, 'name'))
+ ORDER BY input.id
+\gexec
+`)
+
+		// Remove options that are not specified, then add or set the others.
+		// - https://www.postgresql.org/docs/current/sql-alterserver.html
+		_, _ = sql.WriteString(`
+SELECT pg_catalog.format('ALTER SERVER %I OPTIONS (DROP %I)', srv.srvname, existing.option_name)
+  FROM input
+  JOIN pg_catalog.pg_foreign_server AS srv
+    ON srv.srvname = pg_catalog.json_extract_path_text(input.data, 'name')
+ CROSS JOIN LATERAL pg_catalog.pg_options_to_table(srv.srvoptions) AS existing
+ WHERE pg_catalog.json_extract_path(input.data, 'options', existing.option_name) IS NULL
+ ORDER BY input.id
+\gexec
+
+SELECT pg_catalog.format('ALTER SERVER %I OPTIONS (%s %I %L)', srv.srvname,
+       CASE WHEN EXISTS (
+            SELECT 1 FROM pg_catalog.pg_options_to_table(srv.srvoptions)
+            WHERE option_name = specified.key) THEN 'SET' ELSE 'ADD' END,
+       specified.key, specified.value)
+  FROM input
+  JOIN pg_catalog.pg_foreign_server AS srv
+    ON srv.srvname = pg_catalog.json_extract_path_text(input.data, 'name')
+ CROSS JOIN LATERAL pg_catalog.json_each_text(
+       pg_catalog.json_extract_path(input.data, 'options')) AS specified
+ ORDER BY input.id
+\gexec
+`)
+
+		// Replace the user mappings. The PUBLIC mapping applies to every user.
+		// - https://www.postgresql.org/docs/current/sql-createusermapping.html
+		_, _ = sql.WriteString(`
+SELECT pg_catalog.format('DROP USER MAPPING IF EXISTS FOR %s SERVER %I',
+       mapping.role, pg_catalog.json_extract_path_text(input.data, 'name')),
+       pg_catalog.format('CREATE USER MAPPING FOR %s SERVER %I OPTIONS (user %L, password %L)',
+       mapping.role, pg_catalog.json_extract_path_text(input.data, 'name'),
+       mapping.remote_user, mapping.password)
+  FROM input
+ CROSS JOIN LATERAL (
+       SELECT CASE WHEN m."user" = 'PUBLIC' THEN 'PUBLIC'
+                   ELSE pg_catalog.quote_ident(m."user") END AS role,
+              m.remote_user, m.password
+         FROM pg_catalog.json_to_recordset(
+              pg_catalog.json_extract_path(input.data, 'mappings'))
+           AS m ("user" text, remote_user text, password text)
+       ) AS mapping
+ ORDER BY input.id
+\gexec
+`)
+
+		// Commit (finish) the transaction.
+		_, _ = sql.WriteString(`COMMIT;`)
+
+		if err != nil {
+			break
+		}
+
+		var stdout, stderr string
+		stdout, stderr, err = exec.ExecInDatabase(ctx, database, &sql,
+			map[string]string{
+				"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+				"QUIET":         "on", // Do not print successful statements to stdout.
+			})
+
+		log.V(1).Info("wrote foreign servers", "database", database, "stdout", stdout, "stderr", stderr)
+
+		if err != nil {
+			break
+		}
+	}
+
+	return err
+}
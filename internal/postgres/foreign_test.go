@@ -0,0 +1,107 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestWriteForeignServersInPostgreSQL(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Arguments", func(t *testing.T) {
+		expected := errors.New("pass-through")
+		exec := func(
+			_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			assert.Assert(t, stdout != nil, "should capture stdout")
+			assert.Assert(t, stderr != nil, "should capture stderr")
+			assert.Assert(t, strings.Contains(strings.Join(command, "\n"), `PGDATABASE="$1"`))
+			return expected
+		}
+
+		assert.Equal(t, expected, WriteForeignServersInPostgreSQL(ctx, exec,
+			[]v1beta1.PostgresForeignServerSpec{{Name: "one", Database: "app"}}, nil))
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		calls := 0
+		exec := func(
+			_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			calls++
+			return nil
+		}
+
+		assert.NilError(t, WriteForeignServersInPostgreSQL(ctx, exec, nil, nil))
+		assert.Equal(t, calls, 0)
+	})
+
+	t.Run("Servers", func(t *testing.T) {
+		var databases []string
+		var scripts []string
+		exec := func(
+			_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			databases = append(databases, command[5])
+			b, err := ioutil.ReadAll(stdin)
+			scripts = append(scripts, string(b))
+			return err
+		}
+
+		assert.NilError(t, WriteForeignServersInPostgreSQL(ctx, exec,
+			[]v1beta1.PostgresForeignServerSpec{
+				{
+					Name: "reports", Database: "sales", Wrapper: "postgres_fdw",
+					Options: map[string]string{"host": "db.example.com", "dbname": "reports"},
+					UserMappings: []v1beta1.PostgresUserMappingSpec{
+						{User: "PUBLIC", SecretName: "everyone"},
+						{User: "ghost", SecretName: "missing"},
+					},
+				},
+				{Name: "erp", Database: "app", Wrapper: "oracle_fdw"},
+			},
+			map[string]ForeignCredentials{
+				"everyone": {User: "reader", Password: `back\slash`},
+			}))
+
+		// One call per database, in order.
+		assert.DeepEqual(t, databases, []string{"app", "sales"})
+
+		assert.Assert(t, strings.Contains(scripts[0],
+			"\n"+`{"name":"erp","wrapper":"oracle_fdw"}`+"\n"), "got %q", scripts[0])
+
+		// Backslashes are escaped for COPY; mappings without credentials are skipped.
+		assert.Assert(t, strings.Contains(scripts[1], "\n"+
+			`{"mappings":[{"user":"PUBLIC","remote_user":"reader","password":"back\\\\slash"}],`+
+			`"name":"reports","options":{"dbname":"reports","host":"db.example.com"},`+
+			`"wrapper":"postgres_fdw"}`+"\n"), "got %q", scripts[1])
+
+		for _, script := range scripts {
+			assert.Assert(t, strings.Contains(script, "\nBEGIN;"))
+			assert.Assert(t, strings.HasSuffix(script, "COMMIT;"))
+		}
+	})
+}
@@ -0,0 +1,171 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// logMountPath is where to mount the optional log volume.
+const logMountPath = "/pglog"
+
+// LogVolumeMount returns the name and mount path of the PostgreSQL log volume.
+func LogVolumeMount() corev1.VolumeMount {
+	return corev1.VolumeMount{Name: "postgres-logs", MountPath: logMountPath}
+}
+
+// LoggingEnabled returns true when the logging spec of cluster configures the
+// server log of PostgreSQL. Settings for PgBouncer or pgBackRest alone do not.
+func LoggingEnabled(cluster *v1beta1.PostgresCluster) bool {
+	logging := cluster.Spec.Logging
+	return logging != nil && (logging.Destination != "" ||
+		logging.Rotation != nil || logging.Volume != nil || logging.Shipper != nil)
+}
+
+// LogDestination returns the "log_destination" of cluster. It is empty when
+// PostgreSQL should write to standard error without a logging collector.
+// - https://www.postgresql.org/docs/current/runtime-config-logging.html
+func LogDestination(cluster *v1beta1.PostgresCluster) string {
+	if !LoggingEnabled(cluster) {
+		return ""
+	}
+
+	destination := cluster.Spec.Logging.Destination
+	if destination == "" {
+		destination = "csvlog"
+	}
+
+	// The "jsonlog" format was introduced in PostgreSQL 15.
+	if destination == "jsonlog" && cluster.Spec.PostgresVersion < 15 {
+		destination = "csvlog"
+	}
+	return destination
+}
+
+// LogFilePattern returns a glob that matches the log files of cluster.
+func LogFilePattern(cluster *v1beta1.PostgresCluster) string {
+	// PostgreSQL replaces the ".log" suffix of "log_filename" according to
+	// the format of each file.
+	switch LogDestination(cluster) {
+	case "csvlog":
+		return logMountPath + "/*.csv"
+	case "jsonlog":
+		return logMountPath + "/*.json"
+	}
+	return logMountPath + "/*.log"
+}
+
+// LoggingParameters sets the PostgreSQL parameters that write server logs to
+// the log volume according to the logging spec of cluster.
+func LoggingParameters(cluster *v1beta1.PostgresCluster, outParameters *Parameters) {
+	if !LoggingEnabled(cluster) {
+		return
+	}
+
+	outParameters.Mandatory.Add("logging_collector", "on")
+	outParameters.Mandatory.Add("log_directory", logMountPath)
+	outParameters.Mandatory.Add("log_destination", LogDestination(cluster))
+
+	if TimestampedLogs(cluster) {
+		// Rotation by size or more than once a day would reopen or truncate a
+		// file named by weekday. Name each file by the time it starts instead;
+		// the reload sidecar removes old files. See PruneLogsScript.
+		outParameters.Default.Add("log_filename", "postgresql-%Y-%m-%d_%H%M%S.log")
+		outParameters.Default.Add("log_truncate_on_rotation", "off")
+	} else {
+		// Name files by weekday and truncate them when time-based rotation
+		// comes around to the same name. This keeps one week of logs.
+		outParameters.Default.Add("log_filename", "postgresql-%a.log")
+		outParameters.Default.Add("log_truncate_on_rotation", "on")
+	}
+	outParameters.Default.Add("log_rotation_age", "1d")
+	outParameters.Default.Add("log_rotation_size", "0")
+
+	if rotation := cluster.Spec.Logging.Rotation; rotation != nil {
+		if rotation.Age != "" {
+			outParameters.Mandatory.Add("log_rotation_age", rotation.Age)
+		}
+		if rotation.Size != nil {
+			// The unit of "log_rotation_size" is kilobytes; round up.
+			kilobytes := (rotation.Size.Value() + 1023) / 1024
+			outParameters.Mandatory.Add("log_rotation_size", fmt.Sprintf("%dkB", kilobytes))
+		}
+	}
+}
+
+// serverLogRetentionDays is the number of days of timestamped PostgreSQL log
+// files kept in each Pod. It matches the week of files named by weekday.
+const serverLogRetentionDays = 7
+
+// TimestampedLogs returns true when the logging spec of cluster rotates
+// PostgreSQL log files by size or age, so each file is named by the time it
+// starts. Otherwise, files are named by weekday.
+func TimestampedLogs(cluster *v1beta1.PostgresCluster) bool {
+	if !LoggingEnabled(cluster) {
+		return false
+	}
+	rotation := cluster.Spec.Logging.Rotation
+	return rotation != nil && (rotation.Age != "" || rotation.Size != nil)
+}
+
+// PruneLogsScript returns Bash that deletes PostgreSQL log files in directory
+// older than days. It belongs in the body of a loop and looks for files at
+// most once an hour. It is empty when days is not positive.
+func PruneLogsScript(directory string, days int32) string {
+	if days <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(`
+  printf -v now '%%(%%s)T' -1
+  if (( now - ${pruned-0} >= 3600 )); then
+    find %[1]q -maxdepth 1 -type f -name 'postgresql-*' -mtime +%[2]d -delete
+    pruned="${now}"
+  fi`, directory, days)
+}
+
+// RotateLogsScript returns Bash that renames the "*.log" files of directory
+// with the date they were written and deletes renamed files older than days.
+// It belongs in the body of a loop and renames files once a day, when the date
+// changes; nothing happens the first time it runs. It is empty when days is not
+// positive.
+func RotateLogsScript(directory string, days int32) string {
+	if days <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(`
+  printf -v today '%%(%%F)T' -1
+  if [[ -n "${rotated-}" && "${today}" != "${rotated}" ]]; then
+    for file in %[1]q/*.log; do
+      if [[ -f "${file}" ]]; then mv "${file}" "${file}.${rotated}"; fi
+    done
+    find %[1]q -maxdepth 1 -name '*.log.*' -mtime +%[2]d -delete
+  fi
+  rotated="${today}"`, directory, days)
+}
+
+// PGBackRestLogRetention returns the number of days of pgBackRest log files
+// that cluster keeps in each Pod. It is zero when files are never rotated.
+func PGBackRestLogRetention(cluster *v1beta1.PostgresCluster) int32 {
+	if logging := cluster.Spec.Logging; logging != nil &&
+		logging.PGBackRest != nil && logging.PGBackRest.RetentionDays != nil {
+		return *logging.PGBackRest.RetentionDays
+	}
+	return 0
+}
@@ -0,0 +1,177 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestLogVolumeMount(t *testing.T) {
+	mount := LogVolumeMount()
+
+	assert.DeepEqual(t, mount, corev1.VolumeMount{
+		Name:      "postgres-logs",
+		MountPath: "/pglog",
+		ReadOnly:  false,
+	})
+}
+
+func TestLogDestination(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.PostgresVersion = 14
+	assert.Equal(t, LogDestination(cluster), "")
+	assert.Equal(t, LogFilePattern(cluster), "/pglog/*.log")
+
+	cluster.Spec.Logging = new(v1beta1.PostgresLoggingSpec)
+	cluster.Spec.Logging.PGBouncer = new(v1beta1.PGBouncerLoggingSpec)
+	assert.Equal(t, LogDestination(cluster), "", "expected no PostgreSQL settings")
+
+	cluster.Spec.Logging.Volume = new(v1beta1.PostgresLogVolumeSpec)
+	assert.Equal(t, LogDestination(cluster), "csvlog")
+	assert.Equal(t, LogFilePattern(cluster), "/pglog/*.csv")
+
+	cluster.Spec.Logging.Destination = "stderr"
+	assert.Equal(t, LogDestination(cluster), "stderr")
+	assert.Equal(t, LogFilePattern(cluster), "/pglog/*.log")
+
+	cluster.Spec.Logging.Destination = "jsonlog"
+	assert.Equal(t, LogDestination(cluster), "csvlog", "expected fallback before PostgreSQL 15")
+
+	cluster.Spec.PostgresVersion = 15
+	assert.Equal(t, LogDestination(cluster), "jsonlog")
+	assert.Equal(t, LogFilePattern(cluster), "/pglog/*.json")
+}
+
+func TestLoggingParameters(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.PostgresVersion = 13
+
+	t.Run("Disabled", func(t *testing.T) {
+		parameters := Parameters{Mandatory: NewParameterSet(), Default: NewParameterSet()}
+		LoggingParameters(cluster, &parameters)
+
+		assert.DeepEqual(t, parameters.Mandatory.AsMap(), map[string]string{})
+		assert.DeepEqual(t, parameters.Default.AsMap(), map[string]string{})
+	})
+
+	t.Run("OtherComponents", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		assert.NilError(t, yaml.Unmarshal([]byte(`{
+			logging: { pgbouncer: { destination: file }, pgbackrest: { retentionDays: 3 } },
+		}`), &cluster.Spec))
+
+		parameters := Parameters{Mandatory: NewParameterSet(), Default: NewParameterSet()}
+		LoggingParameters(cluster, &parameters)
+
+		assert.DeepEqual(t, parameters.Mandatory.AsMap(), map[string]string{})
+		assert.DeepEqual(t, parameters.Default.AsMap(), map[string]string{})
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Logging = &v1beta1.PostgresLoggingSpec{Destination: "csvlog"}
+
+		parameters := Parameters{Mandatory: NewParameterSet(), Default: NewParameterSet()}
+		LoggingParameters(cluster, &parameters)
+
+		assert.DeepEqual(t, parameters.Mandatory.AsMap(), map[string]string{
+			"log_destination":   "csvlog",
+			"log_directory":     "/pglog",
+			"logging_collector": "on",
+		})
+		assert.DeepEqual(t, parameters.Default.AsMap(), map[string]string{
+			"log_filename":             "postgresql-%a.log",
+			"log_rotation_age":         "1d",
+			"log_rotation_size":        "0",
+			"log_truncate_on_rotation": "on",
+		})
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		assert.NilError(t, yaml.Unmarshal([]byte(`{
+			logging: { rotation: { age: 6h, size: 100M } },
+		}`), &cluster.Spec))
+
+		parameters := Parameters{Mandatory: NewParameterSet(), Default: NewParameterSet()}
+		LoggingParameters(cluster, &parameters)
+
+		age, _ := parameters.Mandatory.Get("log_rotation_age")
+		assert.Equal(t, age, "6h")
+
+		size, _ := parameters.Mandatory.Get("log_rotation_size")
+		assert.Equal(t, size, "97657kB")
+	})
+
+	for _, rotation := range []string{`{ size: 100M }`, `{ age: 30min }`} {
+		t.Run("Timestamped"+rotation, func(t *testing.T) {
+			cluster := cluster.DeepCopy()
+			assert.NilError(t, yaml.Unmarshal([]byte(`{
+				logging: { rotation: `+rotation+` },
+			}`), &cluster.Spec))
+			assert.Assert(t, TimestampedLogs(cluster))
+
+			parameters := Parameters{Mandatory: NewParameterSet(), Default: NewParameterSet()}
+			LoggingParameters(cluster, &parameters)
+
+			// Each rotation starts a new file; none are reopened or truncated.
+			filename, _ := parameters.Default.Get("log_filename")
+			assert.Equal(t, filename, "postgresql-%Y-%m-%d_%H%M%S.log")
+
+			truncate, _ := parameters.Default.Get("log_truncate_on_rotation")
+			assert.Equal(t, truncate, "off")
+		})
+	}
+}
+
+func TestRotateLogsScript(t *testing.T) {
+	assert.Equal(t, RotateLogsScript("/tmp", 0), "")
+
+	script := RotateLogsScript("/tmp", 7)
+	assert.Assert(t, strings.Contains(script, `for file in "/tmp"/*.log; do`), "%s", script)
+	assert.Assert(t, strings.Contains(script, `-mtime +7 -delete`), "%s", script)
+
+	cluster := new(v1beta1.PostgresCluster)
+	assert.Equal(t, PGBackRestLogRetention(cluster), int32(0))
+
+	cluster.Spec.Logging = &v1beta1.PostgresLoggingSpec{
+		PGBackRest: &v1beta1.PGBackRestLoggingSpec{RetentionDays: initialize.Int32(7)},
+	}
+	assert.Equal(t, PGBackRestLogRetention(cluster), int32(7))
+
+	command := reloadCommand("some-name", 7, false)
+	assert.Assert(t, strings.Contains(command[3], script), "%s", command[3])
+	assert.Assert(t, !strings.Contains(command[3], "postgresql-*"), "%s", command[3])
+}
+
+func TestPruneLogsScript(t *testing.T) {
+	assert.Equal(t, PruneLogsScript("/pglog", 0), "")
+
+	script := PruneLogsScript("/pglog", 7)
+	assert.Assert(t, strings.Contains(script,
+		`find "/pglog" -maxdepth 1 -type f -name 'postgresql-*' -mtime +7 -delete`), "%s", script)
+
+	command := reloadCommand("some-name", 0, true)
+	assert.Assert(t, strings.Contains(command[3], script), "%s", command[3])
+}
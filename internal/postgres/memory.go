@@ -0,0 +1,184 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// Memory parameters are integers in some unit of bytes. A value without a unit
+// is a multiple of the parameter's default unit. Units that end in "B" come
+// before "B" itself.
+// - https://www.postgresql.org/docs/current/config-setting.html#CONFIG-SETTING-NAMES-VALUES
+var memoryUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"kB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"TB", 1 << 40},
+	{"B", 1},
+}
+
+// ParseMemory returns the number of bytes represented by value, a PostgreSQL
+// memory parameter. A value without a unit is multiplied by defaultUnit.
+func ParseMemory(value string, defaultUnit int64) (int64, error) {
+	value = strings.TrimSpace(value)
+	number, multiplier := value, float64(defaultUnit)
+
+	for _, unit := range memoryUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			number = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.bytes
+			break
+		}
+	}
+
+	parsed, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid memory value %q", value)
+	}
+	return int64(parsed * multiplier), nil
+}
+
+// memoryParameters returns the settings from the "postgresql.parameters"
+// section of the Patroni dynamic configuration of cluster.
+func memoryParameters(cluster *v1beta1.PostgresCluster) map[string]string {
+	parameters := map[string]string{}
+
+	if cluster.Spec.Patroni == nil || len(cluster.Spec.Patroni.DynamicConfiguration.Raw) == 0 {
+		return parameters
+	}
+
+	var configuration struct {
+		PostgreSQL struct {
+			Parameters map[string]interface{} `json:"parameters"`
+		} `json:"postgresql"`
+	}
+	if json.Unmarshal(cluster.Spec.Patroni.DynamicConfiguration.Raw, &configuration) != nil {
+		return parameters
+	}
+
+	for name, value := range configuration.PostgreSQL.Parameters {
+		parameters[strings.ToLower(name)] = fmt.Sprint(value)
+	}
+	return parameters
+}
+
+// MemoryEstimate is a rough upper bound of the memory PostgreSQL uses with
+// some parameters: its shared buffers plus one work_mem for every connection.
+// A single query can use more than one work_mem, so this is not a guarantee.
+type MemoryEstimate struct {
+	SharedBuffers  int64
+	MaxConnections int64
+	WorkMem        int64
+}
+
+// Total returns the number of bytes in the estimate.
+func (e MemoryEstimate) Total() int64 {
+	return e.SharedBuffers + e.MaxConnections*e.WorkMem
+}
+
+// EstimateMemory returns a MemoryEstimate of the parameters in cluster and,
+// when it is not nil, those of instance. Any parameter that is not set uses the
+// automatically tuned or PostgreSQL default.
+func EstimateMemory(
+	cluster *v1beta1.PostgresCluster, instance *v1beta1.PostgresInstanceSetSpec,
+) (MemoryEstimate, error) {
+	tuned := NewParameters()
+	AutoTuneParameters(cluster, &tuned)
+
+	parameters := tuned.Default.AsMap()
+	for name, value := range memoryParameters(cluster) {
+		parameters[name] = value
+	}
+
+	// Patroni keeps max_connections the same on every member, so only the
+	// other parameters of an instance set take precedence.
+	if instance != nil {
+		for name, value := range instance.Parameters {
+			if name = strings.ToLower(name); name != "max_connections" {
+				parameters[name] = value
+			}
+		}
+	}
+	estimate := MemoryEstimate{
+		SharedBuffers:  128 << 20,
+		MaxConnections: 100,
+		WorkMem:        4 << 20,
+	}
+
+	var err error
+	if value, ok := parameters["shared_buffers"]; ok && err == nil {
+		estimate.SharedBuffers, err = ParseMemory(value, 8<<10)
+	}
+	if value, ok := parameters["work_mem"]; ok && err == nil {
+		estimate.WorkMem, err = ParseMemory(value, 1<<10)
+	}
+	if value, ok := parameters["max_connections"]; ok && err == nil {
+		var connections float64
+		connections, err = strconv.ParseFloat(strings.TrimSpace(value), 64)
+		estimate.MaxConnections = int64(connections)
+		err = errors.WithStack(err)
+	}
+
+	return estimate, err
+}
+
+// MemoryGuardrails returns a message for every instance set in cluster whose
+// memory limit is smaller than the memory PostgreSQL may use according to the
+// parameters of cluster and the set. Instance sets without a memory limit are
+// not checked.
+func MemoryGuardrails(cluster *v1beta1.PostgresCluster) []string {
+	var messages []string
+
+	for i := range cluster.Spec.InstanceSets {
+		set := &cluster.Spec.InstanceSets[i]
+		limit := set.Resources.Limits.Memory()
+		if limit.IsZero() {
+			continue
+		}
+
+		estimate, err := EstimateMemory(cluster, set)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("instance set %q: %v", set.Name, err))
+			continue
+		}
+
+		if estimate.Total() > limit.Value() {
+			messages = append(messages, fmt.Sprintf(
+				"instance set %q: shared_buffers (%s) plus max_connections (%d) times work_mem (%s)"+
+					" is %s, more than its memory limit (%s)",
+				set.Name,
+				resource.NewQuantity(estimate.SharedBuffers, resource.BinarySI),
+				estimate.MaxConnections,
+				resource.NewQuantity(estimate.WorkMem, resource.BinarySI),
+				resource.NewQuantity(estimate.Total(), resource.BinarySI),
+				limit))
+		}
+	}
+
+	return messages
+}
@@ -0,0 +1,160 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestParseMemory(t *testing.T) {
+	for _, tt := range []struct {
+		value    string
+		unit     int64
+		expected int64
+	}{
+		{"16384", 8 << 10, 128 << 20},
+		{"4MB", 1 << 10, 4 << 20},
+		{"512kB", 8 << 10, 512 << 10},
+		{" 2 GB ", 1, 2 << 30},
+		{"1.5GB", 1, 3 << 29},
+		{"1TB", 1, 1 << 40},
+		{"100B", 1 << 10, 100},
+	} {
+		actual, err := ParseMemory(tt.value, tt.unit)
+		assert.NilError(t, err, "%q", tt.value)
+		assert.Equal(t, actual, tt.expected, "%q", tt.value)
+	}
+
+	_, err := ParseMemory("lots", 1)
+	assert.ErrorContains(t, err, `"lots"`)
+}
+
+func TestEstimateMemory(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+
+	t.Run("Defaults", func(t *testing.T) {
+		estimate, err := EstimateMemory(cluster, nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, estimate, MemoryEstimate{
+			SharedBuffers: 128 << 20, MaxConnections: 100, WorkMem: 4 << 20,
+		})
+		assert.Equal(t, estimate.Total(), int64(528<<20))
+	})
+
+	t.Run("Parameters", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Patroni = &v1beta1.PatroniSpec{
+			DynamicConfiguration: runtime.RawExtension{Raw: []byte(`{
+				"postgresql": { "parameters": {
+					"Shared_Buffers": "1GB", "max_connections": 500, "work_mem": 8192
+				}}
+			}`)},
+		}
+
+		estimate, err := EstimateMemory(cluster, nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, estimate, MemoryEstimate{
+			SharedBuffers: 1 << 30, MaxConnections: 500, WorkMem: 8 << 20,
+		})
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Patroni = &v1beta1.PatroniSpec{
+			DynamicConfiguration: runtime.RawExtension{Raw: []byte(`{
+				"postgresql": { "parameters": { "work_mem": "some" } }
+			}`)},
+		}
+
+		_, err := EstimateMemory(cluster, nil)
+		assert.ErrorContains(t, err, "some")
+	})
+
+	t.Run("InstanceParameters", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Patroni = &v1beta1.PatroniSpec{
+			DynamicConfiguration: runtime.RawExtension{Raw: []byte(`{
+				"postgresql": { "parameters": { "shared_buffers": "1GB", "work_mem": "8MB" } }
+			}`)},
+		}
+		instance := &v1beta1.PostgresInstanceSetSpec{Parameters: map[string]string{
+			"Work_Mem": "64MB", "max_connections": "1000",
+		}}
+
+		// The instance set overrides work_mem but not max_connections.
+		estimate, err := EstimateMemory(cluster, instance)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, estimate, MemoryEstimate{
+			SharedBuffers: 1 << 30, MaxConnections: 100, WorkMem: 64 << 20,
+		})
+	})
+}
+
+func TestMemoryGuardrails(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Patroni = &v1beta1.PatroniSpec{
+		DynamicConfiguration: runtime.RawExtension{Raw: []byte(`{
+			"postgresql": { "parameters": {
+				"shared_buffers": "1GB", "max_connections": 200, "work_mem": "16MB"
+			}}
+		}`)},
+	}
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{
+		{Name: "unlimited"},
+		{Name: "small", Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+		}},
+		{Name: "large", Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")},
+		}},
+	}
+
+	messages := MemoryGuardrails(cluster)
+	assert.Assert(t, cmp.Len(messages, 1))
+	assert.Equal(t, messages[0], `instance set "small":`+
+		` shared_buffers (1Gi) plus max_connections (200) times work_mem (16Mi)`+
+		` is 4224Mi, more than its memory limit (2Gi)`)
+
+	t.Run("InstanceParameters", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.InstanceSets[1].Parameters = map[string]string{"work_mem": "4MB"}
+		cluster.Spec.InstanceSets[2].Parameters = map[string]string{"shared_buffers": "8GB"}
+
+		messages := MemoryGuardrails(cluster)
+		assert.Assert(t, cmp.Len(messages, 1))
+		assert.Equal(t, messages[0], `instance set "large":`+
+			` shared_buffers (8Gi) plus max_connections (200) times work_mem (16Mi)`+
+			` is 11392Mi, more than its memory limit (8Gi)`)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.InstanceSets[2].Parameters = map[string]string{"work_mem": "some"}
+
+		messages := MemoryGuardrails(cluster)
+		assert.Assert(t, cmp.Len(messages, 2))
+		assert.Assert(t, cmp.Contains(messages[1], `instance set "large"`))
+		assert.Assert(t, cmp.Contains(messages[1], "some"))
+	})
+}
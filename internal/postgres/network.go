@@ -0,0 +1,254 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/pkg/errors"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+)
+
+// OperatorUser is the PostgreSQL role the operator uses when it connects over
+// the network. It authenticates with a client certificate.
+const OperatorUser = "_crunchyoperator"
+
+// OperatorHBAs populates outHBAs with the records that allow OperatorUser to
+// connect over TLS using certificate authentication. Call it only when the
+// operator connects over the network; see WriteOperatorUserInPostgreSQL.
+func OperatorHBAs(outHBAs *HBAs) {
+	outHBAs.Mandatory = append(outHBAs.Mandatory,
+		*NewHBA().TLS().User(OperatorUser).Method("cert"),
+		*NewHBA().TCP().User(OperatorUser).Method("reject"),
+	)
+}
+
+// networkScriptKey is the context key that allows NetworkExecutor to run a
+// script over the network; see networkScript.
+type networkScriptKey struct{}
+
+// networkScript returns a copy of ctx in which NetworkExecutor runs "psql"
+// scripts over the network. parsePsql implements only part of "psql", so mark
+// only the scripts that TestNetworkScripts compares with "psql" itself.
+func networkScript(ctx context.Context) context.Context {
+	return context.WithValue(ctx, networkScriptKey{}, true)
+}
+
+// NetworkExecutor connects to PostgreSQL over the network to run the "psql"
+// commands of an Executor. It uses the pgconn package of pgx to connect, so
+// only the "psql" script itself is interpreted here; see parsePsql. Only
+// scripts marked by networkScript run this way.
+type NetworkExecutor struct {
+	// Address is the host and port of PostgreSQL.
+	Address string
+
+	// TLS configures connections, including the client certificate.
+	TLS *tls.Config
+
+	// User is the role to connect as. It must authenticate without a password.
+	User string
+
+	// Timeout limits how long each command can take. Zero means no limit.
+	Timeout time.Duration
+
+	// Dial connects to Address. When nil, a net.Dialer is used.
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Executor returns an Executor that runs "psql" commands over the network.
+// Other commands, scripts that are not marked by networkScript, scripts that
+// use features of "psql" that are not implemented, and commands that cannot
+// connect are passed to fallback.
+func (n NetworkExecutor) Executor(fallback Executor) Executor {
+	return func(
+		ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		log := logging.FromContext(ctx)
+
+		query, database, variables, ok := parseExecCommand(command)
+		if !ok || stdin == nil || ctx.Value(networkScriptKey{}) == nil {
+			return fallback(ctx, stdin, stdout, stderr, command...)
+		}
+
+		// Read the whole script so it can be parsed and, when necessary,
+		// passed to fallback.
+		data, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		retreat := func(reason error) error {
+			log.V(1).Info("Unable to run SQL over the network", "reason", reason.Error())
+			return fallback(ctx, bytes.NewReader(data), stdout, stderr, command...)
+		}
+
+		steps, err := parsePsql(string(data), variables)
+		if err != nil {
+			return retreat(err)
+		}
+
+		if n.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, n.Timeout)
+			defer cancel()
+		}
+
+		// Find the databases in which to run the script, if necessary.
+		databases := []string{database}
+		if query != "" {
+			databases = nil
+
+			queries, err := parsePsql(query, variables)
+			var conn *pgconn.PgConn
+			if err == nil {
+				conn, err = n.connect(ctx, "", nil)
+			}
+			if err != nil {
+				return retreat(err)
+			}
+
+			for i := 0; err == nil && i < len(queries); i++ {
+				var results []*pgconn.Result
+				if queries[i].Pset == nil {
+					results, err = conn.Exec(ctx, queries[i].SQL).ReadAll()
+				}
+				for _, result := range results {
+					if err == nil {
+						err = result.Err
+					}
+					for _, row := range result.Rows {
+						if len(row) > 0 && row[0] != nil {
+							databases = append(databases, string(row[0]))
+						}
+					}
+				}
+			}
+			_ = conn.Close(context.Background())
+
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		for i, database := range databases {
+			session := newPsqlSession(stdout, stderr, variables)
+			conn, err := n.connect(ctx, database, session.notice)
+			if err != nil && i == 0 {
+				return retreat(err)
+			}
+			if err == nil {
+				err = session.Run(ctx, conn, steps)
+				_ = conn.Close(context.Background())
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// connect opens a session in database, or the default database when empty.
+// Notices from PostgreSQL are passed to notice, when it is not nil.
+func (n NetworkExecutor) connect(
+	ctx context.Context, database string, notice func(*pgconn.Notice),
+) (*pgconn.PgConn, error) {
+	host, port, err := net.SplitHostPort(n.Address)
+	var number uint64
+	if err == nil {
+		number, err = strconv.ParseUint(port, 10, 16)
+	}
+
+	// Start from an empty connection string so that only the settings below
+	// apply. ConnectConfig requires a Config from ParseConfig.
+	var config *pgconn.Config
+	if err == nil {
+		config, err = pgconn.ParseConfig("")
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	config.Host, config.Port = host, uint16(number)
+	config.User, config.Password = n.User, ""
+	config.Database = database
+	if config.Database == "" {
+		config.Database = "postgres"
+	}
+
+	// Connect only over TLS to the exact address.
+	config.TLSConfig, config.Fallbacks = n.TLS, nil
+	config.LookupFunc = func(_ context.Context, host string) ([]string, error) {
+		return []string{host}, nil
+	}
+	config.DialFunc = n.Dial
+	if config.DialFunc == nil {
+		config.DialFunc = (&net.Dialer{Timeout: 10 * time.Second}).DialContext
+	}
+	if notice != nil {
+		config.OnNotice = func(_ *pgconn.PgConn, n *pgconn.Notice) { notice(n) }
+	}
+
+	conn, err := pgconn.ConnectConfig(ctx, config)
+	return conn, errors.WithStack(err)
+}
+
+// parseExecCommand interprets a command built by the methods of Executor. It
+// returns the query that lists databases, or the single database in which to
+// run, along with any "psql" variables.
+func parseExecCommand(command []string) (
+	query, database string, variables map[string]string, ok bool,
+) {
+	var args []string
+	switch {
+	case len(command) >= 3 &&
+		command[0] == "psql" && command[1] == "-Xw" && command[2] == "--file=-":
+		args, ok = command[3:], true
+
+	case len(command) >= 6 &&
+		command[0] == "bash" && command[1] == "-ceu" && command[2] == "--" &&
+		command[4] == "-":
+		switch command[3] {
+		case execInDatabaseScript:
+			database, args, ok = command[5], command[6:], command[5] != ""
+		case execInDatabasesScript:
+			query, args, ok = command[5], command[6:], command[5] != ""
+		}
+	}
+
+	variables = make(map[string]string, len(args))
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--set=") {
+			return "", "", nil, false
+		}
+		parts := strings.SplitN(strings.TrimPrefix(arg, "--set="), "=", 2)
+		if len(parts) != 2 {
+			return "", "", nil, false
+		}
+		variables[parts[0]] = parts[1]
+	}
+
+	return query, database, variables, ok
+}
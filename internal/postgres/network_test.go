@@ -0,0 +1,553 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gotest.tools/v3/assert"
+
+	"github.com/crunchydata/postgres-operator/internal/pki"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// fakeServer speaks enough of the PostgreSQL protocol to test NetworkExecutor.
+type fakeServer struct {
+	config *tls.Config
+
+	// respond returns the rows or the error for a query.
+	respond func(database, sql string) ([][]string, error)
+
+	mutex     sync.Mutex
+	sessions  []string
+	received  []string
+	copied    []string
+	authError string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	root := pki.NewRootCertificateAuthority()
+	assert.NilError(t, root.Generate())
+	leaf := pki.NewLeafCertificate("server", []string{"server"}, nil)
+	assert.NilError(t, leaf.Generate(root))
+
+	return &fakeServer{config: &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{leaf.Certificate.Certificate},
+			PrivateKey:  leaf.PrivateKey.PrivateKey,
+		}},
+	}}
+}
+
+func (s *fakeServer) Dial(_ context.Context, _, _ string) (net.Conn, error) {
+	client, server := net.Pipe()
+	go s.serve(server)
+	return client, nil
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	read := func(r io.Reader, typed bool) (byte, []byte) {
+		var kind byte
+		if typed {
+			b := make([]byte, 1)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return 0, nil
+			}
+			kind = b[0]
+		}
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return 0, nil
+		}
+		body := make([]byte, binary.BigEndian.Uint32(header)-4)
+		_, _ = io.ReadFull(r, body)
+		return kind, body
+	}
+	write := func(w io.Writer, kind byte, body []byte) {
+		message := []byte{kind, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(message[1:], uint32(4+len(body)))
+		_, _ = w.Write(append(message, body...))
+	}
+	failure := func(message string) []byte {
+		return []byte("SERROR\x00VERROR\x00C42000\x00M" + message + "\x00\x00")
+	}
+
+	// SSLRequest then TLS.
+	read(conn, false)
+	_, _ = conn.Write([]byte{'S'})
+	secure := tls.Server(conn, s.config)
+	reader := bufio.NewReader(secure)
+
+	// StartupMessage.
+	_, startup := read(reader, false)
+	if len(startup) < 4 {
+		return
+	}
+	parameters := strings.Split(string(startup[4:]), "\x00")
+	var database string
+	for i := 0; i+1 < len(parameters); i += 2 {
+		if parameters[i] == "database" {
+			database = parameters[i+1]
+		}
+	}
+
+	if s.authError != "" {
+		write(secure, 'E', failure(s.authError))
+		return
+	}
+
+	s.mutex.Lock()
+	s.sessions = append(s.sessions, database)
+	s.mutex.Unlock()
+
+	write(secure, 'R', []byte{0, 0, 0, 0})
+	write(secure, 'Z', []byte{'I'})
+
+	for {
+		kind, body := read(reader, true)
+		if kind == 0 || kind == 'X' {
+			return
+		}
+		sql := strings.TrimSuffix(string(body), "\x00")
+
+		s.mutex.Lock()
+		s.received = append(s.received, strings.TrimSpace(sql))
+		s.mutex.Unlock()
+
+		if strings.HasPrefix(sql, "COPY") {
+			write(secure, 'G', []byte{0, 0, 0})
+			var data bytes.Buffer
+			for {
+				kind, body := read(reader, true)
+				if kind != 'd' {
+					break
+				}
+				data.Write(body)
+			}
+			s.mutex.Lock()
+			s.copied = append(s.copied, data.String())
+			s.mutex.Unlock()
+			write(secure, 'C', []byte("COPY 1\x00"))
+			write(secure, 'Z', []byte{'I'})
+			continue
+		}
+
+		var rows [][]string
+		var err error
+		if s.respond != nil {
+			rows, err = s.respond(database, strings.TrimSpace(sql))
+		}
+		switch {
+		case err != nil:
+			write(secure, 'E', failure(err.Error()))
+		case rows != nil:
+			description := []byte{0, byte(len(rows[0]))}
+			for range rows[0] {
+				description = append(appendString(description, "column"), make([]byte, 18)...)
+			}
+			write(secure, 'T', description)
+			for _, row := range rows {
+				data := []byte{0, byte(len(row))}
+				for _, value := range row {
+					data = appendUint32(data, uint32(len(value)))
+					data = append(data, value...)
+				}
+				write(secure, 'D', data)
+			}
+			write(secure, 'C', []byte("SELECT\x00"))
+		default:
+			write(secure, 'N', []byte("SNOTICE\x00Mhello\x00\x00"))
+			write(secure, 'C', []byte("OK\x00"))
+		}
+		write(secure, 'Z', []byte{'I'})
+	}
+}
+
+func appendString(b []byte, s string) []byte { return append(append(b, s...), 0) }
+
+func appendUint32(b []byte, n uint32) []byte {
+	return append(b, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func TestNetworkExecutor(t *testing.T) {
+	ctx := networkScript(context.Background())
+	unexpected := func(
+		_ context.Context, _ io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		t.Errorf("unexpected fallback: %q", command)
+		return nil
+	}
+
+	t.Run("Exec", func(t *testing.T) {
+		server := newFakeServer(t)
+		server.respond = func(_, sql string) ([][]string, error) {
+			if strings.HasPrefix(sql, "SELECT format") {
+				return [][]string{{"CREATE ROLE a"}, {"CREATE ROLE b"}}, nil
+			}
+			return nil, nil
+		}
+
+		network := NetworkExecutor{
+			Address: "ignored:5432", User: OperatorUser, Dial: server.Dial,
+			TLS: &tls.Config{InsecureSkipVerify: true}, // #nosec G402 This is a test.
+		}
+
+		stdout, stderr, err := Executor(network.Executor(unexpected)).Exec(ctx,
+			strings.NewReader(`
+SET search_path TO '';
+\copy input (data) from stdin with (format text)
+{"x":1}
+\.
+SELECT format('CREATE ROLE %I', :'name')
+\gexec
+`), map[string]string{"name": "z", "QUIET": "on"})
+
+		assert.NilError(t, err)
+		assert.DeepEqual(t, server.sessions, []string{"postgres"})
+		assert.DeepEqual(t, server.received, []string{
+			`SET search_path TO '';`,
+			`COPY input (data) FROM STDIN with (format text)`,
+			`SELECT format('CREATE ROLE %I', 'z')`,
+			`CREATE ROLE a`,
+			`CREATE ROLE b`,
+		})
+		assert.DeepEqual(t, server.copied, []string{"{\"x\":1}\n"})
+		assert.Equal(t, stdout, "", "expected quiet")
+		assert.Assert(t, strings.Contains(stderr, "NOTICE:  hello"), "%q", stderr)
+	})
+
+	t.Run("OnErrorStop", func(t *testing.T) {
+		server := newFakeServer(t)
+		server.respond = func(_, sql string) ([][]string, error) {
+			if sql == "SELECT 2;" {
+				return nil, errors.New("boom")
+			}
+			return [][]string{{"1"}}, nil
+		}
+
+		network := NetworkExecutor{
+			Address: "ignored:5432", User: OperatorUser, Dial: server.Dial,
+			TLS: &tls.Config{InsecureSkipVerify: true}, // #nosec G402 This is a test.
+		}
+		exec := Executor(network.Executor(unexpected))
+
+		stdout, stderr, err := exec.Exec(ctx,
+			strings.NewReader("SELECT 1;\nSELECT 2;\nSELECT 3;"), nil)
+		assert.NilError(t, err)
+		assert.Equal(t, stdout, "column\n1\n(1 row)\ncolumn\n1\n(1 row)\n")
+		assert.Assert(t, strings.Contains(stderr, "boom"))
+		assert.Equal(t, len(server.received), 3)
+
+		server.received = nil
+		_, _, err = exec.Exec(ctx, strings.NewReader("SELECT 1;\nSELECT 2;\nSELECT 3;"),
+			map[string]string{"ON_ERROR_STOP": "on"})
+		assert.ErrorContains(t, err, "boom")
+		assert.Equal(t, len(server.received), 2)
+	})
+
+	t.Run("InDatabases", func(t *testing.T) {
+		server := newFakeServer(t)
+		server.respond = func(database, sql string) ([][]string, error) {
+			if database == "postgres" && strings.HasPrefix(sql, "SELECT datname") {
+				return [][]string{{"one"}, {"two"}}, nil
+			}
+			return nil, nil
+		}
+
+		network := NetworkExecutor{
+			Address: "ignored:5432", User: OperatorUser, Dial: server.Dial,
+			TLS: &tls.Config{InsecureSkipVerify: true}, // #nosec G402 This is a test.
+		}
+		exec := Executor(network.Executor(unexpected))
+
+		_, _, err := exec.ExecInDatabasesFromQuery(ctx,
+			`SELECT datname FROM pg_database`, `SELECT :'x';`, map[string]string{"x": "y"})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, server.sessions, []string{"postgres", "one", "two"})
+		assert.DeepEqual(t, server.received, []string{
+			`SELECT datname FROM pg_database`, `SELECT 'y';`, `SELECT 'y';`,
+		})
+
+		server.sessions, server.received = nil, nil
+		_, _, err = exec.ExecInDatabase(ctx, "three", strings.NewReader(`SELECT 1;`), nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, server.sessions, []string{"three"})
+		assert.DeepEqual(t, server.received, []string{`SELECT 1;`})
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		server := newFakeServer(t)
+		server.authError = `role "_crunchyoperator" does not exist`
+
+		var calls []string
+		fallback := func(
+			_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			b, err := ioutil.ReadAll(stdin)
+			assert.NilError(t, err)
+			calls = append(calls, string(b))
+			return nil
+		}
+
+		network := NetworkExecutor{
+			Address: "ignored:5432", User: OperatorUser, Dial: server.Dial,
+			TLS: &tls.Config{InsecureSkipVerify: true}, // #nosec G402 This is a test.
+		}
+		exec := Executor(network.Executor(fallback))
+
+		// Unable to authenticate.
+		_, _, err := exec.Exec(ctx, strings.NewReader(`SELECT 1;`), nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, calls, []string{`SELECT 1;`})
+
+		// Unable to verify the server.
+		server.authError = ""
+		network.TLS = &tls.Config{ServerName: "other", MinVersion: tls.VersionTLS12}
+		exec = network.Executor(fallback)
+		_, _, err = exec.Exec(ctx, strings.NewReader(`SELECT 2;`), nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, calls, []string{`SELECT 1;`, `SELECT 2;`})
+
+		// Unsupported meta-commands do not connect.
+		network.Dial = func(context.Context, string, string) (net.Conn, error) {
+			t.Fatal("unexpected connection")
+			return nil, nil
+		}
+		exec = network.Executor(fallback)
+		_, _, err = exec.Exec(ctx, strings.NewReader(`\set x 1`), nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, calls, []string{`SELECT 1;`, `SELECT 2;`, `\set x 1`})
+
+		// Other commands.
+		assert.NilError(t, exec(ctx, strings.NewReader(`SELECT 3;`), nil, nil, "pg_isready"))
+		assert.DeepEqual(t, calls, []string{`SELECT 1;`, `SELECT 2;`, `\set x 1`, `SELECT 3;`})
+
+		// Scripts that are not marked do not connect.
+		_, _, err = exec.Exec(context.Background(), strings.NewReader(`SELECT 4;`), nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, calls, []string{`SELECT 1;`, `SELECT 2;`, `\set x 1`, `SELECT 3;`, `SELECT 4;`})
+	})
+}
+
+// TestNetworkScripts compares the scripts marked by networkScript when they
+// run through "psql" and through NetworkExecutor. Each runs in its own
+// PostgreSQL server, and both should print and change the same things.
+func TestNetworkScripts(t *testing.T) {
+	for _, name := range []string{"initdb", "pg_ctl", "psql"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("requires %q executable", name)
+		}
+	}
+	if os.Geteuid() == 0 {
+		t.Skip(`requires a user other than root to run "initdb"`)
+	}
+
+	ctx := context.Background()
+
+	// start runs PostgreSQL in a new directory and returns its TCP address.
+	// Connections from the same host are trusted.
+	start := func(t *testing.T) string {
+		dir := t.TempDir()
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NilError(t, err)
+		port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+		assert.NilError(t, listener.Close())
+
+		run := func(name string, args ...string) {
+			output, err := exec.Command(name, args...).CombinedOutput()
+			assert.NilError(t, err, "%s", output)
+		}
+		run("initdb", "--auth=trust", "--username=postgres", "--pgdata="+dir+"/data")
+		run("pg_ctl", "--pgdata="+dir+"/data", "--log="+dir+"/log", "--wait",
+			"--options=-c listen_addresses=127.0.0.1 -c port="+port+
+				" -c unix_socket_directories=''", "start")
+		t.Cleanup(func() {
+			_ = exec.Command("pg_ctl", "--pgdata="+dir+"/data", "--mode=immediate", "stop").Run()
+		})
+		return net.JoinHostPort("127.0.0.1", port)
+	}
+
+	// psql runs commands the way they run in the database container.
+	psql := func(address string) Executor {
+		host, port, _ := net.SplitHostPort(address)
+		return func(
+			ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+			cmd.Env = append(os.Environ(),
+				"PGHOST="+host, "PGPORT="+port, "PGUSER=postgres", "PGDATABASE=postgres")
+			cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, stderr
+			return cmd.Run()
+		}
+	}
+
+	// output is what one command printed. Only the severity and message of
+	// each notice or error are compared; "psql" also prints their position
+	// and context.
+	type output struct {
+		Stdout, Stderr string
+		Failed         bool
+	}
+	messages := regexp.MustCompile(`(?m)^(?:psql:[^:]*:[0-9]+: )?((?:ERROR|WARNING|NOTICE):  .*)$`)
+	record := func(exec Executor, outputs *[]output) Executor {
+		return func(
+			ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			var o, e bytes.Buffer
+			err := exec(ctx, stdin, &o, &e, command...)
+
+			var lines []string
+			for _, match := range messages.FindAllStringSubmatch(e.String(), -1) {
+				lines = append(lines, match[1])
+			}
+			*outputs = append(*outputs, output{
+				Stdout: o.String(), Stderr: strings.Join(lines, "\n"), Failed: err != nil,
+			})
+			return err
+		}
+	}
+
+	expected, actual := start(t), start(t)
+	unexpected := func(
+		_ context.Context, _ io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		t.Errorf("unexpected fallback: %q", command)
+		return nil
+	}
+
+	var expectedOutputs, actualOutputs []output
+	executors := []Executor{
+		record(psql(expected), &expectedOutputs),
+		record(NetworkExecutor{Address: actual, User: "postgres"}.Executor(unexpected), &actualOutputs),
+	}
+
+	users := []v1beta1.PostgresUserSpec{
+		{Name: "postgres"},
+		{Name: "alice", Databases: []v1beta1.PostgresIdentifier{"one", "two"}, Options: "CREATEDB"},
+		{
+			Name:       "bob",
+			Databases:  []v1beta1.PostgresIdentifier{"one"},
+			MemberOf:   []v1beta1.PostgresIdentifier{"alice", "missing"},
+			Privileges: []v1beta1.PostgresPrivilegesSpec{{Database: "one", Preset: "readwrite"}},
+		},
+	}
+	verifiers := map[string]string{
+		"alice": "md5" + strings.Repeat("a", 32),
+		"bob":   "md5" + strings.Repeat("b", 32),
+	}
+
+	for _, executor := range executors {
+		// Scripts fail the same way, too.
+		assert.Assert(t, WriteUsersInPostgreSQL(ctx, executor,
+			[]v1beta1.PostgresUserSpec{{Name: "carol", Options: "NOSUCHOPTION"}}, nil) != nil)
+
+		assert.NilError(t, CreateDatabasesInPostgreSQL(ctx, executor, []v1beta1.PostgresDatabaseSpec{
+			{Name: "one"}, {Name: "two", Template: "template0"},
+		}))
+
+		// Running the scripts again changes nothing.
+		for i := 0; i < 2; i++ {
+			assert.NilError(t, WriteUsersInPostgreSQL(ctx, executor, users, verifiers))
+			assert.NilError(t, GrantPublicSchemaInPostgreSQL(ctx, executor, users))
+			assert.NilError(t, GrantPrivilegesInPostgreSQL(ctx, executor, users, users))
+		}
+	}
+	assert.DeepEqual(t, actualOutputs, expectedOutputs)
+
+	// Compare what the scripts changed.
+	state := func(address string) string {
+		var stdout bytes.Buffer
+		for _, database := range []string{"postgres", "one", "two"} {
+			assert.NilError(t, psql(address)(ctx, strings.NewReader(`
+SELECT rolname, rolsuper, rolcreatedb, rolcanlogin, rolpassword, ARRAY(
+       SELECT other.rolname FROM pg_auth_members AS m
+         JOIN pg_roles AS other ON other.oid = m.roleid
+        WHERE m.member = a.oid ORDER BY 1)
+  FROM pg_authid AS a WHERE rolname !~ '^pg_' ORDER BY rolname;
+SELECT datname, datacl FROM pg_database ORDER BY datname;
+SELECT nspname, nspacl FROM pg_namespace ORDER BY nspname;
+SELECT defaclrole::regrole, defaclnamespace::regnamespace, defaclobjtype, defaclacl
+  FROM pg_default_acl ORDER BY 1, 2, 3;
+`), &stdout, nil, "psql", "-Xw", "--file=-", "--dbname="+database))
+		}
+		return stdout.String()
+	}
+	assert.Equal(t, state(actual), state(expected))
+}
+
+func TestOperatorHBAs(t *testing.T) {
+	hbas := NewHBAs()
+	OperatorHBAs(&hbas)
+
+	mandatory := hbas.Mandatory[len(hbas.Mandatory)-2:]
+	assert.Equal(t, len(hbas.Mandatory), len(NewHBAs().Mandatory)+2)
+	assert.Equal(t, mandatory[0].String(), `hostssl all "_crunchyoperator" all cert`)
+	assert.Equal(t, mandatory[1].String(), `host all "_crunchyoperator" all reject`)
+}
+
+func TestParseExecCommand(t *testing.T) {
+	var command []string
+	exec := Executor(func(
+		_ context.Context, _ io.Reader, _, _ io.Writer, c ...string,
+	) error {
+		command = c
+		return nil
+	})
+	variables := map[string]string{"a": "b=c", "ON_ERROR_STOP": "on"}
+
+	_, _, _ = exec.Exec(context.Background(), nil, variables)
+	query, database, vars, ok := parseExecCommand(command)
+	assert.Assert(t, ok)
+	assert.Equal(t, query, "")
+	assert.Equal(t, database, "")
+	assert.DeepEqual(t, vars, variables)
+
+	_, _, _ = exec.ExecInDatabase(context.Background(), "db", nil, variables)
+	query, database, vars, ok = parseExecCommand(command)
+	assert.Assert(t, ok)
+	assert.Equal(t, query, "")
+	assert.Equal(t, database, "db")
+	assert.DeepEqual(t, vars, variables)
+
+	_, _, _ = exec.ExecInAllDatabases(context.Background(), "", variables)
+	query, database, vars, ok = parseExecCommand(command)
+	assert.Assert(t, ok)
+	assert.Assert(t, strings.Contains(query, "pg_database"))
+	assert.Equal(t, database, "")
+	assert.DeepEqual(t, vars, variables)
+
+	_, _, _, ok = parseExecCommand([]string{"psql", "-Xw", "--file=-", "--echo-all"})
+	assert.Assert(t, !ok)
+	_, _, _, ok = parseExecCommand([]string{"patronictl", "list"})
+	assert.Assert(t, !ok)
+}
@@ -16,6 +16,7 @@
 package postgres
 
 import (
+	"context"
 	"strings"
 )
 
@@ -119,3 +120,24 @@ func (ps ParameterSet) Value(name string) string {
 	value, _ := ps.Get(name)
 	return value
 }
+
+// PendingRestart returns the names of parameters that have changed in the
+// configuration files but take effect only after PostgreSQL restarts, sorted.
+// - https://www.postgresql.org/docs/current/view-pg-settings.html
+func PendingRestart(ctx context.Context, exec Executor) ([]string, error) {
+	stdout, _, err := exec.Exec(ctx, strings.NewReader(`
+\pset format unaligned
+\pset tuples_only on
+SELECT name FROM pg_catalog.pg_settings WHERE pending_restart ORDER BY name;
+`),
+		map[string]string{
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	var names []string
+	if err == nil {
+		names = strings.Fields(stdout)
+	}
+	return names, err
+}
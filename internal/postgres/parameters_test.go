@@ -16,6 +16,11 @@
 package postgres
 
 import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
 	"testing"
 
 	"gotest.tools/v3/assert"
@@ -68,3 +73,43 @@ func TestParameterSet(t *testing.T) {
 	ps2.Add("x", "n")
 	assert.Assert(t, ps2.Value("x") != ps.Value("x"))
 }
+
+func TestPendingRestart(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Arguments", func(t *testing.T) {
+		expected := errors.New("pass-through")
+		exec := func(
+			_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			b, err := ioutil.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Assert(t, strings.Contains(string(b), "pending_restart"))
+			assert.Assert(t, strings.Contains(strings.Join(command, " "), "--set=QUIET=on"))
+			return expected
+		}
+
+		names, err := PendingRestart(ctx, exec)
+		assert.Equal(t, expected, err)
+		assert.Assert(t, names == nil)
+	})
+
+	for _, tt := range []struct {
+		stdout   string
+		expected []string
+	}{
+		{stdout: "max_connections\nshared_buffers\n", expected: []string{"max_connections", "shared_buffers"}},
+		{stdout: "", expected: []string{}},
+	} {
+		exec := func(
+			_ context.Context, _ io.Reader, stdout, _ io.Writer, _ ...string,
+		) error {
+			_, err := io.WriteString(stdout, tt.stdout)
+			return err
+		}
+
+		names, err := PendingRestart(ctx, exec)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, names, tt.expected)
+	}
+}
@@ -0,0 +1,415 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/pkg/errors"
+)
+
+// errPsqlUnsupported indicates a script uses a feature of "psql" that is not
+// implemented here.
+var errPsqlUnsupported = errors.New("unsupported psql script")
+
+// psqlStep is one thing a "psql" script does.
+type psqlStep struct {
+	// SQL is a statement with its variables interpolated.
+	SQL string
+
+	// GExec is true when each value returned by SQL is itself a statement.
+	// - https://www.postgresql.org/docs/current/app-psql.html#APP-PSQL-META-COMMAND-GEXEC
+	GExec bool
+
+	// Copy is the data of a "\copy ... from stdin" meta-command.
+	Copy *string
+
+	// Pset is the option and value of a "\pset" meta-command.
+	Pset []string
+}
+
+var (
+	psqlCopy = regexp.MustCompile(`(?is)^\s*(.+?)\s+from\s+stdin\b(.*)$`)
+	psqlName = regexp.MustCompile(`^[A-Za-z0-9_]+`)
+	psqlTag  = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+)
+
+// parsePsql splits script into the statements and meta-commands that "psql"
+// would execute, interpolating variables the same way. It returns an error
+// wrapping errPsqlUnsupported when script contains meta-commands other than
+// "\copy ... from stdin", "\gexec", and "\pset".
+// - https://www.postgresql.org/docs/current/app-psql.html#APP-PSQL-INTERPOLATION
+func parsePsql(script string, variables map[string]string) ([]psqlStep, error) {
+	var steps []psqlStep
+	var buffer strings.Builder
+	depth := 0
+
+	flush := func(step psqlStep) {
+		step.SQL = buffer.String()
+		buffer.Reset()
+		if strings.Trim(step.SQL, " \t\r\n;") != "" {
+			steps = append(steps, step)
+		}
+	}
+	identifier := func(i int) bool {
+		if i < 0 {
+			return false
+		}
+		c := script[i]
+		return c == '_' || c == '$' || c >= '0' && c <= '9' ||
+			c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= 0x80
+	}
+
+	for i := 0; i < len(script); {
+		c := script[i]
+		next := byte(0)
+		if i+1 < len(script) {
+			next = script[i+1]
+		}
+
+		switch {
+		case c == '\'' || c == '"':
+			// Find the end of a string literal or quoted identifier. In an
+			// escape string, a backslash escapes the following character.
+			escapes := c == '\'' && i > 0 && (script[i-1] == 'E' || script[i-1] == 'e') &&
+				!identifier(i-2)
+			j := i + 1
+			for ; j < len(script); j++ {
+				if escapes && script[j] == '\\' {
+					j++
+				} else if script[j] == c {
+					if j+1 < len(script) && script[j+1] == c {
+						j++
+					} else {
+						break
+					}
+				}
+			}
+			j = minInt(j+1, len(script))
+			buffer.WriteString(script[i:j])
+			i = j
+
+		case c == '-' && next == '-':
+			j := strings.IndexByte(script[i:], '\n')
+			if j < 0 {
+				j = len(script) - i
+			}
+			buffer.WriteString(script[i : i+j])
+			i += j
+
+		case c == '/' && next == '*':
+			j, nested := i+2, 1
+			for ; j < len(script) && nested > 0; j++ {
+				if strings.HasPrefix(script[j:], "/*") {
+					nested, j = nested+1, j+1
+				} else if strings.HasPrefix(script[j:], "*/") {
+					nested, j = nested-1, j+1
+				}
+			}
+			buffer.WriteString(script[i:j])
+			i = j
+
+		case c == '$' && !identifier(i-1) && psqlTag.MatchString(script[i:]):
+			tag := psqlTag.FindString(script[i:])
+			j := strings.Index(script[i+len(tag):], tag)
+			if j < 0 {
+				j = len(script)
+			} else {
+				j = i + len(tag) + j + len(tag)
+			}
+			buffer.WriteString(script[i:j])
+			i = j
+
+		case c == '(':
+			depth++
+			buffer.WriteByte(c)
+			i++
+
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+			buffer.WriteByte(c)
+			i++
+
+		case c == ';' && depth == 0:
+			buffer.WriteByte(c)
+			i++
+			flush(psqlStep{})
+
+		case c == ':' && next == ':':
+			buffer.WriteString("::")
+			i += 2
+
+		case c == ':' && (next == '\'' || next == '"'):
+			name := psqlName.FindString(script[i+2:])
+			end := i + 2 + len(name)
+			if value, ok := variables[name]; ok && name != "" &&
+				end < len(script) && script[end] == next {
+				if next == '\'' {
+					buffer.WriteString(quoteLiteral(value))
+				} else {
+					buffer.WriteString(quoteIdentifier(value))
+				}
+				i = end + 1
+			} else {
+				buffer.WriteByte(c)
+				i++
+			}
+
+		case c == ':' && psqlName.MatchString(script[i+1:]):
+			name := psqlName.FindString(script[i+1:])
+			if value, ok := variables[name]; ok {
+				buffer.WriteString(value)
+			} else {
+				buffer.WriteString(":" + name)
+			}
+			i += 1 + len(name)
+
+		case c == '\\':
+			// A meta-command continues to the end of the line.
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script)
+			} else {
+				end += i
+			}
+			fields := strings.Fields(script[i+1 : end])
+			if len(fields) == 0 {
+				return nil, errors.Wrap(errPsqlUnsupported, `\`)
+			}
+			args := strings.TrimSpace(strings.TrimPrefix(script[i+1:end], fields[0]))
+			i = end
+
+			switch {
+			case fields[0] == "gexec":
+				if strings.TrimSpace(buffer.String()) == "" {
+					return nil, errors.Wrap(errPsqlUnsupported, `\gexec without a query`)
+				}
+				flush(psqlStep{GExec: true})
+
+			case fields[0] == "pset" && len(fields) == 3 && strings.TrimSpace(buffer.String()) == "":
+				buffer.Reset()
+				steps = append(steps, psqlStep{Pset: fields[1:]})
+
+			case fields[0] == "copy" && psqlCopy.MatchString(args) &&
+				strings.TrimSpace(buffer.String()) == "":
+				// The data is on the following lines until the special line "\.".
+				match := psqlCopy.FindStringSubmatch(args)
+				var data strings.Builder
+				for i < len(script) {
+					line := script[i+1:]
+					if j := strings.IndexByte(line, '\n'); j >= 0 {
+						line = line[:j]
+					}
+					i += 1 + len(line)
+					if strings.TrimRight(line, "\r") == `\.` {
+						break
+					}
+					data.WriteString(line + "\n")
+				}
+				copied := data.String()
+				buffer.Reset()
+				steps = append(steps, psqlStep{
+					SQL:  "COPY " + match[1] + " FROM STDIN" + match[2],
+					Copy: &copied,
+				})
+
+			default:
+				return nil, errors.Wrapf(errPsqlUnsupported, `\%s`, fields[0])
+			}
+
+		default:
+			buffer.WriteByte(c)
+			i++
+		}
+	}
+
+	// Like "psql", send any remaining statement at the end of the script.
+	flush(psqlStep{})
+
+	return steps, nil
+}
+
+// psqlSession executes psqlSteps on a connection and prints their results
+// similar to "psql" with the "unaligned" format.
+type psqlSession struct {
+	stdout, stderr io.Writer
+
+	// Options from "psql" variables and "\pset" meta-commands.
+	onErrorStop, quiet, tuplesOnly bool
+}
+
+func newPsqlSession(stdout, stderr io.Writer, variables map[string]string) *psqlSession {
+	boolean := func(value string) bool {
+		switch strings.ToLower(value) {
+		case "1", "on", "t", "true", "y", "yes":
+			return true
+		}
+		return false
+	}
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	return &psqlSession{
+		stdout: stdout, stderr: stderr,
+		onErrorStop: boolean(variables["ON_ERROR_STOP"]),
+		quiet:       boolean(variables["QUIET"]),
+	}
+}
+
+// notice prints a notice from PostgreSQL like "psql" does.
+func (s *psqlSession) notice(notice *pgconn.Notice) {
+	fmt.Fprintf(s.stderr, "%s:  %s\n", notice.Severity, notice.Message)
+}
+
+// Run executes steps in order on conn. Like "psql", it stops at the first
+// failed statement only when the ON_ERROR_STOP variable is set.
+func (s *psqlSession) Run(ctx context.Context, conn *pgconn.PgConn, steps []psqlStep) error {
+	for _, step := range steps {
+		switch {
+		case step.Pset != nil:
+			switch step.Pset[0] {
+			case "tuples_only":
+				s.tuplesOnly = step.Pset[1] == "on" || step.Pset[1] == "true"
+			}
+
+		case step.Copy != nil:
+			tag, err := conn.CopyFrom(ctx, strings.NewReader(*step.Copy), step.SQL)
+			if err = s.result(&pgconn.Result{CommandTag: tag, Err: err}); err != nil {
+				return err
+			}
+
+		case step.GExec:
+			var statements []string
+			results, err := conn.Exec(ctx, step.SQL).ReadAll()
+			for _, result := range results {
+				if err == nil {
+					err = result.Err
+				}
+				for _, row := range result.Rows {
+					for _, value := range row {
+						if value != nil {
+							statements = append(statements, string(value))
+						}
+					}
+				}
+			}
+			if err = s.result(&pgconn.Result{Err: err}); err != nil {
+				return err
+			}
+			for _, statement := range statements {
+				if err := s.execute(ctx, conn, statement); err != nil {
+					return err
+				}
+			}
+
+		default:
+			if err := s.execute(ctx, conn, step.SQL); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// execute sends one statement and prints its results.
+func (s *psqlSession) execute(ctx context.Context, conn *pgconn.PgConn, sql string) error {
+	results, err := conn.Exec(ctx, sql).ReadAll()
+	if err != nil && len(results) == 0 {
+		results = append(results, &pgconn.Result{Err: err})
+	}
+	for _, result := range results {
+		if err := s.result(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// result prints one result. It returns the error of result when the session
+// should stop.
+func (s *psqlSession) result(result *pgconn.Result) error {
+	var failure *pgconn.PgError
+	switch {
+	case errors.As(result.Err, &failure):
+		fmt.Fprintf(s.stderr, "%s:  %s\n", failure.Severity, failure.Message)
+		if s.onErrorStop {
+			return errors.WithStack(result.Err)
+		}
+		return nil
+
+	case result.Err != nil:
+		// The connection failed.
+		return errors.WithStack(result.Err)
+
+	case result.FieldDescriptions != nil:
+		if !s.tuplesOnly {
+			columns := make([]string, len(result.FieldDescriptions))
+			for i := range result.FieldDescriptions {
+				columns[i] = string(result.FieldDescriptions[i].Name)
+			}
+			fmt.Fprintln(s.stdout, strings.Join(columns, "|"))
+		}
+		for _, row := range result.Rows {
+			values := make([]string, len(row))
+			for i := range row {
+				values[i] = string(row[i])
+			}
+			fmt.Fprintln(s.stdout, strings.Join(values, "|"))
+		}
+		if !s.tuplesOnly {
+			if len(result.Rows) == 1 {
+				fmt.Fprintln(s.stdout, "(1 row)")
+			} else {
+				fmt.Fprintf(s.stdout, "(%d rows)\n", len(result.Rows))
+			}
+		}
+
+	case !s.quiet && len(result.CommandTag) > 0:
+		fmt.Fprintln(s.stdout, result.CommandTag.String())
+	}
+	return nil
+}
+
+// quoteLiteral quotes value as a string literal, like PQescapeLiteral.
+func quoteLiteral(value string) string {
+	if strings.Contains(value, `\`) {
+		return ` E'` + strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(value) + `'`
+	}
+	return `'` + strings.ReplaceAll(value, `'`, `''`) + `'`
+}
+
+// quoteIdentifier quotes value as an identifier, like PQescapeIdentifier.
+func quoteIdentifier(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
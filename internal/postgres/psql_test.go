@@ -0,0 +1,106 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gotest.tools/v3/assert"
+)
+
+func TestParsePsql(t *testing.T) {
+	t.Run("Statements", func(t *testing.T) {
+		steps, err := parsePsql(strings.Join([]string{
+			`SET search_path TO '';`,
+			`SELECT 'a;b', "c;d", $$e;f$$, $tag$ $$; $tag$, E'g\';h' -- i;j`,
+			`  /* k; /* l; */ m; */ ;`,
+			`CREATE FUNCTION n() RETURNS void AS $$ SELECT 1; $$ LANGUAGE sql;`,
+			`SELECT (1; 2);`,
+			`SELECT 'no semicolon'`,
+		}, "\n"), nil)
+
+		assert.NilError(t, err)
+		assert.Equal(t, len(steps), 5)
+		assert.Equal(t, steps[0].SQL, `SET search_path TO '';`)
+		assert.Equal(t, steps[1].SQL, "\n"+`SELECT 'a;b', "c;d", $$e;f$$, $tag$ $$; $tag$, E'g\';h' -- i;j`+
+			"\n"+`  /* k; /* l; */ m; */ ;`)
+		assert.Equal(t, steps[2].SQL,
+			"\n"+`CREATE FUNCTION n() RETURNS void AS $$ SELECT 1; $$ LANGUAGE sql;`)
+		assert.Equal(t, steps[3].SQL, "\n"+`SELECT (1; 2);`)
+		assert.Equal(t, steps[4].SQL, "\n"+`SELECT 'no semicolon'`)
+	})
+
+	t.Run("Variables", func(t *testing.T) {
+		steps, err := parsePsql(
+			`SELECT :'a', :"a", :a, :'b', :'missing', :missing, 1::int, ':a', ":a", $$:a$$;`,
+			map[string]string{"a": `it's "x"`, "b": `back\slash`})
+
+		assert.NilError(t, err)
+		assert.Equal(t, len(steps), 1)
+		assert.Equal(t, steps[0].SQL,
+			`SELECT 'it''s "x"', "it's ""x""", it's "x",  E'back\\slash', `+
+				`:'missing', :missing, 1::int, ':a', ":a", $$:a$$;`)
+	})
+
+	t.Run("MetaCommands", func(t *testing.T) {
+		steps, err := parsePsql(`
+\pset format unaligned
+\pset tuples_only on
+CREATE TEMPORARY TABLE input (id serial, data json);
+\copy input (data) from stdin with (format text)
+{"a":1}
+{"b":2}
+\.
+SELECT format('CREATE ROLE %I', :'name')
+\gexec
+COMMIT;`, map[string]string{"name": "x"})
+
+		assert.NilError(t, err)
+		assert.Equal(t, len(steps), 6)
+		assert.DeepEqual(t, steps[0].Pset, []string{"format", "unaligned"})
+		assert.DeepEqual(t, steps[1].Pset, []string{"tuples_only", "on"})
+		assert.Equal(t, strings.TrimSpace(steps[2].SQL),
+			`CREATE TEMPORARY TABLE input (id serial, data json);`)
+		assert.Equal(t, steps[3].SQL, `COPY input (data) FROM STDIN with (format text)`)
+		assert.Equal(t, *steps[3].Copy, "{\"a\":1}\n{\"b\":2}\n")
+		assert.Equal(t, strings.TrimSpace(steps[4].SQL), `SELECT format('CREATE ROLE %I', 'x')`)
+		assert.Assert(t, steps[4].GExec)
+		assert.Equal(t, strings.TrimSpace(steps[5].SQL), `COMMIT;`)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		for _, script := range []string{
+			`\set x 1`,
+			`\gexec`,
+			`SELECT 1 \g`,
+			`\copy input to stdout`,
+			`\i some/file.sql`,
+			`\`,
+		} {
+			_, err := parsePsql(script, nil)
+			assert.Assert(t, errors.Is(err, errPsqlUnsupported), "%q: %v", script, err)
+		}
+	})
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	assert.Equal(t, quoteLiteral(""), `''`)
+	assert.Equal(t, quoteLiteral(`a'b`), `'a''b'`)
+	assert.Equal(t, quoteLiteral(`a\'b`), ` E'a\\''b'`)
+	assert.Equal(t, quoteIdentifier(`a"b`), `"a""b"`)
+}
@@ -52,7 +52,8 @@ func DownwardAPIVolumeMount() corev1.VolumeMount {
 }
 
 // InstancePod initializes outInstancePod with the database container and the
-// volumes needed by PostgreSQL.
+// volumes needed by PostgreSQL. When inDataVolume is nil, PostgreSQL data is
+// stored in an ephemeral volume according to inInstanceSpec.
 func InstancePod(ctx context.Context,
 	inCluster *v1beta1.PostgresCluster,
 	inInstanceSpec *v1beta1.PostgresInstanceSetSpec,
@@ -82,14 +83,14 @@ func InstancePod(ctx context.Context,
 	}
 
 	dataVolumeMount := DataVolumeMount()
-	dataVolume := corev1.Volume{
-		Name: dataVolumeMount.Name,
-		VolumeSource: corev1.VolumeSource{
-			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-				ClaimName: inDataVolume.Name,
-				ReadOnly:  false,
-			},
-		},
+	dataVolume := corev1.Volume{Name: dataVolumeMount.Name}
+	if inDataVolume != nil {
+		dataVolume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: inDataVolume.Name,
+			ReadOnly:  false,
+		}
+	} else {
+		dataVolume.VolumeSource = ephemeralVolumeSource(inInstanceSpec.Ephemeral)
 	}
 
 	downwardAPIVolumeMount := DownwardAPIVolumeMount()
@@ -173,7 +174,8 @@ func InstancePod(ctx context.Context,
 	reloader := corev1.Container{
 		Name: naming.ContainerClientCertCopy,
 
-		Command: reloadCommand(naming.ContainerClientCertCopy),
+		Command: reloadCommand(naming.ContainerClientCertCopy,
+			PGBackRestLogRetention(inCluster), TimestampedLogs(inCluster)),
 
 		Image:           container.Image,
 		ImagePullPolicy: container.ImagePullPolicy,
@@ -202,6 +204,14 @@ func InstancePod(ctx context.Context,
 		VolumeMounts: []corev1.VolumeMount{certVolumeMount, dataVolumeMount},
 	}
 
+	// When debugging startup, a damaged data directory should not keep the
+	// Pod from starting. Report the failure and continue.
+	if inInstanceSpec.StartupDebug != nil && *inInstanceSpec.StartupDebug {
+		startup.Command = append([]string{"bash", "-c", "--",
+			`"$@" || echo "Startup failed with status $?; continuing for debugging."`,
+			"startup-debug"}, startup.Command...)
+	}
+
 	outInstancePod.Volumes = []corev1.Volume{
 		certVolume,
 		dataVolume,
@@ -227,10 +237,52 @@ func InstancePod(ctx context.Context,
 		outInstancePod.Volumes = append(outInstancePod.Volumes, walVolume)
 	}
 
+	// Write server logs to a separate volume so they do not fill the data volume.
+	if logging := inCluster.Spec.Logging; LoggingEnabled(inCluster) {
+		logVolumeMount := LogVolumeMount()
+		logVolume := corev1.Volume{
+			Name: logVolumeMount.Name,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		}
+		if logging.Volume != nil {
+			logVolume.EmptyDir.SizeLimit = logging.Volume.SizeLimit
+		}
+
+		container.VolumeMounts = append(container.VolumeMounts, logVolumeMount)
+		outInstancePod.Volumes = append(outInstancePod.Volumes, logVolume)
+
+		// The reloader removes old log files that PostgreSQL does not reuse.
+		if TimestampedLogs(inCluster) {
+			reloader.VolumeMounts = append(reloader.VolumeMounts, logVolumeMount)
+		}
+	}
+
 	outInstancePod.Containers = []corev1.Container{container, reloader}
 	outInstancePod.InitContainers = []corev1.Container{startup}
 }
 
+// ephemeralVolumeSource returns a v1.VolumeSource that is deleted along with
+// its Pod. It is an emptyDir unless spec defines a PersistentVolumeClaim.
+func ephemeralVolumeSource(spec *v1beta1.PostgresEphemeralVolumeSpec) corev1.VolumeSource {
+	if spec != nil && spec.VolumeClaimSpec != nil {
+		return corev1.VolumeSource{
+			Ephemeral: &corev1.EphemeralVolumeSource{
+				VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{
+					Spec: *spec.VolumeClaimSpec,
+				},
+			},
+		}
+	}
+
+	source := corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+	if spec != nil {
+		source.EmptyDir.SizeLimit = spec.SizeLimit
+	}
+	return source
+}
+
 // PodSecurityContext returns a v1.PodSecurityContext for cluster that can write
 // to PersistentVolumes.
 func PodSecurityContext(cluster *v1beta1.PostgresCluster) *corev1.PodSecurityContext {
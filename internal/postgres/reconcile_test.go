@@ -496,6 +496,107 @@ volumes:
 		assert.DeepEqual(t, pod.InitContainers[0].Command[4:],
 			[]string{"startup", "11", "/pgwal/pg11_wal"})
 	})
+
+	t.Run("WithLogging", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Logging = &v1beta1.PostgresLoggingSpec{
+			Volume: &v1beta1.PostgresLogVolumeSpec{
+				SizeLimit: resource.NewQuantity(1<<30, resource.BinarySI),
+			},
+		}
+
+		pod := new(corev1.PodSpec)
+		InstancePod(ctx, cluster, new(v1beta1.PostgresInstanceSetSpec),
+			serverSecretProjection, clientSecretProjection, dataVolume, nil, pod)
+
+		assert.Assert(t, len(pod.Containers) > 0)
+		assert.Assert(t, len(pod.InitContainers) > 0)
+
+		assert.Assert(t, marshalMatches(pod.Containers[0].VolumeMounts, `
+- mountPath: /pgconf/tls
+  name: cert-volume
+  readOnly: true
+- mountPath: /pgdata
+  name: postgres-data
+- mountPath: /etc/database-containerinfo
+  name: database-containerinfo
+  readOnly: true
+- mountPath: /pglog
+  name: postgres-logs`), "expected log mount in %q container", pod.Containers[0].Name)
+
+		assert.Assert(t, marshalMatches(pod.Volumes[len(pod.Volumes)-1], `
+emptyDir:
+  sizeLimit: 1Gi
+name: postgres-logs`))
+
+		for _, container := range pod.InitContainers {
+			for _, mount := range container.VolumeMounts {
+				assert.Assert(t, mount.Name != "postgres-logs",
+					"unexpected log mount in %q container", container.Name)
+			}
+		}
+	})
+
+	t.Run("EphemeralDataVolume", func(t *testing.T) {
+		instance := new(v1beta1.PostgresInstanceSetSpec)
+		instance.Ephemeral = &v1beta1.PostgresEphemeralVolumeSpec{
+			SizeLimit: resource.NewQuantity(1<<30, resource.BinarySI),
+		}
+
+		pod := new(corev1.PodSpec)
+		InstancePod(ctx, cluster, instance,
+			serverSecretProjection, clientSecretProjection, nil, nil, pod)
+
+		assert.Assert(t, marshalMatches(pod.Volumes[1], `
+emptyDir:
+  sizeLimit: 1Gi
+name: postgres-data`))
+
+		t.Run("VolumeClaimSpec", func(t *testing.T) {
+			instance.Ephemeral.VolumeClaimSpec = &corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: resource.MustParse("1Gi"),
+					},
+				},
+			}
+
+			pod := new(corev1.PodSpec)
+			InstancePod(ctx, cluster, instance,
+				serverSecretProjection, clientSecretProjection, nil, nil, pod)
+
+			assert.Assert(t, marshalMatches(pod.Volumes[1], `
+ephemeral:
+  volumeClaimTemplate:
+    metadata:
+      creationTimestamp: null
+    spec:
+      accessModes:
+      - ReadWriteOnce
+      resources:
+        requests:
+          storage: 1Gi
+name: postgres-data`))
+		})
+	})
+
+	t.Run("StartupDebug", func(t *testing.T) {
+		instance := new(v1beta1.PostgresInstanceSetSpec)
+		instance.StartupDebug = initialize.Bool(true)
+
+		pod := new(corev1.PodSpec)
+		InstancePod(ctx, cluster, instance,
+			serverSecretProjection, clientSecretProjection, dataVolume, nil, pod)
+
+		// The startup command runs but does not stop the Pod when it fails.
+		startup := pod.InitContainers[0]
+		assert.Equal(t, startup.Name, "postgres-startup")
+		assert.DeepEqual(t, startup.Command[:5], []string{"bash", "-c", "--",
+			`"$@" || echo "Startup failed with status $?; continuing for debugging."`,
+			"startup-debug"})
+		assert.DeepEqual(t, startup.Command[5:8], []string{"bash", "-ceu", "--"})
+	})
 }
 
 func TestPodSecurityContext(t *testing.T) {
@@ -0,0 +1,63 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// TLSPolicy returns the TLS specification of cluster, or nil when there is none.
+func TLSPolicy(cluster *v1beta1.PostgresCluster) *v1beta1.PostgresTLSSpec {
+	if cluster.Spec.Config == nil {
+		return nil
+	}
+	return cluster.Spec.Config.TLS
+}
+
+// TLSHBAs populates outHBAs with a record that rejects unencrypted TCP
+// connections when cluster requires SSL. Call it after other packages have
+// added their records so that theirs match first.
+func TLSHBAs(cluster *v1beta1.PostgresCluster, outHBAs *HBAs) {
+	policy := TLSPolicy(cluster)
+	if policy == nil || policy.RequireSSL == nil || !*policy.RequireSSL {
+		return
+	}
+
+	// Mandatory records come before any specified in the spec, so this one
+	// takes precedence over those that allow connections without SSL.
+	// - https://www.postgresql.org/docs/current/auth-pg-hba-conf.html
+	outHBAs.Mandatory = append(outHBAs.Mandatory, *NewHBA().NoSSL().Method("reject"))
+}
+
+// TLSParameters sets the parameters of cluster that limit the versions and
+// ciphers of TLS that PostgreSQL accepts.
+func TLSParameters(cluster *v1beta1.PostgresCluster, outParameters *Parameters) {
+	policy := TLSPolicy(cluster)
+	if policy == nil {
+		return
+	}
+
+	// PostgreSQL 12 added this parameter.
+	// - https://www.postgresql.org/docs/current/runtime-config-connection.html#GUC-SSL-MIN-PROTOCOL-VERSION
+	if policy.MinVersion != "" && cluster.Spec.PostgresVersion >= 12 {
+		outParameters.Mandatory.Add("ssl_min_protocol_version", policy.MinVersion)
+	}
+
+	// - https://www.postgresql.org/docs/current/runtime-config-connection.html#GUC-SSL-CIPHERS
+	if policy.Ciphers != "" {
+		outParameters.Mandatory.Add("ssl_ciphers", policy.Ciphers)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestTLSHBAs(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+
+	hbas := NewHBAs()
+	TLSHBAs(cluster, &hbas)
+	assert.Equal(t, len(hbas.Mandatory), len(NewHBAs().Mandatory))
+
+	cluster.Spec.Config = &v1beta1.PostgresConfigSpec{
+		TLS: &v1beta1.PostgresTLSSpec{RequireSSL: initialize.Bool(false)},
+	}
+	TLSHBAs(cluster, &hbas)
+	assert.Equal(t, len(hbas.Mandatory), len(NewHBAs().Mandatory))
+
+	cluster.Spec.Config.TLS.RequireSSL = initialize.Bool(true)
+	TLSHBAs(cluster, &hbas)
+	assert.Equal(t, len(hbas.Mandatory), len(NewHBAs().Mandatory)+1)
+	assert.Equal(t, hbas.Mandatory[len(hbas.Mandatory)-1].String(), `hostnossl all all all reject`)
+}
+
+func TestTLSParameters(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.PostgresVersion = 14
+
+	parameters := NewParameters()
+	TLSParameters(cluster, &parameters)
+	assert.DeepEqual(t, parameters.Mandatory.AsMap(), NewParameters().Mandatory.AsMap())
+
+	cluster.Spec.Config = &v1beta1.PostgresConfigSpec{
+		TLS: &v1beta1.PostgresTLSSpec{MinVersion: "TLSv1.3", Ciphers: "HIGH:!aNULL"},
+	}
+	TLSParameters(cluster, &parameters)
+
+	value, ok := parameters.Mandatory.Get("ssl_min_protocol_version")
+	assert.Assert(t, ok)
+	assert.Equal(t, value, "TLSv1.3")
+
+	value, ok = parameters.Mandatory.Get("ssl_ciphers")
+	assert.Assert(t, ok)
+	assert.Equal(t, value, "HIGH:!aNULL")
+
+	t.Run("PostgreSQL11", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.PostgresVersion = 11
+
+		parameters := NewParameters()
+		TLSParameters(cluster, &parameters)
+
+		_, ok := parameters.Mandatory.Get("ssl_min_protocol_version")
+		assert.Assert(t, !ok)
+	})
+}
@@ -0,0 +1,84 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// AutoTuneEnabled returns whether or not parameters of cluster should be
+// derived from the resource limits of its instance sets.
+func AutoTuneEnabled(cluster *v1beta1.PostgresCluster) bool {
+	return cluster.Spec.Config != nil &&
+		cluster.Spec.Config.AutoTune != nil && *cluster.Spec.Config.AutoTune
+}
+
+// SmallestLimits returns the smallest memory limit, in bytes, and the smallest
+// CPU limit, in whole CPUs, of the instance sets in cluster. Zero means no
+// instance set has that limit.
+func SmallestLimits(cluster *v1beta1.PostgresCluster) (memory, cpus int64) {
+	for i := range cluster.Spec.InstanceSets {
+		limits := cluster.Spec.InstanceSets[i].Resources.Limits
+
+		if m := limits.Memory(); !m.IsZero() && (memory == 0 || m.Value() < memory) {
+			memory = m.Value()
+		}
+		if c := limits.Cpu(); !c.IsZero() && (cpus == 0 || c.MilliValue()/1000 < cpus) {
+			cpus = c.MilliValue() / 1000
+		}
+	}
+	return
+}
+
+// AutoTuneParameters sets default parameters of cluster that are proportional
+// to the resource limits of its instance sets, similar to pgtune. Every
+// instance shares these parameters, so they are based on the smallest limits.
+// - https://github.com/le0pard/pgtune
+func AutoTuneParameters(cluster *v1beta1.PostgresCluster, outParameters *Parameters) {
+	if !AutoTuneEnabled(cluster) {
+		return
+	}
+
+	memory, cpus := SmallestLimits(cluster)
+
+	if memory > 0 {
+		kilobytes := memory >> 10
+
+		// Use a quarter of memory for shared buffers and assume the kernel can
+		// cache most of the rest.
+		// - https://www.postgresql.org/docs/current/runtime-config-resource.html#GUC-SHARED-BUFFERS
+		// - https://www.postgresql.org/docs/current/runtime-config-query.html#GUC-EFFECTIVE-CACHE-SIZE
+		outParameters.Default.Add("shared_buffers", fmt.Sprintf("%dkB", kilobytes/4))
+		outParameters.Default.Add("effective_cache_size", fmt.Sprintf("%dkB", kilobytes*3/4))
+
+		// Maintenance operations benefit little beyond 2GB.
+		// - https://www.postgresql.org/docs/current/runtime-config-resource.html#GUC-MAINTENANCE-WORK-MEM
+		maintenance := kilobytes / 16
+		if maintenance > 2<<20 {
+			maintenance = 2 << 20
+		}
+		outParameters.Default.Add("maintenance_work_mem", fmt.Sprintf("%dkB", maintenance))
+	}
+
+	// The PostgreSQL default of 8 background workers is fine for small
+	// instances. Allow one per CPU on larger ones.
+	// - https://www.postgresql.org/docs/current/runtime-config-resource.html#GUC-MAX-WORKER-PROCESSES
+	if cpus > 8 {
+		outParameters.Default.Add("max_worker_processes", fmt.Sprint(cpus))
+	}
+}
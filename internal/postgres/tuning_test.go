@@ -0,0 +1,117 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestAutoTuneParameters(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.InstanceSets = []v1beta1.PostgresInstanceSetSpec{
+		{Name: "unlimited"},
+		{Name: "large", Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("16"),
+				corev1.ResourceMemory: resource.MustParse("64Gi"),
+			},
+		}},
+		{Name: "small", Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("12500m"),
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+		}},
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		parameters := NewParameters()
+		AutoTuneParameters(cluster, &parameters)
+		assert.DeepEqual(t, parameters.Default.AsMap(), map[string]string{
+			"jit": "off", "password_encryption": "scram-sha-256",
+		})
+
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Config = &v1beta1.PostgresConfigSpec{AutoTune: initialize.Bool(false)}
+		AutoTuneParameters(cluster, &parameters)
+		assert.Assert(t, !parameters.Default.Has("shared_buffers"))
+	})
+
+	cluster.Spec.Config = &v1beta1.PostgresConfigSpec{AutoTune: initialize.Bool(true)}
+
+	t.Run("Smallest", func(t *testing.T) {
+		parameters := NewParameters()
+		AutoTuneParameters(cluster, &parameters)
+
+		assert.Equal(t, parameters.Default.Value("shared_buffers"), "2097152kB")
+		assert.Equal(t, parameters.Default.Value("effective_cache_size"), "6291456kB")
+		assert.Equal(t, parameters.Default.Value("maintenance_work_mem"), "524288kB")
+		assert.Equal(t, parameters.Default.Value("max_worker_processes"), "12")
+		assert.Equal(t, len(parameters.Mandatory.AsMap()), len(NewParameters().Mandatory.AsMap()))
+	})
+
+	t.Run("MaintenanceLimit", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.InstanceSets = cluster.Spec.InstanceSets[1:2]
+
+		parameters := NewParameters()
+		AutoTuneParameters(cluster, &parameters)
+		assert.Equal(t, parameters.Default.Value("shared_buffers"), "16777216kB")
+		assert.Equal(t, parameters.Default.Value("maintenance_work_mem"), "2097152kB")
+		assert.Equal(t, parameters.Default.Value("max_worker_processes"), "16")
+	})
+
+	t.Run("FewCPUs", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.InstanceSets[2].Resources.Limits[corev1.ResourceCPU] = resource.MustParse("2")
+
+		parameters := NewParameters()
+		AutoTuneParameters(cluster, &parameters)
+		assert.Assert(t, !parameters.Default.Has("max_worker_processes"))
+	})
+
+	t.Run("NoLimits", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.InstanceSets = cluster.Spec.InstanceSets[:1]
+
+		parameters := NewParameters()
+		AutoTuneParameters(cluster, &parameters)
+		assert.Assert(t, !parameters.Default.Has("shared_buffers"))
+		assert.Assert(t, !parameters.Default.Has("max_worker_processes"))
+	})
+
+	t.Run("Estimate", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Patroni = &v1beta1.PatroniSpec{
+			DynamicConfiguration: runtime.RawExtension{Raw: []byte(`{
+				"postgresql": { "parameters": { "work_mem": "8MB" } }
+			}`)},
+		}
+
+		estimate, err := EstimateMemory(cluster, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, estimate.SharedBuffers, int64(2<<30), "expected tuned value")
+		assert.Equal(t, estimate.WorkMem, int64(8<<20), "expected spec value")
+	})
+}
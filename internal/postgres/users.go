@@ -19,6 +19,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"strings"
 
 	"github.com/crunchydata/postgres-operator/internal/logging"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
@@ -56,18 +57,21 @@ CREATE TEMPORARY TABLE input (id serial, data json);
 		spec := users[i]
 
 		databases := spec.Databases
+		memberOf := spec.MemberOf
 		options := spec.Options
 
 		// The "postgres" user must always be a superuser that can login to
 		// the "postgres" database.
 		if spec.Name == "postgres" {
 			databases = append(databases[:0:0], "postgres")
+			memberOf = nil
 			options = `LOGIN SUPERUSER`
 		}
 
 		if err == nil {
 			err = encoder.Encode(map[string]interface{}{
 				"databases": databases,
+				"memberOf":  memberOf,
 				"options":   options,
 				"username":  spec.Name,
 				"verifier":  verifiers[string(spec.Name)],
@@ -117,12 +121,27 @@ SELECT pg_catalog.format('GRANT ALL PRIVILEGES ON DATABASE %I TO %I',
        pg_catalog.json_extract_path_text(input.data, 'username'))
   FROM input ORDER BY input.id
 \gexec
+`)
+
+	// Grant membership in any specified roles that exist. Users created above
+	// exist by now, so users can be members of one another.
+	// - https://www.postgresql.org/docs/current/role-membership.html
+	_, _ = sql.WriteString(`
+SELECT pg_catalog.format('GRANT %I TO %I', role.rolname,
+       pg_catalog.json_extract_path_text(input.data, 'username'))
+  FROM input, pg_catalog.pg_roles AS role
+ WHERE role.rolname IN (
+       SELECT pg_catalog.json_array_elements_text(
+              pg_catalog.json_extract_path(
+              pg_catalog.json_strip_nulls(input.data), 'memberOf')))
+ ORDER BY input.id, role.rolname
+\gexec
 `)
 
 	// Commit (finish) the transaction.
 	_, _ = sql.WriteString(`COMMIT;`)
 
-	stdout, stderr, err := exec.Exec(ctx, &sql,
+	stdout, stderr, err := exec.Exec(networkScript(ctx), &sql,
 		map[string]string{
 			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
 			"QUIET":         "on", // Do not print successful statements to stdout.
@@ -132,3 +151,302 @@ SELECT pg_catalog.format('GRANT ALL PRIVILEGES ON DATABASE %I TO %I',
 
 	return err
 }
+
+// GrantPublicSchemaInPostgreSQL calls exec to allow users to create objects in
+// the "public" schema of their specified databases. PostgreSQL v15 revoked this
+// privilege from PUBLIC, so users granted access to a database cannot otherwise
+// create tables in it. The users and databases must already exist.
+// - https://www.postgresql.org/docs/release/15.0/
+func GrantPublicSchemaInPostgreSQL(
+	ctx context.Context, exec Executor, users []v1beta1.PostgresUserSpec,
+) error {
+	log := logging.FromContext(ctx)
+
+	type grant struct {
+		Databases []v1beta1.PostgresIdentifier `json:"databases"`
+		Username  v1beta1.PostgresIdentifier   `json:"username"`
+	}
+
+	grants := make([]grant, 0, len(users))
+	for i := range users {
+		// The "postgres" user is always a superuser; see WriteUsersInPostgreSQL.
+		if users[i].Name != "postgres" && len(users[i].Databases) > 0 {
+			grants = append(grants, grant{
+				Databases: users[i].Databases,
+				Username:  users[i].Name,
+			})
+		}
+	}
+	if len(grants) == 0 {
+		return nil
+	}
+
+	input, err := json.Marshal(grants)
+	if err != nil {
+		return err
+	}
+
+	// Return the names of the specified databases that allow connections.
+	const databases = "" +
+		`SET search_path = '';` +
+		`SELECT datname FROM pg_catalog.pg_database` +
+		` WHERE datallowconn AND datname IN (` +
+		`SELECT pg_catalog.json_array_elements_text(` +
+		`pg_catalog.json_extract_path(input.data, 'databases'))` +
+		` FROM pg_catalog.json_array_elements(:'grants'::json) AS input (data))`
+
+	// Grant CREATE on the "public" schema, when it exists, to the users that
+	// specified the current database.
+	// - https://www.postgresql.org/docs/current/ddl-schemas.html#DDL-SCHEMAS-PATTERNS
+	const sql = `
+SET search_path TO '';
+SELECT pg_catalog.format('GRANT CREATE ON SCHEMA public TO %I',
+       pg_catalog.json_extract_path_text(input.data, 'username'))
+  FROM pg_catalog.json_array_elements(:'grants'::json) AS input (data)
+ WHERE EXISTS (SELECT 1 FROM pg_catalog.pg_namespace WHERE nspname = 'public')
+   AND pg_catalog.current_database() IN (
+       SELECT pg_catalog.json_array_elements_text(
+              pg_catalog.json_extract_path(input.data, 'databases')))
+\gexec
+`
+
+	stdout, stderr, err := exec.ExecInDatabasesFromQuery(networkScript(ctx), databases, sql,
+		map[string]string{
+			"grants": string(input),
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("granted public schema privileges", "stdout", stdout, "stderr", stderr)
+
+	return err
+}
+
+// privilegePresets are the privileges of each PostgresPrivilegesSpec.Preset
+// on databases, schemas, tables, and sequences, in that order.
+// - https://www.postgresql.org/docs/current/ddl-priv.html
+var privilegePresets = map[string][4]string{
+	"readonly":  {`CONNECT`, `USAGE`, `SELECT`, `SELECT`},
+	"readwrite": {`CONNECT, TEMPORARY`, `USAGE`, `SELECT, INSERT, UPDATE, DELETE`, `USAGE, SELECT, UPDATE`},
+	"ddl":       {`CONNECT, TEMPORARY, CREATE`, `USAGE, CREATE`, `ALL`, `ALL`},
+}
+
+// GrantPrivilegesInPostgreSQL calls exec to grant users the privileges of
+// their specified presets in the schemas of their specified databases. The
+// same privileges are granted on tables and sequences that owners create
+// later. Owners are the users that specify the database in their databases.
+// Nothing is revoked. The users and databases must already exist.
+func GrantPrivilegesInPostgreSQL(
+	ctx context.Context, exec Executor, users, owners []v1beta1.PostgresUserSpec,
+) error {
+	log := logging.FromContext(ctx)
+
+	type grant struct {
+		Database   v1beta1.PostgresIdentifier   `json:"database"`
+		Owners     []v1beta1.PostgresIdentifier `json:"owners"`
+		Schemas    []v1beta1.PostgresIdentifier `json:"schemas,omitempty"`
+		Username   v1beta1.PostgresIdentifier   `json:"username"`
+		OnDatabase string                       `json:"onDatabase"`
+		OnSchema   string                       `json:"onSchemas"`
+		OnTable    string                       `json:"onTables"`
+		OnSequence string                       `json:"onSequences"`
+	}
+
+	grants := []grant{}
+	for i := range users {
+		// The "postgres" user is always a superuser; see WriteUsersInPostgreSQL.
+		if users[i].Name == "postgres" {
+			continue
+		}
+		for _, spec := range users[i].Privileges {
+			preset, ok := privilegePresets[spec.Preset]
+			if !ok {
+				continue
+			}
+
+			g := grant{
+				Database:   spec.Database,
+				Owners:     []v1beta1.PostgresIdentifier{},
+				Schemas:    spec.Schemas,
+				Username:   users[i].Name,
+				OnDatabase: preset[0],
+				OnSchema:   preset[1],
+				OnTable:    preset[2],
+				OnSequence: preset[3],
+			}
+			for j := range owners {
+				for _, database := range owners[j].Databases {
+					if database == spec.Database &&
+						owners[j].Name != users[i].Name && owners[j].Name != "postgres" {
+						g.Owners = append(g.Owners, owners[j].Name)
+					}
+				}
+			}
+			grants = append(grants, g)
+		}
+	}
+	if len(grants) == 0 {
+		return nil
+	}
+
+	input, err := json.Marshal(grants)
+	if err != nil {
+		return err
+	}
+
+	// Return the names of the specified databases that allow connections.
+	const databases = "" +
+		`SET search_path = '';` +
+		`SELECT datname FROM pg_catalog.pg_database` +
+		` WHERE datallowconn AND datname IN (` +
+		`SELECT pg_catalog.json_extract_path_text(input.data, 'database')` +
+		` FROM pg_catalog.json_array_elements(:'grants'::json) AS input (data))`
+
+	// Grant privileges on the current database, then on its schemas and every
+	// table and sequence in them. Privileges on tables and sequences created
+	// later are granted through the default privileges of their owners: in the
+	// specified schemas or, when there are none, in every schema including
+	// those created later. Users and owners that do not exist are skipped.
+	// - https://www.postgresql.org/docs/current/sql-grant.html
+	// - https://www.postgresql.org/docs/current/sql-alterdefaultprivileges.html
+	const sql = `
+SET search_path TO '';
+CREATE TEMPORARY TABLE input AS
+SELECT input.data
+  FROM pg_catalog.json_array_elements(:'grants'::json) AS input (data)
+ WHERE pg_catalog.json_extract_path_text(input.data, 'database') = pg_catalog.current_database()
+   AND EXISTS (SELECT 1 FROM pg_catalog.pg_roles
+                WHERE rolname = pg_catalog.json_extract_path_text(input.data, 'username'));
+CREATE TEMPORARY VIEW target AS
+SELECT input.data, n.nspname
+  FROM input, pg_catalog.pg_namespace AS n
+ WHERE n.nspname NOT LIKE 'pg\_%' AND n.nspname <> 'information_schema'
+   AND (pg_catalog.json_extract_path(input.data, 'schemas') IS NULL
+        OR n.nspname IN (SELECT pg_catalog.json_array_elements_text(
+                                pg_catalog.json_extract_path(input.data, 'schemas'))));
+SELECT pg_catalog.format('GRANT %s ON DATABASE %I TO %I',
+       pg_catalog.json_extract_path_text(input.data, 'onDatabase'),
+       pg_catalog.current_database(),
+       pg_catalog.json_extract_path_text(input.data, 'username'))
+  FROM input
+\gexec
+SELECT pg_catalog.format('GRANT %s ON SCHEMA %I TO %I',
+       pg_catalog.json_extract_path_text(target.data, 'onSchemas'), target.nspname,
+       pg_catalog.json_extract_path_text(target.data, 'username')),
+       pg_catalog.format('GRANT %s ON ALL TABLES IN SCHEMA %I TO %I',
+       pg_catalog.json_extract_path_text(target.data, 'onTables'), target.nspname,
+       pg_catalog.json_extract_path_text(target.data, 'username')),
+       pg_catalog.format('GRANT %s ON ALL SEQUENCES IN SCHEMA %I TO %I',
+       pg_catalog.json_extract_path_text(target.data, 'onSequences'), target.nspname,
+       pg_catalog.json_extract_path_text(target.data, 'username'))
+  FROM target ORDER BY target.nspname
+\gexec
+SELECT pg_catalog.format('ALTER DEFAULT PRIVILEGES FOR ROLE %I IN SCHEMA %I GRANT %s ON TABLES TO %I',
+       owner.rolname, target.nspname,
+       pg_catalog.json_extract_path_text(target.data, 'onTables'),
+       pg_catalog.json_extract_path_text(target.data, 'username')),
+       pg_catalog.format('ALTER DEFAULT PRIVILEGES FOR ROLE %I IN SCHEMA %I GRANT %s ON SEQUENCES TO %I',
+       owner.rolname, target.nspname,
+       pg_catalog.json_extract_path_text(target.data, 'onSequences'),
+       pg_catalog.json_extract_path_text(target.data, 'username'))
+  FROM target, pg_catalog.pg_roles AS owner
+ WHERE owner.rolname IN (SELECT pg_catalog.json_array_elements_text(
+                                pg_catalog.json_extract_path(target.data, 'owners')))
+   AND pg_catalog.json_extract_path(target.data, 'schemas') IS NOT NULL
+ ORDER BY target.nspname, owner.rolname
+\gexec
+SELECT pg_catalog.format('ALTER DEFAULT PRIVILEGES FOR ROLE %I GRANT %s ON SCHEMAS TO %I',
+       owner.rolname,
+       pg_catalog.json_extract_path_text(input.data, 'onSchemas'),
+       pg_catalog.json_extract_path_text(input.data, 'username')),
+       pg_catalog.format('ALTER DEFAULT PRIVILEGES FOR ROLE %I GRANT %s ON TABLES TO %I',
+       owner.rolname,
+       pg_catalog.json_extract_path_text(input.data, 'onTables'),
+       pg_catalog.json_extract_path_text(input.data, 'username')),
+       pg_catalog.format('ALTER DEFAULT PRIVILEGES FOR ROLE %I GRANT %s ON SEQUENCES TO %I',
+       owner.rolname,
+       pg_catalog.json_extract_path_text(input.data, 'onSequences'),
+       pg_catalog.json_extract_path_text(input.data, 'username'))
+  FROM input, pg_catalog.pg_roles AS owner
+ WHERE owner.rolname IN (SELECT pg_catalog.json_array_elements_text(
+                                pg_catalog.json_extract_path(input.data, 'owners')))
+   AND pg_catalog.json_extract_path(input.data, 'schemas') IS NULL
+ ORDER BY owner.rolname
+\gexec
+`
+
+	stdout, stderr, err := exec.ExecInDatabasesFromQuery(networkScript(ctx), databases, sql,
+		map[string]string{
+			"grants": string(input),
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("granted privileges", "stdout", stdout, "stderr", stderr)
+
+	return err
+}
+
+// WriteOperatorUserInPostgreSQL calls exec to create the OperatorUser when
+// enabled is true. It is a superuser that can login only with a client
+// certificate; see OperatorHBAs. When enabled is false, an OperatorUser that
+// exists can no longer login and is no longer a superuser.
+func WriteOperatorUserInPostgreSQL(ctx context.Context, exec Executor, enabled bool) error {
+	log := logging.FromContext(ctx)
+
+	sql := `
+SELECT pg_catalog.format('ALTER ROLE %I WITH NOLOGIN NOSUPERUSER PASSWORD NULL', :'username')
+ WHERE EXISTS (SELECT 1 FROM pg_catalog.pg_roles WHERE rolname = :'username')
+\gexec
+`
+	if enabled {
+		sql = `
+SELECT pg_catalog.format('CREATE ROLE %I', :'username')
+ WHERE NOT EXISTS (SELECT 1 FROM pg_catalog.pg_roles WHERE rolname = :'username')
+\gexec
+ALTER ROLE :"username" WITH LOGIN SUPERUSER PASSWORD NULL;
+`
+	}
+
+	stdout, stderr, err := exec.Exec(ctx, strings.NewReader(sql),
+		map[string]string{
+			"username": OperatorUser,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("wrote operator user", "stdout", stdout, "stderr", stderr)
+
+	return err
+}
+
+// ClientCertificateUsers returns the names of users in cluster that
+// authenticate with client certificates.
+func ClientCertificateUsers(cluster *v1beta1.PostgresCluster) []string {
+	if cluster.Spec.Authentication == nil || cluster.Spec.Authentication.ClientCertificates == nil {
+		return nil
+	}
+
+	users := cluster.Spec.Authentication.ClientCertificates.Users
+	names := make([]string, 0, len(users))
+	for _, user := range users {
+		names = append(names, string(user))
+	}
+	return names
+}
+
+// ClientCertificateHBAs populates outHBAs with records that require users of
+// cluster that authenticate with client certificates to present one when
+// connecting over TLS. They come before any password records.
+func ClientCertificateHBAs(cluster *v1beta1.PostgresCluster, outHBAs *HBAs) {
+	for _, user := range ClientCertificateUsers(cluster) {
+		// The "cert" method checks that the certificate is signed by "ssl_ca_file"
+		// and that its common name matches the user name.
+		// - https://www.postgresql.org/docs/current/auth-cert.html
+		outHBAs.Mandatory = append(outHBAs.Mandatory,
+			*NewHBA().TLS().User(user).Method("cert"))
+	}
+}
@@ -92,6 +92,16 @@ SELECT pg_catalog.format('GRANT ALL PRIVILEGES ON DATABASE %I TO %I',
        pg_catalog.json_extract_path_text(input.data, 'username'))
   FROM input ORDER BY input.id
 \gexec
+
+SELECT pg_catalog.format('GRANT %I TO %I', role.rolname,
+       pg_catalog.json_extract_path_text(input.data, 'username'))
+  FROM input, pg_catalog.pg_roles AS role
+ WHERE role.rolname IN (
+       SELECT pg_catalog.json_array_elements_text(
+              pg_catalog.json_extract_path(
+              pg_catalog.json_strip_nulls(input.data), 'memberOf')))
+ ORDER BY input.id, role.rolname
+\gexec
 COMMIT;`))
 			return nil
 		}
@@ -117,9 +127,10 @@ COMMIT;`))
 			assert.NilError(t, err)
 			assert.Assert(t, contains(string(b), `
 \copy input (data) from stdin with (format text)
-{"databases":["db1"],"options":"","username":"user-no-options","verifier":""}
-{"databases":null,"options":"some options here","username":"user-no-databases","verifier":""}
-{"databases":null,"options":"","username":"user-with-verifier","verifier":"some$verifier"}
+{"databases":["db1"],"memberOf":null,"options":"","username":"user-no-options","verifier":""}
+{"databases":null,"memberOf":null,"options":"some options here","username":"user-no-databases","verifier":""}
+{"databases":null,"memberOf":null,"options":"","username":"user-with-verifier","verifier":"some$verifier"}
+{"databases":null,"memberOf":["pg_monitor","readers"],"options":"","username":"user-with-roles","verifier":""}
 \.
 `))
 			return nil
@@ -138,6 +149,10 @@ COMMIT;`))
 				{
 					Name: "user-with-verifier",
 				},
+				{
+					Name:     "user-with-roles",
+					MemberOf: []v1beta1.PostgresIdentifier{"pg_monitor", "readers"},
+				},
 			},
 			map[string]string{
 				"no-user":            "ignored",
@@ -158,7 +173,7 @@ COMMIT;`))
 			assert.NilError(t, err)
 			assert.Assert(t, contains(string(b), `
 \copy input (data) from stdin with (format text)
-{"databases":["postgres"],"options":"LOGIN SUPERUSER","username":"postgres","verifier":"allowed"}
+{"databases":["postgres"],"memberOf":null,"options":"LOGIN SUPERUSER","username":"postgres","verifier":"allowed"}
 \.
 `))
 			return nil
@@ -170,6 +185,7 @@ COMMIT;`))
 					Name:      "postgres",
 					Databases: []v1beta1.PostgresIdentifier{"all", "ignored"},
 					Options:   "NOLOGIN CONNECTION LIMIT 0",
+					MemberOf:  []v1beta1.PostgresIdentifier{"ignored"},
 				},
 			},
 			map[string]string{
@@ -179,3 +195,169 @@ COMMIT;`))
 		assert.Equal(t, calls, 1)
 	})
 }
+
+func TestGrantPublicSchemaInPostgreSQL(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("NoDatabases", func(t *testing.T) {
+		exec := func(
+			_ context.Context, _ io.Reader, _, _ io.Writer, _ ...string,
+		) error {
+			t.Fatal("should not execute")
+			return nil
+		}
+
+		assert.NilError(t, GrantPublicSchemaInPostgreSQL(ctx, exec, nil))
+		assert.NilError(t, GrantPublicSchemaInPostgreSQL(ctx, exec,
+			[]v1beta1.PostgresUserSpec{
+				{Name: "user-no-databases"},
+				{Name: "postgres", Databases: []v1beta1.PostgresIdentifier{"db1"}},
+			}))
+	})
+
+	t.Run("Databases", func(t *testing.T) {
+		calls := 0
+		exec := func(
+			_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			calls++
+
+			b, err := ioutil.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Assert(t, strings.Contains(string(b),
+				`pg_catalog.format('GRANT CREATE ON SCHEMA public TO %I',`))
+			assert.Assert(t, strings.Contains(string(b), `\gexec`))
+
+			assert.Assert(t, cmp.Contains(command, "bash"))
+			assert.Assert(t, cmp.Contains(command,
+				`--set=grants=[{"databases":["db1","db2"],"username":"app"}]`))
+			assert.Assert(t, cmp.Contains(command, "--set=ON_ERROR_STOP=on"))
+			return nil
+		}
+
+		assert.NilError(t, GrantPublicSchemaInPostgreSQL(ctx, exec,
+			[]v1beta1.PostgresUserSpec{
+				{Name: "app", Databases: []v1beta1.PostgresIdentifier{"db1", "db2"}},
+				{Name: "user-no-databases"},
+			}))
+		assert.Equal(t, calls, 1)
+	})
+}
+
+func TestGrantPrivilegesInPostgreSQL(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("NoPrivileges", func(t *testing.T) {
+		exec := func(
+			_ context.Context, _ io.Reader, _, _ io.Writer, _ ...string,
+		) error {
+			t.Fatal("should not execute")
+			return nil
+		}
+
+		assert.NilError(t, GrantPrivilegesInPostgreSQL(ctx, exec, nil, nil))
+		assert.NilError(t, GrantPrivilegesInPostgreSQL(ctx, exec,
+			[]v1beta1.PostgresUserSpec{
+				{Name: "user-no-privileges"},
+				{Name: "postgres", Privileges: []v1beta1.PostgresPrivilegesSpec{
+					{Database: "db1", Preset: "readonly"},
+				}},
+			}, nil))
+	})
+
+	t.Run("Presets", func(t *testing.T) {
+		calls := 0
+		exec := func(
+			_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			calls++
+
+			b, err := ioutil.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Assert(t, strings.Contains(string(b),
+				`pg_catalog.format('GRANT %s ON ALL TABLES IN SCHEMA %I TO %I',`))
+			assert.Assert(t, strings.Contains(string(b),
+				`ALTER DEFAULT PRIVILEGES FOR ROLE %I IN SCHEMA %I GRANT %s ON TABLES TO %I`))
+			assert.Assert(t, strings.Contains(string(b), `\gexec`))
+
+			// Without schemas, objects in schemas created later are included.
+			assert.Assert(t, strings.Contains(string(b),
+				`ALTER DEFAULT PRIVILEGES FOR ROLE %I GRANT %s ON SCHEMAS TO %I`))
+			assert.Assert(t, strings.Contains(string(b),
+				`ALTER DEFAULT PRIVILEGES FOR ROLE %I GRANT %s ON TABLES TO %I`))
+
+			assert.Assert(t, cmp.Contains(command, "bash"))
+			assert.Assert(t, cmp.Contains(command, `--set=grants=[`+
+				`{"database":"db1","owners":["app","migrator"],"username":"reader",`+
+				`"onDatabase":"CONNECT","onSchemas":"USAGE","onTables":"SELECT","onSequences":"SELECT"},`+
+				`{"database":"db2","owners":[],"schemas":["sales"],"username":"reader",`+
+				`"onDatabase":"CONNECT, TEMPORARY","onSchemas":"USAGE",`+
+				`"onTables":"SELECT, INSERT, UPDATE, DELETE","onSequences":"USAGE, SELECT, UPDATE"},`+
+				`{"database":"db1","owners":["app"],"username":"migrator",`+
+				`"onDatabase":"CONNECT, TEMPORARY, CREATE","onSchemas":"USAGE, CREATE",`+
+				`"onTables":"ALL","onSequences":"ALL"}]`))
+			assert.Assert(t, cmp.Contains(command, "--set=ON_ERROR_STOP=on"))
+			return nil
+		}
+
+		users := []v1beta1.PostgresUserSpec{
+			{Name: "app", Databases: []v1beta1.PostgresIdentifier{"db1"}},
+			{Name: "postgres", Databases: []v1beta1.PostgresIdentifier{"db1"}},
+			{Name: "reader", Privileges: []v1beta1.PostgresPrivilegesSpec{
+				{Database: "db1", Preset: "readonly"},
+				{Database: "db2", Preset: "readwrite", Schemas: []v1beta1.PostgresIdentifier{"sales"}},
+			}},
+			{Name: "migrator", Databases: []v1beta1.PostgresIdentifier{"db1"},
+				Privileges: []v1beta1.PostgresPrivilegesSpec{
+					{Database: "db1", Preset: "ddl"},
+				}},
+		}
+
+		assert.NilError(t, GrantPrivilegesInPostgreSQL(ctx, exec, users[2:], users))
+		assert.Equal(t, calls, 1)
+	})
+}
+
+func TestWriteOperatorUserInPostgreSQL(t *testing.T) {
+	var scripts []string
+	exec := func(
+		_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		b, err := ioutil.ReadAll(stdin)
+		assert.NilError(t, err)
+		scripts = append(scripts, string(b))
+		assert.Assert(t, cmp.Contains(command, "--set=username=_crunchyoperator"))
+		assert.Assert(t, cmp.Contains(command, "--set=ON_ERROR_STOP=on"))
+		return nil
+	}
+
+	assert.NilError(t, WriteOperatorUserInPostgreSQL(context.Background(), exec, true))
+	assert.Equal(t, len(scripts), 1)
+	assert.Assert(t, strings.Contains(scripts[0], `CREATE ROLE %I`))
+	assert.Assert(t, strings.Contains(scripts[0], `WITH LOGIN SUPERUSER PASSWORD NULL`))
+
+	assert.NilError(t, WriteOperatorUserInPostgreSQL(context.Background(), exec, false))
+	assert.Equal(t, len(scripts), 2)
+	assert.Assert(t, !strings.Contains(scripts[1], `CREATE ROLE`))
+	assert.Assert(t, strings.Contains(scripts[1], `NOLOGIN NOSUPERUSER`))
+}
+
+func TestClientCertificateHBAs(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+
+	hbas := NewHBAs()
+	ClientCertificateHBAs(cluster, &hbas)
+	assert.Equal(t, len(hbas.Mandatory), len(NewHBAs().Mandatory))
+
+	cluster.Spec.Authentication = &v1beta1.PostgresAuthenticationSpec{
+		ClientCertificates: &v1beta1.PostgresClientCertificatesSpec{
+			Users: []v1beta1.PostgresIdentifier{"app", "reports"},
+		},
+	}
+	assert.DeepEqual(t, ClientCertificateUsers(cluster), []string{"app", "reports"})
+
+	ClientCertificateHBAs(cluster, &hbas)
+	mandatory := hbas.Mandatory[len(hbas.Mandatory)-2:]
+	assert.Equal(t, mandatory[0].String(), `hostssl all "app" all cert`)
+	assert.Equal(t, mandatory[1].String(), `hostssl all "reports" all cert`)
+}
@@ -0,0 +1,192 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package updatecheck compares the PostgreSQL images that clusters run against
+// an index of the latest minor releases. The index is JSON that comes from a
+// ConfigMap, for environments without access to the internet, or from a URL.
+package updatecheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// ConfigMapKey is the key of a ConfigMap that holds the release index.
+const ConfigMapKey = "index.json"
+
+// Release is the latest minor release of one major version of PostgreSQL.
+type Release struct {
+	// Version is the PostgreSQL version of the release, e.g. "16.2".
+	Version string `json:"version"`
+
+	// Image is the container image of the release.
+	Image string `json:"image,omitempty"`
+
+	// Digest is the digest of the image, e.g. "sha256:…". When present, it
+	// is compared to the digest of running containers rather than the image.
+	Digest string `json:"digest,omitempty"`
+}
+
+// Index lists the latest minor release of PostgreSQL images.
+type Index struct {
+	// Postgres is keyed by the same suffix as the RELATED_IMAGE_POSTGRES_*
+	// environment variables, e.g. "16" or "16_GIS_3.4".
+	Postgres map[string]Release `json:"postgres"`
+}
+
+// ParseIndex returns the Index encoded in data.
+func ParseIndex(data []byte) (*Index, error) {
+	index := new(Index)
+	err := json.Unmarshal(data, index)
+	if err == nil {
+		for key, release := range index.Postgres {
+			if release.Version == "" {
+				err = errors.Errorf("release %q has no version", key)
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return index, nil
+}
+
+// Latest returns the latest release for the major version of PostgreSQL, and
+// of PostGIS, that cluster runs. It returns false when there is none.
+func (index *Index) Latest(cluster *v1beta1.PostgresCluster) (Release, bool) {
+	if index == nil {
+		return Release{}, false
+	}
+	key := fmt.Sprint(cluster.Spec.PostgresVersion)
+	if version := cluster.Spec.PostGISVersion; version != "" {
+		key += "_GIS_" + version
+	}
+	release, ok := index.Postgres[key]
+	return release, ok
+}
+
+// Matches returns whether or not a container is running release. The image
+// and imageID are those reported in the status of the container. Digests are
+// compared when both are known; otherwise, images are.
+// - https://docs.k8s.io/reference/kubernetes-api/workload-resources/pod-v1/#containerstatus
+func (release Release) Matches(image, imageID string) bool {
+	digest := ""
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		digest = imageID[i+1:]
+	}
+	if release.Digest != "" && digest != "" {
+		return digest == release.Digest
+	}
+	return release.Image == "" || image == release.Image
+}
+
+// Source loads the release index and keeps it for an interval so that many
+// clusters can be compared without loading it again.
+type Source struct {
+	Interval time.Duration
+	Load     func(context.Context) ([]byte, error)
+
+	mu     sync.Mutex
+	index  *Index
+	err    error
+	loaded time.Time
+}
+
+// NewSource returns a Source that loads the index described by check, or nil
+// when checks are disabled. The ConfigMap is read through reader.
+func NewSource(check config.UpdateCheck, reader client.Reader) *Source {
+	switch {
+	case check.ConfigMapName != "":
+		return &Source{Interval: check.Interval,
+			Load: FromConfigMap(reader, check.ConfigMapNamespace, check.ConfigMapName)}
+	case check.URL != "":
+		return &Source{Interval: check.Interval,
+			Load: FromURL(&http.Client{Timeout: time.Minute}, check.URL)}
+	}
+	return nil
+}
+
+// Index returns the release index and when it was loaded. It loads the index
+// again once Interval has passed, including after an error.
+func (s *Source) Index(ctx context.Context) (*Index, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.loaded.IsZero() || time.Since(s.loaded) >= s.Interval {
+		var data []byte
+		data, s.err = s.Load(ctx)
+		if s.err == nil {
+			s.index, s.err = ParseIndex(data)
+		}
+		s.loaded = time.Now()
+	}
+	return s.index, s.loaded, s.err
+}
+
+// FromConfigMap returns a function that reads the index from the ConfigMapKey
+// of a ConfigMap.
+func FromConfigMap(
+	reader client.Reader, namespace, name string,
+) func(context.Context) ([]byte, error) {
+	return func(ctx context.Context) ([]byte, error) {
+		configmap := &corev1.ConfigMap{}
+		err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, configmap)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		data, ok := configmap.Data[ConfigMapKey]
+		if !ok {
+			return nil, errors.Errorf("ConfigMap %s/%s has no %q", namespace, name, ConfigMapKey)
+		}
+		return []byte(data), nil
+	}
+}
+
+// FromURL returns a function that downloads the index from url.
+func FromURL(hc *http.Client, url string) func(context.Context) ([]byte, error) {
+	return func(ctx context.Context) ([]byte, error) {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		response, err := hc.Do(request)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode != http.StatusOK {
+			return nil, errors.Errorf("GET %s: %s", url, response.Status)
+		}
+
+		// The index is small; refuse anything unreasonably large.
+		data, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+		return data, errors.WithStack(err)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package updatecheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+const testIndex = `{"postgres": {
+  "16": {"version": "16.2", "image": "example.com/postgres:16.2", "digest": "sha256:abc"},
+  "16_GIS_3.4": {"version": "16.2", "image": "example.com/postgis:16.2-3.4"}
+}}`
+
+func TestIndex(t *testing.T) {
+	index, err := ParseIndex([]byte(testIndex))
+	assert.NilError(t, err)
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.PostgresVersion = 15
+	_, ok := index.Latest(cluster)
+	assert.Assert(t, !ok)
+
+	cluster.Spec.PostgresVersion = 16
+	release, ok := index.Latest(cluster)
+	assert.Assert(t, ok)
+	assert.Equal(t, release.Version, "16.2")
+
+	cluster.Spec.PostGISVersion = "3.4"
+	release, ok = index.Latest(cluster)
+	assert.Assert(t, ok)
+	assert.Equal(t, release.Image, "example.com/postgis:16.2-3.4")
+
+	_, err = ParseIndex([]byte(`{"postgres": {"16": {}}}`))
+	assert.ErrorContains(t, err, "no version")
+
+	_, err = ParseIndex([]byte(`[]`))
+	assert.Assert(t, err != nil)
+}
+
+func TestReleaseMatches(t *testing.T) {
+	release := Release{Version: "16.2", Image: "example.com/postgres:16.2", Digest: "sha256:abc"}
+
+	// Digests are compared when known.
+	assert.Assert(t, release.Matches("other", "example.com/postgres@sha256:abc"))
+	assert.Assert(t, !release.Matches("example.com/postgres:16.2", "docker-pullable://example.com/postgres@sha256:def"))
+
+	// Otherwise, images are.
+	assert.Assert(t, release.Matches("example.com/postgres:16.2", ""))
+	assert.Assert(t, !release.Matches("example.com/postgres:16.1", ""))
+
+	assert.Assert(t, Release{Version: "16.2"}.Matches("anything", ""))
+}
+
+func TestSource(t *testing.T) {
+	ctx := context.Background()
+
+	assert.Assert(t, NewSource(config.UpdateCheck{}, nil) == nil)
+
+	t.Run("ConfigMap", func(t *testing.T) {
+		configmap := &corev1.ConfigMap{}
+		configmap.Namespace, configmap.Name = "pgo", "releases"
+		configmap.Data = map[string]string{ConfigMapKey: testIndex}
+		reader := fake.NewClientBuilder().WithObjects(configmap).Build()
+
+		source := NewSource(config.UpdateCheck{
+			ConfigMapNamespace: "pgo", ConfigMapName: "releases", Interval: time.Hour,
+		}, reader)
+		assert.Assert(t, source != nil)
+
+		index, loaded, err := source.Index(ctx)
+		assert.NilError(t, err)
+		assert.Assert(t, !loaded.IsZero())
+		assert.Equal(t, len(index.Postgres), 2)
+
+		missing := FromConfigMap(reader, "pgo", "missing")
+		_, err = missing(ctx)
+		assert.Assert(t, err != nil)
+	})
+
+	t.Run("URL", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			requests++
+			_, _ = w.Write([]byte(testIndex))
+		}))
+		defer server.Close()
+
+		source := NewSource(config.UpdateCheck{URL: server.URL, Interval: time.Hour}, nil)
+		assert.Assert(t, source != nil)
+
+		index, _, err := source.Index(ctx)
+		assert.NilError(t, err)
+		assert.Equal(t, len(index.Postgres), 2)
+
+		// The index is kept for the interval.
+		_, _, err = source.Index(ctx)
+		assert.NilError(t, err)
+		assert.Equal(t, requests, 1)
+
+		source.Interval = 0
+		_, _, err = source.Index(ctx)
+		assert.NilError(t, err)
+		assert.Equal(t, requests, 2)
+	})
+
+	t.Run("URLStatus", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		_, err := FromURL(server.Client(), server.URL)(ctx)
+		assert.ErrorContains(t, err, "404")
+	})
+}
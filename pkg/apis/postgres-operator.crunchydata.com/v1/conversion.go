@@ -0,0 +1,363 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// ConvertTo converts this PostgresCluster to the hub version, v1beta1.
+func (src *PostgresCluster) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.PostgresCluster)
+	if !ok {
+		return fmt.Errorf("unexpected conversion hub %T", dstRaw)
+	}
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	src.Spec.PostgresClusterSpec.DeepCopyInto(&dst.Spec)
+	src.Status.DeepCopyInto(&dst.Status)
+
+	// The v1 backups and config fields hide those in v1beta1.
+	var err error
+	if dst.Spec.Backups, err = convertBackupsTo(src.Spec.Backups); err != nil {
+		return err
+	}
+	if dst.Spec.Config, err = convertConfigTo(src.Spec.Config); err != nil {
+		return err
+	}
+
+	// The v1 authentication field hides the one in v1beta1. Copy everything
+	// but the rules.
+	dst.Spec.Authentication = nil
+	if src.Spec.Authentication != nil &&
+		!equality.Semantic.DeepEqual(src.Spec.Authentication.PostgresAuthenticationSpec,
+			v1beta1.PostgresAuthenticationSpec{}) {
+		dst.Spec.Authentication = src.Spec.Authentication.PostgresAuthenticationSpec.DeepCopy()
+	}
+
+	if src.Spec.Authentication == nil || len(src.Spec.Authentication.Rules) == 0 {
+		return nil
+	}
+
+	if dst.Spec.Patroni == nil {
+		dst.Spec.Patroni = new(v1beta1.PatroniSpec)
+	}
+
+	dynamic, postgresql, err := decodePostgreSQLSection(dst.Spec.Patroni)
+	if err != nil {
+		return err
+	}
+
+	// Structured rules come first so they are matched before any free-form values.
+	hba := make([]interface{}, 0, len(src.Spec.Authentication.Rules))
+	for i := range src.Spec.Authentication.Rules {
+		hba = append(hba, src.Spec.Authentication.Rules[i].String())
+	}
+	if section, ok := postgresql["pg_hba"].([]interface{}); ok {
+		hba = append(hba, section...)
+	}
+	postgresql["pg_hba"] = hba
+	dynamic["postgresql"] = postgresql
+
+	dst.Spec.Patroni.DynamicConfiguration.Raw, err = json.Marshal(dynamic)
+	dst.Spec.Patroni.DynamicConfiguration.Object = nil
+	return err
+}
+
+// ConvertFrom converts from the hub version, v1beta1, to this version.
+func (dst *PostgresCluster) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.PostgresCluster)
+	if !ok {
+		return fmt.Errorf("unexpected conversion hub %T", srcRaw)
+	}
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	src.Spec.DeepCopyInto(&dst.Spec.PostgresClusterSpec)
+	src.Status.DeepCopyInto(&dst.Status)
+	dst.Spec.Authentication = nil
+	dst.Spec.PostgresClusterSpec.Authentication = nil
+
+	dst.Spec.Backups = convertBackupsFrom(src.Spec.Backups)
+	dst.Spec.PostgresClusterSpec.Backups = v1beta1.Backups{}
+	dst.Spec.Config = convertConfigFrom(src.Spec.Config)
+	dst.Spec.PostgresClusterSpec.Config = nil
+
+	if src.Spec.Authentication != nil {
+		dst.Spec.Authentication = &PostgresAuthenticationSpec{
+			PostgresAuthenticationSpec: *src.Spec.Authentication.DeepCopy(),
+		}
+	}
+
+	if dst.Spec.Patroni == nil || len(dst.Spec.Patroni.DynamicConfiguration.Raw) == 0 {
+		return nil
+	}
+
+	dynamic, postgresql, err := decodePostgreSQLSection(dst.Spec.Patroni)
+	if err != nil {
+		return err
+	}
+
+	// Move "postgresql.pg_hba" only when every value can be structured without
+	// changing it. Anything else stays where it is so the round trip is lossless.
+	section, ok := postgresql["pg_hba"].([]interface{})
+	if !ok || len(section) == 0 {
+		return nil
+	}
+	rules := make([]PostgresHBARule, 0, len(section))
+	for _, value := range section {
+		line, _ := value.(string)
+		rule, ok := parseHBARule(line)
+		if !ok {
+			return nil
+		}
+		rules = append(rules, rule)
+	}
+
+	delete(postgresql, "pg_hba")
+	if len(postgresql) == 0 {
+		delete(dynamic, "postgresql")
+	} else {
+		dynamic["postgresql"] = postgresql
+	}
+
+	if dst.Spec.Authentication == nil {
+		dst.Spec.Authentication = new(PostgresAuthenticationSpec)
+	}
+	dst.Spec.Authentication.Rules = rules
+	dst.Spec.Patroni.DynamicConfiguration.Object = nil
+	dst.Spec.Patroni.DynamicConfiguration.Raw = nil
+	if len(dynamic) > 0 {
+		dst.Spec.Patroni.DynamicConfiguration.Raw, err = json.Marshal(dynamic)
+	}
+	return err
+}
+
+// convertBackupsTo returns backups in the hub version, v1beta1. It returns an
+// error when a size cannot be represented there.
+func convertBackupsTo(backups Backups) (v1beta1.Backups, error) {
+	var dst v1beta1.Backups
+	backups.PGBackRest.PGBackRestArchive.DeepCopyInto(&dst.PGBackRest)
+	dst.PGBackRest.Jobs = nil
+
+	if jobs := backups.PGBackRest.Jobs; jobs != nil {
+		dst.PGBackRest.Jobs = jobs.BackupJobs.DeepCopy()
+		dst.PGBackRest.Jobs.BufferSize = ""
+
+		if jobs.BufferSize != nil {
+			size := jobs.BufferSize.Value()
+			if !powerOfTwo(size, 16<<10, 16<<20) {
+				return dst, fmt.Errorf(
+					"spec.backups.pgbackrest.jobs.bufferSize: %v is not a power of two from 16Ki to 16Mi",
+					jobs.BufferSize)
+			}
+
+			// pgBackRest expects sizes with a "B" suffix, e.g. "64KiB" or "1MiB".
+			if size < 1<<20 {
+				dst.PGBackRest.Jobs.BufferSize = fmt.Sprintf("%dKiB", size>>10)
+			} else {
+				dst.PGBackRest.Jobs.BufferSize = fmt.Sprintf("%dMiB", size>>20)
+			}
+		}
+	}
+	return dst, nil
+}
+
+// convertBackupsFrom returns src, in the hub version, as backups of this version.
+func convertBackupsFrom(src v1beta1.Backups) Backups {
+	var backups Backups
+	src.PGBackRest.DeepCopyInto(&backups.PGBackRest.PGBackRestArchive)
+	backups.PGBackRest.PGBackRestArchive.Jobs = nil
+
+	if jobs := src.PGBackRest.Jobs; jobs != nil {
+		backups.PGBackRest.Jobs = &BackupJobs{BackupJobs: *jobs.DeepCopy()}
+		backups.PGBackRest.Jobs.BackupJobs.BufferSize = ""
+
+		if jobs.BufferSize != "" {
+			// The hub only allows values like "64KiB" that parse without the "B".
+			size, err := resource.ParseQuantity(strings.TrimSuffix(jobs.BufferSize, "B"))
+			if err == nil {
+				backups.PGBackRest.Jobs.BufferSize = &size
+			}
+		}
+	}
+	return backups
+}
+
+// convertConfigTo returns config in the hub version, v1beta1. It returns an
+// error when a size cannot be represented there.
+func convertConfigTo(config *PostgresConfigSpec) (*v1beta1.PostgresConfigSpec, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	dst := config.PostgresConfigSpec.DeepCopy()
+	dst.InitDB = nil
+
+	if initdb := config.InitDB; initdb != nil {
+		dst.InitDB = initdb.PostgresInitDBSpec.DeepCopy()
+		dst.InitDB.WALSegmentSize = nil
+
+		if initdb.WALSegmentSize != nil {
+			size := initdb.WALSegmentSize.Value()
+			if !powerOfTwo(size, 1<<20, 1<<30) {
+				return nil, fmt.Errorf(
+					"spec.config.initdb.walSegmentSize: %v is not a power of two from 1Mi to 1Gi",
+					initdb.WALSegmentSize)
+			}
+			megabytes := int32(size >> 20)
+			dst.InitDB.WALSegmentSize = &megabytes
+		}
+	}
+	return dst, nil
+}
+
+// convertConfigFrom returns src, in the hub version, as config of this version.
+func convertConfigFrom(src *v1beta1.PostgresConfigSpec) *PostgresConfigSpec {
+	if src == nil {
+		return nil
+	}
+
+	config := &PostgresConfigSpec{PostgresConfigSpec: *src.DeepCopy()}
+	config.PostgresConfigSpec.InitDB = nil
+
+	if src.InitDB != nil {
+		config.InitDB = &PostgresInitDBSpec{PostgresInitDBSpec: *src.InitDB.DeepCopy()}
+		config.InitDB.PostgresInitDBSpec.WALSegmentSize = nil
+
+		if src.InitDB.WALSegmentSize != nil {
+			config.InitDB.WALSegmentSize = resource.NewQuantity(
+				int64(*src.InitDB.WALSegmentSize)<<20, resource.BinarySI)
+		}
+	}
+	return config
+}
+
+// powerOfTwo returns true when value is a power of two from min to max.
+func powerOfTwo(value, min, max int64) bool {
+	return value >= min && value <= max && value&(value-1) == 0
+}
+
+// decodePostgreSQLSection returns the dynamicConfiguration of spec along with
+// its "postgresql" section. Neither return value is nil when err is nil.
+func decodePostgreSQLSection(spec *v1beta1.PatroniSpec) (
+	map[string]interface{}, map[string]interface{}, error,
+) {
+	dynamic := make(map[string]interface{})
+	if raw := spec.DynamicConfiguration.Raw; len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &dynamic); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	postgresql, _ := dynamic["postgresql"].(map[string]interface{})
+	if postgresql == nil {
+		postgresql = make(map[string]interface{})
+	}
+	return dynamic, postgresql, nil
+}
+
+// String returns rule formatted for the pg_hba.conf file without a newline.
+func (rule PostgresHBARule) String() string {
+	quote := func(value string) string {
+		if strings.ContainsAny(value, " \t\",#") {
+			return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+		}
+		return value
+	}
+
+	fields := []string{rule.Connection}
+	for _, list := range [][]string{rule.Databases, rule.Users} {
+		quoted := make([]string, len(list))
+		for i := range list {
+			quoted[i] = quote(list[i])
+		}
+		fields = append(fields, strings.Join(quoted, ","))
+	}
+
+	if rule.Connection != "local" {
+		if rule.Address == "" {
+			fields = append(fields, "all")
+		} else {
+			fields = append(fields, rule.Address)
+		}
+	}
+	fields = append(fields, rule.Method)
+
+	keys := make([]string, 0, len(rule.Options))
+	for k := range rule.Options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fields = append(fields, k+"="+quote(rule.Options[k]))
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// parseHBARule returns the structured form of line when formatting that form
+// produces exactly line. It returns false for anything else, including quoted
+// values, comments, and the two-field address-and-mask notation.
+func parseHBARule(line string) (PostgresHBARule, bool) {
+	var rule PostgresHBARule
+	if strings.ContainsAny(line, "\"#\t\n") {
+		return rule, false
+	}
+
+	fields := strings.Split(line, " ")
+	if len(fields) < 4 {
+		return rule, false
+	}
+
+	rule.Connection = fields[0]
+	rule.Databases = strings.Split(fields[1], ",")
+	rule.Users = strings.Split(fields[2], ",")
+	fields = fields[3:]
+
+	switch rule.Connection {
+	case "local":
+	case "host", "hostssl", "hostnossl", "hostgssenc", "hostnogssenc":
+		if len(fields) < 2 {
+			return rule, false
+		}
+		rule.Address, fields = fields[0], fields[1:]
+	default:
+		return rule, false
+	}
+
+	rule.Method, fields = fields[0], fields[1:]
+	for _, option := range fields {
+		kv := strings.SplitN(option, "=", 2)
+		if len(kv) != 2 {
+			return rule, false
+		}
+		if rule.Options == nil {
+			rule.Options = make(map[string]string)
+		}
+		rule.Options[kv[0]] = kv[1]
+	}
+
+	return rule, rule.String() == line
+}
@@ -0,0 +1,264 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+	webhook "sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestPostgresClusterConversion(t *testing.T) {
+	var _ conversion.Convertible = new(PostgresCluster)
+	var _ conversion.Hub = new(v1beta1.PostgresCluster)
+
+	scheme := runtime.NewScheme()
+	assert.NilError(t, AddToScheme(scheme))
+	assert.NilError(t, v1beta1.AddToScheme(scheme))
+
+	ok, err := webhook.IsConvertible(scheme, new(PostgresCluster))
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+}
+
+func TestPostgresHBARuleString(t *testing.T) {
+	for _, tt := range []struct {
+		rule   PostgresHBARule
+		expect string
+	}{
+		{
+			rule:   PostgresHBARule{Connection: "local", Databases: []string{"all"}, Users: []string{"postgres"}, Method: "peer"},
+			expect: `local all postgres peer`,
+		},
+		{
+			rule:   PostgresHBARule{Connection: "hostssl", Databases: []string{"app", "other"}, Users: []string{"all"}, Method: "scram-sha-256"},
+			expect: `hostssl app,other all all scram-sha-256`,
+		},
+		{
+			rule: PostgresHBARule{
+				Connection: "host", Databases: []string{"my db"}, Users: []string{"+group"},
+				Address: "10.0.0.0/8", Method: "ldap",
+				Options: map[string]string{"ldapserver": "ldap.example.com", "ldapprefix": "cn=", "ldapsuffix": ", dc=example"},
+			},
+			expect: `host "my db" +group 10.0.0.0/8 ldap ldapprefix=cn= ldapserver=ldap.example.com ldapsuffix=", dc=example"`,
+		},
+	} {
+		assert.Equal(t, tt.rule.String(), tt.expect)
+	}
+}
+
+func TestParseHBARule(t *testing.T) {
+	for _, line := range []string{
+		`local all postgres peer`,
+		`hostssl app,other all all scram-sha-256`,
+		`host all all 10.0.0.0/8 ldap ldapprefix=cn= ldapserver=ldap.example.com`,
+	} {
+		rule, ok := parseHBARule(line)
+		assert.Assert(t, ok, "%q", line)
+		assert.Equal(t, rule.String(), line)
+	}
+
+	for _, line := range []string{
+		``,
+		`# comment`,
+		`local all postgres`,
+		`host all all 10.0.0.0 255.0.0.0 md5`,
+		`host "my db" all all md5`,
+		`host  all all all md5`,
+		`remote all all all md5`,
+		`host all all all ldap ldapserver=b ldapprefix=a`,
+	} {
+		_, ok := parseHBARule(line)
+		assert.Assert(t, !ok, "%q", line)
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	decode := func(t testing.TB, raw []byte) (out map[string]interface{}) {
+		t.Helper()
+		if len(raw) > 0 {
+			assert.NilError(t, json.Unmarshal(raw, &out))
+		}
+		return
+	}
+
+	t.Run("Structured", func(t *testing.T) {
+		hub := new(v1beta1.PostgresCluster)
+		assert.NilError(t, yaml.Unmarshal([]byte(strings.TrimSpace(`
+metadata: { name: hippo, namespace: zoo }
+spec:
+  postgresVersion: 13
+  patroni:
+    dynamicConfiguration:
+      loop_wait: 5
+      postgresql:
+        parameters: { work_mem: 4MB }
+        pg_hba:
+        - hostssl all all all md5
+        - host app app 10.0.0.0/8 scram-sha-256
+status:
+  startupInstance: some-pod
+		`)), hub))
+
+		spoke := new(PostgresCluster)
+		assert.NilError(t, spoke.ConvertFrom(hub))
+
+		assert.Equal(t, spoke.Name, "hippo")
+		assert.Equal(t, spoke.Spec.PostgresVersion, 13)
+		assert.Equal(t, spoke.Status.StartupInstance, "some-pod")
+		assert.DeepEqual(t, spoke.Spec.Authentication, &PostgresAuthenticationSpec{
+			Rules: []PostgresHBARule{
+				{Connection: "hostssl", Databases: []string{"all"}, Users: []string{"all"}, Address: "all", Method: "md5"},
+				{Connection: "host", Databases: []string{"app"}, Users: []string{"app"}, Address: "10.0.0.0/8", Method: "scram-sha-256"},
+			},
+		})
+		assert.DeepEqual(t, decode(t, spoke.Spec.Patroni.DynamicConfiguration.Raw), map[string]interface{}{
+			"loop_wait":  float64(5),
+			"postgresql": map[string]interface{}{"parameters": map[string]interface{}{"work_mem": "4MB"}},
+		})
+
+		back := new(v1beta1.PostgresCluster)
+		assert.NilError(t, spoke.ConvertTo(back))
+
+		assert.DeepEqual(t,
+			decode(t, back.Spec.Patroni.DynamicConfiguration.Raw),
+			decode(t, hub.Spec.Patroni.DynamicConfiguration.Raw))
+
+		back.Spec.Patroni.DynamicConfiguration = hub.Spec.Patroni.DynamicConfiguration
+		assert.DeepEqual(t, back, hub)
+	})
+
+	t.Run("Unstructured", func(t *testing.T) {
+		hub := new(v1beta1.PostgresCluster)
+		hub.Spec.Patroni = new(v1beta1.PatroniSpec)
+		hub.Spec.Patroni.DynamicConfiguration.Raw = []byte(
+			`{"postgresql":{"pg_hba":["hostssl all all all md5","host all all 10.0.0.0 255.0.0.0 md5"]}}`)
+
+		spoke := new(PostgresCluster)
+		assert.NilError(t, spoke.ConvertFrom(hub))
+		assert.Assert(t, spoke.Spec.Authentication == nil)
+		assert.DeepEqual(t, spoke.Spec.Patroni, hub.Spec.Patroni)
+
+		back := new(v1beta1.PostgresCluster)
+		assert.NilError(t, spoke.ConvertTo(back))
+		assert.DeepEqual(t, back, hub)
+	})
+
+	t.Run("ClientCertificates", func(t *testing.T) {
+		hub := new(v1beta1.PostgresCluster)
+		hub.Spec.Authentication = &v1beta1.PostgresAuthenticationSpec{
+			ClientCertificates: &v1beta1.PostgresClientCertificatesSpec{
+				Users: []v1beta1.PostgresIdentifier{"app"},
+			},
+		}
+
+		spoke := new(PostgresCluster)
+		assert.NilError(t, spoke.ConvertFrom(hub))
+		assert.Assert(t, spoke.Spec.PostgresClusterSpec.Authentication == nil)
+		assert.DeepEqual(t, spoke.Spec.Authentication.ClientCertificates,
+			hub.Spec.Authentication.ClientCertificates)
+
+		// The field survives a trip through JSON.
+		data, err := json.Marshal(spoke)
+		assert.NilError(t, err)
+		spoke = new(PostgresCluster)
+		assert.NilError(t, json.Unmarshal(data, spoke))
+
+		back := new(v1beta1.PostgresCluster)
+		assert.NilError(t, spoke.ConvertTo(back))
+		assert.DeepEqual(t, back, hub)
+	})
+
+	t.Run("Sizes", func(t *testing.T) {
+		hub := new(v1beta1.PostgresCluster)
+		assert.NilError(t, yaml.Unmarshal([]byte(strings.TrimSpace(`
+spec:
+  backups:
+    pgbackrest:
+      image: some-image
+      jobs: { bufferSize: 256KiB }
+  config:
+    autoTune: true
+    initdb: { encoding: UTF8, walSegmentSize: 64 }
+		`)), hub))
+
+		spoke := new(PostgresCluster)
+		assert.NilError(t, spoke.ConvertFrom(hub))
+		assert.Equal(t, spoke.Spec.Backups.PGBackRest.Image, "some-image")
+		assert.Equal(t, spoke.Spec.Backups.PGBackRest.Jobs.BufferSize.String(), "256Ki")
+		assert.Equal(t, spoke.Spec.Config.InitDB.Encoding, "UTF8")
+		assert.Equal(t, spoke.Spec.Config.InitDB.WALSegmentSize.String(), "64Mi")
+
+		// The fields survive a trip through JSON.
+		data, err := json.Marshal(spoke)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(string(data), `"bufferSize":"256Ki"`), "%s", data)
+		assert.Assert(t, strings.Contains(string(data), `"walSegmentSize":"64Mi"`), "%s", data)
+		spoke = new(PostgresCluster)
+		assert.NilError(t, json.Unmarshal(data, spoke))
+
+		back := new(v1beta1.PostgresCluster)
+		assert.NilError(t, spoke.ConvertTo(back))
+		assert.DeepEqual(t, back, hub)
+
+		t.Run("Invalid", func(t *testing.T) {
+			spoke := spoke.DeepCopy()
+			size := resource.MustParse("3Mi")
+			spoke.Spec.Config.InitDB.WALSegmentSize = &size
+			assert.ErrorContains(t, spoke.ConvertTo(new(v1beta1.PostgresCluster)),
+				"walSegmentSize: 3Mi is not a power of two")
+
+			spoke = spoke.DeepCopy()
+			spoke.Spec.Config.InitDB.WALSegmentSize = nil
+			size = resource.MustParse("32Mi")
+			spoke.Spec.Backups.PGBackRest.Jobs.BufferSize = &size
+			assert.ErrorContains(t, spoke.ConvertTo(new(v1beta1.PostgresCluster)),
+				"bufferSize: 32Mi is not a power of two")
+		})
+	})
+
+	t.Run("Both", func(t *testing.T) {
+		spoke := new(PostgresCluster)
+		spoke.Spec.Authentication = &PostgresAuthenticationSpec{
+			Rules: []PostgresHBARule{
+				{Connection: "local", Databases: []string{"all"}, Users: []string{"all"}, Method: "peer"},
+			},
+		}
+		spoke.Spec.Patroni = new(v1beta1.PatroniSpec)
+		spoke.Spec.Patroni.DynamicConfiguration.Raw = []byte(
+			`{"postgresql":{"pg_hba":["host all all 10.0.0.0 255.0.0.0 md5"]}}`)
+
+		hub := new(v1beta1.PostgresCluster)
+		assert.NilError(t, spoke.ConvertTo(hub))
+		assert.DeepEqual(t, decode(t, hub.Spec.Patroni.DynamicConfiguration.Raw), map[string]interface{}{
+			"postgresql": map[string]interface{}{
+				"pg_hba": []interface{}{
+					"local all all peer",
+					"host all all 10.0.0.0 255.0.0.0 md5",
+				},
+			},
+		})
+	})
+}
@@ -0,0 +1,165 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// PostgresClusterSpec defines the desired state of PostgresCluster
+type PostgresClusterSpec struct {
+	// The v1 API shares every field with v1beta1. Fields below are structured
+	// versions of values that v1beta1 keeps in free-form configuration.
+
+	v1beta1.PostgresClusterSpec `json:",inline"`
+
+	// Authentication rules for connections to PostgreSQL. These are converted
+	// to "postgresql.pg_hba" of Patroni's dynamicConfiguration and come before
+	// any values there.
+	// +optional
+	Authentication *PostgresAuthenticationSpec `json:"authentication,omitempty"`
+
+	// PostgreSQL backup configuration. When omitted, or when no pgBackRest
+	// repositories are defined, backups are disabled.
+	// +optional
+	Backups Backups `json:"backups,omitempty"`
+
+	// PostgreSQL configuration managed by the operator.
+	// +optional
+	Config *PostgresConfigSpec `json:"config,omitempty"`
+}
+
+// PostgresAuthenticationSpec defines how clients are authenticated by PostgreSQL.
+type PostgresAuthenticationSpec struct {
+	v1beta1.PostgresAuthenticationSpec `json:",inline"`
+
+	// Records for pg_hba.conf, in the order they should be matched.
+	// More info: https://www.postgresql.org/docs/current/auth-pg-hba-conf.html
+	// +optional
+	Rules []PostgresHBARule `json:"rules,omitempty"`
+}
+
+// PostgresHBARule is a single record of pg_hba.conf.
+// More info: https://www.postgresql.org/docs/current/auth-pg-hba-conf.html
+type PostgresHBARule struct {
+	// The kind of connection this rule matches.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum={local,host,hostssl,hostnossl,hostgssenc,hostnogssenc}
+	Connection string `json:"connection"`
+
+	// The databases this rule matches. Use "all" to match every database.
+	// +kubebuilder:validation:MinItems=1
+	Databases []string `json:"databases"`
+
+	// The users this rule matches. Use "all" to match every user.
+	// +kubebuilder:validation:MinItems=1
+	Users []string `json:"users"`
+
+	// The client addresses this rule matches, in CIDR notation or one of the
+	// keywords "all", "samehost", or "samenet". This field is ignored for
+	// "local" connections and defaults to "all" for others.
+	// +kubebuilder:validation:Pattern=`^[^\s"]+$`
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// The authentication method to use when a connection matches this rule.
+	// More info: https://www.postgresql.org/docs/current/auth-methods.html
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-z0-9-]+$`
+	Method string `json:"method"`
+
+	// Options of the authentication method.
+	// +optional
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// Backups defines a PostgreSQL archive configuration.
+type Backups struct {
+	// pgBackRest archive configuration
+	// +kubebuilder:validation:Required
+	PGBackRest PGBackRestArchive `json:"pgbackrest"`
+}
+
+// PGBackRestArchive defines a pgBackRest archive configuration.
+type PGBackRestArchive struct {
+	v1beta1.PGBackRestArchive `json:",inline"`
+
+	// Jobs field allows configuration for all backup jobs
+	// +optional
+	Jobs *BackupJobs `json:"jobs,omitempty"`
+}
+
+// BackupJobs defines the configuration of all backup jobs.
+type BackupJobs struct {
+	v1beta1.BackupJobs `json:",inline"`
+
+	// Size of the buffers pgBackRest uses to copy, compress, and transfer
+	// files during backups. It must be a power of two from 16Ki to 16Mi.
+	// More info: https://pgbackrest.org/configuration.html#section-general/option-buffer-size
+	// +optional
+	BufferSize *resource.Quantity `json:"bufferSize,omitempty"`
+}
+
+// PostgresConfigSpec defines PostgreSQL parameters that the operator derives
+// from other fields and options for initializing the data directory.
+type PostgresConfigSpec struct {
+	v1beta1.PostgresConfigSpec `json:",inline"`
+
+	// Options passed to initdb when the cluster is first bootstrapped. They
+	// have no effect afterward, nor on clusters created from existing data.
+	// More info: https://www.postgresql.org/docs/current/app-initdb.html
+	// +optional
+	InitDB *PostgresInitDBSpec `json:"initdb,omitempty"`
+}
+
+// PostgresInitDBSpec defines options for initdb that cannot be changed once
+// the cluster is bootstrapped.
+type PostgresInitDBSpec struct {
+	v1beta1.PostgresInitDBSpec `json:",inline"`
+
+	// The size of WAL segment files. It must be a power of two from 1Mi to
+	// 1Gi. Defaults to 16Mi.
+	// +optional
+	WALSegmentSize *resource.Quantity `json:"walSegmentSize,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// PostgresCluster is the Schema for the postgresclusters API
+type PostgresCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PostgresClusterSpec           `json:"spec,omitempty"`
+	Status v1beta1.PostgresClusterStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PostgresClusterList contains a list of PostgresCluster
+type PostgresClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PostgresCluster `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PostgresCluster{}, &PostgresClusterList{})
+}
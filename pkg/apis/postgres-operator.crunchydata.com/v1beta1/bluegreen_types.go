@@ -0,0 +1,67 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1beta1
+
+// PostgresBlueGreenSpec makes a new PostgresCluster the "green" copy of an
+// existing "blue" PostgresCluster. Green starts from the latest backup of blue
+// when spec.dataSource.postgresCluster names blue, or from a copy of its schema
+// otherwise, and then follows blue through logical replication. At cutover,
+// blue stops accepting writes and its Services send connections to green.
+type PostgresBlueGreenSpec struct {
+	// The name of the blue PostgresCluster in the same namespace.
+	// +kubebuilder:validation:MinLength=1
+	Source string `json:"source"`
+
+	// The databases to replicate from blue. Every table in them is published.
+	// +kubebuilder:validation:MinItems=1
+	// +listType=set
+	Databases []PostgresIdentifier `json:"databases"`
+
+	// Whether or not to switch from blue to green. Once true, roles of blue
+	// that are not superusers can no longer login, their sessions end, and
+	// green takes over the Services of blue after it has caught up. The
+	// cutover cannot be undone.
+	// +kubebuilder:default=false
+	// +optional
+	Cutover *bool `json:"cutover,omitempty"`
+}
+
+// PostgresBlueGreenStatus defines the observed state of a blue/green switch.
+type PostgresBlueGreenStatus struct {
+	// The step of the switch: Preparing, Subscribing, Replicating, CuttingOver,
+	// or CutOver.
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// The name of the blue PostgresCluster. Its replication slots, publications,
+	// and role are removed when the switch stops before cutover.
+	// +optional
+	Source string `json:"source,omitempty"`
+
+	// The roles of blue that can no longer login because of the cutover.
+	// +optional
+	FencedRoles []string `json:"fencedRoles,omitempty"`
+
+	// The WAL location of blue that green recovers to from a backup. Logical
+	// replication continues from there. It is empty when green copies data.
+	// +optional
+	RestoreLSN string `json:"restoreLSN,omitempty"`
+
+	// The WAL location of blue when it stopped accepting writes. Green must
+	// replicate this far before it takes over.
+	// +optional
+	CutoverLSN string `json:"cutoverLSN,omitempty"`
+}
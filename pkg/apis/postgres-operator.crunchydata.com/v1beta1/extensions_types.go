@@ -0,0 +1,189 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PostgresExtensionsSpec defines the PostgreSQL extensions that the operator
+// installs and configures.
+type PostgresExtensionsSpec struct {
+	// Schedule SQL inside PostgreSQL with pg_cron. Changing this restarts
+	// PostgreSQL.
+	// More info: https://github.com/citusdata/pg_cron
+	// +optional
+	PGCron *PGCronSpec `json:"pgCron,omitempty"`
+
+	// Install pgvector, which stores embeddings and finds their nearest
+	// neighbors, into every database.
+	// More info: https://github.com/pgvector/pgvector
+	// +optional
+	PGVector *PGVectorSpec `json:"pgvector,omitempty"`
+
+	// Install pg_partman into some databases and maintain their partitions on
+	// a schedule. Each database gets a maintenance CronJob that calls
+	// partman.run_maintenance_proc().
+	// More info: https://github.com/pgpartman/pg_partman
+	// +optional
+	PGPartman *PGPartmanSpec `json:"pgPartman,omitempty"`
+
+	// Foreign servers to create along with their data wrappers. The remote
+	// credentials of their user mappings come from Secrets so that they need
+	// not appear in SQL scripts. Removing a server or user mapping from this
+	// list does NOT drop it.
+	// More info: https://www.postgresql.org/docs/current/ddl-foreign-data.html
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	ForeignServers []PostgresForeignServerSpec `json:"foreignServers,omitempty"`
+}
+
+// PGCronSpec defines how pg_cron is installed and the jobs it runs.
+type PGCronSpec struct {
+	// The database in which pg_cron is installed and its metadata is stored.
+	// The cron.job table and the functions that manage it are in this database.
+	// +kubebuilder:validation:Required
+	Database PostgresIdentifier `json:"database"`
+
+	// Jobs that the operator keeps in the cron.job table. Each one appears
+	// there with its name prefixed by "pgo-". Jobs with that prefix that are
+	// not in this list are unscheduled; other jobs are left alone.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Jobs []PGCronJob `json:"jobs,omitempty"`
+}
+
+// PGCronJob defines one job that pg_cron runs on a schedule.
+type PGCronJob struct {
+	// The name of this job. The value may contain only lowercase letters,
+	// numbers, and hyphen.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=59
+	Name string `json:"name"`
+
+	// The schedule in Cron format, in GMT, or an interval such as "30 seconds".
+	// More info: https://github.com/citusdata/pg_cron#what-is-pg_cron
+	// +kubebuilder:validation:MinLength=6
+	// +kubebuilder:validation:Pattern=`^(@[a-z]+|[0-9]+ seconds|\S+( +\S+){4})$`
+	Schedule string `json:"schedule"`
+
+	// The SQL to run. It runs as the postgres superuser.
+	// +kubebuilder:validation:MinLength=1
+	Command string `json:"command"`
+
+	// The database in which to run this job. Defaults to the database in which
+	// pg_cron is installed. This requires pg_cron 1.4 or later.
+	// +optional
+	Database PostgresIdentifier `json:"database,omitempty"`
+}
+
+// PGVectorSpec defines how pgvector is installed and tuned.
+type PGVectorSpec struct {
+	// The version of the vector extension to install or update to, e.g.
+	// "0.5.1". The PostgreSQL image must include it. Defaults to the version
+	// that the image installs by default.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)*$`
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Parameters that suit a kind of workload. "ANN" gives maintenance
+	// operations more memory and parallel workers so that approximate
+	// nearest neighbor indexes, such as HNSW and IVFFlat, build faster. These
+	// are derived from the resource limits of the instance sets. Parameters
+	// in spec.patroni.dynamicConfiguration take precedence.
+	// +kubebuilder:validation:Enum={ANN}
+	// +optional
+	Profile string `json:"profile,omitempty"`
+}
+
+// PGPartmanSpec defines the databases in which pg_partman is installed.
+type PGPartmanSpec struct {
+	// The databases in which to install pg_partman and maintain partitions.
+	// Removing a database from this list stops its maintenance but does NOT
+	// drop the extension.
+	// +kubebuilder:validation:MinItems=1
+	// +listType=map
+	// +listMapKey=name
+	Databases []PGPartmanDatabase `json:"databases"`
+}
+
+// PGPartmanDatabase defines how pg_partman maintains the partitions of one
+// database.
+type PGPartmanDatabase struct {
+	// The database in which pg_partman is installed into the "partman" schema.
+	Name PostgresIdentifier `json:"name"`
+
+	// How often to create and drop partitions, in Cron format.
+	// More info: https://k8s.io/docs/concepts/workloads/controllers/cron-jobs/#cron-schedule-syntax
+	// +kubebuilder:default="@hourly"
+	// +kubebuilder:validation:MinLength=6
+	// +kubebuilder:validation:Pattern=`^(@[a-z]+( [0-9a-z.]+)?|\S+( +\S+){4})$`
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// The IANA time zone of the schedule, e.g. "America/New_York". The
+	// schedule is in UTC when this is not set.
+	// More info: https://www.iana.org/time-zones
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Resource requirements for the maintenance container.
+	// More info: https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// PostgresForeignServerSpec defines a foreign server and the user mappings
+// through which local users connect to it.
+type PostgresForeignServerSpec struct {
+	// The name of the foreign server.
+	Name PostgresIdentifier `json:"name"`
+
+	// The database in which the foreign server, its user mappings, and the
+	// extension of its wrapper are created.
+	Database PostgresIdentifier `json:"database"`
+
+	// The foreign data wrapper of the server. The PostgreSQL image must include
+	// its extension.
+	// +kubebuilder:validation:Enum={postgres_fdw,oracle_fdw}
+	Wrapper string `json:"wrapper"`
+
+	// Options of the foreign server, such as "host", "port", and "dbname" of
+	// postgres_fdw or "dbserver" of oracle_fdw. Options that are not listed
+	// here are removed from the server.
+	// More info: https://www.postgresql.org/docs/current/postgres-fdw.html
+	// +optional
+	Options map[string]string `json:"options,omitempty"`
+
+	// Local users and the Secrets of their remote credentials.
+	// +listType=map
+	// +listMapKey=user
+	// +optional
+	UserMappings []PostgresUserMappingSpec `json:"userMappings,omitempty"`
+}
+
+// PostgresUserMappingSpec defines the remote credentials of one local user.
+type PostgresUserMappingSpec struct {
+	// The local user of this mapping, or "PUBLIC" for every user.
+	User PostgresIdentifier `json:"user"`
+
+	// The name of a Secret in the namespace of the PostgresCluster. Its "user"
+	// and "password" keys are the remote credentials.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+}
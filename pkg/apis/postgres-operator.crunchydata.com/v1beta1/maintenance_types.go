@@ -0,0 +1,77 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PostgresMaintenanceSpec defines SQL maintenance that runs on a schedule.
+type PostgresMaintenanceSpec struct {
+	// Jobs that run SQL against the primary on a schedule. Each one is a
+	// CronJob that connects as a maintenance user without superuser privileges.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MinItems=1
+	Jobs []PostgresMaintenanceJob `json:"jobs"`
+}
+
+// PostgresMaintenanceJob defines one scheduled SQL maintenance task.
+type PostgresMaintenanceJob struct {
+	// The name of this job. The value may contain only lowercase letters,
+	// numbers, and hyphen so that it fits into Kubernetes metadata.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=20
+	Name string `json:"name"`
+
+	// The schedule in Cron format.
+	// More info: https://k8s.io/docs/concepts/workloads/controllers/cron-jobs/#cron-schedule-syntax
+	// +kubebuilder:validation:MinLength=6
+	// +kubebuilder:validation:Pattern=`^(@[a-z]+( [0-9a-z.]+)?|\S+( +\S+){4})$`
+	Schedule string `json:"schedule"`
+
+	// The IANA time zone of the schedule, e.g. "America/New_York". The
+	// schedule is in UTC when this is not set.
+	// More info: https://www.iana.org/time-zones
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// A built-in task to run. Tables that the maintenance user cannot maintain
+	// are skipped. Exactly one of task or sql must be set.
+	// +kubebuilder:validation:Enum={vacuum,analyze,vacuumAnalyze,reindex}
+	// +optional
+	Task string `json:"task,omitempty"`
+
+	// SQL to run. It is executed by psql, stopping at the first error. Exactly
+	// one of task or sql must be set.
+	// +optional
+	SQL string `json:"sql,omitempty"`
+
+	// The database in which to run this job.
+	Database PostgresIdentifier `json:"database"`
+
+	// Resource requirements for the job container.
+	// More info: https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// PostgresMaintenanceStatus defines the observed state of SQL maintenance.
+type PostgresMaintenanceStatus struct {
+	// Identifies the maintenance user that has been installed into PostgreSQL.
+	// +optional
+	UserRevision string `json:"userRevision,omitempty"`
+}
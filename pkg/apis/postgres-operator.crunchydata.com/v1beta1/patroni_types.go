@@ -43,6 +43,7 @@ type PatroniSpec struct {
 	// +optional
 	// +kubebuilder:default=8008
 	// +kubebuilder:validation:Minimum=1024
+	// +kubebuilder:validation:Maximum=65535
 	Port *int32 `json:"port,omitempty"`
 
 	// TODO(cbandy): Describe the downtime involved with changing.
@@ -54,10 +55,116 @@ type PatroniSpec struct {
 	// +kubebuilder:validation:Minimum=1
 	SyncPeriodSeconds *int32 `json:"syncPeriodSeconds,omitempty"`
 
-	// TODO(cbandy): Add UseConfigMaps bool, default false.
-	// TODO(cbandy): Allow other DCS: etcd, raft, etc?
-	// N.B. changing this will cause downtime.
-	// - https://patroni.readthedocs.io/en/latest/kubernetes.html
+	// Switchover gives options to perform ad hoc switchovers in a PostgresCluster.
+	// +optional
+	Switchover *PatroniSwitchover `json:"switchover,omitempty"`
+
+	// How instances whose timeline diverged from the primary, such as a former
+	// primary after a failover, return to the cluster as replicas.
+	// +optional
+	Rewind *PatroniRewind `json:"rewind,omitempty"`
+
+	// How Patroni creates the data directory of a new replica. With
+	// "pgbackrest", it restores the latest backup from a pgBackRest repository
+	// when one is available, and falls back to pg_basebackup from the primary.
+	// With "basebackup", it always copies the data directory from the primary.
+	// Defaults to "pgbackrest".
+	// More info: https://patroni.readthedocs.io/en/latest/replica_bootstrap.html#building-replicas
+	// +optional
+	// +kubebuilder:validation:Enum={basebackup,pgbackrest}
+	ReplicaCreateMethod string `json:"replicaCreateMethod,omitempty"`
+
+	// How Patroni uses the Kubernetes API as its distributed configuration
+	// store (DCS) to elect a leader.
+	// +optional
+	Kubernetes *PatroniKubernetes `json:"kubernetes,omitempty"`
+
+	// TODO(cbandy): Allow other DCS: etcd, raft, etc? The controller observes
+	// members through the labels and annotations Patroni writes to Pods, and
+	// only the Kubernetes DCS writes those.
+}
+
+// PatroniKubernetes defines how Patroni stores its leader lock and
+// configuration in Kubernetes objects.
+// More info: https://patroni.readthedocs.io/en/latest/kubernetes.html
+type PatroniKubernetes struct {
+	// Whether or not Patroni stores its leader lock and configuration in
+	// ConfigMaps rather than Endpoints. Use this where Endpoints cannot be
+	// written, such as when a service mesh manages them. Changing this value
+	// causes downtime: every instance must restart and elect a leader again.
+	// Defaults to false.
+	// +optional
+	UseConfigMaps *bool `json:"useConfigMaps,omitempty"`
+}
+
+// PatroniSwitchover defines a switchover that is performed each time the
+// "postgres-operator.crunchydata.com/trigger-switchover" annotation of the
+// PostgresCluster changes.
+type PatroniSwitchover struct {
+	// Whether or not the operator should allow switchovers in a PostgresCluster.
+	Enabled bool `json:"enabled"`
+
+	// The instance that should become primary during a switchover. When not
+	// set, Patroni chooses the most suitable replica.
+	// +optional
+	TargetInstance *string `json:"targetInstance,omitempty"`
+}
+
+// PatroniRewind defines how Patroni reattaches an instance whose timeline
+// diverged from the primary. Using pg_rewind is fast but changes the data
+// directory in place; removing the data directory creates the replica again
+// from a backup or the primary, which is slower but conservative.
+// More info: https://patroni.readthedocs.io/en/latest/yaml_configuration.html#postgresql
+type PatroniRewind struct {
+	// Whether or not Patroni runs pg_rewind on an instance whose timeline
+	// diverged. Defaults to true.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Whether or not Patroni removes the data directory of an instance, and
+	// creates it again, when pg_rewind fails. Defaults to false.
+	// +optional
+	RemoveDataDirectoryOnFailure *bool `json:"removeDataDirectoryOnFailure,omitempty"`
+
+	// Whether or not Patroni removes the data directory of an instance, and
+	// creates it again, when its timeline diverged and pg_rewind is disabled
+	// or not possible. Defaults to false.
+	// +optional
+	RemoveDataDirectoryOnDivergedTimelines *bool `json:"removeDataDirectoryOnDivergedTimelines,omitempty"`
+}
+
+// PatroniTags are Patroni tags of the members of an instance set. Unset tags
+// are omitted and take the Patroni default, false.
+// More info: https://patroni.readthedocs.io/en/latest/yaml_configuration.html#tags
+type PatroniTags struct {
+	// Whether or not members are prevented from being promoted to primary,
+	// whether by failover or switchover.
+	// +optional
+	NoFailover *bool `json:"nofailover,omitempty"`
+
+	// Whether or not the Patroni REST API health checks of replicas, such as
+	// "/replica", report that members are unavailable for read-only traffic.
+	// +optional
+	NoLoadBalance *bool `json:"noloadbalance,omitempty"`
+
+	// Whether or not members are prevented from streaming WAL from the primary
+	// and instead replay WAL only from the archive. Requires Patroni v3.0.2
+	// or later.
+	// +optional
+	NoStream *bool `json:"nostream,omitempty"`
+
+	// Whether or not members are prevented from being chosen as synchronous
+	// replicas.
+	// +optional
+	NoSync *bool `json:"nosync,omitempty"`
+
+	// The kind of work members do, such as "analytics". It is also a label on
+	// their Pods so that Services can select them; see spec.analyticsService.
+	// Changing this value causes PostgreSQL to restart.
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Workload string `json:"workload,omitempty"`
 }
 
 // Default sets the default values for certain Patroni configuration attributes,
@@ -89,4 +196,18 @@ type PatroniStatus struct {
 	// The PostgreSQL system identifier reported by Patroni.
 	// +optional
 	SystemIdentifier string `json:"systemIdentifier,omitempty"`
+
+	// The value of the "trigger-switchover" annotation when the most recent
+	// switchover completed.
+	// +optional
+	Switchover *string `json:"switchover,omitempty"`
+
+	// The name of the Pod of the most recently observed primary.
+	// +optional
+	Primary string `json:"primary,omitempty"`
+
+	// PostgreSQL parameters that have changed but take effect only after
+	// the instances reporting them restart.
+	// +optional
+	PendingRestart []string `json:"pendingRestart,omitempty"`
 }
@@ -17,6 +17,7 @@ package v1beta1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -57,6 +58,49 @@ type PGBackRestJobStatus struct {
 	Failed int32 `json:"failed,omitempty"`
 }
 
+// PGBackRestRestoreRecord describes a pgBackRest restore that has finished.
+type PGBackRestRestoreRecord struct {
+
+	// The identifier of the restore: the value of the "pgbackrest-restore" annotation,
+	// or one generated by the operator when restoring to create a new cluster.
+	// +kubebuilder:validation:Required
+	ID string `json:"id"`
+
+	// The name of the PostgresCluster whose backups were restored
+	// +optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// The namespace of the PostgresCluster whose backups were restored
+	// +optional
+	ClusterNamespace string `json:"clusterNamespace,omitempty"`
+
+	// The name of the repository that was restored from
+	// +optional
+	RepoName string `json:"repoName,omitempty"`
+
+	// The pgBackRest options used for the restore, such as the recovery target
+	// +optional
+	Options []string `json:"options,omitempty"`
+
+	// The label of the pgBackRest backup set that was restored, e.g. "20211021-135143F".
+	// +optional
+	BackupSet string `json:"backupSet,omitempty"`
+
+	// The time the restore Job was acknowledged by the Job controller.
+	// It is represented in RFC3339 form and is in UTC.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// The time the restore Job finished, whether or not it succeeded.
+	// It is represented in RFC3339 form and is in UTC.
+	// +optional
+	FinishTime *metav1.Time `json:"finishTime,omitempty"`
+
+	// Whether or not the restore succeeded
+	// +kubebuilder:validation:Required
+	Succeeded bool `json:"succeeded"`
+}
+
 type PGBackRestScheduledBackupStatus struct {
 
 	// The name of the associated pgBackRest scheduled backup CronJob
@@ -71,6 +115,11 @@ type PGBackRestScheduledBackupStatus struct {
 	// +kubebuilder:validation:Required
 	Type string `json:"type,omitempty"`
 
+	// Specifies whether or not the Job is finished executing (does not indicate success or
+	// failure).
+	// +optional
+	Finished bool `json:"finished,omitempty"`
+
 	// Represents the time the manual backup Job was acknowledged by the Job controller.
 	// It is represented in RFC3339 form and is in UTC.
 	// +optional
@@ -124,11 +173,17 @@ type PGBackRestArchive struct {
 	// +optional
 	Jobs *BackupJobs `json:"jobs,omitempty"`
 
-	// Defines a pgBackRest repository
+	// Defines how PostgreSQL pushes WAL files to the repositories
+	// +optional
+	Archive *PGBackRestArchivePush `json:"archive,omitempty"`
+
+	// Defines a pgBackRest repository. Backups are disabled when there are no
+	// repositories.
 	// +kubebuilder:validation:MinItems=1
 	// +listType=map
 	// +listMapKey=name
-	Repos []PGBackRestRepo `json:"repos"`
+	// +optional
+	Repos []PGBackRestRepo `json:"repos,omitempty"`
 
 	// Defines configuration for a pgBackRest dedicated repository host.  This section is only
 	// applicable if at least one "volume" (i.e. PVC-based) repository is defined in the "repos"
@@ -144,6 +199,22 @@ type PGBackRestArchive struct {
 	// +optional
 	Restore *PGBackRestRestore `json:"restore,omitempty"`
 
+	// The name of the pgBackRest stanza that holds the backups and WAL of this
+	// cluster. Clusters that share a cloud repository must use different
+	// stanzas. A standby cluster must use the stanza of its primary cluster.
+	// Changing this value starts an empty stanza. Defaults to the namespace and
+	// name of the cluster, e.g. "postgres-operator_hippo", which is also what
+	// "auto" means. Clusters created before this default keep using "db".
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9][A-Za-z0-9_.-]*$`
+	// +optional
+	Stanza string `json:"stanza,omitempty"`
+
+	// Verify the newest full backup in each repository before older backups
+	// expire. While that backup fails verification, nothing in its repository
+	// expires, so retention never removes the only good backup.
+	// +optional
+	VerifyBeforeExpire *bool `json:"verifyBeforeExpire,omitempty"`
+
 	// Configuration for pgBackRest sidecar containers
 	// +optional
 	Sidecars *PGBackRestSidecars `json:"sidecars,omitempty"`
@@ -153,7 +224,62 @@ type PGBackRestArchive struct {
 type PGBackRestSidecars struct {
 	// Defines the configuration for the pgBackRest sidecar container
 	// +optional
-	PGBackRest *Sidecar `json:"pgbackrest,omitempty"`
+	PGBackRest *PGBackRestSidecar `json:"pgbackrest,omitempty"`
+}
+
+// PGBackRestSidecar defines the configuration for the pgBackRest sidecar
+// container of instance Pods. It runs an SSH server through which the
+// repository host runs pgBackRest, e.g. to take backups.
+type PGBackRestSidecar struct {
+	Sidecar `json:",inline"`
+
+	// Additional environment variables for the sidecar container. These are
+	// also set for the pgBackRest commands that it runs.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// The level of messages that the SSH server writes to the log of the
+	// sidecar container. Defaults to INFO.
+	// +kubebuilder:validation:Enum={QUIET,FATAL,ERROR,INFO,VERBOSE,DEBUG,DEBUG1,DEBUG2,DEBUG3}
+	// +optional
+	LogLevel string `json:"logLevel,omitempty"`
+}
+
+// PGBackRestArchivePush defines how pgBackRest archives WAL files
+type PGBackRestArchivePush struct {
+	// The algorithm pgBackRest uses to compress WAL files. Faster algorithms,
+	// such as lz4 and zst, keep up with busy clusters better than gz. Backups
+	// are not affected.
+	// More info: https://pgbackrest.org/configuration.html#section-general/option-compress-type
+	// +kubebuilder:validation:Enum={none,bz2,gz,lz4,zst}
+	// +optional
+	CompressType string `json:"compressType,omitempty"`
+
+	// The compression level pgBackRest uses for WAL files. Lower levels use
+	// less CPU. The range depends on compressType: gz is 0 to 9, bz2 is 1 to 9,
+	// lz4 is -5 to 12, and zst is -7 to 22.
+	// More info: https://pgbackrest.org/configuration.html#section-general/option-compress-level
+	// +kubebuilder:validation:Minimum=-7
+	// +kubebuilder:validation:Maximum=22
+	// +optional
+	CompressLevel *int32 `json:"compressLevel,omitempty"`
+
+	// The largest amount of WAL that can wait to be archived. When more WAL
+	// than this is waiting, pgBackRest reports those files as archived and
+	// drops them so that PostgreSQL does not run out of disk space. Backups
+	// cannot be restored to points in time that need the dropped files.
+	// More info: https://pgbackrest.org/configuration.html#section-archive/option-archive-push-queue-max
+	// +optional
+	QueueMax *resource.Quantity `json:"queueMax,omitempty"`
+
+	// Whether or not to stop sending WAL files to the repositories. PostgreSQL
+	// keeps WAL files in its data directory while this is true and sends them
+	// once it is false again. Set this on a cluster that has moved to another
+	// Kubernetes cluster so that it no longer writes to the repositories used
+	// by the new cluster. Stanzas are neither created nor upgraded while fenced.
+	// Defaults to false.
+	// +optional
+	Fenced *bool `json:"fenced,omitempty"`
 }
 
 type BackupJobs struct {
@@ -162,11 +288,52 @@ type BackupJobs struct {
 	// +optional
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 
+	// Size of the buffers pgBackRest uses to copy, compress, and transfer
+	// files during backups. Smaller buffers issue smaller reads and writes
+	// against storage.
+	// More info: https://pgbackrest.org/configuration.html#section-general/option-buffer-size
+	// +kubebuilder:validation:Enum={"16KiB","32KiB","64KiB","128KiB","256KiB","512KiB","1MiB","2MiB","4MiB","8MiB","16MiB"}
+	// +optional
+	BufferSize string `json:"bufferSize,omitempty"`
+
+	// Number of seconds pgBackRest waits for a read or write to make progress
+	// during backups before failing. Raise this when storage is slow enough
+	// that backups time out.
+	// More info: https://pgbackrest.org/configuration.html#section-io/option-io-timeout
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	IOTimeoutSeconds *int32 `json:"ioTimeoutSeconds,omitempty"`
+
+	// Maximum number of processes pgBackRest uses to compress and transfer
+	// files during backups. Fewer processes read from PostgreSQL storage at
+	// a lower rate. Defaults to 1.
+	// More info: https://pgbackrest.org/configuration.html#section-general/option-process-max
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ProcessMax *int32 `json:"processMax,omitempty"`
+
 	// Priority class name for the pgBackRest backup Job pods. Changing this
 	// value causes PostgreSQL to restart.
 	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/pod-priority-preemption/
 	// +optional
 	PriorityClassName *string `json:"priorityClassName,omitempty"`
+
+	// Scheduling constraints of pgBackRest backup Job pods. These are
+	// independent of the scheduling constraints of PostgreSQL instances.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// A selector which must match a node's labels for a pgBackRest backup Job
+	// pod to be scheduled on that node.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node/#nodeselector
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations of pgBackRest backup Job pods.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 }
 
 // PGBackRestManualBackup contains information that is used for creating a
@@ -220,6 +387,23 @@ type PGBackRestRepoHost struct {
 	// Secret containing custom SSH keys
 	// +optional
 	SSHSecret *corev1.SecretProjection `json:"sshSecret,omitempty"`
+
+	// A passive repository host that keeps a copy of every "volume" repository.
+	// It prefers a different zone than the repository host so that losing one
+	// zone does not lose every local backup.
+	// +optional
+	Standby *PGBackRestStandbyRepoHost `json:"standby,omitempty"`
+}
+
+// PGBackRestStandbyRepoHost defines a passive copy of the pgBackRest repository host.
+type PGBackRestStandbyRepoHost struct {
+
+	// Number of seconds between copies of the repositories. Only files that
+	// changed since the previous copy are transferred.
+	// +optional
+	// +kubebuilder:default=300
+	// +kubebuilder:validation:Minimum=60
+	IntervalSeconds *int32 `json:"intervalSeconds,omitempty"`
 }
 
 // PGBackRestRestore defines an in-place restore for the PostgresCluster.
@@ -234,13 +418,16 @@ type PGBackRestRestore struct {
 
 // PGBackRestBackupSchedules defines a pgBackRest scheduled backup
 type PGBackRestBackupSchedules struct {
-	// Validation set to minimum length of six to account for @daily option
+	// Validation set to minimum length of six to account for @daily option.
+	// The pattern requires a macro or five fields; a validating webhook, when
+	// installed, checks each field.
 
 	// Defines the Cron schedule for a full pgBackRest backup.
 	// Follows the standard Cron schedule syntax:
 	// https://k8s.io/docs/concepts/workloads/controllers/cron-jobs/#cron-schedule-syntax
 	// +optional
 	// +kubebuilder:validation:MinLength=6
+	// +kubebuilder:validation:Pattern=`^(@[a-z]+( [0-9a-z.]+)?|\S+( +\S+){4})$`
 	Full *string `json:"full,omitempty"`
 
 	// Defines the Cron schedule for a differential pgBackRest backup.
@@ -248,6 +435,7 @@ type PGBackRestBackupSchedules struct {
 	// https://k8s.io/docs/concepts/workloads/controllers/cron-jobs/#cron-schedule-syntax
 	// +optional
 	// +kubebuilder:validation:MinLength=6
+	// +kubebuilder:validation:Pattern=`^(@[a-z]+( [0-9a-z.]+)?|\S+( +\S+){4})$`
 	Differential *string `json:"differential,omitempty"`
 
 	// Defines the Cron schedule for an incremental pgBackRest backup.
@@ -255,7 +443,14 @@ type PGBackRestBackupSchedules struct {
 	// https://k8s.io/docs/concepts/workloads/controllers/cron-jobs/#cron-schedule-syntax
 	// +optional
 	// +kubebuilder:validation:MinLength=6
+	// +kubebuilder:validation:Pattern=`^(@[a-z]+( [0-9a-z.]+)?|\S+( +\S+){4})$`
 	Incremental *string `json:"incremental,omitempty"`
+
+	// The IANA time zone of the schedules above, e.g. "America/New_York".
+	// Schedules are in UTC when this is not set.
+	// More info: https://www.iana.org/time-zones
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
 // PGBackRestStatus defines the status of pgBackRest within a PostgresCluster
@@ -282,6 +477,24 @@ type PGBackRestStatus struct {
 	// Status information for in-place restores
 	// +optional
 	Restore *PGBackRestJobStatus `json:"restore,omitempty"`
+
+	// The most recent restores of this cluster, oldest first
+	// +optional
+	RestoreHistory []PGBackRestRestoreRecord `json:"restoreHistory,omitempty"`
+
+	// The name of the pgBackRest stanza that this cluster uses when
+	// spec.backups.pgbackrest.stanza is not set
+	// +optional
+	Stanza string `json:"stanza,omitempty"`
+
+	// The last time the stanza was verified in every repository
+	// +optional
+	StanzaCheckTime *metav1.Time `json:"stanzaCheckTime,omitempty"`
+
+	// Identifies the revision of the Secrets in configuration that every
+	// running pod has mounted.
+	// +optional
+	CredentialsRevision string `json:"credentialsRevision,omitempty"`
 }
 
 // PGBackRestRepo represents a pgBackRest repository.  Only one of its members may be specified.
@@ -334,6 +547,16 @@ type RepoPVC struct {
 	// Defines a PersistentVolumeClaim spec used to create and/or bind a volume
 	// +kubebuilder:validation:Required
 	VolumeClaimSpec corev1.PersistentVolumeClaimSpec `json:"volumeClaimSpec"`
+
+	// How to move the repository to a new volume when the storage class changes
+	// or the requested size decreases, neither of which can happen in place.
+	// "copy" stops the repository host while the repository is copied to the
+	// new volume. "reseed" starts over on an empty volume and discards every
+	// backup in the repository.
+	// Defaults to "copy".
+	// +optional
+	// +kubebuilder:validation:Enum={copy,reseed}
+	Relocation string `json:"relocation,omitempty"`
 }
 
 // RepoAzure represents a pgBackRest repository that is created using Azure storage
@@ -397,4 +620,97 @@ type RepoStatus struct {
 	// commands accordingly.
 	// +optional
 	RepoOptionsHash string `json:"repoOptionsHash,omitempty"`
+
+	// The newest full backup in this repository and whether it passed
+	// verification. This is reported only when verifyBeforeExpire is enabled.
+	// +optional
+	Verification *RepoVerificationStatus `json:"verification,omitempty"`
+}
+
+// RepoVerificationStatus describes the verification of one pgBackRest backup.
+type RepoVerificationStatus struct {
+	// The label of the backup, e.g. "20211015-150000F".
+	Backup string `json:"backup"`
+
+	// The result of verifying the backup: "Pending", "Valid", or "Invalid".
+	// Older backups in the repository expire only once it is "Valid".
+	Result string `json:"result"`
+}
+
+// PGBackRestLoggingSpec defines how verbosely pgBackRest commands log.
+// More info: https://pgbackrest.org/configuration.html#section-log
+type PGBackRestLoggingSpec struct {
+	// The level of messages written to standard output, such as the output of
+	// backup Jobs.
+	// +kubebuilder:validation:Enum={off,error,warn,info,detail,debug,trace}
+	// +optional
+	Console string `json:"console,omitempty"`
+
+	// The level of messages written to files in each container.
+	// +kubebuilder:validation:Enum={off,error,warn,info,detail,debug,trace}
+	// +optional
+	File string `json:"file,omitempty"`
+
+	// The number of days of log files to keep in instance and repository host
+	// Pods. When set, log files are renamed with their date once a day and
+	// deleted after this many days. Otherwise, they grow until the Pod is
+	// replaced.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RetentionDays *int32 `json:"retentionDays,omitempty"`
+}
+
+// PGBackRestDataSource defines a pgBackRest cloud-based repository to restore
+// from, such as one written by a PostgresCluster in another Kubernetes cluster.
+type PGBackRestDataSource struct {
+	// Projected volumes containing custom pgBackRest configuration, such as the
+	// credentials of the repository. These files are mounted under
+	// "/etc/pgbackrest/conf.d" of the restore Job.
+	// +optional
+	Configuration []corev1.VolumeProjection `json:"configuration,omitempty"`
+
+	// Global pgBackRest configuration settings for the restore Job.
+	// +optional
+	Global map[string]string `json:"global,omitempty"`
+
+	// The repository to restore from. It must be an Azure, GCS, or S3 repository.
+	// +kubebuilder:validation:Required
+	Repo PGBackRestRepo `json:"repo"`
+
+	// The name of the pgBackRest stanza in the repository. A PostgresCluster
+	// writes to a stanza named after its namespace and name, e.g.
+	// "postgres-operator_hippo", unless it sets a different one. Defaults to "db".
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9][A-Za-z0-9_.-]*$`
+	// +optional
+	Stanza string `json:"stanza,omitempty"`
+
+	// Command line options to include when running the pgBackRest restore command.
+	// https://pgbackrest.org/command.html#command-restore
+	// +optional
+	Options []string `json:"options,omitempty"`
+
+	// Resource requirements for the pgBackRest restore Job.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Scheduling constraints of the pgBackRest restore Job.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// A selector which must match a node's labels for the pgBackRest restore
+	// Job pod to be scheduled on that node.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node/#nodeselector
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Priority class name for the pgBackRest restore Job pod.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/pod-priority-preemption/
+	// +optional
+	PriorityClassName *string `json:"priorityClassName,omitempty"`
+
+	// Tolerations of the pgBackRest restore Job.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 }
@@ -92,8 +92,17 @@ type PGBouncerPodSpec struct {
 	// +optional
 	// +kubebuilder:default=5432
 	// +kubebuilder:validation:Minimum=1024
+	// +kubebuilder:validation:Maximum=65535
 	Port *int32 `json:"port,omitempty"`
 
+	// Additional connection pools, each served by its own PgBouncer process
+	// and Service. Every pool shares the configuration above except for its
+	// port and pool mode. Changing this value causes PgBouncer to restart.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Pools []PGBouncerPoolSpec `json:"pools,omitempty"`
+
 	// Priority class name for the pgBouncer pod. Changing this value causes
 	// PostgreSQL to restart.
 	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/pod-priority-preemption/
@@ -133,6 +142,34 @@ type PGBouncerPodSpec struct {
 	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
 }
 
+// PGBouncerPoolSpec defines an additional PgBouncer endpoint with its own
+// port, pool mode, and Service.
+type PGBouncerPoolSpec struct {
+	// The name of this pool. The name of its Service ends with this, e.g.
+	// "hippo-pgbouncer-session".
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=15
+	// +kubebuilder:validation:Pattern=`^[a-z]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Port on which this pool listens for client connections. It must differ
+	// from the port of PgBouncer and of every other pool.
+	// +kubebuilder:validation:Minimum=1024
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+
+	// How clients of this pool share server connections. Defaults to the
+	// "pool_mode" global setting, which PgBouncer defaults to "session".
+	// More info: https://www.pgbouncer.org/config.html#pool_mode
+	// +kubebuilder:validation:Enum={session,transaction,statement}
+	// +optional
+	PoolMode string `json:"poolMode,omitempty"`
+
+	// Specification of the service that exposes this pool.
+	// +optional
+	Service *ServiceSpec `json:"service,omitempty"`
+}
+
 // PGBouncerSidecars defines the configuration for pgBouncer sidecar containers
 type PGBouncerSidecars struct {
 	// Defines the configuration for the pgBouncer config sidecar container
@@ -154,12 +191,42 @@ func (s *PGBouncerPodSpec) Default() {
 	}
 }
 
+// PGBouncerLoggingSpec defines what PgBouncer logs and where.
+// More info: https://www.pgbouncer.org/config.html#log-settings
+type PGBouncerLoggingSpec struct {
+	// Where PgBouncer writes its log in addition to standard error. The "file"
+	// destination writes to a volume that is read by the log shipper.
+	// +kubebuilder:default=stderr
+	// +kubebuilder:validation:Enum={stderr,file}
+	// +optional
+	Destination string `json:"destination,omitempty"`
+
+	// Whether or not to log successful logins. Defaults to true.
+	// +optional
+	LogConnections *bool `json:"logConnections,omitempty"`
+
+	// Whether or not to log disconnections and their reasons. Defaults to true.
+	// +optional
+	LogDisconnections *bool `json:"logDisconnections,omitempty"`
+
+	// How often, in seconds, to log aggregated statistics. Zero disables these
+	// messages. Defaults to one minute.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	StatsPeriod *int32 `json:"statsPeriod,omitempty"`
+}
+
 type PGBouncerPodStatus struct {
 
 	// Identifies the revision of PgBouncer assets that have been installed into
 	// PostgreSQL.
 	PostgreSQLRevision string `json:"postgresRevision,omitempty"`
 
+	// Identifies the revision of PgBouncer configuration that every running
+	// pod has reloaded.
+	// +optional
+	ConfigRevision string `json:"configRevision,omitempty"`
+
 	// Total number of ready pods.
 	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
 
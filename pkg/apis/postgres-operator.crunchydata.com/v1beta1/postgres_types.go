@@ -15,6 +15,11 @@
 
 package v1beta1
 
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
 // PostgreSQL identifiers are limited in length but may contain any character.
 // More info: https://www.postgresql.org/docs/current/sql-syntax-lexical.html#SQL-SYNTAX-IDENTIFIERS
 //
@@ -22,6 +27,77 @@ package v1beta1
 // +kubebuilder:validation:MaxLength=63
 type PostgresIdentifier string
 
+type PostgresDatabaseSpec struct {
+
+	// The name of this PostgreSQL database.
+	Name PostgresIdentifier `json:"name"`
+
+	// A ConfigMap key containing SQL to execute in this database once, after
+	// it is created. The SQL runs as the "postgres" superuser and stops at the
+	// first error. Changing the SQL does NOT execute it again; remove and then
+	// restore this field to do so.
+	// +optional
+	InitSQLConfigMapRef *corev1.ConfigMapKeySelector `json:"initSQLConfigMapRef,omitempty"`
+
+	// The locale of this database, e.g. "C" or "en_US.UTF-8". The locale must
+	// be installed in the PostgreSQL image. Requires PostgreSQL 13 or later.
+	// Locale settings take effect only when the database is created; changing
+	// them afterward has no effect. When any locale setting is specified, the
+	// database is created from "template0".
+	// More info: https://www.postgresql.org/docs/current/locale.html
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_.@-]+$`
+	// +optional
+	Locale string `json:"locale,omitempty"`
+
+	// The collation order (LC_COLLATE) of this database. Defaults to the
+	// value of locale.
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_.@-]+$`
+	// +optional
+	LCCollate string `json:"lcCollate,omitempty"`
+
+	// The character classification (LC_CTYPE) of this database. Defaults to
+	// the value of locale.
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_.@-]+$`
+	// +optional
+	LCCtype string `json:"lcCtype,omitempty"`
+
+	// The provider of the default collation of this database. The "icu"
+	// provider requires PostgreSQL 15 or later and icuLocale.
+	// More info: https://www.postgresql.org/docs/current/collation.html
+	// +kubebuilder:validation:Enum={libc,icu}
+	// +optional
+	LocaleProvider string `json:"localeProvider,omitempty"`
+
+	// The ICU locale of this database when localeProvider is "icu", e.g.
+	// "en-US" or "und-u-ks-level2".
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_.@=-]+$`
+	// +optional
+	ICULocale string `json:"icuLocale,omitempty"`
+
+	// The database from which to copy this database when it is created. It
+	// can be another database in this list, which is then created first. The
+	// copy has only what the template has at that moment, so add copies after
+	// the template is initialized. PostgreSQL cannot copy a database while
+	// others are connected to it, so the template cannot be "postgres", the
+	// database of pg_cron, a database of spec.users, pg_partman, or
+	// spec.maintenance. Locale settings require "template0". Changing this has no effect after the database is
+	// created.
+	// More info: https://www.postgresql.org/docs/current/manage-ag-templatedbs.html
+	// +optional
+	Template PostgresIdentifier `json:"template,omitempty"`
+}
+
+// PostgresDatabaseStatus describes the initialization of a PostgreSQL database.
+type PostgresDatabaseStatus struct {
+
+	// The name of the PostgreSQL database.
+	Name PostgresIdentifier `json:"name"`
+
+	// A checksum of the initialization SQL that was executed in this database.
+	// +optional
+	InitSQLChecksum string `json:"initSQLChecksum,omitempty"`
+}
+
 type PostgresUserSpec struct {
 
 	// This value goes into the name of a corev1.Secret and a label value, so
@@ -47,4 +123,267 @@ type PostgresUserSpec struct {
 	// +kubebuilder:validation:Pattern=`^[^;]*$`
 	// +optional
 	Options string `json:"options,omitempty"`
+
+	// Roles of which this user is a member. The user inherits their
+	// privileges. Roles that do not exist are skipped. Removing a role from
+	// this list does NOT revoke membership. This field is ignored for the
+	// "postgres" user.
+	// +listType=set
+	// +optional
+	MemberOf []PostgresIdentifier `json:"memberOf,omitempty"`
+
+	// Privileges on the objects of other users in some databases. Removing a
+	// database from this list does NOT revoke privileges. This field is
+	// ignored for the "postgres" user.
+	// +listType=map
+	// +listMapKey=database
+	// +optional
+	Privileges []PostgresPrivilegesSpec `json:"privileges,omitempty"`
+}
+
+// PostgresPrivilegesSpec defines the privileges of a user in one database.
+type PostgresPrivilegesSpec struct {
+	// The database in which to grant privileges. It is skipped when it does
+	// not exist.
+	Database PostgresIdentifier `json:"database"`
+
+	// A set of privileges to grant. "readonly" can read every table and
+	// sequence. "readwrite" can also change their contents. "ddl" can also
+	// create schemas and objects in every schema and truncate tables. Tables
+	// and sequences that users of the database create later get the same
+	// privileges.
+	// +kubebuilder:validation:Enum={readonly,readwrite,ddl}
+	Preset string `json:"preset"`
+
+	// The schemas in which to grant privileges. Defaults to every schema
+	// other than system schemas, including schemas created later.
+	// +listType=set
+	// +optional
+	Schemas []PostgresIdentifier `json:"schemas,omitempty"`
+}
+
+// PostgresAuthenticationSpec defines how clients are authenticated by PostgreSQL.
+type PostgresAuthenticationSpec struct {
+	// Issue client certificates to some users so they can connect without a
+	// password.
+	// +optional
+	ClientCertificates *PostgresClientCertificatesSpec `json:"clientCertificates,omitempty"`
+}
+
+// PostgresClientCertificatesSpec defines the users that authenticate with
+// client certificates and how those certificates are issued.
+type PostgresClientCertificatesSpec struct {
+	// Names of users in spec.users that must present a client certificate when
+	// connecting over TLS. The certificate, its private key, and the authority
+	// that issued it are stored in the user Secret as "tls.crt", "tls.key", and
+	// "ca.crt", respectively.
+	// +kubebuilder:validation:MinItems=1
+	// +listType=set
+	Users []PostgresIdentifier `json:"users"`
+
+	// A cert-manager issuer of client certificates. When not set, certificates
+	// are issued by the cluster certificate authority. The issuer must sign with
+	// the authority in the "ca.crt" of spec.customTLSSecret.
+	// More info: https://cert-manager.io/docs/concepts/issuer/
+	// +optional
+	IssuerRef *CertManagerIssuerReference `json:"issuerRef,omitempty"`
+}
+
+// CertManagerIssuerReference identifies a cert-manager Issuer or ClusterIssuer.
+type CertManagerIssuerReference struct {
+	// Name of the issuer.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Kind of the issuer. Defaults to "Issuer".
+	// +kubebuilder:validation:Enum={Issuer,ClusterIssuer}
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// API group of the issuer. Defaults to "cert-manager.io".
+	// +optional
+	Group string `json:"group,omitempty"`
+}
+
+// PostgresConfigSpec defines PostgreSQL parameters that the operator derives
+// from other fields and options for initializing the data directory.
+type PostgresConfigSpec struct {
+	// Whether or not to derive shared_buffers, effective_cache_size,
+	// maintenance_work_mem, and max_worker_processes from the smallest memory
+	// and CPU limits of all instance sets. Parameters in
+	// spec.patroni.dynamicConfiguration always take precedence.
+	// +optional
+	AutoTune *bool `json:"autoTune,omitempty"`
+
+	// Options passed to initdb when the cluster is first bootstrapped. They
+	// have no effect afterward, nor on clusters created from existing data.
+	// More info: https://www.postgresql.org/docs/current/app-initdb.html
+	// +optional
+	InitDB *PostgresInitDBSpec `json:"initdb,omitempty"`
+
+	// How connections to PostgreSQL and PgBouncer are encrypted.
+	// +optional
+	TLS *PostgresTLSSpec `json:"tls,omitempty"`
+}
+
+// PostgresTLSSpec defines the encryption that connections to PostgreSQL and
+// PgBouncer must use. Changes here take precedence over parameters in
+// spec.patroni.dynamicConfiguration and settings in spec.proxy.pgBouncer.config.
+type PostgresTLSSpec struct {
+	// The oldest version of TLS that PostgreSQL and PgBouncer accept. This
+	// applies to PostgreSQL 12 and newer.
+	// More info: https://www.postgresql.org/docs/current/runtime-config-connection.html#GUC-SSL-MIN-PROTOCOL-VERSION
+	// +kubebuilder:validation:Enum={TLSv1.2,TLSv1.3}
+	// +optional
+	MinVersion string `json:"minVersion,omitempty"`
+
+	// Whether or not to reject connections over TCP that are not encrypted,
+	// even when pg_hba rules in spec.patroni.dynamicConfiguration allow them.
+	// Connections that the operator configures for loopback addresses, such
+	// as those of the metrics exporter, are still allowed.
+	// +optional
+	RequireSSL *bool `json:"requireSSL,omitempty"`
+
+	// The OpenSSL ciphers that PostgreSQL and PgBouncer accept for TLS 1.2
+	// and older, e.g. "HIGH:!aNULL:!MD5". Defaults to those of each program.
+	// More info: https://www.openssl.org/docs/man1.1.1/man1/ciphers.html
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_:!+@=.-]+$`
+	// +optional
+	Ciphers string `json:"ciphers,omitempty"`
+}
+
+// PostgresInitDBSpec defines options for initdb that cannot be changed once
+// the cluster is bootstrapped.
+type PostgresInitDBSpec struct {
+	// Whether or not to enable checksums on data pages to detect corruption of
+	// storage. Defaults to true.
+	// +optional
+	DataChecksums *bool `json:"dataChecksums,omitempty"`
+
+	// The character set encoding of template databases, e.g. "UTF8" or
+	// "SQL_ASCII". Defaults to "UTF8".
+	// More info: https://www.postgresql.org/docs/current/multibyte.html
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_]+$`
+	// +optional
+	Encoding string `json:"encoding,omitempty"`
+
+	// The locale of template databases, e.g. "C" or "en_US.UTF-8". The locale
+	// must be installed in the PostgreSQL image. Defaults to the locale of the
+	// image.
+	// More info: https://www.postgresql.org/docs/current/locale.html
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_.@-]+$`
+	// +optional
+	Locale string `json:"locale,omitempty"`
+
+	// The collation order (LC_COLLATE) of template databases. Defaults to
+	// the value of locale.
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_.@-]+$`
+	// +optional
+	LCCollate string `json:"lcCollate,omitempty"`
+
+	// The character classification (LC_CTYPE) of template databases. Defaults
+	// to the value of locale.
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_.@-]+$`
+	// +optional
+	LCCtype string `json:"lcCtype,omitempty"`
+
+	// The provider of the default collation of template databases. This
+	// applies to PostgreSQL 15 and newer; older versions always use "libc".
+	// The "icu" provider requires icuLocale.
+	// More info: https://www.postgresql.org/docs/current/collation.html
+	// +kubebuilder:validation:Enum={libc,icu}
+	// +optional
+	LocaleProvider string `json:"localeProvider,omitempty"`
+
+	// The ICU locale of template databases when localeProvider is "icu",
+	// e.g. "en-US" or "und-u-ks-level2".
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_.@=-]+$`
+	// +optional
+	ICULocale string `json:"icuLocale,omitempty"`
+
+	// The size of WAL segment files in megabytes. Defaults to 16.
+	// +kubebuilder:validation:Enum={1,2,4,8,16,32,64,128,256,512,1024}
+	// +optional
+	WALSegmentSize *int32 `json:"walSegmentSize,omitempty"`
+}
+
+// PostgresLoggingSpec defines where PostgreSQL writes its server log and how
+// those files are rotated and shipped.
+type PostgresLoggingSpec struct {
+	// The format of log files. The "jsonlog" format requires PostgreSQL 15 or
+	// later; older versions write "csvlog" instead. Defaults to "csvlog" when
+	// any other PostgreSQL setting here is set.
+	// More info: https://www.postgresql.org/docs/current/runtime-config-logging.html#GUC-LOG-DESTINATION
+	// +kubebuilder:validation:Enum={stderr,csvlog,jsonlog}
+	// +optional
+	Destination string `json:"destination,omitempty"`
+
+	// How often and at what size to start a new log file. When set, each file
+	// is named by the time it starts and files older than seven days are
+	// deleted. Otherwise, files are named by weekday and reused each week.
+	// +optional
+	Rotation *PostgresLogRotationSpec `json:"rotation,omitempty"`
+
+	// The volume that holds log files. Log files are kept apart from the data
+	// volume so they cannot fill it.
+	// +optional
+	Volume *PostgresLogVolumeSpec `json:"volume,omitempty"`
+
+	// A sidecar that reads log files and sends them elsewhere.
+	// +optional
+	Shipper *PostgresLogShipperSpec `json:"shipper,omitempty"`
+
+	// How PgBouncer logs, when it is enabled.
+	// +optional
+	PGBouncer *PGBouncerLoggingSpec `json:"pgbouncer,omitempty"`
+
+	// How pgBackRest commands, such as backups and WAL archiving, log.
+	// +optional
+	PGBackRest *PGBackRestLoggingSpec `json:"pgbackrest,omitempty"`
+}
+
+// PostgresLogRotationSpec defines when PostgreSQL starts a new log file.
+type PostgresLogRotationSpec struct {
+	// The maximum lifetime of a log file, in minutes ("min"), hours ("h"), or
+	// days ("d"). Zero disables time-based rotation. Defaults to one day.
+	// More info: https://www.postgresql.org/docs/current/runtime-config-logging.html#GUC-LOG-ROTATION-AGE
+	// +kubebuilder:validation:Pattern=`^[0-9]+(min|h|d)$`
+	// +optional
+	Age string `json:"age,omitempty"`
+
+	// The maximum size of a log file. Zero disables size-based rotation, which
+	// is the default.
+	// More info: https://www.postgresql.org/docs/current/runtime-config-logging.html#GUC-LOG-ROTATION-SIZE
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+}
+
+// PostgresLogVolumeSpec defines the emptyDir volume that holds log files.
+type PostgresLogVolumeSpec struct {
+	// The total amount of local storage for log files. The kubelet evicts the
+	// Pod when this is exceeded.
+	// More info: https://kubernetes.io/docs/concepts/storage/volumes/#emptydir
+	// +optional
+	SizeLimit *resource.Quantity `json:"sizeLimit,omitempty"`
+}
+
+// PostgresLogShipperSpec defines a Fluent Bit sidecar that tails log files.
+type PostgresLogShipperSpec struct {
+	// Projected volumes containing Fluent Bit configuration, such as [OUTPUT]
+	// sections. Every file ending in ".conf" is included after the inputs that
+	// read log files. Records are tagged by component, either "postgres" or
+	// "pgbouncer", and have "cluster", "component", and "pod" fields.
+	// More info: https://docs.fluentbit.io/manual/administration/configuring-fluent-bit
+	// +kubebuilder:validation:MinItems=1
+	Configuration []corev1.VolumeProjection `json:"configuration"`
+
+	// The image name to use for the Fluent Bit container. The image may also
+	// be set using the RELATED_IMAGE_FLUENTBIT environment variable.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Compute resources of the Fluent Bit container.
+	// More info: https://kubernetes.io/docs/concepts/configuration/manage-resources-containers
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 }
@@ -51,8 +51,7 @@ metadata:
   creationTimestamp: null
 spec:
   backups:
-    pgbackrest:
-      repos: null
+    pgbackrest: {}
   instances: null
   patroni:
     dynamicConfiguration: null
@@ -82,8 +81,7 @@ metadata:
   creationTimestamp: null
 spec:
   backups:
-    pgbackrest:
-      repos: null
+    pgbackrest: {}
   instances:
   - dataVolumeClaimSpec:
       resources: {}
@@ -131,6 +129,30 @@ pgBouncer:
 	})
 }
 
+func TestPostgresClusterStandbyEnabled(t *testing.T) {
+	cluster := new(PostgresCluster)
+	assert.Assert(t, !cluster.StandbyEnabled())
+
+	cluster.Spec.Standby = &PostgresStandbySpec{Enabled: true}
+	assert.Assert(t, cluster.StandbyEnabled())
+
+	// Promotion without a grace period.
+	cluster.Spec.Standby.Enabled = false
+	cluster.Status.Standby = &PostgresStandbyStatus{}
+	assert.Assert(t, !cluster.StandbyEnabled())
+
+	zero, minute := int32(0), int32(60)
+	cluster.Spec.Standby.PromotionGracePeriodSeconds = &zero
+	assert.Assert(t, !cluster.StandbyEnabled())
+
+	// Promotion waits while there is a status.
+	cluster.Spec.Standby.PromotionGracePeriodSeconds = &minute
+	assert.Assert(t, cluster.StandbyEnabled())
+
+	cluster.Status.Standby = nil
+	assert.Assert(t, !cluster.StandbyEnabled())
+}
+
 func TestPostgresInstanceSetSpecDefault(t *testing.T) {
 	var spec PostgresInstanceSetSpec
 	spec.Default(5)
@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -31,9 +32,24 @@ type PostgresClusterSpec struct {
 	// +optional
 	DataSource *DataSource `json:"dataSource,omitempty"`
 
-	// PostgreSQL backup configuration
-	// +kubebuilder:validation:Required
-	Backups Backups `json:"backups"`
+	// Authentication settings for connections to PostgreSQL.
+	// +optional
+	Authentication *PostgresAuthenticationSpec `json:"authentication,omitempty"`
+
+	// PostgreSQL backup configuration. When omitted, or when no pgBackRest
+	// repositories are defined, backups are disabled: no repository host,
+	// backup Jobs, nor pgBackRest configuration is created, and WAL files are
+	// discarded rather than archived.
+	// +optional
+	Backups Backups `json:"backups,omitempty"`
+
+	// Make this cluster the replacement of another through a blue/green switch.
+	// +optional
+	BlueGreen *PostgresBlueGreenSpec `json:"blueGreen,omitempty"`
+
+	// PostgreSQL configuration managed by the operator.
+	// +optional
+	Config *PostgresConfigSpec `json:"config,omitempty"`
 
 	// The secret containing the Certificates and Keys to encrypt PostgreSQL
 	// traffic will need to contain the server TLS certificate, TLS key and the
@@ -61,6 +77,14 @@ type PostgresClusterSpec struct {
 	// namespace as the cluster.
 	// +optional
 	DatabaseInitSQL *DatabaseInitSQL `json:"databaseInitSQL,omitempty"`
+
+	// Databases to create inside PostgreSQL in addition to those of spec.users.
+	// Removing a database from this list does NOT drop the database.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Databases []PostgresDatabaseSpec `json:"databases,omitempty"`
+
 	// Whether or not the PostgreSQL cluster should use the defined default
 	// scheduling constraints. If the field is unset or false, the default
 	// scheduling constraints will be used in addition to any custom constraints
@@ -68,6 +92,10 @@ type PostgresClusterSpec struct {
 	// +optional
 	DisableDefaultPodScheduling *bool `json:"disableDefaultPodScheduling,omitempty"`
 
+	// PostgreSQL extensions that the operator installs and configures.
+	// +optional
+	Extensions *PostgresExtensionsSpec `json:"extensions,omitempty"`
+
 	// The image name to use for PostgreSQL containers. When omitted, the value
 	// comes from an operator environment variable. For standard PostgreSQL images,
 	// the format is RELATED_IMAGE_POSTGRES_{postgresVersion},
@@ -91,12 +119,63 @@ type PostgresClusterSpec struct {
 	// +optional
 	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
 
+	// The CPU architectures for which every image of this cluster is built.
+	// Pods are then required to run on nodes of these architectures so that
+	// mixed clusters of amd64 and arm64 nodes do not fail with "exec format
+	// error". When omitted, the value comes from the PGO_IMAGE_ARCHITECTURES
+	// operator environment variable, a comma-separated list. When that is
+	// also empty, Pods may run on nodes of any architecture.
+	// More info: https://kubernetes.io/docs/reference/labels-annotations-taints/#kubernetes-io-arch
+	// +listType=set
+	// +optional
+	Architectures []Architecture `json:"architectures,omitempty"`
+
+	// DNS policy of every Pod of the cluster: instances, pgBouncer, the pgBackRest
+	// repository host, and Jobs. Set this to "None" along with dnsConfig to
+	// use, for example, a node-local DNS cache.
+	// More info: https://kubernetes.io/docs/concepts/services-networking/dns-pod-service/#pod-s-dns-policy
+	// +kubebuilder:validation:Enum={ClusterFirstWithHostNet,ClusterFirst,Default,None}
+	// +optional
+	DNSPolicy corev1.DNSPolicy `json:"dnsPolicy,omitempty"`
+
+	// DNS parameters of every Pod of the cluster, such as additional search domains.
+	// These are merged with those generated from dnsPolicy.
+	// More info: https://kubernetes.io/docs/concepts/services-networking/dns-pod-service/#pod-dns-config
+	// +optional
+	DNSConfig *corev1.PodDNSConfig `json:"dnsConfig,omitempty"`
+
+	// Entries added to /etc/hosts of every Pod of the cluster. Use these to
+	// reach hosts that are not in DNS, such as a standby outside Kubernetes.
+	// More info: https://kubernetes.io/docs/tasks/network/customize-hosts-file-for-pods/
+	// +listType=map
+	// +listMapKey=ip
+	// +optional
+	HostAliases []corev1.HostAlias `json:"hostAliases,omitempty"`
+
 	// +listType=map
 	// +listMapKey=name
 	// +kubebuilder:validation:MinItems=1
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=2
 	InstanceSets []PostgresInstanceSetSpec `json:"instances"`
 
+	// How PostgreSQL, PgBouncer, and pgBackRest log. When none of the
+	// PostgreSQL settings are set, PostgreSQL writes to the standard error of
+	// the database container.
+	// +optional
+	Logging *PostgresLoggingSpec `json:"logging,omitempty"`
+
+	// Routine SQL maintenance to run on a schedule.
+	// +optional
+	Maintenance *PostgresMaintenanceSpec `json:"maintenance,omitempty"`
+
+	// The class of operator that manages this cluster. An operator manages only
+	// the clusters of its class, set by its PGO_OPERATOR_CLASS environment
+	// variable. Clusters without a class are managed by operators without a
+	// class. Use this to run more than one operator in a Kubernetes cluster.
+	// +kubebuilder:validation:MaxLength=63
+	// +optional
+	OperatorClass string `json:"operatorClass,omitempty"`
+
 	// Whether or not the PostgreSQL cluster is being deployed to an OpenShift
 	// environment. If the field is unset, the operator will automatically
 	// detect the environment.
@@ -110,17 +189,20 @@ type PostgresClusterSpec struct {
 	// +optional
 	// +kubebuilder:default=5432
 	// +kubebuilder:validation:Minimum=1024
+	// +kubebuilder:validation:Maximum=65535
 	Port *int32 `json:"port,omitempty"`
 
 	// The major version of PostgreSQL installed in the PostgreSQL image
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:Minimum=10
-	// +kubebuilder:validation:Maximum=14
+	// +kubebuilder:validation:Maximum=16
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1
 	PostgresVersion int `json:"postgresVersion"`
 
 	// The PostGIS extension version installed in the PostgreSQL image.
 	// When image is not set, indicates a PostGIS enabled image will be used.
+	// PostGIS, its raster and topology extensions, and fuzzystrmatch are
+	// created in every database and updated when this changes.
 	// +optional
 	PostGISVersion string `json:"postGISVersion,omitempty"`
 
@@ -136,6 +218,30 @@ type PostgresClusterSpec struct {
 	// +optional
 	Service *ServiceSpec `json:"service,omitempty"`
 
+	// Specification of a Service that exposes only the PostgreSQL replicas of
+	// instance sets tagged with the "analytics" workload. Heavy read-only
+	// queries sent there stay off the other replicas, such as those that are
+	// synchronous. The Service is deleted when this is unset.
+	// +optional
+	AnalyticsService *ServiceSpec `json:"analyticsService,omitempty"`
+
+	// Periodically measure how full the PostgreSQL data and WAL volumes are,
+	// report it in status, and warn when they are nearly full.
+	// +optional
+	VolumeUsage *PostgresVolumeUsageSpec `json:"volumeUsage,omitempty"`
+
+	// Periodically read the memory and CPU usage of each PostgreSQL instance
+	// from the Kubernetes Metrics API, report it in status, and set the
+	// Overloaded condition when memory stays nearly full. This requires
+	// metrics-server or another implementation of that API.
+	// +optional
+	ResourceUsage *PostgresResourceUsageSpec `json:"resourceUsage,omitempty"`
+
+	// Specification of how the primary service routes to the PostgreSQL
+	// primary instance.
+	// +optional
+	PrimaryService *PrimaryServiceSpec `json:"primaryService,omitempty"`
+
 	// Whether or not the PostgreSQL cluster should be stopped.
 	// When this is true, workloads are scaled to zero and CronJobs
 	// are suspended.
@@ -143,6 +249,18 @@ type PostgresClusterSpec struct {
 	// +optional
 	Shutdown *bool `json:"shutdown,omitempty"`
 
+	// A one-time repair of the data directory of one instance. It runs when
+	// the "postgres-operator.crunchydata.com/repair" annotation changes.
+	// +optional
+	Repair *PostgresRepairSpec `json:"repair,omitempty"`
+
+	// Replace replicas whose PostgreSQL keeps failing. A replica that has
+	// crashed or failed to start for failureSeconds is removed along with its
+	// volumes, and a new replica is created from a backup or the primary. One
+	// replica is replaced at a time, and only while the primary is ready.
+	// +optional
+	ReplicaHealing *PostgresReplicaHealingSpec `json:"replicaHealing,omitempty"`
+
 	// Run this cluster as a read-only copy of an existing cluster or archive.
 	// +optional
 	Standby *PostgresStandbySpec `json:"standby,omitempty"`
@@ -153,6 +271,10 @@ type PostgresClusterSpec struct {
 	// +optional
 	SupplementalGroups []int64 `json:"supplementalGroups,omitempty"`
 
+	// Steps to take when the PostgresCluster is deleted.
+	// +optional
+	Teardown *PostgresTeardownSpec `json:"teardown,omitempty"`
+
 	// Users to create inside PostgreSQL and the databases they should access.
 	// The default creates one user that can access one database matching the
 	// PostgresCluster name. An empty list creates no users. Removing a user
@@ -163,6 +285,17 @@ type PostgresClusterSpec struct {
 	Users []PostgresUserSpec `json:"users,omitempty"`
 }
 
+// PostgresTeardownSpec defines the steps taken when a PostgresCluster is deleted.
+// Backup schedules are suspended first, then any final backup is taken, then
+// instances are stopped.
+type PostgresTeardownSpec struct {
+	// The name of a pgBackRest repository to take a full backup to before
+	// instances are stopped. Deletion waits until this backup succeeds.
+	// +kubebuilder:validation:Pattern=^repo[1-4]
+	// +optional
+	FinalBackupRepoName string `json:"finalBackupRepoName,omitempty"`
+}
+
 // DataSource defines data sources for a new PostgresCluster.
 type DataSource struct {
 	// Defines a pgBackRest data source that can be used to pre-populate the PostgreSQL data
@@ -170,9 +303,22 @@ type DataSource struct {
 	// +optional
 	PostgresCluster *PostgresClusterDataSource `json:"postgresCluster,omitempty"`
 
+	// Defines a pgBackRest repository to restore from when initializing the
+	// PostgreSQL data directory of a new PostgresCluster. Unlike postgresCluster,
+	// the repository does not need to belong to a PostgresCluster in this
+	// Kubernetes cluster.
+	// +optional
+	PGBackRest *PGBackRestDataSource `json:"pgbackrest,omitempty"`
+
 	// Defines any existing volumes to reuse for this PostgresCluster.
 	// +optional
 	Volumes *DataSourceVolumes `json:"volumes,omitempty"`
+
+	// Defines an existing Patroni cluster in this namespace to adopt. It is
+	// stopped and the pgData volume of its leader becomes the data of this
+	// PostgresCluster.
+	// +optional
+	Patroni *PatroniDataSource `json:"patroni,omitempty"`
 }
 
 // DataSourceVolumes defines any existing volumes to reuse for this PostgresCluster.
@@ -205,6 +351,109 @@ type DataSourceVolume struct {
 	Directory string `json:"directory,omitempty"`
 }
 
+// PatroniDataSource identifies the StatefulSet of a Patroni cluster that was
+// deployed without the operator.
+type PatroniDataSource struct {
+	// A label selector for the StatefulSet of the Patroni cluster. That
+	// StatefulSet must also have the "postgres-operator.crunchydata.com/adopt"
+	// annotation set to the name of this PostgresCluster.
+	// +kubebuilder:validation:Required
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// The name of the volume claim template of the pgData volume in the
+	// StatefulSet. Defaults to the first template.
+	// +optional
+	VolumeClaimTemplate string `json:"volumeClaimTemplate,omitempty"`
+
+	// The directory of the PostgreSQL data in the pgData volume, e.g. "pgroot/data".
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Directory string `json:"directory"`
+}
+
+// PostgresAdoptionStatus describes the Patroni cluster being adopted.
+type PostgresAdoptionStatus struct {
+	// The name of the StatefulSet of the Patroni cluster.
+	// +kubebuilder:validation:Required
+	StatefulSet string `json:"statefulSet"`
+
+	// The name of the pgData volume of the Patroni leader.
+	// +kubebuilder:validation:Required
+	PGDataVolume string `json:"pgDataVolume"`
+}
+
+// PostgresExportStatus defines the observed state of the latest export.
+type PostgresExportStatus struct {
+	// The value of the "postgres-operator.crunchydata.com/export" annotation
+	// that requested this export.
+	// +kubebuilder:validation:Required
+	ID string `json:"id"`
+
+	// The name of the ConfigMap that holds the exported objects.
+	// +optional
+	ConfigMap string `json:"configMap,omitempty"`
+
+	// Whether or not the export is done.
+	// +kubebuilder:validation:Required
+	Finished bool `json:"finished"`
+
+	// Whether or not the export completed successfully.
+	// +optional
+	Succeeded bool `json:"succeeded,omitempty"`
+
+	// A human-readable description of the outcome of the export.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// When the export was requested. It is represented in RFC3339 form and is in UTC.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// When the export finished. It is represented in RFC3339 form and is in UTC.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// PostgresReplicaHealingSpec defines when a failed replica is replaced.
+type PostgresReplicaHealingSpec struct {
+	// Number of seconds a replica must be failed before it is replaced.
+	// +optional
+	// +kubebuilder:default=600
+	// +kubebuilder:validation:Minimum=60
+	FailureSeconds *int32 `json:"failureSeconds,omitempty"`
+}
+
+// PostgresReplicaHealingStatus describes the replacement of a failed replica.
+type PostgresReplicaHealingStatus struct {
+	// The failed instance that was removed.
+	Instance string `json:"instance"`
+
+	// The instance set that the failed instance belonged to.
+	InstanceSet string `json:"instanceSet"`
+
+	// When the failed instance was removed. It is represented in RFC3339 form
+	// and is in UTC.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+}
+
+// PostgresUpgradeAvailableStatus describes a newer minor release of the major
+// version of PostgreSQL that the cluster runs.
+type PostgresUpgradeAvailableStatus struct {
+	// The PostgreSQL version of the release, e.g. "16.2".
+	// +kubebuilder:validation:Required
+	Version string `json:"version"`
+
+	// The container image of the release.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// When the operator last compared the running images against its release
+	// index. It is represented in RFC3339 form and is in UTC.
+	// +optional
+	CheckTime *metav1.Time `json:"checkTime,omitempty"`
+}
+
 // DatabaseInitSQL defines a ConfigMap containing custom SQL that will
 // be run after the cluster is initialized. This ConfigMap must be in the same
 // namespace as the cluster.
@@ -253,6 +502,12 @@ type PostgresClusterDataSource struct {
 	// +optional
 	Affinity *corev1.Affinity `json:"affinity,omitempty"`
 
+	// A selector which must match a node's labels for the pgBackRest restore
+	// Job pod to be scheduled on that node.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node/#nodeselector
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
 	// Priority class name for the pgBackRest restore Job pod. Changing this
 	// value causes PostgreSQL to restart.
 	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/pod-priority-preemption/
@@ -286,6 +541,11 @@ func (s *PostgresClusterSpec) Default() {
 	}
 }
 
+// Architecture is the name of a CPU architecture as reported by Kubernetes
+// nodes in the "kubernetes.io/arch" label.
+// +kubebuilder:validation:Enum={amd64,arm64,ppc64le,s390x}
+type Architecture string
+
 // Backups defines a PostgreSQL archive configuration
 type Backups struct {
 
@@ -329,14 +589,73 @@ type PostgresClusterStatus struct {
 	// Identifies the users that have been installed into PostgreSQL.
 	UsersRevision string `json:"usersRevision,omitempty"`
 
+	// Identifies the foreign servers and user mappings that have been
+	// installed into PostgreSQL.
+	// +optional
+	ForeignServersRevision string `json:"foreignServersRevision,omitempty"`
+
+	// The Secret of the first user in spec.users. It contains the keys of the
+	// Service Binding Specification so that workloads can be bound to this
+	// cluster as a Provisioned Service.
+	// More info: https://github.com/servicebinding/spec#provisioned-service
+	// +optional
+	Binding *PostgresClusterBinding `json:"binding,omitempty"`
+
 	// Current state of PostgreSQL cluster monitoring tool configuration
 	// +optional
 	Monitoring MonitoringStatus `json:"monitoring,omitempty"`
 
+	// Current state of scheduled SQL maintenance.
+	// +optional
+	Maintenance *PostgresMaintenanceStatus `json:"maintenance,omitempty"`
+
+	// Current state of the latest repair.
+	// +optional
+	Repair *PostgresRepairStatus `json:"repair,omitempty"`
+
+	// Present while this cluster replaces another through spec.blueGreen.
+	// +optional
+	BlueGreen *PostgresBlueGreenStatus `json:"blueGreen,omitempty"`
+
+	// The Patroni cluster adopted through spec.dataSource.patroni.
+	// +optional
+	Adoption *PostgresAdoptionStatus `json:"adoption,omitempty"`
+
+	// Present while the cluster is a standby or waiting to be promoted.
+	// +optional
+	Standby *PostgresStandbyStatus `json:"standby,omitempty"`
+
+	// Present while a failed replica is being replaced through spec.replicaHealing.
+	// +optional
+	ReplicaHealing *PostgresReplicaHealingStatus `json:"replicaHealing,omitempty"`
+
+	// The latest export requested through the "postgres-operator.crunchydata.com/export"
+	// annotation.
+	// +optional
+	Export *PostgresExportStatus `json:"export,omitempty"`
+
+	// Present when the operator knows of a newer minor release of PostgreSQL
+	// than the one the cluster runs. See the "PGO_UPDATE_INDEX_CONFIGMAP" and
+	// "PGO_UPDATE_INDEX_URL" operator settings.
+	// +optional
+	UpgradeAvailable *PostgresUpgradeAvailableStatus `json:"upgradeAvailable,omitempty"`
+
+	// The hash of the newest audit record the operator wrote for this cluster.
+	// Audit records that no longer include it have been removed. See the
+	// "PGO_AUDIT_LOG" operator setting.
+	// +optional
+	AuditHead string `json:"auditHead,omitempty"`
+
 	// DatabaseInitSQL state of custom database initialization in the cluster
 	// +optional
 	DatabaseInitSQL *string `json:"databaseInitSQL,omitempty"`
 
+	// Databases in which initialization SQL has been executed.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Databases []PostgresDatabaseStatus `json:"databases,omitempty"`
+
 	// observedGeneration represents the .metadata.generation on which the status was based.
 	// +optional
 	// +kubebuilder:validation:Minimum=0
@@ -352,10 +671,22 @@ type PostgresClusterStatus struct {
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// PostgresClusterBinding is a reference to a Secret that can be bound to a
+// workload according to the Service Binding Specification.
+type PostgresClusterBinding struct {
+	// The name of the Secret.
+	Name string `json:"name"`
+}
+
 // PostgresClusterStatus condition types.
 const (
 	PersistentVolumeResizing = "PersistentVolumeResizing"
 	ProxyAvailable           = "ProxyAvailable"
+
+	// Initialized becomes true once, after PostgreSQL first bootstraps and
+	// takes the backup from which replicas are created. It does not change
+	// after that.
+	Initialized = "Initialized"
 )
 
 type PostgresInstanceSetSpec struct {
@@ -366,16 +697,76 @@ type PostgresInstanceSetSpec struct {
 	// +kubebuilder:default=""
 	Name string `json:"name"`
 
+	// Names this instance set had before it was renamed. Instances of these
+	// sets move to this one, keeping their data volumes and Patroni member
+	// names. Each StatefulSet is recreated so PostgreSQL restarts, one instance
+	// at a time. Names of other instance sets in this cluster are ignored.
+	// +listType=set
+	// +optional
+	PreviousNames []string `json:"previousNames,omitempty"`
+
 	// Scheduling constraints of a PostgreSQL pod. Changing this value causes
 	// PostgreSQL to restart.
 	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node
 	// +optional
 	Affinity *corev1.Affinity `json:"affinity,omitempty"`
 
-	// Defines a PersistentVolumeClaim for PostgreSQL data.
+	// Defines a PersistentVolumeClaim for PostgreSQL data. Required unless
+	// ephemeral is set.
 	// More info: https://kubernetes.io/docs/concepts/storage/persistent-volumes
-	// +kubebuilder:validation:Required
-	DataVolumeClaimSpec corev1.PersistentVolumeClaimSpec `json:"dataVolumeClaimSpec"`
+	// +optional
+	DataVolumeClaimSpec corev1.PersistentVolumeClaimSpec `json:"dataVolumeClaimSpec,omitempty"`
+
+	// Environment variables of the PostgreSQL container in addition to those
+	// set by the operator. Variables set by the operator cannot be changed.
+	// Changing this value causes PostgreSQL to restart.
+	// More info: https://kubernetes.io/docs/tasks/inject-data-application/define-environment-variable-container/
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// ConfigMaps and Secrets that are sources of environment variables of the
+	// PostgreSQL container. Variables in env and those set by the operator take
+	// precedence. Changing this value causes PostgreSQL to restart.
+	// More info: https://kubernetes.io/docs/tasks/configure-pod-container/configure-pod-configmap/#configure-all-key-value-pairs-in-a-configmap-as-container-environment-variables
+	// +optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+
+	// Whether or not to start the PostgreSQL containers of this set without
+	// Patroni or PostgreSQL. The containers wait so that you can exec into them
+	// and repair the data directory. PostgreSQL is unavailable on these Pods
+	// until this is unset. Changing this value causes PostgreSQL to restart.
+	// +optional
+	StartupDebug *bool `json:"startupDebug,omitempty"`
+
+	// Stores PostgreSQL data in a volume that is deleted along with its Pod
+	// rather than in a PersistentVolumeClaim. Data is lost whenever the Pod is
+	// recreated, so this is meant for short-lived clusters such as those in
+	// automated tests. When set, dataVolumeClaimSpec is ignored.
+	// More info: https://kubernetes.io/docs/concepts/storage/ephemeral-volumes/
+	// +optional
+	Ephemeral *PostgresEphemeralVolumeSpec `json:"ephemeral,omitempty"`
+
+	// PostgreSQL parameters of every member of this instance set, such as a
+	// larger work_mem on analytics replicas. These take precedence over those
+	// in spec.patroni.dynamicConfiguration. Parameters that Patroni keeps the
+	// same on every member, such as max_connections, and those set by the
+	// operator are ignored. Changing this value takes effect when Patroni
+	// reloads its configuration; some parameters also require PostgreSQL to
+	// restart.
+	// More info: https://patroni.readthedocs.io/en/latest/dynamic_configuration.html
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// Whether the PersistentVolumeClaims of instances in this set are deleted
+	// or kept when instances are removed. Kept volumes are reused when the set
+	// scales up again. Volumes are always deleted when the set is removed from
+	// the spec, when a failed replica is replaced, and when an instance moves
+	// to another storage class.
+	// More info: https://kubernetes.io/docs/concepts/workloads/controllers/statefulset/#persistentvolumeclaim-retention
+	// +optional
+	PersistentVolumeClaimRetentionPolicy *PostgresVolumeRetentionPolicy `json:"persistentVolumeClaimRetentionPolicy,omitempty"`
 
 	// Priority class name for the PostgreSQL pod. Changing this value causes
 	// PostgreSQL to restart.
@@ -383,6 +774,15 @@ type PostgresInstanceSetSpec struct {
 	// +optional
 	PriorityClassName *string `json:"priorityClassName,omitempty"`
 
+	// Number of seconds after the node of a PostgreSQL pod stops being ready
+	// that a pod stuck terminating on that node is forcefully deleted. The
+	// pod of a primary is deleted only after its Patroni leader lock expires
+	// so that a replica can be promoted. When unset, such pods are left for
+	// the kubelet or an administrator to remove.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	RecoverAfterNodeFailureSeconds *int32 `json:"recoverAfterNodeFailureSeconds,omitempty"`
+
 	// +optional
 	// +kubebuilder:default=1
 	// +kubebuilder:validation:Minimum=1
@@ -396,6 +796,12 @@ type PostgresInstanceSetSpec struct {
 	// +optional
 	Sidecars *InstanceSidecars `json:"sidecars,omitempty"`
 
+	// Patroni tags of every member of this instance set, such as those that
+	// keep reporting replicas from ever becoming primary. Changing this value
+	// takes effect when Patroni reloads its configuration.
+	// +optional
+	Tags *PatroniTags `json:"tags,omitempty"`
+
 	// Tolerations of a PostgreSQL pod. Changing this value causes PostgreSQL to restart.
 	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration
 	// +optional
@@ -411,6 +817,46 @@ type PostgresInstanceSetSpec struct {
 	// More info: https://www.postgresql.org/docs/current/wal.html
 	// +optional
 	WALVolumeClaimSpec *corev1.PersistentVolumeClaimSpec `json:"walVolumeClaimSpec,omitempty"`
+
+	// Whether or not to require that PostgreSQL pods of this cluster be spread
+	// evenly across zones. Replicas also prefer zones without the primary.
+	// Pods remain Pending when there are not enough zones. Changing this value
+	// causes PostgreSQL to restart.
+	// More info: https://kubernetes.io/docs/reference/labels-annotations-taints/#topologykubernetesiozone
+	// +optional
+	ZoneSpread *bool `json:"zoneSpread,omitempty"`
+}
+
+// PostgresEphemeralVolumeSpec defines a PostgreSQL data volume that lives only
+// as long as its Pod.
+type PostgresEphemeralVolumeSpec struct {
+	// The total amount of local storage for PostgreSQL data when the volume is
+	// an emptyDir. The kubelet evicts the Pod when this is exceeded.
+	// More info: https://kubernetes.io/docs/concepts/storage/volumes/#emptydir
+	// +optional
+	SizeLimit *resource.Quantity `json:"sizeLimit,omitempty"`
+
+	// Defines a PersistentVolumeClaim that is created with the Pod and deleted
+	// with it. When omitted, the volume is an emptyDir on the node.
+	// More info: https://kubernetes.io/docs/concepts/storage/ephemeral-volumes/#generic-ephemeral-volumes
+	// +optional
+	VolumeClaimSpec *corev1.PersistentVolumeClaimSpec `json:"volumeClaimSpec,omitempty"`
+}
+
+// PostgresVolumeRetentionPolicy defines what happens to the PostgreSQL data
+// and WAL volumes of instances when they are removed.
+type PostgresVolumeRetentionPolicy struct {
+	// What happens to volumes when the PostgresCluster is deleted. Retained
+	// volumes have no owner reference and are reused by a PostgresCluster of
+	// the same name. Defaults to "Delete".
+	// +optional
+	// +kubebuilder:validation:Enum={Delete,Retain}
+	WhenDeleted string `json:"whenDeleted,omitempty"`
+
+	// What happens to volumes when replicas decreases. Defaults to "Delete".
+	// +optional
+	// +kubebuilder:validation:Enum={Delete,Retain}
+	WhenScaled string `json:"whenScaled,omitempty"`
 }
 
 // InstanceSidecars defines the configuration for instance sidecar containers
@@ -446,6 +892,156 @@ type PostgresInstanceSetStatus struct {
 	// Total number of non-terminated pods that have the desired specification.
 	// +optional
 	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// Number of scheduled pods in each zone. This is reported only when
+	// zoneSpread is enabled and the operator has permission to read Nodes.
+	// +optional
+	Zones map[string]int32 `json:"zones,omitempty"`
+
+	// The name of the headless Service that resolves to the ready members of
+	// this set.
+	// +optional
+	ServiceName string `json:"serviceName,omitempty"`
+
+	// Current members of this set, sorted by name.
+	// +optional
+	Members []PostgresInstanceMemberStatus `json:"members,omitempty"`
+
+	// Usage of the PostgreSQL volumes of each member, sorted by Pod name and
+	// volume. This is reported only when volumeUsage is enabled.
+	// +optional
+	Volumes []PostgresVolumeUsageStatus `json:"volumes,omitempty"`
+
+	// Memory and CPU usage of each member, sorted by Pod name. This is
+	// reported only when resourceUsage is enabled.
+	// +optional
+	Resources []PostgresResourceUsageStatus `json:"resources,omitempty"`
+}
+
+// PostgresVolumeUsageSpec defines how often PostgreSQL volumes are measured and
+// when they are considered nearly full.
+type PostgresVolumeUsageSpec struct {
+	// Number of seconds between measurements of each volume.
+	// +optional
+	// +kubebuilder:default=300
+	// +kubebuilder:validation:Minimum=10
+	IntervalSeconds *int32 `json:"intervalSeconds,omitempty"`
+
+	// Percent of a volume's capacity at or above which a Warning event is
+	// emitted on the PostgresCluster.
+	// +optional
+	// +kubebuilder:default=80
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	WarningPercent *int32 `json:"warningPercent,omitempty"`
+
+	// Percent of the primary's WAL volume at or above which WAL archiving is
+	// suspended while it is failing. Completed WAL files are then discarded
+	// rather than kept until the volume fills and PostgreSQL stops. Archiving
+	// resumes once usage falls below warningPercent. This is disabled when
+	// not set.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	ArchiveSacrificePercent *int32 `json:"archiveSacrificePercent,omitempty"`
+}
+
+// PostgresVolumeUsageStatus is a measurement of one PostgreSQL volume.
+type PostgresVolumeUsageStatus struct {
+	// The name of the Pod that mounts this volume.
+	Pod string `json:"pod"`
+
+	// The kind of volume: "pgdata" or "pgwal".
+	Volume string `json:"volume"`
+
+	// Total size of the filesystem on this volume.
+	Capacity resource.Quantity `json:"capacity"`
+
+	// Amount of the filesystem in use.
+	Used resource.Quantity `json:"used"`
+
+	// Percent of capacity in use, rounded up.
+	UsedPercent int32 `json:"usedPercent"`
+
+	// When this volume was measured.
+	LastObservedTime metav1.Time `json:"lastObservedTime"`
+}
+
+// PostgresResourceUsageSpec defines how often PostgreSQL instances are measured
+// and when they are considered overloaded.
+type PostgresResourceUsageSpec struct {
+	// Number of seconds between measurements of each instance.
+	// +optional
+	// +kubebuilder:default=60
+	// +kubebuilder:validation:Minimum=15
+	IntervalSeconds *int32 `json:"intervalSeconds,omitempty"`
+
+	// Percent of the database container's memory limit at or above which an
+	// instance is considered overloaded.
+	// +optional
+	// +kubebuilder:default=90
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	MemoryPercent *int32 `json:"memoryPercent,omitempty"`
+
+	// Number of seconds memory must stay at or above memoryPercent before the
+	// instance is considered overloaded.
+	// +optional
+	// +kubebuilder:default=300
+	// +kubebuilder:validation:Minimum=0
+	SustainedSeconds *int32 `json:"sustainedSeconds,omitempty"`
+}
+
+// PostgresResourceUsageStatus is a measurement of one PostgreSQL instance.
+type PostgresResourceUsageStatus struct {
+	// The name of the Pod of this instance.
+	Pod string `json:"pod"`
+
+	// Memory in use by the database container.
+	Memory resource.Quantity `json:"memory"`
+
+	// Memory limit of the database container. Instances without one are
+	// never overloaded.
+	// +optional
+	MemoryLimit *resource.Quantity `json:"memoryLimit,omitempty"`
+
+	// Percent of the memory limit in use, rounded up.
+	// +optional
+	MemoryPercent int32 `json:"memoryPercent,omitempty"`
+
+	// CPU in use by the database container.
+	CPU resource.Quantity `json:"cpu"`
+
+	// When memory reached memoryPercent without falling below it since.
+	// +optional
+	HighMemorySince *metav1.Time `json:"highMemorySince,omitempty"`
+
+	// What to change when this instance is overloaded.
+	// +optional
+	Recommendation string `json:"recommendation,omitempty"`
+
+	// When this instance was measured.
+	LastObservedTime metav1.Time `json:"lastObservedTime"`
+}
+
+// PostgresInstanceMemberStatus describes a single PostgreSQL instance so that
+// clients can route connections to it directly.
+type PostgresInstanceMemberStatus struct {
+	// The name of the Pod of this member.
+	Name string `json:"name"`
+
+	// The fully qualified domain name of this member. It resolves even when
+	// the member is not ready.
+	// +optional
+	FQDN string `json:"fqdn,omitempty"`
+
+	// The role of this member in the cluster: "primary" or "replica". This
+	// is empty while it is unknown.
+	// +optional
+	Role string `json:"role,omitempty"`
+
+	// Whether or not this member is ready to accept connections.
+	Ready bool `json:"ready"`
 }
 
 // PostgresProxySpec is a union of the supported PostgreSQL proxies.
@@ -478,9 +1074,47 @@ type PostgresStandbySpec struct {
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:Pattern=^repo[1-4]
 	RepoName string `json:"repoName"`
+
+	// How long to keep applying WAL from the repository after enabled changes
+	// to false. PostgreSQL is promoted once it has replayed no new WAL for this
+	// many seconds, i.e. once the former primary stops archiving. Defaults to
+	// zero, which promotes immediately.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	PromotionGracePeriodSeconds *int32 `json:"promotionGracePeriodSeconds,omitempty"`
+}
+
+// StandbyEnabled returns whether or not the cluster is a standby. It is while
+// spec.standby.enabled is true and, after that changes to false, while its
+// promotion waits for spec.standby.promotionGracePeriodSeconds. The wait ends
+// when status.standby is removed.
+func (c *PostgresCluster) StandbyEnabled() bool {
+	standby := c.Spec.Standby
+	if standby == nil {
+		return false
+	}
+	return standby.Enabled || (c.Status.Standby != nil &&
+		standby.PromotionGracePeriodSeconds != nil && *standby.PromotionGracePeriodSeconds > 0)
+}
+
+// PostgresStandbyStatus describes a standby cluster and its promotion.
+type PostgresStandbyStatus struct {
+	// When spec.standby.enabled changed to false.
+	// +optional
+	PromotionRequestTime *metav1.Time `json:"promotionRequestTime,omitempty"`
+
+	// The latest WAL location replayed by the standby leader while its promotion
+	// waits.
+	// +optional
+	ReplayLSN string `json:"replayLSN,omitempty"`
+
+	// When replayLSN last changed.
+	// +optional
+	ReplayTime *metav1.Time `json:"replayTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:storageversion
 // +kubebuilder:subresource:status
 // +operator-sdk:csv:customresourcedefinitions:resources={{ConfigMap,v1},{Secret,v1},{Service,v1},{CronJob,v1beta1},{Deployment,v1},{Job,v1},{StatefulSet,v1},{PersistentVolumeClaim,v1}}
 
@@ -557,6 +1191,39 @@ func (meta *Metadata) GetAnnotationsOrNil() map[string]string {
 type MonitoringSpec struct {
 	// +optional
 	PGMonitor *PGMonitorSpec `json:"pgmonitor,omitempty"`
+
+	// Statistics that PostgreSQL collects about queries.
+	// +optional
+	QueryStatistics *QueryStatisticsSpec `json:"queryStatistics,omitempty"`
+}
+
+// QueryStatisticsSpec defines how PostgreSQL measures queries and what the
+// PostgreSQL Exporter reports about them.
+type QueryStatisticsSpec struct {
+	// Whether or not to load pg_stat_statements and create it in every
+	// database. Defaults to true. Changing this value causes PostgreSQL to
+	// restart.
+	// More info: https://www.postgresql.org/docs/current/pgstatstatements.html
+	// +optional
+	PGStatStatements *bool `json:"pgStatStatements,omitempty"`
+
+	// The number of statements the PostgreSQL Exporter reports in each ranking,
+	// such as longest total or mean execution time. Defaults to 20.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TopStatements *int32 `json:"topStatements,omitempty"`
+
+	// Whether or not PostgreSQL times I/O calls.
+	// More info: https://www.postgresql.org/docs/current/runtime-config-statistics.html#GUC-TRACK-IO-TIMING
+	// +optional
+	TrackIOTiming *bool `json:"trackIOTiming,omitempty"`
+
+	// Log every statement that runs for at least this long, such as "250ms"
+	// or "5s". A value of "-1" disables this logging, which is the default.
+	// More info: https://www.postgresql.org/docs/current/runtime-config-logging.html#GUC-LOG-MIN-DURATION-STATEMENT
+	// +kubebuilder:validation:Pattern=`^(-1|[0-9]+(us|ms|s|min|h|d)?)$`
+	// +optional
+	LogMinDurationStatement string `json:"logMinDurationStatement,omitempty"`
 }
 
 // MonitoringStatus is the current state of PostgreSQL cluster monitoring tool
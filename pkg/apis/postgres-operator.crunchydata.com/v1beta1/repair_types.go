@@ -0,0 +1,99 @@
+/*
+ Copyright 2021 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PostgresRepairSpec defines a one-time repair of the data directory of one
+// PostgreSQL instance.
+type PostgresRepairSpec struct {
+	// Whether or not repairs are enabled for this PostgresCluster. A repair
+	// runs once for each value of the "postgres-operator.crunchydata.com/repair"
+	// annotation while this is true.
+	// +kubebuilder:default=false
+	Enabled *bool `json:"enabled"`
+
+	// The name of the instance to repair, e.g. "hippo-instance1-abcd".
+	// +kubebuilder:validation:MinLength=1
+	Instance string `json:"instance"`
+
+	// How to repair the instance. With "singleUser", PostgreSQL runs sql in
+	// single-user mode. With "resetWAL", pg_resetwal runs with options. Every
+	// instance stops during these. With "rewind", pg_rewind runs with options
+	// and the primary as its source. Only the repaired instance stops during
+	// this, and it cannot be the primary.
+	// More info: https://www.postgresql.org/docs/current/app-postgres.html#APP-POSTGRES-SINGLE-USER
+	// +kubebuilder:validation:Enum={singleUser,resetWAL,rewind}
+	Method string `json:"method"`
+
+	// SQL to run in single-user mode. Statements end with a semicolon followed
+	// by a blank line. Required when method is "singleUser".
+	// +optional
+	SQL string `json:"sql,omitempty"`
+
+	// The database in which to run sql in single-user mode. Defaults to "postgres".
+	// +optional
+	Database PostgresIdentifier `json:"database,omitempty"`
+
+	// Command line options for pg_resetwal or pg_rewind.
+	// +optional
+	Options []string `json:"options,omitempty"`
+
+	// Resource requirements for the repair container.
+	// More info: https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// PostgresRepairStatus defines the observed state of the latest repair.
+type PostgresRepairStatus struct {
+	// The value of the "postgres-operator.crunchydata.com/repair" annotation
+	// that requested this repair.
+	// +kubebuilder:validation:Required
+	ID string `json:"id"`
+
+	// The instance being repaired.
+	// +optional
+	Instance string `json:"instance,omitempty"`
+
+	// The method of this repair.
+	// +optional
+	Method string `json:"method,omitempty"`
+
+	// Whether or not the repair is done. Instances stopped for the repair
+	// start again once it is.
+	// +kubebuilder:validation:Required
+	Finished bool `json:"finished"`
+
+	// Whether or not the repair completed successfully.
+	// +optional
+	Succeeded bool `json:"succeeded,omitempty"`
+
+	// A human-readable description of the outcome of the repair.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// When the repair was requested. It is represented in RFC3339 form and is in UTC.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// When the repair finished. It is represented in RFC3339 form and is in UTC.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
@@ -25,6 +25,20 @@ type ServiceSpec struct {
 	Type string `json:"type"`
 }
 
+// PrimaryServiceSpec defines how the primary Service finds the PostgreSQL
+// primary instance.
+type PrimaryServiceSpec struct {
+	// How the primary Service tracks the PostgreSQL primary. With "endpointSlice",
+	// the Service resolves to the endpoints that Patroni maintains for its leader.
+	// With "patroniLabel", the Service selects the Pod that is labeled as the
+	// leader, and the operator repairs stale role labels after a failover.
+	// Defaults to "endpointSlice".
+	// +optional
+	// +kubebuilder:default=endpointSlice
+	// +kubebuilder:validation:Enum={patroniLabel,endpointSlice}
+	SelectorMode string `json:"selectorMode,omitempty"`
+}
+
 // Sidecar defines the configuration of a sidecar container
 type Sidecar struct {
 	// Resource requirements for a sidecar container
@@ -30,11 +30,40 @@ import (
 func (in *BackupJobs) DeepCopyInto(out *BackupJobs) {
 	*out = *in
 	in.Resources.DeepCopyInto(&out.Resources)
+	if in.IOTimeoutSeconds != nil {
+		in, out := &in.IOTimeoutSeconds, &out.IOTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ProcessMax != nil {
+		in, out := &in.ProcessMax, &out.ProcessMax
+		*out = new(int32)
+		**out = **in
+	}
 	if in.PriorityClassName != nil {
 		in, out := &in.PriorityClassName, &out.PriorityClassName
 		*out = new(string)
 		**out = **in
 	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupJobs.
@@ -63,6 +92,21 @@ func (in *Backups) DeepCopy() *Backups {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertManagerIssuerReference) DeepCopyInto(out *CertManagerIssuerReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerIssuerReference.
+func (in *CertManagerIssuerReference) DeepCopy() *CertManagerIssuerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(CertManagerIssuerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataSource) DeepCopyInto(out *DataSource) {
 	*out = *in
@@ -71,11 +115,21 @@ func (in *DataSource) DeepCopyInto(out *DataSource) {
 		*out = new(PostgresClusterDataSource)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.PGBackRest != nil {
+		in, out := &in.PGBackRest, &out.PGBackRest
+		*out = new(PGBackRestDataSource)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Volumes != nil {
 		in, out := &in.Volumes, &out.Volumes
 		*out = new(DataSourceVolumes)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Patroni != nil {
+		in, out := &in.Patroni, &out.Patroni
+		*out = new(PatroniDataSource)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSource.
@@ -228,6 +282,11 @@ func (in *MonitoringSpec) DeepCopyInto(out *MonitoringSpec) {
 		*out = new(PGMonitorSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.QueryStatistics != nil {
+		in, out := &in.QueryStatistics, &out.QueryStatistics
+		*out = new(QueryStatisticsSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringSpec.
@@ -282,6 +341,11 @@ func (in *PGBackRestArchive) DeepCopyInto(out *PGBackRestArchive) {
 		*out = new(BackupJobs)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Archive != nil {
+		in, out := &in.Archive, &out.Archive
+		*out = new(PGBackRestArchivePush)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Repos != nil {
 		in, out := &in.Repos, &out.Repos
 		*out = make([]PGBackRestRepo, len(*in))
@@ -304,6 +368,11 @@ func (in *PGBackRestArchive) DeepCopyInto(out *PGBackRestArchive) {
 		*out = new(PGBackRestRestore)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.VerifyBeforeExpire != nil {
+		in, out := &in.VerifyBeforeExpire, &out.VerifyBeforeExpire
+		*out = new(bool)
+		**out = **in
+	}
 	if in.Sidecars != nil {
 		in, out := &in.Sidecars, &out.Sidecars
 		*out = new(PGBackRestSidecars)
@@ -321,6 +390,36 @@ func (in *PGBackRestArchive) DeepCopy() *PGBackRestArchive {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBackRestArchivePush) DeepCopyInto(out *PGBackRestArchivePush) {
+	*out = *in
+	if in.CompressLevel != nil {
+		in, out := &in.CompressLevel, &out.CompressLevel
+		*out = new(int32)
+		**out = **in
+	}
+	if in.QueueMax != nil {
+		in, out := &in.QueueMax, &out.QueueMax
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Fenced != nil {
+		in, out := &in.Fenced, &out.Fenced
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBackRestArchivePush.
+func (in *PGBackRestArchivePush) DeepCopy() *PGBackRestArchivePush {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBackRestArchivePush)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PGBackRestBackupSchedules) DeepCopyInto(out *PGBackRestBackupSchedules) {
 	*out = *in
@@ -351,6 +450,66 @@ func (in *PGBackRestBackupSchedules) DeepCopy() *PGBackRestBackupSchedules {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBackRestDataSource) DeepCopyInto(out *PGBackRestDataSource) {
+	*out = *in
+	if in.Configuration != nil {
+		in, out := &in.Configuration, &out.Configuration
+		*out = make([]v1.VolumeProjection, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Global != nil {
+		in, out := &in.Global, &out.Global
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Repo.DeepCopyInto(&out.Repo)
+	if in.Options != nil {
+		in, out := &in.Options, &out.Options
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PriorityClassName != nil {
+		in, out := &in.PriorityClassName, &out.PriorityClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBackRestDataSource.
+func (in *PGBackRestDataSource) DeepCopy() *PGBackRestDataSource {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBackRestDataSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PGBackRestJobStatus) DeepCopyInto(out *PGBackRestJobStatus) {
 	*out = *in
@@ -374,6 +533,26 @@ func (in *PGBackRestJobStatus) DeepCopy() *PGBackRestJobStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBackRestLoggingSpec) DeepCopyInto(out *PGBackRestLoggingSpec) {
+	*out = *in
+	if in.RetentionDays != nil {
+		in, out := &in.RetentionDays, &out.RetentionDays
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBackRestLoggingSpec.
+func (in *PGBackRestLoggingSpec) DeepCopy() *PGBackRestLoggingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBackRestLoggingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PGBackRestManualBackup) DeepCopyInto(out *PGBackRestManualBackup) {
 	*out = *in
@@ -472,6 +651,11 @@ func (in *PGBackRestRepoHost) DeepCopyInto(out *PGBackRestRepoHost) {
 		*out = new(v1.SecretProjection)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Standby != nil {
+		in, out := &in.Standby, &out.Standby
+		*out = new(PGBackRestStandbyRepoHost)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBackRestRepoHost.
@@ -509,6 +693,34 @@ func (in *PGBackRestRestore) DeepCopy() *PGBackRestRestore {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBackRestRestoreRecord) DeepCopyInto(out *PGBackRestRestoreRecord) {
+	*out = *in
+	if in.Options != nil {
+		in, out := &in.Options, &out.Options
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.FinishTime != nil {
+		in, out := &in.FinishTime, &out.FinishTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBackRestRestoreRecord.
+func (in *PGBackRestRestoreRecord) DeepCopy() *PGBackRestRestoreRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBackRestRestoreRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PGBackRestScheduledBackupStatus) DeepCopyInto(out *PGBackRestScheduledBackupStatus) {
 	*out = *in
@@ -532,12 +744,35 @@ func (in *PGBackRestScheduledBackupStatus) DeepCopy() *PGBackRestScheduledBackup
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBackRestSidecar) DeepCopyInto(out *PGBackRestSidecar) {
+	*out = *in
+	in.Sidecar.DeepCopyInto(&out.Sidecar)
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBackRestSidecar.
+func (in *PGBackRestSidecar) DeepCopy() *PGBackRestSidecar {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBackRestSidecar)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PGBackRestSidecars) DeepCopyInto(out *PGBackRestSidecars) {
 	*out = *in
 	if in.PGBackRest != nil {
 		in, out := &in.PGBackRest, &out.PGBackRest
-		*out = new(Sidecar)
+		*out = new(PGBackRestSidecar)
 		(*in).DeepCopyInto(*out)
 	}
 }
@@ -552,6 +787,26 @@ func (in *PGBackRestSidecars) DeepCopy() *PGBackRestSidecars {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBackRestStandbyRepoHost) DeepCopyInto(out *PGBackRestStandbyRepoHost) {
+	*out = *in
+	if in.IntervalSeconds != nil {
+		in, out := &in.IntervalSeconds, &out.IntervalSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBackRestStandbyRepoHost.
+func (in *PGBackRestStandbyRepoHost) DeepCopy() *PGBackRestStandbyRepoHost {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBackRestStandbyRepoHost)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PGBackRestStatus) DeepCopyInto(out *PGBackRestStatus) {
 	*out = *in
@@ -575,13 +830,26 @@ func (in *PGBackRestStatus) DeepCopyInto(out *PGBackRestStatus) {
 	if in.Repos != nil {
 		in, out := &in.Repos, &out.Repos
 		*out = make([]RepoStatus, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Restore != nil {
 		in, out := &in.Restore, &out.Restore
 		*out = new(PGBackRestJobStatus)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RestoreHistory != nil {
+		in, out := &in.RestoreHistory, &out.RestoreHistory
+		*out = make([]PGBackRestRestoreRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StanzaCheckTime != nil {
+		in, out := &in.StanzaCheckTime, &out.StanzaCheckTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBackRestStatus.
@@ -637,6 +905,36 @@ func (in *PGBouncerConfiguration) DeepCopy() *PGBouncerConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBouncerLoggingSpec) DeepCopyInto(out *PGBouncerLoggingSpec) {
+	*out = *in
+	if in.LogConnections != nil {
+		in, out := &in.LogConnections, &out.LogConnections
+		*out = new(bool)
+		**out = **in
+	}
+	if in.LogDisconnections != nil {
+		in, out := &in.LogDisconnections, &out.LogDisconnections
+		*out = new(bool)
+		**out = **in
+	}
+	if in.StatsPeriod != nil {
+		in, out := &in.StatsPeriod, &out.StatsPeriod
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerLoggingSpec.
+func (in *PGBouncerLoggingSpec) DeepCopy() *PGBouncerLoggingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBouncerLoggingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PGBouncerPodSpec) DeepCopyInto(out *PGBouncerPodSpec) {
 	*out = *in
@@ -661,6 +959,13 @@ func (in *PGBouncerPodSpec) DeepCopyInto(out *PGBouncerPodSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.Pools != nil {
+		in, out := &in.Pools, &out.Pools
+		*out = make([]PGBouncerPoolSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.PriorityClassName != nil {
 		in, out := &in.PriorityClassName, &out.PriorityClassName
 		*out = new(string)
@@ -723,6 +1028,26 @@ func (in *PGBouncerPodStatus) DeepCopy() *PGBouncerPodStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBouncerPoolSpec) DeepCopyInto(out *PGBouncerPoolSpec) {
+	*out = *in
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(ServiceSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerPoolSpec.
+func (in *PGBouncerPoolSpec) DeepCopy() *PGBouncerPoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBouncerPoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PGBouncerSidecars) DeepCopyInto(out *PGBouncerSidecars) {
 	*out = *in
@@ -744,465 +1069,1804 @@ func (in *PGBouncerSidecars) DeepCopy() *PGBouncerSidecars {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PGMonitorSpec) DeepCopyInto(out *PGMonitorSpec) {
+func (in *PGCronJob) DeepCopyInto(out *PGCronJob) {
 	*out = *in
-	if in.Exporter != nil {
-		in, out := &in.Exporter, &out.Exporter
-		*out = new(ExporterSpec)
-		(*in).DeepCopyInto(*out)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGMonitorSpec.
-func (in *PGMonitorSpec) DeepCopy() *PGMonitorSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGCronJob.
+func (in *PGCronJob) DeepCopy() *PGCronJob {
 	if in == nil {
 		return nil
 	}
-	out := new(PGMonitorSpec)
+	out := new(PGCronJob)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PatroniSpec) DeepCopyInto(out *PatroniSpec) {
+func (in *PGCronSpec) DeepCopyInto(out *PGCronSpec) {
 	*out = *in
-	in.DynamicConfiguration.DeepCopyInto(&out.DynamicConfiguration)
-	if in.LeaderLeaseDurationSeconds != nil {
-		in, out := &in.LeaderLeaseDurationSeconds, &out.LeaderLeaseDurationSeconds
-		*out = new(int32)
-		**out = **in
-	}
+	if in.Jobs != nil {
+		in, out := &in.Jobs, &out.Jobs
+		*out = make([]PGCronJob, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGCronSpec.
+func (in *PGCronSpec) DeepCopy() *PGCronSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PGCronSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGMonitorSpec) DeepCopyInto(out *PGMonitorSpec) {
+	*out = *in
+	if in.Exporter != nil {
+		in, out := &in.Exporter, &out.Exporter
+		*out = new(ExporterSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGMonitorSpec.
+func (in *PGMonitorSpec) DeepCopy() *PGMonitorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PGMonitorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGPartmanDatabase) DeepCopyInto(out *PGPartmanDatabase) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGPartmanDatabase.
+func (in *PGPartmanDatabase) DeepCopy() *PGPartmanDatabase {
+	if in == nil {
+		return nil
+	}
+	out := new(PGPartmanDatabase)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGPartmanSpec) DeepCopyInto(out *PGPartmanSpec) {
+	*out = *in
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]PGPartmanDatabase, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGPartmanSpec.
+func (in *PGPartmanSpec) DeepCopy() *PGPartmanSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PGPartmanSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGVectorSpec) DeepCopyInto(out *PGVectorSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGVectorSpec.
+func (in *PGVectorSpec) DeepCopy() *PGVectorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PGVectorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatroniDataSource) DeepCopyInto(out *PatroniDataSource) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatroniDataSource.
+func (in *PatroniDataSource) DeepCopy() *PatroniDataSource {
+	if in == nil {
+		return nil
+	}
+	out := new(PatroniDataSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatroniKubernetes) DeepCopyInto(out *PatroniKubernetes) {
+	*out = *in
+	if in.UseConfigMaps != nil {
+		in, out := &in.UseConfigMaps, &out.UseConfigMaps
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatroniKubernetes.
+func (in *PatroniKubernetes) DeepCopy() *PatroniKubernetes {
+	if in == nil {
+		return nil
+	}
+	out := new(PatroniKubernetes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatroniRewind) DeepCopyInto(out *PatroniRewind) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RemoveDataDirectoryOnFailure != nil {
+		in, out := &in.RemoveDataDirectoryOnFailure, &out.RemoveDataDirectoryOnFailure
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RemoveDataDirectoryOnDivergedTimelines != nil {
+		in, out := &in.RemoveDataDirectoryOnDivergedTimelines, &out.RemoveDataDirectoryOnDivergedTimelines
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatroniRewind.
+func (in *PatroniRewind) DeepCopy() *PatroniRewind {
+	if in == nil {
+		return nil
+	}
+	out := new(PatroniRewind)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatroniSpec) DeepCopyInto(out *PatroniSpec) {
+	*out = *in
+	in.DynamicConfiguration.DeepCopyInto(&out.DynamicConfiguration)
+	if in.LeaderLeaseDurationSeconds != nil {
+		in, out := &in.LeaderLeaseDurationSeconds, &out.LeaderLeaseDurationSeconds
+		*out = new(int32)
+		**out = **in
+	}
 	if in.Port != nil {
 		in, out := &in.Port, &out.Port
 		*out = new(int32)
 		**out = **in
 	}
-	if in.SyncPeriodSeconds != nil {
-		in, out := &in.SyncPeriodSeconds, &out.SyncPeriodSeconds
-		*out = new(int32)
-		**out = **in
+	if in.SyncPeriodSeconds != nil {
+		in, out := &in.SyncPeriodSeconds, &out.SyncPeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Switchover != nil {
+		in, out := &in.Switchover, &out.Switchover
+		*out = new(PatroniSwitchover)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rewind != nil {
+		in, out := &in.Rewind, &out.Rewind
+		*out = new(PatroniRewind)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Kubernetes != nil {
+		in, out := &in.Kubernetes, &out.Kubernetes
+		*out = new(PatroniKubernetes)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatroniSpec.
+func (in *PatroniSpec) DeepCopy() *PatroniSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PatroniSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatroniStatus) DeepCopyInto(out *PatroniStatus) {
+	*out = *in
+	if in.Switchover != nil {
+		in, out := &in.Switchover, &out.Switchover
+		*out = new(string)
+		**out = **in
+	}
+	if in.PendingRestart != nil {
+		in, out := &in.PendingRestart, &out.PendingRestart
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatroniStatus.
+func (in *PatroniStatus) DeepCopy() *PatroniStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PatroniStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatroniSwitchover) DeepCopyInto(out *PatroniSwitchover) {
+	*out = *in
+	if in.TargetInstance != nil {
+		in, out := &in.TargetInstance, &out.TargetInstance
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatroniSwitchover.
+func (in *PatroniSwitchover) DeepCopy() *PatroniSwitchover {
+	if in == nil {
+		return nil
+	}
+	out := new(PatroniSwitchover)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatroniTags) DeepCopyInto(out *PatroniTags) {
+	*out = *in
+	if in.NoFailover != nil {
+		in, out := &in.NoFailover, &out.NoFailover
+		*out = new(bool)
+		**out = **in
+	}
+	if in.NoLoadBalance != nil {
+		in, out := &in.NoLoadBalance, &out.NoLoadBalance
+		*out = new(bool)
+		**out = **in
+	}
+	if in.NoStream != nil {
+		in, out := &in.NoStream, &out.NoStream
+		*out = new(bool)
+		**out = **in
+	}
+	if in.NoSync != nil {
+		in, out := &in.NoSync, &out.NoSync
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatroniTags.
+func (in *PatroniTags) DeepCopy() *PatroniTags {
+	if in == nil {
+		return nil
+	}
+	out := new(PatroniTags)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresAdoptionStatus) DeepCopyInto(out *PostgresAdoptionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresAdoptionStatus.
+func (in *PostgresAdoptionStatus) DeepCopy() *PostgresAdoptionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresAdoptionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresAuthenticationSpec) DeepCopyInto(out *PostgresAuthenticationSpec) {
+	*out = *in
+	if in.ClientCertificates != nil {
+		in, out := &in.ClientCertificates, &out.ClientCertificates
+		*out = new(PostgresClientCertificatesSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresAuthenticationSpec.
+func (in *PostgresAuthenticationSpec) DeepCopy() *PostgresAuthenticationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresAuthenticationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresBlueGreenSpec) DeepCopyInto(out *PostgresBlueGreenSpec) {
+	*out = *in
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]PostgresIdentifier, len(*in))
+		copy(*out, *in)
+	}
+	if in.Cutover != nil {
+		in, out := &in.Cutover, &out.Cutover
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresBlueGreenSpec.
+func (in *PostgresBlueGreenSpec) DeepCopy() *PostgresBlueGreenSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresBlueGreenSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresBlueGreenStatus) DeepCopyInto(out *PostgresBlueGreenStatus) {
+	*out = *in
+	if in.FencedRoles != nil {
+		in, out := &in.FencedRoles, &out.FencedRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresBlueGreenStatus.
+func (in *PostgresBlueGreenStatus) DeepCopy() *PostgresBlueGreenStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresBlueGreenStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresClientCertificatesSpec) DeepCopyInto(out *PostgresClientCertificatesSpec) {
+	*out = *in
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]PostgresIdentifier, len(*in))
+		copy(*out, *in)
+	}
+	if in.IssuerRef != nil {
+		in, out := &in.IssuerRef, &out.IssuerRef
+		*out = new(CertManagerIssuerReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresClientCertificatesSpec.
+func (in *PostgresClientCertificatesSpec) DeepCopy() *PostgresClientCertificatesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresClientCertificatesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresCluster) DeepCopyInto(out *PostgresCluster) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresCluster.
+func (in *PostgresCluster) DeepCopy() *PostgresCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PostgresCluster) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresClusterBinding) DeepCopyInto(out *PostgresClusterBinding) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresClusterBinding.
+func (in *PostgresClusterBinding) DeepCopy() *PostgresClusterBinding {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresClusterBinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresClusterDataSource) DeepCopyInto(out *PostgresClusterDataSource) {
+	*out = *in
+	if in.Options != nil {
+		in, out := &in.Options, &out.Options
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PriorityClassName != nil {
+		in, out := &in.PriorityClassName, &out.PriorityClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresClusterDataSource.
+func (in *PostgresClusterDataSource) DeepCopy() *PostgresClusterDataSource {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresClusterDataSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresClusterList) DeepCopyInto(out *PostgresClusterList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PostgresCluster, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresClusterList.
+func (in *PostgresClusterList) DeepCopy() *PostgresClusterList {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresClusterList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PostgresClusterList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresClusterSpec) DeepCopyInto(out *PostgresClusterSpec) {
+	*out = *in
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(Metadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DataSource != nil {
+		in, out := &in.DataSource, &out.DataSource
+		*out = new(DataSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Authentication != nil {
+		in, out := &in.Authentication, &out.Authentication
+		*out = new(PostgresAuthenticationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Backups.DeepCopyInto(&out.Backups)
+	if in.BlueGreen != nil {
+		in, out := &in.BlueGreen, &out.BlueGreen
+		*out = new(PostgresBlueGreenSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = new(PostgresConfigSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CustomTLSSecret != nil {
+		in, out := &in.CustomTLSSecret, &out.CustomTLSSecret
+		*out = new(v1.SecretProjection)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CustomReplicationClientTLSSecret != nil {
+		in, out := &in.CustomReplicationClientTLSSecret, &out.CustomReplicationClientTLSSecret
+		*out = new(v1.SecretProjection)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DatabaseInitSQL != nil {
+		in, out := &in.DatabaseInitSQL, &out.DatabaseInitSQL
+		*out = new(DatabaseInitSQL)
+		**out = **in
+	}
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]PostgresDatabaseSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DisableDefaultPodScheduling != nil {
+		in, out := &in.DisableDefaultPodScheduling, &out.DisableDefaultPodScheduling
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = new(PostgresExtensionsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]Architecture, len(*in))
+		copy(*out, *in)
+	}
+	if in.DNSConfig != nil {
+		in, out := &in.DNSConfig, &out.DNSConfig
+		*out = new(v1.PodDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostAliases != nil {
+		in, out := &in.HostAliases, &out.HostAliases
+		*out = make([]v1.HostAlias, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.InstanceSets != nil {
+		in, out := &in.InstanceSets, &out.InstanceSets
+		*out = make([]PostgresInstanceSetSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Logging != nil {
+		in, out := &in.Logging, &out.Logging
+		*out = new(PostgresLoggingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = new(PostgresMaintenanceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OpenShift != nil {
+		in, out := &in.OpenShift, &out.OpenShift
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Patroni != nil {
+		in, out := &in.Patroni, &out.Patroni
+		*out = new(PatroniSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(PostgresProxySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Monitoring != nil {
+		in, out := &in.Monitoring, &out.Monitoring
+		*out = new(MonitoringSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(ServiceSpec)
+		**out = **in
+	}
+	if in.AnalyticsService != nil {
+		in, out := &in.AnalyticsService, &out.AnalyticsService
+		*out = new(ServiceSpec)
+		**out = **in
+	}
+	if in.VolumeUsage != nil {
+		in, out := &in.VolumeUsage, &out.VolumeUsage
+		*out = new(PostgresVolumeUsageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResourceUsage != nil {
+		in, out := &in.ResourceUsage, &out.ResourceUsage
+		*out = new(PostgresResourceUsageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrimaryService != nil {
+		in, out := &in.PrimaryService, &out.PrimaryService
+		*out = new(PrimaryServiceSpec)
+		**out = **in
+	}
+	if in.Shutdown != nil {
+		in, out := &in.Shutdown, &out.Shutdown
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Repair != nil {
+		in, out := &in.Repair, &out.Repair
+		*out = new(PostgresRepairSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReplicaHealing != nil {
+		in, out := &in.ReplicaHealing, &out.ReplicaHealing
+		*out = new(PostgresReplicaHealingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Standby != nil {
+		in, out := &in.Standby, &out.Standby
+		*out = new(PostgresStandbySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SupplementalGroups != nil {
+		in, out := &in.SupplementalGroups, &out.SupplementalGroups
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
+	if in.Teardown != nil {
+		in, out := &in.Teardown, &out.Teardown
+		*out = new(PostgresTeardownSpec)
+		**out = **in
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]PostgresUserSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresClusterSpec.
+func (in *PostgresClusterSpec) DeepCopy() *PostgresClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresClusterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresClusterStatus) DeepCopyInto(out *PostgresClusterStatus) {
+	*out = *in
+	if in.InstanceSets != nil {
+		in, out := &in.InstanceSets, &out.InstanceSets
+		*out = make([]PostgresInstanceSetStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Patroni != nil {
+		in, out := &in.Patroni, &out.Patroni
+		*out = new(PatroniStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PGBackRest != nil {
+		in, out := &in.PGBackRest, &out.PGBackRest
+		*out = new(PGBackRestStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	out.Proxy = in.Proxy
+	if in.Binding != nil {
+		in, out := &in.Binding, &out.Binding
+		*out = new(PostgresClusterBinding)
+		**out = **in
+	}
+	out.Monitoring = in.Monitoring
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = new(PostgresMaintenanceStatus)
+		**out = **in
+	}
+	if in.Repair != nil {
+		in, out := &in.Repair, &out.Repair
+		*out = new(PostgresRepairStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BlueGreen != nil {
+		in, out := &in.BlueGreen, &out.BlueGreen
+		*out = new(PostgresBlueGreenStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Adoption != nil {
+		in, out := &in.Adoption, &out.Adoption
+		*out = new(PostgresAdoptionStatus)
+		**out = **in
+	}
+	if in.Standby != nil {
+		in, out := &in.Standby, &out.Standby
+		*out = new(PostgresStandbyStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReplicaHealing != nil {
+		in, out := &in.ReplicaHealing, &out.ReplicaHealing
+		*out = new(PostgresReplicaHealingStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Export != nil {
+		in, out := &in.Export, &out.Export
+		*out = new(PostgresExportStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpgradeAvailable != nil {
+		in, out := &in.UpgradeAvailable, &out.UpgradeAvailable
+		*out = new(PostgresUpgradeAvailableStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DatabaseInitSQL != nil {
+		in, out := &in.DatabaseInitSQL, &out.DatabaseInitSQL
+		*out = new(string)
+		**out = **in
+	}
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]PostgresDatabaseStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresClusterStatus.
+func (in *PostgresClusterStatus) DeepCopy() *PostgresClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresConfigSpec) DeepCopyInto(out *PostgresConfigSpec) {
+	*out = *in
+	if in.AutoTune != nil {
+		in, out := &in.AutoTune, &out.AutoTune
+		*out = new(bool)
+		**out = **in
+	}
+	if in.InitDB != nil {
+		in, out := &in.InitDB, &out.InitDB
+		*out = new(PostgresInitDBSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(PostgresTLSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresConfigSpec.
+func (in *PostgresConfigSpec) DeepCopy() *PostgresConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresDatabaseSpec) DeepCopyInto(out *PostgresDatabaseSpec) {
+	*out = *in
+	if in.InitSQLConfigMapRef != nil {
+		in, out := &in.InitSQLConfigMapRef, &out.InitSQLConfigMapRef
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresDatabaseSpec.
+func (in *PostgresDatabaseSpec) DeepCopy() *PostgresDatabaseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresDatabaseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresDatabaseStatus) DeepCopyInto(out *PostgresDatabaseStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresDatabaseStatus.
+func (in *PostgresDatabaseStatus) DeepCopy() *PostgresDatabaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresDatabaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresEphemeralVolumeSpec) DeepCopyInto(out *PostgresEphemeralVolumeSpec) {
+	*out = *in
+	if in.SizeLimit != nil {
+		in, out := &in.SizeLimit, &out.SizeLimit
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.VolumeClaimSpec != nil {
+		in, out := &in.VolumeClaimSpec, &out.VolumeClaimSpec
+		*out = new(v1.PersistentVolumeClaimSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresEphemeralVolumeSpec.
+func (in *PostgresEphemeralVolumeSpec) DeepCopy() *PostgresEphemeralVolumeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresEphemeralVolumeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresExportStatus) DeepCopyInto(out *PostgresExportStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresExportStatus.
+func (in *PostgresExportStatus) DeepCopy() *PostgresExportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresExportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresExtensionsSpec) DeepCopyInto(out *PostgresExtensionsSpec) {
+	*out = *in
+	if in.PGCron != nil {
+		in, out := &in.PGCron, &out.PGCron
+		*out = new(PGCronSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PGVector != nil {
+		in, out := &in.PGVector, &out.PGVector
+		*out = new(PGVectorSpec)
+		**out = **in
+	}
+	if in.PGPartman != nil {
+		in, out := &in.PGPartman, &out.PGPartman
+		*out = new(PGPartmanSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ForeignServers != nil {
+		in, out := &in.ForeignServers, &out.ForeignServers
+		*out = make([]PostgresForeignServerSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresExtensionsSpec.
+func (in *PostgresExtensionsSpec) DeepCopy() *PostgresExtensionsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresExtensionsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresForeignServerSpec) DeepCopyInto(out *PostgresForeignServerSpec) {
+	*out = *in
+	if in.Options != nil {
+		in, out := &in.Options, &out.Options
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.UserMappings != nil {
+		in, out := &in.UserMappings, &out.UserMappings
+		*out = make([]PostgresUserMappingSpec, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresForeignServerSpec.
+func (in *PostgresForeignServerSpec) DeepCopy() *PostgresForeignServerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresForeignServerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresInitDBSpec) DeepCopyInto(out *PostgresInitDBSpec) {
+	*out = *in
+	if in.DataChecksums != nil {
+		in, out := &in.DataChecksums, &out.DataChecksums
+		*out = new(bool)
+		**out = **in
+	}
+	if in.WALSegmentSize != nil {
+		in, out := &in.WALSegmentSize, &out.WALSegmentSize
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresInitDBSpec.
+func (in *PostgresInitDBSpec) DeepCopy() *PostgresInitDBSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresInitDBSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresInstanceMemberStatus) DeepCopyInto(out *PostgresInstanceMemberStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresInstanceMemberStatus.
+func (in *PostgresInstanceMemberStatus) DeepCopy() *PostgresInstanceMemberStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresInstanceMemberStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresInstanceSetSpec) DeepCopyInto(out *PostgresInstanceSetSpec) {
+	*out = *in
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(Metadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PreviousNames != nil {
+		in, out := &in.PreviousNames, &out.PreviousNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	in.DataVolumeClaimSpec.DeepCopyInto(&out.DataVolumeClaimSpec)
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]v1.EnvFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StartupDebug != nil {
+		in, out := &in.StartupDebug, &out.StartupDebug
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Ephemeral != nil {
+		in, out := &in.Ephemeral, &out.Ephemeral
+		*out = new(PostgresEphemeralVolumeSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PersistentVolumeClaimRetentionPolicy != nil {
+		in, out := &in.PersistentVolumeClaimRetentionPolicy, &out.PersistentVolumeClaimRetentionPolicy
+		*out = new(PostgresVolumeRetentionPolicy)
+		**out = **in
+	}
+	if in.PriorityClassName != nil {
+		in, out := &in.PriorityClassName, &out.PriorityClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.RecoverAfterNodeFailureSeconds != nil {
+		in, out := &in.RecoverAfterNodeFailureSeconds, &out.RecoverAfterNodeFailureSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Sidecars != nil {
+		in, out := &in.Sidecars, &out.Sidecars
+		*out = new(InstanceSidecars)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = new(PatroniTags)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WALVolumeClaimSpec != nil {
+		in, out := &in.WALVolumeClaimSpec, &out.WALVolumeClaimSpec
+		*out = new(v1.PersistentVolumeClaimSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ZoneSpread != nil {
+		in, out := &in.ZoneSpread, &out.ZoneSpread
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresInstanceSetSpec.
+func (in *PostgresInstanceSetSpec) DeepCopy() *PostgresInstanceSetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresInstanceSetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresInstanceSetStatus) DeepCopyInto(out *PostgresInstanceSetStatus) {
+	*out = *in
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]PostgresInstanceMemberStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]PostgresVolumeUsageStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]PostgresResourceUsageStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatroniSpec.
-func (in *PatroniSpec) DeepCopy() *PatroniSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresInstanceSetStatus.
+func (in *PostgresInstanceSetStatus) DeepCopy() *PostgresInstanceSetStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PatroniSpec)
+	out := new(PostgresInstanceSetStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PatroniStatus) DeepCopyInto(out *PatroniStatus) {
+func (in *PostgresLogRotationSpec) DeepCopyInto(out *PostgresLogRotationSpec) {
 	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatroniStatus.
-func (in *PatroniStatus) DeepCopy() *PatroniStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresLogRotationSpec.
+func (in *PostgresLogRotationSpec) DeepCopy() *PostgresLogRotationSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PatroniStatus)
+	out := new(PostgresLogRotationSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PostgresCluster) DeepCopyInto(out *PostgresCluster) {
+func (in *PostgresLogShipperSpec) DeepCopyInto(out *PostgresLogShipperSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	if in.Configuration != nil {
+		in, out := &in.Configuration, &out.Configuration
+		*out = make([]v1.VolumeProjection, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresCluster.
-func (in *PostgresCluster) DeepCopy() *PostgresCluster {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresLogShipperSpec.
+func (in *PostgresLogShipperSpec) DeepCopy() *PostgresLogShipperSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PostgresCluster)
+	out := new(PostgresLogShipperSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PostgresCluster) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresLogVolumeSpec) DeepCopyInto(out *PostgresLogVolumeSpec) {
+	*out = *in
+	if in.SizeLimit != nil {
+		in, out := &in.SizeLimit, &out.SizeLimit
+		x := (*in).DeepCopy()
+		*out = &x
 	}
-	return nil
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresLogVolumeSpec.
+func (in *PostgresLogVolumeSpec) DeepCopy() *PostgresLogVolumeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresLogVolumeSpec)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PostgresClusterDataSource) DeepCopyInto(out *PostgresClusterDataSource) {
+func (in *PostgresLoggingSpec) DeepCopyInto(out *PostgresLoggingSpec) {
 	*out = *in
-	if in.Options != nil {
-		in, out := &in.Options, &out.Options
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	if in.Rotation != nil {
+		in, out := &in.Rotation, &out.Rotation
+		*out = new(PostgresLogRotationSpec)
+		(*in).DeepCopyInto(*out)
 	}
-	in.Resources.DeepCopyInto(&out.Resources)
-	if in.Affinity != nil {
-		in, out := &in.Affinity, &out.Affinity
-		*out = new(v1.Affinity)
+	if in.Volume != nil {
+		in, out := &in.Volume, &out.Volume
+		*out = new(PostgresLogVolumeSpec)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.PriorityClassName != nil {
-		in, out := &in.PriorityClassName, &out.PriorityClassName
-		*out = new(string)
-		**out = **in
+	if in.Shipper != nil {
+		in, out := &in.Shipper, &out.Shipper
+		*out = new(PostgresLogShipperSpec)
+		(*in).DeepCopyInto(*out)
 	}
-	if in.Tolerations != nil {
-		in, out := &in.Tolerations, &out.Tolerations
-		*out = make([]v1.Toleration, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.PGBouncer != nil {
+		in, out := &in.PGBouncer, &out.PGBouncer
+		*out = new(PGBouncerLoggingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PGBackRest != nil {
+		in, out := &in.PGBackRest, &out.PGBackRest
+		*out = new(PGBackRestLoggingSpec)
+		(*in).DeepCopyInto(*out)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresClusterDataSource.
-func (in *PostgresClusterDataSource) DeepCopy() *PostgresClusterDataSource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresLoggingSpec.
+func (in *PostgresLoggingSpec) DeepCopy() *PostgresLoggingSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PostgresClusterDataSource)
+	out := new(PostgresLoggingSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PostgresClusterList) DeepCopyInto(out *PostgresClusterList) {
+func (in *PostgresMaintenanceJob) DeepCopyInto(out *PostgresMaintenanceJob) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]PostgresCluster, len(*in))
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresMaintenanceJob.
+func (in *PostgresMaintenanceJob) DeepCopy() *PostgresMaintenanceJob {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresMaintenanceJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresMaintenanceSpec) DeepCopyInto(out *PostgresMaintenanceSpec) {
+	*out = *in
+	if in.Jobs != nil {
+		in, out := &in.Jobs, &out.Jobs
+		*out = make([]PostgresMaintenanceJob, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresClusterList.
-func (in *PostgresClusterList) DeepCopy() *PostgresClusterList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresMaintenanceSpec.
+func (in *PostgresMaintenanceSpec) DeepCopy() *PostgresMaintenanceSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PostgresClusterList)
+	out := new(PostgresMaintenanceSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PostgresClusterList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresMaintenanceStatus) DeepCopyInto(out *PostgresMaintenanceStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresMaintenanceStatus.
+func (in *PostgresMaintenanceStatus) DeepCopy() *PostgresMaintenanceStatus {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(PostgresMaintenanceStatus)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PostgresClusterSpec) DeepCopyInto(out *PostgresClusterSpec) {
+func (in *PostgresPrivilegesSpec) DeepCopyInto(out *PostgresPrivilegesSpec) {
 	*out = *in
-	if in.Metadata != nil {
-		in, out := &in.Metadata, &out.Metadata
-		*out = new(Metadata)
-		(*in).DeepCopyInto(*out)
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]PostgresIdentifier, len(*in))
+		copy(*out, *in)
 	}
-	if in.DataSource != nil {
-		in, out := &in.DataSource, &out.DataSource
-		*out = new(DataSource)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresPrivilegesSpec.
+func (in *PostgresPrivilegesSpec) DeepCopy() *PostgresPrivilegesSpec {
+	if in == nil {
+		return nil
 	}
-	in.Backups.DeepCopyInto(&out.Backups)
-	if in.CustomTLSSecret != nil {
-		in, out := &in.CustomTLSSecret, &out.CustomTLSSecret
-		*out = new(v1.SecretProjection)
+	out := new(PostgresPrivilegesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresProxySpec) DeepCopyInto(out *PostgresProxySpec) {
+	*out = *in
+	if in.PGBouncer != nil {
+		in, out := &in.PGBouncer, &out.PGBouncer
+		*out = new(PGBouncerPodSpec)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.CustomReplicationClientTLSSecret != nil {
-		in, out := &in.CustomReplicationClientTLSSecret, &out.CustomReplicationClientTLSSecret
-		*out = new(v1.SecretProjection)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresProxySpec.
+func (in *PostgresProxySpec) DeepCopy() *PostgresProxySpec {
+	if in == nil {
+		return nil
 	}
-	if in.DatabaseInitSQL != nil {
-		in, out := &in.DatabaseInitSQL, &out.DatabaseInitSQL
-		*out = new(DatabaseInitSQL)
-		**out = **in
+	out := new(PostgresProxySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresProxyStatus) DeepCopyInto(out *PostgresProxyStatus) {
+	*out = *in
+	out.PGBouncer = in.PGBouncer
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresProxyStatus.
+func (in *PostgresProxyStatus) DeepCopy() *PostgresProxyStatus {
+	if in == nil {
+		return nil
 	}
-	if in.DisableDefaultPodScheduling != nil {
-		in, out := &in.DisableDefaultPodScheduling, &out.DisableDefaultPodScheduling
+	out := new(PostgresProxyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresRepairSpec) DeepCopyInto(out *PostgresRepairSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
 		*out = new(bool)
 		**out = **in
 	}
-	if in.ImagePullSecrets != nil {
-		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
-		*out = make([]v1.LocalObjectReference, len(*in))
+	if in.Options != nil {
+		in, out := &in.Options, &out.Options
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.InstanceSets != nil {
-		in, out := &in.InstanceSets, &out.InstanceSets
-		*out = make([]PostgresInstanceSetSpec, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresRepairSpec.
+func (in *PostgresRepairSpec) DeepCopy() *PostgresRepairSpec {
+	if in == nil {
+		return nil
 	}
-	if in.OpenShift != nil {
-		in, out := &in.OpenShift, &out.OpenShift
-		*out = new(bool)
+	out := new(PostgresRepairSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresRepairStatus) DeepCopyInto(out *PostgresRepairStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresRepairStatus.
+func (in *PostgresRepairStatus) DeepCopy() *PostgresRepairStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresRepairStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresReplicaHealingSpec) DeepCopyInto(out *PostgresReplicaHealingSpec) {
+	*out = *in
+	if in.FailureSeconds != nil {
+		in, out := &in.FailureSeconds, &out.FailureSeconds
+		*out = new(int32)
 		**out = **in
 	}
-	if in.Patroni != nil {
-		in, out := &in.Patroni, &out.Patroni
-		*out = new(PatroniSpec)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresReplicaHealingSpec.
+func (in *PostgresReplicaHealingSpec) DeepCopy() *PostgresReplicaHealingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresReplicaHealingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresReplicaHealingStatus) DeepCopyInto(out *PostgresReplicaHealingStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
 	}
-	if in.Port != nil {
-		in, out := &in.Port, &out.Port
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresReplicaHealingStatus.
+func (in *PostgresReplicaHealingStatus) DeepCopy() *PostgresReplicaHealingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresReplicaHealingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresResourceUsageSpec) DeepCopyInto(out *PostgresResourceUsageSpec) {
+	*out = *in
+	if in.IntervalSeconds != nil {
+		in, out := &in.IntervalSeconds, &out.IntervalSeconds
 		*out = new(int32)
 		**out = **in
 	}
-	if in.Proxy != nil {
-		in, out := &in.Proxy, &out.Proxy
-		*out = new(PostgresProxySpec)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Monitoring != nil {
-		in, out := &in.Monitoring, &out.Monitoring
-		*out = new(MonitoringSpec)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Service != nil {
-		in, out := &in.Service, &out.Service
-		*out = new(ServiceSpec)
+	if in.MemoryPercent != nil {
+		in, out := &in.MemoryPercent, &out.MemoryPercent
+		*out = new(int32)
 		**out = **in
 	}
-	if in.Shutdown != nil {
-		in, out := &in.Shutdown, &out.Shutdown
-		*out = new(bool)
+	if in.SustainedSeconds != nil {
+		in, out := &in.SustainedSeconds, &out.SustainedSeconds
+		*out = new(int32)
 		**out = **in
 	}
-	if in.Standby != nil {
-		in, out := &in.Standby, &out.Standby
-		*out = new(PostgresStandbySpec)
-		**out = **in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresResourceUsageSpec.
+func (in *PostgresResourceUsageSpec) DeepCopy() *PostgresResourceUsageSpec {
+	if in == nil {
+		return nil
 	}
-	if in.SupplementalGroups != nil {
-		in, out := &in.SupplementalGroups, &out.SupplementalGroups
-		*out = make([]int64, len(*in))
-		copy(*out, *in)
+	out := new(PostgresResourceUsageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresResourceUsageStatus) DeepCopyInto(out *PostgresResourceUsageStatus) {
+	*out = *in
+	out.Memory = in.Memory.DeepCopy()
+	if in.MemoryLimit != nil {
+		in, out := &in.MemoryLimit, &out.MemoryLimit
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	out.CPU = in.CPU.DeepCopy()
+	if in.HighMemorySince != nil {
+		in, out := &in.HighMemorySince, &out.HighMemorySince
+		*out = (*in).DeepCopy()
 	}
-	if in.Users != nil {
-		in, out := &in.Users, &out.Users
-		*out = make([]PostgresUserSpec, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	in.LastObservedTime.DeepCopyInto(&out.LastObservedTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresResourceUsageStatus.
+func (in *PostgresResourceUsageStatus) DeepCopy() *PostgresResourceUsageStatus {
+	if in == nil {
+		return nil
 	}
+	out := new(PostgresResourceUsageStatus)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresClusterSpec.
-func (in *PostgresClusterSpec) DeepCopy() *PostgresClusterSpec {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresStandbySpec) DeepCopyInto(out *PostgresStandbySpec) {
+	*out = *in
+	if in.PromotionGracePeriodSeconds != nil {
+		in, out := &in.PromotionGracePeriodSeconds, &out.PromotionGracePeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresStandbySpec.
+func (in *PostgresStandbySpec) DeepCopy() *PostgresStandbySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PostgresClusterSpec)
+	out := new(PostgresStandbySpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PostgresClusterStatus) DeepCopyInto(out *PostgresClusterStatus) {
+func (in *PostgresStandbyStatus) DeepCopyInto(out *PostgresStandbyStatus) {
 	*out = *in
-	if in.InstanceSets != nil {
-		in, out := &in.InstanceSets, &out.InstanceSets
-		*out = make([]PostgresInstanceSetStatus, len(*in))
-		copy(*out, *in)
+	if in.PromotionRequestTime != nil {
+		in, out := &in.PromotionRequestTime, &out.PromotionRequestTime
+		*out = (*in).DeepCopy()
 	}
-	if in.Patroni != nil {
-		in, out := &in.Patroni, &out.Patroni
-		*out = new(PatroniStatus)
-		**out = **in
+	if in.ReplayTime != nil {
+		in, out := &in.ReplayTime, &out.ReplayTime
+		*out = (*in).DeepCopy()
 	}
-	if in.PGBackRest != nil {
-		in, out := &in.PGBackRest, &out.PGBackRest
-		*out = new(PGBackRestStatus)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresStandbyStatus.
+func (in *PostgresStandbyStatus) DeepCopy() *PostgresStandbyStatus {
+	if in == nil {
+		return nil
 	}
-	out.Proxy = in.Proxy
-	out.Monitoring = in.Monitoring
-	if in.DatabaseInitSQL != nil {
-		in, out := &in.DatabaseInitSQL, &out.DatabaseInitSQL
-		*out = new(string)
+	out := new(PostgresStandbyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresTLSSpec) DeepCopyInto(out *PostgresTLSSpec) {
+	*out = *in
+	if in.RequireSSL != nil {
+		in, out := &in.RequireSSL, &out.RequireSSL
+		*out = new(bool)
 		**out = **in
 	}
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]metav1.Condition, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresClusterStatus.
-func (in *PostgresClusterStatus) DeepCopy() *PostgresClusterStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresTLSSpec.
+func (in *PostgresTLSSpec) DeepCopy() *PostgresTLSSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PostgresClusterStatus)
+	out := new(PostgresTLSSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PostgresInstanceSetSpec) DeepCopyInto(out *PostgresInstanceSetSpec) {
+func (in *PostgresTeardownSpec) DeepCopyInto(out *PostgresTeardownSpec) {
 	*out = *in
-	if in.Metadata != nil {
-		in, out := &in.Metadata, &out.Metadata
-		*out = new(Metadata)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresTeardownSpec.
+func (in *PostgresTeardownSpec) DeepCopy() *PostgresTeardownSpec {
+	if in == nil {
+		return nil
 	}
-	if in.Affinity != nil {
-		in, out := &in.Affinity, &out.Affinity
-		*out = new(v1.Affinity)
-		(*in).DeepCopyInto(*out)
+	out := new(PostgresTeardownSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresUpgradeAvailableStatus) DeepCopyInto(out *PostgresUpgradeAvailableStatus) {
+	*out = *in
+	if in.CheckTime != nil {
+		in, out := &in.CheckTime, &out.CheckTime
+		*out = (*in).DeepCopy()
 	}
-	in.DataVolumeClaimSpec.DeepCopyInto(&out.DataVolumeClaimSpec)
-	if in.PriorityClassName != nil {
-		in, out := &in.PriorityClassName, &out.PriorityClassName
-		*out = new(string)
-		**out = **in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresUpgradeAvailableStatus.
+func (in *PostgresUpgradeAvailableStatus) DeepCopy() *PostgresUpgradeAvailableStatus {
+	if in == nil {
+		return nil
 	}
-	if in.Replicas != nil {
-		in, out := &in.Replicas, &out.Replicas
-		*out = new(int32)
-		**out = **in
+	out := new(PostgresUpgradeAvailableStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresUserMappingSpec) DeepCopyInto(out *PostgresUserMappingSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresUserMappingSpec.
+func (in *PostgresUserMappingSpec) DeepCopy() *PostgresUserMappingSpec {
+	if in == nil {
+		return nil
 	}
-	in.Resources.DeepCopyInto(&out.Resources)
-	if in.Sidecars != nil {
-		in, out := &in.Sidecars, &out.Sidecars
-		*out = new(InstanceSidecars)
-		(*in).DeepCopyInto(*out)
+	out := new(PostgresUserMappingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresUserSpec) DeepCopyInto(out *PostgresUserSpec) {
+	*out = *in
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]PostgresIdentifier, len(*in))
+		copy(*out, *in)
 	}
-	if in.Tolerations != nil {
-		in, out := &in.Tolerations, &out.Tolerations
-		*out = make([]v1.Toleration, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.MemberOf != nil {
+		in, out := &in.MemberOf, &out.MemberOf
+		*out = make([]PostgresIdentifier, len(*in))
+		copy(*out, *in)
 	}
-	if in.TopologySpreadConstraints != nil {
-		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
-		*out = make([]v1.TopologySpreadConstraint, len(*in))
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]PostgresPrivilegesSpec, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.WALVolumeClaimSpec != nil {
-		in, out := &in.WALVolumeClaimSpec, &out.WALVolumeClaimSpec
-		*out = new(v1.PersistentVolumeClaimSpec)
-		(*in).DeepCopyInto(*out)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresInstanceSetSpec.
-func (in *PostgresInstanceSetSpec) DeepCopy() *PostgresInstanceSetSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresUserSpec.
+func (in *PostgresUserSpec) DeepCopy() *PostgresUserSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PostgresInstanceSetSpec)
+	out := new(PostgresUserSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PostgresInstanceSetStatus) DeepCopyInto(out *PostgresInstanceSetStatus) {
+func (in *PostgresVolumeRetentionPolicy) DeepCopyInto(out *PostgresVolumeRetentionPolicy) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresInstanceSetStatus.
-func (in *PostgresInstanceSetStatus) DeepCopy() *PostgresInstanceSetStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresVolumeRetentionPolicy.
+func (in *PostgresVolumeRetentionPolicy) DeepCopy() *PostgresVolumeRetentionPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(PostgresInstanceSetStatus)
+	out := new(PostgresVolumeRetentionPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PostgresProxySpec) DeepCopyInto(out *PostgresProxySpec) {
+func (in *PostgresVolumeUsageSpec) DeepCopyInto(out *PostgresVolumeUsageSpec) {
 	*out = *in
-	if in.PGBouncer != nil {
-		in, out := &in.PGBouncer, &out.PGBouncer
-		*out = new(PGBouncerPodSpec)
-		(*in).DeepCopyInto(*out)
+	if in.IntervalSeconds != nil {
+		in, out := &in.IntervalSeconds, &out.IntervalSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.WarningPercent != nil {
+		in, out := &in.WarningPercent, &out.WarningPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ArchiveSacrificePercent != nil {
+		in, out := &in.ArchiveSacrificePercent, &out.ArchiveSacrificePercent
+		*out = new(int32)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresProxySpec.
-func (in *PostgresProxySpec) DeepCopy() *PostgresProxySpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresVolumeUsageSpec.
+func (in *PostgresVolumeUsageSpec) DeepCopy() *PostgresVolumeUsageSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PostgresProxySpec)
+	out := new(PostgresVolumeUsageSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PostgresProxyStatus) DeepCopyInto(out *PostgresProxyStatus) {
+func (in *PostgresVolumeUsageStatus) DeepCopyInto(out *PostgresVolumeUsageStatus) {
 	*out = *in
-	out.PGBouncer = in.PGBouncer
+	out.Capacity = in.Capacity.DeepCopy()
+	out.Used = in.Used.DeepCopy()
+	in.LastObservedTime.DeepCopyInto(&out.LastObservedTime)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresProxyStatus.
-func (in *PostgresProxyStatus) DeepCopy() *PostgresProxyStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresVolumeUsageStatus.
+func (in *PostgresVolumeUsageStatus) DeepCopy() *PostgresVolumeUsageStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PostgresProxyStatus)
+	out := new(PostgresVolumeUsageStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PostgresStandbySpec) DeepCopyInto(out *PostgresStandbySpec) {
+func (in *PrimaryServiceSpec) DeepCopyInto(out *PrimaryServiceSpec) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresStandbySpec.
-func (in *PostgresStandbySpec) DeepCopy() *PostgresStandbySpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrimaryServiceSpec.
+func (in *PrimaryServiceSpec) DeepCopy() *PrimaryServiceSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PostgresStandbySpec)
+	out := new(PrimaryServiceSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PostgresUserSpec) DeepCopyInto(out *PostgresUserSpec) {
+func (in *QueryStatisticsSpec) DeepCopyInto(out *QueryStatisticsSpec) {
 	*out = *in
-	if in.Databases != nil {
-		in, out := &in.Databases, &out.Databases
-		*out = make([]PostgresIdentifier, len(*in))
-		copy(*out, *in)
+	if in.PGStatStatements != nil {
+		in, out := &in.PGStatStatements, &out.PGStatStatements
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TopStatements != nil {
+		in, out := &in.TopStatements, &out.TopStatements
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TrackIOTiming != nil {
+		in, out := &in.TrackIOTiming, &out.TrackIOTiming
+		*out = new(bool)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresUserSpec.
-func (in *PostgresUserSpec) DeepCopy() *PostgresUserSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QueryStatisticsSpec.
+func (in *QueryStatisticsSpec) DeepCopy() *QueryStatisticsSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PostgresUserSpec)
+	out := new(QueryStatisticsSpec)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -1287,6 +2951,11 @@ func (in *RepoS3) DeepCopy() *RepoS3 {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RepoStatus) DeepCopyInto(out *RepoStatus) {
 	*out = *in
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(RepoVerificationStatus)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepoStatus.
@@ -1299,6 +2968,21 @@ func (in *RepoStatus) DeepCopy() *RepoStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepoVerificationStatus) DeepCopyInto(out *RepoVerificationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepoVerificationStatus.
+func (in *RepoVerificationStatus) DeepCopy() *RepoVerificationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RepoVerificationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceSpec) DeepCopyInto(out *ServiceSpec) {
 	*out = *in